@@ -6,6 +6,7 @@
 package addrmgr
 
 import (
+	"bytes"
 	"container/list"
 	crand "crypto/rand" // for seeding
 	"encoding/base32"
@@ -25,6 +26,7 @@ import (
 
 	"github.com/decred/dcrd/chaincfg/chainhash"
 	"github.com/decred/dcrd/wire"
+	"golang.org/x/crypto/sha3"
 )
 
 // PeersFilename is the default filename to store serialized peers.
@@ -59,6 +61,8 @@ type serializedKnownAddress struct {
 	TimeStamp   int64
 	LastAttempt int64
 	LastSuccess int64
+	Network     wire.NetworkID `json:",omitempty"`
+	SrcNetwork  wire.NetworkID `json:",omitempty"`
 	// no refcount or tried, that is available from context.
 }
 
@@ -159,7 +163,36 @@ const (
 	getAddrPercent = 23
 
 	// serialisationVersion is the current version of the on-disk format.
-	serialisationVersion = 1
+	// Version 2 added the networks of addresses that are not IP addresses
+	// since CJDNS addresses can not be distinguished from IPv6 addresses by
+	// their string representation alone.
+	serialisationVersion = 2
+
+	// torV3Version is the version byte of Tor v3 onion service addresses.
+	torV3Version = 0x03
+
+	// torV3ChecksumLen is the number of bytes of the checksum included in
+	// Tor v3 onion service addresses.
+	torV3ChecksumLen = 2
+
+	// torV3HostLen is the length of a Tor v3 onion service host, which is
+	// the base32 encoding of the public key, checksum, and version followed
+	// by ".onion".
+	torV3HostLen = 62
+
+	// i2pHostLen is the length of an I2P host, which is the base32 encoding
+	// of the destination hash followed by ".b32.i2p".
+	i2pHostLen = 60
+)
+
+var (
+	// torV3ChecksumPrefix is the prefix of the data hashed to produce the
+	// checksum of Tor v3 onion service addresses.
+	torV3ChecksumPrefix = []byte(".onion checksum")
+
+	// i2pEncoding is the base32 encoding used by I2P addresses, which is
+	// the standard encoding without padding.
+	i2pEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
 )
 
 // updateAddress is a helper function to either update an address already known
@@ -391,6 +424,8 @@ func (a *AddrManager) savePeers() {
 		ska.Attempts = v.attempts
 		ska.LastAttempt = v.lastattempt.Unix()
 		ska.LastSuccess = v.lastsuccess.Unix()
+		ska.Network = v.na.Network
+		ska.SrcNetwork = v.srcAddr.Network
 		// Tried and refs are implicit in the rest of the structure
 		// and will be worked out from context on unserialisation.
 		sam.Addresses[i] = ska
@@ -476,7 +511,7 @@ func (a *AddrManager) deserializePeers(filePath string) error {
 		return fmt.Errorf("error reading %s: %v", filePath, err)
 	}
 
-	if sam.Version != serialisationVersion {
+	if sam.Version < 1 || sam.Version > serialisationVersion {
 		return fmt.Errorf("unknown version %v in serialized "+
 			"addrmanager", sam.Version)
 	}
@@ -494,6 +529,12 @@ func (a *AddrManager) deserializePeers(filePath string) error {
 			return fmt.Errorf("failed to deserialize netaddress "+
 				"%s: %v", v.Src, err)
 		}
+		if v.Network != 0 {
+			ka.na.Network = v.Network
+		}
+		if v.SrcNetwork != 0 {
+			ka.srcAddr.Network = v.SrcNetwork
+		}
 		ka.attempts = v.Attempts
 		ka.lastattempt = time.Unix(v.LastAttempt, 0)
 		ka.lastsuccess = time.Unix(v.LastSuccess, 0)
@@ -712,11 +753,56 @@ func (a *AddrManager) reset() {
 	a.addrChanged = true
 }
 
+// torV3Checksum returns the checksum of the Tor v3 onion service address with
+// the passed public key.
+func torV3Checksum(pubKey []byte) []byte {
+	h := sha3.New256()
+	h.Write(torV3ChecksumPrefix)
+	h.Write(pubKey)
+	h.Write([]byte{torV3Version})
+	return h.Sum(nil)[:torV3ChecksumLen]
+}
+
 // HostToNetAddress returns a netaddress given a host address. If the address is
-// a Tor .onion address this will be taken care of. Else if the host is not an
-// IP address it will be resolved (via Tor if required).
+// a Tor .onion address or an I2P .b32.i2p address this will be taken care of.
+// Else if the host is not an IP address it will be resolved (via Tor if
+// required).
 func (a *AddrManager) HostToNetAddress(host string, port uint16, services wire.ServiceFlag) (*wire.NetAddress, error) {
-	// Tor address is 16 char base32 + ".onion"
+	// Tor v3 address is 56 char base32 + ".onion"
+	if len(host) == torV3HostLen && strings.HasSuffix(host, ".onion") {
+		data, err := base32.StdEncoding.DecodeString(
+			strings.ToUpper(host[:torV3HostLen-6]))
+		if err != nil {
+			return nil, err
+		}
+		pubKey := data[:32]
+		checksum := data[32 : 32+torV3ChecksumLen]
+		if data[len(data)-1] != torV3Version {
+			return nil, fmt.Errorf("unsupported onion address "+
+				"version %d", data[len(data)-1])
+		}
+		if !bytes.Equal(checksum, torV3Checksum(pubKey)) {
+			return nil, fmt.Errorf("invalid onion address checksum "+
+				"for %s", host)
+		}
+		na := wire.NewNetAddressIPPort(net.IP(pubKey), port, services)
+		na.Network = wire.NetworkTorV3
+		return na, nil
+	}
+
+	// I2P address is 52 char base32 + ".b32.i2p"
+	if len(host) == i2pHostLen && strings.HasSuffix(host, ".b32.i2p") {
+		data, err := i2pEncoding.DecodeString(
+			strings.ToUpper(host[:i2pHostLen-8]))
+		if err != nil {
+			return nil, err
+		}
+		na := wire.NewNetAddressIPPort(net.IP(data), port, services)
+		na.Network = wire.NetworkI2P
+		return na, nil
+	}
+
+	// Tor v2 address is 16 char base32 + ".onion"
 	var ip net.IP
 	if len(host) == 22 && host[16:] == ".onion" {
 		// go base32 encoding uses capitals (as does the rfc
@@ -744,9 +830,22 @@ func (a *AddrManager) HostToNetAddress(host string, port uint16, services wire.S
 }
 
 // ipString returns a string for the ip from the provided NetAddress. If the
-// ip is in the range used for Tor addresses then it will be transformed into
-// the relevant .onion address.
+// ip is in the range used for Tor addresses or the address is a Tor v3 address
+// then it will be transformed into the relevant .onion address.  Likewise, I2P
+// addresses are transformed into the relevant .b32.i2p address.
 func ipString(na *wire.NetAddress) string {
+	if isTorV3(na) {
+		data := make([]byte, 0, len(na.IP)+torV3ChecksumLen+1)
+		data = append(data, na.IP...)
+		data = append(data, torV3Checksum(na.IP)...)
+		data = append(data, torV3Version)
+		base32 := base32.StdEncoding.EncodeToString(data)
+		return strings.ToLower(base32) + ".onion"
+	}
+	if isI2P(na) {
+		return strings.ToLower(i2pEncoding.EncodeToString(na.IP)) +
+			".b32.i2p"
+	}
 	if isOnionCatTor(na) {
 		// We know now that na.IP is long enogh.
 		base32 := base32.StdEncoding.EncodeToString(na.IP[6:])
@@ -992,11 +1091,28 @@ func (a *AddrManager) SetServices(addr *wire.NetAddress, services wire.ServiceFl
 	}
 }
 
+// GetServices returns the services last known to be supported by the given
+// address or zero when the address is unknown.
+func (a *AddrManager) GetServices(addr *wire.NetAddress) wire.ServiceFlag {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	ka := a.find(addr)
+	if ka == nil {
+		return 0
+	}
+
+	ka.mtx.Lock()
+	services := ka.na.Services
+	ka.mtx.Unlock()
+	return services
+}
+
 // AddLocalAddress adds na to the list of known local addresses to advertise
 // with the given priority.
 func (a *AddrManager) AddLocalAddress(na *wire.NetAddress, priority AddressPriority) error {
 	if !IsRoutable(na) {
-		return fmt.Errorf("address %s is not routable", na.IP)
+		return fmt.Errorf("address %s is not routable", ipString(na))
 	}
 
 	a.lamtx.Lock()
@@ -1034,8 +1150,22 @@ func getReachabilityFrom(localAddr, remoteAddr *wire.NetAddress) int {
 		return Unreachable
 	}
 
-	if isOnionCatTor(remoteAddr) {
-		if isOnionCatTor(localAddr) {
+	if isI2P(remoteAddr) {
+		if isI2P(localAddr) {
+			return Private
+		}
+		return Unreachable
+	}
+
+	if isCJDNS(remoteAddr) {
+		if isCJDNS(localAddr) {
+			return Private
+		}
+		return Default
+	}
+
+	if isOnionCatTor(remoteAddr) || isTorV3(remoteAddr) {
+		if isOnionCatTor(localAddr) || isTorV3(localAddr) {
 			return Private
 		}
 
@@ -1070,6 +1200,10 @@ func getReachabilityFrom(localAddr, remoteAddr *wire.NetAddress) int {
 	}
 
 	/* ipv6 */
+	if !localAddr.IsIP() {
+		return Unreachable
+	}
+
 	var tunnelled bool
 	// Is our v6 tunnelled?
 	if isRFC3964(localAddr) || isRFC6052(localAddr) || isRFC6145(localAddr) {
@@ -1115,15 +1249,15 @@ func (a *AddrManager) GetBestLocalAddress(remoteAddr *wire.NetAddress) *wire.Net
 		}
 	}
 	if bestAddress != nil {
-		log.Debugf("Suggesting address %s:%d for %s:%d", bestAddress.IP,
-			bestAddress.Port, remoteAddr.IP, remoteAddr.Port)
+		log.Debugf("Suggesting address %s for %s",
+			NetAddressKey(bestAddress), NetAddressKey(remoteAddr))
 	} else {
-		log.Debugf("No worthy address for %s:%d", remoteAddr.IP,
-			remoteAddr.Port)
+		log.Debugf("No worthy address for %s", NetAddressKey(remoteAddr))
 
 		// Send something unroutable if nothing suitable.
 		var ip net.IP
-		if !isIPv4(remoteAddr) && !isOnionCatTor(remoteAddr) {
+		if !isIPv4(remoteAddr) && !isOnionCatTor(remoteAddr) &&
+			!isTorV3(remoteAddr) {
 			ip = net.IPv6zero
 		} else {
 			ip = net.IPv4zero
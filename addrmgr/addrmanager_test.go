@@ -316,6 +316,30 @@ func TestConnected(t *testing.T) {
 	}
 }
 
+func TestServices(t *testing.T) {
+	n := New("testservices", lookupFunc)
+
+	// Add a new address and get it
+	err := n.addAddressByIP(someIP + ":8333")
+	if err != nil {
+		t.Fatalf("Adding address failed: %v", err)
+	}
+	na := n.GetAddress().NetAddress()
+
+	services := wire.SFNodeNetwork | wire.SFNodeP2PV2
+	n.SetServices(na, services)
+	if got := n.GetServices(na); got != services {
+		t.Errorf("GetServices: got %v, want %v", got, services)
+	}
+
+	// Ensure unknown addresses have no services.
+	unknown := wire.NewNetAddressIPPort(net.ParseIP("173.194.115.67"), 8333,
+		wire.SFNodeNetwork)
+	if got := n.GetServices(unknown); got != 0 {
+		t.Errorf("GetServices: got %v for unknown address, want 0", got)
+	}
+}
+
 func TestNeedMoreAddresses(t *testing.T) {
 	n := New("testneedmoreaddresses", lookupFunc)
 	addrsToAdd := 1500
@@ -559,3 +583,92 @@ func TestCorruptPeersFile(t *testing.T) {
 		t.Fatalf("Corrupt peers file has not been removed: %s", peersFile)
 	}
 }
+
+// TestHostToNetAddressNonIP ensures Tor v3 and I2P hosts are converted to net
+// addresses on the appropriate network and back again.
+func TestHostToNetAddressNonIP(t *testing.T) {
+	const (
+		torV3Host = "pg6mmjiyjmcrsslvykfwnntlaru7p5svn6y2ymmju6nubxndf4pscryd.onion"
+		i2pHost   = "ukeu3k5oycgaauneqgtnvselmt4yemvoilkln7jpvamvfx7dnkdq.b32.i2p"
+	)
+	tests := []struct {
+		host    string
+		network wire.NetworkID
+	}{
+		{torV3Host, wire.NetworkTorV3},
+		{i2pHost, wire.NetworkI2P},
+	}
+
+	amgr := New("testhosttonetaddressnonip", lookupFunc)
+	for _, test := range tests {
+		na, err := amgr.HostToNetAddress(test.host, 9108, wire.SFNodeNetwork)
+		if err != nil {
+			t.Errorf("HostToNetAddress %s: unexpected error: %v",
+				test.host, err)
+			continue
+		}
+		if na.Network != test.network || len(na.IP) != 32 {
+			t.Errorf("HostToNetAddress %s: unexpected address - got "+
+				"network %v with %d byte address, want %v", test.host,
+				na.Network, len(na.IP), test.network)
+			continue
+		}
+		want := test.host + ":9108"
+		if key := NetAddressKey(na); key != want {
+			t.Errorf("NetAddressKey: got %s, want %s", key, want)
+		}
+	}
+
+	// Ensure Tor v3 addresses with an invalid checksum are rejected.
+	badChecksum := torV3Host[:52] + "aaad.onion"
+	_, err := amgr.HostToNetAddress(badChecksum, 9108, wire.SFNodeNetwork)
+	if err == nil {
+		t.Errorf("HostToNetAddress: unexpected success for %s",
+			badChecksum)
+	}
+}
+
+// TestSerializeNonIPAddresses ensures addresses that are not IP addresses
+// survive a round trip through the peers file.
+func TestSerializeNonIPAddresses(t *testing.T) {
+	dir, err := ioutil.TempDir("", "testserializenonipaddresses")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	torV3 := wire.NewNetAddressIPPort(make(net.IP, 32), 9108,
+		wire.SFNodeNetwork)
+	torV3.IP[0] = 0x01
+	torV3.Network = wire.NetworkTorV3
+	cjdns := wire.NewNetAddressIPPort(net.ParseIP("fc00::1"), 9108,
+		wire.SFNodeNetwork)
+	cjdns.Network = wire.NetworkCJDNS
+	src := wire.NewNetAddressIPPort(net.ParseIP("fc00::2"), 9108,
+		wire.SFNodeNetwork)
+	src.Network = wire.NetworkCJDNS
+
+	amgr := New(dir, lookupFunc)
+	amgr.AddAddresses([]*wire.NetAddress{torV3, cjdns}, src)
+	amgr.savePeers()
+
+	amgr = New(dir, lookupFunc)
+	amgr.loadPeers()
+	for _, na := range []*wire.NetAddress{torV3, cjdns} {
+		ka := amgr.find(na)
+		if ka == nil {
+			t.Errorf("address %s not found after reload",
+				NetAddressKey(na))
+			continue
+		}
+		if ka.na.Network != na.Network {
+			t.Errorf("address %s has network %v after reload, want "+
+				"%v", NetAddressKey(na), ka.na.Network, na.Network)
+		}
+		if ka.srcAddr.Network != src.Network {
+			t.Errorf("source of %s has network %v after reload, "+
+				"want %v", NetAddressKey(na), ka.srcAddr.Network,
+				src.Network)
+		}
+	}
+}
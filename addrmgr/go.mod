@@ -3,6 +3,9 @@ module github.com/decred/dcrd/addrmgr
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/decred/dcrd/chaincfg/chainhash v1.0.1
-	github.com/decred/dcrd/wire v1.2.0
+	github.com/decred/dcrd/wire v1.3.0
 	github.com/decred/slog v1.0.0
+	golang.org/x/crypto v0.0.0-20190131182504-b8fe1690c613
 )
+
+replace github.com/decred/dcrd/wire => ../wire
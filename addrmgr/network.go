@@ -118,6 +118,22 @@ func isOnionCatTor(na *wire.NetAddress) bool {
 	return onionCatNet.Contains(na.IP)
 }
 
+// isTorV3 returns whether or not the passed address is a Tor v3 onion service
+// address.
+func isTorV3(na *wire.NetAddress) bool {
+	return na.Network == wire.NetworkTorV3
+}
+
+// isI2P returns whether or not the passed address is an I2P address.
+func isI2P(na *wire.NetAddress) bool {
+	return na.Network == wire.NetworkI2P
+}
+
+// isCJDNS returns whether or not the passed address is a CJDNS address.
+func isCJDNS(na *wire.NetAddress) bool {
+	return na.Network == wire.NetworkCJDNS
+}
+
 // isRFC1918 returns whether or not the passed address is part of the IPv4
 // private network address space as defined by RFC1918 (10.0.0.0/8,
 // 172.16.0.0/12, or 192.168.0.0/16).
@@ -213,7 +229,16 @@ func isRFC6598(na *wire.NetAddress) bool {
 // considered invalid under the following circumstances:
 // IPv4: It is either a zero or all bits set address.
 // IPv6: It is either a zero or RFC3849 documentation address.
+// Tor v3 and I2P: It is not 32 bytes.
+// CJDNS: It is not 16 bytes in the fc00::/8 range.
 func isValid(na *wire.NetAddress) bool {
+	switch na.Network {
+	case wire.NetworkTorV3, wire.NetworkI2P:
+		return len(na.IP) == 32
+	case wire.NetworkCJDNS:
+		return len(na.IP) == net.IPv6len && na.IP[0] == 0xfc
+	}
+
 	// IsUnspecified returns if address is 0, so only all bits set, and
 	// RFC3849 need to be explicitly checked.
 	return na.IP != nil && !(na.IP.IsUnspecified() ||
@@ -222,8 +247,12 @@ func isValid(na *wire.NetAddress) bool {
 
 // IsRoutable returns whether or not the passed address is routable over
 // the public internet.  This is true as long as the address is valid and is not
-// in any reserved ranges.
+// in any reserved ranges.  Tor v3, I2P, and CJDNS addresses are routable over
+// their respective networks as long as they are valid.
 func IsRoutable(na *wire.NetAddress) bool {
+	if !na.IsIP() {
+		return isValid(na)
+	}
 	return isValid(na) && !(isRFC1918(na) || isRFC2544(na) ||
 		isRFC3927(na) || isRFC4862(na) || isRFC3849(na) ||
 		isRFC4843(na) || isRFC5737(na) || isRFC6598(na) ||
@@ -233,9 +262,24 @@ func IsRoutable(na *wire.NetAddress) bool {
 // GroupKey returns a string representing the network group an address is part
 // of.  This is the /16 for IPv4, the /32 (/36 for he.net) for IPv6, the string
 // "local" for a local address, the string "tor:key" where key is the /4 of the
-// onion address for Tor address, and the string "unroutable" for an unroutable
-// address.
+// onion address for Tor v2 addresses, the strings "torv3:key" and "i2p:key"
+// where key is the /4 of the public key or hash for Tor v3 and I2P addresses,
+// the string "cjdns:" followed by the /16 for CJDNS addresses, and the string
+// "unroutable" for an unroutable address.
 func GroupKey(na *wire.NetAddress) string {
+	if !na.IsIP() {
+		if !IsRoutable(na) {
+			return "unroutable"
+		}
+		switch {
+		case isTorV3(na):
+			return fmt.Sprintf("torv3:%d", na.IP[0]&((1<<4)-1))
+		case isI2P(na):
+			return fmt.Sprintf("i2p:%d", na.IP[0]&((1<<4)-1))
+		default:
+			return "cjdns:" + na.IP.Mask(net.CIDRMask(16, 128)).String()
+		}
+	}
 	if isLocal(na) {
 		return "local"
 	}
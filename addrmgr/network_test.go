@@ -200,3 +200,51 @@ func TestGroupKey(t *testing.T) {
 		}
 	}
 }
+
+// TestNonIPNetworks ensures addresses on networks that are not represented by
+// IP addresses are validated and grouped according to their network.
+func TestNonIPNetworks(t *testing.T) {
+	key32 := make(net.IP, 32)
+	key32[0] = 0xa5
+	cjdns := net.ParseIP("fc32:1234:5678::1")
+	tests := []struct {
+		name     string
+		network  wire.NetworkID
+		ip       net.IP
+		routable bool
+		expected string
+	}{
+		{name: "torv3", network: wire.NetworkTorV3, ip: key32,
+			routable: true, expected: "torv3:5"},
+		{name: "torv3 bad len", network: wire.NetworkTorV3, ip: key32[:16],
+			routable: false, expected: "unroutable"},
+		{name: "i2p", network: wire.NetworkI2P, ip: key32,
+			routable: true, expected: "i2p:5"},
+		{name: "cjdns", network: wire.NetworkCJDNS, ip: cjdns,
+			routable: true, expected: "cjdns:fc32::"},
+		{name: "cjdns outside fc00::/8", network: wire.NetworkCJDNS,
+			ip: net.ParseIP("fd00::1"), routable: false,
+			expected: "unroutable"},
+	}
+
+	for i, test := range tests {
+		na := wire.NewNetAddressIPPort(test.ip, 8333, wire.SFNodeNetwork)
+		na.Network = test.network
+		if rv := IsRoutable(na); rv != test.routable {
+			t.Errorf("IsRoutable #%d (%s): got %v want %v", i,
+				test.name, rv, test.routable)
+		}
+		if key := GroupKey(na); key != test.expected {
+			t.Errorf("TestNonIPNetworks #%d (%s): unexpected group key "+
+				"- got '%s', want '%s'", i, test.name,
+				key, test.expected)
+		}
+	}
+
+	// Ensure the same raw IPv6 address is unroutable when it is not marked
+	// as a CJDNS address.
+	na := wire.NewNetAddressIPPort(cjdns, 8333, wire.SFNodeNetwork)
+	if IsRoutable(na) {
+		t.Errorf("IsRoutable: fc00::/8 address is routable as IPv6")
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// anchorsFilename is the name of the file in the data directory that houses
+// the addresses of the block relay only peers the server was connected to when
+// it last shut down.
+const anchorsFilename = "anchors.json"
+
+// saveAnchors writes the passed anchor addresses to the anchors file in the
+// provided data directory.  Any existing anchors file is replaced.
+func saveAnchors(dataDir string, addrs []string) error {
+	serialized, err := json.Marshal(addrs)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first and rename it into place so a crash
+	// during shutdown can't leave a partially written file behind.
+	anchorsFile := filepath.Join(dataDir, anchorsFilename)
+	tmpFile := anchorsFile + ".new"
+	if err := ioutil.WriteFile(tmpFile, serialized, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, anchorsFile)
+}
+
+// loadAnchors reads the anchor addresses from the anchors file in the provided
+// data directory and removes the file so the anchors are only used for a
+// single startup.  This prevents repeatedly reconnecting to the same peers in
+// the event they are the reason the server is unable to stay running.  No
+// addresses and no error are returned when the file does not exist.
+func loadAnchors(dataDir string) ([]string, error) {
+	anchorsFile := filepath.Join(dataDir, anchorsFilename)
+	serialized, err := ioutil.ReadFile(anchorsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := os.Remove(anchorsFile); err != nil {
+		return nil, err
+	}
+
+	var addrs []string
+	if err := json.Unmarshal(serialized, &addrs); err != nil {
+		return nil, err
+	}
+	return addrs, nil
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestAnchors ensures anchor addresses round trip through the anchors file and
+// that the file is removed once loaded.
+func TestAnchors(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "anchors")
+	if err != nil {
+		t.Fatalf("TempDir: unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dataDir)
+
+	// Ensure loading without an anchors file produces no addresses.
+	addrs, err := loadAnchors(dataDir)
+	if err != nil {
+		t.Fatalf("loadAnchors: unexpected error: %v", err)
+	}
+	if len(addrs) != 0 {
+		t.Fatalf("loadAnchors: unexpected addresses %v", addrs)
+	}
+
+	want := []string{"127.0.0.1:9108", "[::1]:9108",
+		"vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyyd.onion:9108"}
+	if err := saveAnchors(dataDir, want); err != nil {
+		t.Fatalf("saveAnchors: unexpected error: %v", err)
+	}
+	addrs, err = loadAnchors(dataDir)
+	if err != nil {
+		t.Fatalf("loadAnchors: unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(addrs, want) {
+		t.Fatalf("loadAnchors: mismatched addresses - got %v, want %v",
+			addrs, want)
+	}
+
+	// Ensure the anchors are only loaded once.
+	anchorsFile := filepath.Join(dataDir, anchorsFilename)
+	if _, err := os.Stat(anchorsFile); !os.IsNotExist(err) {
+		t.Fatalf("anchors file still exists after loading: %v", err)
+	}
+}
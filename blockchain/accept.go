@@ -11,6 +11,7 @@ import (
 	"github.com/decred/dcrd/blockchain/stake"
 	"github.com/decred/dcrd/database"
 	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/wire"
 )
 
 // maybeAcceptBlock potentially accepts a block into the block chain and, if
@@ -79,14 +80,23 @@ func (b *BlockChain) maybeAcceptBlock(block *dcrutil.Block, flags BehaviorFlags)
 		return 0, err
 	}
 
-	// Create a new block node for the block and add it to the block index.
+	// Create a new block node for the block and add it to the block index
+	// unless the index already contains a node for its header, in which case
+	// the existing node is updated to reflect the block data is available.
 	// The block could either be on a side chain or the main chain, but it
 	// starts off as a side chain regardless.
-	blockHeader := &block.MsgBlock().Header
-	newNode := newBlockNode(blockHeader, prevNode)
-	newNode.populateTicketInfo(stake.FindSpentTicketsInBlock(block.MsgBlock()))
-	newNode.status = statusDataStored
-	b.index.AddNode(newNode)
+	spentTickets := stake.FindSpentTicketsInBlock(block.MsgBlock())
+	newNode := b.index.LookupNode(block.Hash())
+	if newNode != nil {
+		newNode.populateTicketInfo(spentTickets)
+		b.index.SetStatusFlags(newNode, statusDataStored)
+	} else {
+		blockHeader := &block.MsgBlock().Header
+		newNode = newBlockNode(blockHeader, prevNode)
+		newNode.populateTicketInfo(spentTickets)
+		newNode.status = statusDataStored
+		b.index.AddNode(newNode)
+	}
 
 	// Ensure the new block index entry is written to the database.
 	err = b.flushBlockIndex()
@@ -152,3 +162,68 @@ func (b *BlockChain) maybeAcceptBlock(block *dcrutil.Block, flags BehaviorFlags)
 
 	return forkLen, nil
 }
+
+// maybeAcceptBlockHeader potentially accepts the passed block header into the
+// block index without the associated block data.  It performs all of the
+// validation checks on the header which only depend on the headers of its
+// ancestors, such as the proof of work, difficulty, stake difficulty, and
+// checkpoint checks, before adding it.  The header must already be known to
+// connect to a header in the block index.
+//
+// The flags are also passed to checkBlockHeaderSanity and
+// checkBlockHeaderPositional.  See their documentation for how the flags modify
+// their behavior.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) maybeAcceptBlockHeader(header *wire.BlockHeader, flags BehaviorFlags) error {
+	// There is nothing more to do when the header is already known unless
+	// it is known to be invalid.
+	blockHash := header.BlockHash()
+	if node := b.index.LookupNode(&blockHash); node != nil {
+		if b.index.NodeStatus(node).KnownInvalid() {
+			str := fmt.Sprintf("block %s is known to be invalid",
+				blockHash)
+			return ruleError(ErrKnownInvalidBlock, str)
+		}
+		return nil
+	}
+
+	prevHash := &header.PrevBlock
+	prevNode := b.index.LookupNode(prevHash)
+	if prevNode == nil {
+		str := fmt.Sprintf("previous block %s is not known", prevHash)
+		return ruleError(ErrMissingParent, str)
+	}
+	if b.index.NodeStatus(prevNode).KnownInvalid() {
+		str := fmt.Sprintf("previous block %s is known to be invalid",
+			prevHash)
+		return ruleError(ErrInvalidAncestorBlock, str)
+	}
+
+	// The header must pass all of the context-free sanity checks and the
+	// validation rules which depend on having the headers of all ancestors
+	// available.
+	err := checkBlockHeaderSanity(header, b.timeSource, flags, b.chainParams)
+	if err != nil {
+		return err
+	}
+	err = b.checkBlockHeaderPositional(header, prevNode, flags)
+	if err != nil {
+		return err
+	}
+	if flags&BFFastAdd != BFFastAdd {
+		err := b.checkBlockHeaderStakeDifficulty(header, prevNode)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Create a new block node for the header without any block data and
+	// add it to the block index.
+	newNode := newBlockNode(header, prevNode)
+	newNode.status = statusNone
+	b.index.AddNode(newNode)
+
+	// Ensure the new block index entry is written to the database.
+	return b.flushBlockIndex()
+}
@@ -7,3 +7,143 @@ package blockchain
 
 // TODO Make benchmarking tests for various functions, such as sidechain
 // evaluation.
+
+import (
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/database"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/wire"
+)
+
+const (
+	// benchUtxoCacheSize is the maximum size of the utxo cache used in the
+	// benchmarks that make use of the cache.
+	benchUtxoCacheSize = 100 * 1024 * 1024
+
+	// benchTxnsPerBlock is the number of transactions in each block that is
+	// simulated by the utxo cache benchmarks.
+	benchTxnsPerBlock = 100
+)
+
+// benchTx returns a unique transaction with a single pay-to-pubkey-hash output
+// for the provided number.
+func benchTx(n int) *dcrutil.Tx {
+	pkScript := make([]byte, 25)
+	pkScript[0], pkScript[1], pkScript[2] = 0x76, 0xa9, 0x14
+	pkScript[23], pkScript[24] = 0x88, 0xac
+	tx := wire.NewMsgTx()
+	tx.AddTxOut(wire.NewTxOut(100000000, pkScript))
+	tx.LockTime = uint32(n)
+	return dcrutil.NewTx(tx)
+}
+
+// benchUtxoCacheSetup returns a chain instance along with a teardown function
+// the caller should invoke when done and the hashes of the passed number of
+// transactions that have their utxos stored in the database.
+func benchUtxoCacheSetup(b *testing.B, dbName string, numTxns int) (*BlockChain, func(), []chainhash.Hash) {
+	b.Helper()
+
+	chain, teardownFunc, err := chainSetup(dbName, &chaincfg.RegNetParams)
+	if err != nil {
+		b.Fatalf("Failed to setup chain instance: %v", err)
+	}
+
+	view := NewUtxoViewpoint()
+	hashes := make([]chainhash.Hash, 0, numTxns)
+	for i := 0; i < numTxns; i++ {
+		tx := benchTx(i)
+		view.AddTxOuts(tx, 1, uint32(i))
+		hashes = append(hashes, *tx.Hash())
+	}
+	err = chain.db.Update(func(dbTx database.Tx) error {
+		return dbPutUtxoView(dbTx, view)
+	})
+	if err != nil {
+		teardownFunc()
+		b.Fatalf("Failed to store utxos: %v", err)
+	}
+
+	return chain, teardownFunc, hashes
+}
+
+// benchmarkFetchUtxos benchmarks loading utxos into a view via a utxo cache of
+// the provided maximum size.
+func benchmarkFetchUtxos(b *testing.B, dbName string, maxSize uint64) {
+	chain, teardownFunc, hashes := benchUtxoCacheSetup(b, dbName,
+		benchTxnsPerBlock)
+	defer teardownFunc()
+	cache := newUtxoCache(chain.db, maxSize)
+	filteredSet := make(viewFilteredSet)
+	for _, hash := range hashes {
+		filteredSet[hash] = struct{}{}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		view := NewUtxoViewpoint()
+		if err := view.fetchUtxosMain(cache, filteredSet); err != nil {
+			b.Fatalf("Failed to fetch utxos: %v", err)
+		}
+	}
+}
+
+// BenchmarkFetchUtxosNoCache benchmarks loading utxos into a view when the
+// utxo cache is disabled and thus every lookup hits the database.
+func BenchmarkFetchUtxosNoCache(b *testing.B) {
+	benchmarkFetchUtxos(b, "benchfetchutxosnocache", 0)
+}
+
+// BenchmarkFetchUtxosCache benchmarks loading utxos into a view when they are
+// available in the utxo cache.
+func BenchmarkFetchUtxosCache(b *testing.B) {
+	benchmarkFetchUtxos(b, "benchfetchutxoscache", benchUtxoCacheSize)
+}
+
+// benchmarkConnectUtxos benchmarks storing the utxos created by simulated
+// blocks via a utxo cache of the provided maximum size the same way they are
+// stored when connecting blocks.
+func benchmarkConnectUtxos(b *testing.B, dbName string, maxSize uint64) {
+	chain, teardownFunc, _ := benchUtxoCacheSetup(b, dbName, 0)
+	defer teardownFunc()
+	cache := newUtxoCache(chain.db, maxSize)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		view := NewUtxoViewpoint()
+		for j := 0; j < benchTxnsPerBlock; j++ {
+			view.AddTxOuts(benchTx(i*benchTxnsPerBlock+j), int64(i), uint32(j))
+		}
+
+		var blockHash chainhash.Hash
+		flushUtxos := cache.needsFlush(view)
+		err := chain.db.Update(func(dbTx database.Tx) error {
+			if !flushUtxos {
+				return nil
+			}
+			return cache.dbFlush(dbTx, view, &blockHash, int64(i))
+		})
+		if err != nil {
+			b.Fatalf("Failed to store utxos: %v", err)
+		}
+		cache.commit(view)
+		if flushUtxos {
+			cache.markFlushed(int64(i))
+		}
+	}
+}
+
+// BenchmarkConnectUtxosNoCache benchmarks storing the utxos created by
+// simulated blocks when the utxo cache is disabled and thus they are written
+// to the database for every block.
+func BenchmarkConnectUtxosNoCache(b *testing.B) {
+	benchmarkConnectUtxos(b, "benchconnectutxosnocache", 0)
+}
+
+// BenchmarkConnectUtxosCache benchmarks storing the utxos created by simulated
+// blocks when they are batched in the utxo cache.
+func BenchmarkConnectUtxosCache(b *testing.B) {
+	benchmarkConnectUtxos(b, "benchconnectutxoscache", benchUtxoCacheSize)
+}
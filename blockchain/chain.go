@@ -8,6 +8,7 @@ package blockchain
 import (
 	"container/list"
 	"fmt"
+	"io"
 	"math/big"
 	"sync"
 	"time"
@@ -148,11 +149,19 @@ type BlockChain struct {
 	sigCache            *txscript.SigCache
 	indexManager        IndexManager
 	interrupt           <-chan struct{}
+	pruneTarget         uint64
+	assumeValid         chainhash.Hash
+	snapshotFile        io.ReadSeeker
+	snapshotInfo        *SnapshotInfo
 
 	// subsidyCache is the cache that provides quick lookup of subsidy
 	// values.
 	subsidyCache *SubsidyCache
 
+	// utxoCache is the write-back cache that sits between utxo viewpoints
+	// and the utxo set in the database.
+	utxoCache *utxoCache
+
 	// chainLock protects concurrent access to the vast majority of the
 	// fields in this struct below this point.
 	chainLock sync.RWMutex
@@ -162,6 +171,10 @@ type BlockChain struct {
 	noVerify      bool
 	noCheckpoints bool
 
+	// snapshot houses the state of the chain state snapshot the chain state
+	// was loaded from, if any.  It is protected by the chain lock.
+	snapshot *snapshotState
+
 	// These fields are related to the memory block index.  They both have
 	// their own locks, however they are often also protected by the chain
 	// lock to help prevent logic races when blocks are being processed.
@@ -759,6 +772,10 @@ func (b *BlockChain) connectBlock(node *blockNode, block, parent *dcrutil.Block,
 		node.stakeNode.Winners(), node.stakeNode.MissedTickets(),
 		node.stakeNode.FinalState())
 
+	// Determine if the utxo cache needs to be flushed along with the
+	// modifications made by the block.
+	flushUtxos := b.utxoCache.needsFlush(view)
+
 	// Atomically insert info into the database.
 	err = b.db.Update(func(dbTx database.Tx) error {
 		// Update best block state.
@@ -767,12 +784,16 @@ func (b *BlockChain) connectBlock(node *blockNode, block, parent *dcrutil.Block,
 			return err
 		}
 
-		// Update the utxo set using the state of the utxo view.  This
-		// entails removing all of the utxos spent and adding the new
-		// ones created by the block.
-		err = dbPutUtxoView(dbTx, view)
-		if err != nil {
-			return err
+		// Update the utxo set using the state of the utxo cache and view
+		// when the cache needs to be flushed.  This entails removing all
+		// of the utxos spent and adding the new ones created by the
+		// block.  Otherwise, the modifications are only merged into the
+		// cache below.
+		if flushUtxos {
+			err = b.utxoCache.dbFlush(dbTx, view, &node.hash, node.height)
+			if err != nil {
+				return err
+			}
 		}
 
 		// Update the transaction spend journal by adding a record for
@@ -804,8 +825,15 @@ func (b *BlockChain) connectBlock(node *blockNode, block, parent *dcrutil.Block,
 		return err
 	}
 
+	// Merge the modifications into the utxo cache now that the rest of the
+	// modifications have been committed to the database.
+	b.utxoCache.commit(view)
+	if flushUtxos {
+		b.utxoCache.markFlushed(node.height)
+	}
+
 	// Prune fully spent entries and mark all entries in the view unmodified
-	// now that the modifications have been committed to the database.
+	// now that the modifications have been committed.
 	view.commit()
 
 	// This node is now the end of the best chain.
@@ -938,10 +966,14 @@ func (b *BlockChain) disconnectBlock(node *blockNode, block, parent *dcrutil.Blo
 			return err
 		}
 
-		// Update the utxo set using the state of the utxo view.  This
-		// entails restoring all of the utxos spent and removing the new
-		// ones created by the block.
-		err = dbPutUtxoView(dbTx, view)
+		// Update the utxo set using the state of the utxo cache and view.
+		// This entails restoring all of the utxos spent and removing the
+		// new ones created by the block.  The cache is always flushed
+		// when disconnecting blocks so the utxo set in the database is
+		// never consistent with a block that is no longer in the main
+		// chain.
+		err = b.utxoCache.dbFlush(dbTx, view, &prevNode.hash,
+			prevNode.height)
 		if err != nil {
 			return err
 		}
@@ -975,6 +1007,11 @@ func (b *BlockChain) disconnectBlock(node *blockNode, block, parent *dcrutil.Blo
 		return err
 	}
 
+	// Merge the modifications into the utxo cache now that they have been
+	// committed to the database.
+	b.utxoCache.commit(view)
+	b.utxoCache.markFlushed(prevNode.height)
+
 	// Prune fully spent entries and mark all entries in the view unmodified
 	// now that the modifications have been committed to the database.
 	view.commit()
@@ -1137,7 +1174,7 @@ func (b *BlockChain) reorganizeChainInternal(targetTip *blockNode) error {
 		// Update the view to unspend all of the spent txos and remove the utxos
 		// created by the block.  Also, if the block votes against its parent,
 		// reconnect all of the regular transactions.
-		err = view.disconnectBlock(b.db, block, parent, stxos)
+		err = view.disconnectBlock(b.utxoCache, block, parent, stxos)
 		if err != nil {
 			return err
 		}
@@ -1200,7 +1237,7 @@ func (b *BlockChain) reorganizeChainInternal(targetTip *blockNode) error {
 			// In the case the block votes against the parent, also disconnect
 			// all of the regular transactions in the parent block.  Finally,
 			// provide an stxo slice so the spent txout details are generated.
-			err := view.connectBlock(b.db, block, parent, &stxos)
+			err := view.connectBlock(b.utxoCache, block, parent, &stxos)
 			if err != nil {
 				return err
 			}
@@ -1379,6 +1416,11 @@ func (b *BlockChain) ForceHeadReorganization(formerBest chainhash.Hash, newBest
 func (b *BlockChain) flushBlockIndex() error {
 	b.index.RLock()
 	for node := range b.index.modified {
+		// The ticket data of nodes without the block data available,
+		// such as those for headers only, can't be loaded.
+		if !node.status.HaveData() {
+			continue
+		}
 		if err := b.maybeFetchTicketInfo(node); err != nil {
 			b.index.RUnlock()
 			return err
@@ -1474,7 +1516,7 @@ func (b *BlockChain) connectBestChain(node *blockNode, block, parent *dcrutil.Bl
 		// the parent, its regular transaction tree must be
 		// disconnected.
 		if fastAdd {
-			err := view.connectBlock(b.db, block, parent, &stxos)
+			err := view.connectBlock(b.utxoCache, block, parent, &stxos)
 			if err != nil {
 				return 0, err
 			}
@@ -1642,6 +1684,58 @@ func (b *BlockChain) MaxBlockSize() (int64, error) {
 	return maxSize, err
 }
 
+// bestHeaderNode returns the node for the known block header with the most
+// cumulative proof of work that is not known to be invalid.  The tip of the
+// main chain is returned when no header has more work.
+//
+// This function MUST be called with the block index lock held (for reads).
+func (b *BlockChain) bestHeaderNode() *blockNode {
+	bestHeader := b.bestChain.Tip()
+	for _, nodes := range b.index.chainTips {
+		for _, node := range nodes {
+			if node.status.KnownInvalid() ||
+				node.workSum.Cmp(bestHeader.workSum) <= 0 {
+
+				continue
+			}
+			bestHeader = node
+		}
+	}
+	return bestHeader
+}
+
+// BestHeader returns the hash and height of the known block header with the
+// most cumulative proof of work that is not known to be invalid.  Unlike the
+// tip of the main chain, the header may not have its block data available,
+// such as when only headers are downloaded.  The tip of the main chain is
+// returned when no header has more work.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) BestHeader() (chainhash.Hash, int64) {
+	b.index.RLock()
+	bestHeader := b.bestHeaderNode()
+	b.index.RUnlock()
+	return bestHeader.hash, bestHeader.height
+}
+
+// BestHeaderHashByHeight returns the hash of the block header at the given
+// height in the chain of headers that ends with the best header as returned by
+// BestHeader.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) BestHeaderHashByHeight(height int64) (*chainhash.Hash, error) {
+	b.index.RLock()
+	node := b.bestHeaderNode().Ancestor(height)
+	b.index.RUnlock()
+	if node == nil {
+		str := fmt.Sprintf("no block header at height %d exists in the "+
+			"best header chain", height)
+		return nil, errNotInMainChain(str)
+	}
+
+	return &node.hash, nil
+}
+
 // HeaderByHash returns the block header identified by the given hash or an
 // error if it doesn't exist.  Note that this will return headers from both the
 // main chain and any side chains.
@@ -2061,6 +2155,43 @@ type Config struct {
 	// This field can be nil if the caller does not wish to make use of an
 	// index manager.
 	IndexManager IndexManager
+
+	// PruneTarget is the target size, in bytes, of the stored block data.
+	// The oldest block data is periodically deleted, as needed, to stay near
+	// the target.  The block headers, the utxo set, the stake database, and
+	// the block data and spend journal entries for the most recent
+	// MinRetainedBlocks blocks are always retained.  The database must
+	// implement the database.BlockPruner interface when this is set.
+	//
+	// This field can be zero to disable pruning.
+	PruneTarget uint64
+
+	// UtxoCacheMaxSize is the maximum size, in bytes, of the in-memory cache
+	// of unspent transaction outputs.  Modified utxos are kept in the cache
+	// and written to the database in batches.
+	//
+	// This field can be zero to write all modified utxos to the database as
+	// soon as each block is connected or disconnected.
+	UtxoCacheMaxSize uint64
+
+	// AssumeValid is the hash of a block that is assumed to be valid.  The
+	// scripts of the block and all of its ancestors are not validated when
+	// it is part of the best known header chain, although all other checks
+	// are still performed.  Blocks are fully validated when the block is not
+	// known or is not part of the best header chain.
+	//
+	// This field can be the zero hash to validate the scripts of all blocks.
+	// Callers will typically set it to the value in the chain parameters.
+	AssumeValid chainhash.Hash
+
+	// Snapshot is a chain state snapshot previously created with
+	// DumpSnapshot to load the chain state from.  The snapshot is only
+	// loaded when the database does not contain a chain yet, and its
+	// commitment hash must be listed in the chain parameters.  The history
+	// leading up to the snapshot is not validated by the chain.
+	//
+	// This field can be nil to initialize the chain from the genesis block.
+	Snapshot io.ReadSeeker
 }
 
 // New returns a BlockChain instance using the provided configuration details.
@@ -2072,6 +2203,12 @@ func New(config *Config) (*BlockChain, error) {
 	if config.ChainParams == nil {
 		return nil, AssertError("blockchain.New chain parameters nil")
 	}
+	if config.PruneTarget != 0 {
+		if _, ok := config.DB.(database.BlockPruner); !ok {
+			return nil, AssertError("blockchain.New database does not " +
+				"support pruning")
+		}
+	}
 
 	// Generate a checkpoint by height map from the provided checkpoints.
 	params := config.ChainParams
@@ -2100,7 +2237,10 @@ func New(config *Config) (*BlockChain, error) {
 		sigCache:                      config.SigCache,
 		indexManager:                  config.IndexManager,
 		interrupt:                     config.Interrupt,
+		pruneTarget:                   config.PruneTarget,
+		assumeValid:                   config.AssumeValid,
 		index:                         newBlockIndex(config.DB, params),
+		utxoCache:                     newUtxoCache(config.DB, config.UtxoCacheMaxSize),
 		bestChain:                     newChainView(nil),
 		orphans:                       make(map[chainhash.Hash]*orphanBlock),
 		prevOrphans:                   make(map[chainhash.Hash][]*orphanBlock),
@@ -2114,6 +2254,16 @@ func New(config *Config) (*BlockChain, error) {
 		calcStakeVersionCache:         make(map[[chainhash.HashSize]byte]uint32),
 	}
 
+	// Ensure the chain state snapshot to load, if any, is known to be valid.
+	if config.Snapshot != nil {
+		info, err := readSnapshotInfo(config.Snapshot, params)
+		if err != nil {
+			return nil, err
+		}
+		b.snapshotFile = config.Snapshot
+		b.snapshotInfo = info
+	}
+
 	// Initialize the chain state from the passed database.  When the db
 	// does not yet contain any chain state, both it and the chain state
 	// will be initialized to contain only the genesis block.
@@ -2121,6 +2271,13 @@ func New(config *Config) (*BlockChain, error) {
 		return nil, err
 	}
 
+	// Bring the utxo set up to date with the best chain state as needed.
+	// This is necessary when the utxo cache was not flushed prior to the
+	// last shutdown.
+	if err := b.initUtxoCache(); err != nil {
+		return nil, err
+	}
+
 	// Initialize and catch up all of the currently active optional indexes
 	// as needed.
 	if config.IndexManager != nil {
@@ -1163,8 +1163,16 @@ func dbFetchUtxoEntry(dbTx database.Tx, hash *chainhash.Hash) (*UtxoEntry, error
 // particular, only the entries that have been marked as modified are written
 // to the database.
 func dbPutUtxoView(dbTx database.Tx, view *UtxoViewpoint) error {
+	return dbPutUtxoEntries(dbTx, view.entries)
+}
+
+// dbPutUtxoEntries uses an existing database transaction to update the utxo
+// set in the database based on the provided utxo entries.  Only the entries
+// that have been marked as modified are written to the database and those that
+// are now fully spent are removed from it.
+func dbPutUtxoEntries(dbTx database.Tx, entries map[chainhash.Hash]*UtxoEntry) error {
 	utxoBucket := dbTx.Metadata().Bucket(dbnamespace.UtxoSetBucketName)
-	for txHashIter, entry := range view.entries {
+	for txHashIter, entry := range entries {
 		// No need to update the database if the entry was not modified.
 		if entry == nil || !entry.modified {
 			continue
@@ -1436,6 +1444,171 @@ func dbPutBestState(dbTx database.Tx, snapshot *BestState, workSum *big.Int) err
 	return dbTx.Metadata().Put(dbnamespace.ChainStateKeyName, serializedData)
 }
 
+// -----------------------------------------------------------------------------
+// The utxo set state identifies the block the utxo set in the database is
+// consistent with.  Since modified utxos are cached in memory and only
+// periodically flushed to the database, it may lag behind the best chain state
+// and is updated every time the utxo cache is flushed.
+//
+// The serialized format is:
+//
+//   <block hash><block height>
+//
+//   Field             Type             Size
+//   block hash        chainhash.Hash   chainhash.HashSize
+//   block height      uint32           4 bytes
+// -----------------------------------------------------------------------------
+
+// utxoSetState represents the data to be stored in the database for the block
+// the utxo set is consistent with.
+type utxoSetState struct {
+	hash   chainhash.Hash
+	height uint32
+}
+
+// serializeUtxoSetState returns the serialization of the passed utxo set
+// state.  This is data to be stored in the chain state bucket.
+func serializeUtxoSetState(state *utxoSetState) []byte {
+	serializedData := make([]byte, chainhash.HashSize+4)
+	copy(serializedData[0:chainhash.HashSize], state.hash[:])
+	dbnamespace.ByteOrder.PutUint32(serializedData[chainhash.HashSize:],
+		state.height)
+	return serializedData
+}
+
+// deserializeUtxoSetState deserializes the passed serialized utxo set state.
+func deserializeUtxoSetState(serializedData []byte) (*utxoSetState, error) {
+	if len(serializedData) != chainhash.HashSize+4 {
+		return nil, database.Error{
+			ErrorCode: database.ErrCorruption,
+			Description: fmt.Sprintf("corrupt utxo set state size; want %v "+
+				"got %v", chainhash.HashSize+4, len(serializedData)),
+		}
+	}
+
+	var state utxoSetState
+	copy(state.hash[:], serializedData[0:chainhash.HashSize])
+	state.height = dbnamespace.ByteOrder.Uint32(
+		serializedData[chainhash.HashSize:])
+	return &state, nil
+}
+
+// dbPutUtxoSetState uses an existing database transaction to update the utxo
+// set state with the given parameters.
+func dbPutUtxoSetState(dbTx database.Tx, state *utxoSetState) error {
+	return dbTx.Metadata().Put(dbnamespace.UtxoSetStateKeyName,
+		serializeUtxoSetState(state))
+}
+
+// dbFetchUtxoSetState uses an existing database transaction to fetch the utxo
+// set state.  Both the state and the error will be nil when it does not exist.
+func dbFetchUtxoSetState(dbTx database.Tx) (*utxoSetState, error) {
+	serializedData := dbTx.Metadata().Get(dbnamespace.UtxoSetStateKeyName)
+	if serializedData == nil {
+		return nil, nil
+	}
+	return deserializeUtxoSetState(serializedData)
+}
+
+// -----------------------------------------------------------------------------
+// The snapshot state consists of the height and hash of the block a chain state
+// snapshot was created at, the commitment hash of the snapshot, and flags that
+// indicate whether the snapshot was completely loaded into the database and
+// whether the history leading up to it has been validated.  It only exists in
+// databases that were initialized from a snapshot.
+//
+// The serialized format is:
+//
+//   <block height><block hash><commitment hash><flags>
+//
+//   Field             Type             Size
+//   block height      uint32           4 bytes
+//   block hash        chainhash.Hash   chainhash.HashSize
+//   commitment hash   chainhash.Hash   chainhash.HashSize
+//   flags             byte             1 byte
+// -----------------------------------------------------------------------------
+
+const (
+	// snapshotStateSize is the serialized size of the snapshot state.
+	snapshotStateSize = 4 + 2*chainhash.HashSize + 1
+
+	// snapshotFlagLoaded indicates the snapshot was completely loaded into
+	// the database.
+	snapshotFlagLoaded = 1 << 0
+
+	// snapshotFlagValidated indicates the history leading up to the
+	// snapshot has been validated.
+	snapshotFlagValidated = 1 << 1
+)
+
+// snapshotState represents the data to be stored in the database for the
+// snapshot the chain state was loaded from.
+type snapshotState struct {
+	info      SnapshotInfo
+	loaded    bool
+	validated bool
+}
+
+// serializeSnapshotState returns the serialization of the passed snapshot
+// state.  This is data to be stored in the chain state bucket.
+func serializeSnapshotState(state *snapshotState) []byte {
+	serializedData := make([]byte, snapshotStateSize)
+	dbnamespace.ByteOrder.PutUint32(serializedData[0:4],
+		uint32(state.info.Height))
+	offset := 4
+	copy(serializedData[offset:], state.info.Hash[:])
+	offset += chainhash.HashSize
+	copy(serializedData[offset:], state.info.Commitment[:])
+	offset += chainhash.HashSize
+	if state.loaded {
+		serializedData[offset] |= snapshotFlagLoaded
+	}
+	if state.validated {
+		serializedData[offset] |= snapshotFlagValidated
+	}
+	return serializedData
+}
+
+// deserializeSnapshotState deserializes the passed serialized snapshot state.
+func deserializeSnapshotState(serializedData []byte) (*snapshotState, error) {
+	if len(serializedData) != snapshotStateSize {
+		return nil, database.Error{
+			ErrorCode: database.ErrCorruption,
+			Description: fmt.Sprintf("corrupt snapshot state size; want %v "+
+				"got %v", snapshotStateSize, len(serializedData)),
+		}
+	}
+
+	var state snapshotState
+	state.info.Height = int64(dbnamespace.ByteOrder.Uint32(serializedData[0:4]))
+	offset := 4
+	copy(state.info.Hash[:], serializedData[offset:])
+	offset += chainhash.HashSize
+	copy(state.info.Commitment[:], serializedData[offset:])
+	offset += chainhash.HashSize
+	state.loaded = serializedData[offset]&snapshotFlagLoaded != 0
+	state.validated = serializedData[offset]&snapshotFlagValidated != 0
+	return &state, nil
+}
+
+// dbPutSnapshotState uses an existing database transaction to update the
+// snapshot state with the given parameters.
+func dbPutSnapshotState(dbTx database.Tx, state *snapshotState) error {
+	return dbTx.Metadata().Put(dbnamespace.SnapshotStateKeyName,
+		serializeSnapshotState(state))
+}
+
+// dbFetchSnapshotState uses an existing database transaction to fetch the
+// snapshot state.  Both the state and the error will be nil when it does not
+// exist.
+func dbFetchSnapshotState(dbTx database.Tx) (*snapshotState, error) {
+	serializedData := dbTx.Metadata().Get(dbnamespace.SnapshotStateKeyName)
+	if serializedData == nil {
+		return nil, nil
+	}
+	return deserializeSnapshotState(serializedData)
+}
+
 // createChainState initializes both the database and the chain state to the
 // genesis block.  This includes creating the necessary buckets and inserting
 // the genesis block, so it must only be called on an uninitialized database.
@@ -1622,6 +1795,11 @@ func (b *BlockChain) initChainState() error {
 		return err
 	}
 
+	// Load the chain state from the configured snapshot as needed.
+	if err := b.maybeLoadSnapshot(); err != nil {
+		return err
+	}
+
 	// Attempt to load the chain state from the database.
 	err = b.db.View(func(dbTx database.Tx) error {
 		// Fetch the stored chain state from the database metadata.
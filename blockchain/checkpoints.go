@@ -72,6 +72,31 @@ func (b *BlockChain) LatestCheckpoint() *chaincfg.Checkpoint {
 	return checkpoint
 }
 
+// isAssumedValid returns whether or not the passed node is the block that is
+// assumed to be valid or one of its ancestors.  This is never the case when the
+// assumed valid block is not known or is not part of the best known header
+// chain, such as when it has been reorganized away, so blocks are fully
+// validated in those cases.
+//
+// This function MUST be called with the chain lock held (for reads).
+func (b *BlockChain) isAssumedValid(node *blockNode) bool {
+	if b.assumeValid == *zeroHash {
+		return false
+	}
+
+	b.index.RLock()
+	defer b.index.RUnlock()
+	assumeValidNode := b.index.lookupNode(&b.assumeValid)
+	if assumeValidNode == nil || assumeValidNode.status.KnownInvalid() {
+		return false
+	}
+	bestHeader := b.bestHeaderNode()
+	if bestHeader.Ancestor(assumeValidNode.height) != assumeValidNode {
+		return false
+	}
+	return assumeValidNode.Ancestor(node.height) == node
+}
+
 // verifyCheckpoint returns whether the passed block height and hash combination
 // match the hard-coded checkpoint data.  It also returns true if there is no
 // checkpoint data for the passed block height.
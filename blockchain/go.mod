@@ -2,11 +2,12 @@ module github.com/decred/dcrd/blockchain
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/dchest/blake256 v1.0.0
 	github.com/dchest/siphash v1.2.1 // indirect
-	github.com/decred/dcrd/blockchain/stake v1.1.0
-	github.com/decred/dcrd/chaincfg v1.3.0
+	github.com/decred/dcrd/blockchain/stake v1.2.0
+	github.com/decred/dcrd/chaincfg v1.4.0
 	github.com/decred/dcrd/chaincfg/chainhash v1.0.1
-	github.com/decred/dcrd/database v1.0.3
+	github.com/decred/dcrd/database v1.1.0
 	github.com/decred/dcrd/dcrec v0.0.0-20190130161649-59ed4247a1d5
 	github.com/decred/dcrd/dcrec/edwards v0.0.0-20190130161649-59ed4247a1d5 // indirect
 	github.com/decred/dcrd/dcrec/secp256k1 v1.0.1
@@ -23,3 +24,9 @@ require (
 	golang.org/x/sys v0.0.0-20190203050204-7ae0202eb74c // indirect
 	gopkg.in/yaml.v2 v2.2.2 // indirect
 )
+
+replace (
+	github.com/decred/dcrd/blockchain/stake => ./stake
+	github.com/decred/dcrd/chaincfg => ../chaincfg
+	github.com/decred/dcrd/database => ../database
+)
@@ -40,6 +40,14 @@ var (
 	// chain state.
 	ChainStateKeyName = []byte("chainstate")
 
+	// UtxoSetStateKeyName is the name of the db key used to store the block
+	// the utxo set in the database is consistent with.
+	UtxoSetStateKeyName = []byte("utxosetstate")
+
+	// SnapshotStateKeyName is the name of the db key used to store the
+	// state of the chain state snapshot the database was loaded from.
+	SnapshotStateKeyName = []byte("snapshotstate")
+
 	// SpendJournalBucketName is the name of the db bucket used to house
 	// transactions outputs that are spent in each block.
 	SpendJournalBucketName = []byte("spendjournal")
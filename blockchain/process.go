@@ -11,6 +11,7 @@ import (
 
 	"github.com/decred/dcrd/chaincfg/chainhash"
 	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/wire"
 )
 
 // BehaviorFlags is a bitmask defining tweaks to the normal behavior when
@@ -210,3 +211,21 @@ func (b *BlockChain) ProcessBlock(block *dcrutil.Block, flags BehaviorFlags) (in
 
 	return forkLen, false, nil
 }
+
+// ProcessBlockHeader is the main workhorse for handling insertion of new block
+// headers into the block index without their block data.  It includes
+// functionality such as rejecting duplicate and invalid headers and ensuring
+// headers connect to a known header and follow all of the rules that only
+// depend on the headers of their ancestors, such as proof of work, difficulty,
+// stake difficulty, and checkpoints.
+//
+// Headers that are already known are not an error unless they are known to be
+// invalid.  The best known header is available via BestHeader.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) ProcessBlockHeader(header *wire.BlockHeader, flags BehaviorFlags) error {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	return b.maybeAcceptBlockHeader(header, flags)
+}
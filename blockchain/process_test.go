@@ -0,0 +1,253 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+)
+
+// TestProcessBlockHeader ensures processing block headers without their block
+// data works as expected including rejecting headers that do not connect or
+// are invalid and later accepting the full blocks for the headers.
+func TestProcessBlockHeader(t *testing.T) {
+	// Create a test harness initialized with the genesis block as the tip and
+	// generate enough blocks to reach stake validation height.
+	params := &chaincfg.RegNetParams
+	g, teardownFunc := newChaingenHarness(t, params, "processheadertest")
+	defer teardownFunc()
+	g.AdvanceToStakeValidationHeight()
+
+	// Create a separate chain instance that only processes the headers.
+	hc, hcTeardownFunc, err := chainSetup("processheadertesthc", params)
+	if err != nil {
+		t.Fatalf("Failed to setup chain instance: %v", err)
+	}
+	defer hcTeardownFunc()
+
+	// Ensure all of the headers are accepted.
+	tip := g.chain.BestSnapshot()
+	for height := int64(1); height <= tip.Height; height++ {
+		header, err := g.chain.HeaderByHeight(height)
+		if err != nil {
+			t.Fatalf("failed to fetch header at height %d: %v", height,
+				err)
+		}
+		if err := hc.ProcessBlockHeader(&header, BFNone); err != nil {
+			t.Fatalf("failed to process header at height %d: %v",
+				height, err)
+		}
+	}
+
+	// Ensure the best header is the tip of the full chain while the main
+	// chain remains at the genesis block since no block data is available.
+	bestHash, bestHeight := hc.BestHeader()
+	if bestHash != tip.Hash || bestHeight != tip.Height {
+		t.Fatalf("unexpected best header -- got %s (height %d), want %s "+
+			"(height %d)", bestHash, bestHeight, tip.Hash, tip.Height)
+	}
+	if hcTip := hc.BestSnapshot(); hcTip.Height != 0 {
+		t.Fatalf("unexpected main chain height -- got %d, want 0",
+			hcTip.Height)
+	}
+	wantHash, err := g.chain.BlockHashByHeight(1)
+	if err != nil {
+		t.Fatalf("failed to fetch block hash at height 1: %v", err)
+	}
+	gotHash, err := hc.BestHeaderHashByHeight(1)
+	if err != nil {
+		t.Fatalf("failed to fetch best header hash at height 1: %v", err)
+	}
+	if *gotHash != *wantHash {
+		t.Fatalf("unexpected best header hash at height 1 -- got %v, "+
+			"want %v", gotHash, wantHash)
+	}
+
+	// Ensure processing a header that is already known is not an error.
+	tipHeader, err := g.chain.HeaderByHeight(tip.Height)
+	if err != nil {
+		t.Fatalf("failed to fetch tip header: %v", err)
+	}
+	if err := hc.ProcessBlockHeader(&tipHeader, BFNone); err != nil {
+		t.Fatalf("failed to process duplicate header: %v", err)
+	}
+
+	// expectRuleError ensures the provided error is a rule error with the
+	// given error code.
+	expectRuleError := func(err error, code ErrorCode) {
+		t.Helper()
+
+		rerr, ok := err.(RuleError)
+		if !ok {
+			t.Fatalf("unexpected error type -- got %T (%v), want "+
+				"blockchain.RuleError", err, err)
+		}
+		if rerr.ErrorCode != code {
+			t.Fatalf("unexpected error code -- got %v, want %v",
+				rerr.ErrorCode, code)
+		}
+	}
+
+	// Ensure a header that does not connect to a known header is rejected.
+	orphanHeader := tipHeader
+	orphanHeader.PrevBlock = chainhash.Hash{0x01}
+	err = hc.ProcessBlockHeader(&orphanHeader, BFNoPoWCheck)
+	expectRuleError(err, ErrMissingParent)
+
+	// Ensure a header that builds on the tip with an invalid stake
+	// difficulty is rejected.
+	tipNode := hc.index.LookupNode(&tip.Hash)
+	badHeader := tipHeader
+	badHeader.PrevBlock = tip.Hash
+	badHeader.Height++
+	badHeader.Timestamp = tipHeader.Timestamp.Add(time.Second)
+	badHeader.Bits, err = hc.calcNextRequiredDifficulty(tipNode,
+		badHeader.Timestamp)
+	if err != nil {
+		t.Fatalf("failed to calculate difficulty: %v", err)
+	}
+	badHeader.SBits = tipHeader.SBits + 1
+	for {
+		sdiffV1, err := hc.calcNextRequiredStakeDifficultyV1(tipNode)
+		if err != nil {
+			t.Fatalf("failed to calculate stake difficulty: %v", err)
+		}
+		sdiffV2, err := hc.calcNextRequiredStakeDifficultyV2(tipNode)
+		if err != nil {
+			t.Fatalf("failed to calculate stake difficulty: %v", err)
+		}
+		if badHeader.SBits != sdiffV1 && badHeader.SBits != sdiffV2 {
+			break
+		}
+		badHeader.SBits++
+	}
+	err = hc.ProcessBlockHeader(&badHeader, BFNoPoWCheck)
+	expectRuleError(err, ErrUnexpectedDifficulty)
+
+	// Ensure the full blocks are accepted to the main chain and that the
+	// existing block index entries for the headers are used for them.
+	numNodes := len(hc.index.index)
+	for height := int64(1); height <= tip.Height; height++ {
+		block, err := g.chain.BlockByHeight(height)
+		if err != nil {
+			t.Fatalf("failed to fetch block at height %d: %v", height,
+				err)
+		}
+		_, isOrphan, err := hc.ProcessBlock(block, BFNone)
+		if err != nil {
+			t.Fatalf("failed to process block at height %d: %v",
+				height, err)
+		}
+		if isOrphan {
+			t.Fatalf("block at height %d was unexpectedly an orphan",
+				height)
+		}
+	}
+	if hcTip := hc.BestSnapshot(); hcTip.Hash != tip.Hash {
+		t.Fatalf("unexpected main chain tip -- got %s, want %s",
+			hcTip.Hash, tip.Hash)
+	}
+	if len(hc.index.index) != numNodes {
+		t.Fatalf("unexpected number of block index entries -- got %d, "+
+			"want %d", len(hc.index.index), numNodes)
+	}
+}
+
+// TestAssumeValid ensures blocks are only assumed to have valid scripts when
+// they are ancestors of the assumed valid block and that block is part of the
+// best known header chain.
+func TestAssumeValid(t *testing.T) {
+	// Create a test harness initialized with the genesis block as the tip and
+	// generate enough blocks to reach stake validation height.
+	params := &chaincfg.RegNetParams
+	g, teardownFunc := newChaingenHarness(t, params, "assumevalidtest")
+	defer teardownFunc()
+	g.AdvanceToStakeValidationHeight()
+	tip := g.chain.BestSnapshot()
+
+	// Create a separate chain instance that assumes the tip of the full chain
+	// is valid.
+	hc, hcTeardownFunc, err := chainSetup("assumevalidtesthc", params)
+	if err != nil {
+		t.Fatalf("Failed to setup chain instance: %v", err)
+	}
+	defer hcTeardownFunc()
+	hc.assumeValid = tip.Hash
+
+	// Ensure blocks are not assumed valid when the assumed valid block is not
+	// known.
+	genesis := hc.bestChain.Tip()
+	if hc.isAssumedValid(genesis) {
+		t.Fatal("block assumed valid when the assumed valid block is unknown")
+	}
+
+	// Ensure the ancestors of the assumed valid block are assumed valid once
+	// its header is known.
+	for height := int64(1); height <= tip.Height; height++ {
+		header, err := g.chain.HeaderByHeight(height)
+		if err != nil {
+			t.Fatalf("failed to fetch header at height %d: %v", height,
+				err)
+		}
+		if err := hc.ProcessBlockHeader(&header, BFNone); err != nil {
+			t.Fatalf("failed to process header at height %d: %v",
+				height, err)
+		}
+	}
+	for height := int64(0); height <= tip.Height; height++ {
+		hash, err := hc.BestHeaderHashByHeight(height)
+		if err != nil {
+			t.Fatalf("failed to fetch best header hash at height %d: %v",
+				height, err)
+		}
+		node := hc.index.LookupNode(hash)
+		if !hc.isAssumedValid(node) {
+			t.Fatalf("block at height %d is not assumed valid", height)
+		}
+	}
+
+	// Ensure the full blocks are accepted to the main chain when their scripts
+	// are assumed valid.
+	for height := int64(1); height <= tip.Height; height++ {
+		block, err := g.chain.BlockByHeight(height)
+		if err != nil {
+			t.Fatalf("failed to fetch block at height %d: %v", height,
+				err)
+		}
+		if _, _, err := hc.ProcessBlock(block, BFNone); err != nil {
+			t.Fatalf("failed to process block at height %d: %v",
+				height, err)
+		}
+	}
+	if hcTip := hc.BestSnapshot(); hcTip.Hash != tip.Hash {
+		t.Fatalf("unexpected main chain tip -- got %s, want %s",
+			hcTip.Hash, tip.Hash)
+	}
+
+	// Create a block that extends the main chain along with a side chain
+	// block and assume the side chain block is valid.
+	//
+	//   ... -> bsv# -> b1
+	//              \-> b1a
+	outs := g.OldestCoinbaseOuts()
+	svhTipName := g.TipName()
+	g.NextBlock("b1", nil, outs[1:])
+	g.Accepted()
+	g.SetTip(svhTipName)
+	g.NextBlock("b1a", nil, outs[1:])
+	g.AcceptedToSideChainWithExpectedTip("b1")
+	g.chain.assumeValid = g.Tip().BlockHash()
+
+	// Ensure the ancestors of the side chain block are not assumed valid since
+	// it is not part of the best header chain.
+	node := g.chain.index.LookupNode(&tip.Hash)
+	if g.chain.isAssumedValid(node) {
+		t.Fatal("block assumed valid when the assumed valid block is not " +
+			"part of the best header chain")
+	}
+}
@@ -2,8 +2,17 @@ package blockchain
 
 import (
 	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/database"
 )
 
+// MinRetainedBlocks is the minimum number of the most recent blocks for which
+// the block data and spend journal entries are retained when pruning block
+// data.  They are required in order to disconnect blocks during a
+// reorganization.
+const MinRetainedBlocks = 288
+
 // pruningIntervalInMinutes is the interval in which to prune the blockchain's
 // nodes and restore memory to the garbage collector.
 const pruningIntervalInMinutes = 5
@@ -36,4 +45,84 @@ func (c *chainPruner) pruneChainIfNeeded() {
 
 	c.lastNodeInsertTime = now
 	c.chain.pruneStakeNodes()
+	if err := c.chain.pruneBlockData(); err != nil {
+		log.Warnf("Unable to prune block data: %v", err)
+	}
+}
+
+// pruneBlockData deletes the oldest block data, as needed, to reduce the size
+// of the stored block data to the configured prune target.  The data for the
+// most recent MinRetainedBlocks blocks is never deleted.  The spend journal
+// entries for the pruned blocks are removed as well since they are only useful
+// with the block data.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) pruneBlockData() error {
+	if b.pruneTarget == 0 {
+		return nil
+	}
+
+	// The block the utxo set in the database is consistent with and all
+	// blocks after it are also retained, along with their spend journal
+	// entries, since they are needed to bring the utxo set up to date after
+	// an unclean shutdown.
+	pruneHeight := b.bestChain.Tip().height - MinRetainedBlocks
+	flushedHeight := b.utxoCache.lastFlushedHeight()
+	if pruneHeight >= flushedHeight {
+		pruneHeight = flushedHeight - 1
+	}
+	if pruneHeight <= 0 {
+		return nil
+	}
+
+	pruner, ok := b.db.(database.BlockPruner)
+	if !ok {
+		return nil
+	}
+
+	// Mark the blocks as no longer having their block data stored and
+	// remove their spend journal entries in the same database transaction
+	// that removes the blocks, which is committed before the block data is
+	// deleted.  That way an interruption never results in the block index
+	// claiming block data is stored when it is not.
+	canPrune := func(hash *chainhash.Hash) bool {
+		node := b.index.LookupNode(hash)
+		return node == nil || node.height <= pruneHeight
+	}
+	prepare := func(dbTx database.Tx, pruned []chainhash.Hash) error {
+		for i := range pruned {
+			node := b.index.LookupNode(&pruned[i])
+			if node != nil {
+				prunedNode := *node
+				prunedNode.status &^= statusDataStored
+				if err := dbPutBlockNode(dbTx, &prunedNode); err != nil {
+					return err
+				}
+			}
+			err := dbRemoveSpendJournalEntry(dbTx, &pruned[i])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	pruned, pruneErr := pruner.PruneBlocks(b.pruneTarget, canPrune, prepare)
+	if len(pruned) == 0 {
+		return pruneErr
+	}
+
+	// Update the in-memory state to match the database.
+	b.mainchainBlockCacheLock.Lock()
+	for i := range pruned {
+		delete(b.mainchainBlockCache, pruned[i])
+	}
+	b.mainchainBlockCacheLock.Unlock()
+	for i := range pruned {
+		if node := b.index.LookupNode(&pruned[i]); node != nil {
+			b.index.UnsetStatusFlags(node, statusDataStored)
+		}
+	}
+
+	log.Infof("Pruned the block data for %d blocks", len(pruned))
+	return pruneErr
 }
@@ -0,0 +1,779 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/dchest/blake256"
+	"github.com/decred/dcrd/blockchain/internal/dbnamespace"
+	"github.com/decred/dcrd/blockchain/stake"
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/database"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/wire"
+)
+
+const (
+	// snapshotVersion is the current version of the chain state snapshot
+	// format.
+	snapshotVersion = 1
+
+	// snapshotHeaderSize is the size of the header of a chain state snapshot.
+	snapshotHeaderSize = 12 + chainhash.HashSize
+
+	// maxSnapshotKeySize is the maximum allowed size of the key of a record
+	// in a chain state snapshot.
+	maxSnapshotKeySize = 64
+
+	// maxSnapshotValueSize is the maximum allowed size of the value of a
+	// record in a chain state snapshot.
+	maxSnapshotValueSize = 32 * 1024 * 1024
+
+	// snapshotBatchSize is the approximate number of bytes of records that
+	// are written to the database in each database transaction when loading
+	// a chain state snapshot.
+	snapshotBatchSize = 32 * 1024 * 1024
+)
+
+// -----------------------------------------------------------------------------
+// A chain state snapshot contains everything needed to continue validating the
+// chain from the block it was created at without the history leading up to it.
+// That is the utxo set, the ticket database state, and the block index entries
+// of the main chain, along with the block data and spend journal entries of the
+// most recent MinRetainedBlocks blocks so that the chain can be reorganized the
+// same way as when the block data is pruned.
+//
+// The serialized format is:
+//
+//   <version><network><block height><block hash><records><commitment hash>
+//
+//   Field             Type             Size
+//   version           uint32           4 bytes
+//   network           uint32           4 bytes
+//   block height      uint32           4 bytes
+//   block hash        chainhash.Hash   chainhash.HashSize
+//   records           []record         variable
+//   commitment hash   chainhash.Hash   chainhash.HashSize
+//
+// Each record is serialized as:
+//
+//   <record type><key><value>
+//
+//   Field             Type             Size
+//   record type       byte             1 byte
+//   key               []byte           variable length byte array
+//   value             []byte           variable length byte array
+//
+// The keys and values are the same as those stored in the database with the
+// exception of the status of the block index entries, which only indicates the
+// blocks are valid, and the keys of the ticket database entries, which are
+// prefixed with the stake.DatabaseEntryType of the entry.  The records end with
+// a record of type snapshotRecordEnd.
+//
+// The commitment hash is the BLAKE-256 hash of everything before it.  Since the
+// records are written in a deterministic order, any two nodes with the same
+// chain state produce the same commitment hash.
+// -----------------------------------------------------------------------------
+
+// snapshotRecordType identifies the kind of a record in a chain state snapshot.
+type snapshotRecordType byte
+
+// These constants define the kinds of records in a chain state snapshot.
+const (
+	snapshotRecordEnd snapshotRecordType = iota
+	snapshotRecordBlock
+	snapshotRecordSpendJournal
+	snapshotRecordBlockIndex
+	snapshotRecordUtxo
+	snapshotRecordTicket
+	snapshotRecordChainState
+)
+
+// SnapshotInfo describes a chain state snapshot.
+type SnapshotInfo struct {
+	// Height and Hash identify the block the snapshot was created at.
+	Height int64
+	Hash   chainhash.Hash
+
+	// Commitment is the hash that commits to the entire contents of the
+	// snapshot.
+	Commitment chainhash.Hash
+}
+
+// snapshotRecord is a single record in a chain state snapshot.
+type snapshotRecord struct {
+	recordType snapshotRecordType
+	key        []byte
+	value      []byte
+}
+
+// snapshotWriter serializes a chain state snapshot while calculating its
+// commitment hash.
+type snapshotWriter struct {
+	w      *bufio.Writer
+	hasher hash.Hash
+	mw     io.Writer
+}
+
+// newSnapshotWriter returns a new snapshot writer that writes to the passed
+// writer.
+func newSnapshotWriter(w io.Writer) *snapshotWriter {
+	bw := bufio.NewWriter(w)
+	hasher := blake256.New()
+	return &snapshotWriter{
+		w:      bw,
+		hasher: hasher,
+		mw:     io.MultiWriter(bw, hasher),
+	}
+}
+
+// writeHeader writes the snapshot header for the passed network and block.
+func (s *snapshotWriter) writeHeader(net wire.CurrencyNet, hash *chainhash.Hash, height int64) error {
+	var header [snapshotHeaderSize]byte
+	binary.LittleEndian.PutUint32(header[0:4], snapshotVersion)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(net))
+	binary.LittleEndian.PutUint32(header[8:12], uint32(height))
+	copy(header[12:], hash[:])
+	_, err := s.mw.Write(header[:])
+	return err
+}
+
+// writeRecord writes a record with the passed type, key, and value.
+func (s *snapshotWriter) writeRecord(recordType snapshotRecordType, key, value []byte) error {
+	if _, err := s.mw.Write([]byte{byte(recordType)}); err != nil {
+		return err
+	}
+	if err := wire.WriteVarBytes(s.mw, 0, key); err != nil {
+		return err
+	}
+	return wire.WriteVarBytes(s.mw, 0, value)
+}
+
+// finish writes the final record followed by the commitment hash and flushes
+// the underlying writer.  It returns the commitment hash.
+func (s *snapshotWriter) finish() (chainhash.Hash, error) {
+	var commitment chainhash.Hash
+	if err := s.writeRecord(snapshotRecordEnd, nil, nil); err != nil {
+		return commitment, err
+	}
+	copy(commitment[:], s.hasher.Sum(nil))
+	if _, err := s.w.Write(commitment[:]); err != nil {
+		return commitment, err
+	}
+	return commitment, s.w.Flush()
+}
+
+// snapshotReader deserializes a chain state snapshot while calculating its
+// commitment hash.
+type snapshotReader struct {
+	r      *bufio.Reader
+	hasher hash.Hash
+	tr     io.Reader
+}
+
+// newSnapshotReader returns a new snapshot reader that reads from the passed
+// reader.
+func newSnapshotReader(r io.Reader) *snapshotReader {
+	br := bufio.NewReader(r)
+	hasher := blake256.New()
+	return &snapshotReader{
+		r:      br,
+		hasher: hasher,
+		tr:     io.TeeReader(br, hasher),
+	}
+}
+
+// readHeader reads the snapshot header and ensures it is for the current
+// version of the format and the passed network.  It returns the height and hash
+// of the block the snapshot was created at.
+func (s *snapshotReader) readHeader(net wire.CurrencyNet) (int64, chainhash.Hash, error) {
+	var hash chainhash.Hash
+	var header [snapshotHeaderSize]byte
+	if _, err := io.ReadFull(s.tr, header[:]); err != nil {
+		return 0, hash, err
+	}
+	version := binary.LittleEndian.Uint32(header[0:4])
+	if version != snapshotVersion {
+		return 0, hash, fmt.Errorf("unsupported snapshot version %d",
+			version)
+	}
+	snapshotNet := wire.CurrencyNet(binary.LittleEndian.Uint32(header[4:8]))
+	if snapshotNet != net {
+		return 0, hash, fmt.Errorf("snapshot is for network %v instead "+
+			"of %v", snapshotNet, net)
+	}
+	height := int64(binary.LittleEndian.Uint32(header[8:12]))
+	copy(hash[:], header[12:])
+	return height, hash, nil
+}
+
+// readRecord reads the next record.
+func (s *snapshotReader) readRecord() (*snapshotRecord, error) {
+	var recordType [1]byte
+	if _, err := io.ReadFull(s.tr, recordType[:]); err != nil {
+		return nil, err
+	}
+	key, err := wire.ReadVarBytes(s.tr, 0, maxSnapshotKeySize, "key")
+	if err != nil {
+		return nil, err
+	}
+	value, err := wire.ReadVarBytes(s.tr, 0, maxSnapshotValueSize, "value")
+	if err != nil {
+		return nil, err
+	}
+	return &snapshotRecord{
+		recordType: snapshotRecordType(recordType[0]),
+		key:        key,
+		value:      value,
+	}, nil
+}
+
+// readCommitment reads the commitment hash that follows the final record and
+// ensures it matches the hash of the data that was read.
+func (s *snapshotReader) readCommitment() (chainhash.Hash, error) {
+	var commitment chainhash.Hash
+	if _, err := io.ReadFull(s.r, commitment[:]); err != nil {
+		return commitment, err
+	}
+	var calculated chainhash.Hash
+	copy(calculated[:], s.hasher.Sum(nil))
+	if calculated != commitment {
+		return commitment, fmt.Errorf("snapshot commitment hash %v does "+
+			"not match the calculated hash %v", commitment, calculated)
+	}
+	return commitment, nil
+}
+
+// readSnapshotInfo reads the information about the chain state snapshot in the
+// passed reader and ensures it is listed as a known valid snapshot in the
+// provided chain parameters.  Note that the commitment hash is not verified
+// against the contents of the snapshot until it is loaded.
+func readSnapshotInfo(r io.ReadSeeker, params *chaincfg.Params) (*SnapshotInfo, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	height, hash, err := newSnapshotReader(r).readHeader(params.Net)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.Seek(-chainhash.HashSize, io.SeekEnd); err != nil {
+		return nil, err
+	}
+	info := SnapshotInfo{Height: height, Hash: hash}
+	if _, err := io.ReadFull(r, info.Commitment[:]); err != nil {
+		return nil, err
+	}
+
+	for _, snapshot := range params.Snapshots {
+		if snapshot.Height == info.Height &&
+			*snapshot.Hash == info.Commitment {
+
+			return &info, nil
+		}
+	}
+	return nil, fmt.Errorf("snapshot %v at height %d is not a known valid "+
+		"snapshot for the %s network", info.Commitment, info.Height,
+		params.Name)
+}
+
+// snapshotRetainStart returns the height of the first block for which the
+// block data and spend journal entry are included in a chain state snapshot
+// created at the passed height.
+func snapshotRetainStart(height int64) int64 {
+	start := height - MinRetainedBlocks + 1
+	if start < 1 {
+		start = 1
+	}
+	return start
+}
+
+// DumpSnapshot writes a snapshot of the chain state as of the current best
+// block to the passed writer.  The snapshot includes the utxo set, the ticket
+// database state, and the block index of the main chain.  It returns
+// information about the snapshot including its commitment hash, which must be
+// listed in the chain parameters in order to load the snapshot.
+//
+// Blocks can't be processed while the utxo cache is flushed and the data of the
+// most recent blocks is written, but they can while the rest of the snapshot is
+// written from a read-only view of the database.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) DumpSnapshot(w io.Writer) (*SnapshotInfo, error) {
+	b.chainLock.Lock()
+
+	// Ensure the utxo set and block index in the database are consistent
+	// with the best chain.
+	tip := b.bestChain.Tip()
+	if err := b.utxoCache.flush(&tip.hash, tip.height); err != nil {
+		b.chainLock.Unlock()
+		return nil, err
+	}
+	if err := b.flushBlockIndex(); err != nil {
+		b.chainLock.Unlock()
+		return nil, err
+	}
+
+	// Start a read-only view of the database which is not affected by any
+	// blocks processed once the chain lock is released.
+	dbTx, err := b.db.Begin(false)
+	if err != nil {
+		b.chainLock.Unlock()
+		return nil, err
+	}
+	defer dbTx.Rollback()
+	meta := dbTx.Metadata()
+
+	// Write the block data and spend journal entries of the most recent
+	// blocks.  The block data is not part of the view and may be pruned
+	// once the chain lock is released, so this is done while holding it.
+	sw := newSnapshotWriter(w)
+	retainStart := snapshotRetainStart(tip.height)
+	err = func() error {
+		defer b.chainLock.Unlock()
+
+		err := sw.writeHeader(b.chainParams.Net, &tip.hash, tip.height)
+		if err != nil {
+			return err
+		}
+		spendBucket := meta.Bucket(dbnamespace.SpendJournalBucketName)
+		for height := retainStart; height <= tip.height; height++ {
+			node := b.bestChain.NodeByHeight(height)
+			blockBytes, err := dbTx.FetchBlock(&node.hash)
+			if err != nil {
+				return err
+			}
+			err = sw.writeRecord(snapshotRecordBlock, node.hash[:],
+				blockBytes)
+			if err != nil {
+				return err
+			}
+			err = sw.writeRecord(snapshotRecordSpendJournal, node.hash[:],
+				spendBucket.Get(node.hash[:]))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		return nil, err
+	}
+
+	// Write the block index entries of the main chain as of the tip the
+	// view was started at.  The status of the entries depends on which
+	// block data is available, so it only records that the blocks are
+	// valid.
+	mainChain := make([]*blockNode, tip.height+1)
+	for node := tip; node != nil; node = node.parent {
+		mainChain[node.height] = node
+	}
+	indexBucket := meta.Bucket(dbnamespace.BlockIndexBucketName)
+	for height, node := range mainChain {
+		key := blockIndexKey(&node.hash, uint32(height))
+		serialized := indexBucket.Get(key)
+		if serialized == nil {
+			return nil, AssertError(fmt.Sprintf("DumpSnapshot: block "+
+				"index entry for block %s (height %d) does not exist",
+				node.hash, height))
+		}
+		entry, err := deserializeBlockIndexEntry(serialized)
+		if err != nil {
+			return nil, err
+		}
+		entry.status = statusValid
+		serialized, err = serializeBlockIndexEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		err = sw.writeRecord(snapshotRecordBlockIndex, key, serialized)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Write the utxo set.
+	cursor := meta.Bucket(dbnamespace.UtxoSetBucketName).Cursor()
+	for ok := cursor.First(); ok; ok = cursor.Next() {
+		err := sw.writeRecord(snapshotRecordUtxo, cursor.Key(),
+			cursor.Value())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Write the ticket database state.  The block undo data and new tickets
+	// for the parent of the first retained block are also needed in order
+	// to disconnect that block.
+	err = stake.ForEachDatabaseEntry(dbTx, uint32(retainStart-1),
+		func(entryType stake.DatabaseEntryType, key, value []byte) error {
+			recordKey := make([]byte, 1+len(key))
+			recordKey[0] = byte(entryType)
+			copy(recordKey[1:], key)
+			return sw.writeRecord(snapshotRecordTicket, recordKey, value)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	// Write the best chain state.
+	err = sw.writeRecord(snapshotRecordChainState, nil,
+		meta.Get(dbnamespace.ChainStateKeyName))
+	if err != nil {
+		return nil, err
+	}
+
+	commitment, err := sw.finish()
+	if err != nil {
+		return nil, err
+	}
+	return &SnapshotInfo{
+		Height:     tip.height,
+		Hash:       tip.hash,
+		Commitment: commitment,
+	}, nil
+}
+
+// dbPutSnapshotRecord uses an existing database transaction to store the passed
+// chain state snapshot record.  The passed set of blocks with their block data
+// stored is updated as blocks are stored and used to set the status of the
+// block index entries accordingly.
+func dbPutSnapshotRecord(dbTx database.Tx, record *snapshotRecord, storedBlocks map[chainhash.Hash]struct{}) error {
+	meta := dbTx.Metadata()
+	switch record.recordType {
+	case snapshotRecordBlock:
+		block, err := dcrutil.NewBlockFromBytes(record.value)
+		if err != nil {
+			return err
+		}
+		if err := dbMaybeStoreBlock(dbTx, block); err != nil {
+			return err
+		}
+		storedBlocks[*block.Hash()] = struct{}{}
+		return nil
+
+	case snapshotRecordSpendJournal:
+		bucket := meta.Bucket(dbnamespace.SpendJournalBucketName)
+		return bucket.Put(record.key, record.value)
+
+	case snapshotRecordBlockIndex:
+		entry, err := deserializeBlockIndexEntry(record.value)
+		if err != nil {
+			return err
+		}
+		entry.status = statusValid
+		if _, ok := storedBlocks[entry.header.BlockHash()]; ok {
+			entry.status |= statusDataStored
+		}
+		serialized, err := serializeBlockIndexEntry(entry)
+		if err != nil {
+			return err
+		}
+		bucket := meta.Bucket(dbnamespace.BlockIndexBucketName)
+		return bucket.Put(record.key, serialized)
+
+	case snapshotRecordUtxo:
+		bucket := meta.Bucket(dbnamespace.UtxoSetBucketName)
+		return bucket.Put(record.key, record.value)
+
+	case snapshotRecordTicket:
+		if len(record.key) == 0 {
+			return fmt.Errorf("malformed snapshot ticket database entry")
+		}
+		entryType := stake.DatabaseEntryType(record.key[0])
+		return stake.PutDatabaseEntry(dbTx, entryType, record.key[1:],
+			record.value)
+	}
+
+	return fmt.Errorf("unknown snapshot record type %d", record.recordType)
+}
+
+// verifySnapshot reads the entire configured chain state snapshot without
+// writing anything to the database and ensures its contents match the expected
+// commitment hash and its best chain state is for the block the snapshot was
+// created at.  It returns the serialized best chain state.
+func (b *BlockChain) verifySnapshot() ([]byte, error) {
+	info := b.snapshotInfo
+	if _, err := b.snapshotFile.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	sr := newSnapshotReader(b.snapshotFile)
+	if _, _, err := sr.readHeader(b.chainParams.Net); err != nil {
+		return nil, err
+	}
+	var chainState []byte
+	for {
+		if interruptRequested(b.interrupt) {
+			return nil, errInterruptRequested
+		}
+
+		record, err := sr.readRecord()
+		if err != nil {
+			return nil, err
+		}
+		if record.recordType == snapshotRecordEnd {
+			break
+		}
+		if record.recordType == snapshotRecordChainState {
+			chainState = record.value
+		}
+	}
+
+	commitment, err := sr.readCommitment()
+	if err != nil {
+		return nil, err
+	}
+	if commitment != info.Commitment {
+		return nil, fmt.Errorf("snapshot commitment hash %v does not match "+
+			"the expected hash %v", commitment, info.Commitment)
+	}
+	best, err := deserializeBestChainState(chainState)
+	if err != nil {
+		return nil, err
+	}
+	if best.hash != info.Hash || int64(best.height) != info.Height {
+		return nil, fmt.Errorf("snapshot best chain state %v (height %d) "+
+			"does not match the snapshot block %v (height %d)", best.hash,
+			best.height, info.Hash, info.Height)
+	}
+	return chainState, nil
+}
+
+// loadSnapshot loads the chain state from the configured chain state snapshot
+// into the database.  The database must only contain the genesis block or a
+// partially loaded chain state from the same snapshot.
+//
+// The entire snapshot is verified against its commitment hash before anything
+// is written to the database.  The records are then written in batches and the
+// snapshot is only marked as loaded once all of them have been written, so an
+// interrupted load is detected on the next startup.
+func (b *BlockChain) loadSnapshot() error {
+	info := b.snapshotInfo
+	log.Infof("Verifying chain state snapshot %v at height %d...",
+		info.Commitment, info.Height)
+	chainState, err := b.verifySnapshot()
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Loading chain state snapshot %v at height %d...",
+		info.Commitment, info.Height)
+	if _, err := b.snapshotFile.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	sr := newSnapshotReader(b.snapshotFile)
+	if _, _, err := sr.readHeader(b.chainParams.Net); err != nil {
+		return err
+	}
+
+	// Record that the database is being loaded from the snapshot.
+	state := &snapshotState{info: *info}
+	err = b.db.Update(func(dbTx database.Tx) error {
+		return dbPutSnapshotState(dbTx, state)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Write the records to the database in batches.  The best chain state is
+	// written last along with the utxo set state.
+	storedBlocks := map[chainhash.Hash]struct{}{*b.chainParams.GenesisHash: {}}
+	var batch []*snapshotRecord
+	var batchSize int
+	writeBatch := func() error {
+		err := b.db.Update(func(dbTx database.Tx) error {
+			for _, record := range batch {
+				err := dbPutSnapshotRecord(dbTx, record, storedBlocks)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		batch = batch[:0]
+		batchSize = 0
+		return err
+	}
+	for {
+		if interruptRequested(b.interrupt) {
+			return errInterruptRequested
+		}
+
+		record, err := sr.readRecord()
+		if err != nil {
+			return err
+		}
+		if record.recordType == snapshotRecordEnd {
+			break
+		}
+		if record.recordType == snapshotRecordChainState {
+			continue
+		}
+
+		batch = append(batch, record)
+		batchSize += len(record.key) + len(record.value)
+		if batchSize >= snapshotBatchSize {
+			if err := writeBatch(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := writeBatch(); err != nil {
+		return err
+	}
+
+	// Ensure the snapshot did not change since it was verified.
+	commitment, err := sr.readCommitment()
+	if err != nil {
+		return err
+	}
+	if commitment != info.Commitment {
+		return fmt.Errorf("snapshot commitment hash %v does not match the "+
+			"expected hash %v", commitment, info.Commitment)
+	}
+
+	state.loaded = true
+	err = b.db.Update(func(dbTx database.Tx) error {
+		err := dbTx.Metadata().Put(dbnamespace.ChainStateKeyName, chainState)
+		if err != nil {
+			return err
+		}
+		err = dbPutUtxoSetState(dbTx, &utxoSetState{
+			hash:   info.Hash,
+			height: uint32(info.Height),
+		})
+		if err != nil {
+			return err
+		}
+		return dbPutSnapshotState(dbTx, state)
+	})
+	if err != nil {
+		return err
+	}
+
+	b.snapshot = state
+	log.Infof("Loaded chain state snapshot at height %d", info.Height)
+	return nil
+}
+
+// maybeLoadSnapshot loads the chain state from the configured chain state
+// snapshot, if any, when the database does not contain a chain yet or only
+// contains a partially loaded chain state from the same snapshot.  It also
+// loads the state of the snapshot the database was loaded from, if any.
+func (b *BlockChain) maybeLoadSnapshot() error {
+	var state *snapshotState
+	var best bestChainState
+	err := b.db.View(func(dbTx database.Tx) error {
+		var err error
+		state, err = dbFetchSnapshotState(dbTx)
+		if err != nil {
+			return err
+		}
+		serializedData := dbTx.Metadata().Get(dbnamespace.ChainStateKeyName)
+		best, err = deserializeBestChainState(serializedData)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if b.snapshotInfo == nil {
+		if state != nil && !state.loaded {
+			return fmt.Errorf("the database only contains a partially "+
+				"loaded chain state snapshot at height %d -- the "+
+				"snapshot must be loaded again or the database removed",
+				state.info.Height)
+		}
+		b.snapshot = state
+		return nil
+	}
+
+	switch {
+	case state != nil && state.info.Commitment != b.snapshotInfo.Commitment:
+		return fmt.Errorf("the database already contains the chain state "+
+			"from snapshot %v", state.info.Commitment)
+
+	case state != nil && state.loaded:
+		log.Infof("The chain state was already loaded from snapshot %v",
+			state.info.Commitment)
+		b.snapshot = state
+		return nil
+
+	case state == nil && best.hash != *b.chainParams.GenesisHash:
+		return fmt.Errorf("unable to load a chain state snapshot into a " +
+			"database that already contains a chain")
+	}
+
+	return b.loadSnapshot()
+}
+
+// FetchLoadedSnapshot returns information about the chain state snapshot the
+// chain state in the passed database was loaded from, or nil when it was not
+// loaded from a snapshot.  This includes snapshots that are only partially
+// loaded and those for which the history leading up to them has been validated
+// since the block data before them is not available in either case.
+//
+// It allows callers to determine whether or not the history of the chain is
+// available before creating a chain instance from the database.
+func FetchLoadedSnapshot(db database.DB) (*SnapshotInfo, error) {
+	var state *snapshotState
+	err := db.View(func(dbTx database.Tx) error {
+		var err error
+		state, err = dbFetchSnapshotState(dbTx)
+		return err
+	})
+	if err != nil || state == nil {
+		return nil, err
+	}
+	info := state.info
+	return &info, nil
+}
+
+// UnvalidatedSnapshot returns information about the chain state snapshot the
+// chain state was loaded from when the history leading up to it has not been
+// validated yet.  It returns nil otherwise.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) UnvalidatedSnapshot() *SnapshotInfo {
+	b.chainLock.RLock()
+	defer b.chainLock.RUnlock()
+
+	if b.snapshot == nil || b.snapshot.validated {
+		return nil
+	}
+	info := b.snapshot.info
+	return &info
+}
+
+// MarkSnapshotValidated records that the history leading up to the chain state
+// snapshot the chain state was loaded from has been validated.  The caller is
+// responsible for validating the history, typically by connecting all of the
+// blocks up to the snapshot with a separate chain instance and ensuring its
+// snapshot has the same commitment hash.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) MarkSnapshotValidated() error {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	if b.snapshot == nil || b.snapshot.validated {
+		return nil
+	}
+	state := *b.snapshot
+	state.validated = true
+	err := b.db.Update(func(dbTx database.Tx) error {
+		return dbPutSnapshotState(dbTx, &state)
+	})
+	if err != nil {
+		return err
+	}
+	b.snapshot = &state
+	return nil
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/decred/dcrd/blockchain/internal/dbnamespace"
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/database"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/txscript"
+)
+
+// TestSnapshot ensures chain state snapshots can be dumped and loaded into a
+// new database, and only known valid snapshots are loaded.
+func TestSnapshot(t *testing.T) {
+	// Create a test harness initialized with the genesis block as the tip and
+	// generate enough blocks to reach stake validation height.
+	params := &chaincfg.RegNetParams
+	g, teardownFunc := newChaingenHarness(t, params, "snapshottest")
+	defer teardownFunc()
+	g.AdvanceToStakeValidationHeight()
+	tip := g.chain.BestSnapshot()
+
+	// Dump a snapshot of the chain state.
+	var buf bytes.Buffer
+	info, err := g.chain.DumpSnapshot(&buf)
+	if err != nil {
+		t.Fatalf("failed to dump snapshot: %v", err)
+	}
+	if info.Height != tip.Height || info.Hash != tip.Hash {
+		t.Fatalf("unexpected snapshot block -- got %s (height %d), want "+
+			"%s (height %d)", info.Hash, info.Height, tip.Hash, tip.Height)
+	}
+
+	// Create a new database to load the snapshot into.
+	dbPath, err := ioutil.TempDir("", "snapshottestload")
+	if err != nil {
+		t.Fatalf("unable to create test db path: %v", err)
+	}
+	defer os.RemoveAll(dbPath)
+	db, err := database.Create(testDbType, dbPath, blockDataNet)
+	if err != nil {
+		t.Fatalf("error creating db: %v", err)
+	}
+	defer db.Close()
+
+	// Ensure a snapshot that is not listed in the chain parameters is
+	// refused.
+	paramsCopy := *params
+	config := Config{
+		DB:          db,
+		ChainParams: &paramsCopy,
+		TimeSource:  NewMedianTime(),
+		SigCache:    txscript.NewSigCache(1000),
+		Snapshot:    bytes.NewReader(buf.Bytes()),
+	}
+	if _, err := New(&config); err == nil {
+		t.Fatal("unknown snapshot was loaded")
+	}
+
+	// Ensure a listed snapshot with contents that do not match its commitment
+	// hash is refused without writing anything to the database.
+	paramsCopy.Snapshots = []chaincfg.Snapshot{
+		{Height: info.Height, Hash: &info.Commitment},
+	}
+	corrupted := append([]byte(nil), buf.Bytes()...)
+	corrupted[len(corrupted)/2] ^= 0x01
+	config.Snapshot = bytes.NewReader(corrupted)
+	if _, err := New(&config); err == nil {
+		t.Fatal("corrupted snapshot was loaded")
+	}
+	var state *snapshotState
+	var utxoSetWritten bool
+	err = db.View(func(dbTx database.Tx) error {
+		var err error
+		state, err = dbFetchSnapshotState(dbTx)
+		utxoBucket := dbTx.Metadata().Bucket(dbnamespace.UtxoSetBucketName)
+		utxoSetWritten = utxoBucket.Cursor().First()
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unable to check the database: %v", err)
+	}
+	if state != nil {
+		t.Fatalf("unexpected snapshot state %v after corrupted load",
+			state.info)
+	}
+	if utxoSetWritten {
+		t.Fatal("utxo set written by corrupted load")
+	}
+	if loaded, err := FetchLoadedSnapshot(db); err != nil || loaded != nil {
+		t.Fatalf("unexpected loaded snapshot %v (err %v) after corrupted "+
+			"load", loaded, err)
+	}
+
+	// Ensure a listed snapshot is loaded and results in the same chain state.
+	config.Snapshot = bytes.NewReader(buf.Bytes())
+	chain, err := New(&config)
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	if best := chain.BestSnapshot(); best.Hash != tip.Hash {
+		t.Fatalf("unexpected main chain tip -- got %s, want %s", best.Hash,
+			tip.Hash)
+	}
+	loadedInfo, err := chain.DumpSnapshot(ioutil.Discard)
+	if err != nil {
+		t.Fatalf("failed to dump loaded snapshot: %v", err)
+	}
+	if loadedInfo.Commitment != info.Commitment {
+		t.Fatalf("unexpected loaded snapshot commitment -- got %s, want %s",
+			loadedInfo.Commitment, info.Commitment)
+	}
+
+	// Ensure the database records the snapshot it was loaded from.
+	loaded, err := FetchLoadedSnapshot(db)
+	if err != nil {
+		t.Fatalf("failed to fetch loaded snapshot: %v", err)
+	}
+	if loaded == nil || *loaded != *info {
+		t.Fatalf("unexpected loaded snapshot -- got %v, want %v", loaded,
+			info)
+	}
+
+	// Ensure the history leading up to the snapshot is reported as not
+	// validated until it is marked as such.
+	unvalidated := chain.UnvalidatedSnapshot()
+	if unvalidated == nil || *unvalidated != *info {
+		t.Fatalf("unexpected unvalidated snapshot -- got %v, want %v",
+			unvalidated, info)
+	}
+	if err := chain.MarkSnapshotValidated(); err != nil {
+		t.Fatalf("failed to mark snapshot validated: %v", err)
+	}
+	if unvalidated := chain.UnvalidatedSnapshot(); unvalidated != nil {
+		t.Fatalf("unexpected unvalidated snapshot %v", unvalidated)
+	}
+	if loaded, err := FetchLoadedSnapshot(db); err != nil || loaded == nil {
+		t.Fatalf("loaded snapshot not retained after validation (err %v)",
+			err)
+	}
+
+	// Ensure blocks after the snapshot are connected to the loaded chain.
+	outs := g.OldestCoinbaseOuts()
+	g.NextBlock("b1", nil, outs[1:])
+	g.Accepted()
+	_, _, err = chain.ProcessBlock(dcrutil.NewBlock(g.Tip()), BFNone)
+	if err != nil {
+		t.Fatalf("failed to process block after snapshot: %v", err)
+	}
+	if best := chain.BestSnapshot(); best.Hash != g.Tip().BlockHash() {
+		t.Fatalf("unexpected main chain tip -- got %s, want %s", best.Hash,
+			g.Tip().BlockHash())
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package stake
+
+import (
+	"fmt"
+
+	"github.com/decred/dcrd/blockchain/stake/internal/dbnamespace"
+	"github.com/decred/dcrd/blockchain/stake/internal/ticketdb"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/database"
+)
+
+// DatabaseEntryType identifies the kind of a ticket database entry that is
+// exported to or imported from a chain state snapshot.
+type DatabaseEntryType byte
+
+// These constants define the kinds of ticket database entries.
+const (
+	// DbEntryLiveTicket is a ticket in the live ticket bucket.
+	DbEntryLiveTicket DatabaseEntryType = iota
+
+	// DbEntryMissedTicket is a ticket in the missed ticket bucket.
+	DbEntryMissedTicket
+
+	// DbEntryRevokedTicket is a ticket in the revoked ticket bucket.
+	DbEntryRevokedTicket
+
+	// DbEntryBlockUndoData is the undo data for the block at a given
+	// height.
+	DbEntryBlockUndoData
+
+	// DbEntryNewTickets is the list of tickets included in the block at a
+	// given height.
+	DbEntryNewTickets
+
+	// DbEntryBestState is the best chain state of the ticket database.
+	DbEntryBestState
+)
+
+// databaseEntryBuckets maps the kinds of ticket database entries that are
+// stored in buckets to the names of their buckets.
+var databaseEntryBuckets = map[DatabaseEntryType][]byte{
+	DbEntryLiveTicket:    dbnamespace.LiveTicketsBucketName,
+	DbEntryMissedTicket:  dbnamespace.MissedTicketsBucketName,
+	DbEntryRevokedTicket: dbnamespace.RevokedTicketsBucketName,
+	DbEntryBlockUndoData: dbnamespace.StakeBlockUndoDataBucketName,
+	DbEntryNewTickets:    dbnamespace.TicketsInBlockBucketName,
+}
+
+// ForEachDatabaseEntry calls the provided function with the serialized key and
+// value of every ticket database entry needed to restore the state of the best
+// node in another database.  This includes all live, missed, and revoked
+// tickets along with the best chain state, and the block undo data and new
+// tickets for the blocks from the passed height through the best node.  The
+// block undo data and new tickets are needed to disconnect those blocks.
+//
+// The entries are provided in a deterministic order.
+func ForEachDatabaseEntry(dbTx database.Tx, minHeight uint32, fn func(entryType DatabaseEntryType, key, value []byte) error) error {
+	meta := dbTx.Metadata()
+	for _, entryType := range []DatabaseEntryType{DbEntryLiveTicket,
+		DbEntryMissedTicket, DbEntryRevokedTicket} {
+
+		bucket := meta.Bucket(databaseEntryBuckets[entryType])
+		err := bucket.ForEach(func(k, v []byte) error {
+			return fn(entryType, k, v)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	state, err := ticketdb.DbFetchBestState(dbTx)
+	if err != nil {
+		return err
+	}
+	for height := minHeight; height <= state.Height; height++ {
+		k := make([]byte, 4)
+		dbnamespace.ByteOrder.PutUint32(k, height)
+		for _, entryType := range []DatabaseEntryType{DbEntryBlockUndoData,
+			DbEntryNewTickets} {
+
+			bucket := meta.Bucket(databaseEntryBuckets[entryType])
+			v := bucket.Get(k)
+			if v == nil {
+				str := fmt.Sprintf("missing %v entry for height %d",
+					entryType, height)
+				return stakeRuleError(ErrDatabaseCorrupt, str)
+			}
+			if err := fn(entryType, k, v); err != nil {
+				return err
+			}
+		}
+	}
+
+	return fn(DbEntryBestState, nil, meta.Get(dbnamespace.StakeChainStateKeyName))
+}
+
+// PutDatabaseEntry stores a ticket database entry previously provided by
+// ForEachDatabaseEntry.  The ticket database must already have been created
+// with InitDatabaseState.
+func PutDatabaseEntry(dbTx database.Tx, entryType DatabaseEntryType, key, value []byte) error {
+	meta := dbTx.Metadata()
+	switch entryType {
+	case DbEntryLiveTicket, DbEntryMissedTicket, DbEntryRevokedTicket:
+		if len(key) != chainhash.HashSize || len(value) != 5 {
+			str := fmt.Sprintf("malformed %v entry", entryType)
+			return stakeRuleError(ErrDatabaseCorrupt, str)
+		}
+
+	case DbEntryBlockUndoData, DbEntryNewTickets:
+		if len(key) != 4 {
+			str := fmt.Sprintf("malformed %v entry", entryType)
+			return stakeRuleError(ErrDatabaseCorrupt, str)
+		}
+
+	case DbEntryBestState:
+		return meta.Put(dbnamespace.StakeChainStateKeyName, value)
+
+	default:
+		str := fmt.Sprintf("unknown ticket database entry type %d",
+			entryType)
+		return stakeRuleError(ErrDatabaseCorrupt, str)
+	}
+
+	return meta.Bucket(databaseEntryBuckets[entryType]).Put(key, value)
+}
+
+// databaseEntryTypeStrings is a map of ticket database entry types back to
+// their constant names for pretty printing.
+var databaseEntryTypeStrings = map[DatabaseEntryType]string{
+	DbEntryLiveTicket:    "DbEntryLiveTicket",
+	DbEntryMissedTicket:  "DbEntryMissedTicket",
+	DbEntryRevokedTicket: "DbEntryRevokedTicket",
+	DbEntryBlockUndoData: "DbEntryBlockUndoData",
+	DbEntryNewTickets:    "DbEntryNewTickets",
+	DbEntryBestState:     "DbEntryBestState",
+}
+
+// String returns the DatabaseEntryType as a human-readable name.
+func (t DatabaseEntryType) String() string {
+	if s := databaseEntryTypeStrings[t]; s != "" {
+		return s
+	}
+	return fmt.Sprintf("Unknown DatabaseEntryType (%d)", int(t))
+}
@@ -9,7 +9,6 @@ import (
 	"fmt"
 
 	"github.com/decred/dcrd/chaincfg/chainhash"
-	"github.com/decred/dcrd/database"
 	"github.com/decred/dcrd/dcrutil"
 	"github.com/decred/dcrd/txscript"
 )
@@ -114,27 +113,21 @@ func (b *BlockChain) TicketsWithAddress(address dcrutil.Address) ([]chainhash.Ha
 	tickets := sn.LiveTickets()
 
 	var ticketsWithAddr []chainhash.Hash
-	err := b.db.View(func(dbTx database.Tx) error {
-		for _, hash := range tickets {
-			utxo, err := dbFetchUtxoEntry(dbTx, &hash)
-			if err != nil {
-				return err
-			}
-
-			_, addrs, _, err :=
-				txscript.ExtractPkScriptAddrs(txscript.DefaultScriptVersion,
-					utxo.PkScriptByIndex(0), b.chainParams)
-			if err != nil {
-				return err
-			}
-			if addrs[0].EncodeAddress() == address.EncodeAddress() {
-				ticketsWithAddr = append(ticketsWithAddr, hash)
-			}
+	for i := range tickets {
+		utxo, err := b.utxoCache.fetchEntry(&tickets[i])
+		if err != nil {
+			return nil, err
+		}
+
+		_, addrs, _, err :=
+			txscript.ExtractPkScriptAddrs(txscript.DefaultScriptVersion,
+				utxo.PkScriptByIndex(0), b.chainParams)
+		if err != nil {
+			return nil, err
+		}
+		if addrs[0].EncodeAddress() == address.EncodeAddress() {
+			ticketsWithAddr = append(ticketsWithAddr, tickets[i])
 		}
-		return nil
-	})
-	if err != nil {
-		return nil, err
 	}
 
 	return ticketsWithAddr, nil
@@ -226,19 +219,13 @@ func (b *BlockChain) TicketPoolValue() (dcrutil.Amount, error) {
 	b.chainLock.RUnlock()
 
 	var amt int64
-	err := b.db.View(func(dbTx database.Tx) error {
-		for _, hash := range sn.LiveTickets() {
-			utxo, err := dbFetchUtxoEntry(dbTx, &hash)
-			if err != nil {
-				return err
-			}
-
-			amt += utxo.sparseOutputs[0].amount
+	for _, hash := range sn.LiveTickets() {
+		utxo, err := b.utxoCache.fetchEntry(&hash)
+		if err != nil {
+			return 0, err
 		}
-		return nil
-	})
-	if err != nil {
-		return 0, err
+
+		amt += utxo.sparseOutputs[0].amount
 	}
 	return dcrutil.Amount(amt), nil
 }
@@ -0,0 +1,389 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/database"
+)
+
+const (
+	// utxoCacheFlushInterval is the maximum amount of time the utxo cache
+	// is allowed to hold modified entries before they are flushed to the
+	// database.
+	utxoCacheFlushInterval = 2 * time.Minute
+
+	// utxoEntryBaseSize is the approximate number of bytes of memory used
+	// by a cached utxo entry excluding its outputs and stake extra data.
+	// It accounts for the entry itself, its sparse outputs map, and its key
+	// and slot in the cache map.
+	utxoEntryBaseSize = 256
+
+	// utxoOutputBaseSize is the approximate number of bytes of memory used
+	// by each output of a cached utxo entry excluding its public key
+	// script.
+	utxoOutputBaseSize = 48
+)
+
+// utxoCache provides an in-memory write-back cache that sits between utxo
+// viewpoints and the utxo set in the database.
+//
+// Entries loaded from the database are cached while there is room so that
+// later lookups do not need to hit the database, and the modified entries of
+// each connected or disconnected block are merged into the cache instead of
+// being written to the database right away.  The modified entries are then
+// flushed to the database in a single batch periodically, when the cache
+// exceeds its maximum size, whenever a block is disconnected, and on
+// shutdown.
+//
+// Every flush also records the block the utxo set in the database is
+// consistent with in the same database transaction.  That block is always
+// the tip of the best chain written by dbPutBestState or one of its ancestors,
+// so the utxo set is brought back up to date with the best chain after an
+// unclean shutdown by replaying the blocks after it.
+type utxoCache struct {
+	db      database.DB
+	maxSize uint64
+
+	// The following fields are protected by the mutex.
+	//
+	// entries houses the cached utxo entries.  Entries that are marked as
+	// modified have not been written to the database yet and fully spent
+	// entries that are marked as modified still need to be removed from
+	// it.
+	//
+	// flushedHeight is the height of the block the utxo set in the
+	// database is consistent with.
+	mtx           sync.Mutex
+	entries       map[chainhash.Hash]*UtxoEntry
+	totalSize     uint64
+	lastFlush     time.Time
+	flushedHeight int64
+}
+
+// newUtxoCache returns a new utxo cache backed by the provided database that
+// is limited to approximately the passed number of bytes.  A maximum size of
+// zero results in all modifications being written to the database
+// immediately.
+func newUtxoCache(db database.DB, maxSize uint64) *utxoCache {
+	return &utxoCache{
+		db:        db,
+		maxSize:   maxSize,
+		entries:   make(map[chainhash.Hash]*UtxoEntry),
+		lastFlush: time.Now(),
+	}
+}
+
+// utxoEntrySize returns the approximate number of bytes of memory used by the
+// passed utxo entry when it is cached.
+func utxoEntrySize(entry *UtxoEntry) uint64 {
+	size := uint64(utxoEntryBaseSize + len(entry.stakeExtra))
+	for _, output := range entry.sparseOutputs {
+		size += uint64(utxoOutputBaseSize + len(output.pkScript))
+	}
+	return size
+}
+
+// put adds the passed entry to the cache, replacing any existing entry for the
+// same hash.
+//
+// This function MUST be called with the cache lock held.
+func (c *utxoCache) put(hash chainhash.Hash, entry *UtxoEntry) {
+	if existing, ok := c.entries[hash]; ok {
+		c.totalSize -= utxoEntrySize(existing)
+	}
+	c.entries[hash] = entry
+	c.totalSize += utxoEntrySize(entry)
+}
+
+// fetchEntries loads the utxo entries for the provided set of transactions
+// into the passed view from the point of view of the end of the main chain.
+// Entries that are not already cached are loaded from the database and added
+// to the cache as long as there is room for them.
+//
+// Fully spent transactions, or those which otherwise don't exist, will result
+// in a nil entry in the view.
+//
+// This function is safe for concurrent access.
+func (c *utxoCache) fetchEntries(view *UtxoViewpoint, filteredSet viewFilteredSet) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	// Use the cached entries when possible and determine which entries need
+	// to be loaded from the database.  Note that the view is given copies of
+	// the cached entries since it is allowed to modify them.
+	var missing []chainhash.Hash
+	for hash := range filteredSet {
+		entry, ok := c.entries[hash]
+		if !ok {
+			missing = append(missing, hash)
+			continue
+		}
+		if entry.IsFullySpent() {
+			view.entries[hash] = nil
+			continue
+		}
+		view.entries[hash] = entry.Clone()
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return c.db.View(func(dbTx database.Tx) error {
+		for i := range missing {
+			hash := &missing[i]
+			entry, err := dbFetchUtxoEntry(dbTx, hash)
+			if err != nil {
+				return err
+			}
+			if entry == nil {
+				view.entries[*hash] = nil
+				continue
+			}
+
+			// Only cache the entry when doing so would not exceed the
+			// maximum size of the cache.
+			if c.totalSize+utxoEntrySize(entry) > c.maxSize {
+				view.entries[*hash] = entry
+				continue
+			}
+			c.put(*hash, entry)
+			view.entries[*hash] = entry.Clone()
+		}
+		return nil
+	})
+}
+
+// fetchEntry loads and returns the utxo entry for the passed hash from the
+// point of view of the end of the main chain.  Both the entry and the error
+// will be nil when there is no unspent entry for the hash.
+//
+// This function is safe for concurrent access.
+func (c *utxoCache) fetchEntry(hash *chainhash.Hash) (*UtxoEntry, error) {
+	view := NewUtxoViewpoint()
+	filteredSet := viewFilteredSet{*hash: struct{}{}}
+	if err := c.fetchEntries(view, filteredSet); err != nil {
+		return nil, err
+	}
+	return view.entries[*hash], nil
+}
+
+// needsFlush returns whether or not the cache needs to be flushed once the
+// modified entries of the passed view, if any, are merged into it.  This is
+// the case when it would exceed its maximum size or has not been flushed for
+// longer than the flush interval.
+//
+// This function is safe for concurrent access.
+func (c *utxoCache) needsFlush(view *UtxoViewpoint) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if time.Since(c.lastFlush) >= utxoCacheFlushInterval {
+		return true
+	}
+	size := c.totalSize
+	if view != nil {
+		for _, entry := range view.entries {
+			if entry != nil && entry.modified {
+				size += utxoEntrySize(entry)
+			}
+		}
+	}
+	return size > c.maxSize
+}
+
+// dbFlush uses an existing database transaction to write all of the modified
+// cached entries followed by the modified entries in the passed view, if any,
+// to the database and to record that the utxo set in the database is
+// consistent with the block with the provided hash and height.
+//
+// The cache is not updated to reflect the flush since the database
+// transaction might still fail.  Callers must call commit with the same view
+// followed by markFlushed once the database transaction succeeds.
+//
+// This function is safe for concurrent access.
+func (c *utxoCache) dbFlush(dbTx database.Tx, view *UtxoViewpoint, hash *chainhash.Hash, height int64) error {
+	c.mtx.Lock()
+	err := dbPutUtxoEntries(dbTx, c.entries)
+	c.mtx.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// The modified entries in the view are written after those in the cache
+	// since they are more recent.
+	if view != nil {
+		if err := dbPutUtxoView(dbTx, view); err != nil {
+			return err
+		}
+	}
+
+	return dbPutUtxoSetState(dbTx, &utxoSetState{
+		hash:   *hash,
+		height: uint32(height),
+	})
+}
+
+// commit merges the modified entries in the passed view into the cache.  The
+// merged entries remain marked as modified until the cache is flushed.
+//
+// This function is safe for concurrent access.
+func (c *utxoCache) commit(view *UtxoViewpoint) {
+	c.mtx.Lock()
+	for hash, entry := range view.entries {
+		if entry == nil || !entry.modified {
+			continue
+		}
+
+		cachedEntry := entry.Clone()
+		cachedEntry.modified = true
+		c.put(hash, cachedEntry)
+	}
+	c.mtx.Unlock()
+}
+
+// markFlushed updates the cache to reflect that all of its entries have been
+// written to the database along with the utxo set state for the provided
+// height.  Fully spent entries are removed since they no longer exist in the
+// database, and all entries are evicted when the cache exceeds its maximum
+// size.
+//
+// This function is safe for concurrent access.
+func (c *utxoCache) markFlushed(height int64) {
+	c.mtx.Lock()
+	if c.totalSize > c.maxSize {
+		c.entries = make(map[chainhash.Hash]*UtxoEntry)
+		c.totalSize = 0
+	} else {
+		for hash, entry := range c.entries {
+			if entry.IsFullySpent() {
+				c.totalSize -= utxoEntrySize(entry)
+				delete(c.entries, hash)
+				continue
+			}
+			entry.modified = false
+		}
+	}
+	c.lastFlush = time.Now()
+	c.flushedHeight = height
+	c.mtx.Unlock()
+}
+
+// flush writes all of the modified cached entries to the database and records
+// that the utxo set in the database is consistent with the block with the
+// provided hash and height.
+//
+// This function is safe for concurrent access.
+func (c *utxoCache) flush(hash *chainhash.Hash, height int64) error {
+	err := c.db.Update(func(dbTx database.Tx) error {
+		return c.dbFlush(dbTx, nil, hash, height)
+	})
+	if err != nil {
+		return err
+	}
+
+	c.markFlushed(height)
+	return nil
+}
+
+// lastFlushedHeight returns the height of the block the utxo set in the database
+// is consistent with.
+//
+// This function is safe for concurrent access.
+func (c *utxoCache) lastFlushedHeight() int64 {
+	c.mtx.Lock()
+	height := c.flushedHeight
+	c.mtx.Unlock()
+	return height
+}
+
+// initUtxoCache ensures the utxo set in the database is consistent with the
+// best chain state by replaying the effects of all blocks connected after the
+// last time the utxo cache was flushed.  This is the case after an unclean
+// shutdown.
+func (b *BlockChain) initUtxoCache() error {
+	var state *utxoSetState
+	err := b.db.View(func(dbTx database.Tx) error {
+		var err error
+		state, err = dbFetchUtxoSetState(dbTx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// The utxo set is always consistent with the best chain state in
+	// databases that predate the utxo cache, so simply record that.
+	tip := b.bestChain.Tip()
+	if state == nil {
+		return b.utxoCache.flush(&tip.hash, tip.height)
+	}
+
+	b.utxoCache.mtx.Lock()
+	b.utxoCache.flushedHeight = int64(state.height)
+	b.utxoCache.mtx.Unlock()
+	if state.hash == tip.hash {
+		return nil
+	}
+
+	// The utxo set is only ever flushed for blocks in the best chain and
+	// blocks are never disconnected without flushing it, so the block it is
+	// consistent with must be an ancestor of the current tip.
+	node := b.index.LookupNode(&state.hash)
+	if node == nil || !b.bestChain.Contains(node) {
+		return AssertError(fmt.Sprintf("initUtxoCache: utxo set state "+
+			"block %s (height %d) is not in the main chain", state.hash,
+			state.height))
+	}
+
+	log.Infof("Replaying %d blocks to bring the utxo set up to date with the "+
+		"best chain...", tip.height-node.height)
+	for n := b.bestChain.Next(node); n != nil; n = b.bestChain.Next(n) {
+		if interruptRequested(b.interrupt) {
+			return errInterruptRequested
+		}
+
+		block, err := b.fetchMainChainBlockByNode(n)
+		if err != nil {
+			return err
+		}
+		parent, err := b.fetchMainChainBlockByNode(n.parent)
+		if err != nil {
+			return err
+		}
+
+		view := NewUtxoViewpoint()
+		view.SetBestHash(&n.parent.hash)
+		if err := view.connectBlock(b.utxoCache, block, parent, nil); err != nil {
+			return err
+		}
+		b.utxoCache.commit(view)
+
+		if n != tip && b.utxoCache.needsFlush(nil) {
+			if err := b.utxoCache.flush(&n.hash, n.height); err != nil {
+				return err
+			}
+		}
+	}
+
+	return b.utxoCache.flush(&tip.hash, tip.height)
+}
+
+// FlushUtxoCache writes all of the modified entries in the utxo cache to the
+// database.  It should be called on shutdown to avoid having to replay the
+// most recent blocks on the next startup.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) FlushUtxoCache() error {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	tip := b.bestChain.Tip()
+	return b.utxoCache.flush(&tip.hash, tip.height)
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/database"
+	"github.com/decred/dcrd/txscript"
+)
+
+// TestUtxoCache ensures the utxo cache holds modified utxos in memory until it
+// is flushed, that the utxo set in the database is brought up to date with the
+// best chain when the cache was not flushed prior to shutdown, and that the
+// cache is flushed when blocks are disconnected.
+func TestUtxoCache(t *testing.T) {
+	// Create a test harness initialized with the genesis block as the tip
+	// and configure it with a utxo cache that is large enough to never need
+	// to be flushed due to its size.
+	params := &chaincfg.RegNetParams
+	g, teardownFunc := newChaingenHarness(t, params, "utxocachetest")
+	defer teardownFunc()
+	g.chain.utxoCache = newUtxoCache(g.chain.db, 100*1024*1024)
+
+	// fetchDBUtxoSet returns the utxo entry for the passed hash along with
+	// the utxo set state directly from the database.
+	fetchDBUtxoSet := func(hash *chainhash.Hash) (*UtxoEntry, *utxoSetState) {
+		t.Helper()
+
+		var entry *UtxoEntry
+		var state *utxoSetState
+		err := g.chain.db.View(func(dbTx database.Tx) error {
+			var err error
+			entry, err = dbFetchUtxoEntry(dbTx, hash)
+			if err != nil {
+				return err
+			}
+			state, err = dbFetchUtxoSetState(dbTx)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("failed to fetch utxo set from database: %v", err)
+		}
+		return entry, state
+	}
+
+	// Ensure the modified utxos are only available via the cache after
+	// connecting blocks without flushing it.
+	g.AdvanceToStakeValidationHeight()
+	tip := g.chain.BestSnapshot()
+	tipCoinbaseHash := g.Tip().Transactions[0].TxHash()
+	entry, err := g.chain.FetchUtxoEntry(&tipCoinbaseHash)
+	if err != nil {
+		t.Fatalf("failed to fetch utxo entry: %v", err)
+	}
+	if entry == nil {
+		t.Fatal("tip coinbase utxo entry is not available via the cache")
+	}
+	dbEntry, state := fetchDBUtxoSet(&tipCoinbaseHash)
+	if dbEntry != nil {
+		t.Fatal("tip coinbase utxo entry was written to the database " +
+			"before the cache was flushed")
+	}
+	if state.height != 0 || state.hash != *params.GenesisHash {
+		t.Fatalf("unexpected utxo set state -- got %s (height %d), want %s "+
+			"(height 0)", state.hash, state.height, params.GenesisHash)
+	}
+
+	// Simulate an unclean shutdown by creating a new chain instance with the
+	// same database without flushing the cache and ensure the utxo set in
+	// the database is brought up to date with the best chain.
+	chain, err := New(&Config{
+		DB:               g.chain.db,
+		ChainParams:      g.chain.chainParams,
+		TimeSource:       NewMedianTime(),
+		SigCache:         txscript.NewSigCache(1000),
+		UtxoCacheMaxSize: 100 * 1024 * 1024,
+	})
+	if err != nil {
+		t.Fatalf("failed to create chain instance: %v", err)
+	}
+	if height := chain.utxoCache.lastFlushedHeight(); height != tip.Height {
+		t.Fatalf("unexpected flushed height -- got %d, want %d", height,
+			tip.Height)
+	}
+	for hash, cachedEntry := range g.chain.utxoCache.entries {
+		dbEntry, _ := fetchDBUtxoSet(&hash)
+		if cachedEntry.IsFullySpent() {
+			if dbEntry != nil {
+				t.Fatalf("spent utxo entry %s is in the database", hash)
+			}
+			continue
+		}
+		if dbEntry == nil {
+			t.Fatalf("utxo entry %s is not in the database", hash)
+		}
+		want, err := serializeUtxoEntry(cachedEntry)
+		if err != nil {
+			t.Fatalf("failed to serialize utxo entry: %v", err)
+		}
+		got, err := serializeUtxoEntry(dbEntry)
+		if err != nil {
+			t.Fatalf("failed to serialize utxo entry: %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("mismatched utxo entry %s -- got %x, want %x", hash,
+				got, want)
+		}
+	}
+	_, state = fetchDBUtxoSet(&tipCoinbaseHash)
+	if state.hash != tip.Hash || int64(state.height) != tip.Height {
+		t.Fatalf("unexpected utxo set state -- got %s (height %d), want %s "+
+			"(height %d)", state.hash, state.height, tip.Hash, tip.Height)
+	}
+
+	// Continue with the new chain instance and create a block that will be
+	// reorganized away.
+	//
+	//   ... -> bsv# -> b1
+	g.chain = chain
+	svhTipName := g.TipName()
+	outs := g.OldestCoinbaseOuts()
+	g.NextBlock("b1", nil, outs[1:])
+	g.Accepted()
+	b1CoinbaseHash := g.Tip().Transactions[0].TxHash()
+
+	// Reorganize to a side chain and ensure the cache was flushed when the
+	// block was disconnected.
+	//
+	//   ... -> bsv# -> b1
+	//              \-> b1a -> b2a
+	g.SetTip(svhTipName)
+	g.NextBlock("b1a", nil, outs[1:])
+	g.AcceptedToSideChainWithExpectedTip("b1")
+	g.NextBlock("b2a", nil, nil)
+	g.Accepted()
+	g.ExpectTip("b2a")
+	if height := chain.utxoCache.lastFlushedHeight(); height != tip.Height {
+		t.Fatalf("unexpected flushed height -- got %d, want %d", height,
+			tip.Height)
+	}
+	_, state = fetchDBUtxoSet(&b1CoinbaseHash)
+	if state.hash != tip.Hash {
+		t.Fatalf("unexpected utxo set state -- got %s, want %s",
+			state.hash, tip.Hash)
+	}
+
+	// Ensure the utxos created by the disconnected block are no longer
+	// available while those created by the new tip are.
+	entry, err = chain.FetchUtxoEntry(&b1CoinbaseHash)
+	if err != nil {
+		t.Fatalf("failed to fetch utxo entry: %v", err)
+	}
+	if entry != nil {
+		t.Fatal("disconnected block coinbase utxo entry is still available")
+	}
+	tipCoinbaseHash = g.Tip().Transactions[0].TxHash()
+	entry, err = chain.FetchUtxoEntry(&tipCoinbaseHash)
+	if err != nil {
+		t.Fatalf("failed to fetch utxo entry: %v", err)
+	}
+	if entry == nil {
+		t.Fatal("tip coinbase utxo entry is not available via the cache")
+	}
+}
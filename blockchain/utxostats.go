@@ -0,0 +1,95 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"github.com/dchest/blake256"
+	"github.com/decred/dcrd/blockchain/internal/dbnamespace"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/wire"
+)
+
+// UtxoStats houses statistics about the utxo set as of a given block.
+type UtxoStats struct {
+	// Hash and Height identify the block the statistics are for.
+	Hash   chainhash.Hash
+	Height int64
+
+	// Transactions is the number of transactions with unspent outputs and
+	// Utxos is the total number of unspent outputs.
+	Transactions int64
+	Utxos        int64
+
+	// SerializedSize is the total size of the serialized keys and values of
+	// the utxo set entries in the database.
+	SerializedSize int64
+
+	// Total is the total amount of all unspent outputs in atoms.
+	Total int64
+
+	// SetHash is the BLAKE-256 hash of the serialized keys and length
+	// prefixed values of all utxo set entries in the order of their keys.
+	// Since the serialization is deterministic, nodes with the same utxo set
+	// produce the same hash.
+	SetHash chainhash.Hash
+}
+
+// FetchUtxoStats returns statistics about the utxo set as of the current best
+// block.  The utxo cache is flushed to the database and the entire utxo set in
+// the database is then iterated, so this is an expensive operation.
+//
+// Blocks can't be processed while the utxo cache is flushed, but they can while
+// the utxo set is iterated from a read-only view of the database.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) FetchUtxoStats() (*UtxoStats, error) {
+	b.chainLock.Lock()
+
+	// Ensure the utxo set in the database is consistent with the best
+	// chain.
+	tip := b.bestChain.Tip()
+	if err := b.utxoCache.flush(&tip.hash, tip.height); err != nil {
+		b.chainLock.Unlock()
+		return nil, err
+	}
+
+	// Start a read-only view of the database which is not affected by any
+	// blocks processed once the chain lock is released.
+	dbTx, err := b.db.Begin(false)
+	b.chainLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	defer dbTx.Rollback()
+
+	stats := UtxoStats{Hash: tip.hash, Height: tip.height}
+	hasher := blake256.New()
+	utxoBucket := dbTx.Metadata().Bucket(dbnamespace.UtxoSetBucketName)
+	cursor := utxoBucket.Cursor()
+	for ok := cursor.First(); ok; ok = cursor.Next() {
+		key, serialized := cursor.Key(), cursor.Value()
+		entry, err := deserializeUtxoEntry(serialized)
+		if err != nil {
+			return nil, err
+		}
+
+		stats.Transactions++
+		for _, out := range entry.sparseOutputs {
+			if out.spent {
+				continue
+			}
+			stats.Utxos++
+			stats.Total += out.amount
+		}
+		stats.SerializedSize += int64(len(key) + len(serialized))
+		hasher.Write(key)
+		if err := wire.WriteVarBytes(hasher, 0, serialized); err != nil {
+			return nil, err
+		}
+	}
+	copy(stats.SetHash[:], hasher.Sum(nil))
+
+	return &stats, nil
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg"
+)
+
+// TestFetchUtxoStats ensures the utxo set statistics reflect the best chain and
+// include the modifications that are only in the utxo cache.
+func TestFetchUtxoStats(t *testing.T) {
+	// Create a test harness initialized with the genesis block as the tip and
+	// generate enough blocks to reach stake validation height.  Use a utxo
+	// cache that is never flushed on its own.
+	params := &chaincfg.RegNetParams
+	g, teardownFunc := newChaingenHarness(t, params, "fetchutxostatstest")
+	defer teardownFunc()
+	g.chain.utxoCache.maxSize = 1 << 40
+	g.AdvanceToStakeValidationHeight()
+
+	stats, err := g.chain.FetchUtxoStats()
+	if err != nil {
+		t.Fatalf("failed to fetch utxo stats: %v", err)
+	}
+	tip := g.chain.BestSnapshot()
+	if stats.Hash != tip.Hash || stats.Height != tip.Height {
+		t.Fatalf("unexpected utxo stats block -- got %s (height %d), "+
+			"want %s (height %d)", stats.Hash, stats.Height, tip.Hash,
+			tip.Height)
+	}
+	if stats.Transactions == 0 || stats.Utxos < stats.Transactions ||
+		stats.Total <= 0 || stats.SerializedSize <= 0 {
+
+		t.Fatalf("unexpected utxo stats %+v", stats)
+	}
+
+	// Ensure the statistics are the same when nothing changed.
+	stats2, err := g.chain.FetchUtxoStats()
+	if err != nil {
+		t.Fatalf("failed to fetch utxo stats: %v", err)
+	}
+	if *stats2 != *stats {
+		t.Fatalf("mismatched utxo stats -- got %+v, want %+v", stats2,
+			stats)
+	}
+
+	// Ensure the statistics change once another block is connected.
+	outs := g.OldestCoinbaseOuts()
+	g.NextBlock("b1", nil, outs[1:])
+	g.Accepted()
+	stats2, err = g.chain.FetchUtxoStats()
+	if err != nil {
+		t.Fatalf("failed to fetch utxo stats: %v", err)
+	}
+	if stats2.Height != stats.Height+1 || stats2.SetHash == stats.SetHash {
+		t.Fatalf("utxo stats not updated -- got %+v, previous %+v",
+			stats2, stats)
+	}
+}
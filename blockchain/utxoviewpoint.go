@@ -534,10 +534,10 @@ func (view *UtxoViewpoint) disconnectStakeTransactions(block *dcrutil.Block, stx
 // restoring the outputs spent by it with the help of the provided spent txo
 // information.
 //func (view *UtxoViewpoint) disconnectDisapprovedBlock(db database.DB, block *dcrutil.Block, stxos []spentTxOut) error {
-func (view *UtxoViewpoint) disconnectDisapprovedBlock(db database.DB, block *dcrutil.Block) error {
+func (view *UtxoViewpoint) disconnectDisapprovedBlock(cache *utxoCache, block *dcrutil.Block) error {
 	// Load all of the spent txos for the block from the database spend journal.
 	var stxos []spentTxOut
-	err := db.View(func(dbTx database.Tx) error {
+	err := cache.db.View(func(dbTx database.Tx) error {
 		var err error
 		stxos, err = dbFetchSpendJournalEntry(dbTx, block)
 		return err
@@ -548,7 +548,7 @@ func (view *UtxoViewpoint) disconnectDisapprovedBlock(db database.DB, block *dcr
 
 	// Load all of the utxos referenced by the inputs for all transactions in
 	// the block that don't already exist in the utxo view from the database.
-	err = view.fetchRegularInputUtxos(db, block)
+	err = view.fetchRegularInputUtxos(cache, block)
 	if err != nil {
 		return err
 	}
@@ -578,11 +578,11 @@ func (view *UtxoViewpoint) disconnectDisapprovedBlock(db database.DB, block *dcr
 //
 // In addition, when the 'stxos' argument is not nil, it will be updated to
 // append an entry for each spent txout.
-func (view *UtxoViewpoint) connectBlock(db database.DB, block, parent *dcrutil.Block, stxos *[]spentTxOut) error {
+func (view *UtxoViewpoint) connectBlock(cache *utxoCache, block, parent *dcrutil.Block, stxos *[]spentTxOut) error {
 	// Disconnect the transactions in the regular tree of the parent block if
 	// the passed block disapproves it.
 	if !headerApprovesParent(&block.MsgBlock().Header) {
-		err := view.disconnectDisapprovedBlock(db, parent)
+		err := view.disconnectDisapprovedBlock(cache, parent)
 		if err != nil {
 			return err
 		}
@@ -590,7 +590,7 @@ func (view *UtxoViewpoint) connectBlock(db database.DB, block, parent *dcrutil.B
 
 	// Load all of the utxos referenced by the inputs for all transactions in
 	// the block that don't already exist in the utxo view from the database.
-	err := view.fetchInputUtxos(db, block)
+	err := view.fetchInputUtxos(cache, block)
 	if err != nil {
 		return err
 	}
@@ -636,7 +636,7 @@ func (view *UtxoViewpoint) connectBlock(db database.DB, block, parent *dcrutil.B
 // Note that, unlike block connection, the spent transaction output (stxo)
 // information is required and failure to provide it will result in an assertion
 // panic.
-func (view *UtxoViewpoint) disconnectBlock(db database.DB, block, parent *dcrutil.Block, stxos []spentTxOut) error {
+func (view *UtxoViewpoint) disconnectBlock(cache *utxoCache, block, parent *dcrutil.Block, stxos []spentTxOut) error {
 	// Sanity check the correct number of stxos are provided.
 	if len(stxos) != countSpentOutputs(block) {
 		panicf("provided %v stxos for block %v (height %v) which spends %v "+
@@ -646,7 +646,7 @@ func (view *UtxoViewpoint) disconnectBlock(db database.DB, block, parent *dcruti
 
 	// Load all of the utxos referenced by the inputs for all transactions in
 	// the block don't already exist in the utxo view from the database.
-	err := view.fetchInputUtxos(db, block)
+	err := view.fetchInputUtxos(cache, block)
 	if err != nil {
 		return err
 	}
@@ -669,7 +669,7 @@ func (view *UtxoViewpoint) disconnectBlock(db database.DB, block, parent *dcruti
 		// Load all of the utxos referenced by the inputs for all transactions
 		// in the regular tree of the parent block that don't already exist in
 		// the utxo view from the database.
-		err := view.fetchRegularInputUtxos(db, parent)
+		err := view.fetchRegularInputUtxos(cache, parent)
 		if err != nil {
 			return err
 		}
@@ -720,12 +720,13 @@ func (set viewFilteredSet) add(view *UtxoViewpoint, hash *chainhash.Hash) {
 
 // fetchUtxosMain fetches unspent transaction output data about the provided
 // set of transactions from the point of view of the end of the main chain at
-// the time of the call.
+// the time of the call.  The data is loaded from the utxo cache, which in turn
+// loads any entries it does not already have from the database.
 //
 // Upon completion of this function, the view will contain an entry for each
 // requested transaction.  Fully spent transactions, or those which otherwise
 // don't exist, will result in a nil entry in the view.
-func (view *UtxoViewpoint) fetchUtxosMain(db database.DB, filteredSet viewFilteredSet) error {
+func (view *UtxoViewpoint) fetchUtxosMain(cache *utxoCache, filteredSet viewFilteredSet) error {
 	// Nothing to do if there are no requested hashes.
 	if len(filteredSet) == 0 {
 		return nil
@@ -739,19 +740,7 @@ func (view *UtxoViewpoint) fetchUtxosMain(db database.DB, filteredSet viewFilter
 	// since other code uses the presence of an entry in the store as a way
 	// to optimize spend and unspend updates to apply only to the specific
 	// utxos that the caller needs access to.
-	return db.View(func(dbTx database.Tx) error {
-		for hash := range filteredSet {
-			hashCopy := hash
-			entry, err := dbFetchUtxoEntry(dbTx, &hashCopy)
-			if err != nil {
-				return err
-			}
-
-			view.entries[hash] = entry
-		}
-
-		return nil
-	})
+	return cache.fetchEntries(view, filteredSet)
 }
 
 // addRegularInputUtxos adds any outputs of transactions in the regular tree of
@@ -808,12 +797,12 @@ func (view *UtxoViewpoint) addRegularInputUtxos(block *dcrutil.Block) viewFilter
 // the view from the database as needed.  In particular, referenced entries that
 // are earlier in the block are added to the view and entries that are already
 // in the view are not modified.
-func (view *UtxoViewpoint) fetchRegularInputUtxos(db database.DB, block *dcrutil.Block) error {
+func (view *UtxoViewpoint) fetchRegularInputUtxos(cache *utxoCache, block *dcrutil.Block) error {
 	// Add any outputs of transactions in the regular tree of the block that are
 	// referenced by inputs of transactions that are located later in the tree
 	// and fetch any inputs that are not already in the view from the database.
 	filteredSet := view.addRegularInputUtxos(block)
-	return view.fetchUtxosMain(db, filteredSet)
+	return view.fetchUtxosMain(cache, filteredSet)
 }
 
 // fetchInputUtxos loads utxo details about the input transactions referenced
@@ -822,7 +811,7 @@ func (view *UtxoViewpoint) fetchRegularInputUtxos(db database.DB, block *dcrutil
 // referenced entries that are earlier in the regular tree of the block are
 // added to the view.  In all cases, entries that are already in the view are
 // not modified.
-func (view *UtxoViewpoint) fetchInputUtxos(db database.DB, block *dcrutil.Block) error {
+func (view *UtxoViewpoint) fetchInputUtxos(cache *utxoCache, block *dcrutil.Block) error {
 	// Add any outputs of transactions in the regular tree of the block that are
 	// referenced by inputs of transactions that are located later in the tree
 	// and, while doing so, determine which inputs are not already in the view
@@ -852,7 +841,7 @@ func (view *UtxoViewpoint) fetchInputUtxos(db database.DB, block *dcrutil.Block)
 	}
 
 	// Request the input utxos from the database.
-	return view.fetchUtxosMain(db, filteredSet)
+	return view.fetchUtxosMain(cache, filteredSet)
 }
 
 // clone returns a deep copy of the view.
@@ -917,7 +906,7 @@ func (b *BlockChain) FetchUtxoView(tx *dcrutil.Tx, includePrevRegularTxns bool)
 
 			// Disconnect the transactions in the regular tree of the parent
 			// block.
-			err = view.disconnectDisapprovedBlock(b.db, parent)
+			err = view.disconnectDisapprovedBlock(b.utxoCache, parent)
 			if err != nil {
 				b.disapprovedViewLock.Unlock()
 				return nil, err
@@ -951,7 +940,7 @@ func (b *BlockChain) FetchUtxoView(tx *dcrutil.Tx, includePrevRegularTxns bool)
 		}
 	}
 
-	err := view.fetchUtxosMain(b.db, filteredSet)
+	err := view.fetchUtxosMain(b.utxoCache, filteredSet)
 	return view, err
 }
 
@@ -969,15 +958,5 @@ func (b *BlockChain) FetchUtxoEntry(txHash *chainhash.Hash) (*UtxoEntry, error)
 	b.chainLock.RLock()
 	defer b.chainLock.RUnlock()
 
-	var entry *UtxoEntry
-	err := b.db.View(func(dbTx database.Tx) error {
-		var err error
-		entry, err = dbFetchUtxoEntry(dbTx, txHash)
-		return err
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	return entry, nil
+	return b.utxoCache.fetchEntry(txHash)
 }
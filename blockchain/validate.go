@@ -1193,6 +1193,101 @@ func (b *BlockChain) checkBlockHeaderContext(header *wire.BlockHeader, prevNode
 	return nil
 }
 
+// isHeaderStakeDiffAlgoActive returns whether or not the stake difficulty
+// algorithm agenda is active for the block after the passed node, which might
+// not have its block data available, along with whether or not that could be
+// determined.
+//
+// The state of the agenda is determined with deploymentState, which relies on
+// the votes and stake versions that are part of the block data.  So, it is
+// only evaluated for the passed node when its block data is available or the
+// state is defined by definition, and is otherwise evaluated for the most
+// recent ancestor on the main chain, all of which have their block data
+// available.  The state of the ancestor also applies to the passed node when
+// both are in the same rule change interval.  Otherwise, since the agenda
+// remains active forever once active, never activates once failed, and always
+// becomes active at the start of the next rule change interval once locked in,
+// those states also determine the state for the passed node.  The state can't
+// be determined while voting on the agenda is still in progress beyond the
+// main chain.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) isHeaderStakeDiffAlgoActive(prevNode *blockNode) (bool, bool, error) {
+	// The agenda is treated as active when voting on it is not enabled for
+	// the current network.
+	const deploymentID = chaincfg.VoteIDSDiffAlgorithm
+	deploymentVer, ok := b.deploymentVers[deploymentID]
+	if !ok {
+		return true, true, nil
+	}
+
+	interval := int64(b.chainParams.RuleChangeActivationInterval)
+	svh := b.chainParams.StakeValidationHeight
+	stateNode := prevNode
+	if !b.index.NodeStatus(prevNode).HaveData() &&
+		prevNode.height+1 >= svh+interval {
+
+		stateNode = b.bestChain.FindFork(prevNode)
+	}
+	state, err := b.deploymentState(stateNode, deploymentVer, deploymentID)
+	if err != nil {
+		return false, false, err
+	}
+	sameInterval := calcWantHeight(svh, interval, prevNode.height+1) ==
+		calcWantHeight(svh, interval, stateNode.height+1)
+	switch {
+	case state.State == ThresholdActive:
+		return true, true, nil
+
+	case state.State == ThresholdFailed || sameInterval:
+		return false, true, nil
+
+	case state.State == ThresholdLockedIn:
+		return true, true, nil
+	}
+
+	return false, false, nil
+}
+
+// checkBlockHeaderStakeDifficulty ensures the stake difficulty specified in the
+// block header matches the difficulty calculated by the stake difficulty
+// retarget algorithm required by the stake difficulty algorithm agenda based on
+// the previous block.
+//
+// This is used to validate headers without having the full block data for all
+// of their ancestors available.  The state of the agenda is determined as
+// described by isHeaderStakeDiffAlgoActive.  When it can't be determined, the
+// stake difficulty is not checked here and is instead checked by
+// checkBlockHeaderContext once the block data is available.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) checkBlockHeaderStakeDifficulty(header *wire.BlockHeader, prevNode *blockNode) error {
+	// The genesis block is valid by definition.
+	if prevNode == nil {
+		return nil
+	}
+
+	isActive, known, err := b.isHeaderStakeDiffAlgoActive(prevNode)
+	if err != nil || !known {
+		return err
+	}
+	var expSDiff int64
+	if isActive {
+		expSDiff, err = b.calcNextRequiredStakeDifficultyV2(prevNode)
+	} else {
+		expSDiff, err = b.calcNextRequiredStakeDifficultyV1(prevNode)
+	}
+	if err != nil {
+		return err
+	}
+	if header.SBits != expSDiff {
+		errStr := fmt.Sprintf("block stake difficulty of %d is not the "+
+			"expected value of %d", header.SBits, expSDiff)
+		return ruleError(ErrUnexpectedDifficulty, errStr)
+	}
+	return nil
+}
+
 // checkCoinbaseUniqueHeight checks to ensure that for all blocks height > 1 the
 // coinbase contains the height encoding to make coinbase hash collisions
 // impossible.
@@ -1430,7 +1525,7 @@ func (b *BlockChain) checkDupTxs(txSet []*dcrutil.Tx, view *UtxoViewpoint) error
 	for _, tx := range txSet {
 		filteredSet.add(view, tx.Hash())
 	}
-	err := view.fetchUtxosMain(b.db, filteredSet)
+	err := view.fetchUtxosMain(b.utxoCache, filteredSet)
 	if err != nil {
 		return err
 	}
@@ -2461,7 +2556,7 @@ func (b *BlockChain) createLegacySeqLockView(block, parent *dcrutil.Block, view
 	// tree of the parent block and the parent block outputs are available in
 	// the legacy view so long as it has not been disapproved.
 	if headerApprovesParent(&block.MsgBlock().Header) {
-		err := seqLockView.fetchRegularInputUtxos(b.db, parent)
+		err := seqLockView.fetchRegularInputUtxos(b.utxoCache, parent)
 		if err != nil {
 			return nil, err
 		}
@@ -2488,7 +2583,7 @@ func (b *BlockChain) createLegacySeqLockView(block, parent *dcrutil.Block, view
 			filteredSet.add(seqLockView, originHash)
 		}
 	}
-	err := seqLockView.fetchUtxosMain(b.db, filteredSet)
+	err := seqLockView.fetchUtxosMain(b.utxoCache, filteredSet)
 	if err != nil {
 		return nil, err
 	}
@@ -2889,6 +2984,14 @@ func (b *BlockChain) checkConnectBlock(node *blockNode, block, parent *dcrutil.B
 	if checkpoint != nil && node.height <= checkpoint.Height {
 		runScripts = false
 	}
+
+	// Similarly, don't run scripts if this node is an ancestor of the block
+	// that is assumed to be valid and that block is part of the best known
+	// header chain.  All other checks, including those related to stake and
+	// votes, are still performed.
+	if runScripts && b.isAssumedValid(node) {
+		runScripts = false
+	}
 	var scriptFlags txscript.ScriptFlags
 	if runScripts {
 		var err error
@@ -2922,7 +3025,7 @@ func (b *BlockChain) checkConnectBlock(node *blockNode, block, parent *dcrutil.B
 	// Disconnect all of the transactions in the regular transaction tree of
 	// the parent if the block being checked votes against it.
 	if node.height > 1 && !voteBitsApproveParent(node.voteBits) {
-		err := view.disconnectDisapprovedBlock(b.db, parent)
+		err := view.disconnectDisapprovedBlock(b.utxoCache, parent)
 		if err != nil {
 			return err
 		}
@@ -2941,7 +3044,7 @@ func (b *BlockChain) checkConnectBlock(node *blockNode, block, parent *dcrutil.B
 	//
 	// These utxo entries are needed for verification of things such as
 	// transaction inputs, counting pay-to-script-hashes, and scripts.
-	err = view.fetchInputUtxos(b.db, block)
+	err = view.fetchInputUtxos(b.utxoCache, block)
 	if err != nil {
 		return err
 	}
@@ -3164,7 +3267,7 @@ func (b *BlockChain) CheckConnectBlockTemplate(block *dcrutil.Block) error {
 	// Update the view to unspend all of the spent txos and remove the utxos
 	// created by the tip block.  Also, if the block votes against its parent,
 	// reconnect all of the regular transactions.
-	err = view.disconnectBlock(b.db, tipBlock, parent, stxos)
+	err = view.disconnectBlock(b.utxoCache, tipBlock, parent, stxos)
 	if err != nil {
 		return err
 	}
@@ -572,6 +572,176 @@ func TestCheckBlockHeaderContext(t *testing.T) {
 	}
 }
 
+// TestCheckBlockHeaderStakeDifficulty ensures the stake difficulty of headers
+// is required to follow the stake difficulty algorithm selected by the state
+// of the stake difficulty algorithm agenda both when the block data of their
+// ancestors is available and when it is not.
+func TestCheckBlockHeaderStakeDifficulty(t *testing.T) {
+	// Find the deployment of the stake difficulty algorithm agenda along with
+	// the yes vote choice within it.
+	params := &chaincfg.RegNetParams
+	deploymentVer, deployment, err := findDeployment(params,
+		chaincfg.VoteIDSDiffAlgorithm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	yesChoice, err := findDeploymentChoice(deployment, "yes")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The agenda is locked in for the blocks in the third rule change
+	// interval after stake validation height and active after that.
+	bc := newFakeChain(params)
+	svh := params.StakeValidationHeight
+	interval := int64(params.RuleChangeActivationInterval)
+	windowSize := params.StakeDiffWindowSize
+	lockedInHeight := svh + 2*interval
+	activeHeight := svh + 3*interval
+
+	// extend adds the passed number of nodes that vote yes on the agenda to
+	// the passed node and returns the new tip.  Nodes with block data use the
+	// stake difficulty required by the agenda and nodes without block data
+	// use the passed algorithm.
+	timestamp := bc.bestChain.Tip().timestamp
+	extend := func(tip *blockNode, numNodes int64, haveData, useV2 bool) *blockNode {
+		t.Helper()
+
+		for i := int64(0); i < numNodes; i++ {
+			var sdiff int64
+			var err error
+			switch {
+			case haveData:
+				sdiff, err = bc.calcNextRequiredStakeDifficulty(tip)
+			case useV2:
+				sdiff, err = bc.calcNextRequiredStakeDifficultyV2(tip)
+			default:
+				sdiff, err = bc.calcNextRequiredStakeDifficultyV1(tip)
+			}
+			if err != nil {
+				t.Fatalf("failed to calculate stake difficulty: %v", err)
+			}
+			timestamp++
+			node := newFakeNode(tip, int32(deploymentVer), deploymentVer,
+				tip.bits, time.Unix(timestamp, 0))
+			appendFakeVotes(node, params.TicketsPerBlock, deploymentVer,
+				yesChoice.Bits|0x01)
+			if node.height >= params.StakeEnabledHeight {
+				node.freshStake = params.MaxFreshStakePerBlock
+				node.poolSize = tip.poolSize +
+					uint32(params.MaxFreshStakePerBlock)
+			}
+			node.sbits = sdiff
+			if !haveData {
+				node.status = statusNone
+			}
+			bc.index.AddNode(node)
+			if haveData {
+				bc.bestChain.SetTip(node)
+			}
+			tip = node
+		}
+		return tip
+	}
+
+	// checkAlgorithms ensures headers that build on the passed tip are only
+	// accepted when they use the passed stake difficulty algorithm.  Headers
+	// using either algorithm are accepted when checkBoth is set since their
+	// stake difficulty is not checked when the state of the agenda can't be
+	// determined.
+	const checkV1, checkV2, checkBoth = 1, 2, 3
+	checkAlgorithms := func(tip *blockNode, allowed int) {
+		t.Helper()
+
+		sdiffV1, err := bc.calcNextRequiredStakeDifficultyV1(tip)
+		if err != nil {
+			t.Fatalf("failed to calculate stake difficulty: %v", err)
+		}
+		sdiffV2, err := bc.calcNextRequiredStakeDifficultyV2(tip)
+		if err != nil {
+			t.Fatalf("failed to calculate stake difficulty: %v", err)
+		}
+		if sdiffV1 == sdiffV2 {
+			t.Fatalf("algorithms produce the same stake difficulty %d "+
+				"for height %d", sdiffV1, tip.height+1)
+		}
+		tests := []struct {
+			name  string
+			sdiff int64
+			allow bool
+		}{
+			{"original", sdiffV1, allowed&checkV1 != 0},
+			{"new", sdiffV2, allowed&checkV2 != 0},
+		}
+		for _, test := range tests {
+			header := wire.BlockHeader{
+				PrevBlock: tip.hash,
+				Height:    uint32(tip.height + 1),
+				SBits:     test.sdiff,
+			}
+			err := bc.checkBlockHeaderStakeDifficulty(&header, tip)
+			if test.allow && err != nil {
+				t.Fatalf("%s algorithm at height %d rejected: %v",
+					test.name, header.Height, err)
+			}
+			if !test.allow {
+				rerr, ok := err.(RuleError)
+				if !ok || rerr.ErrorCode != ErrUnexpectedDifficulty {
+					t.Fatalf("%s algorithm at height %d not "+
+						"rejected as expected: %v", test.name,
+						header.Height, err)
+				}
+			}
+		}
+	}
+
+	// Ensure only the original algorithm is allowed for the start of the
+	// last stake difficulty window before the agenda activates when the
+	// block data is available.
+	tip := bc.bestChain.Tip()
+	tip = extend(tip, activeHeight-1-windowSize-tip.height, true, false)
+	checkAlgorithms(tip, checkV1)
+
+	// Ensure only the new algorithm is allowed once the agenda is active
+	// when the block data is available.
+	tip = extend(tip, windowSize, true, false)
+	checkAlgorithms(tip, checkV2)
+
+	// Ensure the algorithm is determined from the main chain for headers
+	// without block data on a branch from a block where the agenda is locked
+	// in.  Only the original algorithm is allowed within the same rule change
+	// interval and only the new algorithm is allowed after it.
+	forkNode := tip.Ancestor(lockedInHeight + windowSize - 1)
+	branchTip := extend(forkNode, activeHeight-1-windowSize-forkNode.height,
+		false, false)
+	checkAlgorithms(branchTip, checkV1)
+	branchTip = extend(branchTip, windowSize, false, false)
+	checkAlgorithms(branchTip, checkV2)
+
+	// Ensure only the new algorithm is allowed for headers without block
+	// data on a branch from a block where the agenda is active.
+	branchTip = extend(tip, 2*interval, false, true)
+	checkAlgorithms(branchTip, checkV2)
+
+	// Ensure only the original algorithm is allowed for headers without
+	// block data on a branch from a block where voting on the agenda is still
+	// in progress within the same rule change interval, while the stake
+	// difficulty is not checked after it since the state of the agenda
+	// depends on the votes of the blocks without block data.
+	forkNode = tip.Ancestor(lockedInHeight - interval + windowSize - 1)
+	branchTip = extend(forkNode, lockedInHeight-1-windowSize-forkNode.height,
+		false, false)
+	checkAlgorithms(branchTip, checkV1)
+	branchTip = extend(branchTip, windowSize, false, false)
+	checkAlgorithms(branchTip, checkBoth)
+
+	// Ensure only the new algorithm is allowed when voting on it is not
+	// enabled.
+	delete(bc.deploymentVers, chaincfg.VoteIDSDiffAlgorithm)
+	branchTip = extend(branchTip, windowSize, false, true)
+	checkAlgorithms(branchTip, checkV2)
+}
+
 // TestTxValidationErrors ensures certain malformed freestanding transactions
 // are rejected as as expected.
 func TestTxValidationErrors(t *testing.T) {
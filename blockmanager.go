@@ -9,6 +9,7 @@ import (
 	"container/list"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
 	"path/filepath"
@@ -27,10 +28,53 @@ import (
 )
 
 const (
-	// minInFlightBlocks is the minimum number of blocks that should be
-	// in the request queue for headers-first mode before requesting
-	// more.
-	minInFlightBlocks = 10
+	// maxInFlightBlocksPerPeer is the maximum number of blocks that may be
+	// requested from a single peer at once while downloading the blocks
+	// described by the headers in headers-first mode.
+	maxInFlightBlocksPerPeer = 16
+
+	// blockDownloadWindow is the maximum number of blocks past the current
+	// best chain tip that may be requested in headers-first mode.  Blocks
+	// are requested from multiple peers concurrently within the window and
+	// processed in order as they arrive.
+	blockDownloadWindow = 1024
+
+	// maxHeaderListLen is the maximum number of headers of blocks that have
+	// not been processed yet that are kept in headers-first mode.  More
+	// headers are only requested from the sync peer once enough of the
+	// blocks have been processed to make room for a full headers message.
+	maxHeaderListLen = 8 * blockDownloadWindow
+
+	// maxBufferedBlockBytes is the maximum total serialized size of the
+	// blocks received in headers-first mode that are held until all of the
+	// blocks before them have been processed.  Only the blocks before the
+	// first held block are requested once it is reached.
+	maxBufferedBlockBytes = 64 * 1024 * 1024
+
+	// blockStallTimeout is the amount of time a peer is given to deliver a
+	// block requested in headers-first mode before it is considered to be
+	// stalling and the request is assigned to another peer.
+	blockStallTimeout = 30 * time.Second
+
+	// blockStallCheckInterval is the interval at which the outstanding
+	// block requests in headers-first mode are checked for stalls.
+	blockStallCheckInterval = 5 * time.Second
+
+	// syncPeerCheckInterval is the interval at which the block download
+	// rates of the sync candidate peers are sampled and the sync peer is
+	// checked for being significantly slower than the other candidates in
+	// headers-first mode.
+	syncPeerCheckInterval = 30 * time.Second
+
+	// minSyncPeerRateSamples is the minimum number of block download rate
+	// samples a peer must have before its rate is compared with that of the
+	// other sync candidates.
+	minSyncPeerRateSamples = 3
+
+	// syncPeerSlowRatio is the factor by which another sync candidate must
+	// download blocks faster than the sync peer in headers-first mode for
+	// it to replace the sync peer.
+	syncPeerSlowRatio = 3
 
 	// blockDbNamePrefix is the prefix for the block database name.  The
 	// database type is appended to this value to form the full block
@@ -98,6 +142,20 @@ type headersMsg struct {
 	peer    *serverPeer
 }
 
+// cmpctBlockMsg packages a Decred cmpctblock message and the peer it came from
+// together so the block handler has access to that information.
+type cmpctBlockMsg struct {
+	cmpctBlock *wire.MsgCmpctBlock
+	peer       *serverPeer
+}
+
+// blockTxnMsg packages a Decred blocktxn message and the peer it came from
+// together so the block handler has access to that information.
+type blockTxnMsg struct {
+	blockTxn *wire.MsgBlockTxn
+	peer     *serverPeer
+}
+
 // donePeerMsg signifies a newly disconnected peer to the block handler.
 type donePeerMsg struct {
 	peer *serverPeer
@@ -116,6 +174,12 @@ type getSyncPeerMsg struct {
 	reply chan *serverPeer
 }
 
+// getSyncInfoMsg is a message type to be sent across the message channel for
+// retrieving information about the chain sync.
+type getSyncInfoMsg struct {
+	reply chan *syncInfo
+}
+
 // requestFromPeerMsg is a message type to be sent across the message channel
 // for requesting either blocks or transactions from a given peer. It routes
 // this through the block manager so the block manager doesn't ban the peer
@@ -235,6 +299,24 @@ type processTransactionMsg struct {
 	reply         chan processTransactionResponse
 }
 
+// processPackageResponse is a response sent to the reply channel of a
+// processPackageMsg.
+type processPackageResponse struct {
+	acceptedTxs []*dcrutil.Tx
+	txErrs      []error
+	err         error
+}
+
+// processPackageMsg is a message type to be sent across the message channel
+// for requesting a package of transactions to be processed through the block
+// manager.
+type processPackageMsg struct {
+	txns          []*dcrutil.Tx
+	rateLimit     bool
+	allowHighFees bool
+	reply         chan processPackageResponse
+}
+
 // isCurrentMsg is a message type to be sent across the message channel for
 // requesting whether or not the block manager believes it is synced with
 // the currently connected peers.
@@ -289,11 +371,42 @@ type setParentTemplateMsg struct {
 type setParentTemplateResponse struct {
 }
 
+// syncPeerInfo describes the block download state of a sync candidate peer.
+type syncPeerInfo struct {
+	peer           *serverPeer
+	blocksInFlight int
+	downloadRate   float64
+	lastBlockStall time.Time
+}
+
+// syncInfo describes the state of the chain sync along with the block download
+// state of the sync candidate peers.
+type syncInfo struct {
+	syncPeer           *serverPeer
+	syncHeight         int64
+	headersFirstMode   bool
+	syncPeerSwitches   uint32
+	lastSyncPeerSwitch time.Time
+	peers              []syncPeerInfo
+}
+
 // headerNode is used as a node in a list of headers that are linked together
-// between checkpoints.
+// ahead of the main chain in headers-first mode.
 type headerNode struct {
 	height int64
 	hash   *chainhash.Hash
+
+	// requestedFrom is the peer the associated block is currently requested
+	// from and requestTime is when the request was made.  requestedFrom is
+	// nil when the block is not in flight.
+	requestedFrom *serverPeer
+	requestTime   time.Time
+
+	// block is the associated block once it has been received along with
+	// the peer that sent it.  Blocks are held until all of the blocks
+	// before them have been processed.
+	block     *dcrutil.Block
+	blockPeer *serverPeer
 }
 
 // blockManager provides a concurrency safe block manager for handling all
@@ -310,15 +423,34 @@ type blockManager struct {
 	requestedEverBlocks map[chainhash.Hash]uint8
 	progressLogger      *blockProgressLogger
 	syncPeer            *serverPeer
+	candidatePeers      *list.List
 	msgChan             chan interface{}
 	wg                  sync.WaitGroup
 	quit                chan struct{}
 
-	// The following fields are used for headers-first mode.
-	headersFirstMode bool
-	headerList       *list.List
-	startHeader      *list.Element
-	nextCheckpoint   *chaincfg.Checkpoint
+	// The following fields are used for headers-first mode.  The header list
+	// contains the headers of the blocks that have not been processed yet
+	// in order and the index maps their hashes to their list elements.
+	// lastHeader is the newest header known to link to the chain and is
+	// used to verify the next downloaded header connects properly.
+	// headersPaused is set when requesting more headers is deferred because
+	// the header list is full and bufferedBlockBytes is the total size of
+	// the received blocks held in the header list.
+	headersFirstMode   bool
+	headersSynced      bool
+	headersPaused      bool
+	headerList         *list.List
+	headerIndex        map[chainhash.Hash]*list.Element
+	lastHeader         *headerNode
+	nextCheckpoint     *chaincfg.Checkpoint
+	fastAddHeight      int64
+	bufferedBlockBytes int64
+
+	// syncPeerSwitches is the number of times the sync peer was replaced
+	// for being significantly slower than another candidate and
+	// lastSyncPeerSwitch is when that last happened.
+	syncPeerSwitches   uint32
+	lastSyncPeerSwitch time.Time
 
 	// lotteryDataBroadcastMutex is a mutex protecting the map
 	// that checks if block lottery data has been broadcasted
@@ -339,22 +471,30 @@ type blockManager struct {
 	// peers.
 	syncHeightMtx sync.Mutex
 	syncHeight    int64
+
+	// historyValidator validates the history leading up to the chain state
+	// snapshot the chain was loaded from when it has not been validated yet.
+	// It is only accessed by the block handler.
+	historyValidator *historyValidator
 }
 
 // resetHeaderState sets the headers-first mode state to values appropriate for
 // syncing from a new peer.
 func (b *blockManager) resetHeaderState(newestHash *chainhash.Hash, newestHeight int64) {
 	b.headersFirstMode = false
+	b.headersSynced = false
+	b.headersPaused = false
 	b.headerList.Init()
-	b.startHeader = nil
-
-	// When there is a next checkpoint, add an entry for the latest known
-	// block into the header pool.  This allows the next downloaded header
-	// to prove it links to the chain properly.
-	if b.nextCheckpoint != nil {
-		node := headerNode{height: newestHeight, hash: newestHash}
-		b.headerList.PushBack(&node)
+	b.headerIndex = make(map[chainhash.Hash]*list.Element)
+	b.fastAddHeight = 0
+	b.bufferedBlockBytes = 0
+	if !cfg.DisableCheckpoints {
+		b.nextCheckpoint = b.findNextHeaderCheckpoint(newestHeight)
 	}
+
+	// Keep track of the latest known block.  This allows the next
+	// downloaded header to prove it links to the chain properly.
+	b.lastHeader = &headerNode{height: newestHeight, hash: newestHash}
 }
 
 // SyncHeight returns latest known block being synced to.
@@ -408,6 +548,10 @@ func (b *blockManager) startSync(peers *list.List) {
 	}
 
 	best := b.chain.BestSnapshot()
+	bestHeight := best.Height
+	if cfg.HeadersOnly {
+		_, bestHeight = b.chain.BestHeader()
+	}
 	var bestPeer *serverPeer
 	var enext *list.Element
 	for e := peers.Front(); e != nil; e = enext {
@@ -420,7 +564,7 @@ func (b *blockManager) startSync(peers *list.List) {
 		// doesn't have a later block when it's equal, it will likely
 		// have one soon so it is a reasonable choice.  It also allows
 		// the case where both are at 0 such as during regression test.
-		if sp.LastBlock() < best.Height {
+		if sp.LastBlock() < bestHeight {
 			peers.Remove(e)
 			continue
 		}
@@ -451,28 +595,41 @@ func (b *blockManager) startSync(peers *list.List) {
 		bmgrLog.Infof("Syncing to block height %d from peer %v",
 			bestPeer.LastBlock(), bestPeer.Addr())
 
-		// When the current height is less than a known checkpoint we
-		// can use block headers to learn about which blocks comprise
-		// the chain up to the checkpoint and perform less validation
-		// for them.  This is possible since each header contains the
-		// hash of the previous header and a merkle root.  Therefore if
-		// we validate all of the received headers link together
-		// properly and the checkpoint hashes match, we can be sure the
-		// hashes for the blocks in between are accurate.  Further, once
-		// the full blocks are downloaded, the merkle root is computed
-		// and compared against the value in the header which proves the
-		// full block hasn't been tampered with.
+		// When the peer has blocks the local chain does not, use block
+		// headers to learn about which blocks comprise the chain up to
+		// the best block known to the peer before downloading them.
+		// This is possible since each header contains the hash of the
+		// previous header and a merkle root.  Therefore if we validate
+		// all of the received headers link together properly and have
+		// the required proof of work, we can be sure the hashes for the
+		// blocks in between are accurate.  Further, once the full blocks
+		// are downloaded, the merkle root is computed and compared
+		// against the value in the header which proves the full block
+		// hasn't been tampered with.
+		//
+		// Knowing the hashes of the blocks ahead of time allows them to
+		// be downloaded from multiple peers concurrently.  Blocks up to
+		// a verified checkpoint also undergo less validation.
 		//
-		// Once we have passed the final checkpoint, or checkpoints are
-		// disabled, use standard inv messages learn about the blocks
-		// and fully validate them.  Finally, regression test mode does
-		// not support the headers-first approach so do normal block
-		// downloads when in regression test mode.
-		if b.nextCheckpoint != nil &&
-			best.Height < b.nextCheckpoint.Height &&
-			!cfg.DisableCheckpoints {
-
-			err := bestPeer.PushGetHeadersMsg(locator, b.nextCheckpoint.Hash)
+		// Otherwise, use standard inv messages to learn about the blocks
+		// and fully validate them.
+		//
+		// Only the headers are ever downloaded in headers-only mode.
+		if cfg.HeadersOnly {
+			bestHeaderHash, _ := b.chain.BestHeader()
+			locator := b.chain.BlockLocatorFromHash(&bestHeaderHash)
+			err := b.requestHeadersOnly(bestPeer, locator)
+			if err != nil {
+				bmgrLog.Errorf("Failed to push getheadermsg for the "+
+					"latest headers: %v", err)
+				return
+			}
+			bmgrLog.Infof("Downloading headers for blocks %d to "+
+				"%d from peer %s", bestHeight+1,
+				bestPeer.LastBlock(), bestPeer.Addr())
+		} else if bestPeer.LastBlock() > best.Height {
+			b.resetHeaderState(&best.Hash, best.Height)
+			err := bestPeer.PushGetHeadersMsg(locator, &zeroHash)
 			if err != nil {
 				bmgrLog.Errorf("Failed to push getheadermsg for the "+
 					"latest blocks: %v", err)
@@ -481,7 +638,7 @@ func (b *blockManager) startSync(peers *list.List) {
 			b.headersFirstMode = true
 			bmgrLog.Infof("Downloading headers for blocks %d to "+
 				"%d from peer %s", best.Height+1,
-				b.nextCheckpoint.Height, bestPeer.Addr())
+				bestPeer.LastBlock(), bestPeer.Addr())
 		} else {
 			err := bestPeer.PushGetBlocksMsg(locator, &zeroHash)
 			if err != nil {
@@ -544,8 +701,16 @@ func (b *blockManager) handleNewPeerMsg(peers *list.List, sp *serverPeer) {
 	// Add the peer as a candidate to sync from.
 	peers.PushBack(sp)
 
-	// Start syncing by choosing the best candidate if needed.
+	// Start syncing by choosing the best candidate if needed.  Otherwise,
+	// when already downloading blocks in headers-first mode, make use of
+	// the new peer to download them.
 	b.startSync(peers)
+	if b.headersFirstMode && b.syncPeer != sp {
+		b.fetchHeaderBlocks()
+	}
+	if b.historyValidator != nil {
+		b.historyValidator.fetchBlocks()
+	}
 
 	// Grab the mining state from this peer after we're synced.
 	if !cfg.NoMiningStateSync {
@@ -582,15 +747,45 @@ func (b *blockManager) handleDonePeerMsg(peers *list.List, sp *serverPeer) {
 		delete(b.requestedBlocks, k)
 	}
 
+	// Request the history blocks that were in flight from the peer from
+	// the remaining peers.
+	if b.historyValidator != nil {
+		b.historyValidator.handleDonePeer(sp)
+	}
+
+	// Discard any block that was being reconstructed from a compact block
+	// sent by the peer.
+	sp.cmpctBlock = nil
+
+	// Assign any blocks that were being downloaded from the quitting peer
+	// in headers-first mode to the remaining peers while keeping the
+	// headers that were already downloaded.  When the quitting peer is the
+	// sync peer, the remaining headers are requested from the best of the
+	// remaining candidates instead.
+	if b.headersFirstMode {
+		b.releaseHeaderBlocks(sp)
+		if b.syncPeer == sp {
+			b.syncPeer = nil
+			newSyncPeer := b.headersFirstSyncCandidate(peers)
+			if newSyncPeer == nil {
+				best := b.chain.BestSnapshot()
+				b.resetHeaderState(&best.Hash, best.Height)
+				b.startSync(peers)
+				return
+			}
+			bmgrLog.Infof("Continuing headers-first sync from peer %s",
+				newSyncPeer)
+			b.setHeadersFirstSyncPeer(newSyncPeer)
+			return
+		}
+		b.fetchHeaderBlocks()
+		return
+	}
+
 	// Attempt to find a new peer to sync from if the quitting peer is the
-	// sync peer.  Also, reset the headers-first state if in headers-first
-	// mode so
+	// sync peer.
 	if b.syncPeer != nil && b.syncPeer == sp {
 		b.syncPeer = nil
-		if b.headersFirstMode {
-			best := b.chain.BestSnapshot()
-			b.resetHeaderState(&best.Hash, best.Height)
-		}
 		b.startSync(peers)
 	}
 }
@@ -655,6 +850,12 @@ func (b *blockManager) handleTxMsg(tmsg *txMsg) {
 		return
 	}
 
+	// Record the time the peer relayed a novel transaction so it is
+	// protected from inbound peer eviction.
+	if len(acceptedTxs) > 0 {
+		atomic.StoreInt64(&tmsg.peer.lastTxTime, time.Now().UnixNano())
+	}
+
 	b.server.AnnounceNewTransactions(acceptedTxs)
 }
 
@@ -857,6 +1058,12 @@ func (b *blockManager) checkBlockForHiddenVotes(block *dcrutil.Block) {
 
 // handleBlockMsg handles block messages from all peers.
 func (b *blockManager) handleBlockMsg(bmsg *blockMsg) {
+	// Hand blocks requested to validate the history leading up to the chain
+	// state snapshot to the history validator.
+	if b.historyValidator != nil && b.historyValidator.handleBlockMsg(bmsg) {
+		return
+	}
+
 	// If we didn't ask for this block then the peer is misbehaving.
 	blockHash := bmsg.block.Hash()
 	if _, exists := bmsg.peer.requestedBlocks[*blockHash]; !exists {
@@ -882,38 +1089,43 @@ func (b *blockManager) handleBlockMsg(bmsg *blockMsg) {
 		}
 	}
 
-	// When in headers-first mode, if the block matches the hash of the
-	// first header in the list of headers that are being fetched, it's
-	// eligible for less validation since the headers have already been
-	// verified to link together and are valid up to the next checkpoint.
-	// Also, remove the list entry for all blocks except the checkpoint
-	// since it is needed to verify the next round of headers links
-	// properly.
-	isCheckpointBlock := false
-	behaviorFlags := blockchain.BFNone
-	if b.headersFirstMode {
-		firstNodeEl := b.headerList.Front()
-		if firstNodeEl != nil {
-			firstNode := firstNodeEl.Value.(*headerNode)
-			if blockHash.IsEqual(firstNode.hash) {
-				behaviorFlags |= blockchain.BFFastAdd
-				if firstNode.hash.IsEqual(b.nextCheckpoint.Hash) {
-					isCheckpointBlock = true
-				} else {
-					b.headerList.Remove(firstNodeEl)
-				}
-			}
-		}
-	}
-
 	// Remove block from request maps. Either chain will know about it and
 	// so we shouldn't have any more instances of trying to fetch it, or we
 	// will fail the insert and thus we'll retry next time we get an inv.
 	delete(bmsg.peer.requestedBlocks, *blockHash)
 	delete(b.requestedBlocks, *blockHash)
 
+	// When in headers-first mode, blocks that match one of the headers
+	// being fetched are held until all of the blocks before them have
+	// been received so they are processed in order regardless of which
+	// peer delivered them first.
+	if b.headersFirstMode {
+		if e, ok := b.headerIndex[*blockHash]; ok {
+			node := e.Value.(*headerNode)
+			if node.block == nil {
+				blockSize := bmsg.block.MsgBlock().SerializeSize()
+				bmsg.peer.blockDownloadRate.AddBytes(blockSize)
+				node.requestedFrom = nil
+				node.block = bmsg.block
+				node.blockPeer = bmsg.peer
+				b.bufferedBlockBytes += int64(blockSize)
+				b.processHeaderBlocks()
+			}
+			return
+		}
+	}
+
+	b.processPeerBlock(bmsg, blockchain.BFNone)
+}
+
+// processPeerBlock processes the passed block received from a peer with the
+// provided behavior flags, requests the parents of orphans, and updates the
+// chain state and peer heights accordingly.  It returns whether or not the
+// block was accepted.
+func (b *blockManager) processPeerBlock(bmsg *blockMsg, behaviorFlags blockchain.BehaviorFlags) bool {
 	// Process the block to include validation, best chain selection, orphan
 	// handling, etc.
+	blockHash := bmsg.block.Hash()
 	forkLen, isOrphan, err := b.chain.ProcessBlock(bmsg.block,
 		behaviorFlags)
 	if err != nil {
@@ -938,7 +1150,7 @@ func (b *blockManager) handleBlockMsg(bmsg *blockMsg) {
 		code, reason := mempool.ErrToRejectErr(err)
 		bmsg.peer.PushRejectMsg(wire.CmdBlock, code, reason,
 			blockHash, false)
-		return
+		return false
 	}
 
 	// Meta-data about the new block this peer is reporting. We use this
@@ -982,6 +1194,10 @@ func (b *blockManager) handleBlockMsg(bmsg *blockMsg) {
 		// update the chain state.
 		b.progressLogger.logBlockHeight(bmsg.block)
 
+		// Record the time the peer relayed a novel block so it is
+		// protected from inbound peer eviction.
+		atomic.StoreInt64(&bmsg.peer.lastBlockTime, time.Now().UnixNano())
+
 		onMainChain := !isOrphan && forkLen == 0
 		if onMainChain {
 			// A new block is connected, however, this new block may have
@@ -1041,153 +1257,729 @@ func (b *blockManager) handleBlockMsg(bmsg *blockMsg) {
 		}
 	}
 
-	// Nothing more to do if we aren't in headers-first mode.
-	if !b.headersFirstMode {
+	return true
+}
+
+// processHeaderBlocks processes the blocks received in headers-first mode in
+// order until reaching one that has not been received yet and then requests
+// more blocks as needed.  Blocks up to the most recent verified checkpoint are
+// eligible for less validation since their headers have already been verified
+// to link together and lead to the checkpoint.
+//
+// Headers-first mode is exited once all of the blocks described by the headers
+// of the sync peer's best chain have been processed, or when one of the blocks
+// is rejected, and normal mode is used to request any remaining blocks.
+func (b *blockManager) processHeaderBlocks() {
+	for e := b.headerList.Front(); e != nil; e = b.headerList.Front() {
+		// Discard the headers of blocks that were added to the main
+		// chain by other means, such as via a compact block.
+		node := e.Value.(*headerNode)
+		if b.chain.MainChainHasBlock(node.hash) {
+			b.removeHeaderNode(e)
+			continue
+		}
+		if node.block == nil {
+			break
+		}
+		b.removeHeaderNode(e)
+
+		behaviorFlags := blockchain.BFNone
+		if node.height <= b.fastAddHeight {
+			behaviorFlags |= blockchain.BFFastAdd
+		}
+		bmsg := blockMsg{block: node.block, peer: node.blockPeer}
+		if !b.processPeerBlock(&bmsg, behaviorFlags) {
+			bmgrLog.Warnf("Block %v at height %d described by the "+
+				"downloaded headers was rejected -- switching to "+
+				"normal mode", node.hash, node.height)
+			b.exitHeadersFirstMode()
+			return
+		}
+	}
+
+	if b.headersSynced && b.headerList.Len() == 0 {
+		bmgrLog.Infof("Processed the blocks for all downloaded headers " +
+			"-- switching to normal mode")
+		b.exitHeadersFirstMode()
 		return
 	}
 
-	// This is headers-first mode, so if the block is not a checkpoint
-	// request more blocks using the header list when the request queue is
-	// getting short.
-	if !isCheckpointBlock {
-		if b.startHeader != nil &&
-			len(bmsg.peer.requestedBlocks) < minInFlightBlocks {
-			b.fetchHeaderBlocks()
-		}
+	// Resume requesting headers once there is room for them.
+	if b.headersPaused {
+		b.requestNextHeaders()
+	}
+
+	b.fetchHeaderBlocks()
+}
+
+// removeHeaderNode removes the passed element of the header list along with
+// its entry in the header index and no longer accounts for its block as being
+// held.
+func (b *blockManager) removeHeaderNode(e *list.Element) {
+	node := b.headerList.Remove(e).(*headerNode)
+	delete(b.headerIndex, *node.hash)
+	if node.block != nil {
+		b.bufferedBlockBytes -= int64(node.block.MsgBlock().SerializeSize())
+	}
+}
+
+// requestNextHeaders requests the headers after the latest downloaded header
+// from the sync peer in headers-first mode.  The request is deferred when the
+// header list does not have room for a full headers message until enough of
+// the blocks have been processed.
+func (b *blockManager) requestNextHeaders() {
+	if b.headerList.Len()+wire.MaxBlockHeadersPerMsg > maxHeaderListLen {
+		b.headersPaused = true
+		return
+	}
+	b.headersPaused = false
+	if b.syncPeer == nil {
 		return
 	}
 
-	// This is headers-first mode and the block is a checkpoint.  When
-	// there is a next checkpoint, get the next round of headers by asking
-	// for headers starting from the block after this one up to the next
-	// checkpoint.
-	prevHeight := b.nextCheckpoint.Height
-	prevHash := b.nextCheckpoint.Hash
-	b.nextCheckpoint = b.findNextHeaderCheckpoint(prevHeight)
-	if b.nextCheckpoint != nil {
-		locator := blockchain.BlockLocator([]*chainhash.Hash{prevHash})
-		err := bmsg.peer.PushGetHeadersMsg(locator, b.nextCheckpoint.Hash)
-		if err != nil {
-			bmgrLog.Warnf("Failed to send getheaders message to "+
-				"peer %s: %v", bmsg.peer.Addr(), err)
-			return
-		}
-		bmgrLog.Infof("Downloading headers for blocks %d to %d from "+
-			"peer %s", prevHeight+1, b.nextCheckpoint.Height,
-			b.syncPeer.Addr())
+	locator := blockchain.BlockLocator([]*chainhash.Hash{b.lastHeader.hash})
+	err := b.syncPeer.PushGetHeadersMsg(locator, &zeroHash)
+	if err != nil {
+		bmgrLog.Warnf("Failed to send getheaders message to peer %s: %v",
+			b.syncPeer.Addr(), err)
+	}
+}
+
+// exitHeadersFirstMode discards the headers-first mode state and switches to
+// normal mode by requesting blocks after the current best chain tip up to the
+// end of the chain (zero hash) from the sync peer.
+func (b *blockManager) exitHeadersFirstMode() {
+	best := b.chain.BestSnapshot()
+	b.resetHeaderState(&best.Hash, best.Height)
+	if b.syncPeer == nil {
 		return
 	}
 
-	// This is headers-first mode, the block is a checkpoint, and there are
-	// no more checkpoints, so switch to normal mode by requesting blocks
-	// from the block after this one up to the end of the chain (zero hash).
-	b.headersFirstMode = false
-	b.headerList.Init()
-	bmgrLog.Infof("Reached the final checkpoint -- switching to normal mode")
-	locator := blockchain.BlockLocator([]*chainhash.Hash{blockHash})
-	err = bmsg.peer.PushGetBlocksMsg(locator, &zeroHash)
+	locator, err := b.chain.LatestBlockLocator()
 	if err != nil {
-		bmgrLog.Warnf("Failed to send getblocks message to peer %s: %v",
-			bmsg.peer.Addr(), err)
+		bmgrLog.Errorf("Failed to get block locator for the latest "+
+			"block: %v", err)
 		return
 	}
+	err = b.syncPeer.PushGetBlocksMsg(locator, &zeroHash)
+	if err != nil {
+		bmgrLog.Warnf("Failed to send getblocks message to peer %s: %v",
+			b.syncPeer.Addr(), err)
+	}
 }
 
-// fetchHeaderBlocks creates and sends a request to the syncPeer for the next
-// list of blocks to be downloaded based on the current list of headers.
-func (b *blockManager) fetchHeaderBlocks() {
-	// Nothing to do if there is no start header.
-	if b.startHeader == nil {
-		bmgrLog.Warnf("fetchHeaderBlocks called with no start header")
+// requestFullBlock requests the full block with the passed hash from the peer
+// after an attempt to reconstruct it from a compact block failed.
+func (b *blockManager) requestFullBlock(sp *serverPeer, hash *chainhash.Hash) {
+	sp.cmpctBlock = nil
+	sp.requestedBlocks[*hash] = struct{}{}
+	if _, exists := b.requestedBlocks[*hash]; !exists {
+		b.requestedBlocks[*hash] = struct{}{}
+		b.requestedEverBlocks[*hash] = 0
+		b.limitMap(b.requestedBlocks, maxRequestedBlocks)
+	}
+
+	gdmsg := wire.NewMsgGetData()
+	gdmsg.AddInvVect(wire.NewInvVect(wire.InvTypeBlock, hash))
+	sp.QueueMessage(gdmsg, nil)
+}
+
+// processCmpctBlockState assembles the block that was reconstructed from a
+// compact block and processes it the same way as a full block received from
+// the peer.  The full block is requested instead when the reconstructed block
+// does not match its header.
+func (b *blockManager) processCmpctBlockState(sp *serverPeer, state *cmpctBlockState) {
+	msgBlock, err := state.msgBlock()
+	if err != nil {
+		bmgrLog.Debugf("Failed to reconstruct block %v from compact "+
+			"block sent by %s: %v -- requesting full block", state.hash,
+			sp, err)
+		b.requestFullBlock(sp, &state.hash)
 		return
 	}
 
-	// Build up a getdata request for the list of blocks the headers
-	// describe.  The size hint will be limited to wire.MaxInvPerMsg by
-	// the function, so no need to double check it here.
-	gdmsg := wire.NewMsgGetDataSizeHint(uint(b.headerList.Len()))
-	numRequested := 0
-	for e := b.startHeader; e != nil; e = e.Next() {
-		node, ok := e.Value.(*headerNode)
-		if !ok {
-			bmgrLog.Warn("Header list node type is not a headerNode")
+	// Treat the block as requested from the peer since the compact block
+	// was either requested or sent by a peer that was asked to announce
+	// blocks via compact blocks.
+	sp.requestedBlocks[state.hash] = struct{}{}
+	b.requestedBlocks[state.hash] = struct{}{}
+	b.handleBlockMsg(&blockMsg{block: dcrutil.NewBlock(msgBlock), peer: sp})
+}
+
+// handleCmpctBlockMsg handles compact block messages from all peers.  It
+// attempts to reconstruct the block from the transactions in the memory pool
+// and requests any missing transactions from the peer.  It falls back to
+// requesting the full block when the block can't be reconstructed.
+func (b *blockManager) handleCmpctBlockMsg(cmsg *cmpctBlockMsg) {
+	msg := cmsg.cmpctBlock
+	sp := cmsg.peer
+	blockHash := msg.Header.BlockHash()
+	_, requested := sp.requestedBlocks[blockHash]
+
+	// Compact blocks are only expected in response to a request or from
+	// peers that were asked to announce new blocks via compact blocks.
+	if !requested && !sp.cmpctHighBandwidth {
+		bmgrLog.Warnf("Got unrequested compact block %v from %s -- "+
+			"disconnecting", blockHash, sp.Addr())
+		sp.Disconnect()
+		return
+	}
+
+	// Ignore the compact block when the block is already known.
+	haveBlock, err := b.chain.HaveBlock(&blockHash)
+	if err != nil {
+		bmgrLog.Warnf("Unexpected failure when checking for existing "+
+			"block %v: %v", blockHash, err)
+		return
+	}
+	if haveBlock {
+		delete(sp.requestedBlocks, blockHash)
+		delete(b.requestedBlocks, blockHash)
+		return
+	}
+
+	// Ensure the header has the claimed proof of work to prevent peers from
+	// forcing reconstruction attempts of arbitrary compact blocks.
+	err = blockchain.CheckProofOfWork(&msg.Header,
+		b.server.chainParams.PowLimit)
+	if err != nil {
+		bmgrLog.Warnf("Got compact block %v with invalid proof of work "+
+			"from %s -- disconnecting", blockHash, sp.Addr())
+		sp.Disconnect()
+		return
+	}
+
+	// Compact blocks are only useful for blocks that build on known blocks
+	// while the chain is current since the memory pool is otherwise
+	// unlikely to contain the transactions.  Fall back to requesting the
+	// full block which also ensures any missing parents are requested via
+	// the normal orphan handling.
+	prevKnown, err := b.chain.HaveBlock(&msg.Header.PrevBlock)
+	if err != nil || !prevKnown || !b.current() || b.headersFirstMode {
+		b.requestFullBlock(sp, &blockHash)
+		return
+	}
+
+	// Attempt to reconstruct the block using the transactions in the memory
+	// pool, which include the votes, tickets, and revocations of the stake
+	// tree.
+	txDescs := b.server.txMemPool.TxDescs()
+	candidates := make([]*wire.MsgTx, 0, len(txDescs))
+	for _, txDesc := range txDescs {
+		candidates = append(candidates, txDesc.Tx.MsgTx())
+	}
+	state, err := newCmpctBlockState(msg, candidates)
+	if err != nil {
+		bmgrLog.Debugf("Unable to use compact block %v from %s: %v -- "+
+			"requesting full block", blockHash, sp, err)
+		b.requestFullBlock(sp, &blockHash)
+		return
+	}
+	if state.isComplete() {
+		bmgrLog.Debugf("Reconstructed block %v from compact block sent "+
+			"by %s", blockHash, sp)
+		b.processCmpctBlockState(sp, state)
+		return
+	}
+
+	// Request the missing transactions from the peer.
+	bmgrLog.Debugf("Requesting %d regular and %d stake transactions for "+
+		"compact block %v from %s", len(state.regular.missing),
+		len(state.stake.missing), blockHash, sp)
+	sp.cmpctBlock = state
+	sp.requestedBlocks[blockHash] = struct{}{}
+	if _, exists := b.requestedBlocks[blockHash]; !exists {
+		b.requestedBlocks[blockHash] = struct{}{}
+		b.requestedEverBlocks[blockHash] = 0
+		b.limitMap(b.requestedBlocks, maxRequestedBlocks)
+	}
+	sp.QueueMessage(state.getBlockTxnMsg(), nil)
+}
+
+// handleBlockTxnMsg handles blocktxn messages from all peers.  The provided
+// transactions are used to complete the block being reconstructed from a
+// compact block previously sent by the peer.
+func (b *blockManager) handleBlockTxnMsg(bmsg *blockTxnMsg) {
+	msg := bmsg.blockTxn
+	sp := bmsg.peer
+	state := sp.cmpctBlock
+	if state == nil || state.hash != msg.BlockHash {
+		bmgrLog.Warnf("Got unrequested block transactions for %v from "+
+			"%s -- disconnecting", msg.BlockHash, sp.Addr())
+		sp.Disconnect()
+		return
+	}
+	sp.cmpctBlock = nil
+
+	if err := state.addBlockTxns(msg); err != nil {
+		bmgrLog.Debugf("Invalid block transactions from %s: %v -- "+
+			"requesting full block", sp, err)
+		b.requestFullBlock(sp, &state.hash)
+		return
+	}
+	b.processCmpctBlockState(sp, state)
+}
+
+// nextDownloadPeer returns the sync candidate peer that should be assigned the
+// next request for a block at the passed height in headers-first mode.  Only
+// peers that claim to have the block and have fewer than the maximum number of
+// blocks in flight are considered.  Peers that have not stalled recently are
+// preferred and, among those, the peer with the fewest blocks in flight is
+// chosen so the requests are spread out.  It returns nil when there are no
+// suitable peers.
+func (b *blockManager) nextDownloadPeer(height int64, now time.Time) *serverPeer {
+	var bestPeer *serverPeer
+	var bestStalled bool
+	for e := b.candidatePeers.Front(); e != nil; e = e.Next() {
+		sp := e.Value.(*serverPeer)
+		if sp.LastBlock() < height ||
+			len(sp.requestedBlocks) >= maxInFlightBlocksPerPeer {
+
+			continue
+		}
+
+		stalled := now.Sub(sp.lastBlockStall) < blockStallTimeout
+		if bestPeer == nil || (bestStalled && !stalled) ||
+			(bestStalled == stalled &&
+				len(sp.requestedBlocks) < len(bestPeer.requestedBlocks)) {
+
+			bestPeer = sp
+			bestStalled = stalled
+		}
+	}
+	return bestPeer
+}
+
+// fetchHeaderBlocks creates and sends requests for the blocks described by the
+// list of headers that are within the download window and are not already in
+// flight.  The requests are spread among the sync candidate peers.  Only the
+// blocks before the first held block are requested when the held blocks have
+// reached the maximum total size.
+func (b *blockManager) fetchHeaderBlocks() {
+	// Build up getdata requests for each peer for the blocks the headers
+	// describe up to the end of the download window.
+	now := time.Now()
+	windowEnd := b.chain.BestSnapshot().Height + blockDownloadWindow
+	bufferFull := b.bufferedBlockBytes >= maxBufferedBlockBytes
+	gdmsgs := make(map[*serverPeer]*wire.MsgGetData)
+	for e := b.headerList.Front(); e != nil; e = e.Next() {
+		node := e.Value.(*headerNode)
+		if node.height > windowEnd {
+			break
+		}
+		if node.block != nil {
+			// The blocks after a held block can't be processed
+			// until the blocks before it are, so don't request
+			// any more of them when the held blocks are already
+			// using the maximum allowed space.
+			if bufferFull {
+				break
+			}
+			continue
+		}
+		if node.requestedFrom != nil {
 			continue
 		}
 
+		// Skip blocks that are already known orphans since they will
+		// be connected as soon as their parent is processed.
+		if b.chain.IsKnownOrphan(node.hash) {
+			continue
+		}
+
+		// Stop when none of the peers are able to accept more requests
+		// for the block.  Since the blocks are in order, none of them
+		// will be able to accept requests for later blocks either.
+		sp := b.nextDownloadPeer(node.height, now)
+		if sp == nil {
+			break
+		}
+
+		gdmsg, ok := gdmsgs[sp]
+		if !ok {
+			gdmsg = wire.NewMsgGetDataSizeHint(maxInFlightBlocksPerPeer)
+			gdmsgs[sp] = gdmsg
+		}
 		iv := wire.NewInvVect(wire.InvTypeBlock, node.hash)
-		haveInv, err := b.haveInventory(iv)
+		err := gdmsg.AddInvVect(iv)
 		if err != nil {
-			bmgrLog.Warnf("Unexpected failure when checking for "+
-				"existing inventory during header block "+
-				"fetch: %v", err)
+			bmgrLog.Warnf("Failed to add invvect while fetching "+
+				"block headers: %v", err)
 			continue
 		}
-		if !haveInv {
-			b.requestedBlocks[*node.hash] = struct{}{}
+		b.requestedBlocks[*node.hash] = struct{}{}
+		if _, ok := b.requestedEverBlocks[*node.hash]; !ok {
 			b.requestedEverBlocks[*node.hash] = 0
-			b.syncPeer.requestedBlocks[*node.hash] = struct{}{}
-			err = gdmsg.AddInvVect(iv)
-			if err != nil {
-				bmgrLog.Warnf("Failed to add invvect while fetching "+
-					"block headers: %v", err)
+		}
+		sp.requestedBlocks[*node.hash] = struct{}{}
+		node.requestedFrom = sp
+		node.requestTime = now
+	}
+	for sp, gdmsg := range gdmsgs {
+		sp.QueueMessage(gdmsg, nil)
+	}
+}
+
+// handleBlockStalls checks the blocks that are in flight in headers-first mode
+// for peers that have not delivered them within the stall timeout.  The blocks
+// requested from stalling peers are assigned to other peers and the stalling
+// peers are disconnected with the exception of the sync peer, which is still
+// needed to provide headers.
+func (b *blockManager) handleBlockStalls() {
+	if !b.headersFirstMode {
+		return
+	}
+
+	// Find the peers that have stalled.
+	now := time.Now()
+	stalledPeers := make(map[*serverPeer]struct{})
+	for e := b.headerList.Front(); e != nil; e = e.Next() {
+		node := e.Value.(*headerNode)
+		if node.requestedFrom == nil ||
+			now.Sub(node.requestTime) < blockStallTimeout {
+
+			continue
+		}
+		stalledPeers[node.requestedFrom] = struct{}{}
+	}
+	if len(stalledPeers) == 0 {
+		return
+	}
+
+	// Release all blocks in flight from the stalled peers so they are
+	// requested again.
+	for e := b.headerList.Front(); e != nil; e = e.Next() {
+		node := e.Value.(*headerNode)
+		if _, ok := stalledPeers[node.requestedFrom]; ok {
+			delete(node.requestedFrom.requestedBlocks, *node.hash)
+			node.requestedFrom = nil
+		}
+	}
+	for sp := range stalledPeers {
+		sp.lastBlockStall = now
+		if sp == b.syncPeer || b.candidatePeers.Len() <= 1 {
+			bmgrLog.Infof("Peer %s stalled while downloading blocks "+
+				"-- reassigning requests", sp)
+			continue
+		}
+		bmgrLog.Infof("Peer %s stalled while downloading blocks -- "+
+			"disconnecting", sp)
+		sp.Disconnect()
+	}
+
+	b.fetchHeaderBlocks()
+}
+
+// checkSyncPeer samples the block download rates of the sync candidate peers
+// and, when in headers-first mode, replaces the sync peer with the fastest
+// candidate when it is significantly faster than the sync peer.  This prevents
+// a sync peer that only trickles blocks from slowing down the initial chain
+// sync indefinitely.
+func (b *blockManager) checkSyncPeer() {
+	for e := b.candidatePeers.Front(); e != nil; e = e.Next() {
+		sp := e.Value.(*serverPeer)
+		busy := len(sp.requestedBlocks) > 0
+		sp.blockDownloadRate.Sample(syncPeerCheckInterval, busy)
+	}
+
+	if !b.headersFirstMode || b.syncPeer == nil {
+		return
+	}
+	syncRate := &b.syncPeer.blockDownloadRate
+	if syncRate.Samples() < minSyncPeerRateSamples {
+		return
+	}
+
+	// Find the fastest candidate that has the blocks the sync peer has.
+	var fastest *serverPeer
+	for e := b.candidatePeers.Front(); e != nil; e = e.Next() {
+		sp := e.Value.(*serverPeer)
+		if sp == b.syncPeer ||
+			sp.blockDownloadRate.Samples() < minSyncPeerRateSamples ||
+			sp.LastBlock() < b.syncPeer.LastBlock() {
+
+			continue
+		}
+		if fastest == nil || sp.blockDownloadRate.Rate() >
+			fastest.blockDownloadRate.Rate() {
+
+			fastest = sp
+		}
+	}
+	if fastest == nil || fastest.blockDownloadRate.Rate() <=
+		syncRate.Rate()*syncPeerSlowRatio {
+
+		return
+	}
+
+	bmgrLog.Infof("Sync peer %s is downloading blocks at %.0f bytes/s "+
+		"compared to %.0f bytes/s from peer %s -- switching sync peer",
+		b.syncPeer, syncRate.Rate(), fastest.blockDownloadRate.Rate(),
+		fastest)
+	b.switchSyncPeer(fastest)
+}
+
+// releaseHeaderBlocks releases all blocks in flight from the passed peer in
+// headers-first mode so they are requested again from the other peers.
+func (b *blockManager) releaseHeaderBlocks(sp *serverPeer) {
+	for e := b.headerList.Front(); e != nil; e = e.Next() {
+		node := e.Value.(*headerNode)
+		if node.requestedFrom == sp {
+			delete(sp.requestedBlocks, *node.hash)
+			node.requestedFrom = nil
+		}
+	}
+}
+
+// headersFirstSyncCandidate returns the sync candidate peer that is best
+// suited to continue an ongoing headers-first sync, or nil when none of the
+// peers know about blocks beyond the best chain.  Peers that have not stalled
+// recently are preferred.
+func (b *blockManager) headersFirstSyncCandidate(peers *list.List) *serverPeer {
+	now := time.Now()
+	bestHeight := b.chain.BestSnapshot().Height
+	var bestPeer *serverPeer
+	var bestStalled bool
+	for e := peers.Front(); e != nil; e = e.Next() {
+		sp := e.Value.(*serverPeer)
+		if sp.LastBlock() <= bestHeight {
+			continue
+		}
+
+		stalled := now.Sub(sp.lastBlockStall) < blockStallTimeout
+		if bestPeer == nil || (bestStalled && !stalled) ||
+			(bestStalled == stalled && sp.LastBlock() > bestPeer.LastBlock()) {
+
+			bestPeer = sp
+			bestStalled = stalled
+		}
+	}
+	return bestPeer
+}
+
+// setHeadersFirstSyncPeer makes the passed peer the sync peer in headers-first
+// mode while keeping the headers that were already downloaded.  The remaining
+// headers, if any, are requested from the new sync peer and the blocks within
+// the download window that are not in flight are requested from all peers.
+func (b *blockManager) setHeadersFirstSyncPeer(sp *serverPeer) {
+	b.syncPeer = sp
+	b.syncHeightMtx.Lock()
+	b.syncHeight = sp.LastBlock()
+	b.syncHeightMtx.Unlock()
+
+	if !b.headersSynced {
+		b.requestNextHeaders()
+	}
+
+	b.fetchHeaderBlocks()
+}
+
+// switchSyncPeer replaces the sync peer with the passed peer in headers-first
+// mode.  The blocks in flight from the previous sync peer are requested again
+// from the other peers and the remaining headers, if any, are requested from
+// the new sync peer.
+func (b *blockManager) switchSyncPeer(sp *serverPeer) {
+	now := time.Now()
+	prevSyncPeer := b.syncPeer
+	b.releaseHeaderBlocks(prevSyncPeer)
+
+	// Treat the previous sync peer as if it stalled so it is only assigned
+	// new block requests when no other peers are available.  Any headers it
+	// sends in response to an outstanding request are ignored.
+	prevSyncPeer.lastBlockStall = now
+	prevSyncPeer.syncPeerReplaced = !b.headersSynced
+
+	b.syncPeerSwitches++
+	b.lastSyncPeerSwitch = now
+	b.setHeadersFirstSyncPeer(sp)
+}
+
+// currentSyncInfo returns information about the state of the chain sync along
+// with the block download state of the sync candidate peers.
+func (b *blockManager) currentSyncInfo() *syncInfo {
+	info := &syncInfo{
+		syncPeer:           b.syncPeer,
+		syncHeight:         b.SyncHeight(),
+		headersFirstMode:   b.headersFirstMode,
+		syncPeerSwitches:   b.syncPeerSwitches,
+		lastSyncPeerSwitch: b.lastSyncPeerSwitch,
+		peers:              make([]syncPeerInfo, 0, b.candidatePeers.Len()),
+	}
+	for e := b.candidatePeers.Front(); e != nil; e = e.Next() {
+		sp := e.Value.(*serverPeer)
+		info.peers = append(info.peers, syncPeerInfo{
+			peer:           sp,
+			blocksInFlight: len(sp.requestedBlocks),
+			downloadRate:   sp.blockDownloadRate.Rate(),
+			lastBlockStall: sp.lastBlockStall,
+		})
+	}
+	return info
+}
+
+// requestHeadersOnly requests the headers after the passed block locator from
+// the passed peer in headers-only mode and records that the peer is expected to
+// respond with them.
+func (b *blockManager) requestHeadersOnly(sp *serverPeer, locator blockchain.BlockLocator) error {
+	if err := sp.PushGetHeadersMsg(locator, &zeroHash); err != nil {
+		return err
+	}
+	sp.headersRequested = true
+	return nil
+}
+
+// handleHeadersOnlyMsg handles headers messages from all peers when running in
+// headers-only mode.  Each header is validated against the headers of its
+// ancestors and added to the block index without any block data.  Unlike
+// headers-first mode, headers are also requested from peers other than the sync
+// peer when they announce new blocks.
+func (b *blockManager) handleHeadersOnlyMsg(hmsg *headersMsg) {
+	// The remote peer is misbehaving if we didn't request headers from it.
+	msg := hmsg.headers
+	numHeaders := len(msg.Headers)
+	if !hmsg.peer.headersRequested {
+		bmgrLog.Warnf("Got %d unrequested headers from %s -- "+
+			"disconnecting", numHeaders, hmsg.peer.Addr())
+		hmsg.peer.Disconnect()
+		return
+	}
+	hmsg.peer.headersRequested = false
+	if numHeaders == 0 {
+		return
+	}
+
+	for _, blockHeader := range msg.Headers {
+		err := b.chain.ProcessBlockHeader(blockHeader, blockchain.BFNone)
+		if err != nil {
+			// Request the headers that connect the announced ones to
+			// the best known header when they don't connect to any
+			// known header since the peer is likely announcing new
+			// blocks on a chain that is not known yet.
+			if rErr, ok := err.(blockchain.RuleError); ok &&
+				rErr.ErrorCode == blockchain.ErrMissingParent {
+
+				bestHeaderHash, _ := b.chain.BestHeader()
+				locator := b.chain.BlockLocatorFromHash(&bestHeaderHash)
+				err := b.requestHeadersOnly(hmsg.peer, locator)
+				if err != nil {
+					bmgrLog.Warnf("Failed to send getheaders "+
+						"message to peer %s: %v",
+						hmsg.peer.Addr(), err)
+				}
+				return
+			}
+
+			if _, ok := err.(blockchain.RuleError); ok {
+				bmgrLog.Warnf("Block header %s from peer %s failed "+
+					"validation: %v -- disconnecting",
+					blockHeader.BlockHash(), hmsg.peer.Addr(), err)
+				hmsg.peer.Disconnect()
+				return
 			}
-			numRequested++
+
+			bmgrLog.Errorf("Failed to process block header %s: %v",
+				blockHeader.BlockHash(), err)
+			return
 		}
-		b.startHeader = e.Next()
-		if numRequested >= wire.MaxInvPerMsg {
-			break
+	}
+
+	// Update the height of the peer based on the final header it sent.
+	lastHeader := msg.Headers[numHeaders-1]
+	lastHash := lastHeader.BlockHash()
+	hmsg.peer.UpdateLastAnnouncedBlock(&lastHash)
+	if int64(lastHeader.Height) > hmsg.peer.LastBlock() {
+		hmsg.peer.UpdateLastBlockHeight(int64(lastHeader.Height))
+	}
+
+	// Request the next batch of headers starting from the final header
+	// when the message was full since the peer likely has more.
+	if numHeaders == wire.MaxBlockHeadersPerMsg {
+		locator := blockchain.BlockLocator([]*chainhash.Hash{&lastHash})
+		err := b.requestHeadersOnly(hmsg.peer, locator)
+		if err != nil {
+			bmgrLog.Warnf("Failed to send getheaders message to "+
+				"peer %s: %v", hmsg.peer.Addr(), err)
 		}
+		return
 	}
-	if len(gdmsg.InvList) > 0 {
-		b.syncPeer.QueueMessage(gdmsg, nil)
+
+	if hmsg.peer == b.syncPeer {
+		_, bestHeight := b.chain.BestHeader()
+		bmgrLog.Infof("Received block headers up to height %d from "+
+			"peer %s", bestHeight, hmsg.peer)
 	}
 }
 
 // handleHeadersMsg handles headers messages from all peers.
 func (b *blockManager) handleHeadersMsg(hmsg *headersMsg) {
-	// The remote peer is misbehaving if we didn't request headers.
+	// Headers are requested from all peers in headers-only mode.
+	if cfg.HeadersOnly {
+		b.handleHeadersOnlyMsg(hmsg)
+		return
+	}
+
+	// The remote peer is misbehaving if we didn't request headers.  Headers
+	// are only ever requested from the sync peer.
 	msg := hmsg.headers
 	numHeaders := len(msg.Headers)
-	if !b.headersFirstMode {
+	if hmsg.peer != b.syncPeer {
+		// Ignore headers requested from a peer before it was replaced
+		// as the sync peer.
+		if hmsg.peer.syncPeerReplaced {
+			bmgrLog.Debugf("Ignoring %d headers from %s received "+
+				"after it was replaced as the sync peer",
+				numHeaders, hmsg.peer)
+			return
+		}
+
 		bmgrLog.Warnf("Got %d unrequested headers from %s -- "+
 			"disconnecting", numHeaders, hmsg.peer.Addr())
 		hmsg.peer.Disconnect()
 		return
 	}
 
-	// Nothing to do for an empty headers message.
+	// Ignore headers that were requested prior to leaving headers-first
+	// mode.
+	if !b.headersFirstMode {
+		bmgrLog.Debugf("Ignoring %d headers from %s received after "+
+			"leaving headers-first mode", numHeaders, hmsg.peer)
+		return
+	}
+
+	// An empty headers message means the sync peer does not have any more
+	// headers.
 	if numHeaders == 0 {
+		b.headersSynced = true
+		b.processHeaderBlocks()
 		return
 	}
 
 	// Process all of the received headers ensuring each one connects to the
-	// previous and that checkpoints match.
-	receivedCheckpoint := false
-	var finalHash *chainhash.Hash
-	for _, blockHeader := range msg.Headers {
+	// previous, is valid according to the headers of its ancestors, which
+	// includes the proof of work and difficulty, and that checkpoints match.
+	for i := range msg.Headers {
+		blockHeader := msg.Headers[i]
 		blockHash := blockHeader.BlockHash()
-		finalHash = &blockHash
 
-		// Ensure there is a previous header to compare against.
-		prevNodeEl := b.headerList.Back()
-		if prevNodeEl == nil {
-			bmgrLog.Warnf("Header list does not contain a previous" +
-				"element as expected -- disconnecting peer")
-			hmsg.peer.Disconnect()
-			return
-		}
-
-		// Ensure the header properly connects to the previous one and
-		// add it to the list of headers.
-		node := headerNode{hash: &blockHash}
-		prevNode := prevNodeEl.Value.(*headerNode)
-		if prevNode.hash.IsEqual(&blockHeader.PrevBlock) {
-			node.height = prevNode.height + 1
-			e := b.headerList.PushBack(&node)
-			if b.startHeader == nil {
-				b.startHeader = e
+		// Ensure the header properly connects to the previous one.
+		prevNode := b.lastHeader
+		if !prevNode.hash.IsEqual(&blockHeader.PrevBlock) {
+			// The first header not connecting to the best chain tip
+			// while it connects to a known block means the peer is
+			// on a side chain.  Switch to normal mode in that case
+			// since it handles side chains via orphans.
+			if i == 0 && b.headerList.Len() == 0 &&
+				prevNode.height == b.chain.BestSnapshot().Height {
+
+				haveBlock, err := b.chain.HaveBlock(&blockHeader.PrevBlock)
+				if err == nil && haveBlock {
+					bmgrLog.Infof("Peer %s is on a side chain -- "+
+						"switching to normal mode", hmsg.peer)
+					b.exitHeadersFirstMode()
+					return
+				}
 			}
-		} else {
+
 			bmgrLog.Warnf("Received block header that does not "+
 				"properly connect to the chain from peer %s "+
 				"-- disconnecting", hmsg.peer.Addr())
@@ -1195,14 +1987,30 @@ func (b *blockManager) handleHeadersMsg(hmsg *headersMsg) {
 			return
 		}
 
-		// Verify the header at the next checkpoint height matches.
-		if node.height == b.nextCheckpoint.Height {
-			if node.hash.IsEqual(b.nextCheckpoint.Hash) {
-				receivedCheckpoint = true
-				bmgrLog.Infof("Verified downloaded block "+
-					"header against checkpoint at height "+
-					"%d/hash %s", node.height, node.hash)
-			} else {
+		// Validate the header and add it to the block index of the
+		// chain.  This also allows the chain to skip script validation
+		// for the ancestors of an assumed valid block since it must be
+		// part of the best known header chain.
+		err := b.chain.ProcessBlockHeader(blockHeader, blockchain.BFNone)
+		if _, ok := err.(blockchain.RuleError); ok {
+			bmgrLog.Warnf("Block header %s from peer %s failed "+
+				"validation: %v -- disconnecting", blockHash,
+				hmsg.peer.Addr(), err)
+			hmsg.peer.Disconnect()
+			return
+		}
+		if err != nil {
+			bmgrLog.Errorf("Failed to process block header %s: %v",
+				blockHash, err)
+			return
+		}
+
+		// Verify the header at the next checkpoint height matches.  All
+		// blocks up to a verified checkpoint are eligible for less
+		// validation.
+		node := &headerNode{height: prevNode.height + 1, hash: &blockHash}
+		if b.nextCheckpoint != nil && node.height == b.nextCheckpoint.Height {
+			if !node.hash.IsEqual(b.nextCheckpoint.Hash) {
 				bmgrLog.Warnf("Block header at height %d/hash "+
 					"%s from peer %s does NOT match "+
 					"expected checkpoint hash of %s -- "+
@@ -1212,35 +2020,32 @@ func (b *blockManager) handleHeadersMsg(hmsg *headersMsg) {
 				hmsg.peer.Disconnect()
 				return
 			}
-			break
+
+			bmgrLog.Infof("Verified downloaded block header against "+
+				"checkpoint at height %d/hash %s", node.height,
+				node.hash)
+			b.fastAddHeight = node.height
+			b.nextCheckpoint = b.findNextHeaderCheckpoint(node.height)
 		}
-	}
 
-	// When this header is a checkpoint, switch to fetching the blocks for
-	// all of the headers since the last checkpoint.
-	if receivedCheckpoint {
-		// Since the first entry of the list is always the final block
-		// that is already in the database and is only used to ensure
-		// the next header links properly, it must be removed before
-		// fetching the blocks.
-		b.headerList.Remove(b.headerList.Front())
-		bmgrLog.Infof("Received %v block headers: Fetching blocks",
-			b.headerList.Len())
-		b.progressLogger.SetLastLogTime(time.Now())
-		b.fetchHeaderBlocks()
-		return
+		// Add the header to the list of headers.
+		b.headerIndex[blockHash] = b.headerList.PushBack(node)
+		b.lastHeader = node
 	}
 
-	// This header is not a checkpoint, so request the next batch of
-	// headers starting from the latest known header and ending with the
-	// next checkpoint.
-	locator := blockchain.BlockLocator([]*chainhash.Hash{finalHash})
-	err := hmsg.peer.PushGetHeadersMsg(locator, b.nextCheckpoint.Hash)
-	if err != nil {
-		bmgrLog.Warnf("Failed to send getheaders message to "+
-			"peer %s: %v", hmsg.peer.Addr(), err)
-		return
+	// Request the next batch of headers starting from the latest known
+	// header when the message was full since the peer likely has more.
+	// Otherwise, all of the headers the peer knows about have been
+	// received.
+	if numHeaders == wire.MaxBlockHeadersPerMsg {
+		b.requestNextHeaders()
+	} else {
+		b.headersSynced = true
+		bmgrLog.Infof("Received block headers up to height %d from "+
+			"peer %s", b.lastHeader.height, hmsg.peer)
 	}
+
+	b.fetchHeaderBlocks()
 }
 
 // haveInventory returns whether or not the inventory represented by the passed
@@ -1276,9 +2081,51 @@ func (b *blockManager) haveInventory(invVect *wire.InvVect) (bool, error) {
 	return true, nil
 }
 
+// handleHeadersOnlyInvMsg handles inv messages from all peers when running in
+// headers-only mode.  The headers for any announced blocks that are not already
+// known are requested from the peer while all other inventory is ignored since
+// neither blocks nor transactions are downloaded.
+func (b *blockManager) handleHeadersOnlyInvMsg(imsg *invMsg) {
+	var lastBlock *wire.InvVect
+	for _, iv := range imsg.inv.InvList {
+		if iv.Type == wire.InvTypeBlock {
+			imsg.peer.AddKnownInventory(iv)
+			lastBlock = iv
+		}
+	}
+	if lastBlock == nil {
+		return
+	}
+	imsg.peer.UpdateLastAnnouncedBlock(&lastBlock.Hash)
+
+	// Update the height of the peer when the header for the final announced
+	// block is already known.  Otherwise, request the headers that lead up
+	// to it.
+	header, err := b.chain.HeaderByHash(&lastBlock.Hash)
+	if err == nil {
+		if int64(header.Height) > imsg.peer.LastBlock() {
+			imsg.peer.UpdateLastBlockHeight(int64(header.Height))
+		}
+		return
+	}
+	bestHeaderHash, _ := b.chain.BestHeader()
+	locator := b.chain.BlockLocatorFromHash(&bestHeaderHash)
+	err = b.requestHeadersOnly(imsg.peer, locator)
+	if err != nil {
+		bmgrLog.Warnf("Failed to send getheaders message to peer %s: %v",
+			imsg.peer.Addr(), err)
+	}
+}
+
 // handleInvMsg handles inv messages from all peers.
 // We examine the inventory advertised by the remote peer and act accordingly.
 func (b *blockManager) handleInvMsg(imsg *invMsg) {
+	// Only headers are requested in headers-only mode.
+	if cfg.HeadersOnly {
+		b.handleHeadersOnlyInvMsg(imsg)
+		return
+	}
+
 	// Attempt to find the final block in the inventory list.  There may
 	// not be one.
 	lastBlock := -1
@@ -1410,6 +2257,8 @@ func (b *blockManager) handleInvMsg(imsg *invMsg) {
 
 	// Request as much as possible at once.  Anything that won't fit into
 	// the request will be requested on the next inv message.
+	requestCmpct := isCurrent && !b.headersFirstMode && !cfg.BlocksOnly &&
+		imsg.peer.CmpctBlockVersion() == wire.CmpctBlockVersion1
 	numRequested := 0
 	gdmsg := wire.NewMsgGetData()
 	requestQueue := imsg.peer.requestQueue
@@ -1427,6 +2276,15 @@ func (b *blockManager) handleInvMsg(imsg *invMsg) {
 				b.requestedEverBlocks[iv.Hash] = 0
 				b.limitMap(b.requestedBlocks, maxRequestedBlocks)
 				imsg.peer.requestedBlocks[iv.Hash] = struct{}{}
+
+				// Request new blocks as compact blocks when the
+				// chain is current and the peer supports them
+				// since the memory pool likely already contains
+				// most of their transactions.
+				if requestCmpct {
+					iv = wire.NewInvVect(wire.InvTypeCmpctBlock,
+						&iv.Hash)
+				}
 				gdmsg.AddInvVect(iv)
 				numRequested++
 			}
@@ -1479,10 +2337,23 @@ func (b *blockManager) limitMap(m map[chainhash.Hash]struct{}, limit int) {
 // important because the block manager controls which blocks are needed and how
 // the fetching should proceed.
 func (b *blockManager) blockHandler() {
-	candidatePeers := list.New()
+	candidatePeers := b.candidatePeers
+	stallTicker := time.NewTicker(blockStallCheckInterval)
+	defer stallTicker.Stop()
+	syncPeerTicker := time.NewTicker(syncPeerCheckInterval)
+	defer syncPeerTicker.Stop()
 out:
 	for {
 		select {
+		case <-stallTicker.C:
+			b.handleBlockStalls()
+			if b.historyValidator != nil {
+				b.historyValidator.handleStalls()
+			}
+
+		case <-syncPeerTicker.C:
+			b.checkSyncPeer()
+
 		case m := <-b.msgChan:
 			switch msg := m.(type) {
 			case *newPeerMsg:
@@ -1502,12 +2373,31 @@ out:
 			case *headersMsg:
 				b.handleHeadersMsg(msg)
 
+			case *cmpctBlockMsg:
+				b.handleCmpctBlockMsg(msg)
+				msg.peer.blockProcessed <- struct{}{}
+
+			case *blockTxnMsg:
+				b.handleBlockTxnMsg(msg)
+				msg.peer.blockProcessed <- struct{}{}
+
 			case *donePeerMsg:
 				b.handleDonePeerMsg(candidatePeers, msg.peer)
 
+			case *historyProcessedMsg:
+				if b.historyValidator != nil {
+					b.historyValidator.handleProcessed(msg.height)
+				}
+
+			case *historyDoneMsg:
+				b.historyValidator = nil
+
 			case getSyncPeerMsg:
 				msg.reply <- b.syncPeer
 
+			case getSyncInfoMsg:
+				msg.reply <- b.currentSyncInfo()
+
 			case requestFromPeerMsg:
 				err := b.requestFromPeer(msg.peer, msg.blocks, msg.txs)
 				msg.reply <- requestFromPeerResponse{
@@ -1615,6 +2505,15 @@ out:
 					err:         err,
 				}
 
+			case processPackageMsg:
+				acceptedTxs, txErrs, err := b.server.txMemPool.ProcessPackage(
+					msg.txns, msg.rateLimit, msg.allowHighFees)
+				msg.reply <- processPackageResponse{
+					acceptedTxs: acceptedTxs,
+					txErrs:      txErrs,
+					err:         err,
+				}
+
 			case isCurrentMsg:
 				msg.reply <- b.current()
 
@@ -1648,6 +2547,13 @@ out:
 		}
 	}
 
+	// Write the unspent transaction outputs held in the cache to the
+	// database so the most recent blocks do not need to be replayed on the
+	// next startup.
+	if err := b.chain.FlushUtxoCache(); err != nil {
+		bmgrLog.Errorf("Unable to flush the utxo cache: %v", err)
+	}
+
 	b.wg.Done()
 	bmgrLog.Trace("Block handler done")
 }
@@ -1857,7 +2763,8 @@ func (b *blockManager) handleNotifyMsg(notification *blockchain.Notification) {
 		txMemPool := b.server.txMemPool
 		handleConnectedBlockTxns := func(txns []*dcrutil.Tx) {
 			for _, tx := range txns {
-				txMemPool.RemoveTransaction(tx, false)
+				txMemPool.RemoveTransactionWithReason(tx, false,
+					mempool.RemovalReasonMined)
 				txMemPool.RemoveDoubleSpends(tx)
 				txMemPool.RemoveOrphan(tx)
 				acceptedTxs := txMemPool.ProcessOrphans(tx)
@@ -1899,7 +2806,8 @@ func (b *blockManager) handleNotifyMsg(notification *blockchain.Notification) {
 			for _, tx := range parentBlock.Transactions()[1:] {
 				_, err := txMemPool.MaybeAcceptTransaction(tx, false, true)
 				if err != nil && !isDoubleSpendOrDuplicateError(err) {
-					txMemPool.RemoveTransaction(tx, true)
+					txMemPool.RemoveTransactionWithReason(tx, true,
+						mempool.RemovalReasonReorg)
 				}
 			}
 		}
@@ -1963,7 +2871,8 @@ func (b *blockManager) handleNotifyMsg(notification *blockchain.Notification) {
 		txMemPool := b.server.txMemPool
 		if !headerApprovesParent(&block.MsgBlock().Header) {
 			for _, tx := range parentBlock.Transactions()[1:] {
-				txMemPool.RemoveTransaction(tx, false)
+				txMemPool.RemoveTransactionWithReason(tx, false,
+					mempool.RemovalReasonMined)
 				txMemPool.RemoveDoubleSpends(tx)
 				txMemPool.RemoveOrphan(tx)
 				txMemPool.ProcessOrphans(tx)
@@ -1996,7 +2905,8 @@ func (b *blockManager) handleNotifyMsg(notification *blockchain.Notification) {
 			for _, tx := range txns {
 				_, err := txMemPool.MaybeAcceptTransaction(tx, false, true)
 				if err != nil && !isDoubleSpendOrDuplicateError(err) {
-					txMemPool.RemoveTransaction(tx, true)
+					txMemPool.RemoveTransactionWithReason(tx, true,
+						mempool.RemovalReasonReorg)
 				}
 			}
 		}
@@ -2085,6 +2995,30 @@ func (b *blockManager) QueueHeaders(headers *wire.MsgHeaders, sp *serverPeer) {
 	b.msgChan <- &headersMsg{headers: headers, peer: sp}
 }
 
+// QueueCmpctBlock adds the passed compact block message and peer to the block
+// handling queue.
+func (b *blockManager) QueueCmpctBlock(cmpctBlock *wire.MsgCmpctBlock, sp *serverPeer) {
+	// Don't accept more blocks if we're shutting down.
+	if atomic.LoadInt32(&b.shutdown) != 0 {
+		sp.blockProcessed <- struct{}{}
+		return
+	}
+
+	b.msgChan <- &cmpctBlockMsg{cmpctBlock: cmpctBlock, peer: sp}
+}
+
+// QueueBlockTxn adds the passed blocktxn message and peer to the block handling
+// queue.
+func (b *blockManager) QueueBlockTxn(blockTxn *wire.MsgBlockTxn, sp *serverPeer) {
+	// Don't accept more blocks if we're shutting down.
+	if atomic.LoadInt32(&b.shutdown) != 0 {
+		sp.blockProcessed <- struct{}{}
+		return
+	}
+
+	b.msgChan <- &blockTxnMsg{blockTxn: blockTxn, peer: sp}
+}
+
 // DonePeer informs the blockmanager that a peer has disconnected.
 func (b *blockManager) DonePeer(sp *serverPeer) {
 	// Ignore if we are shutting down.
@@ -2105,6 +3039,9 @@ func (b *blockManager) Start() {
 	bmgrLog.Trace("Starting block manager")
 	b.wg.Add(1)
 	go b.blockHandler()
+	if b.historyValidator != nil {
+		b.historyValidator.Start()
+	}
 }
 
 // Stop gracefully shuts down the block manager by stopping all asynchronous
@@ -2129,6 +3066,14 @@ func (b *blockManager) SyncPeer() *serverPeer {
 	return <-reply
 }
 
+// SyncInfo returns information about the state of the chain sync along with
+// the block download state of the sync candidate peers.
+func (b *blockManager) SyncInfo() *syncInfo {
+	reply := make(chan *syncInfo)
+	b.msgChan <- getSyncInfoMsg{reply: reply}
+	return <-reply
+}
+
 // RequestFromPeer allows an outside caller to request blocks or transactions
 // from a peer. The requests are logged in the blockmanager's internal map of
 // requests so they do not later ban the peer for sending the respective data.
@@ -2306,6 +3251,17 @@ func (b *blockManager) ProcessTransaction(tx *dcrutil.Tx, allowOrphans bool,
 	return response.acceptedTxs, response.err
 }
 
+// ProcessPackage makes use of ProcessPackage on an internal instance of a block
+// chain.  It is funneled through the block manager since blockchain is not safe
+// for concurrent access.
+func (b *blockManager) ProcessPackage(txns []*dcrutil.Tx, rateLimit bool,
+	allowHighFees bool) ([]*dcrutil.Tx, []error, error) {
+	reply := make(chan processPackageResponse, 1)
+	b.msgChan <- processPackageMsg{txns, rateLimit, allowHighFees, reply}
+	response := <-reply
+	return response.acceptedTxs, response.txErrs, response.err
+}
+
 // IsCurrent returns whether or not the block manager believes it is synced with
 // the connected peers.
 func (b *blockManager) IsCurrent() bool {
@@ -2362,36 +3318,57 @@ func newBlockManager(s *server, indexManager blockchain.IndexManager, interrupt
 		requestedEverBlocks: make(map[chainhash.Hash]uint8),
 		progressLogger:      newBlockProgressLogger("Processed", bmgrLog),
 		msgChan:             make(chan interface{}, cfg.MaxPeers*3),
+		candidatePeers:      list.New(),
 		headerList:          list.New(),
 		AggressiveMining:    !cfg.NonAggressive,
 		quit:                make(chan struct{}),
 	}
 
+	// Open the chain state snapshot to load the chain state from when
+	// requested.  It is only loaded when the database does not contain a
+	// chain yet.
+	var snapshot io.ReadSeeker
+	if cfg.LoadSnapshot != "" {
+		f, err := os.Open(cfg.LoadSnapshot)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		snapshot = f
+	}
+
 	// Create a new block chain instance with the appropriate configuration.
 	var err error
 	bm.chain, err = blockchain.New(&blockchain.Config{
-		DB:            s.db,
-		Interrupt:     interrupt,
-		ChainParams:   s.chainParams,
-		TimeSource:    s.timeSource,
-		Notifications: bm.handleNotifyMsg,
-		SigCache:      s.sigCache,
-		IndexManager:  indexManager,
+		DB:               s.db,
+		Interrupt:        interrupt,
+		ChainParams:      s.chainParams,
+		TimeSource:       s.timeSource,
+		Notifications:    bm.handleNotifyMsg,
+		SigCache:         s.sigCache,
+		IndexManager:     indexManager,
+		PruneTarget:      cfg.Prune * 1024 * 1024,
+		UtxoCacheMaxSize: cfg.UtxoCacheMaxSize * 1024 * 1024,
+		AssumeValid:      cfg.assumeValid,
+		Snapshot:         snapshot,
 	})
 	if err != nil {
 		return nil, err
 	}
 	best := bm.chain.BestSnapshot()
 	bm.chain.DisableCheckpoints(cfg.DisableCheckpoints)
-	if !cfg.DisableCheckpoints {
-		// Initialize the next checkpoint based on the current height.
-		bm.nextCheckpoint = bm.findNextHeaderCheckpoint(best.Height)
-		if bm.nextCheckpoint != nil {
-			bm.resetHeaderState(&best.Hash, best.Height)
-		}
-	} else {
+	if cfg.DisableCheckpoints {
 		bmgrLog.Info("Checkpoints are disabled")
 	}
+	if cfg.assumeValid != zeroHash {
+		bmgrLog.Infof("Assuming block %v and its ancestors have valid "+
+			"scripts when it is part of the best header chain",
+			cfg.assumeValid)
+	}
+
+	// Initialize the headers-first state, including the next checkpoint,
+	// based on the current height.
+	bm.resetHeaderState(&best.Hash, best.Height)
 
 	// Dump the blockchain here if asked for it, and quit.
 	if cfg.DumpBlockchain != "" {
@@ -2408,6 +3385,16 @@ func newBlockManager(s *server, indexManager blockchain.IndexManager, interrupt
 	bm.syncHeight = best.Height
 	bm.syncHeightMtx.Unlock()
 
+	// Validate the history leading up to the chain state snapshot the chain
+	// was loaded from in the background when it has not been validated yet.
+	if snapshot := bm.chain.UnvalidatedSnapshot(); snapshot != nil {
+		bm.historyValidator, err = newHistoryValidator(&bm, snapshot,
+			interrupt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &bm, nil
 }
 
@@ -0,0 +1,607 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/blockchain"
+	"github.com/decred/dcrd/blockchain/chaingen"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/database"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/mempool/v2"
+	"github.com/decred/dcrd/peer"
+	"github.com/decred/dcrd/txscript"
+	"github.com/decred/dcrd/wire"
+)
+
+// headersFirstTestHarness houses a block manager in headers-first mode with
+// the headers of a set of generated blocks that extend its chain and the sync
+// candidate peers to download them from.
+type headersFirstTestHarness struct {
+	t      *testing.T
+	bm     *blockManager
+	blocks []*dcrutil.Block
+	peers  []*serverPeer
+}
+
+// newHeadersFirstTestHarness creates a block manager with a chain that only
+// contains the genesis block and places it in headers-first mode with the
+// headers of the passed number of generated blocks.  The passed number of sync
+// candidate peers, which are not connected and claim to have all of the
+// blocks, are added and the first one is made the sync peer.  The returned
+// function restores the global configuration and removes the database.
+func newHeadersFirstTestHarness(t *testing.T, numBlocks, numPeers int) (*headersFirstTestHarness, func()) {
+	t.Helper()
+
+	dataDir, err := ioutil.TempDir("", "blockmanagertest")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	oldCfg := cfg
+	cfg = &config{DataDir: dataDir, DbType: "ffldb", DisableCheckpoints: true}
+	var db database.DB
+	teardown := func() {
+		if db != nil {
+			db.Close()
+		}
+		cfg = oldCfg
+		os.RemoveAll(dataDir)
+	}
+
+	params := *regNetParams.Params
+	db, err = database.Create("ffldb", filepath.Join(dataDir, "blocks"),
+		params.Net)
+	if err != nil {
+		teardown()
+		t.Fatalf("unable to create db: %v", err)
+	}
+	chain, err := blockchain.New(&blockchain.Config{
+		DB:          db,
+		ChainParams: &params,
+		TimeSource:  blockchain.NewMedianTime(),
+		SigCache:    txscript.NewSigCache(1000),
+	})
+	if err != nil {
+		teardown()
+		t.Fatalf("unable to create chain: %v", err)
+	}
+
+	// Generate the blocks without connecting them.
+	g, err := chaingen.MakeGenerator(&params)
+	if err != nil {
+		teardown()
+		t.Fatalf("unable to create generator: %v", err)
+	}
+	var blocks []*dcrutil.Block
+	for i := 0; i < numBlocks; i++ {
+		if i == 0 {
+			g.CreatePremineBlock("bp", 0)
+		} else {
+			g.NextBlock(fmt.Sprintf("b%d", i), nil, nil)
+		}
+		blocks = append(blocks, dcrutil.NewBlock(g.Tip()))
+	}
+
+	s := &server{chainParams: &params}
+	s.txMemPool = mempool.New(&mempool.Config{
+		BestHeight: func() int64 { return chain.BestSnapshot().Height },
+	})
+	bm := &blockManager{
+		server:              s,
+		chain:               chain,
+		rejectedTxns:        make(map[chainhash.Hash]struct{}),
+		requestedBlocks:     make(map[chainhash.Hash]struct{}),
+		requestedEverBlocks: make(map[chainhash.Hash]uint8),
+		progressLogger:      newBlockProgressLogger("Processed", bmgrLog),
+		candidatePeers:      list.New(),
+		headerList:          list.New(),
+		quit:                make(chan struct{}),
+	}
+	best := chain.BestSnapshot()
+	bm.resetHeaderState(&best.Hash, best.Height)
+
+	// Add the headers of the generated blocks as if they were downloaded
+	// from the sync peer.
+	bm.headersFirstMode = true
+	bm.headersSynced = true
+	for _, block := range blocks {
+		node := &headerNode{height: block.Height(), hash: block.Hash()}
+		bm.headerIndex[*node.hash] = bm.headerList.PushBack(node)
+		bm.lastHeader = node
+	}
+
+	h := &headersFirstTestHarness{t: t, bm: bm, blocks: blocks}
+	for i := 0; i < numPeers; i++ {
+		sp := newServerPeer(s, false)
+		addr := fmt.Sprintf("127.0.0.%d:18555", i+1)
+		sp.Peer, err = peer.NewOutboundPeer(&peer.Config{
+			ChainParams: &params,
+		}, addr)
+		if err != nil {
+			teardown()
+			t.Fatalf("unable to create peer: %v", err)
+		}
+		sp.UpdateLastBlockHeight(int64(numBlocks))
+		bm.candidatePeers.PushBack(sp)
+		h.peers = append(h.peers, sp)
+	}
+	bm.syncPeer = h.peers[0]
+
+	return h, teardown
+}
+
+// requested returns the heights of the blocks that are in flight from the
+// passed peer according to the headers-first state.  It also ensures the
+// requests the peer tracks are consistent with the headers.
+func (h *headersFirstTestHarness) requested(sp *serverPeer) []int64 {
+	h.t.Helper()
+
+	var heights []int64
+	for e := h.bm.headerList.Front(); e != nil; e = e.Next() {
+		node := e.Value.(*headerNode)
+		if node.requestedFrom == sp {
+			heights = append(heights, node.height)
+			if _, ok := sp.requestedBlocks[*node.hash]; !ok {
+				h.t.Fatalf("block %d assigned to peer %s is not "+
+					"tracked by it", node.height, sp)
+			}
+		}
+	}
+	if len(heights) != len(sp.requestedBlocks) {
+		h.t.Fatalf("peer %s tracks %d requests, want %d", sp,
+			len(sp.requestedBlocks), len(heights))
+	}
+	return heights
+}
+
+// unassigned returns the number of blocks described by the headers that have
+// neither been received nor requested from any peer.
+func (h *headersFirstTestHarness) unassigned() int {
+	var count int
+	for e := h.bm.headerList.Front(); e != nil; e = e.Next() {
+		node := e.Value.(*headerNode)
+		if node.block == nil && node.requestedFrom == nil {
+			count++
+		}
+	}
+	return count
+}
+
+// disconnected returns whether or not the passed peer was disconnected.
+func disconnected(sp *serverPeer) bool {
+	done := make(chan struct{})
+	go func() {
+		sp.WaitForDisconnect()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(100 * time.Millisecond):
+		return false
+	}
+}
+
+// TestBlockStalls ensures the blocks in flight from a peer that stalls in
+// headers-first mode are assigned to the other peers and that the stalling
+// peer is disconnected unless it is the sync peer.
+func TestBlockStalls(t *testing.T) {
+	const numBlocks, numPeers = 12, 3
+	h, teardown := newHeadersFirstTestHarness(t, numBlocks, numPeers)
+	defer teardown()
+
+	// Ensure the requests are spread evenly among the peers.
+	bm := h.bm
+	bm.fetchHeaderBlocks()
+	for _, sp := range h.peers {
+		if n := len(h.requested(sp)); n != numBlocks/numPeers {
+			t.Fatalf("peer %s was assigned %d blocks, want %d", sp, n,
+				numBlocks/numPeers)
+		}
+	}
+
+	// Make the requests to the second peer older than the stall timeout and
+	// ensure only that peer is considered stalled.
+	stalled, syncPeer := h.peers[1], h.peers[0]
+	stalledHeights := h.requested(stalled)
+	for e := bm.headerList.Front(); e != nil; e = e.Next() {
+		node := e.Value.(*headerNode)
+		if node.requestedFrom == stalled {
+			node.requestTime = time.Now().Add(-blockStallTimeout - time.Second)
+		}
+	}
+	syncRequests := h.requested(syncPeer)
+	bm.handleBlockStalls()
+	if n := len(h.requested(stalled)); n != 0 {
+		t.Fatalf("stalled peer still has %d blocks in flight", n)
+	}
+	if stalled.lastBlockStall.IsZero() || !syncPeer.lastBlockStall.IsZero() {
+		t.Fatalf("unexpected stall times -- stalled %v, sync peer %v",
+			stalled.lastBlockStall, syncPeer.lastBlockStall)
+	}
+	if !disconnected(stalled) {
+		t.Fatal("stalled peer was not disconnected")
+	}
+	if n := h.unassigned(); n != 0 {
+		t.Fatalf("%d blocks of the stalled peer were not reassigned", n)
+	}
+	if got := h.requested(syncPeer); len(got) <= len(syncRequests) {
+		t.Fatalf("stalled blocks %v were not reassigned to the sync "+
+			"peer -- got %v", stalledHeights, got)
+	}
+
+	// Ensure a stalling sync peer has its requests reassigned without being
+	// disconnected since it is still needed to provide headers.
+	for e := bm.headerList.Front(); e != nil; e = e.Next() {
+		node := e.Value.(*headerNode)
+		if node.requestedFrom == syncPeer {
+			node.requestTime = time.Now().Add(-blockStallTimeout - time.Second)
+		}
+	}
+	bm.handleBlockStalls()
+	if syncPeer.lastBlockStall.IsZero() {
+		t.Fatal("stalled sync peer was not marked as stalled")
+	}
+	if disconnected(syncPeer) {
+		t.Fatal("stalled sync peer was disconnected")
+	}
+	if n := len(h.requested(h.peers[2])); n != numBlocks {
+		t.Fatalf("remaining peer has %d blocks in flight, want %d", n,
+			numBlocks)
+	}
+}
+
+// TestDonePeerReassign ensures the blocks in flight from a peer that
+// disconnects in headers-first mode are assigned to the remaining peers while
+// the downloaded headers and the requests to the other peers are kept, even
+// when the peer is the sync peer.
+func TestDonePeerReassign(t *testing.T) {
+	const numBlocks, numPeers = 12, 3
+	h, teardown := newHeadersFirstTestHarness(t, numBlocks, numPeers)
+	defer teardown()
+
+	bm := h.bm
+	bm.fetchHeaderBlocks()
+	lastHeader := bm.lastHeader
+
+	// Ensure the blocks of a peer other than the sync peer are reassigned
+	// without affecting the sync peer.
+	syncRequests := h.requested(h.peers[0])
+	bm.handleDonePeerMsg(bm.candidatePeers, h.peers[2])
+	if bm.syncPeer != h.peers[0] {
+		t.Fatalf("sync peer changed to %s", bm.syncPeer)
+	}
+	if n := h.unassigned(); n != 0 {
+		t.Fatalf("%d blocks of the departed peer were not reassigned", n)
+	}
+	if got := h.requested(h.peers[0]); len(got) < len(syncRequests) ||
+		got[0] != syncRequests[0] {
+
+		t.Fatalf("sync peer requests changed from %v to %v",
+			syncRequests, got)
+	}
+
+	// Ensure the blocks of the sync peer are reassigned to the remaining
+	// peer, which becomes the new sync peer, while the headers are kept.
+	remaining := h.requested(h.peers[1])
+	bm.handleDonePeerMsg(bm.candidatePeers, h.peers[0])
+	if bm.syncPeer != h.peers[1] {
+		t.Fatalf("unexpected sync peer %v, want %s", bm.syncPeer,
+			h.peers[1])
+	}
+	if !bm.headersFirstMode || bm.headerList.Len() != numBlocks ||
+		bm.lastHeader != lastHeader {
+
+		t.Fatalf("headers-first state was reset -- mode %v, headers %d",
+			bm.headersFirstMode, bm.headerList.Len())
+	}
+	if n := h.unassigned(); n != 0 {
+		t.Fatalf("%d blocks of the departed sync peer were not "+
+			"reassigned", n)
+	}
+	got := h.requested(h.peers[1])
+	if len(got) != numBlocks {
+		t.Fatalf("remaining peer has %d blocks in flight, want %d",
+			len(got), numBlocks)
+	}
+	for _, height := range remaining {
+		if _, ok := h.peers[1].requestedBlocks[*h.blocks[height-1].Hash()]; !ok {
+			t.Fatalf("request for block %d to the remaining peer was "+
+				"dropped", height)
+		}
+	}
+
+	// Ensure the headers-first state is reset when there are no more peers
+	// to continue from.
+	bm.handleDonePeerMsg(bm.candidatePeers, h.peers[1])
+	if bm.syncPeer != nil || bm.headersFirstMode {
+		t.Fatalf("unexpected state without peers -- sync peer %v, "+
+			"headers-first mode %v", bm.syncPeer, bm.headersFirstMode)
+	}
+}
+
+// TestHeaderBlocksOrder ensures the blocks received in headers-first mode are
+// handed to the chain in order regardless of the order they are received in
+// and that headers-first mode is exited once they are all processed.
+func TestHeaderBlocksOrder(t *testing.T) {
+	const numBlocks, numPeers = 6, 2
+	h, teardown := newHeadersFirstTestHarness(t, numBlocks, numPeers)
+	defer teardown()
+
+	bm := h.bm
+	bm.fetchHeaderBlocks()
+	peerOf := func(block *dcrutil.Block) *serverPeer {
+		for _, sp := range h.peers {
+			if _, ok := sp.requestedBlocks[*block.Hash()]; ok {
+				return sp
+			}
+		}
+		t.Fatalf("block %d was not requested", block.Height())
+		return nil
+	}
+
+	// Deliver all blocks except the first one in reverse order and ensure
+	// none of them are processed.
+	for i := numBlocks - 1; i > 0; i-- {
+		block := h.blocks[i]
+		bm.handleBlockMsg(&blockMsg{block: block, peer: peerOf(block)})
+		if height := bm.chain.BestSnapshot().Height; height != 0 {
+			t.Fatalf("block processed out of order -- best height %d",
+				height)
+		}
+		if bm.chain.IsKnownOrphan(block.Hash()) {
+			t.Fatalf("block %d was handed to the chain as an orphan",
+				block.Height())
+		}
+	}
+
+	// Deliver the first block and ensure all of the blocks are processed
+	// and headers-first mode is exited.
+	first := h.blocks[0]
+	bm.handleBlockMsg(&blockMsg{block: first, peer: peerOf(first)})
+	best := bm.chain.BestSnapshot()
+	tip := h.blocks[numBlocks-1]
+	if best.Hash != *tip.Hash() {
+		t.Fatalf("unexpected best block %s (height %d), want %s",
+			best.Hash, best.Height, tip.Hash())
+	}
+	if bm.headersFirstMode || bm.headerList.Len() != 0 ||
+		bm.bufferedBlockBytes != 0 {
+
+		t.Fatalf("headers-first mode not exited -- mode %v, headers %d, "+
+			"buffered bytes %d", bm.headersFirstMode,
+			bm.headerList.Len(), bm.bufferedBlockBytes)
+	}
+	for _, sp := range h.peers {
+		if len(sp.requestedBlocks) != 0 {
+			t.Fatalf("peer %s still has %d blocks in flight", sp,
+				len(sp.requestedBlocks))
+		}
+	}
+}
+
+// TestBufferedBlockBytesLimit ensures only the blocks before the first held
+// block are requested in headers-first mode once the held blocks reach the
+// maximum total size.
+func TestBufferedBlockBytesLimit(t *testing.T) {
+	const numBlocks, numPeers = 6, 2
+	h, teardown := newHeadersFirstTestHarness(t, numBlocks, numPeers)
+	defer teardown()
+
+	// Deliver the second block so it is held and release the requests of
+	// all other blocks so they are requested again.
+	bm := h.bm
+	bm.fetchHeaderBlocks()
+	second := h.blocks[1]
+	for _, sp := range h.peers {
+		if _, ok := sp.requestedBlocks[*second.Hash()]; ok {
+			bm.handleBlockMsg(&blockMsg{block: second, peer: sp})
+		}
+	}
+	secondSize := int64(second.MsgBlock().SerializeSize())
+	if bm.bufferedBlockBytes != secondSize {
+		t.Fatalf("unexpected buffered bytes %d, want %d",
+			bm.bufferedBlockBytes, secondSize)
+	}
+	for _, sp := range h.peers {
+		bm.releaseHeaderBlocks(sp)
+	}
+
+	// Pretend the held block uses the maximum allowed space and ensure only
+	// the first block is requested.
+	bm.bufferedBlockBytes = maxBufferedBlockBytes
+	bm.fetchHeaderBlocks()
+	if n := h.unassigned(); n != numBlocks-2 {
+		t.Fatalf("%d blocks were not requested, want %d", n, numBlocks-2)
+	}
+	var firstPeer *serverPeer
+	first := h.blocks[0]
+	for _, sp := range h.peers {
+		if _, ok := sp.requestedBlocks[*first.Hash()]; ok {
+			firstPeer = sp
+		}
+	}
+	if firstPeer == nil {
+		t.Fatal("first block was not requested")
+	}
+
+	// Ensure the remaining blocks are requested once the held block is
+	// processed.  The peers claim to have all of the blocks again since
+	// processing the blocks updates their heights to those of the blocks
+	// they delivered.
+	bm.bufferedBlockBytes = secondSize
+	bm.handleBlockMsg(&blockMsg{block: first, peer: firstPeer})
+	if height := bm.chain.BestSnapshot().Height; height != 2 {
+		t.Fatalf("unexpected best height %d, want 2", height)
+	}
+	if bm.bufferedBlockBytes != 0 {
+		t.Fatalf("unexpected buffered bytes %d after processing the "+
+			"held blocks", bm.bufferedBlockBytes)
+	}
+	for _, sp := range h.peers {
+		sp.UpdateLastBlockHeight(numBlocks)
+	}
+	bm.fetchHeaderBlocks()
+	if n := h.unassigned(); n != 0 {
+		t.Fatalf("%d blocks were not requested after the held block "+
+			"was processed", n)
+	}
+}
+
+// TestHeaderListLimit ensures more headers are only requested in headers-first
+// mode when the header list has room for a full headers message.
+func TestHeaderListLimit(t *testing.T) {
+	const numBlocks, numPeers = 2, 1
+	h, teardown := newHeadersFirstTestHarness(t, numBlocks, numPeers)
+	defer teardown()
+
+	// Fill the header list so it does not have room for a full headers
+	// message until both of the generated blocks are processed.
+	bm := h.bm
+	bm.headersSynced = false
+	numFake := maxHeaderListLen - wire.MaxBlockHeadersPerMsg - numBlocks + 2
+	for i := 0; i < numFake; i++ {
+		var hash chainhash.Hash
+		hash[0], hash[1], hash[2] = byte(i), byte(i>>8), 0xff
+		node := &headerNode{height: bm.lastHeader.height + 1, hash: &hash}
+		bm.headerIndex[hash] = bm.headerList.PushBack(node)
+		bm.lastHeader = node
+	}
+	bm.requestNextHeaders()
+	if !bm.headersPaused {
+		t.Fatal("headers requested when the header list is full")
+	}
+
+	// Ensure processing the first block does not make enough room.
+	bm.fetchHeaderBlocks()
+	sp := h.peers[0]
+	bm.handleBlockMsg(&blockMsg{block: h.blocks[0], peer: sp})
+	if !bm.headersPaused {
+		t.Fatal("headers requested without room for a full message")
+	}
+
+	// Ensure headers are requested once processing the second block makes
+	// enough room.
+	bm.handleBlockMsg(&blockMsg{block: h.blocks[1], peer: sp})
+	if bm.headersPaused {
+		t.Fatal("headers not requested after making room for them")
+	}
+	if n := bm.headerList.Len(); n+wire.MaxBlockHeadersPerMsg != maxHeaderListLen {
+		t.Fatalf("unexpected header list length %d", n)
+	}
+}
+
+// TestHeadersFirstValidation ensures the headers downloaded in headers-first
+// mode are fully validated and added to the block index and that the sync peer
+// is disconnected when one is invalid.
+func TestHeadersFirstValidation(t *testing.T) {
+	const numBlocks, numPeers = 4, 2
+	h, teardown := newHeadersFirstTestHarness(t, numBlocks, numPeers)
+	defer teardown()
+
+	// Ensure valid headers are added to the block index of the chain.
+	bm := h.bm
+	startHeadersFirst := func(sp *serverPeer) {
+		best := bm.chain.BestSnapshot()
+		bm.resetHeaderState(&best.Hash, best.Height)
+		bm.headersFirstMode = true
+		bm.syncPeer = sp
+	}
+	syncPeer := h.peers[0]
+	startHeadersFirst(syncPeer)
+	headers := wire.NewMsgHeaders()
+	for _, block := range h.blocks[:2] {
+		headers.AddBlockHeader(&block.MsgBlock().Header)
+	}
+	bm.handleHeadersMsg(&headersMsg{headers: headers, peer: syncPeer})
+	if hash, _ := bm.chain.BestHeader(); hash != *h.blocks[1].Hash() {
+		t.Fatalf("unexpected best header %s, want %s", hash,
+			h.blocks[1].Hash())
+	}
+	if bm.headerList.Len() != 2 {
+		t.Fatalf("unexpected number of headers %d, want 2",
+			bm.headerList.Len())
+	}
+
+	// Ensure a header with an invalid difficulty that connects to the
+	// previous one is rejected and the sync peer is disconnected.
+	syncPeer = h.peers[1]
+	startHeadersFirst(syncPeer)
+	badHeader := h.blocks[2].MsgBlock().Header
+	badHeader.Bits--
+	headers = wire.NewMsgHeaders()
+	for _, block := range h.blocks[:2] {
+		headers.AddBlockHeader(&block.MsgBlock().Header)
+	}
+	headers.AddBlockHeader(&badHeader)
+	bm.handleHeadersMsg(&headersMsg{headers: headers, peer: syncPeer})
+	if hash, _ := bm.chain.BestHeader(); hash != *h.blocks[1].Hash() {
+		t.Fatalf("invalid header accepted -- best header %s", hash)
+	}
+	if bm.headerList.Len() != 2 {
+		t.Fatalf("unexpected number of headers %d, want 2",
+			bm.headerList.Len())
+	}
+	if !disconnected(syncPeer) {
+		t.Fatal("peer that sent an invalid header was not disconnected")
+	}
+}
+
+// TestHeadersOnlyUnsolicited ensures headers are only accepted in headers-only
+// mode from peers they were requested from.
+func TestHeadersOnlyUnsolicited(t *testing.T) {
+	const numBlocks, numPeers = 4, 2
+	h, teardown := newHeadersFirstTestHarness(t, numBlocks, numPeers)
+	defer teardown()
+
+	bm := h.bm
+	best := bm.chain.BestSnapshot()
+	bm.resetHeaderState(&best.Hash, best.Height)
+	cfg.HeadersOnly = true
+	headers := wire.NewMsgHeaders()
+	for _, block := range h.blocks {
+		headers.AddBlockHeader(&block.MsgBlock().Header)
+	}
+
+	// Ensure unsolicited headers are rejected and the peer is disconnected.
+	unsolicited := h.peers[1]
+	bm.handleHeadersMsg(&headersMsg{headers: headers, peer: unsolicited})
+	if _, height := bm.chain.BestHeader(); height != 0 {
+		t.Fatalf("unsolicited headers accepted -- best header height %d",
+			height)
+	}
+	if !disconnected(unsolicited) {
+		t.Fatal("peer that sent unsolicited headers was not disconnected")
+	}
+
+	// Ensure requested headers are accepted and another response from the
+	// same peer is rejected.
+	requested := h.peers[0]
+	locator := bm.chain.BlockLocatorFromHash(&best.Hash)
+	if err := bm.requestHeadersOnly(requested, locator); err != nil {
+		t.Fatalf("failed to request headers: %v", err)
+	}
+	bm.handleHeadersMsg(&headersMsg{headers: headers, peer: requested})
+	tip := h.blocks[numBlocks-1]
+	if hash, height := bm.chain.BestHeader(); hash != *tip.Hash() {
+		t.Fatalf("unexpected best header %s (height %d), want %s", hash,
+			height, tip.Hash())
+	}
+	if disconnected(requested) {
+		t.Fatal("peer that sent requested headers was disconnected")
+	}
+	bm.handleHeadersMsg(&headersMsg{headers: headers, peer: requested})
+	if !disconnected(requested) {
+		t.Fatal("peer that sent headers twice was not disconnected")
+	}
+}
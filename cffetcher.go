@@ -0,0 +1,292 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/gcs"
+	"github.com/decred/dcrd/gcs/blockcf"
+	"github.com/decred/dcrd/wire"
+)
+
+const (
+	// cfFetchTimeout is the duration to wait for a peer to respond to a
+	// request for a committed filter or committed filter header before
+	// trying the next peer.
+	cfFetchTimeout = 10 * time.Second
+
+	// maxCFFetchPeers is the maximum number of peers a committed filter or
+	// committed filter header is requested from before giving up.
+	maxCFFetchPeers = 3
+)
+
+// errCFFetchShutdown is returned when fetching a committed filter or committed
+// filter header is interrupted by shutdown.
+var errCFFetchShutdown = errors.New("shutdown requested")
+
+// cfFetchPeer describes the functionality needed to request committed filters
+// and committed filter headers from a remote peer.
+type cfFetchPeer interface {
+	QueueMessage(msg wire.Message, doneChan chan<- struct{})
+	Addr() string
+}
+
+// cfRequestKey identifies a committed filter or committed filter header by the
+// hash of the block it is for and its filter type.
+type cfRequestKey struct {
+	hash       chainhash.Hash
+	filterType wire.FilterType
+}
+
+// cfFilterRequest describes a caller waiting on a committed filter along with
+// the peer it is currently requested from.
+type cfFilterRequest struct {
+	peer cfFetchPeer
+	c    chan []byte
+}
+
+// cfHeaderRequest describes a caller waiting on a committed filter header along
+// with the peer it is currently requested from.
+type cfHeaderRequest struct {
+	peer cfFetchPeer
+	c    chan chainhash.Hash
+}
+
+// cfFetcher requests committed filters and committed filter headers from
+// remote peers on demand and delivers their responses to the callers waiting
+// on them.  It is used in headers-only mode where the block data needed to
+// build them is not available.
+//
+// Responses are only delivered when they are from the peer the data is
+// currently requested from.  Further, filters are only accepted when they match
+// the filter headers of their block and its parent.  Note that the filter
+// headers themselves are trusted to be what the responding peer claims.
+type cfFetcher struct {
+	timeout time.Duration
+
+	mtx     sync.Mutex
+	filters map[cfRequestKey][]*cfFilterRequest
+	headers map[cfRequestKey][]*cfHeaderRequest
+}
+
+// newCFFetcher returns a new committed filter fetcher that waits for the
+// passed duration for each peer to respond.
+func newCFFetcher(timeout time.Duration) *cfFetcher {
+	return &cfFetcher{
+		timeout: timeout,
+		filters: make(map[cfRequestKey][]*cfFilterRequest),
+		headers: make(map[cfRequestKey][]*cfHeaderRequest),
+	}
+}
+
+// filterMatchesHeader returns whether or not the passed serialized committed
+// filter is the one committed to by the passed filter header given the filter
+// header of the previous block.
+func filterMatchesHeader(data []byte, prevHeader, header *chainhash.Hash) bool {
+	filter, err := gcs.FromNBytes(blockcf.P, data)
+	if err != nil {
+		return false
+	}
+	return gcs.MakeHeaderForFilter(filter, prevHeader) == *header
+}
+
+// FetchFilter requests the committed filter of the passed type for the block
+// with the passed hash from the provided peers in turn until one of them
+// responds with a filter that matches the filter headers of the block and its
+// parent, which are fetched first.  The previous block hash must be nil for
+// the genesis block.  The quit channel interrupts the request.
+func (f *cfFetcher) FetchFilter(peers []cfFetchPeer, hash, prevHash *chainhash.Hash, filterType wire.FilterType, quit <-chan struct{}) ([]byte, error) {
+	header, err := f.FetchFilterHeader(peers, hash, filterType, quit)
+	if err != nil {
+		return nil, err
+	}
+	var prevHeader chainhash.Hash
+	if prevHash != nil {
+		prevHeader, err = f.FetchFilterHeader(peers, prevHash, filterType,
+			quit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	key := cfRequestKey{hash: *hash, filterType: filterType}
+	req := &cfFilterRequest{c: make(chan []byte, 1)}
+	f.mtx.Lock()
+	f.filters[key] = append(f.filters[key], req)
+	f.mtx.Unlock()
+	defer func() {
+		f.mtx.Lock()
+		f.filters[key] = removeFilterRequest(f.filters[key], req)
+		if len(f.filters[key]) == 0 {
+			delete(f.filters, key)
+		}
+		f.mtx.Unlock()
+	}()
+
+	for i, p := range peers {
+		if i == maxCFFetchPeers {
+			break
+		}
+
+		// Only accept a response from the peer the filter is requested
+		// from and discard any response from the previous peer that
+		// arrived after it timed out.
+		f.mtx.Lock()
+		req.peer = p
+		select {
+		case <-req.c:
+		default:
+		}
+		f.mtx.Unlock()
+
+		p.QueueMessage(wire.NewMsgGetCFilter(hash, filterType), nil)
+		select {
+		case data := <-req.c:
+			if filterMatchesHeader(data, &prevHeader, &header) {
+				return data, nil
+			}
+			peerLog.Debugf("Peer %s sent a %v filter for block %v "+
+				"that does not match its filter header", p.Addr(),
+				filterType, hash)
+		case <-time.After(f.timeout):
+			peerLog.Debugf("Timeout waiting for %v filter for block %v "+
+				"from peer %s", filterType, hash, p.Addr())
+		case <-quit:
+			return nil, errCFFetchShutdown
+		}
+	}
+
+	return nil, fmt.Errorf("no peers provided the %v filter for block %v",
+		filterType, hash)
+}
+
+// FetchFilterHeader requests the committed filter header of the passed type
+// for the block with the passed hash from the provided peers in turn until one
+// of them responds.  The quit channel interrupts the request.
+func (f *cfFetcher) FetchFilterHeader(peers []cfFetchPeer, hash *chainhash.Hash, filterType wire.FilterType, quit <-chan struct{}) (chainhash.Hash, error) {
+	key := cfRequestKey{hash: *hash, filterType: filterType}
+	req := &cfHeaderRequest{c: make(chan chainhash.Hash, 1)}
+	f.mtx.Lock()
+	f.headers[key] = append(f.headers[key], req)
+	f.mtx.Unlock()
+	defer func() {
+		f.mtx.Lock()
+		f.headers[key] = removeHeaderRequest(f.headers[key], req)
+		if len(f.headers[key]) == 0 {
+			delete(f.headers, key)
+		}
+		f.mtx.Unlock()
+	}()
+
+	// A request without any block locators and the block hash as the stop
+	// hash requests the header for only that block.
+	for i, p := range peers {
+		if i == maxCFFetchPeers {
+			break
+		}
+
+		// Only accept a response from the peer the header is requested
+		// from and discard any response from the previous peer that
+		// arrived after it timed out.
+		f.mtx.Lock()
+		req.peer = p
+		select {
+		case <-req.c:
+		default:
+		}
+		f.mtx.Unlock()
+
+		msg := wire.NewMsgGetCFHeaders()
+		msg.HashStop = *hash
+		msg.FilterType = filterType
+		p.QueueMessage(msg, nil)
+		select {
+		case header := <-req.c:
+			return header, nil
+		case <-time.After(f.timeout):
+			peerLog.Debugf("Timeout waiting for %v filter header for "+
+				"block %v from peer %s", filterType, hash, p.Addr())
+		case <-quit:
+			return chainhash.Hash{}, errCFFetchShutdown
+		}
+	}
+
+	return chainhash.Hash{}, fmt.Errorf("no peers provided the %v filter "+
+		"header for block %v", filterType, hash)
+}
+
+// DeliverFilter delivers the passed committed filter from the passed peer to
+// all callers waiting on it from that peer.  It returns whether or not the
+// filter was requested from the peer.
+func (f *cfFetcher) DeliverFilter(p cfFetchPeer, msg *wire.MsgCFilter) bool {
+	key := cfRequestKey{hash: msg.BlockHash, filterType: msg.FilterType}
+	var delivered bool
+	f.mtx.Lock()
+	for _, req := range f.filters[key] {
+		if req.peer != p {
+			continue
+		}
+		select {
+		case req.c <- msg.Data:
+		default:
+		}
+		delivered = true
+	}
+	f.mtx.Unlock()
+	return delivered
+}
+
+// DeliverFilterHeaders delivers the committed filter header for the stop hash
+// of the passed message from the passed peer to all callers waiting on it from
+// that peer.  It returns whether or not the header was requested from the peer.
+func (f *cfFetcher) DeliverFilterHeaders(p cfFetchPeer, msg *wire.MsgCFHeaders) bool {
+	if len(msg.HeaderHashes) == 0 {
+		return false
+	}
+
+	key := cfRequestKey{hash: msg.StopHash, filterType: msg.FilterType}
+	header := *msg.HeaderHashes[len(msg.HeaderHashes)-1]
+	var delivered bool
+	f.mtx.Lock()
+	for _, req := range f.headers[key] {
+		if req.peer != p {
+			continue
+		}
+		select {
+		case req.c <- header:
+		default:
+		}
+		delivered = true
+	}
+	f.mtx.Unlock()
+	return delivered
+}
+
+// removeFilterRequest returns the passed slice of requests without the provided
+// request.
+func removeFilterRequest(reqs []*cfFilterRequest, req *cfFilterRequest) []*cfFilterRequest {
+	for i := range reqs {
+		if reqs[i] == req {
+			return append(reqs[:i], reqs[i+1:]...)
+		}
+	}
+	return reqs
+}
+
+// removeHeaderRequest returns the passed slice of requests without the provided
+// request.
+func removeHeaderRequest(reqs []*cfHeaderRequest, req *cfHeaderRequest) []*cfHeaderRequest {
+	for i := range reqs {
+		if reqs[i] == req {
+			return append(reqs[:i], reqs[i+1:]...)
+		}
+	}
+	return reqs
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/gcs"
+	"github.com/decred/dcrd/gcs/blockcf"
+	"github.com/decred/dcrd/wire"
+)
+
+// fakeCFPeer provides a fake peer that responds to committed filter and
+// committed filter header requests via a committed filter fetcher when it is
+// not silent.
+type fakeCFPeer struct {
+	fetcher  *cfFetcher
+	silent   bool
+	filter   []byte
+	headers  map[chainhash.Hash]chainhash.Hash
+	requests int
+}
+
+// QueueMessage records the request and responds to it unless the peer is
+// silent.
+func (p *fakeCFPeer) QueueMessage(msg wire.Message, doneChan chan<- struct{}) {
+	p.requests++
+	if p.silent {
+		return
+	}
+
+	switch m := msg.(type) {
+	case *wire.MsgGetCFilter:
+		resp := wire.NewMsgCFilter(&m.BlockHash, m.FilterType, p.filter)
+		go p.fetcher.DeliverFilter(p, resp)
+
+	case *wire.MsgGetCFHeaders:
+		resp := wire.NewMsgCFHeaders()
+		resp.StopHash = m.HashStop
+		resp.FilterType = m.FilterType
+		header := p.headers[m.HashStop]
+		resp.AddCFHeader(&header)
+		go p.fetcher.DeliverFilterHeaders(p, resp)
+	}
+}
+
+// Addr returns a fake address for the peer.
+func (p *fakeCFPeer) Addr() string {
+	return "127.0.0.1:9108"
+}
+
+// TestCFFetcher ensures committed filters and committed filter headers are
+// fetched from the peers in turn until one of them responds with valid data
+// and that responses are only accepted from the peers they were requested
+// from.
+func TestCFFetcher(t *testing.T) {
+	// Create a filter along with the filter headers of its block and the
+	// previous block that commit to it.
+	blockHash, prevBlockHash := chainhash.Hash{0x01}, chainhash.Hash{0x02}
+	f, err := gcs.NewFilter(blockcf.P, blockcf.Key(&blockHash),
+		[][]byte{{0x01, 0x02, 0x03}})
+	if err != nil {
+		t.Fatalf("failed to create filter: %v", err)
+	}
+	prevHeader := chainhash.Hash{0x03}
+	headers := map[chainhash.Hash]chainhash.Hash{
+		prevBlockHash: prevHeader,
+		blockHash:     gcs.MakeHeaderForFilter(f, &prevHeader),
+	}
+
+	fetcher := newCFFetcher(50 * time.Millisecond)
+	quit := make(chan struct{})
+	silentPeer := &fakeCFPeer{fetcher: fetcher, silent: true}
+	badPeer := &fakeCFPeer{
+		fetcher: fetcher,
+		filter:  append([]byte{0x00, 0x00, 0x00, 0x02}, f.Bytes()...),
+		headers: headers,
+	}
+	goodPeer := &fakeCFPeer{
+		fetcher: fetcher,
+		filter:  f.NBytes(),
+		headers: headers,
+	}
+
+	// Ensure the filter is fetched from the final peer after the first one
+	// fails to respond and the second one responds with a filter that does
+	// not match the filter headers.
+	peers := []cfFetchPeer{silentPeer, badPeer, goodPeer}
+	filter, err := fetcher.FetchFilter(peers, &blockHash, &prevBlockHash,
+		wire.GCSFilterRegular, quit)
+	if err != nil {
+		t.Fatalf("failed to fetch filter: %v", err)
+	}
+	if !bytes.Equal(filter, goodPeer.filter) {
+		t.Fatalf("unexpected filter -- got %x, want %x", filter,
+			goodPeer.filter)
+	}
+	if goodPeer.requests != 1 {
+		t.Fatalf("unexpected number of filter requests to final peer "+
+			"-- got %d, want 1", goodPeer.requests)
+	}
+
+	// Ensure a filter that does not match the filter headers is rejected.
+	_, err = fetcher.FetchFilter([]cfFetchPeer{badPeer}, &blockHash,
+		&prevBlockHash, wire.GCSFilterRegular, quit)
+	if err == nil {
+		t.Fatal("fetching mismatched filter did not fail")
+	}
+
+	// Ensure the filter header is fetched from the second peer after the
+	// first one fails to respond.
+	header, err := fetcher.FetchFilterHeader(peers, &blockHash,
+		wire.GCSFilterExtended, quit)
+	if err != nil {
+		t.Fatalf("failed to fetch filter header: %v", err)
+	}
+	if header != headers[blockHash] {
+		t.Fatalf("unexpected filter header -- got %v, want %v", header,
+			headers[blockHash])
+	}
+
+	// Ensure an error is returned when no peers respond.
+	_, err = fetcher.FetchFilterHeader([]cfFetchPeer{silentPeer}, &blockHash,
+		wire.GCSFilterRegular, quit)
+	if err == nil {
+		t.Fatal("fetching filter header from silent peer did not fail")
+	}
+
+	// Ensure unrequested responses are not delivered and no outstanding
+	// requests remain.
+	msg := wire.NewMsgCFilter(&blockHash, wire.GCSFilterRegular, nil)
+	if fetcher.DeliverFilter(goodPeer, msg) {
+		t.Fatal("unrequested filter was delivered")
+	}
+	if len(fetcher.filters) != 0 || len(fetcher.headers) != 0 {
+		t.Fatalf("unexpected outstanding requests -- got %d filters and "+
+			"%d headers", len(fetcher.filters), len(fetcher.headers))
+	}
+
+	// Ensure responses are only delivered when they are from the peer the
+	// data is currently requested from.
+	key := cfRequestKey{hash: blockHash, filterType: wire.GCSFilterRegular}
+	fetcher.filters[key] = []*cfFilterRequest{{
+		peer: silentPeer,
+		c:    make(chan []byte, 1),
+	}}
+	fetcher.headers[key] = []*cfHeaderRequest{{
+		peer: silentPeer,
+		c:    make(chan chainhash.Hash, 1),
+	}}
+	headersMsg := wire.NewMsgCFHeaders()
+	headersMsg.StopHash = blockHash
+	headersMsg.FilterType = wire.GCSFilterRegular
+	headersMsg.AddCFHeader(&prevHeader)
+	if fetcher.DeliverFilter(goodPeer, msg) {
+		t.Fatal("filter from a peer it was not requested from was delivered")
+	}
+	if fetcher.DeliverFilterHeaders(goodPeer, headersMsg) {
+		t.Fatal("filter header from a peer it was not requested from was " +
+			"delivered")
+	}
+	if !fetcher.DeliverFilter(silentPeer, msg) {
+		t.Fatal("requested filter was not delivered")
+	}
+	if !fetcher.DeliverFilterHeaders(silentPeer, headersMsg) {
+		t.Fatal("requested filter header was not delivered")
+	}
+	delete(fetcher.filters, key)
+	delete(fetcher.headers, key)
+
+	// Ensure shutdown interrupts the request.
+	close(quit)
+	_, err = fetcher.FetchFilterHeader([]cfFetchPeer{silentPeer}, &blockHash,
+		wire.GCSFilterRegular, quit)
+	if err != errCFFetchShutdown {
+		t.Fatalf("unexpected error -- got %v, want %v", err,
+			errCFFetchShutdown)
+	}
+}
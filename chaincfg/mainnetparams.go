@@ -8,6 +8,7 @@ package chaincfg
 import (
 	"time"
 
+	"github.com/decred/dcrd/chaincfg/chainhash"
 	"github.com/decred/dcrd/wire"
 )
 
@@ -71,6 +72,14 @@ var MainNetParams = Params{
 		{295940, newHashFromStr("0000000000000000148852c8a919addf4043f9f267b13c08df051d359f1622ca")},
 	},
 
+	// There is no assumed valid block after the latest checkpoint yet.  It
+	// must be set by hand to a recent block after the latest checkpoint
+	// with each release.
+	AssumeValid: chainhash.Hash{},
+
+	// There are no known valid chain state snapshots.
+	Snapshots: nil,
+
 	// The miner confirmation window is defined as:
 	//   target proof of work timespan / target proof of work spacing
 	RuleChangeActivationQuorum:     4032, // 10 % of RuleChangeActivationInterval * TicketsPerBlock
@@ -73,6 +73,14 @@ type Checkpoint struct {
 	Hash   *chainhash.Hash
 }
 
+// Snapshot identifies a chain state snapshot that is known to be valid by the
+// height of the block it was created at and its commitment hash.  Snapshots are
+// only loaded when they are listed in the parameters for the network.
+type Snapshot struct {
+	Height int64
+	Hash   *chainhash.Hash
+}
+
 // Vote describes a voting instance.  It is self-describing so that the UI can
 // be directly implemented using the fields.  Mask determines which bits can be
 // used.  Bits are enumerated and must be consecutive.  Each vote requires one
@@ -338,6 +346,23 @@ type Params struct {
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints []Checkpoint
 
+	// AssumeValid is the hash of a block that has been externally verified
+	// to be valid.  Script validation is skipped for the block and all of
+	// its ancestors when it is part of the best known header chain.
+	//
+	// Script validation is already skipped for blocks at or before the
+	// latest checkpoint, so the assumed valid block only provides a benefit
+	// when it is after the latest checkpoint.  It is not derived from the
+	// checkpoints and must be updated by hand to a recent block with each
+	// release.
+	//
+	// The zero hash disables the feature.
+	AssumeValid chainhash.Hash
+
+	// Snapshots houses the chain state snapshots that are allowed to be
+	// loaded ordered from oldest to newest.
+	Snapshots []Snapshot
+
 	// These fields are related to voting on consensus rule changes as
 	// defined by BIP0009.
 	//
@@ -8,6 +8,7 @@ import (
 	"math"
 	"time"
 
+	"github.com/decred/dcrd/chaincfg/chainhash"
 	"github.com/decred/dcrd/wire"
 )
 
@@ -56,6 +57,12 @@ var RegNetParams = Params{
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints: nil,
 
+	// There is no assumed valid block.
+	AssumeValid: chainhash.Hash{},
+
+	// There are no known valid chain state snapshots.
+	Snapshots: nil,
+
 	// Consensus rule change deployments.
 	//
 	// The miner confirmation window is defined as:
@@ -8,6 +8,7 @@ package chaincfg
 import (
 	"time"
 
+	"github.com/decred/dcrd/chaincfg/chainhash"
 	"github.com/decred/dcrd/wire"
 )
 
@@ -55,6 +56,14 @@ var TestNet3Params = Params{
 		{83520, newHashFromStr("0000000001e6244d95feae8b598e854905158c7bc781daf874afff88675ef0c8")},
 	},
 
+	// There is no assumed valid block after the latest checkpoint yet.  It
+	// must be set by hand to a recent block after the latest checkpoint
+	// with each release.
+	AssumeValid: chainhash.Hash{},
+
+	// There are no known valid chain state snapshots.
+	Snapshots: nil,
+
 	// Consensus rule change deployments.
 	//
 	// The miner confirmation window is defined as:
@@ -0,0 +1,290 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/dchest/siphash"
+	"github.com/decred/dcrd/blockchain"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/wire"
+)
+
+const (
+	// maxHighBandwidthCmpctPeers is the maximum number of outbound peers
+	// that are asked to announce new blocks by directly sending compact
+	// blocks as opposed to inventory vectors.
+	maxHighBandwidthCmpctPeers = 3
+
+	// maxCmpctBlockDepth is the maximum depth from the tip of the main chain
+	// for which compact blocks are served in response to getdata requests.
+	// Requests for blocks deeper than this are served full blocks instead
+	// since the requesting peer is unlikely to have their transactions.
+	maxCmpctBlockDepth = 5
+
+	// maxBlockTxnDepth is the maximum depth from the tip of the main chain
+	// for which individual block transactions are served in response to
+	// getblocktxn requests.  Requests for blocks deeper than this are
+	// served full blocks instead.
+	maxBlockTxnDepth = 10
+)
+
+var (
+	// errCmpctShortIDCollision is returned when a compact block contains
+	// duplicate short transaction IDs within the same transaction tree
+	// which makes it impossible to reconstruct unambiguously.
+	errCmpctShortIDCollision = errors.New("compact block contains " +
+		"colliding short transaction ids")
+
+	// errCmpctBlockIncomplete is returned when attempting to assemble a
+	// block from a compact block that is still missing transactions.
+	errCmpctBlockIncomplete = errors.New("compact block is missing " +
+		"transactions")
+)
+
+// cmpctShortIDKeys returns the keys used to calculate the short transaction IDs
+// of a compact block with the passed header and nonce.  They are the first two
+// little-endian uint64s of the BLAKE-256 hash of the serialized header followed
+// by the little-endian nonce.
+func cmpctShortIDKeys(header *wire.BlockHeader, nonce uint64) (uint64, uint64) {
+	var buf [wire.MaxBlockHeaderPayload + 8]byte
+	headerBytes, _ := header.Bytes()
+	n := copy(buf[:], headerBytes)
+	binary.LittleEndian.PutUint64(buf[n:], nonce)
+	hash := chainhash.HashB(buf[:n+8])
+	return binary.LittleEndian.Uint64(hash[0:8]),
+		binary.LittleEndian.Uint64(hash[8:16])
+}
+
+// cmpctShortID returns the short transaction ID for the transaction with the
+// passed full hash (which commits to both the prefix and witness) using the
+// provided keys.
+func cmpctShortID(k0, k1 uint64, txHashFull *chainhash.Hash) uint64 {
+	return siphash.Hash(k0, k1, txHashFull[:]) & wire.MaxCmpctShortID
+}
+
+// newCmpctBlock returns a compact block for the passed block that uses the
+// provided nonce to key the short transaction IDs.  The coinbase is always
+// prefilled since it is impossible for other peers to already have it while
+// the remaining transactions of both trees are represented by short IDs.
+func newCmpctBlock(block *wire.MsgBlock, nonce uint64) *wire.MsgCmpctBlock {
+	msg := wire.NewMsgCmpctBlock(&block.Header, nonce)
+	k0, k1 := cmpctShortIDKeys(&block.Header, nonce)
+	for i, tx := range block.Transactions {
+		if i == 0 {
+			msg.PrefilledTxs = append(msg.PrefilledTxs, wire.PrefilledTx{
+				Index: 0,
+				Tx:    tx,
+			})
+			continue
+		}
+		txHash := tx.TxHashFull()
+		msg.ShortIDs = append(msg.ShortIDs, cmpctShortID(k0, k1, &txHash))
+	}
+	for _, stx := range block.STransactions {
+		txHash := stx.TxHashFull()
+		msg.SShortIDs = append(msg.SShortIDs, cmpctShortID(k0, k1, &txHash))
+	}
+	return msg
+}
+
+// cmpctTreeState houses the state of a single transaction tree of a block that
+// is being reconstructed from a compact block.
+type cmpctTreeState struct {
+	txns    []*wire.MsgTx
+	missing []uint32
+}
+
+// newCmpctTreeState places the prefilled transactions of a compact block tree at
+// their positions and returns the state along with a map of the short IDs to
+// the positions of the remaining transactions.
+func newCmpctTreeState(shortIDs []uint64, prefilled []wire.PrefilledTx) (*cmpctTreeState, map[uint64]int, error) {
+	numTxns := len(shortIDs) + len(prefilled)
+	tree := &cmpctTreeState{txns: make([]*wire.MsgTx, numTxns)}
+	for _, ptx := range prefilled {
+		tree.txns[ptx.Index] = ptx.Tx
+	}
+
+	positions := make(map[uint64]int, len(shortIDs))
+	var shortIDIdx int
+	for i := range tree.txns {
+		if tree.txns[i] != nil {
+			continue
+		}
+		shortID := shortIDs[shortIDIdx]
+		shortIDIdx++
+		if _, ok := positions[shortID]; ok {
+			return nil, nil, errCmpctShortIDCollision
+		}
+		positions[shortID] = i
+	}
+
+	return tree, positions, nil
+}
+
+// fill sets the transactions at the positions identified by the passed short
+// IDs from the candidate transactions and records the positions that remain
+// missing.  Short IDs that match more than one candidate are treated as
+// missing so the correct transaction is requested from the peer.
+func (t *cmpctTreeState) fill(positions map[uint64]int, candidates map[uint64]*wire.MsgTx) {
+	for shortID, pos := range positions {
+		if tx := candidates[shortID]; tx != nil {
+			t.txns[pos] = tx
+		}
+	}
+	for i, tx := range t.txns {
+		if tx == nil {
+			t.missing = append(t.missing, uint32(i))
+		}
+	}
+}
+
+// cmpctBlockState houses the state of a block that is being reconstructed from
+// a compact block.
+type cmpctBlockState struct {
+	header  wire.BlockHeader
+	hash    chainhash.Hash
+	regular *cmpctTreeState
+	stake   *cmpctTreeState
+}
+
+// newCmpctBlockState attempts to reconstruct the block described by the passed
+// compact block using the provided candidate transactions, which are typically
+// all of the transactions in the memory pool.  The returned state tracks the
+// transactions of each tree that could not be found so they can be requested
+// from the peer.
+func newCmpctBlockState(msg *wire.MsgCmpctBlock, candidates []*wire.MsgTx) (*cmpctBlockState, error) {
+	regular, regularPositions, err := newCmpctTreeState(msg.ShortIDs,
+		msg.PrefilledTxs)
+	if err != nil {
+		return nil, err
+	}
+	stake, stakePositions, err := newCmpctTreeState(msg.SShortIDs,
+		msg.PrefilledSTxs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Determine the short IDs of all candidate transactions that are
+	// referenced by the compact block.  Short IDs that match more than one
+	// candidate are marked with a nil entry so they are requested instead.
+	k0, k1 := cmpctShortIDKeys(&msg.Header, msg.Nonce)
+	matched := make(map[uint64]*wire.MsgTx)
+	for _, tx := range candidates {
+		txHash := tx.TxHashFull()
+		shortID := cmpctShortID(k0, k1, &txHash)
+		_, inRegular := regularPositions[shortID]
+		_, inStake := stakePositions[shortID]
+		if !inRegular && !inStake {
+			continue
+		}
+		if _, ok := matched[shortID]; ok {
+			matched[shortID] = nil
+			continue
+		}
+		matched[shortID] = tx
+	}
+	regular.fill(regularPositions, matched)
+	stake.fill(stakePositions, matched)
+
+	return &cmpctBlockState{
+		header:  msg.Header,
+		hash:    msg.Header.BlockHash(),
+		regular: regular,
+		stake:   stake,
+	}, nil
+}
+
+// isComplete returns whether or not all transactions of the block are known.
+func (s *cmpctBlockState) isComplete() bool {
+	return len(s.regular.missing) == 0 && len(s.stake.missing) == 0
+}
+
+// getBlockTxnMsg returns a getblocktxn message that requests all of the
+// transactions that are still missing.
+func (s *cmpctBlockState) getBlockTxnMsg() *wire.MsgGetBlockTxn {
+	return wire.NewMsgGetBlockTxn(&s.hash, s.regular.missing,
+		s.stake.missing)
+}
+
+// addBlockTxns fills in the missing transactions with those provided in
+// response to a getblocktxn message.  The transactions must be in the same
+// order as requested.
+func (s *cmpctBlockState) addBlockTxns(msg *wire.MsgBlockTxn) error {
+	if len(msg.Transactions) != len(s.regular.missing) ||
+		len(msg.STransactions) != len(s.stake.missing) {
+
+		return fmt.Errorf("blocktxn message contains %d regular and %d "+
+			"stake transactions while %d and %d were requested",
+			len(msg.Transactions), len(msg.STransactions),
+			len(s.regular.missing), len(s.stake.missing))
+	}
+
+	for i, pos := range s.regular.missing {
+		s.regular.txns[pos] = msg.Transactions[i]
+	}
+	for i, pos := range s.stake.missing {
+		s.stake.txns[pos] = msg.STransactions[i]
+	}
+	s.regular.missing = nil
+	s.stake.missing = nil
+	return nil
+}
+
+// msgBlock assembles the reconstructed block.  An error is returned when the
+// block is still missing transactions or when the merkle roots of the
+// reconstructed transaction trees do not match the header, which happens when
+// a short ID matched the wrong transaction.
+func (s *cmpctBlockState) msgBlock() (*wire.MsgBlock, error) {
+	if !s.isComplete() {
+		return nil, errCmpctBlockIncomplete
+	}
+
+	merkles := blockchain.BuildMsgTxMerkleTreeStore(s.regular.txns)
+	if !s.header.MerkleRoot.IsEqual(merkles[len(merkles)-1]) {
+		return nil, fmt.Errorf("reconstructed block %v has mismatched "+
+			"merkle root", s.hash)
+	}
+	stakeMerkles := blockchain.BuildMsgTxMerkleTreeStore(s.stake.txns)
+	if !s.header.StakeRoot.IsEqual(stakeMerkles[len(stakeMerkles)-1]) {
+		return nil, fmt.Errorf("reconstructed block %v has mismatched "+
+			"stake merkle root", s.hash)
+	}
+
+	return &wire.MsgBlock{
+		Header:        s.header,
+		Transactions:  s.regular.txns,
+		STransactions: s.stake.txns,
+	}, nil
+}
+
+// newBlockTxnMsg returns a blocktxn message for the passed block that contains
+// the transactions at the requested indexes of each tree.  An error is
+// returned if any of the indexes are out of range.
+func newBlockTxnMsg(block *dcrutil.Block, req *wire.MsgGetBlockTxn) (*wire.MsgBlockTxn, error) {
+	msgBlock := block.MsgBlock()
+	msg := wire.NewMsgBlockTxn(block.Hash())
+	for _, idx := range req.Indexes {
+		if int(idx) >= len(msgBlock.Transactions) {
+			return nil, fmt.Errorf("regular transaction index %d is "+
+				"out of range for block %v", idx, block.Hash())
+		}
+		msg.Transactions = append(msg.Transactions,
+			msgBlock.Transactions[idx])
+	}
+	for _, idx := range req.STxIndexes {
+		if int(idx) >= len(msgBlock.STransactions) {
+			return nil, fmt.Errorf("stake transaction index %d is "+
+				"out of range for block %v", idx, block.Hash())
+		}
+		msg.STransactions = append(msg.STransactions,
+			msgBlock.STransactions[idx])
+	}
+	return msg, nil
+}
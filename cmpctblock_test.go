@@ -0,0 +1,175 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/decred/dcrd/blockchain"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/wire"
+)
+
+// cmpctTestTx returns a unique transaction for use in the compact block tests
+// based on the passed seed.
+func cmpctTestTx(seed uint32) *wire.MsgTx {
+	var prevHash chainhash.Hash
+	prevHash[0] = byte(seed)
+	prevHash[1] = byte(seed >> 8)
+	tx := wire.NewMsgTx()
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&prevHash, seed, 0),
+		int64(seed)+1, []byte{0x51}))
+	tx.AddTxOut(wire.NewTxOut(int64(seed), []byte{0x51}))
+	return tx
+}
+
+// cmpctTestBlock returns a block with the given number of regular and stake
+// transactions along with the merkle roots in the header that commit to them.
+func cmpctTestBlock(numTxns, numSTxns uint32) *wire.MsgBlock {
+	block := &wire.MsgBlock{Header: wire.BlockHeader{Height: 100}}
+	for i := uint32(0); i < numTxns; i++ {
+		block.Transactions = append(block.Transactions, cmpctTestTx(i))
+	}
+	for i := uint32(0); i < numSTxns; i++ {
+		block.STransactions = append(block.STransactions,
+			cmpctTestTx(1000+i))
+	}
+	merkles := blockchain.BuildMsgTxMerkleTreeStore(block.Transactions)
+	block.Header.MerkleRoot = *merkles[len(merkles)-1]
+	stakeMerkles := blockchain.BuildMsgTxMerkleTreeStore(block.STransactions)
+	block.Header.StakeRoot = *stakeMerkles[len(stakeMerkles)-1]
+	return block
+}
+
+// TestCmpctBlockReconstruct ensures blocks are reconstructed from compact
+// blocks both when all transactions are already known and when some of them
+// must be requested via getblocktxn.
+func TestCmpctBlockReconstruct(t *testing.T) {
+	block := cmpctTestBlock(5, 3)
+	cmpctBlock := newCmpctBlock(block, 0x1122334455667788)
+	if len(cmpctBlock.PrefilledTxs) != 1 || cmpctBlock.PrefilledTxs[0].Index != 0 {
+		t.Fatalf("coinbase is not prefilled: %v", spew.Sdump(cmpctBlock))
+	}
+
+	// Ensure the block is immediately complete when all of the transactions
+	// are candidates.  Include an unrelated transaction as well.
+	var candidates []*wire.MsgTx
+	candidates = append(candidates, block.Transactions[1:]...)
+	candidates = append(candidates, block.STransactions...)
+	candidates = append(candidates, cmpctTestTx(5000))
+	state, err := newCmpctBlockState(cmpctBlock, candidates)
+	if err != nil {
+		t.Fatalf("newCmpctBlockState: unexpected error: %v", err)
+	}
+	if !state.isComplete() {
+		t.Fatal("block is not complete with all transactions known")
+	}
+	got, err := state.msgBlock()
+	if err != nil {
+		t.Fatalf("msgBlock: unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, block) {
+		t.Fatalf("mismatched block\ngot: %v\nwant: %v", spew.Sdump(got),
+			spew.Sdump(block))
+	}
+
+	// Remove a regular and a stake transaction from the candidates and
+	// ensure they are requested.
+	candidates = []*wire.MsgTx{block.Transactions[1], block.Transactions[2],
+		block.Transactions[4], block.STransactions[0],
+		block.STransactions[2]}
+	state, err = newCmpctBlockState(cmpctBlock, candidates)
+	if err != nil {
+		t.Fatalf("newCmpctBlockState: unexpected error: %v", err)
+	}
+	if state.isComplete() {
+		t.Fatal("block is complete with missing transactions")
+	}
+	if _, err := state.msgBlock(); err != errCmpctBlockIncomplete {
+		t.Fatalf("msgBlock: unexpected error - got %v, want %v", err,
+			errCmpctBlockIncomplete)
+	}
+	getBlockTxn := state.getBlockTxnMsg()
+	wantGetBlockTxn := wire.NewMsgGetBlockTxn(&state.hash, []uint32{3},
+		[]uint32{1})
+	if !reflect.DeepEqual(getBlockTxn, wantGetBlockTxn) {
+		t.Fatalf("mismatched getblocktxn\ngot: %v\nwant: %v",
+			spew.Sdump(getBlockTxn), spew.Sdump(wantGetBlockTxn))
+	}
+
+	// Ensure the response created from the full block fills in the missing
+	// transactions.
+	blockTxn, err := newBlockTxnMsg(dcrutil.NewBlock(block), getBlockTxn)
+	if err != nil {
+		t.Fatalf("newBlockTxnMsg: unexpected error: %v", err)
+	}
+	if err := state.addBlockTxns(blockTxn); err != nil {
+		t.Fatalf("addBlockTxns: unexpected error: %v", err)
+	}
+	got, err = state.msgBlock()
+	if err != nil {
+		t.Fatalf("msgBlock: unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, block) {
+		t.Fatalf("mismatched block\ngot: %v\nwant: %v", spew.Sdump(got),
+			spew.Sdump(block))
+	}
+}
+
+// TestCmpctBlockErrors ensures invalid compact blocks and responses are
+// detected.
+func TestCmpctBlockErrors(t *testing.T) {
+	block := cmpctTestBlock(4, 2)
+
+	// Ensure duplicate short IDs within a tree are rejected.
+	cmpctBlock := newCmpctBlock(block, 1)
+	cmpctBlock.ShortIDs[1] = cmpctBlock.ShortIDs[0]
+	_, err := newCmpctBlockState(cmpctBlock, nil)
+	if err != errCmpctShortIDCollision {
+		t.Fatalf("newCmpctBlockState: unexpected error - got %v, want %v",
+			err, errCmpctShortIDCollision)
+	}
+
+	// Ensure a blocktxn response with the wrong number of transactions is
+	// rejected.
+	cmpctBlock = newCmpctBlock(block, 2)
+	state, err := newCmpctBlockState(cmpctBlock, nil)
+	if err != nil {
+		t.Fatalf("newCmpctBlockState: unexpected error: %v", err)
+	}
+	blockTxn := wire.NewMsgBlockTxn(&state.hash)
+	if err := state.addBlockTxns(blockTxn); err == nil {
+		t.Fatal("addBlockTxns: unexpected success with missing " +
+			"transactions")
+	}
+
+	// Ensure a response containing the wrong transactions is detected via
+	// the merkle root.
+	blockTxn.Transactions = append(blockTxn.Transactions,
+		block.Transactions[1:]...)
+	blockTxn.Transactions[0] = cmpctTestTx(5000)
+	blockTxn.STransactions = block.STransactions
+	if err := state.addBlockTxns(blockTxn); err != nil {
+		t.Fatalf("addBlockTxns: unexpected error: %v", err)
+	}
+	if _, err := state.msgBlock(); err == nil {
+		t.Fatal("msgBlock: unexpected success with wrong transaction")
+	}
+
+	// Ensure requests for transactions outside of the block are rejected.
+	req := wire.NewMsgGetBlockTxn(&state.hash, []uint32{4}, nil)
+	if _, err := newBlockTxnMsg(dcrutil.NewBlock(block), req); err == nil {
+		t.Fatal("newBlockTxnMsg: unexpected success for out of range " +
+			"regular index")
+	}
+	req = wire.NewMsgGetBlockTxn(&state.hash, nil, []uint32{2})
+	if _, err := newBlockTxnMsg(dcrutil.NewBlock(block), req); err == nil {
+		t.Fatal("newBlockTxnMsg: unexpected success for out of range " +
+			"stake index")
+	}
+}
@@ -22,13 +22,16 @@ import (
 	"time"
 
 	"github.com/btcsuite/go-socks/socks"
+	"github.com/decred/dcrd/chaincfg/chainhash"
 	"github.com/decred/dcrd/connmgr"
 	"github.com/decred/dcrd/database"
 	_ "github.com/decred/dcrd/database/ffldb"
 	"github.com/decred/dcrd/dcrutil"
 	"github.com/decred/dcrd/internal/version"
 	"github.com/decred/dcrd/mempool/v2"
+	"github.com/decred/dcrd/mining"
 	"github.com/decred/dcrd/sampleconfig"
+	"github.com/decred/dcrd/txscript"
 	"github.com/decred/slog"
 	flags "github.com/jessevdk/go-flags"
 )
@@ -41,6 +44,8 @@ const (
 	defaultLogFilename           = "dcrd.log"
 	defaultMaxSameIP             = 5
 	defaultMaxPeers              = 125
+	defaultBlockRelayOnlyPeers   = 2
+	defaultMaxAnchors            = 2
 	defaultBanDuration           = time.Hour * 24
 	defaultBanThreshold          = 100
 	defaultMaxRPCClients         = 10
@@ -51,13 +56,20 @@ const (
 	defaultBlockMinSize          = 0
 	defaultBlockMaxSize          = 375000
 	blockMaxSizeMin              = 1000
+	pruneTargetMin               = 1024
 	defaultAddrIndex             = false
 	defaultGenerate              = false
 	defaultNoMiningStateSync     = false
 	defaultAllowOldVotes         = false
 	defaultMaxOrphanTransactions = 1000
 	defaultMaxOrphanTxSize       = 5000
+	defaultMaxMempoolSize        = 300
+	defaultMaxAncestorCount      = 25
+	defaultMaxAncestorSize       = 101000
+	defaultMaxDescendantCount    = 25
+	defaultMaxDescendantSize     = 101000
 	defaultSigCacheMaxSize       = 100000
+	defaultUtxoCacheMaxSize      = 150
 	defaultTxIndex               = false
 	defaultNoExistsAddrIndex     = false
 	defaultNoCFilters            = false
@@ -103,6 +115,9 @@ type config struct {
 	Listeners            []string      `long:"listen" description:"Add an interface/port to listen for connections (default all interfaces port: 9108, testnet: 19108)"`
 	MaxSameIP            int           `long:"maxsameip" description:"Max number of connections with the same IP -- 0 to disable"`
 	MaxPeers             int           `long:"maxpeers" description:"Max number of inbound and outbound peers"`
+	BlockRelayOnlyPeers  int           `long:"blockrelayonlypeers" description:"Number of additional outbound peers that are only used to relay blocks"`
+	MaxAnchors           int           `long:"maxanchors" description:"Max number of block relay only peers to save on shutdown and reconnect to first on startup"`
+	MaxUploadTarget      uint32        `long:"maxuploadtarget" description:"Max number of MiB to upload to peers per 24 hours -- Historical blocks are no longer served to non-whitelisted peers once it is reached -- 0 to disable"`
 	DisableBanning       bool          `long:"nobanning" description:"Disable banning of misbehaving peers"`
 	BanDuration          time.Duration `long:"banduration" description:"How long to ban misbehaving peers.  Valid time units are {s, m, h}.  Minimum 1 second"`
 	BanThreshold         uint32        `long:"banthreshold" description:"Maximum allowed ban score before disconnecting and banning misbehaving peers."`
@@ -129,10 +144,12 @@ type config struct {
 	OnionProxyPass       string        `long:"onionpass" default-mask:"-" description:"Password for onion proxy server"`
 	NoOnion              bool          `long:"noonion" description:"Disable connecting to tor hidden services"`
 	TorIsolation         bool          `long:"torisolation" description:"Enable Tor stream isolation by randomizing user credentials for each connection."`
+	NoV2Transport        bool          `long:"nov2transport" description:"Disable the encrypted v2 peer-to-peer transport"`
 	TestNet              bool          `long:"testnet" description:"Use the test network"`
 	SimNet               bool          `long:"simnet" description:"Use the simulation test network"`
 	RegNet               bool          `long:"regnet" description:"Use the regression test network"`
 	DisableCheckpoints   bool          `long:"nocheckpoints" description:"Disable built-in checkpoints.  Don't do this unless you know what you're doing."`
+	AssumeValid          string        `long:"assumevalid" description:"Hash of a block that is assumed to be valid, which skips script validation for it and its ancestors when it is part of the best header chain -- 0 to validate all scripts (default: network specific)"`
 	DbType               string        `long:"dbtype" description:"Database backend to use for the Block Chain"`
 	Profile              string        `long:"profile" description:"Enable HTTP profiling on given [addr:]port -- NOTE port must be between 1024 and 65536"`
 	CPUProfile           string        `long:"cpuprofile" description:"Write CPU profile to the specified file"`
@@ -145,16 +162,25 @@ type config struct {
 	FreeTxRelayLimit     float64       `long:"limitfreerelay" description:"Limit relay of transactions with no transaction fee to the given amount in thousands of bytes per minute"`
 	NoRelayPriority      bool          `long:"norelaypriority" description:"Do not require free or low-fee transactions to have high priority for relaying"`
 	MaxOrphanTxs         int           `long:"maxorphantx" description:"Max number of orphan transactions to keep in memory"`
+	MaxMempoolSize       uint32        `long:"maxmempool" description:"Max size in MiB of the transaction memory pool -- Transactions with the lowest fee rates are evicted when it is exceeded -- 0 to disable"`
+	NoPersistMempool     bool          `long:"nopersistmempool" description:"Do not save the mempool on shutdown and load it on startup"`
 	Generate             bool          `long:"generate" description:"Generate (mine) coins using the CPU"`
 	MiningAddrs          []string      `long:"miningaddr" description:"Add the specified payment address to the list of addresses to use for generated blocks -- At least one address is required if the generate option is set"`
 	BlockMinSize         uint32        `long:"blockminsize" description:"Mininum block size in bytes to be used when creating a block"`
 	BlockMaxSize         uint32        `long:"blockmaxsize" description:"Maximum block size in bytes to be used when creating a block"`
 	BlockPrioritySize    uint32        `long:"blockprioritysize" description:"Size in bytes for high-priority/low-fee transactions when creating a block"`
+	MiningSelector       string        `long:"miningselector" description:"Transaction selection strategy to use when creating a block {standard, feerate, ancestorfeerate, priority}"`
+	MiningAllowAddrs     []string      `long:"miningallowaddr" description:"Only include regular transactions paying to at least one of the specified addresses when creating a block -- May be specified multiple times"`
+	MiningDenyAddrs      []string      `long:"miningdenyaddr" description:"Do not include regular transactions paying to the specified address when creating a block -- May be specified multiple times"`
 	SigCacheMaxSize      uint          `long:"sigcachemaxsize" description:"The maximum number of entries in the signature verification cache"`
+	UtxoCacheMaxSize     uint64        `long:"utxocachemaxsize" description:"The maximum size in MiB of the in-memory cache of unspent transaction outputs that are written to the database in batches -- 0 to write them to the database as each block is processed"`
 	NonAggressive        bool          `long:"nonaggressive" description:"Disable mining off of the parent block of the blockchain if there aren't enough voters"`
 	NoMiningStateSync    bool          `long:"nominingstatesync" description:"Disable synchronizing the mining state with other nodes"`
 	AllowOldVotes        bool          `long:"allowoldvotes" description:"Enable the addition of very old votes to the mempool"`
 	BlocksOnly           bool          `long:"blocksonly" description:"Do not accept transactions from remote peers."`
+	Prune                uint64        `long:"prune" description:"Delete the oldest block data to keep the stored block data near the specified number of MiB while retaining the headers, UTXO set, and recent blocks -- Incompatible with --txindex and --addrindex -- 0 to disable (minimum 1024)"`
+	HeadersOnly          bool          `long:"headersonly" description:"Only download and validate block headers without maintaining the UTXO set -- Committed filters are fetched from peers on demand and transactions are not accepted"`
+	LoadSnapshot         string        `long:"loadsnapshot" description:"Load the chain state from the specified snapshot file created with the dumputxoset RPC when the database does not contain a chain yet and sync from its height while the history is validated in the background -- Only snapshots listed in the network parameters are accepted -- Incompatible with --txindex, --addrindex, and --headersonly"`
 	AcceptNonStd         bool          `long:"acceptnonstd" description:"Accept and relay non-standard transactions to the network regardless of the default settings for the active network."`
 	RejectNonStd         bool          `long:"rejectnonstd" description:"Reject non-standard transactions regardless of the default settings for the active network."`
 	TxIndex              bool          `long:"txindex" description:"Maintain a full hash-based transaction index which makes all transactions available via the getrawtransaction RPC"`
@@ -174,8 +200,12 @@ type config struct {
 	oniondial            func(string, string) (net.Conn, error)
 	dial                 func(string, string) (net.Conn, error)
 	miningAddrs          []dcrutil.Address
+	miningSelector       mining.NewTxSelectorFunc
+	miningAllowScripts   [][]byte
+	miningDenyScripts    [][]byte
 	minRelayTxFee        dcrutil.Amount
 	whitelists           []*net.IPNet
+	assumeValid          chainhash.Hash
 }
 
 // serviceOptions defines the configuration options for the daemon as a service on
@@ -437,6 +467,8 @@ func loadConfig() (*config, []string, error) {
 		DebugLevel:           defaultLogLevel,
 		MaxSameIP:            defaultMaxSameIP,
 		MaxPeers:             defaultMaxPeers,
+		BlockRelayOnlyPeers:  defaultBlockRelayOnlyPeers,
+		MaxAnchors:           defaultMaxAnchors,
 		BanDuration:          defaultBanDuration,
 		BanThreshold:         defaultBanThreshold,
 		RPCMaxClients:        defaultMaxRPCClients,
@@ -452,8 +484,11 @@ func loadConfig() (*config, []string, error) {
 		BlockMinSize:         defaultBlockMinSize,
 		BlockMaxSize:         defaultBlockMaxSize,
 		BlockPrioritySize:    mempool.DefaultBlockPrioritySize,
+		MiningSelector:       mining.TxSelectorStandard,
 		MaxOrphanTxs:         defaultMaxOrphanTransactions,
+		MaxMempoolSize:       defaultMaxMempoolSize,
 		SigCacheMaxSize:      defaultSigCacheMaxSize,
+		UtxoCacheMaxSize:     defaultUtxoCacheMaxSize,
 		Generate:             defaultGenerate,
 		NoMiningStateSync:    defaultNoMiningStateSync,
 		TxIndex:              defaultTxIndex,
@@ -735,6 +770,24 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// Don't allow negative block relay only peer and anchor counts.
+	if cfg.BlockRelayOnlyPeers < 0 {
+		str := "%s: the blockrelayonlypeers option may not be less " +
+			"than 0 -- parsed [%d]"
+		err := fmt.Errorf(str, funcName, cfg.BlockRelayOnlyPeers)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+	if cfg.MaxAnchors < 0 {
+		str := "%s: the maxanchors option may not be less than 0 " +
+			"-- parsed [%d]"
+		err := fmt.Errorf(str, funcName, cfg.MaxAnchors)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// Validate any given whitelisted IP addresses and networks.
 	if len(cfg.Whitelists) > 0 {
 		var ip net.IP
@@ -870,6 +923,23 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// Ensure the upload target leaves room for serving historical blocks
+	// after reserving the space needed to relay new blocks.
+	const bytesPerMiB = 1024 * 1024
+	minUploadTargetMiB := (minUploadTarget(activeNetParams.Params) +
+		bytesPerMiB - 1) / bytesPerMiB
+	if cfg.MaxUploadTarget != 0 &&
+		uint64(cfg.MaxUploadTarget) < minUploadTargetMiB {
+
+		str := "%s: the maxuploadtarget option must be 0 or at least %d " +
+			"MiB to leave room for relaying new blocks -- parsed [%d]"
+		err := fmt.Errorf(str, funcName, minUploadTargetMiB,
+			cfg.MaxUploadTarget)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// Limit the max orphan count to a sane vlue.
 	if cfg.MaxOrphanTxs < 0 {
 		str := "%s: the maxorphantx option may not be less than 0 " +
@@ -933,6 +1003,26 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// Parse the assumed valid block hash, defaulting to the one for the
+	// active network.
+	switch cfg.AssumeValid {
+	case "":
+		cfg.assumeValid = activeNetParams.AssumeValid
+	case "0":
+		// Leave the zero hash so the scripts of all blocks are validated.
+	default:
+		hash, err := chainhash.NewHashFromStr(cfg.AssumeValid)
+		if err != nil {
+			str := "%s: the assumevalid option '%s' is not a valid " +
+				"block hash: %v"
+			err := fmt.Errorf(str, funcName, cfg.AssumeValid, err)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+		cfg.assumeValid = *hash
+	}
+
 	// Check mining addresses are valid and saved parsed versions.
 	cfg.miningAddrs = make([]dcrutil.Address, 0, len(cfg.MiningAddrs))
 	for _, strAddr := range cfg.MiningAddrs {
@@ -954,6 +1044,113 @@ func loadConfig() (*config, []string, error) {
 		cfg.miningAddrs = append(cfg.miningAddrs, addr)
 	}
 
+	// Ensure the transaction selection strategy exists.
+	var ok bool
+	cfg.miningSelector, ok = mining.TxSelectorByName(cfg.MiningSelector)
+	if !ok {
+		str := "%s: the miningselector option must be one of %v -- " +
+			"got %q"
+		err := fmt.Errorf(str, funcName, mining.TxSelectorNames(),
+			cfg.MiningSelector)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Check the addresses used to filter the transactions included when
+	// creating a block are valid and save the scripts that pay to them.
+	addrsToScripts := func(option string, strAddrs []string) ([][]byte, error) {
+		scripts := make([][]byte, 0, len(strAddrs))
+		for _, strAddr := range strAddrs {
+			addr, err := dcrutil.DecodeAddress(strAddr)
+			if err != nil {
+				str := "%s: %s address '%s' failed to decode: %v"
+				return nil, fmt.Errorf(str, funcName, option, strAddr, err)
+			}
+			if !addr.IsForNet(activeNetParams.Params) {
+				str := "%s: %s address '%s' is on the wrong network"
+				return nil, fmt.Errorf(str, funcName, option, strAddr)
+			}
+			script, err := txscript.PayToAddrScript(addr)
+			if err != nil {
+				str := "%s: %s address '%s' is not supported: %v"
+				return nil, fmt.Errorf(str, funcName, option, strAddr, err)
+			}
+			scripts = append(scripts, script)
+		}
+		return scripts, nil
+	}
+	cfg.miningAllowScripts, err = addrsToScripts("miningallowaddr",
+		cfg.MiningAllowAddrs)
+	if err == nil {
+		cfg.miningDenyScripts, err = addrsToScripts("miningdenyaddr",
+			cfg.MiningDenyAddrs)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Validate the prune target.
+	if cfg.Prune != 0 && cfg.Prune < pruneTargetMin {
+		str := "%s: the prune option must be 0 to disable it or at " +
+			"least %d MiB -- parsed [%d]"
+		err := fmt.Errorf(str, funcName, pruneTargetMin, cfg.Prune)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --prune does not mix with the indexes that rely on the block data of
+	// all blocks being available.
+	if cfg.Prune != 0 && (cfg.TxIndex || cfg.AddrIndex) {
+		str := "%s: the --prune option may not be activated at the " +
+			"same time as the --txindex or --addrindex options"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --headersonly does not mix with the options that rely on the full
+	// block data or the UTXO set.
+	if cfg.HeadersOnly && (cfg.TxIndex || cfg.AddrIndex || cfg.Generate) {
+		str := "%s: the --headersonly option may not be activated at " +
+			"the same time as the --txindex, --addrindex, or " +
+			"--generate options"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --loadsnapshot does not mix with the options that rely on the full
+	// block data or the UTXO set.
+	if cfg.LoadSnapshot != "" &&
+		(cfg.TxIndex || cfg.AddrIndex || cfg.HeadersOnly) {
+
+		str := "%s: the --loadsnapshot option may not be activated at " +
+			"the same time as the --txindex, --addrindex, or " +
+			"--headersonly options"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	if cfg.LoadSnapshot != "" {
+		cfg.LoadSnapshot = cleanAndExpandPath(cfg.LoadSnapshot)
+	}
+
+	// Transactions can't be validated without the UTXO set, so they are
+	// not accepted from remote peers in headers-only mode.  The same
+	// applies to the votes synchronized with the mining state.
+	if cfg.HeadersOnly {
+		cfg.BlocksOnly = true
+		cfg.NoMiningStateSync = true
+	}
+
 	// Ensure there is at least one mining address when the generate flag is
 	// set.
 	if cfg.Generate && len(cfg.MiningAddrs) == 0 {
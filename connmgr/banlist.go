@@ -0,0 +1,375 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package connmgr
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// banListVersion is the current version of the serialized ban list.
+	banListVersion = 1
+
+	// maxBanListEntries is the maximum number of bans a ban list houses.
+	// The bans that expire soonest are removed once it is exceeded.
+	maxBanListEntries = 10000
+
+	// banListSaveDelay is how long to wait after the ban list is modified
+	// before persisting it so bursts of modifications only result in a
+	// single write.
+	banListSaveDelay = 10 * time.Second
+)
+
+// BanEntry describes a banned IP address or subnet.
+type BanEntry struct {
+	// Subnet is the banned subnet.  Individual IP addresses are represented
+	// by subnets with all bits of the mask set.
+	Subnet *net.IPNet
+
+	// Created is the time the ban was created.
+	Created time.Time
+
+	// Expiry is the time the ban expires.
+	Expiry time.Time
+
+	// Reason is a human-readable description of why the ban was created.
+	Reason string
+}
+
+// serializedBanEntry is the persisted form of a BanEntry.
+type serializedBanEntry struct {
+	Subnet  string `json:"subnet"`
+	Created int64  `json:"created"`
+	Expiry  int64  `json:"expiry"`
+	Reason  string `json:"reason"`
+}
+
+// serializedBanList is the persisted form of a BanList.
+type serializedBanList struct {
+	Version int                  `json:"version"`
+	Bans    []serializedBanEntry `json:"bans"`
+}
+
+// ParseSubnet parses the passed string as either an individual IP address or
+// a subnet in CIDR notation.  Individual IP addresses are returned as a subnet
+// with all bits of the mask set.
+func ParseSubnet(s string) (*net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		_, subnet, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		return subnet, nil
+	}
+
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address or subnet %q", s)
+	}
+	bits := net.IPv6len * 8
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		bits = net.IPv4len * 8
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// BanList houses banned IP addresses and subnets along with when the bans
+// expire.  The list is persisted to a file shortly after it is modified so that
+// bans survive restarts.  Save must be called on shutdown to persist any
+// modifications that have not been saved yet.
+//
+// All methods are safe for concurrent access.
+type BanList struct {
+	filePath  string
+	timeNow   func() time.Time
+	saveDelay time.Duration
+
+	// saveMtx serializes writes to the file so that a snapshot of the list
+	// is never replaced by an older one.
+	saveMtx sync.Mutex
+
+	mtx       sync.Mutex
+	bans      map[string]*BanEntry
+	dirty     bool
+	saveTimer *time.Timer
+}
+
+// NewBanList returns a new empty ban list that is persisted to the passed file
+// path.  An empty path disables persistence.  Call Load to load any existing
+// bans from the file.
+func NewBanList(filePath string) *BanList {
+	return &BanList{
+		filePath:  filePath,
+		timeNow:   time.Now,
+		saveDelay: banListSaveDelay,
+		bans:      make(map[string]*BanEntry),
+	}
+}
+
+// removeExpired removes all expired bans and returns whether or not any were
+// removed.
+//
+// This function MUST be called with the mutex held (for writes).
+func (b *BanList) removeExpired() bool {
+	now := b.timeNow()
+	var removed bool
+	for key, entry := range b.bans {
+		if !now.Before(entry.Expiry) {
+			delete(b.bans, key)
+			removed = true
+		}
+	}
+	return removed
+}
+
+// limitSize removes the bans that expire soonest until the list houses no more
+// than the maximum allowed number of bans.
+//
+// This function MUST be called with the mutex held (for writes).
+func (b *BanList) limitSize() {
+	if len(b.bans) <= maxBanListEntries {
+		return
+	}
+
+	entries := make([]*BanEntry, 0, len(b.bans))
+	for _, entry := range b.bans {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Expiry.Before(entries[j].Expiry)
+	})
+	for _, entry := range entries[:len(entries)-maxBanListEntries] {
+		delete(b.bans, entry.Subnet.String())
+	}
+}
+
+// markDirty marks the ban list as modified and schedules it to be saved after
+// the save delay unless a save is already scheduled.
+//
+// This function MUST be called with the mutex held (for writes).
+func (b *BanList) markDirty() {
+	b.dirty = true
+	if b.filePath == "" || b.saveTimer != nil {
+		return
+	}
+	b.saveTimer = time.AfterFunc(b.saveDelay, func() {
+		if err := b.Save(); err != nil {
+			log.Errorf("Unable to save ban list: %v", err)
+		}
+	})
+}
+
+// Save writes the ban list to its file when it has been modified since it was
+// last saved.  Expired bans are not saved.  The list is written to a temporary
+// file which is then renamed into place so the file is never left partially
+// written.
+func (b *BanList) Save() error {
+	if b.filePath == "" {
+		return nil
+	}
+
+	b.saveMtx.Lock()
+	defer b.saveMtx.Unlock()
+
+	// Take a snapshot of the bans so the file is written without holding
+	// the mutex.
+	b.mtx.Lock()
+	if b.saveTimer != nil {
+		b.saveTimer.Stop()
+		b.saveTimer = nil
+	}
+	if !b.dirty {
+		b.mtx.Unlock()
+		return nil
+	}
+	b.removeExpired()
+	list := serializedBanList{
+		Version: banListVersion,
+		Bans:    make([]serializedBanEntry, 0, len(b.bans)),
+	}
+	for _, entry := range b.bans {
+		list.Bans = append(list.Bans, serializedBanEntry{
+			Subnet:  entry.Subnet.String(),
+			Created: entry.Created.Unix(),
+			Expiry:  entry.Expiry.Unix(),
+			Reason:  entry.Reason,
+		})
+	}
+	b.dirty = false
+	b.mtx.Unlock()
+
+	sort.Slice(list.Bans, func(i, j int) bool {
+		return list.Bans[i].Subnet < list.Bans[j].Subnet
+	})
+	err := writeBanList(b.filePath, &list)
+	if err != nil {
+		// Ensure the bans are saved again by the next save attempt.
+		b.mtx.Lock()
+		b.dirty = true
+		b.mtx.Unlock()
+	}
+	return err
+}
+
+// writeBanList writes the passed serialized ban list to the given file by way
+// of a temporary file that is renamed into place.
+func writeBanList(filePath string, list *serializedBanList) error {
+	serialized, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+
+	tmpFile := filePath + ".new"
+	if err := ioutil.WriteFile(tmpFile, serialized, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, filePath)
+}
+
+// Load replaces the bans with those persisted to the file of the ban list.  It
+// is not an error if the file does not exist.  Expired bans are discarded.
+func (b *BanList) Load() error {
+	if b.filePath == "" {
+		return nil
+	}
+
+	serialized, err := ioutil.ReadFile(b.filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var list serializedBanList
+	if err := json.Unmarshal(serialized, &list); err != nil {
+		return fmt.Errorf("unable to decode ban list %s: %v", b.filePath,
+			err)
+	}
+	if list.Version != banListVersion {
+		return fmt.Errorf("unknown ban list version %d in %s",
+			list.Version, b.filePath)
+	}
+
+	bans := make(map[string]*BanEntry, len(list.Bans))
+	for _, sEntry := range list.Bans {
+		_, subnet, err := net.ParseCIDR(sEntry.Subnet)
+		if err != nil {
+			return fmt.Errorf("invalid subnet in ban list %s: %v",
+				b.filePath, err)
+		}
+		bans[subnet.String()] = &BanEntry{
+			Subnet:  subnet,
+			Created: time.Unix(sEntry.Created, 0),
+			Expiry:  time.Unix(sEntry.Expiry, 0),
+			Reason:  sEntry.Reason,
+		}
+	}
+
+	b.mtx.Lock()
+	b.bans = bans
+	b.removeExpired()
+	b.limitSize()
+	b.mtx.Unlock()
+	return nil
+}
+
+// Ban bans the passed subnet until the provided expiry time for the given
+// reason and schedules the ban list to be saved.  An existing ban of the same
+// subnet is replaced.  The bans that expire soonest are removed when the list
+// exceeds the maximum number of bans.
+func (b *BanList) Ban(subnet *net.IPNet, expiry time.Time, reason string) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.removeExpired()
+	b.bans[subnet.String()] = &BanEntry{
+		Subnet:  subnet,
+		Created: b.timeNow(),
+		Expiry:  expiry,
+		Reason:  reason,
+	}
+	b.limitSize()
+	b.markDirty()
+}
+
+// Unban removes the ban of the passed subnet and schedules the ban list to be
+// saved.  It returns whether or not the subnet was banned.  Note that only bans
+// of the exact subnet are removed, so an address that is banned as part of a
+// larger subnet remains banned.
+func (b *BanList) Unban(subnet *net.IPNet) bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	removed := b.removeExpired()
+	key := subnet.String()
+	_, banned := b.bans[key]
+	delete(b.bans, key)
+	if banned || removed {
+		b.markDirty()
+	}
+	return banned
+}
+
+// Clear removes all bans and schedules the ban list to be saved.
+func (b *BanList) Clear() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.bans = make(map[string]*BanEntry)
+	b.markDirty()
+}
+
+// IsBanned returns whether or not the passed IP address is banned either
+// directly or as part of a banned subnet.
+func (b *BanList) IsBanned(ip net.IP) bool {
+	_, banned := b.BanExpiry(ip)
+	return banned
+}
+
+// BanExpiry returns the latest time a ban that applies to the passed IP address
+// expires along with whether or not it is banned.
+func (b *BanList) BanExpiry(ip net.IP) (time.Time, bool) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	now := b.timeNow()
+	var expiry time.Time
+	var banned bool
+	for _, entry := range b.bans {
+		if !now.Before(entry.Expiry) || !entry.Subnet.Contains(ip) {
+			continue
+		}
+		if entry.Expiry.After(expiry) {
+			expiry = entry.Expiry
+		}
+		banned = true
+	}
+	return expiry, banned
+}
+
+// Entries returns the bans that have not expired sorted by subnet.
+func (b *BanList) Entries() []BanEntry {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.removeExpired()
+	entries := make([]BanEntry, 0, len(b.bans))
+	for _, entry := range b.bans {
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Subnet.String() < entries[j].Subnet.String()
+	})
+	return entries
+}
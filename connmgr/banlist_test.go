@@ -0,0 +1,267 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package connmgr
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestParseSubnet ensures individual IP addresses and subnets are parsed as
+// expected.
+func TestParseSubnet(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "192.168.1.1", want: "192.168.1.1/32"},
+		{in: "192.168.1.1/24", want: "192.168.1.0/24"},
+		{in: "fe80::1", want: "fe80::1/128"},
+		{in: "2001:db8::/32", want: "2001:db8::/32"},
+		{in: "192.168.1.256", wantErr: true},
+		{in: "192.168.1.0/33", wantErr: true},
+		{in: "example.com", wantErr: true},
+	}
+
+	for _, test := range tests {
+		subnet, err := ParseSubnet(test.in)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("ParseSubnet(%q): unexpected success", test.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSubnet(%q): unexpected error: %v", test.in, err)
+			continue
+		}
+		if subnet.String() != test.want {
+			t.Errorf("ParseSubnet(%q): got %v, want %v", test.in, subnet,
+				test.want)
+		}
+	}
+}
+
+// TestBanList ensures banning, unbanning, expiry and persistence of the ban list
+// work as expected.
+func TestBanList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "banlist")
+	if err != nil {
+		t.Fatalf("TempDir: unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "banlist.json")
+
+	now := time.Unix(1550000000, 0)
+	banList := NewBanList(filePath)
+	banList.timeNow = func() time.Time { return now }
+
+	mustParseSubnet := func(s string) *net.IPNet {
+		subnet, err := ParseSubnet(s)
+		if err != nil {
+			t.Fatalf("ParseSubnet(%q): unexpected error: %v", s, err)
+		}
+		return subnet
+	}
+	ip := net.ParseIP("10.0.0.1")
+	subnetIP := net.ParseIP("192.168.1.200")
+	otherIP := net.ParseIP("192.168.2.1")
+
+	// Ban an individual address and a subnet and ensure addresses are
+	// banned accordingly.
+	banList.Ban(mustParseSubnet("10.0.0.1"), now.Add(time.Hour), "manual")
+	banList.Ban(mustParseSubnet("192.168.1.0/24"), now.Add(2*time.Hour),
+		"misbehaving")
+	if !banList.IsBanned(ip) || !banList.IsBanned(subnetIP) {
+		t.Fatal("IsBanned: banned address not reported as banned")
+	}
+	if banList.IsBanned(otherIP) {
+		t.Fatal("IsBanned: address outside of banned subnet is banned")
+	}
+	if expiry, _ := banList.BanExpiry(subnetIP); !expiry.Equal(now.Add(2 * time.Hour)) {
+		t.Fatalf("BanExpiry: unexpected expiry %v", expiry)
+	}
+
+	// Ensure the bans survive saving and reloading the ban list.
+	if err := banList.Save(); err != nil {
+		t.Fatalf("Save: unexpected error: %v", err)
+	}
+	reloaded := NewBanList(filePath)
+	reloaded.timeNow = banList.timeNow
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Load: unexpected error: %v", err)
+	}
+	entries := reloaded.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("Entries: got %d entries, want 2", len(entries))
+	}
+	if entries[0].Subnet.String() != "10.0.0.1/32" ||
+		entries[0].Reason != "manual" ||
+		!entries[0].Expiry.Equal(now.Add(time.Hour)) ||
+		!entries[0].Created.Equal(now) {
+
+		t.Fatalf("Entries: unexpected entry %+v", entries[0])
+	}
+	if entries[1].Subnet.String() != "192.168.1.0/24" {
+		t.Fatalf("Entries: unexpected entry %+v", entries[1])
+	}
+
+	// Ensure unbanning only removes the exact subnet.
+	if reloaded.Unban(mustParseSubnet("192.168.1.200")) {
+		t.Fatal("Unban: removed ban of address within banned subnet")
+	}
+	if !reloaded.Unban(mustParseSubnet("10.0.0.1")) {
+		t.Fatal("Unban: did not remove banned address")
+	}
+	if reloaded.IsBanned(ip) {
+		t.Fatal("IsBanned: unbanned address reported as banned")
+	}
+
+	// Ensure bans expire.
+	now = now.Add(2 * time.Hour)
+	if reloaded.IsBanned(subnetIP) {
+		t.Fatal("IsBanned: expired ban reported as banned")
+	}
+	if entries := reloaded.Entries(); len(entries) != 0 {
+		t.Fatalf("Entries: unexpected expired entries %v", entries)
+	}
+
+	// Ensure clearing removes all bans from the file as well.
+	now = now.Add(-time.Hour)
+	banList.Clear()
+	if err := banList.Save(); err != nil {
+		t.Fatalf("Save: unexpected error: %v", err)
+	}
+	reloaded = NewBanList(filePath)
+	reloaded.timeNow = banList.timeNow
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Load: unexpected error: %v", err)
+	}
+	if entries := reloaded.Entries(); len(entries) != 0 {
+		t.Fatalf("Entries: unexpected entries after clear %v", entries)
+	}
+}
+
+// TestBanListLimit ensures the bans that expire soonest are removed once the
+// ban list exceeds the maximum number of bans.
+func TestBanListLimit(t *testing.T) {
+	now := time.Unix(1550000000, 0)
+	banList := NewBanList("")
+	banList.timeNow = func() time.Time { return now }
+
+	// Fill the list with bans that expire in reverse order of their
+	// addresses so the first ones added expire last.
+	ipForIndex := func(i int) net.IP {
+		return net.IPv4(10, byte(i>>16), byte(i>>8), byte(i))
+	}
+	for i := 0; i < maxBanListEntries; i++ {
+		subnet, err := ParseSubnet(ipForIndex(i).String())
+		if err != nil {
+			t.Fatalf("ParseSubnet: unexpected error: %v", err)
+		}
+		expiry := now.Add(time.Duration(maxBanListEntries-i) * time.Minute)
+		banList.Ban(subnet, expiry, "misbehaving")
+	}
+	if got := len(banList.Entries()); got != maxBanListEntries {
+		t.Fatalf("Entries: got %d entries, want %d", got,
+			maxBanListEntries)
+	}
+
+	// Ensure adding another ban removes the one that expires soonest.
+	subnet, err := ParseSubnet("192.168.1.1")
+	if err != nil {
+		t.Fatalf("ParseSubnet: unexpected error: %v", err)
+	}
+	banList.Ban(subnet, now.Add(24*time.Hour), "manual")
+	if got := len(banList.Entries()); got != maxBanListEntries {
+		t.Fatalf("Entries: got %d entries, want %d", got,
+			maxBanListEntries)
+	}
+	if !banList.IsBanned(subnet.IP) {
+		t.Fatal("IsBanned: new ban was not added")
+	}
+	if banList.IsBanned(ipForIndex(maxBanListEntries - 1)) {
+		t.Fatal("IsBanned: ban that expires soonest was not removed")
+	}
+	if !banList.IsBanned(ipForIndex(maxBanListEntries - 2)) {
+		t.Fatal("IsBanned: ban that expires later was removed")
+	}
+}
+
+// TestBanListSave ensures modifications of the ban list are saved once the
+// save delay elapses and that expired bans are not saved.
+func TestBanListSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "banlist")
+	if err != nil {
+		t.Fatalf("TempDir: unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "banlist.json")
+
+	mustParseSubnet := func(s string) *net.IPNet {
+		subnet, err := ParseSubnet(s)
+		if err != nil {
+			t.Fatalf("ParseSubnet(%q): unexpected error: %v", s, err)
+		}
+		return subnet
+	}
+
+	// Ensure the file is not written until the save delay elapses and that
+	// it then contains all of the modifications.
+	now := time.Now()
+	banList := NewBanList(filePath)
+	banList.saveDelay = 50 * time.Millisecond
+	banList.Ban(mustParseSubnet("10.0.0.1"), now.Add(time.Hour), "manual")
+	banList.Ban(mustParseSubnet("10.0.0.2"), now.Add(time.Hour), "manual")
+	if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+		t.Fatalf("Stat: ban list saved before save delay (err %v)", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(filePath); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("ban list was not saved after save delay")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	reloaded := NewBanList(filePath)
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Load: unexpected error: %v", err)
+	}
+	if entries := reloaded.Entries(); len(entries) != 2 {
+		t.Fatalf("Entries: got %d entries, want 2", len(entries))
+	}
+
+	// Ensure expired bans are not saved.
+	now = time.Unix(1550000000, 0)
+	banList = NewBanList(filePath)
+	banList.timeNow = func() time.Time { return now }
+	banList.saveDelay = time.Hour
+	banList.Ban(mustParseSubnet("10.0.0.1"), now.Add(time.Hour), "manual")
+	banList.Ban(mustParseSubnet("10.0.0.2"), now.Add(2*time.Hour), "manual")
+	now = now.Add(time.Hour)
+	if err := banList.Save(); err != nil {
+		t.Fatalf("Save: unexpected error: %v", err)
+	}
+	serialized, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("ReadFile: unexpected error: %v", err)
+	}
+	var list serializedBanList
+	if err := json.Unmarshal(serialized, &list); err != nil {
+		t.Fatalf("Unmarshal: unexpected error: %v", err)
+	}
+	if len(list.Bans) != 1 || list.Bans[0].Subnet != "10.0.0.2/32" {
+		t.Fatalf("unexpected saved bans %+v", list.Bans)
+	}
+}
@@ -6,6 +6,7 @@
 package connmgr
 
 import (
+	"crypto/rand"
 	"errors"
 	"fmt"
 	"net"
@@ -67,6 +68,10 @@ type ConnReq struct {
 	conn       net.Conn
 	Addr       net.Addr
 	Permanent  bool
+
+	// BlockRelayOnly indicates the connection is only used to relay blocks
+	// as opposed to also relaying transactions and addresses.
+	BlockRelayOnly bool
 }
 
 // updateState updates the state of the connection request.
@@ -121,6 +126,16 @@ type Config struct {
 	// maintain. Defaults to 8.
 	TargetOutbound uint32
 
+	// TargetBlockRelayOnly is the number of additional outbound network
+	// connections that are only used to relay blocks to maintain.
+	TargetBlockRelayOnly uint32
+
+	// Anchors are the addresses to make the initial block relay only
+	// connections to on start.  Additional addresses beyond the number of
+	// target block relay only connections are ignored.  Connections to
+	// anchors that fail are replaced by connections to new addresses.
+	Anchors []net.Addr
+
 	// RetryDuration is the duration to wait before retrying connection
 	// requests. Defaults to 5s.
 	RetryDuration time.Duration
@@ -177,6 +192,10 @@ type ConnManager struct {
 	failedAttempts uint64
 	requests       chan interface{}
 	quit           chan struct{}
+
+	// evictionKey is a random key used to select which network groups are
+	// protected from inbound peer eviction.
+	evictionKey [32]byte
 }
 
 // handleFailedConn handles a connection failed due to a disconnect or any
@@ -205,10 +224,10 @@ func (cm *ConnManager) handleFailedConn(c *ConnReq) {
 				"-- retrying connection in: %v", maxFailedAttempts,
 				cm.cfg.RetryDuration)
 			time.AfterFunc(cm.cfg.RetryDuration, func() {
-				cm.NewConnReq()
+				cm.newConnReq(c.BlockRelayOnly)
 			})
 		} else {
-			go cm.NewConnReq()
+			go cm.newConnReq(c.BlockRelayOnly)
 		}
 	}
 }
@@ -253,7 +272,9 @@ out:
 						go cm.cfg.OnDisconnection(connReq)
 					}
 
-					if uint32(len(conns)) < cm.cfg.TargetOutbound && msg.retry {
+					targetOutbound := cm.cfg.TargetOutbound +
+						cm.cfg.TargetBlockRelayOnly
+					if uint32(len(conns)) < targetOutbound && msg.retry {
 						cm.handleFailedConn(connReq)
 					}
 				} else {
@@ -279,6 +300,12 @@ out:
 // NewConnReq creates a new connection request and connects to the
 // corresponding address.
 func (cm *ConnManager) NewConnReq() {
+	cm.newConnReq(false)
+}
+
+// newConnReq creates a new connection request with the provided role and
+// connects to the corresponding address.
+func (cm *ConnManager) newConnReq(blockRelayOnly bool) {
 	if atomic.LoadInt32(&cm.stop) != 0 {
 		return
 	}
@@ -286,7 +313,7 @@ func (cm *ConnManager) NewConnReq() {
 		return
 	}
 
-	c := &ConnReq{}
+	c := &ConnReq{BlockRelayOnly: blockRelayOnly}
 	atomic.StoreUint64(&c.id, atomic.AddUint64(&cm.connReqCount, 1))
 
 	addr, err := cm.cfg.GetNewAddress()
@@ -387,6 +414,21 @@ func (cm *ConnManager) Start() {
 	for i := atomic.LoadUint64(&cm.connReqCount); i < uint64(cm.cfg.TargetOutbound); i++ {
 		go cm.NewConnReq()
 	}
+
+	// Make the block relay only connections, starting with the anchors.
+	if cm.cfg.GetNewAddress == nil {
+		return
+	}
+	for i := uint32(0); i < cm.cfg.TargetBlockRelayOnly; i++ {
+		if int(i) < len(cm.cfg.Anchors) {
+			go cm.Connect(&ConnReq{
+				Addr:           cm.cfg.Anchors[i],
+				BlockRelayOnly: true,
+			})
+			continue
+		}
+		go cm.newConnReq(true)
+	}
 }
 
 // Wait blocks until the connection manager halts gracefully.
@@ -434,5 +476,8 @@ func New(cfg *Config) (*ConnManager, error) {
 		requests: make(chan interface{}),
 		quit:     make(chan struct{}),
 	}
+	if _, err := rand.Read(cm.evictionKey[:]); err != nil {
+		return nil, err
+	}
 	return &cm, nil
 }
@@ -240,6 +240,62 @@ func TestTargetOutbound(t *testing.T) {
 	cmgr.Stop()
 }
 
+// TestTargetBlockRelayOnly tests the target number of block relay only
+// outbound connections are made in addition to the target outbound connections
+// and that the anchors are connected first.
+func TestTargetBlockRelayOnly(t *testing.T) {
+	targetOutbound := uint32(4)
+	targetBlockRelayOnly := uint32(3)
+	anchor := &net.TCPAddr{IP: net.ParseIP("127.0.0.2"), Port: 18555}
+	connected := make(chan *ConnReq)
+	cmgr, err := New(&Config{
+		TargetOutbound:       targetOutbound,
+		TargetBlockRelayOnly: targetBlockRelayOnly,
+		Anchors:              []net.Addr{anchor},
+		Dial:                 mockDialer,
+		GetNewAddress: func() (net.Addr, error) {
+			return &net.TCPAddr{
+				IP:   net.ParseIP("127.0.0.1"),
+				Port: 18555,
+			}, nil
+		},
+		OnConnection: func(c *ConnReq, conn net.Conn) {
+			connected <- c
+		},
+	})
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	cmgr.Start()
+	var numBlockRelayOnly uint32
+	var anchorConnected bool
+	for i := uint32(0); i < targetOutbound+targetBlockRelayOnly; i++ {
+		c := <-connected
+		if !c.BlockRelayOnly {
+			continue
+		}
+		numBlockRelayOnly++
+		if c.Addr.String() == anchor.String() {
+			anchorConnected = true
+		}
+	}
+	if numBlockRelayOnly != targetBlockRelayOnly {
+		t.Fatalf("block relay only: got %d connections, want %d",
+			numBlockRelayOnly, targetBlockRelayOnly)
+	}
+	if !anchorConnected {
+		t.Fatal("block relay only: anchor was not connected")
+	}
+
+	select {
+	case c := <-connected:
+		t.Fatalf("block relay only: got unexpected connection - %v", c.Addr)
+	case <-time.After(time.Millisecond):
+		break
+	}
+	cmgr.Stop()
+}
+
 // TestPassAddrAlongDialAddr tests if when using the DialAddr config option,
 // any address object returned by GetNewAddress will be correctly passed along
 // to DialAddr to be used for connecting to a host.
@@ -0,0 +1,224 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package connmgr
+
+import (
+	"crypto/sha256"
+	"sort"
+	"time"
+)
+
+const (
+	// evictProtectNetGroups is the number of inbound peers in distinct
+	// network groups that are protected from eviction.  The groups are
+	// selected by a keyed hash so an attacker can not predict which groups
+	// are protected.
+	evictProtectNetGroups = 4
+
+	// evictProtectPing is the number of inbound peers with the lowest ping
+	// times that are protected from eviction.
+	evictProtectPing = 8
+
+	// evictProtectTx is the number of inbound peers that most recently
+	// relayed novel transactions that are protected from eviction.
+	evictProtectTx = 4
+
+	// evictProtectBlock is the number of inbound peers that most recently
+	// relayed novel blocks that are protected from eviction.
+	evictProtectBlock = 4
+)
+
+// EvictionCandidate houses information about an inbound peer that is used to
+// determine which peer to evict in order to make room for a new inbound peer.
+type EvictionCandidate struct {
+	// ID uniquely identifies the peer.
+	ID int32
+
+	// NetGroup is the network group of the address of the peer such as
+	// that returned by addrmgr.GroupKey.
+	NetGroup string
+
+	// PingTime is the latest ping time of the peer.  It is zero when the
+	// ping time is not known yet.
+	PingTime time.Duration
+
+	// LastBlockTime is the last time the peer relayed a block that was not
+	// already known.  It is the zero time when it never did.
+	LastBlockTime time.Time
+
+	// LastTxTime is the last time the peer relayed a transaction that was
+	// not already known.  It is the zero time when it never did.
+	LastTxTime time.Time
+
+	// ConnTime is the time the connection to the peer was established.
+	ConnTime time.Time
+}
+
+// protectCandidates sorts the passed candidates so the most deserving of
+// protection according to the provided less function are first and returns
+// the remaining candidates after removing up to the given number of them.
+func protectCandidates(candidates []*EvictionCandidate, n int, less func(a, b *EvictionCandidate) bool) []*EvictionCandidate {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return less(candidates[i], candidates[j])
+	})
+	if n > len(candidates) {
+		n = len(candidates)
+	}
+	return candidates[n:]
+}
+
+// protectNetGroups returns the remaining candidates after removing a single
+// candidate from each of up to evictProtectNetGroups distinct network groups.
+// The groups are chosen by the keyed hash of the group name so that the choice
+// is deterministic for a given key yet not predictable without it.
+func protectNetGroups(candidates []*EvictionCandidate, key []byte) []*EvictionCandidate {
+	groupHashes := make(map[string][sha256.Size]byte)
+	for _, c := range candidates {
+		if _, ok := groupHashes[c.NetGroup]; ok {
+			continue
+		}
+		data := make([]byte, 0, len(key)+len(c.NetGroup))
+		data = append(data, key...)
+		data = append(data, c.NetGroup...)
+		groupHashes[c.NetGroup] = sha256.Sum256(data)
+	}
+	groups := make([]string, 0, len(groupHashes))
+	for group := range groupHashes {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		hi, hj := groupHashes[groups[i]], groupHashes[groups[j]]
+		for k := range hi {
+			if hi[k] != hj[k] {
+				return hi[k] > hj[k]
+			}
+		}
+		return false
+	})
+	if len(groups) > evictProtectNetGroups {
+		groups = groups[:evictProtectNetGroups]
+	}
+
+	// Protect the longest connected candidate of each selected group.
+	protected := make(map[string]*EvictionCandidate, len(groups))
+	for _, group := range groups {
+		protected[group] = nil
+	}
+	for _, c := range candidates {
+		best, ok := protected[c.NetGroup]
+		if !ok {
+			continue
+		}
+		if best == nil || c.ConnTime.Before(best.ConnTime) {
+			protected[c.NetGroup] = c
+		}
+	}
+	remaining := make([]*EvictionCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		if protected[c.NetGroup] != c {
+			remaining = append(remaining, c)
+		}
+	}
+	return remaining
+}
+
+// selectInboundToEvict implements the inbound peer eviction algorithm using the
+// provided key for the keyed network group hash.  See
+// ConnManager.SelectInboundToEvict for details.
+func selectInboundToEvict(candidates []EvictionCandidate, key []byte) (int32, bool) {
+	remaining := make([]*EvictionCandidate, 0, len(candidates))
+	for i := range candidates {
+		remaining = append(remaining, &candidates[i])
+	}
+
+	// Protect peers in a few distinct network groups since an attacker
+	// would have to control addresses in those specific groups.
+	remaining = protectNetGroups(remaining, key)
+
+	// Protect the peers with the lowest ping times since they are the most
+	// responsive and an attacker would have to be close to the node.
+	// Unknown ping times are treated as the worst.
+	remaining = protectCandidates(remaining, evictProtectPing,
+		func(a, b *EvictionCandidate) bool {
+			if a.PingTime == 0 || b.PingTime == 0 {
+				return a.PingTime != 0
+			}
+			return a.PingTime < b.PingTime
+		})
+
+	// Protect the peers that most recently relayed novel transactions and
+	// blocks since they are doing useful work for the node.
+	remaining = protectCandidates(remaining, evictProtectTx,
+		func(a, b *EvictionCandidate) bool {
+			return a.LastTxTime.After(b.LastTxTime)
+		})
+	remaining = protectCandidates(remaining, evictProtectBlock,
+		func(a, b *EvictionCandidate) bool {
+			return a.LastBlockTime.After(b.LastBlockTime)
+		})
+
+	// Protect the half of the remaining peers that have been connected the
+	// longest since an attacker would have had to connect them long ago.
+	remaining = protectCandidates(remaining, len(remaining)/2,
+		func(a, b *EvictionCandidate) bool {
+			return a.ConnTime.Before(b.ConnTime)
+		})
+
+	if len(remaining) == 0 {
+		return 0, false
+	}
+
+	// Determine the network group with the most remaining peers, preferring
+	// the group with the most recently connected peer when there are ties,
+	// and evict its most recently connected peer.
+	groups := make(map[string][]*EvictionCandidate)
+	for _, c := range remaining {
+		groups[c.NetGroup] = append(groups[c.NetGroup], c)
+	}
+	var evictGroup []*EvictionCandidate
+	var evictGroupNewest *EvictionCandidate
+	for _, group := range groups {
+		var newest *EvictionCandidate
+		for _, c := range group {
+			if newest == nil || c.ConnTime.After(newest.ConnTime) {
+				newest = c
+			}
+		}
+		if len(group) > len(evictGroup) || (len(group) ==
+			len(evictGroup) && newest.ConnTime.After(
+			evictGroupNewest.ConnTime)) {
+
+			evictGroup = group
+			evictGroupNewest = newest
+		}
+	}
+	return evictGroupNewest.ID, true
+}
+
+// SelectInboundToEvict selects an inbound peer to evict from the passed
+// candidates in order to make room for a new inbound peer when the maximum
+// number of peers is reached.  It returns false when all of the candidates are
+// protected from eviction.
+//
+// The following peers are protected from eviction, in order:
+//   - A peer from each of up to 4 network groups selected by a keyed hash
+//   - The 8 peers with the lowest ping times
+//   - The 4 peers that most recently relayed novel transactions
+//   - The 4 peers that most recently relayed novel blocks
+//   - Half of the remaining peers that have been connected the longest
+//
+// The most recently connected of the remaining peers in the network group with
+// the most remaining peers is then selected.  This makes it difficult for an
+// attacker to occupy all inbound slots since honest peers with desirable
+// characteristics are protected and connections from the same network group
+// are evicted first.
+//
+// Callers are expected to exclude peers that must never be evicted, such as
+// whitelisted peers, from the candidates.
+//
+// This function is safe for concurrent access.
+func (cm *ConnManager) SelectInboundToEvict(candidates []EvictionCandidate) (int32, bool) {
+	return selectInboundToEvict(candidates, cm.evictionKey[:])
+}
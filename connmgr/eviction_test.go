@@ -0,0 +1,167 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package connmgr
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+// TestSelectInboundToEvictSmall ensures no peer is selected for eviction when
+// all of the candidates are protected.
+func TestSelectInboundToEvictSmall(t *testing.T) {
+	key := []byte("eviction test key")
+	now := time.Now()
+	for n := 0; n <= 20; n++ {
+		candidates := make([]EvictionCandidate, 0, n)
+		for i := 0; i < n; i++ {
+			candidates = append(candidates, EvictionCandidate{
+				ID:       int32(i),
+				NetGroup: fmt.Sprintf("group%d", i),
+				ConnTime: now.Add(-time.Duration(i) * time.Minute),
+			})
+		}
+		if id, ok := selectInboundToEvict(candidates, key); ok {
+			t.Fatalf("%d candidates: unexpected eviction of peer %d", n,
+				id)
+		}
+	}
+}
+
+// TestSelectInboundToEvictNetGroup ensures the most recently connected peer in
+// the network group with the most connections is selected when an attacker
+// makes many connections from a single network group.
+func TestSelectInboundToEvictNetGroup(t *testing.T) {
+	key := []byte("eviction test key")
+	now := time.Now()
+
+	// Create honest peers in distinct groups that have been connected for a
+	// while followed by attacker peers in the same group that connected
+	// recently and never responded to pings.
+	var candidates []EvictionCandidate
+	for i := 0; i < 30; i++ {
+		candidates = append(candidates, EvictionCandidate{
+			ID:       int32(i),
+			NetGroup: fmt.Sprintf("honest%d", i),
+			PingTime: time.Duration(i+1) * time.Millisecond,
+			ConnTime: now.Add(-time.Duration(i+60) * time.Minute),
+		})
+	}
+	const newestAttacker = 79
+	for i := 30; i <= newestAttacker; i++ {
+		candidates = append(candidates, EvictionCandidate{
+			ID:       int32(i),
+			NetGroup: "attacker",
+			ConnTime: now.Add(-time.Duration(newestAttacker-i) * time.Second),
+		})
+	}
+
+	id, ok := selectInboundToEvict(candidates, key)
+	if !ok {
+		t.Fatal("no peer selected for eviction")
+	}
+	if id != newestAttacker {
+		t.Fatalf("unexpected peer selected for eviction - got %d, want %d",
+			id, newestAttacker)
+	}
+}
+
+// TestSelectInboundToEvictProtected simulates random populations of inbound
+// peers and ensures the peers that are expected to be protected are never
+// selected for eviction.
+func TestSelectInboundToEvictProtected(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	key := make([]byte, 32)
+	rng.Read(key)
+	now := time.Now()
+
+	// topIDs returns the IDs of the first n candidates with a non-zero value
+	// according to the passed less function.
+	topIDs := func(candidates []EvictionCandidate, n int, isZero func(c *EvictionCandidate) bool, less func(a, b *EvictionCandidate) bool) map[int32]struct{} {
+		sorted := make([]*EvictionCandidate, 0, len(candidates))
+		for i := range candidates {
+			if !isZero(&candidates[i]) {
+				sorted = append(sorted, &candidates[i])
+			}
+		}
+		sort.Slice(sorted, func(i, j int) bool {
+			return less(sorted[i], sorted[j])
+		})
+		if n > len(sorted) {
+			n = len(sorted)
+		}
+		ids := make(map[int32]struct{}, n)
+		for _, c := range sorted[:n] {
+			ids[c.ID] = struct{}{}
+		}
+		return ids
+	}
+
+	for iter := 0; iter < 1000; iter++ {
+		// Create a random population where the attributes of each peer
+		// are unique so the protected peers are unambiguous.
+		numPeers := 21 + rng.Intn(105)
+		numGroups := 1 + rng.Intn(numPeers)
+		perm := rng.Perm(numPeers * 4)
+		candidates := make([]EvictionCandidate, 0, numPeers)
+		for i := 0; i < numPeers; i++ {
+			c := EvictionCandidate{
+				ID:       int32(i),
+				NetGroup: fmt.Sprintf("group%d", rng.Intn(numGroups)),
+				ConnTime: now.Add(-time.Duration(perm[i]+1) * time.Second),
+			}
+			if rng.Intn(4) != 0 {
+				c.PingTime = time.Duration(perm[numPeers+i]+1) *
+					time.Millisecond
+			}
+			if rng.Intn(2) == 0 {
+				c.LastTxTime = now.Add(-time.Duration(
+					perm[numPeers*2+i]+1) * time.Second)
+			}
+			if rng.Intn(3) == 0 {
+				c.LastBlockTime = now.Add(-time.Duration(
+					perm[numPeers*3+i]+1) * time.Second)
+			}
+			candidates = append(candidates, c)
+		}
+
+		protected := make(map[string]map[int32]struct{})
+		protected["ping"] = topIDs(candidates, evictProtectPing,
+			func(c *EvictionCandidate) bool { return c.PingTime == 0 },
+			func(a, b *EvictionCandidate) bool {
+				return a.PingTime < b.PingTime
+			})
+		protected["tx"] = topIDs(candidates, evictProtectTx,
+			func(c *EvictionCandidate) bool { return c.LastTxTime.IsZero() },
+			func(a, b *EvictionCandidate) bool {
+				return a.LastTxTime.After(b.LastTxTime)
+			})
+		protected["block"] = topIDs(candidates, evictProtectBlock,
+			func(c *EvictionCandidate) bool {
+				return c.LastBlockTime.IsZero()
+			},
+			func(a, b *EvictionCandidate) bool {
+				return a.LastBlockTime.After(b.LastBlockTime)
+			})
+
+		id, ok := selectInboundToEvict(candidates, key)
+		if !ok {
+			t.Fatalf("iter %d: no peer selected for eviction from %d "+
+				"candidates", iter, numPeers)
+		}
+		if id < 0 || int(id) >= numPeers {
+			t.Fatalf("iter %d: selected unknown peer %d", iter, id)
+		}
+		for kind, ids := range protected {
+			if _, ok := ids[id]; ok {
+				t.Fatalf("iter %d: selected peer %d protected by %s",
+					iter, id, kind)
+			}
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"net"
+	"strconv"
 )
 
 const (
@@ -49,6 +50,26 @@ var (
 	}
 )
 
+// OnionAddr implements the net.Addr interface and represents a Tor onion
+// service address.  Onion services do not resolve to IP addresses, so they must
+// be dialed by host name via a Tor proxy instead.
+type OnionAddr struct {
+	Host string
+	Port int
+}
+
+// Network returns the network of the address which is always "tcp".  This is
+// part of the net.Addr interface implementation.
+func (a *OnionAddr) Network() string {
+	return "tcp"
+}
+
+// String returns the address in the form host:port.  This is part of the
+// net.Addr interface implementation.
+func (a *OnionAddr) String() string {
+	return net.JoinHostPort(a.Host, strconv.Itoa(a.Port))
+}
+
 // TorLookupIP uses Tor to resolve DNS via the SOCKS extension they provide for
 // resolution over the Tor network. Tor itself doesn't support ipv6 so this
 // doesn't either.
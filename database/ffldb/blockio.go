@@ -311,6 +311,30 @@ func (s *blockStore) deleteFile(fileNum uint32) error {
 	return nil
 }
 
+// removeFile closes the block file for the passed flat file number when it is
+// open and then removes it.  It is used to delete the files that house pruned
+// block data.
+func (s *blockStore) removeFile(fileNum uint32) error {
+	s.obfMutex.Lock()
+	if blockFile, ok := s.openBlockFiles[fileNum]; ok {
+		s.lruMutex.Lock()
+		s.openBlocksLRU.Remove(s.fileNumToLRUElem[fileNum])
+		delete(s.fileNumToLRUElem, fileNum)
+		s.lruMutex.Unlock()
+
+		// Close the file under the write lock for the file in case any
+		// readers are currently reading from it.
+		blockFile.Lock()
+		_ = blockFile.file.Close()
+		blockFile.Unlock()
+
+		delete(s.openBlockFiles, fileNum)
+	}
+	s.obfMutex.Unlock()
+
+	return s.deleteFileFunc(fileNum)
+}
+
 // blockFile attempts to return an existing file handle for the passed flat file
 // number if it is already open as well as marking it as most recently used.  It
 // will also open the file when it's not already open subject to the rules
@@ -716,6 +740,33 @@ func (s *blockStore) handleRollback(oldBlockFileNum, oldBlockOffset uint32) {
 	}
 }
 
+// firstBlockFileNum returns the number of the oldest flat block file in the
+// database directory.  The oldest files are not necessarily numbered from zero
+// since they are removed when pruning block data.  False is returned when there
+// are no block files.
+func firstBlockFileNum(dbPath string) (uint32, bool) {
+	filePaths, err := filepath.Glob(filepath.Join(dbPath, "*.fdb"))
+	if err != nil {
+		return 0, false
+	}
+
+	var firstFile uint32
+	var found bool
+	for _, filePath := range filePaths {
+		var fileNum uint32
+		_, err := fmt.Sscanf(filepath.Base(filePath), blockFilenameTemplate,
+			&fileNum)
+		if err != nil {
+			continue
+		}
+		if !found || fileNum < firstFile {
+			firstFile = fileNum
+			found = true
+		}
+	}
+	return firstFile, found
+}
+
 // scanBlockFiles searches the database directory for all flat block files to
 // find the end of the most recent file.  This position is considered the
 // current write cursor which is also stored in the metadata.  Thus, it is used
@@ -724,7 +775,11 @@ func (s *blockStore) handleRollback(oldBlockFileNum, oldBlockOffset uint32) {
 func scanBlockFiles(dbPath string) (int, uint32) {
 	lastFile := -1
 	fileLen := uint32(0)
-	for i := 0; ; i++ {
+	firstFile, ok := firstBlockFileNum(dbPath)
+	if !ok {
+		return lastFile, fileLen
+	}
+	for i := int(firstFile); ; i++ {
 		filePath := blockFilePath(dbPath, uint32(i))
 		st, err := os.Stat(filePath)
 		if err != nil {
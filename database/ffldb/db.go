@@ -1796,6 +1796,9 @@ type db struct {
 // Enforce db implements the database.DB interface.
 var _ database.DB = (*db)(nil)
 
+// Enforce db implements the database.BlockPruner interface.
+var _ database.BlockPruner = (*db)(nil)
+
 // Type returns the database driver type the current database instance was
 // created with.
 //
@@ -1957,6 +1960,118 @@ func (db *db) Update(fn func(database.Tx) error) error {
 	return tx.Commit()
 }
 
+// PruneBlocks deletes the flat files that house the oldest block data, as
+// needed, until the total size of the flat files is no more than the provided
+// target size.  The flat files are deleted in order from oldest to newest and
+// pruning stops at the first file that contains a block the provided function
+// does not allow to be pruned.  The file that is currently being written to is
+// never deleted.  The hashes of the pruned blocks, which are no longer
+// available from the database, are returned.
+//
+// The block index entries for the pruned blocks are removed and the provided
+// prepare function is invoked in the same transaction, which is committed
+// before the flat files are deleted so that an interruption never results in
+// entries that reference missing block data.
+//
+// This function is part of the database.BlockPruner interface implementation.
+func (db *db) PruneBlocks(targetSize uint64, canPrune func(hash *chainhash.Hash) bool, prepare func(tx database.Tx, pruned []chainhash.Hash) error) ([]chainhash.Hash, error) {
+	// A read-write transaction is used to prevent any new blocks from being
+	// written while the files to delete are determined.
+	tx, err := db.begin(true)
+	if err != nil {
+		return nil, err
+	}
+	defer rollbackOnPanic(tx)
+
+	// Group the hashes of all stored blocks by the flat file they are in.
+	fileBlocks := make(map[uint32][]chainhash.Hash)
+	cursor := tx.blockIdxBucket.Cursor()
+	for ok := cursor.First(); ok; ok = cursor.Next() {
+		var hash chainhash.Hash
+		copy(hash[:], cursor.Key())
+		loc := deserializeBlockLoc(cursor.Value())
+		fileBlocks[loc.blockFileNum] = append(fileBlocks[loc.blockFileNum],
+			hash)
+	}
+
+	// Determine the total size of the flat files.
+	wc := db.store.writeCursor
+	wc.RLock()
+	curFileNum, curOffset := wc.curFileNum, wc.curOffset
+	wc.RUnlock()
+	firstFileNum, ok := firstBlockFileNum(db.store.basePath)
+	if !ok {
+		firstFileNum = curFileNum
+	}
+	totalSize := uint64(curOffset)
+	fileSizes := make(map[uint32]uint64)
+	for fileNum := firstFileNum; fileNum < curFileNum; fileNum++ {
+		st, err := os.Stat(blockFilePath(db.store.basePath, fileNum))
+		if err != nil {
+			continue
+		}
+		fileSizes[fileNum] = uint64(st.Size())
+		totalSize += fileSizes[fileNum]
+	}
+
+	// canPruneAll returns whether or not all of the passed blocks are
+	// allowed to be pruned.
+	canPruneAll := func(hashes []chainhash.Hash) bool {
+		for i := range hashes {
+			if !canPrune(&hashes[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Remove the block index entries for the blocks in the oldest files
+	// until enough data has been pruned to reach the target size.
+	var prunedHashes []chainhash.Hash
+	var prunedFiles []uint32
+	for fileNum := firstFileNum; fileNum < curFileNum &&
+		totalSize > targetSize; fileNum++ {
+
+		hashes := fileBlocks[fileNum]
+		if !canPruneAll(hashes) {
+			break
+		}
+		for i := range hashes {
+			err := tx.blockIdxBucket.Delete(hashes[i][:])
+			if err != nil {
+				_ = tx.Rollback()
+				return nil, err
+			}
+		}
+		prunedHashes = append(prunedHashes, hashes...)
+		prunedFiles = append(prunedFiles, fileNum)
+		totalSize -= fileSizes[fileNum]
+	}
+	if len(prunedFiles) == 0 {
+		return nil, tx.Rollback()
+	}
+	tx.managed = true
+	err = prepare(tx, prunedHashes)
+	tx.managed = false
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	// Delete the flat files now that nothing references them.
+	for _, fileNum := range prunedFiles {
+		if err := db.store.removeFile(fileNum); err != nil {
+			return prunedHashes, err
+		}
+		log.Debugf("Pruned block file %d", fileNum)
+	}
+
+	return prunedHashes, nil
+}
+
 // Close cleanly shuts down the database and syncs all data.  It will block
 // until all database transactions have been finalized (rolled back or
 // committed).
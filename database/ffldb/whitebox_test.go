@@ -13,14 +13,17 @@ import (
 	"compress/bzip2"
 	"encoding/binary"
 	"encoding/gob"
+	"errors"
 	"fmt"
 	"hash/crc32"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/btcsuite/goleveldb/leveldb"
 	ldberrors "github.com/btcsuite/goleveldb/leveldb/errors"
+	"github.com/decred/dcrd/chaincfg/chainhash"
 	"github.com/decred/dcrd/database"
 	"github.com/decred/dcrd/dcrutil"
 	"github.com/decred/dcrd/wire"
@@ -681,3 +684,129 @@ func TestFailureScenarios(t *testing.T) {
 	// Test various corruption scenarios.
 	testCorruption(tc)
 }
+
+// TestPruneBlocks ensures pruning deletes the oldest flat block files until
+// the target size is reached or a block that is not allowed to be pruned is
+// encountered and that the database can be reopened afterwards.
+func TestPruneBlocks(t *testing.T) {
+	t.Parallel()
+
+	// Create a new database to run tests against.
+	dbPath := filepath.Join(os.TempDir(), "ffldb-pruneblocks")
+	_ = os.RemoveAll(dbPath)
+	idb, err := database.Create(dbType, dbPath, blockDataNet)
+	if err != nil {
+		t.Fatalf("Failed to create test database (%s) %v", dbType, err)
+	}
+	defer os.RemoveAll(dbPath)
+
+	// Change the maximum file size to a small value to force multiple flat
+	// files with the test data set and store all of the blocks.
+	idb.(*db).store.maxBlockFileSize = 16384 // 16KiB
+	blocks, err := loadBlocks(t, blockDataFile, blockDataNet)
+	if err != nil {
+		idb.Close()
+		t.Fatalf("loadBlocks: Unexpected error: %v", err)
+	}
+	err = idb.Update(func(tx database.Tx) error {
+		for _, block := range blocks {
+			if err := tx.StoreBlock(block); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		idb.Close()
+		t.Fatalf("StoreBlock: Unexpected error: %v", err)
+	}
+
+	// Ensure nothing is pruned when the prepare function fails.
+	keepBlock := blocks[len(blocks)/2]
+	canPrune := func(hash *chainhash.Hash) bool {
+		return *hash != *keepBlock.Hash()
+	}
+	errPrepare := errors.New("prepare failed")
+	_, err = idb.(*db).PruneBlocks(0, canPrune,
+		func(tx database.Tx, pruned []chainhash.Hash) error {
+			return errPrepare
+		})
+	if err != errPrepare {
+		idb.Close()
+		t.Fatalf("PruneBlocks: unexpected error -- got %v, want %v", err,
+			errPrepare)
+	}
+	if firstFile, _ := firstBlockFileNum(dbPath); firstFile != 0 {
+		idb.Close()
+		t.Fatalf("PruneBlocks: block file %d pruned after failed prepare",
+			firstFile)
+	}
+
+	// Ensure pruning stops at the file that contains a block which is not
+	// allowed to be pruned even though the target size is not reached and
+	// the prepare function is invoked with the pruned blocks.
+	var prepared []chainhash.Hash
+	pruned, err := idb.(*db).PruneBlocks(0, canPrune,
+		func(tx database.Tx, pruned []chainhash.Hash) error {
+			prepared = pruned
+			return nil
+		})
+	if err != nil {
+		idb.Close()
+		t.Fatalf("PruneBlocks: Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(prepared, pruned) {
+		idb.Close()
+		t.Fatalf("PruneBlocks: mismatched prepared blocks -- got %v, "+
+			"want %v", prepared, pruned)
+	}
+	if len(pruned) == 0 || len(pruned) >= len(blocks)/2 {
+		idb.Close()
+		t.Fatalf("PruneBlocks: unexpected number of pruned blocks -- "+
+			"got %d, want between 1 and %d", len(pruned),
+			len(blocks)/2-1)
+	}
+	firstFile, ok := firstBlockFileNum(dbPath)
+	if !ok || firstFile == 0 {
+		idb.Close()
+		t.Fatalf("PruneBlocks: unexpected first block file %d (found %v)",
+			firstFile, ok)
+	}
+
+	// Ensure the pruned blocks are no longer available while the others
+	// are.
+	err = idb.View(func(tx database.Tx) error {
+		for i := range pruned {
+			hasBlock, err := tx.HasBlock(&pruned[i])
+			if err != nil {
+				return err
+			}
+			if hasBlock {
+				return fmt.Errorf("pruned block %v still exists",
+					pruned[i])
+			}
+		}
+		_, err := tx.FetchBlock(keepBlock.Hash())
+		return err
+	})
+	if err != nil {
+		idb.Close()
+		t.Fatalf("View: Unexpected error: %v", err)
+	}
+
+	// Ensure the database can be reopened after pruning and the most recent
+	// block is still available.
+	idb.Close()
+	idb, err = database.Open(dbType, dbPath, blockDataNet)
+	if err != nil {
+		t.Fatalf("Failed to reopen test database (%s) %v", dbType, err)
+	}
+	defer idb.Close()
+	err = idb.View(func(tx database.Tx) error {
+		_, err := tx.FetchBlock(blocks[len(blocks)-1].Hash())
+		return err
+	})
+	if err != nil {
+		t.Fatalf("FetchBlock: Unexpected error: %v", err)
+	}
+}
@@ -465,3 +465,26 @@ type DB interface {
 	// back or committed).
 	Close() error
 }
+
+// BlockPruner is an optional interface that may be implemented by a DB to
+// support deleting the oldest block data.  Callers are expected to check for
+// it with a type assertion.
+type BlockPruner interface {
+	// PruneBlocks deletes the oldest block data, as needed, until the total
+	// size of the stored block data is no more than the provided target
+	// size.  Only block data for which the provided function returns true
+	// is deleted, and, depending on the backend, blocks may only be deleted
+	// in groups, so the target size is not guaranteed to be reached.  The
+	// hashes of the pruned blocks, which are no longer available, are
+	// returned.
+	//
+	// The provided prepare function is invoked with a read-write
+	// transaction and the hashes of the blocks that are about to be pruned
+	// before any block data is deleted.  This allows the caller to update
+	// any of its state that refers to the block data in the same
+	// transaction that removes the blocks from the database.  Nothing is
+	// pruned when it returns an error.
+	//
+	// NOTE: Pruning can't be undone once the transaction is committed.
+	PruneBlocks(targetSize uint64, canPrune func(hash *chainhash.Hash) bool, prepare func(tx Tx, pruned []chainhash.Hash) error) ([]chainhash.Hash, error)
+}
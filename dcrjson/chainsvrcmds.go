@@ -46,6 +46,19 @@ const (
 	NDisconnect NodeSubCmd = "disconnect"
 )
 
+// SetBanSubCmd defines the type used in the setban JSON-RPC command for the
+// sub command field.
+type SetBanSubCmd string
+
+const (
+	// SBAdd indicates the specified IP address or subnet should be banned.
+	SBAdd SetBanSubCmd = "add"
+
+	// SBRemove indicates the ban of the specified IP address or subnet
+	// should be removed.
+	SBRemove SetBanSubCmd = "remove"
+)
+
 // AddNodeCmd defines the addnode JSON-RPC command.
 type AddNodeCmd struct {
 	Addr   string
@@ -79,6 +92,15 @@ type SStxCommitOut struct {
 	ChangeAmt  int64  `json:"changeamt"`
 }
 
+// ClearBannedCmd defines the clearbanned JSON-RPC command.
+type ClearBannedCmd struct{}
+
+// NewClearBannedCmd returns a new instance which can be used to issue a
+// clearbanned JSON-RPC command.
+func NewClearBannedCmd() *ClearBannedCmd {
+	return &ClearBannedCmd{}
+}
+
 // CreateRawSStxCmd is a type handling custom marshaling and
 // unmarshaling of createrawsstx JSON RPC commands.
 type CreateRawSStxCmd struct {
@@ -185,6 +207,19 @@ func NewDecodeScriptCmd(hexScript string) *DecodeScriptCmd {
 	}
 }
 
+// DumpUtxoSetCmd defines the dumputxoset JSON-RPC command.
+type DumpUtxoSetCmd struct {
+	Path string
+}
+
+// NewDumpUtxoSetCmd returns a new instance which can be used to issue a
+// dumputxoset JSON-RPC command.
+func NewDumpUtxoSetCmd(path string) *DumpUtxoSetCmd {
+	return &DumpUtxoSetCmd{
+		Path: path,
+	}
+}
+
 // EstimateFeeCmd defines the estimatefee JSON-RPC command.
 type EstimateFeeCmd struct {
 	NumBlocks int64
@@ -198,6 +233,21 @@ func NewEstimateFeeCmd(numBlocks int64) *EstimateFeeCmd {
 	}
 }
 
+// EstimateRawFeeCmd defines the estimaterawfee JSON-RPC command.
+type EstimateRawFeeCmd struct {
+	Confirmations int64
+	Threshold     *float64 `jsonrpcdefault:"0.95"`
+}
+
+// NewEstimateRawFeeCmd returns a new instance which can be used to issue an
+// estimaterawfee JSON-RPC command.
+func NewEstimateRawFeeCmd(confirmations int64, threshold *float64) *EstimateRawFeeCmd {
+	return &EstimateRawFeeCmd{
+		Confirmations: confirmations,
+		Threshold:     threshold,
+	}
+}
+
 // EstimateSmartFeeMode defines estimation mode to be used with
 // the estimatesmartfee command.
 type EstimateSmartFeeMode string
@@ -210,6 +260,10 @@ const (
 	// EstimateSmartFeeConservative potentially returns
 	// a conservative result.
 	EstimateSmartFeeConservative EstimateSmartFeeMode = "conservative"
+
+	// EstimateSmartFeeTicket returns a result based solely on the fees
+	// paid by previously observed ticket purchases.
+	EstimateSmartFeeTicket EstimateSmartFeeMode = "ticket"
 )
 
 // EstimateSmartFeeCmd defines the estimatesmartfee JSON-RPC command.
@@ -828,6 +882,15 @@ func NewGetStakeVersionsCmd(hash string, count int32) *GetStakeVersionsCmd {
 	}
 }
 
+// GetSyncInfoCmd defines the getsyncinfo JSON-RPC command.
+type GetSyncInfoCmd struct{}
+
+// NewGetSyncInfoCmd returns a new instance which can be used to issue a
+// getsyncinfo JSON-RPC command.
+func NewGetSyncInfoCmd() *GetSyncInfoCmd {
+	return &GetSyncInfoCmd{}
+}
+
 // GetTicketPoolValueCmd defines the getticketpoolvalue JSON-RPC command.
 type GetTicketPoolValueCmd struct{}
 
@@ -912,6 +975,15 @@ func NewHelpCmd(command *string) *HelpCmd {
 	}
 }
 
+// ListBannedCmd defines the listbanned JSON-RPC command.
+type ListBannedCmd struct{}
+
+// NewListBannedCmd returns a new instance which can be used to issue a
+// listbanned JSON-RPC command.
+func NewListBannedCmd() *ListBannedCmd {
+	return &ListBannedCmd{}
+}
+
 // LiveTicketsCmd is a type handling custom marshaling and
 // unmarshaling of livetickets JSON RPC commands.
 type LiveTicketsCmd struct{}
@@ -922,6 +994,15 @@ func NewLiveTicketsCmd() *LiveTicketsCmd {
 	return &LiveTicketsCmd{}
 }
 
+// LoadMempoolCmd defines the loadmempool JSON-RPC command.
+type LoadMempoolCmd struct{}
+
+// NewLoadMempoolCmd returns a new instance which can be used to issue a
+// loadmempool JSON-RPC command.
+func NewLoadMempoolCmd() *LoadMempoolCmd {
+	return &LoadMempoolCmd{}
+}
+
 // MissedTicketsCmd is a type handling custom marshaling and
 // unmarshaling of missedtickets JSON RPC commands.
 type MissedTicketsCmd struct{}
@@ -981,6 +1062,15 @@ func NewRebroadcastWinnersCmd() *RebroadcastWinnersCmd {
 	return &RebroadcastWinnersCmd{}
 }
 
+// SaveMempoolCmd defines the savemempool JSON-RPC command.
+type SaveMempoolCmd struct{}
+
+// NewSaveMempoolCmd returns a new instance which can be used to issue a
+// savemempool JSON-RPC command.
+func NewSaveMempoolCmd() *SaveMempoolCmd {
+	return &SaveMempoolCmd{}
+}
+
 // SearchRawTransactionsCmd defines the searchrawtransactions JSON-RPC command.
 type SearchRawTransactionsCmd struct {
 	Address     string
@@ -1009,6 +1099,24 @@ func NewSearchRawTransactionsCmd(address string, verbose, skip, count *int, vinE
 	}
 }
 
+// SendRawPackageCmd defines the sendrawpackage JSON-RPC command.
+type SendRawPackageCmd struct {
+	HexTxs        []string
+	AllowHighFees *bool `jsonrpcdefault:"false"`
+}
+
+// NewSendRawPackageCmd returns a new instance which can be used to issue a
+// sendrawpackage JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewSendRawPackageCmd(hexTxs []string, allowHighFees *bool) *SendRawPackageCmd {
+	return &SendRawPackageCmd{
+		HexTxs:        hexTxs,
+		AllowHighFees: allowHighFees,
+	}
+}
+
 // SendRawTransactionCmd defines the sendrawtransaction JSON-RPC command.
 type SendRawTransactionCmd struct {
 	HexTx         string
@@ -1027,6 +1135,28 @@ func NewSendRawTransactionCmd(hexTx string, allowHighFees *bool) *SendRawTransac
 	}
 }
 
+// SetBanCmd defines the setban JSON-RPC command.
+type SetBanCmd struct {
+	Subnet   string
+	SubCmd   SetBanSubCmd `jsonrpcusage:"\"add|remove\""`
+	BanTime  *int64       `jsonrpcdefault:"0"`
+	Absolute *bool        `jsonrpcdefault:"false"`
+}
+
+// NewSetBanCmd returns a new instance which can be used to issue a setban
+// JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewSetBanCmd(subnet string, subCmd SetBanSubCmd, banTime *int64, absolute *bool) *SetBanCmd {
+	return &SetBanCmd{
+		Subnet:   subnet,
+		SubCmd:   subCmd,
+		BanTime:  banTime,
+		Absolute: absolute,
+	}
+}
+
 // SetGenerateCmd defines the setgenerate JSON-RPC command.
 type SetGenerateCmd struct {
 	Generate     bool
@@ -1079,6 +1209,24 @@ func NewSubmitBlockCmd(hexBlock string, options *SubmitBlockOptions) *SubmitBloc
 	}
 }
 
+// TestMempoolAcceptCmd defines the testmempoolaccept JSON-RPC command.
+type TestMempoolAcceptCmd struct {
+	HexTxs        []string
+	AllowHighFees *bool `jsonrpcdefault:"false"`
+}
+
+// NewTestMempoolAcceptCmd returns a new instance which can be used to issue a
+// testmempoolaccept JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewTestMempoolAcceptCmd(hexTxs []string, allowHighFees *bool) *TestMempoolAcceptCmd {
+	return &TestMempoolAcceptCmd{
+		HexTxs:        hexTxs,
+		AllowHighFees: allowHighFees,
+	}
+}
+
 // TicketFeeInfoCmd defines the ticketsfeeinfo JSON-RPC command.
 type TicketFeeInfoCmd struct {
 	Blocks  *uint32
@@ -1197,13 +1345,16 @@ func init() {
 	flags := UsageFlag(0)
 
 	MustRegisterCmd("addnode", (*AddNodeCmd)(nil), flags)
+	MustRegisterCmd("clearbanned", (*ClearBannedCmd)(nil), flags)
 	MustRegisterCmd("createrawssrtx", (*CreateRawSSRtxCmd)(nil), flags)
 	MustRegisterCmd("createrawsstx", (*CreateRawSStxCmd)(nil), flags)
 	MustRegisterCmd("createrawtransaction", (*CreateRawTransactionCmd)(nil), flags)
 	MustRegisterCmd("debuglevel", (*DebugLevelCmd)(nil), flags)
 	MustRegisterCmd("decoderawtransaction", (*DecodeRawTransactionCmd)(nil), flags)
 	MustRegisterCmd("decodescript", (*DecodeScriptCmd)(nil), flags)
+	MustRegisterCmd("dumputxoset", (*DumpUtxoSetCmd)(nil), flags)
 	MustRegisterCmd("estimatefee", (*EstimateFeeCmd)(nil), flags)
+	MustRegisterCmd("estimaterawfee", (*EstimateRawFeeCmd)(nil), flags)
 	MustRegisterCmd("estimatesmartfee", (*EstimateSmartFeeCmd)(nil), flags)
 	MustRegisterCmd("estimatestakediff", (*EstimateStakeDiffCmd)(nil), flags)
 	MustRegisterCmd("existsaddress", (*ExistsAddressCmd)(nil), flags)
@@ -1246,23 +1397,30 @@ func init() {
 	MustRegisterCmd("getstakedifficulty", (*GetStakeDifficultyCmd)(nil), flags)
 	MustRegisterCmd("getstakeversioninfo", (*GetStakeVersionInfoCmd)(nil), flags)
 	MustRegisterCmd("getstakeversions", (*GetStakeVersionsCmd)(nil), flags)
+	MustRegisterCmd("getsyncinfo", (*GetSyncInfoCmd)(nil), flags)
 	MustRegisterCmd("getticketpoolvalue", (*GetTicketPoolValueCmd)(nil), flags)
 	MustRegisterCmd("gettxout", (*GetTxOutCmd)(nil), flags)
 	MustRegisterCmd("gettxoutsetinfo", (*GetTxOutSetInfoCmd)(nil), flags)
 	MustRegisterCmd("getvoteinfo", (*GetVoteInfoCmd)(nil), flags)
 	MustRegisterCmd("getwork", (*GetWorkCmd)(nil), flags)
 	MustRegisterCmd("help", (*HelpCmd)(nil), flags)
+	MustRegisterCmd("listbanned", (*ListBannedCmd)(nil), flags)
 	MustRegisterCmd("livetickets", (*LiveTicketsCmd)(nil), flags)
+	MustRegisterCmd("loadmempool", (*LoadMempoolCmd)(nil), flags)
 	MustRegisterCmd("missedtickets", (*MissedTicketsCmd)(nil), flags)
 	MustRegisterCmd("node", (*NodeCmd)(nil), flags)
 	MustRegisterCmd("ping", (*PingCmd)(nil), flags)
 	MustRegisterCmd("rebroadcastmissed", (*RebroadcastMissedCmd)(nil), flags)
 	MustRegisterCmd("rebroadcastwinners", (*RebroadcastWinnersCmd)(nil), flags)
+	MustRegisterCmd("savemempool", (*SaveMempoolCmd)(nil), flags)
 	MustRegisterCmd("searchrawtransactions", (*SearchRawTransactionsCmd)(nil), flags)
+	MustRegisterCmd("sendrawpackage", (*SendRawPackageCmd)(nil), flags)
 	MustRegisterCmd("sendrawtransaction", (*SendRawTransactionCmd)(nil), flags)
+	MustRegisterCmd("setban", (*SetBanCmd)(nil), flags)
 	MustRegisterCmd("setgenerate", (*SetGenerateCmd)(nil), flags)
 	MustRegisterCmd("stop", (*StopCmd)(nil), flags)
 	MustRegisterCmd("submitblock", (*SubmitBlockCmd)(nil), flags)
+	MustRegisterCmd("testmempoolaccept", (*TestMempoolAcceptCmd)(nil), flags)
 	MustRegisterCmd("ticketfeeinfo", (*TicketFeeInfoCmd)(nil), flags)
 	MustRegisterCmd("ticketsforaddress", (*TicketsForAddressCmd)(nil), flags)
 	MustRegisterCmd("ticketvwap", (*TicketVWAPCmd)(nil), flags)
@@ -39,6 +39,17 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"addnode","params":["127.0.0.1","remove"],"id":1}`,
 			unmarshalled: &AddNodeCmd{Addr: "127.0.0.1", SubCmd: ANRemove},
 		},
+		{
+			name: "clearbanned",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("clearbanned")
+			},
+			staticCmd: func() interface{} {
+				return NewClearBannedCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"clearbanned","params":[],"id":1}`,
+			unmarshalled: &ClearBannedCmd{},
+		},
 		{
 			name: "createrawtransaction",
 			newCmd: func() (interface{}, error) {
@@ -114,6 +125,17 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"decodescript","params":["00"],"id":1}`,
 			unmarshalled: &DecodeScriptCmd{HexScript: "00"},
 		},
+		{
+			name: "dumputxoset",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("dumputxoset", "utxoset.dat")
+			},
+			staticCmd: func() interface{} {
+				return NewDumpUtxoSetCmd("utxoset.dat")
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"dumputxoset","params":["utxoset.dat"],"id":1}`,
+			unmarshalled: &DumpUtxoSetCmd{Path: "utxoset.dat"},
+		},
 		{
 			name: "estimatefee",
 			newCmd: func() (interface{}, error) {
@@ -127,6 +149,34 @@ func TestChainSvrCmds(t *testing.T) {
 				NumBlocks: 6,
 			},
 		},
+		{
+			name: "estimaterawfee",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("estimaterawfee", 6)
+			},
+			staticCmd: func() interface{} {
+				return NewEstimateRawFeeCmd(6, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"estimaterawfee","params":[6],"id":1}`,
+			unmarshalled: &EstimateRawFeeCmd{
+				Confirmations: 6,
+				Threshold:     Float64(0.95),
+			},
+		},
+		{
+			name: "estimaterawfee optional",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("estimaterawfee", 6, 0.8)
+			},
+			staticCmd: func() interface{} {
+				return NewEstimateRawFeeCmd(6, Float64(0.8))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"estimaterawfee","params":[6,0.8],"id":1}`,
+			unmarshalled: &EstimateRawFeeCmd{
+				Confirmations: 6,
+				Threshold:     Float64(0.8),
+			},
+		},
 		{
 			name: "estimatesmartfee",
 			newCmd: func() (interface{}, error) {
@@ -693,6 +743,17 @@ func TestChainSvrCmds(t *testing.T) {
 				Count: 1,
 			},
 		},
+		{
+			name: "getsyncinfo",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("getsyncinfo")
+			},
+			staticCmd: func() interface{} {
+				return NewGetSyncInfoCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getsyncinfo","params":[],"id":1}`,
+			unmarshalled: &GetSyncInfoCmd{},
+		},
 		{
 			name: "gettxout",
 			newCmd: func() (interface{}, error) {
@@ -773,6 +834,17 @@ func TestChainSvrCmds(t *testing.T) {
 				Data: String("00112233"),
 			},
 		},
+		{
+			name: "listbanned",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("listbanned")
+			},
+			staticCmd: func() interface{} {
+				return NewListBannedCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"listbanned","params":[],"id":1}`,
+			unmarshalled: &ListBannedCmd{},
+		},
 		{
 			name: "help",
 			newCmd: func() (interface{}, error) {
@@ -853,6 +925,28 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"ping","params":[],"id":1}`,
 			unmarshalled: &PingCmd{},
 		},
+		{
+			name: "loadmempool",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("loadmempool")
+			},
+			staticCmd: func() interface{} {
+				return NewLoadMempoolCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"loadmempool","params":[],"id":1}`,
+			unmarshalled: &LoadMempoolCmd{},
+		},
+		{
+			name: "savemempool",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("savemempool")
+			},
+			staticCmd: func() interface{} {
+				return NewSaveMempoolCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"savemempool","params":[],"id":1}`,
+			unmarshalled: &SaveMempoolCmd{},
+		},
 		{
 			name: "searchrawtransactions",
 			newCmd: func() (interface{}, error) {
@@ -994,6 +1088,34 @@ func TestChainSvrCmds(t *testing.T) {
 				FilterAddrs: &[]string{"1Address"},
 			},
 		},
+		{
+			name: "sendrawpackage",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("sendrawpackage", []string{"1122", "3344"})
+			},
+			staticCmd: func() interface{} {
+				return NewSendRawPackageCmd([]string{"1122", "3344"}, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"sendrawpackage","params":[["1122","3344"]],"id":1}`,
+			unmarshalled: &SendRawPackageCmd{
+				HexTxs:        []string{"1122", "3344"},
+				AllowHighFees: Bool(false),
+			},
+		},
+		{
+			name: "sendrawpackage optional",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("sendrawpackage", []string{"1122"}, false)
+			},
+			staticCmd: func() interface{} {
+				return NewSendRawPackageCmd([]string{"1122"}, Bool(false))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"sendrawpackage","params":[["1122"],false],"id":1}`,
+			unmarshalled: &SendRawPackageCmd{
+				HexTxs:        []string{"1122"},
+				AllowHighFees: Bool(false),
+			},
+		},
 		{
 			name: "sendrawtransaction",
 			newCmd: func() (interface{}, error) {
@@ -1022,6 +1144,39 @@ func TestChainSvrCmds(t *testing.T) {
 				AllowHighFees: Bool(false),
 			},
 		},
+		{
+			name: "setban",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("setban", "192.168.0.0/24", SBAdd)
+			},
+			staticCmd: func() interface{} {
+				return NewSetBanCmd("192.168.0.0/24", SBAdd, nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"setban","params":["192.168.0.0/24","add"],"id":1}`,
+			unmarshalled: &SetBanCmd{
+				Subnet:   "192.168.0.0/24",
+				SubCmd:   SBAdd,
+				BanTime:  Int64(0),
+				Absolute: Bool(false),
+			},
+		},
+		{
+			name: "setban optional",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("setban", "192.168.0.1", SBAdd, 1550000000, true)
+			},
+			staticCmd: func() interface{} {
+				return NewSetBanCmd("192.168.0.1", SBAdd, Int64(1550000000),
+					Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"setban","params":["192.168.0.1","add",1550000000,true],"id":1}`,
+			unmarshalled: &SetBanCmd{
+				Subnet:   "192.168.0.1",
+				SubCmd:   SBAdd,
+				BanTime:  Int64(1550000000),
+				Absolute: Bool(true),
+			},
+		},
 		{
 			name: "setgenerate",
 			newCmd: func() (interface{}, error) {
@@ -1094,6 +1249,34 @@ func TestChainSvrCmds(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "testmempoolaccept",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("testmempoolaccept", []string{"1122", "3344"})
+			},
+			staticCmd: func() interface{} {
+				return NewTestMempoolAcceptCmd([]string{"1122", "3344"}, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"testmempoolaccept","params":[["1122","3344"]],"id":1}`,
+			unmarshalled: &TestMempoolAcceptCmd{
+				HexTxs:        []string{"1122", "3344"},
+				AllowHighFees: Bool(false),
+			},
+		},
+		{
+			name: "testmempoolaccept optional",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("testmempoolaccept", []string{"1122"}, true)
+			},
+			staticCmd: func() interface{} {
+				return NewTestMempoolAcceptCmd([]string{"1122"}, Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"testmempoolaccept","params":[["1122"],true],"id":1}`,
+			unmarshalled: &TestMempoolAcceptCmd{
+				HexTxs:        []string{"1122"},
+				AllowHighFees: Bool(true),
+			},
+		},
 		{
 			name: "validateaddress",
 			newCmd: func() (interface{}, error) {
@@ -26,6 +26,14 @@ type DecodeScriptResult struct {
 	P2sh      string   `json:"p2sh,omitempty"`
 }
 
+// DumpUtxoSetResult models the data returned from the dumputxoset command.
+type DumpUtxoSetResult struct {
+	Height     int64  `json:"height"`
+	Hash       string `json:"hash"`
+	Commitment string `json:"commitment"`
+	Path       string `json:"path"`
+}
+
 // EstimateSmartFeeResult models the data returned from the estimatesmartfee
 // command.
 type EstimateSmartFeeResult struct {
@@ -34,6 +42,33 @@ type EstimateSmartFeeResult struct {
 	Blocks  int64    `json:"blocks"`
 }
 
+// EstimateRawFeeBucketRange models a range of fee rate buckets considered by
+// the estimaterawfee command.
+type EstimateRawFeeBucketRange struct {
+	StartRange     float64 `json:"startrange"`
+	EndRange       float64 `json:"endrange"`
+	WithinTarget   float64 `json:"withintarget"`
+	TotalConfirmed float64 `json:"totalconfirmed"`
+	InMempool      float64 `json:"inmempool"`
+}
+
+// EstimateRawFeeHorizonResult models the estimate of a single decay horizon
+// returned from the estimaterawfee command.
+type EstimateRawFeeHorizonResult struct {
+	FeeRate float64                    `json:"feerate,omitempty"`
+	Decay   float64                    `json:"decay"`
+	Pass    *EstimateRawFeeBucketRange `json:"pass,omitempty"`
+	Fail    *EstimateRawFeeBucketRange `json:"fail,omitempty"`
+	Errors  []string                   `json:"errors,omitempty"`
+}
+
+// EstimateRawFeeResult models the data returned from the estimaterawfee
+// command.
+type EstimateRawFeeResult struct {
+	Short *EstimateRawFeeHorizonResult `json:"short"`
+	Long  *EstimateRawFeeHorizonResult `json:"long"`
+}
+
 // EstimateStakeDiffResult models the data returned from the estimatestakediff
 // command.
 type EstimateStakeDiffResult struct {
@@ -293,35 +328,51 @@ type GetNetworkInfoResult struct {
 	LocalAddresses  []LocalAddressesResult `json:"localaddresses"`
 }
 
+// GetNetTotalsUploadTargetResult models the upload target data returned from
+// the getnettotals command.
+type GetNetTotalsUploadTargetResult struct {
+	TimeFrame             int64  `json:"timeframe"`
+	Target                uint64 `json:"target"`
+	TargetReached         bool   `json:"targetreached"`
+	ServeHistoricalBlocks bool   `json:"servehistoricalblocks"`
+	BytesLeftInCycle      uint64 `json:"bytesleftincycle"`
+	TimeLeftInCycle       int64  `json:"timeleftincycle"`
+}
+
 // GetNetTotalsResult models the data returned from the getnettotals command.
 type GetNetTotalsResult struct {
-	TotalBytesRecv uint64 `json:"totalbytesrecv"`
-	TotalBytesSent uint64 `json:"totalbytessent"`
-	TimeMillis     int64  `json:"timemillis"`
+	TotalBytesRecv uint64                         `json:"totalbytesrecv"`
+	TotalBytesSent uint64                         `json:"totalbytessent"`
+	TimeMillis     int64                          `json:"timemillis"`
+	UploadTarget   GetNetTotalsUploadTargetResult `json:"uploadtarget"`
 }
 
 // GetPeerInfoResult models the data returned from the getpeerinfo command.
 type GetPeerInfoResult struct {
-	ID             int32   `json:"id"`
-	Addr           string  `json:"addr"`
-	AddrLocal      string  `json:"addrlocal,omitempty"`
-	Services       string  `json:"services"`
-	RelayTxes      bool    `json:"relaytxes"`
-	LastSend       int64   `json:"lastsend"`
-	LastRecv       int64   `json:"lastrecv"`
-	BytesSent      uint64  `json:"bytessent"`
-	BytesRecv      uint64  `json:"bytesrecv"`
-	ConnTime       int64   `json:"conntime"`
-	TimeOffset     int64   `json:"timeoffset"`
-	PingTime       float64 `json:"pingtime"`
-	PingWait       float64 `json:"pingwait,omitempty"`
-	Version        uint32  `json:"version"`
-	SubVer         string  `json:"subver"`
-	Inbound        bool    `json:"inbound"`
-	StartingHeight int64   `json:"startingheight"`
-	CurrentHeight  int64   `json:"currentheight,omitempty"`
-	BanScore       int32   `json:"banscore"`
-	SyncNode       bool    `json:"syncnode"`
+	ID              int32             `json:"id"`
+	Addr            string            `json:"addr"`
+	AddrLocal       string            `json:"addrlocal,omitempty"`
+	Services        string            `json:"services"`
+	RelayTxes       bool              `json:"relaytxes"`
+	LastSend        int64             `json:"lastsend"`
+	LastRecv        int64             `json:"lastrecv"`
+	BytesSent       uint64            `json:"bytessent"`
+	BytesRecv       uint64            `json:"bytesrecv"`
+	ConnTime        int64             `json:"conntime"`
+	TimeOffset      int64             `json:"timeoffset"`
+	PingTime        float64           `json:"pingtime"`
+	PingWait        float64           `json:"pingwait,omitempty"`
+	Version         uint32            `json:"version"`
+	SubVer          string            `json:"subver"`
+	Inbound         bool              `json:"inbound"`
+	StartingHeight  int64             `json:"startingheight"`
+	CurrentHeight   int64             `json:"currentheight,omitempty"`
+	BanScore        int32             `json:"banscore"`
+	SyncNode        bool              `json:"syncnode"`
+	Transport       string            `json:"transport"`
+	ConnType        string            `json:"conntype"`
+	BytesSentPerMsg map[string]uint64 `json:"bytessentpermsg"`
+	BytesRecvPerMsg map[string]uint64 `json:"bytesrecvpermsg"`
 }
 
 // GetRawMempoolVerboseResult models the data returned from the getrawmempool
@@ -335,6 +386,12 @@ type GetRawMempoolVerboseResult struct {
 	StartingPriority float64  `json:"startingpriority"`
 	CurrentPriority  float64  `json:"currentpriority"`
 	Depends          []string `json:"depends"`
+	AncestorCount    int64    `json:"ancestorcount"`
+	AncestorSize     int64    `json:"ancestorsize"`
+	AncestorFees     float64  `json:"ancestorfees"`
+	DescendantCount  int64    `json:"descendantcount"`
+	DescendantSize   int64    `json:"descendantsize"`
+	DescendantFees   float64  `json:"descendantfees"`
 }
 
 // TxRawResult models the data from the getrawtransaction command.
@@ -404,6 +461,27 @@ type GetStakeVersionsResult struct {
 	StakeVersions []StakeVersions `json:"stakeversions"`
 }
 
+// GetSyncInfoPeerResult models the block download state of a sync candidate
+// peer returned from the getsyncinfo command.
+type GetSyncInfoPeerResult struct {
+	ID             int32  `json:"id"`
+	Addr           string `json:"addr"`
+	SyncNode       bool   `json:"syncnode"`
+	BlocksInFlight int32  `json:"blocksinflight"`
+	DownloadRate   int64  `json:"downloadrate"`
+	LastStall      int64  `json:"laststall"`
+}
+
+// GetSyncInfoResult models the data returned from the getsyncinfo command.
+type GetSyncInfoResult struct {
+	SyncNode           string                  `json:"syncnode"`
+	SyncHeight         int64                   `json:"syncheight"`
+	HeadersFirstMode   bool                    `json:"headersfirstmode"`
+	SyncNodeSwitches   uint32                  `json:"syncnodeswitches"`
+	LastSyncNodeSwitch int64                   `json:"lastsyncnodeswitch"`
+	Peers              []GetSyncInfoPeerResult `json:"peers"`
+}
+
 // GetTxOutResult models the data from the gettxout command.
 type GetTxOutResult struct {
 	BestBlock     string             `json:"bestblock"`
@@ -414,6 +492,17 @@ type GetTxOutResult struct {
 	Coinbase      bool               `json:"coinbase"`
 }
 
+// GetTxOutSetInfoResult models the data from the gettxoutsetinfo command.
+type GetTxOutSetInfoResult struct {
+	Height         int64   `json:"height"`
+	BestBlock      string  `json:"bestblock"`
+	Transactions   int64   `json:"transactions"`
+	TxOuts         int64   `json:"txouts"`
+	SerializedSize int64   `json:"serializedsize"`
+	HashSerialized string  `json:"hashserialized"`
+	TotalAmount    float64 `json:"totalamount"`
+}
+
 // Choice models an individual choice inside an Agenda.
 type Choice struct {
 	ID          string  `json:"id"`
@@ -461,6 +550,14 @@ type Ticket struct {
 	Owner string `json:"owner"`
 }
 
+// ListBannedResult models the data returned from the listbanned command.
+type ListBannedResult struct {
+	Address     string `json:"address"`
+	BanCreated  int64  `json:"bancreated"`
+	BannedUntil int64  `json:"banneduntil"`
+	BanReason   string `json:"banreason"`
+}
+
 // LiveTicketsResult models the data returned from the livetickets
 // command.
 type LiveTicketsResult struct {
@@ -542,6 +639,26 @@ type SearchRawTransactionsResult struct {
 	Blocktime     int64        `json:"blocktime,omitempty"`
 }
 
+// SendRawPackageResult models the data returned for each transaction from the
+// sendrawpackage command.
+type SendRawPackageResult struct {
+	TxID       string `json:"txid"`
+	Accepted   bool   `json:"accepted"`
+	RejectCode string `json:"rejectcode,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// TestMempoolAcceptResult models the data returned for each transaction from
+// the testmempoolaccept command.
+type TestMempoolAcceptResult struct {
+	TxID       string  `json:"txid"`
+	Allowed    bool    `json:"allowed"`
+	Size       int64   `json:"size,omitempty"`
+	Fee        float64 `json:"fee,omitempty"`
+	RejectCode string  `json:"rejectcode,omitempty"`
+	Reason     string  `json:"reason,omitempty"`
+}
+
 // TxFeeInfoResult models the data returned from the ticketfeeinfo command.
 // command.
 type TxFeeInfoResult struct {
@@ -111,6 +111,25 @@ func NewStopNotifyBlocksCmd() *StopNotifyBlocksCmd {
 	return &StopNotifyBlocksCmd{}
 }
 
+// NotifyMempoolEventsCmd defines the notifymempoolevents JSON-RPC command.
+type NotifyMempoolEventsCmd struct{}
+
+// NewNotifyMempoolEventsCmd returns a new instance which can be used to issue
+// a notifymempoolevents JSON-RPC command.
+func NewNotifyMempoolEventsCmd() *NotifyMempoolEventsCmd {
+	return &NotifyMempoolEventsCmd{}
+}
+
+// StopNotifyMempoolEventsCmd defines the stopnotifymempoolevents JSON-RPC
+// command.
+type StopNotifyMempoolEventsCmd struct{}
+
+// NewStopNotifyMempoolEventsCmd returns a new instance which can be used to
+// issue a stopnotifymempoolevents JSON-RPC command.
+func NewStopNotifyMempoolEventsCmd() *StopNotifyMempoolEventsCmd {
+	return &StopNotifyMempoolEventsCmd{}
+}
+
 // NotifyNewTransactionsCmd defines the notifynewtransactions JSON-RPC command.
 type NotifyNewTransactionsCmd struct {
 	Verbose *bool `jsonrpcdefault:"false"`
@@ -168,6 +187,7 @@ func init() {
 	MustRegisterCmd("authenticate", (*AuthenticateCmd)(nil), flags)
 	MustRegisterCmd("loadtxfilter", (*LoadTxFilterCmd)(nil), flags)
 	MustRegisterCmd("notifyblocks", (*NotifyBlocksCmd)(nil), flags)
+	MustRegisterCmd("notifymempoolevents", (*NotifyMempoolEventsCmd)(nil), flags)
 	MustRegisterCmd("notifynewtransactions", (*NotifyNewTransactionsCmd)(nil), flags)
 	MustRegisterCmd("notifynewtickets", (*NotifyNewTicketsCmd)(nil), flags)
 	MustRegisterCmd("notifyspentandmissedtickets",
@@ -178,6 +198,8 @@ func init() {
 		(*NotifyWinningTicketsCmd)(nil), flags)
 	MustRegisterCmd("session", (*SessionCmd)(nil), flags)
 	MustRegisterCmd("stopnotifyblocks", (*StopNotifyBlocksCmd)(nil), flags)
+	MustRegisterCmd("stopnotifymempoolevents",
+		(*StopNotifyMempoolEventsCmd)(nil), flags)
 	MustRegisterCmd("stopnotifynewtransactions", (*StopNotifyNewTransactionsCmd)(nil), flags)
 	MustRegisterCmd("rescan", (*RescanCmd)(nil), flags)
 }
@@ -131,6 +131,28 @@ func TestChainSvrWsCmds(t *testing.T) {
 				Verbose: Bool(true),
 			},
 		},
+		{
+			name: "notifymempoolevents",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("notifymempoolevents")
+			},
+			staticCmd: func() interface{} {
+				return NewNotifyMempoolEventsCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"notifymempoolevents","params":[],"id":1}`,
+			unmarshalled: &NotifyMempoolEventsCmd{},
+		},
+		{
+			name: "stopnotifymempoolevents",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("stopnotifymempoolevents")
+			},
+			staticCmd: func() interface{} {
+				return NewStopNotifyMempoolEventsCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"stopnotifymempoolevents","params":[],"id":1}`,
+			unmarshalled: &StopNotifyMempoolEventsCmd{},
+		},
 		{
 			name: "stopnotifynewtransactions",
 			newCmd: func() (interface{}, error) {
@@ -17,6 +17,15 @@ const (
 	// the chain server that a block has been disconnected.
 	BlockDisconnectedNtfnMethod = "blockdisconnected"
 
+	// MempoolTxAddedNtfnMethod is the method used for notifications from
+	// the chain server that a transaction has been added to the mempool.
+	MempoolTxAddedNtfnMethod = "mempooltxadded"
+
+	// MempoolTxRemovedNtfnMethod is the method used for notifications from
+	// the chain server that a transaction has been removed from the mempool
+	// along with the reason it was removed.
+	MempoolTxRemovedNtfnMethod = "mempooltxremoved"
+
 	// NewTicketsNtfnMethod is the method of the daemon newtickets notification.
 	NewTicketsNtfnMethod = "newtickets"
 
@@ -80,6 +89,38 @@ func NewBlockDisconnectedNtfn(header string) *BlockDisconnectedNtfn {
 	}
 }
 
+// MempoolTxAddedNtfn defines the mempooltxadded JSON-RPC notification.
+type MempoolTxAddedNtfn struct {
+	TxID   string  `json:"txid"`
+	TxType string  `json:"txtype"`
+	Fee    float64 `json:"fee"`
+}
+
+// NewMempoolTxAddedNtfn returns a new instance which can be used to issue a
+// mempooltxadded JSON-RPC notification.
+func NewMempoolTxAddedNtfn(txHash string, txType string, fee float64) *MempoolTxAddedNtfn {
+	return &MempoolTxAddedNtfn{
+		TxID:   txHash,
+		TxType: txType,
+		Fee:    fee,
+	}
+}
+
+// MempoolTxRemovedNtfn defines the mempooltxremoved JSON-RPC notification.
+type MempoolTxRemovedNtfn struct {
+	TxID   string `json:"txid"`
+	Reason string `json:"reason"`
+}
+
+// NewMempoolTxRemovedNtfn returns a new instance which can be used to issue a
+// mempooltxremoved JSON-RPC notification.
+func NewMempoolTxRemovedNtfn(txHash string, reason string) *MempoolTxRemovedNtfn {
+	return &MempoolTxRemovedNtfn{
+		TxID:   txHash,
+		Reason: reason,
+	}
+}
+
 // NewTicketsNtfn is a type handling custom marshaling and
 // unmarshaling of newtickets JSON websocket notifications.
 type NewTicketsNtfn struct {
@@ -219,6 +260,8 @@ func init() {
 
 	MustRegisterCmd(BlockConnectedNtfnMethod, (*BlockConnectedNtfn)(nil), flags)
 	MustRegisterCmd(BlockDisconnectedNtfnMethod, (*BlockDisconnectedNtfn)(nil), flags)
+	MustRegisterCmd(MempoolTxAddedNtfnMethod, (*MempoolTxAddedNtfn)(nil), flags)
+	MustRegisterCmd(MempoolTxRemovedNtfnMethod, (*MempoolTxRemovedNtfn)(nil), flags)
 	MustRegisterCmd(NewTicketsNtfnMethod, (*NewTicketsNtfn)(nil), flags)
 	MustRegisterCmd(ReorganizationNtfnMethod, (*ReorganizationNtfn)(nil), flags)
 	MustRegisterCmd(TxAcceptedNtfnMethod, (*TxAcceptedNtfn)(nil), flags)
@@ -70,6 +70,35 @@ func TestChainSvrWsNtfns(t *testing.T) {
 				Tickets:   []string{"a", "b"},
 			},
 		},
+		{
+			name: "mempooltxadded",
+			newNtfn: func() (interface{}, error) {
+				return NewCmd("mempooltxadded", "123", "regular", 0.0001)
+			},
+			staticNtfn: func() interface{} {
+				return NewMempoolTxAddedNtfn("123", "regular", 0.0001)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"mempooltxadded","params":["123","regular",0.0001],"id":null}`,
+			unmarshalled: &MempoolTxAddedNtfn{
+				TxID:   "123",
+				TxType: "regular",
+				Fee:    0.0001,
+			},
+		},
+		{
+			name: "mempooltxremoved",
+			newNtfn: func() (interface{}, error) {
+				return NewCmd("mempooltxremoved", "123", "mined")
+			},
+			staticNtfn: func() interface{} {
+				return NewMempoolTxRemovedNtfn("123", "mined")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"mempooltxremoved","params":["123","mined"],"id":null}`,
+			unmarshalled: &MempoolTxRemovedNtfn{
+				TxID:   "123",
+				Reason: "mined",
+			},
+		},
 		{
 			name: "relevanttxaccepted",
 			newNtfn: func() (interface{}, error) {
@@ -38,6 +38,15 @@ Application Options:
       --maxsameip=          Max number of connections with the same IP -- 0 to
                             disable (default: 5)
       --maxpeers=           Max number of inbound and outbound peers (125)
+      --blockrelayonlypeers=
+                            Number of additional outbound peers that are only
+                            used to relay blocks (2)
+      --maxanchors=         Max number of block relay only peers to save on
+                            shutdown and reconnect to first on startup (2)
+      --maxuploadtarget=    Max number of MiB to upload to peers per 24 hours --
+                            Historical blocks are no longer served to
+                            non-whitelisted peers once it is reached -- 0 to
+                            disable
       --nobanning           Disable banning of misbehaving peers
       --banduration=        How long to ban misbehaving peers.  Valid time units
                             are {s, m, h}.  Minimum 1 second (24h0m0s)
@@ -74,11 +83,16 @@ Application Options:
       --noonion             Disable connecting to tor hidden services
       --torisolation        Enable Tor stream isolation by randomizing user
                             credentials for each connection.
+      --nov2transport       Disable the encrypted v2 peer-to-peer transport
       --testnet             Use the test network
       --simnet              Use the simulation test network
       --regnet              Use the regression test network
       --nocheckpoints       Disable built-in checkpoints.  Don't do this unless
                             you know what you're doing.
+      --assumevalid=        Hash of a block that is assumed to be valid, which
+                            skips script validation for it and its ancestors
+                            when it is part of the best header chain -- 0 to
+                            validate all scripts (default: network specific)
       --dbtype=             Database backend to use for the Block Chain (ffldb)
       --profile=            Enable HTTP profiling on given [addr:]port -- NOTE: port
                             must be between 1024 and 65536
@@ -103,6 +117,11 @@ Application Options:
                             high priority for relaying
       --maxorphantx=        Max number of orphan transactions to keep in memory
                             (1000)
+      --maxmempool=         Max size in MiB of the transaction memory pool --
+                            Transactions with the lowest fee rates are evicted
+                            when it is exceeded -- 0 to disable (300)
+      --nopersistmempool    Do not save the mempool on shutdown and load it on
+                            startup
       --generate            Generate (mine) bitcoins using the CPU
       --miningaddr=         Add the specified payment address to the list of
                             addresses to use for generated blocks -- At least
@@ -114,6 +133,15 @@ Application Options:
                             a block (375000)
       --blockprioritysize=  Size in bytes for high-priority/low-fee transactions
                             when creating a block (20000)
+      --miningselector=     Transaction selection strategy to use when creating
+                            a block {standard, feerate, ancestorfeerate,
+                            priority} (standard)
+      --miningallowaddr=    Only include regular transactions paying to at least
+                            one of the specified addresses when creating a block
+                            -- May be specified multiple times
+      --miningdenyaddr=     Do not include regular transactions paying to the
+                            specified address when creating a block -- May be
+                            specified multiple times
       --nonaggressive       Disable mining off of the parent block of the blockchain
                             if there aren't enough voters
       --nominingstatesync   Disable synchronizing the mining state with other nodes
@@ -121,7 +149,27 @@ Application Options:
 
       --sigcachemaxsize=    The maximum number of entries in the signature
                             verification cache.
+      --utxocachemaxsize=   The maximum size in MiB of the in-memory cache of
+                            unspent transaction outputs that are written to the
+                            database in batches -- 0 to write them to the
+                            database as each block is processed (default: 150)
       --blocksonly          Do not accept transactions from remote peers.
+      --prune=              Delete the oldest block data to keep the stored
+                            block data near the specified number of MiB while
+                            retaining the headers, UTXO set, and recent blocks
+                            -- Incompatible with --txindex and --addrindex -- 0
+                            to disable (minimum 1024)
+      --headersonly         Only download and validate block headers without
+                            maintaining the UTXO set -- Committed filters are
+                            fetched from peers on demand and transactions are
+                            not accepted
+      --loadsnapshot=       Load the chain state from the specified snapshot
+                            file created with the dumputxoset RPC when the
+                            database does not contain a chain yet and sync from
+                            its height while the history is validated in the
+                            background -- Only snapshots listed in the network
+                            parameters are accepted -- Incompatible with
+                            --txindex, --addrindex, and --headersonly
       --acceptnonstd        Accept and relay non-standard transactions to
                             the network regardless of the default settings
                             for the active network.
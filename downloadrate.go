@@ -0,0 +1,58 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import "time"
+
+// downloadRateSampleWeight is the weight given to the most recent sample when
+// updating the moving average of a block download rate.
+const downloadRateSampleWeight = 0.5
+
+// blockDownloadRate tracks the rate at which a peer delivers the blocks that
+// were requested from it.  The rate is an exponential moving average of the
+// number of bytes delivered per second during each sampled interval.
+//
+// It is not safe for concurrent access.
+type blockDownloadRate struct {
+	intervalBytes uint64
+	rate          float64
+	samples       uint32
+}
+
+// AddBytes adds the passed number of bytes to those delivered during the
+// current interval.
+func (r *blockDownloadRate) AddBytes(numBytes int) {
+	r.intervalBytes += uint64(numBytes)
+}
+
+// Sample ends the current interval, which lasted for the passed duration, and
+// updates the rate with the bytes delivered during it.  Intervals during which
+// the peer was idle, meaning it had no blocks in flight and did not deliver
+// any, are not sampled since they say nothing about its throughput.
+func (r *blockDownloadRate) Sample(interval time.Duration, busy bool) {
+	intervalBytes := r.intervalBytes
+	r.intervalBytes = 0
+	if interval <= 0 || (intervalBytes == 0 && !busy) {
+		return
+	}
+
+	sample := float64(intervalBytes) / interval.Seconds()
+	if r.samples == 0 {
+		r.rate = sample
+	} else {
+		r.rate += downloadRateSampleWeight * (sample - r.rate)
+	}
+	r.samples++
+}
+
+// Rate returns the average number of bytes delivered per second.
+func (r *blockDownloadRate) Rate() float64 {
+	return r.rate
+}
+
+// Samples returns the number of intervals the rate is based on.
+func (r *blockDownloadRate) Samples() uint32 {
+	return r.samples
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestBlockDownloadRate ensures the block download rate is averaged over the
+// sampled intervals as expected and that idle intervals are ignored.
+func TestBlockDownloadRate(t *testing.T) {
+	var rate blockDownloadRate
+
+	// Ensure idle intervals are not sampled.
+	rate.Sample(10*time.Second, false)
+	if rate.Samples() != 0 || rate.Rate() != 0 {
+		t.Fatalf("idle interval sampled - got %d samples with rate %v",
+			rate.Samples(), rate.Rate())
+	}
+
+	// The first sample is used as is.
+	rate.AddBytes(40000)
+	rate.AddBytes(60000)
+	rate.Sample(10*time.Second, false)
+	if rate.Samples() != 1 || rate.Rate() != 10000 {
+		t.Fatalf("unexpected first sample - got %d samples with rate %v",
+			rate.Samples(), rate.Rate())
+	}
+
+	// Ensure an interval without any delivered bytes is sampled when the
+	// peer had blocks in flight and moves the average towards zero.
+	rate.Sample(10*time.Second, true)
+	if rate.Samples() != 2 || rate.Rate() != 5000 {
+		t.Fatalf("unexpected stalled sample - got %d samples with rate %v",
+			rate.Samples(), rate.Rate())
+	}
+
+	// Ensure later samples move the average towards them.
+	rate.AddBytes(250000)
+	rate.Sample(10*time.Second, true)
+	if rate.Samples() != 3 || rate.Rate() != 15000 {
+		t.Fatalf("unexpected sample - got %d samples with rate %v",
+			rate.Samples(), rate.Rate())
+	}
+}
@@ -76,6 +76,13 @@ Estimation stage:
   confirmation within the desired confirmation window is > 95%
 - Average all such buckets to get the estimated fee rate
 
+The statistics are tracked with both a long and a short decay horizon. The
+conservative estimation mode uses the long horizon and the 95% threshold
+described above, while the economical mode uses the short horizon and a lower
+threshold (85%) so that it reacts faster to recent fee conditions.
+EstimateFee and EstimateTicketFee use the conservative mode, while
+EstimateFeeWithMode and EstimateTicketFeeWithMode accept the mode to use.
+
 Simulation
 
 Development of the estimator was originally performed and simulated using the
@@ -35,9 +35,28 @@ const (
 	DefaultFeeRateStep float64 = 1.1
 
 	// defaultDecay is the default value used to decay old transactions from the
-	// estimator.
+	// estimator.  It defines the long decay horizon used by the conservative
+	// estimation mode.
 	defaultDecay float64 = 0.998
 
+	// shortDecay is the value used to decay old transactions from the
+	// statistics tracked with a short decay horizon, which are used by the
+	// economical estimation mode so that it reacts faster to changes in the
+	// fees paid by recent transactions.
+	shortDecay float64 = 0.962
+
+	// conservativeSuccessPct is the minimum percentage of transactions that
+	// must have been confirmed within the target confirmation range in order
+	// for a fee rate bucket to be considered by the conservative estimation
+	// mode.
+	conservativeSuccessPct float64 = 0.95
+
+	// economicalSuccessPct is the minimum percentage of transactions that
+	// must have been confirmed within the target confirmation range in order
+	// for a fee rate bucket to be considered by the economical estimation
+	// mode.
+	economicalSuccessPct float64 = 0.85
+
 	// maxAllowedBucketFees is an upper bound of how many bucket fees can be
 	// used in the estimator. This is verified during estimator initialization
 	// and database loading.
@@ -67,8 +86,53 @@ var (
 	dbKeyMaxConfirms  = []byte("maxConfirms")
 	dbKeyBestHeight   = []byte("bestHeight")
 	dbKeyBucketPrefix = []byte{0x01, 0x70, 0x1d, 0x00}
+
+	// dbKeyTicketBucketPrefix is the prefix of the keys used to store the
+	// confirmation statistics of ticket purchases.
+	dbKeyTicketBucketPrefix = []byte{0x01, 0x70, 0x1d, 0x01}
+
+	// dbKeyShortBucketPrefix and dbKeyShortTicketBucketPrefix are the
+	// prefixes of the keys used to store the confirmation statistics tracked
+	// with a short decay horizon of regular transactions and ticket
+	// purchases respectively.
+	dbKeyShortBucketPrefix       = []byte{0x01, 0x70, 0x1d, 0x02}
+	dbKeyShortTicketBucketPrefix = []byte{0x01, 0x70, 0x1d, 0x03}
 )
 
+// EstimateMode defines the mode used to estimate fees.  Each mode maps to a
+// different minimum success percentage and decay horizon of the statistics
+// used to derive the estimate.
+type EstimateMode int
+
+const (
+	// EstimateConservative estimates fees using statistics decayed over a
+	// long horizon and requiring a high percentage of transactions to have
+	// been confirmed within the target.  It is less responsive to short
+	// term drops in fees and potentially returns a higher fee rate.
+	EstimateConservative EstimateMode = iota
+
+	// EstimateEconomical estimates fees using statistics decayed over a
+	// short horizon and requiring a lower percentage of transactions to have
+	// been confirmed within the target.  It is more responsive to recent fee
+	// conditions and potentially returns a lower fee rate.
+	EstimateEconomical
+)
+
+// estimateModeStrings is a map of estimation modes back to their constant
+// names for pretty printing.
+var estimateModeStrings = map[EstimateMode]string{
+	EstimateConservative: "EstimateConservative",
+	EstimateEconomical:   "EstimateEconomical",
+}
+
+// String returns the EstimateMode in human-readable form.
+func (m EstimateMode) String() string {
+	if s, ok := estimateModeStrings[m]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown EstimateMode (%d)", int(m))
+}
+
 // ErrTargetConfTooLarge is the type of error returned when an user of the
 // estimator requested a confirmation range higher than tracked by the estimator.
 type ErrTargetConfTooLarge struct {
@@ -95,6 +159,42 @@ type txConfirmStatBucket struct {
 	feeSum       float64
 }
 
+// txConfirmStats houses the confirmation statistics tracked for a single class
+// of transactions.  Regular transactions and ticket purchases are tracked
+// separately since tickets compete for the limited number of fresh stake slots
+// in each block and thus confirm under different fee conditions.
+type txConfirmStats struct {
+	// decay is the factor applied to the confirmed statistics every time a
+	// new block is mined.
+	decay float64
+
+	// buckets are the confirmed tx count and fee sum by bucket fee.
+	buckets []txConfirmStatBucket
+
+	// memPool are the mempool transaction count and fee sum by bucket fee.
+	memPool []txConfirmStatBucket
+}
+
+// newTxConfirmStats returns empty confirmation statistics for the given number
+// of fee rate buckets and confirmation ranges which are decayed by the passed
+// factor.
+func newTxConfirmStats(nbBuckets int, maxConfirms int32, decay float64) txConfirmStats {
+	s := txConfirmStats{
+		decay:   decay,
+		buckets: make([]txConfirmStatBucket, nbBuckets),
+		memPool: make([]txConfirmStatBucket, nbBuckets),
+	}
+	for i := 0; i < nbBuckets; i++ {
+		s.buckets[i] = txConfirmStatBucket{
+			confirmed: make([]txConfirmStatBucketCount, maxConfirms),
+		}
+		s.memPool[i] = txConfirmStatBucket{
+			confirmed: make([]txConfirmStatBucketCount, maxConfirms),
+		}
+	}
+	return s
+}
+
 // EstimatorConfig stores the configuration parameters for a given fee
 // estimator. It is used to initialize an empty fee estimator.
 type EstimatorConfig struct {
@@ -155,17 +255,22 @@ type Estimator struct {
 	// bucketFeeBounds are the upper bounds for each individual fee bucket.
 	bucketFeeBounds []feeRate
 
-	// buckets are the confirmed tx count and fee sum by bucket fee.
-	buckets []txConfirmStatBucket
+	// regular are the confirmation statistics of regular transactions.
+	regular txConfirmStats
 
-	// memPool are the mempool transaction count and fee sum by bucket fee.
-	memPool []txConfirmStatBucket
+	// tickets are the confirmation statistics of ticket purchases.
+	tickets txConfirmStats
+
+	// shortRegular and shortTickets are the confirmation statistics of
+	// regular transactions and ticket purchases tracked with a short decay
+	// horizon.
+	shortRegular txConfirmStats
+	shortTickets txConfirmStats
 
 	// memPoolTxs is the map of transaction hashes and data of known mempool txs.
 	memPoolTxs map[chainhash.Hash]memPoolTxDesc
 
 	maxConfirms int32
-	decay       float64
 	bestHeight  int64
 	db          *leveldb.DB
 	lock        sync.RWMutex
@@ -192,7 +297,6 @@ func NewEstimator(cfg *EstimatorConfig) (*Estimator, error) {
 			"maximum allowed (%d)", cfg.MaxConfirms, maxAllowedConfirms)
 	}
 
-	decay := defaultDecay
 	maxConfirms := cfg.MaxConfirms
 	max := float64(cfg.MaxBucketFee)
 	var bucketFees []feeRate
@@ -214,24 +318,16 @@ func NewEstimator(cfg *EstimatorConfig) (*Estimator, error) {
 	nbBuckets := len(bucketFees)
 	res := &Estimator{
 		bucketFeeBounds: bucketFees,
-		buckets:         make([]txConfirmStatBucket, nbBuckets),
-		memPool:         make([]txConfirmStatBucket, nbBuckets),
+		regular:         newTxConfirmStats(nbBuckets, int32(maxConfirms), defaultDecay),
+		tickets:         newTxConfirmStats(nbBuckets, int32(maxConfirms), defaultDecay),
+		shortRegular:    newTxConfirmStats(nbBuckets, int32(maxConfirms), shortDecay),
+		shortTickets:    newTxConfirmStats(nbBuckets, int32(maxConfirms), shortDecay),
 		maxConfirms:     int32(maxConfirms),
-		decay:           decay,
 		memPoolTxs:      make(map[chainhash.Hash]memPoolTxDesc),
 		bestHeight:      -1,
 		chainParams:     cfg.ChainParams,
 	}
 
-	for i := range bucketFees {
-		res.buckets[i] = txConfirmStatBucket{
-			confirmed: make([]txConfirmStatBucketCount, maxConfirms),
-		}
-		res.memPool[i] = txConfirmStatBucket{
-			confirmed: make([]txConfirmStatBucketCount, maxConfirms),
-		}
-	}
-
 	if cfg.DatabaseFile != "" {
 		db, err := leveldb.OpenFile(cfg.DatabaseFile, nil)
 		if err != nil {
@@ -249,8 +345,50 @@ func NewEstimator(cfg *EstimatorConfig) (*Estimator, error) {
 	return res, nil
 }
 
-// DumpBuckets returns the internal estimator state as a string.
+// txStats returns the confirmation statistics of all decay horizons used to
+// track ticket purchases when isTicket is true or regular transactions
+// otherwise.
+func (stats *Estimator) txStats(isTicket bool) []*txConfirmStats {
+	if isTicket {
+		return []*txConfirmStats{&stats.tickets, &stats.shortTickets}
+	}
+	return []*txConfirmStats{&stats.regular, &stats.shortRegular}
+}
+
+// modeStats returns the confirmation statistics and the minimum success
+// percentage to use when estimating the fee of ticket purchases (when isTicket
+// is true) or regular transactions in the given mode.
+func (stats *Estimator) modeStats(isTicket bool, mode EstimateMode) (*txConfirmStats, float64, error) {
+	switch mode {
+	case EstimateConservative:
+		if isTicket {
+			return &stats.tickets, conservativeSuccessPct, nil
+		}
+		return &stats.regular, conservativeSuccessPct, nil
+
+	case EstimateEconomical:
+		if isTicket {
+			return &stats.shortTickets, economicalSuccessPct, nil
+		}
+		return &stats.shortRegular, economicalSuccessPct, nil
+	}
+
+	return nil, 0, fmt.Errorf("unknown estimation mode %v", mode)
+}
+
+// DumpBuckets returns the internal estimator state as a string.  The
+// statistics of regular transactions are followed by those of ticket
+// purchases, first for the long and then for the short decay horizon.
 func (stats *Estimator) DumpBuckets() string {
+	return "Regular transactions:\n" + stats.dumpBuckets(&stats.regular) +
+		"\nTickets:\n" + stats.dumpBuckets(&stats.tickets) +
+		"\nRegular transactions (short horizon):\n" +
+		stats.dumpBuckets(&stats.shortRegular) +
+		"\nTickets (short horizon):\n" + stats.dumpBuckets(&stats.shortTickets)
+}
+
+// dumpBuckets returns the passed confirmation statistics as a string.
+func (stats *Estimator) dumpBuckets(s *txConfirmStats) string {
 	res := "          |"
 	for c := 0; c < int(stats.maxConfirms); c++ {
 		if c == int(stats.maxConfirms)-1 {
@@ -266,10 +404,10 @@ func (stats *Estimator) DumpBuckets() string {
 		res += fmt.Sprintf("%10.8f", stats.bucketFeeBounds[i]/1e8)
 		for c := 0; c < int(stats.maxConfirms); c++ {
 			avg := float64(0)
-			count := stats.buckets[i].confirmed[c].txCount
-			if stats.buckets[i].confirmed[c].txCount > 0 {
-				avg = stats.buckets[i].confirmed[c].feeSum /
-					stats.buckets[i].confirmed[c].txCount / 1e8
+			count := s.buckets[i].confirmed[c].txCount
+			if s.buckets[i].confirmed[c].txCount > 0 {
+				avg = s.buckets[i].confirmed[c].feeSum /
+					s.buckets[i].confirmed[c].txCount / 1e8
 			}
 
 			res += fmt.Sprintf("| %.8f %6.1f", avg, count)
@@ -303,6 +441,11 @@ func (stats *Estimator) DumpBuckets() string {
 // information in the estimator without the saving the corresponding data in the
 // mempool itself could result in transactions lingering in the mempool
 // estimator forever.
+//
+// Version 1 databases did not track ticket purchases separately and version 2
+// databases did not track statistics with a short decay horizon, so they are
+// upgraded by loading the statistics they contain and starting with empty
+// statistics for the missing ones.
 func (stats *Estimator) loadFromDatabase(replaceBuckets bool) error {
 	if stats.db == nil {
 		return errors.New("estimator database is not open")
@@ -310,7 +453,7 @@ func (stats *Estimator) loadFromDatabase(replaceBuckets bool) error {
 
 	// Database version is currently hardcoded here as this is the only
 	// place that uses it.
-	currentDbVersion := []byte{1}
+	currentDbVersion := []byte{3}
 
 	version, err := stats.db.Get(dbKeyVersion, nil)
 	if err != nil && err != leveldb.ErrNotFound {
@@ -354,7 +497,9 @@ func (stats *Estimator) loadFromDatabase(replaceBuckets bool) error {
 		return nil
 	}
 
-	if !bytes.Equal(currentDbVersion, version) {
+	upgrade := len(version) == 1 && version[0] >= 1 &&
+		version[0] < currentDbVersion[0]
+	if !upgrade && !bytes.Equal(currentDbVersion, version) {
 		return fmt.Errorf("incompatible database version: %d", version)
 	}
 
@@ -410,10 +555,70 @@ func (stats *Estimator) loadFromDatabase(replaceBuckets bool) error {
 		}
 	}
 
-	fileBuckets := make([]txConfirmStatBucket, fileNbBucketFees)
+	regularBuckets, err := stats.loadBuckets(dbKeyBucketPrefix,
+		fileNbBucketFees, fileMaxConfirms)
+	if err != nil {
+		return err
+	}
+	ticketBuckets, err := stats.loadBuckets(dbKeyTicketBucketPrefix,
+		fileNbBucketFees, fileMaxConfirms)
+	if err != nil {
+		return err
+	}
+	shortRegularBuckets, err := stats.loadBuckets(dbKeyShortBucketPrefix,
+		fileNbBucketFees, fileMaxConfirms)
+	if err != nil {
+		return err
+	}
+	shortTicketBuckets, err := stats.loadBuckets(dbKeyShortTicketBucketPrefix,
+		fileNbBucketFees, fileMaxConfirms)
+	if err != nil {
+		return err
+	}
+
+	stats.bucketFeeBounds = fileBucketFees
+	stats.regular = newTxConfirmStats(fileNbBucketFees, fileMaxConfirms,
+		defaultDecay)
+	stats.regular.buckets = regularBuckets
+	stats.tickets = newTxConfirmStats(fileNbBucketFees, fileMaxConfirms,
+		defaultDecay)
+	stats.tickets.buckets = ticketBuckets
+	stats.shortRegular = newTxConfirmStats(fileNbBucketFees, fileMaxConfirms,
+		shortDecay)
+	stats.shortRegular.buckets = shortRegularBuckets
+	stats.shortTickets = newTxConfirmStats(fileNbBucketFees, fileMaxConfirms,
+		shortDecay)
+	stats.shortTickets.buckets = shortTicketBuckets
+	stats.maxConfirms = fileMaxConfirms
+
+	if upgrade {
+		// Store the statistics missing from older versions along with the
+		// new version so the database is fully upgraded.
+		if err := stats.updateDatabase(); err != nil {
+			return fmt.Errorf("error upgrading estimator db: %v", err)
+		}
+		if err := stats.db.Put(dbKeyVersion, currentDbVersion, nil); err != nil {
+			return fmt.Errorf("error writing estimator db version: %v", err)
+		}
+		log.Infof("Upgraded fee estimator database to version %d",
+			currentDbVersion[0])
+	}
 
-	iter := stats.db.NewIterator(ldbutil.BytesPrefix(dbKeyBucketPrefix), nil)
-	err = nil
+	log.Debug("Loaded fee estimator database")
+
+	return nil
+}
+
+// loadBuckets reads the confirmed statistics stored in the database under the
+// passed key prefix.  Buckets that are not stored in the database are empty.
+func (stats *Estimator) loadBuckets(prefix []byte, nbBuckets int, maxConfirms int32) ([]txConfirmStatBucket, error) {
+	buckets := make([]txConfirmStatBucket, nbBuckets)
+	for i := range buckets {
+		buckets[i].confirmed = make([]txConfirmStatBucketCount, maxConfirms)
+	}
+
+	iter := stats.db.NewIterator(ldbutil.BytesPrefix(prefix), nil)
+	var err error
 	var fbytes [8]byte
 	for iter.Next() {
 		key := iter.Key()
@@ -423,13 +628,13 @@ func (stats *Estimator) loadFromDatabase(replaceBuckets bool) error {
 			break
 		}
 		idx := int(int32(dbByteOrder.Uint32(key[4:])))
-		if (idx >= len(fileBuckets)) || (idx < 0) {
+		if (idx >= len(buckets)) || (idx < 0) {
 			err = fmt.Errorf("wrong bucket index read from db (%d vs %d)",
-				idx, len(fileBuckets))
+				idx, len(buckets))
 			break
 		}
 		value := iter.Value()
-		if len(value) != 8+8+int(fileMaxConfirms)*16 {
+		if len(value) != 8+8+int(maxConfirms)*16 {
 			err = errors.New("wrong size of data in bucket read from db")
 			break
 		}
@@ -443,29 +648,23 @@ func (stats *Estimator) loadFromDatabase(replaceBuckets bool) error {
 			return math.Float64frombits(dbByteOrder.Uint64(fbytes[:]))
 		}
 
-		fileBuckets[idx].confirmCount = readf()
-		fileBuckets[idx].feeSum = readf()
-		fileBuckets[idx].confirmed = make([]txConfirmStatBucketCount, fileMaxConfirms)
-		for i := range fileBuckets[idx].confirmed {
-			fileBuckets[idx].confirmed[i].txCount = readf()
-			fileBuckets[idx].confirmed[i].feeSum = readf()
+		buckets[idx].confirmCount = readf()
+		buckets[idx].feeSum = readf()
+		for i := range buckets[idx].confirmed {
+			buckets[idx].confirmed[i].txCount = readf()
+			buckets[idx].confirmed[i].feeSum = readf()
 		}
 	}
 	iter.Release()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	err = iter.Error()
 	if err != nil {
-		return fmt.Errorf("error on bucket iterator: %v", err)
+		return nil, fmt.Errorf("error on bucket iterator: %v", err)
 	}
 
-	stats.bucketFeeBounds = fileBucketFees
-	stats.buckets = fileBuckets
-	stats.maxConfirms = fileMaxConfirms
-	log.Debug("Loaded fee estimator database")
-
-	return nil
+	return buckets, nil
 }
 
 // updateDatabase updates the current database file with the current bucket
@@ -480,7 +679,6 @@ func (stats *Estimator) updateDatabase() error {
 	buf := bytes.NewBuffer(nil)
 
 	var key [8]byte
-	copy(key[:], dbKeyBucketPrefix)
 	var fbytes [8]byte
 	writef := func(f float64) {
 		dbByteOrder.PutUint64(fbytes[:], math.Float64bits(f))
@@ -490,17 +688,24 @@ func (stats *Estimator) updateDatabase() error {
 		}
 	}
 
-	for i, b := range stats.buckets {
-		dbByteOrder.PutUint32(key[4:], uint32(i))
-		buf.Reset()
-		writef(b.confirmCount)
-		writef(b.feeSum)
-		for _, c := range b.confirmed {
-			writef(c.txCount)
-			writef(c.feeSum)
+	putBuckets := func(prefix []byte, buckets []txConfirmStatBucket) {
+		copy(key[:], prefix)
+		for i, b := range buckets {
+			dbByteOrder.PutUint32(key[4:], uint32(i))
+			buf.Reset()
+			writef(b.confirmCount)
+			writef(b.feeSum)
+			for _, c := range b.confirmed {
+				writef(c.txCount)
+				writef(c.feeSum)
+			}
+			batch.Put(key[:], buf.Bytes())
 		}
-		batch.Put(key[:], buf.Bytes())
 	}
+	putBuckets(dbKeyBucketPrefix, stats.regular.buckets)
+	putBuckets(dbKeyTicketBucketPrefix, stats.tickets.buckets)
+	putBuckets(dbKeyShortBucketPrefix, stats.shortRegular.buckets)
+	putBuckets(dbKeyShortTicketBucketPrefix, stats.shortTickets.buckets)
 
 	var bestHeightBytes [8]byte
 
@@ -544,24 +749,34 @@ func (stats *Estimator) confirmRange(blocksToConfirm int32) int32 {
 func (stats *Estimator) updateMovingAverages(newHeight int64) {
 	log.Debugf("Updated moving averages into block %d", newHeight)
 
+	stats.decayStats(&stats.regular)
+	stats.decayStats(&stats.tickets)
+	stats.decayStats(&stats.shortRegular)
+	stats.decayStats(&stats.shortTickets)
+	stats.bestHeight = newHeight
+}
+
+// decayStats updates the moving averages of the passed confirmation statistics
+// as described by updateMovingAverages.
+func (stats *Estimator) decayStats(s *txConfirmStats) {
 	// decay the existing stats so that, over time, we rely on more up to date
 	// information regarding fees.
-	for b := 0; b < len(stats.buckets); b++ {
-		bucket := &stats.buckets[b]
-		bucket.feeSum *= stats.decay
-		bucket.confirmCount *= stats.decay
+	for b := 0; b < len(s.buckets); b++ {
+		bucket := &s.buckets[b]
+		bucket.feeSum *= s.decay
+		bucket.confirmCount *= s.decay
 		for c := 0; c < len(bucket.confirmed); c++ {
 			conf := &bucket.confirmed[c]
-			conf.feeSum *= stats.decay
-			conf.txCount *= stats.decay
+			conf.feeSum *= s.decay
+			conf.txCount *= s.decay
 		}
 	}
 
 	// For unconfirmed (mempool) transactions, every transaction will now take
 	// at least one additional block to confirm. So for every fee bucket, we
 	// move the stats up one confirmation range.
-	for b := 0; b < len(stats.memPool); b++ {
-		bucket := &stats.memPool[b]
+	for b := 0; b < len(s.memPool); b++ {
+		bucket := &s.memPool[b]
 
 		// The last confirmation range represents all txs confirmed at >= than
 		// the initial maxConfirms, so we *add* the second to last range into
@@ -581,16 +796,14 @@ func (stats *Estimator) updateMovingAverages(newHeight int64) {
 		bucket.confirmed[0].txCount = 0
 		bucket.confirmed[0].feeSum = 0
 	}
-
-	stats.bestHeight = newHeight
 }
 
 // newMemPoolTx records a new memPool transaction into the stats. A brand new
 // mempool transaction has a minimum confirmation range of 1, so it is inserted
 // into the very first confirmation range bucket of the appropriate fee rate
 // bucket.
-func (stats *Estimator) newMemPoolTx(bucketIdx int32, fees feeRate) {
-	conf := &stats.memPool[bucketIdx].confirmed[0]
+func (stats *Estimator) newMemPoolTx(s *txConfirmStats, bucketIdx int32, fees feeRate) {
+	conf := &s.memPool[bucketIdx].confirmed[0]
 	conf.feeSum += float64(fees)
 	conf.txCount++
 }
@@ -599,10 +812,10 @@ func (stats *Estimator) newMemPoolTx(bucketIdx int32, fees feeRate) {
 // Note that this should only be called if the transaction had been seen and
 // previously tracked by calling newMemPoolTx for it. Failing to observe that
 // will result in undefined statistical results.
-func (stats *Estimator) newMinedTx(blocksToConfirm int32, rate feeRate) {
+func (stats *Estimator) newMinedTx(s *txConfirmStats, blocksToConfirm int32, rate feeRate) {
 	bucketIdx := stats.lowerBucket(rate)
 	confirmIdx := stats.confirmRange(blocksToConfirm)
-	bucket := &stats.buckets[bucketIdx]
+	bucket := &s.buckets[bucketIdx]
 
 	// increase the counts for all confirmation ranges starting at the first
 	// confirmIdx because it took at least `blocksToConfirm` for this tx to be
@@ -618,10 +831,10 @@ func (stats *Estimator) newMinedTx(blocksToConfirm int32, rate feeRate) {
 	bucket.feeSum += float64(rate)
 }
 
-func (stats *Estimator) removeFromMemPool(blocksInMemPool int32, rate feeRate) {
+func (stats *Estimator) removeFromMemPool(s *txConfirmStats, blocksInMemPool int32, rate feeRate) {
 	bucketIdx := stats.lowerBucket(rate)
 	confirmIdx := stats.confirmRange(blocksInMemPool + 1)
-	bucket := &stats.memPool[bucketIdx]
+	bucket := &s.memPool[bucketIdx]
 	conf := &bucket.confirmed[confirmIdx]
 	conf.feeSum -= float64(rate)
 	conf.txCount--
@@ -640,18 +853,90 @@ func (stats *Estimator) removeFromMemPool(blocksInMemPool int32, rate feeRate) {
 	}
 }
 
+// BucketRangeStats describes the statistics of a range of consecutive fee rate
+// buckets considered while estimating a fee.
+type BucketRangeStats struct {
+	// StartRange and EndRange are the lower and upper bounds of the fee
+	// rates (per KB) of the transactions in the range.  The highest bucket
+	// is unbounded, so ranges which include it report dcrutil.MaxAmount as
+	// their upper bound.
+	StartRange dcrutil.Amount
+	EndRange   dcrutil.Amount
+
+	// WithinTarget is the (decayed) number of transactions in the range that
+	// were confirmed within the target confirmation range.
+	WithinTarget float64
+
+	// TotalConfirmed is the (decayed) total number of transactions in the
+	// range that were confirmed.
+	TotalConfirmed float64
+
+	// InMemPool is the number of transactions in the range that are still
+	// in the mempool after waiting for the target confirmation range.
+	InMemPool float64
+}
+
+// RawFeeEstimate describes the details of the statistics used to produce a fee
+// estimate.
+type RawFeeEstimate struct {
+	// FeeRate is the estimated fee rate (per KB).  It is zero when no
+	// estimate could be produced, in which case Err is set.
+	FeeRate dcrutil.Amount
+
+	// Decay is the factor used to decay the statistics every new block.
+	Decay float64
+
+	// SuccessPct is the minimum percentage of transactions confirmed within
+	// the target required for a range of buckets to pass.
+	SuccessPct float64
+
+	// Pass is the lowest range of buckets which met the success percentage
+	// and from which the fee rate was derived.  It is nil when no range
+	// passed.
+	Pass *BucketRangeStats
+
+	// Fail is the highest range of buckets which did not meet the success
+	// percentage.  It is nil when no range failed.
+	Fail *BucketRangeStats
+
+	// Err is the reason no fee rate could be estimated, if any.
+	Err error
+}
+
+// bucketRangeStats returns the statistics of the fee rate buckets with indexes
+// in the range [start, end].
+func (stats *Estimator) bucketRangeStats(s *txConfirmStats, start, end int, confirmRangeIdx int32) *BucketRangeStats {
+	res := &BucketRangeStats{EndRange: dcrutil.MaxAmount}
+	if start > 0 {
+		res.StartRange = dcrutil.Amount(stats.bucketFeeBounds[start-1])
+	}
+	if !math.IsInf(float64(stats.bucketFeeBounds[end]), 1) {
+		res.EndRange = dcrutil.Amount(stats.bucketFeeBounds[end])
+	}
+	for b := start; b <= end; b++ {
+		res.WithinTarget += s.buckets[b].confirmed[confirmRangeIdx].txCount
+		res.TotalConfirmed += s.buckets[b].confirmCount
+		res.InMemPool += s.memPool[b].confirmed[confirmRangeIdx].txCount
+	}
+	return res
+}
+
 // estimateMedianFee estimates the median fee rate for the current recorded
 // statistics such that at least successPct transactions have been mined on all
 // tracked fee rate buckets with fee >= to the median.
 // In other words, this is the median fee of the lowest bucket such that it and
 // all higher fee buckets have >= successPct transactions confirmed in at most
-// `targetConfs` confirmations.
+// `targetConfs` confirmations.  Only the passed confirmation statistics are
+// considered.
 // Note that sometimes the requested combination of targetConfs and successPct is
 // not achieveable (hypothetical example: 99% of txs confirmed within 1 block)
 // or there are not enough recorded statistics to derive a successful estimate
 // (eg: confirmation tracking has only started or there was a period of very few
 // transactions). In those situations, the appropriate error is returned.
-func (stats *Estimator) estimateMedianFee(targetConfs int32, successPct float64) (feeRate, error) {
+//
+// When raw is not nil, it is filled with the ranges of buckets which passed and
+// failed the success percentage.
+func (stats *Estimator) estimateMedianFee(s *txConfirmStats, targetConfs int32, successPct float64, raw *RawFeeEstimate) (feeRate, error) {
 	if targetConfs <= 0 {
 		return 0, errors.New("target confirmation range cannot be <= 0")
 	}
@@ -666,25 +951,30 @@ func (stats *Estimator) estimateMedianFee(targetConfs int32, successPct float64)
 			ReqConfirms: targetConfs}
 	}
 
-	startIdx := len(stats.buckets) - 1
+	startIdx := len(s.buckets) - 1
 	confirmRangeIdx := stats.confirmRange(targetConfs)
 
 	var totalTxs, confirmedTxs float64
 	bestBucketsStt := startIdx
 	bestBucketsEnd := startIdx
 	curBucketsEnd := startIdx
+	passed := false
 
 	for b := startIdx; b >= 0; b-- {
-		totalTxs += stats.buckets[b].confirmCount
-		confirmedTxs += stats.buckets[b].confirmed[confirmRangeIdx].txCount
+		totalTxs += s.buckets[b].confirmCount
+		confirmedTxs += s.buckets[b].confirmed[confirmRangeIdx].txCount
 
 		// Add the mempool (unconfirmed) transactions to the total tx count
 		// since a very large mempool for the given bucket might mean that
 		// miners are reluctant to include these in their mined blocks.
-		totalTxs += stats.memPool[b].confirmed[confirmRangeIdx].txCount
+		totalTxs += s.memPool[b].confirmed[confirmRangeIdx].txCount
 
 		if totalTxs > minTxCount {
 			if confirmedTxs/totalTxs < successPct {
+				if raw != nil {
+					raw.Fail = stats.bucketRangeStats(s, b, curBucketsEnd,
+						confirmRangeIdx)
+				}
 				if curBucketsEnd == startIdx {
 					return 0, ErrNoSuccessPctBucketFound
 				}
@@ -696,22 +986,28 @@ func (stats *Estimator) estimateMedianFee(targetConfs int32, successPct float64)
 			curBucketsEnd = b - 1
 			totalTxs = 0
 			confirmedTxs = 0
+			passed = true
 		}
 	}
 
+	if raw != nil && passed {
+		raw.Pass = stats.bucketRangeStats(s, bestBucketsStt, bestBucketsEnd,
+			confirmRangeIdx)
+	}
+
 	txCount := float64(0)
 	for b := bestBucketsStt; b <= bestBucketsEnd; b++ {
-		txCount += stats.buckets[b].confirmCount
+		txCount += s.buckets[b].confirmCount
 	}
 	if txCount <= 0 {
 		return 0, ErrNotEnoughTxsForEstimate
 	}
 	txCount /= 2
 	for b := bestBucketsStt; b <= bestBucketsEnd; b++ {
-		if stats.buckets[b].confirmCount < txCount {
-			txCount -= stats.buckets[b].confirmCount
+		if s.buckets[b].confirmCount < txCount {
+			txCount -= s.buckets[b].confirmCount
 		} else {
-			median := stats.buckets[b].feeSum / stats.buckets[b].confirmCount
+			median := s.buckets[b].feeSum / s.buckets[b].confirmCount
 			return feeRate(median), nil
 		}
 	}
@@ -720,20 +1016,74 @@ func (stats *Estimator) estimateMedianFee(targetConfs int32, successPct float64)
 }
 
 // EstimateFee is the public version of estimateMedianFee. It calculates the
-// suggested fee for a transaction to be confirmed in at most `targetConf`
-// blocks after publishing with a high degree of certainty.
+// suggested fee for a regular transaction to be confirmed in at most
+// `targetConf` blocks after publishing with a high degree of certainty.  It is
+// equivalent to calling EstimateFeeWithMode with EstimateConservative.
 //
 // This function is safe to be called from multiple goroutines but might block
 // until concurrent modifications to the internal database state are complete.
 func (stats *Estimator) EstimateFee(targetConfs int32) (dcrutil.Amount, error) {
+	return stats.estimateFee(false, targetConfs, EstimateConservative)
+}
+
+// EstimateFeeWithMode calculates the suggested fee for a regular transaction
+// to be confirmed in at most `targetConf` blocks after publishing.  The mode
+// defines the decay horizon of the statistics and the degree of certainty used
+// for the estimate.
+//
+// This function is safe to be called from multiple goroutines but might block
+// until concurrent modifications to the internal database state are complete.
+func (stats *Estimator) EstimateFeeWithMode(targetConfs int32, mode EstimateMode) (dcrutil.Amount, error) {
+	return stats.estimateFee(false, targetConfs, mode)
+}
+
+// EstimateTicketFee calculates the suggested fee for a ticket purchase to be
+// confirmed in at most `targetConf` blocks after publishing with a high degree
+// of certainty.  The estimate is based solely on the previously observed
+// ticket purchases.  It is equivalent to calling EstimateTicketFeeWithMode with
+// EstimateConservative.
+//
+// This function is safe to be called from multiple goroutines but might block
+// until concurrent modifications to the internal database state are complete.
+func (stats *Estimator) EstimateTicketFee(targetConfs int32) (dcrutil.Amount, error) {
+	return stats.estimateFee(true, targetConfs, EstimateConservative)
+}
+
+// EstimateTicketFeeWithMode calculates the suggested fee for a ticket purchase
+// to be confirmed in at most `targetConf` blocks after publishing using the
+// given mode.  See EstimateFeeWithMode for more details.
+//
+// This function is safe to be called from multiple goroutines but might block
+// until concurrent modifications to the internal database state are complete.
+func (stats *Estimator) EstimateTicketFeeWithMode(targetConfs int32, mode EstimateMode) (dcrutil.Amount, error) {
+	return stats.estimateFee(true, targetConfs, mode)
+}
+
+// estimateFee calculates the suggested fee for ticket purchases when isTicket
+// is true or regular transactions otherwise.  See EstimateFeeWithMode for more
+// details.
+//
+// This function is safe to be called from multiple goroutines.
+func (stats *Estimator) estimateFee(isTicket bool, targetConfs int32, mode EstimateMode) (dcrutil.Amount, error) {
 	stats.lock.RLock()
-	rate, err := stats.estimateMedianFee(targetConfs, 0.95)
+	s, successPct, err := stats.modeStats(isTicket, mode)
+	if err != nil {
+		stats.lock.RUnlock()
+		return 0, err
+	}
+	rate, err := stats.estimateMedianFee(s, targetConfs, successPct, nil)
 	stats.lock.RUnlock()
 
 	if err != nil {
 		return 0, err
 	}
 
+	return stats.roundFeeRate(rate), nil
+}
+
+// roundFeeRate rounds the passed fee rate to an amount that is never lower than
+// the minimum tracked fee rate.
+func (stats *Estimator) roundFeeRate(rate feeRate) dcrutil.Amount {
 	rate = feeRate(math.Round(float64(rate)))
 	if rate < stats.bucketFeeBounds[0] {
 		// Prevent our public facing api to ever return something lower than the
@@ -741,7 +1091,54 @@ func (stats *Estimator) EstimateFee(targetConfs int32) (dcrutil.Amount, error) {
 		rate = stats.bucketFeeBounds[0]
 	}
 
-	return dcrutil.Amount(rate), nil
+	return dcrutil.Amount(rate)
+}
+
+// EstimateRawFee estimates the fee for a regular transaction to be confirmed
+// in at most `targetConf` blocks after publishing and returns the details of
+// the statistics that produced the estimate.  The mode only selects the decay
+// horizon of the statistics since the minimum success percentage is provided
+// by successPct, which must be in the range (0, 1].
+//
+// Failing to produce an estimate from the tracked statistics is not considered
+// an error and is instead reported by the Err field of the returned details.
+//
+// This function is safe to be called from multiple goroutines but might block
+// until concurrent modifications to the internal database state are complete.
+func (stats *Estimator) EstimateRawFee(targetConfs int32, successPct float64, mode EstimateMode) (*RawFeeEstimate, error) {
+	if successPct <= 0 || successPct > 1 {
+		return nil, fmt.Errorf("success percentage %v is not in the range "+
+			"(0, 1]", successPct)
+	}
+	if targetConfs <= 0 {
+		return nil, errors.New("target confirmation range cannot be <= 0")
+	}
+
+	stats.lock.RLock()
+	defer stats.lock.RUnlock()
+
+	if (targetConfs - 1) >= stats.maxConfirms {
+		return nil, ErrTargetConfTooLarge{MaxConfirms: stats.maxConfirms,
+			ReqConfirms: targetConfs}
+	}
+
+	s, _, err := stats.modeStats(false, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := &RawFeeEstimate{
+		Decay:      s.decay,
+		SuccessPct: successPct,
+	}
+	rate, err := stats.estimateMedianFee(s, targetConfs, successPct, raw)
+	if err != nil {
+		raw.Err = err
+		return raw, nil
+	}
+	raw.FeeRate = stats.roundFeeRate(rate)
+
+	return raw, nil
 }
 
 // Enable establishes the current best height of the blockchain after
@@ -768,6 +1165,10 @@ func (stats *Estimator) IsEnabled() bool {
 // currently recorded best chain hash, using the total fee amount (in atoms) and
 // with the provided size (in bytes).
 //
+// Ticket purchases are tracked separately from regular transactions.  Votes and
+// revocations are not tracked since they don't compete for inclusion in blocks
+// based on their fees.
+//
 // This is safe to be called from multiple goroutines.
 func (stats *Estimator) AddMemPoolTransaction(txHash *chainhash.Hash, fee, size int64, txType stake.TxType) {
 	stats.lock.Lock()
@@ -777,6 +1178,10 @@ func (stats *Estimator) AddMemPoolTransaction(txHash *chainhash.Hash, fee, size
 		return
 	}
 
+	if txType != stake.TxTypeRegular && txType != stake.TxTypeSStx {
+		return
+	}
+
 	if _, exists := stats.memPoolTxs[*txHash]; exists {
 		// we should not double count transactions
 		return
@@ -811,7 +1216,9 @@ func (stats *Estimator) AddMemPoolTransaction(txHash *chainhash.Hash, fee, size
 		isTicket:    txType == stake.TxTypeSStx,
 	}
 	stats.memPoolTxs[*txHash] = tx
-	stats.newMemPoolTx(tx.bucketIndex, rate)
+	for _, s := range stats.txStats(tx.isTicket) {
+		stats.newMemPoolTx(s, tx.bucketIndex, rate)
+	}
 }
 
 // RemoveMemPoolTransaction from statistics tracking.
@@ -828,7 +1235,10 @@ func (stats *Estimator) RemoveMemPoolTransaction(txHash *chainhash.Hash) {
 
 	log.Debugf("Removing tx %s from mempool", txHash)
 
-	stats.removeFromMemPool(int32(stats.bestHeight-desc.addedHeight), desc.fees)
+	for _, s := range stats.txStats(desc.isTicket) {
+		stats.removeFromMemPool(s, int32(stats.bestHeight-desc.addedHeight),
+			desc.fees)
+	}
 	delete(stats.memPoolTxs, *txHash)
 }
 
@@ -850,7 +1260,11 @@ func (stats *Estimator) processMinedTransaction(blockHeight int64, txh *chainhas
 		return
 	}
 
-	stats.removeFromMemPool(int32(blockHeight-desc.addedHeight), desc.fees)
+	txStats := stats.txStats(desc.isTicket)
+	for _, s := range txStats {
+		stats.removeFromMemPool(s, int32(blockHeight-desc.addedHeight),
+			desc.fees)
+	}
 	delete(stats.memPoolTxs, *txh)
 
 	if blockHeight <= desc.addedHeight {
@@ -878,7 +1292,9 @@ func (stats *Estimator) processMinedTransaction(blockHeight int64, txh *chainhas
 
 	log.Debugf("Processing mined tx %s (rate %.8f, delay %d)", txh,
 		desc.fees/1e8, mineDelay)
-	stats.newMinedTx(mineDelay, desc.fees)
+	for _, s := range txStats {
+		stats.newMinedTx(s, mineDelay, desc.fees)
+	}
 }
 
 // ProcessBlock processes all mined transactions in the provided block.
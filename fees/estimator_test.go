@@ -0,0 +1,623 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package fees
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/btcsuite/goleveldb/leveldb"
+	ldbutil "github.com/btcsuite/goleveldb/leveldb/util"
+	"github.com/decred/dcrd/blockchain/stake"
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/wire"
+)
+
+const (
+	// testMinBucketFee is the fee rate of the lowest bucket tracked by the
+	// estimators created by the tests.
+	testMinBucketFee = 10000
+
+	// testTxSize is the size of the transactions added to the estimators
+	// created by the tests.  Since the estimator calculates the fee rate as
+	// fee / size * 1000, using a size of 1000 bytes makes the fee rate equal
+	// to the fee.
+	testTxSize = 1000
+)
+
+// newTestEstimator returns a new estimator using the default configuration
+// which is backed by the passed database file when it is not empty.
+func newTestEstimator(t *testing.T, dbFile string) *Estimator {
+	t.Helper()
+
+	est, err := NewEstimator(&EstimatorConfig{
+		MaxConfirms:  DefaultMaxConfirmations,
+		MinBucketFee: testMinBucketFee,
+		MaxBucketFee: dcrutil.Amount(DefaultMaxBucketFeeMultiplier) *
+			testMinBucketFee,
+		FeeRateStep:  DefaultFeeRateStep,
+		DatabaseFile: dbFile,
+		ChainParams:  &chaincfg.MainNetParams,
+	})
+	if err != nil {
+		t.Fatalf("unable to create estimator: %v", err)
+	}
+	return est
+}
+
+// testTxGen generates unique transactions to feed to an estimator and keeps
+// track of the ones that are in its mempool.
+type testTxGen struct {
+	t         *testing.T
+	est       *Estimator
+	nextNonce uint32
+	regular   []*wire.MsgTx
+	tickets   []*wire.MsgTx
+}
+
+// addMemPoolTxns adds the passed number of new transactions of the passed
+// type paying the passed fee rate to the mempool of the estimator.
+func (g *testTxGen) addMemPoolTxns(count int, rate int64, txType stake.TxType) {
+	for i := 0; i < count; i++ {
+		tx := wire.NewMsgTx()
+		tx.LockTime = g.nextNonce
+		g.nextNonce++
+		txHash := tx.TxHash()
+		g.est.AddMemPoolTransaction(&txHash, rate*testTxSize/1000,
+			testTxSize, txType)
+		if txType == stake.TxTypeSStx {
+			g.tickets = append(g.tickets, tx)
+		} else {
+			g.regular = append(g.regular, tx)
+		}
+	}
+}
+
+// mineBlock processes a block at the passed height which contains all of the
+// transactions currently in the mempool of the estimator.
+func (g *testTxGen) mineBlock(height int64) {
+	g.t.Helper()
+
+	g.mineTxns(height, g.regular, g.tickets)
+	g.regular, g.tickets = nil, nil
+}
+
+// mineEmptyBlock processes a block at the passed height which does not contain
+// any of the transactions in the mempool of the estimator.
+func (g *testTxGen) mineEmptyBlock(height int64) {
+	g.t.Helper()
+
+	g.mineTxns(height, nil, nil)
+}
+
+// mineTxns processes a block at the passed height which contains the passed
+// regular transactions and ticket purchases.  Callers are responsible for
+// removing the transactions from the ones tracked as in the mempool.
+func (g *testTxGen) mineTxns(height int64, regular, tickets []*wire.MsgTx) {
+	g.t.Helper()
+
+	block := &wire.MsgBlock{Header: wire.BlockHeader{Height: uint32(height)}}
+	block.Transactions = regular
+	block.STransactions = tickets
+	if err := g.est.ProcessBlock(dcrutil.NewBlock(block)); err != nil {
+		g.t.Fatalf("unable to process block at height %d: %v", height, err)
+	}
+}
+
+// TestTicketTracking ensures ticket purchases are tracked separately from
+// regular transactions, take the split transaction into account when they are
+// mined, and are estimated from their own statistics.
+func TestTicketTracking(t *testing.T) {
+	est := newTestEstimator(t, "")
+	defer est.Close()
+	est.Enable(100)
+	g := &testTxGen{t: t, est: est}
+
+	// Ensure ticket purchases are only added to the ticket mempool
+	// statistics while regular transactions are only added to the regular
+	// ones.  Votes are not tracked at all.
+	const numTxns = 10
+	const regularRate, ticketRate = 20000, 50000
+	g.addMemPoolTxns(numTxns, regularRate, stake.TxTypeRegular)
+	g.addMemPoolTxns(numTxns, ticketRate, stake.TxTypeSStx)
+	var voteHash chainhash.Hash
+	est.AddMemPoolTransaction(&voteHash, ticketRate, testTxSize,
+		stake.TxTypeSSGen)
+	if _, ok := est.memPoolTxs[voteHash]; ok {
+		t.Fatal("vote was added to the estimator mempool")
+	}
+	regularIdx := est.lowerBucket(regularRate)
+	ticketIdx := est.lowerBucket(ticketRate)
+	tests := []struct {
+		name  string
+		stats *txConfirmStats
+		idx   int32
+		want  float64
+	}{
+		{"regular in regular", &est.regular, regularIdx, numTxns},
+		{"regular in short regular", &est.shortRegular, regularIdx, numTxns},
+		{"ticket in tickets", &est.tickets, ticketIdx, numTxns},
+		{"ticket in short tickets", &est.shortTickets, ticketIdx, numTxns},
+		{"ticket in regular", &est.regular, ticketIdx, 0},
+		{"regular in tickets", &est.tickets, regularIdx, 0},
+	}
+	for _, test := range tests {
+		got := test.stats.memPool[test.idx].confirmed[0].txCount
+		if got != test.want {
+			t.Fatalf("%s: unexpected mempool tx count -- got %v, want %v",
+				test.name, got, test.want)
+		}
+	}
+
+	// Mine all of the transactions two blocks after they entered the
+	// mempool.  Ensure the regular transactions are recorded as confirmed
+	// within two blocks while the tickets are recorded as confirmed within a
+	// single block since they have to wait for their split transaction.
+	g.mineEmptyBlock(101)
+	g.mineBlock(102)
+	if n := est.regular.buckets[regularIdx].confirmed[0].txCount; n != 0 {
+		t.Fatalf("unexpected regular txns confirmed within 1 block: %v", n)
+	}
+	if n := est.regular.buckets[regularIdx].confirmed[1].txCount; n != numTxns {
+		t.Fatalf("unexpected regular txns confirmed within 2 blocks: %v", n)
+	}
+	if n := est.tickets.buckets[ticketIdx].confirmed[0].txCount; n != numTxns {
+		t.Fatalf("unexpected tickets confirmed within 1 block: %v", n)
+	}
+	if n := est.tickets.buckets[regularIdx].confirmCount; n != 0 {
+		t.Fatalf("unexpected confirmed tickets in regular bucket: %v", n)
+	}
+	for _, s := range []*txConfirmStats{&est.regular, &est.tickets} {
+		for i := range s.memPool {
+			for c := range s.memPool[i].confirmed {
+				if n := s.memPool[i].confirmed[c].txCount; n != 0 {
+					t.Fatalf("unexpected mempool tx count %v in bucket "+
+						"%d range %d after mining", n, i, c)
+				}
+			}
+		}
+	}
+
+	// Ensure the ticket fee estimate is only based on the tickets and the
+	// regular fee estimate is only based on the regular transactions.
+	fee, err := est.EstimateTicketFee(1)
+	if err != nil {
+		t.Fatalf("EstimateTicketFee: unexpected error: %v", err)
+	}
+	if fee != ticketRate {
+		t.Fatalf("EstimateTicketFee: unexpected fee -- got %v, want %v",
+			fee, dcrutil.Amount(ticketRate))
+	}
+	if _, err := est.EstimateFee(1); err != ErrNoSuccessPctBucketFound {
+		t.Fatalf("EstimateFee: unexpected error -- got %v, want %v", err,
+			ErrNoSuccessPctBucketFound)
+	}
+	fee, err = est.EstimateFee(2)
+	if err != nil {
+		t.Fatalf("EstimateFee: unexpected error: %v", err)
+	}
+	if fee != regularRate {
+		t.Fatalf("EstimateFee: unexpected fee -- got %v, want %v", fee,
+			dcrutil.Amount(regularRate))
+	}
+}
+
+// TestEstimateModeThresholds ensures the conservative and economical
+// estimation modes require their respective minimum success percentages.
+func TestEstimateModeThresholds(t *testing.T) {
+	est := newTestEstimator(t, "")
+	defer est.Close()
+	est.Enable(100)
+	g := &testTxGen{t: t, est: est}
+
+	// Mine 90% of the transactions in the block after they entered the
+	// mempool and the rest two blocks later.
+	const rate = 20000
+	g.addMemPoolTxns(10, rate, stake.TxTypeRegular)
+	g.mineTxns(101, g.regular[:9], nil)
+	g.regular = g.regular[9:]
+	g.mineEmptyBlock(102)
+	g.mineBlock(103)
+
+	tests := []struct {
+		name    string
+		confs   int32
+		mode    EstimateMode
+		want    dcrutil.Amount
+		wantErr error
+	}{{
+		name:    "conservative below threshold",
+		confs:   1,
+		mode:    EstimateConservative,
+		wantErr: ErrNoSuccessPctBucketFound,
+	}, {
+		name:  "economical above threshold",
+		confs: 1,
+		mode:  EstimateEconomical,
+		want:  rate,
+	}, {
+		name:  "conservative all confirmed",
+		confs: 3,
+		mode:  EstimateConservative,
+		want:  rate,
+	}, {
+		name:  "economical all confirmed",
+		confs: 3,
+		mode:  EstimateEconomical,
+		want:  rate,
+	}}
+	for _, test := range tests {
+		fee, err := est.EstimateFeeWithMode(test.confs, test.mode)
+		if err != test.wantErr {
+			t.Errorf("%s: unexpected error -- got %v, want %v", test.name,
+				err, test.wantErr)
+			continue
+		}
+		if fee != test.want {
+			t.Errorf("%s: unexpected fee -- got %v, want %v", test.name,
+				fee, test.want)
+		}
+	}
+
+	// Ensure the success percentage passed to the raw estimate overrides the
+	// one of the mode.
+	raw, err := est.EstimateRawFee(1, economicalSuccessPct,
+		EstimateConservative)
+	if err != nil {
+		t.Fatalf("EstimateRawFee: unexpected error: %v", err)
+	}
+	if raw.Err != nil || raw.FeeRate != rate {
+		t.Fatalf("EstimateRawFee: unexpected estimate -- got %v (err %v), "+
+			"want %v", raw.FeeRate, raw.Err, dcrutil.Amount(rate))
+	}
+}
+
+// TestDecayHorizons ensures the statistics used by the conservative and
+// economical estimation modes decay at their respective rates on every block.
+func TestDecayHorizons(t *testing.T) {
+	est := newTestEstimator(t, "")
+	defer est.Close()
+	est.Enable(100)
+	g := &testTxGen{t: t, est: est}
+
+	const numTxns, rate = 10, 20000
+	g.addMemPoolTxns(numTxns, rate, stake.TxTypeRegular)
+	g.addMemPoolTxns(numTxns, rate, stake.TxTypeSStx)
+	g.mineBlock(101)
+
+	// Mine enough empty blocks for the statistics of the short horizon to
+	// decay below a single transaction while the ones of the long horizon
+	// still retain most of the transactions.
+	const numBlocks = 60
+	for i := int64(1); i <= numBlocks; i++ {
+		g.mineEmptyBlock(101 + i)
+	}
+	idx := est.lowerBucket(rate)
+	tests := []struct {
+		name  string
+		stats *txConfirmStats
+		decay float64
+	}{
+		{"regular", &est.regular, defaultDecay},
+		{"tickets", &est.tickets, defaultDecay},
+		{"short regular", &est.shortRegular, shortDecay},
+		{"short tickets", &est.shortTickets, shortDecay},
+	}
+	for _, test := range tests {
+		want := numTxns * math.Pow(test.decay, numBlocks)
+		bucket := &test.stats.buckets[idx]
+		if math.Abs(bucket.confirmCount-want) > 1e-9 {
+			t.Errorf("%s: unexpected confirm count -- got %v, want %v",
+				test.name, bucket.confirmCount, want)
+		}
+		if got := bucket.confirmed[0].txCount; math.Abs(got-want) > 1e-9 {
+			t.Errorf("%s: unexpected confirmed tx count -- got %v, want %v",
+				test.name, got, want)
+		}
+		if got, want := bucket.feeSum, want*rate; math.Abs(got-want) > 1e-6 {
+			t.Errorf("%s: unexpected fee sum -- got %v, want %v",
+				test.name, got, want)
+		}
+	}
+
+	// Ensure the conservative mode still produces an estimate while the
+	// economical mode no longer has enough transactions for one.
+	fee, err := est.EstimateFee(1)
+	if err != nil {
+		t.Fatalf("conservative: unexpected error: %v", err)
+	}
+	if fee != rate {
+		t.Fatalf("conservative: unexpected fee -- got %v, want %v", fee,
+			dcrutil.Amount(rate))
+	}
+	if _, err := est.EstimateFeeWithMode(1, EstimateEconomical); err !=
+		ErrNotEnoughTxsForEstimate {
+
+		t.Fatalf("economical: unexpected error -- got %v, want %v", err,
+			ErrNotEnoughTxsForEstimate)
+	}
+}
+
+// TestEstimateRawFee ensures the details of raw fee estimates describe the
+// statistics used to produce them.
+func TestEstimateRawFee(t *testing.T) {
+	est := newTestEstimator(t, "")
+	defer est.Close()
+
+	// Ensure invalid success percentages are rejected.
+	for _, successPct := range []float64{0, -0.5, 1.01} {
+		_, err := est.EstimateRawFee(1, successPct, EstimateConservative)
+		if err == nil {
+			t.Fatalf("EstimateRawFee: did not receive expected error for "+
+				"success percentage %v", successPct)
+		}
+	}
+
+	// Ensure a failed estimate is reported in the details instead of as an
+	// error.
+	raw, err := est.EstimateRawFee(1, 0.95, EstimateConservative)
+	if err != nil {
+		t.Fatalf("EstimateRawFee: unexpected error: %v", err)
+	}
+	want := &RawFeeEstimate{
+		Decay:      defaultDecay,
+		SuccessPct: 0.95,
+		Err:        ErrNotEnoughTxsForEstimate,
+	}
+	if !reflect.DeepEqual(raw, want) {
+		t.Fatalf("EstimateRawFee: unexpected estimate -- got %+v, want %+v",
+			raw, want)
+	}
+
+	// Mine transactions paying a high fee rate in the block after they
+	// entered the mempool and transactions paying a low fee rate two blocks
+	// later.  Leave a few more transactions paying the low fee rate in the
+	// mempool.
+	est.Enable(100)
+	g := &testTxGen{t: t, est: est}
+	const highRate, lowRate = 20000, 12000
+	g.addMemPoolTxns(10, highRate, stake.TxTypeRegular)
+	g.mineBlock(101)
+	g.addMemPoolTxns(10, lowRate, stake.TxTypeRegular)
+	g.mineEmptyBlock(102)
+	g.mineEmptyBlock(103)
+	g.mineBlock(104)
+	g.addMemPoolTxns(5, lowRate, stake.TxTypeRegular)
+
+	highIdx := est.lowerBucket(highRate)
+	lowIdx := est.lowerBucket(lowRate)
+	bound := func(idx int32) dcrutil.Amount {
+		return dcrutil.Amount(est.bucketFeeBounds[idx])
+	}
+	tests := []struct {
+		name  string
+		mode  EstimateMode
+		decay float64
+	}{
+		{"conservative", EstimateConservative, defaultDecay},
+		{"economical", EstimateEconomical, shortDecay},
+	}
+	for _, test := range tests {
+		raw, err := est.EstimateRawFee(1, 0.95, test.mode)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+		highTxns := 10 * math.Pow(test.decay, 3)
+		lowTxns := 10.0
+		want := &RawFeeEstimate{
+			FeeRate:    highRate,
+			Decay:      test.decay,
+			SuccessPct: 0.95,
+			Pass: &BucketRangeStats{
+				StartRange:     bound(highIdx - 1),
+				EndRange:       dcrutil.MaxAmount,
+				WithinTarget:   highTxns,
+				TotalConfirmed: highTxns,
+			},
+			Fail: &BucketRangeStats{
+				StartRange:     bound(lowIdx - 1),
+				EndRange:       bound(highIdx - 1),
+				TotalConfirmed: lowTxns,
+				InMemPool:      5,
+			},
+		}
+		if !rawFeeEstimatesEqual(raw, want) {
+			t.Fatalf("%s: unexpected estimate -- got %+v (pass %+v, fail "+
+				"%+v), want %+v (pass %+v, fail %+v)", test.name, raw,
+				raw.Pass, raw.Fail, want, want.Pass, want.Fail)
+		}
+	}
+}
+
+// rawFeeEstimatesEqual returns whether or not the passed raw fee estimates are
+// equal, allowing for small differences in the decayed transaction counts.
+func rawFeeEstimatesEqual(a, b *RawFeeEstimate) bool {
+	rangesEqual := func(a, b *BucketRangeStats) bool {
+		if a == nil || b == nil {
+			return a == b
+		}
+		const epsilon = 1e-9
+		return a.StartRange == b.StartRange && a.EndRange == b.EndRange &&
+			math.Abs(a.WithinTarget-b.WithinTarget) < epsilon &&
+			math.Abs(a.TotalConfirmed-b.TotalConfirmed) < epsilon &&
+			math.Abs(a.InMemPool-b.InMemPool) < epsilon
+	}
+	return a.FeeRate == b.FeeRate && a.Decay == b.Decay &&
+		a.SuccessPct == b.SuccessPct && a.Err == b.Err &&
+		rangesEqual(a.Pass, b.Pass) && rangesEqual(a.Fail, b.Fail)
+}
+
+// TestDatabaseUpgradeV1 ensures a version 1 database, which does not track
+// ticket purchases separately, is upgraded to the current version such that
+// the statistics of regular transactions are retained, the ticket statistics
+// start out empty, and the upgraded database loads again.
+func TestDatabaseUpgradeV1(t *testing.T) {
+	dir, err := ioutil.TempDir("", "feesdbupgradev1")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	dbFile := filepath.Join(dir, "feesdb")
+
+	// Populate a database with regular transactions and ticket purchases.
+	est := newTestEstimator(t, dbFile)
+	est.Enable(100)
+	g := &testTxGen{t: t, est: est}
+	g.addMemPoolTxns(10, 20000, stake.TxTypeRegular)
+	g.addMemPoolTxns(10, 50000, stake.TxTypeSStx)
+	g.mineBlock(101)
+	wantRegular := est.regular.buckets
+	est.Close()
+
+	// Convert the database to version 1 by removing all statistics other
+	// than those of regular transactions and resetting the version.
+	convertDatabase(t, dbFile, 1, dbKeyTicketBucketPrefix,
+		dbKeyShortBucketPrefix, dbKeyShortTicketBucketPrefix)
+
+	// Ensure the database is upgraded and the upgraded database loads again
+	// with the same statistics.
+	for i := 0; i < 2; i++ {
+		est = newTestEstimator(t, dbFile)
+		if !reflect.DeepEqual(est.regular.buckets, wantRegular) {
+			est.Close()
+			t.Fatalf("load %d: regular statistics were not retained", i)
+		}
+		for _, s := range []*txConfirmStats{&est.tickets, &est.shortRegular,
+			&est.shortTickets} {
+
+			if !statsEmpty(s) {
+				est.Close()
+				t.Fatalf("load %d: statistics missing from version 1 are "+
+					"not empty", i)
+			}
+		}
+		est.Close()
+		checkDatabaseVersion(t, dbFile, 3)
+	}
+}
+
+// TestDatabaseUpgradeV2 ensures a version 2 database, which does not track
+// statistics with a short decay horizon, is upgraded to the current version
+// such that the statistics of regular transactions and ticket purchases are
+// retained, the short horizon statistics start out empty, and the upgraded
+// database loads again.
+func TestDatabaseUpgradeV2(t *testing.T) {
+	dir, err := ioutil.TempDir("", "feesdbupgradev2")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	dbFile := filepath.Join(dir, "feesdb")
+
+	// Populate a database with regular transactions and ticket purchases.
+	est := newTestEstimator(t, dbFile)
+	est.Enable(100)
+	g := &testTxGen{t: t, est: est}
+	g.addMemPoolTxns(10, 20000, stake.TxTypeRegular)
+	g.addMemPoolTxns(10, 50000, stake.TxTypeSStx)
+	g.mineBlock(101)
+	wantRegular := est.regular.buckets
+	wantTickets := est.tickets.buckets
+	est.Close()
+
+	// Convert the database to version 2 by removing the short horizon
+	// statistics and resetting the version.
+	convertDatabase(t, dbFile, 2, dbKeyShortBucketPrefix,
+		dbKeyShortTicketBucketPrefix)
+
+	// Ensure the database is upgraded and the upgraded database loads again
+	// with the same statistics.
+	for i := 0; i < 2; i++ {
+		est = newTestEstimator(t, dbFile)
+		if !reflect.DeepEqual(est.regular.buckets, wantRegular) {
+			est.Close()
+			t.Fatalf("load %d: regular statistics were not retained", i)
+		}
+		if !reflect.DeepEqual(est.tickets.buckets, wantTickets) {
+			est.Close()
+			t.Fatalf("load %d: ticket statistics were not retained", i)
+		}
+		if !statsEmpty(&est.shortRegular) || !statsEmpty(&est.shortTickets) {
+			est.Close()
+			t.Fatalf("load %d: short horizon statistics are not empty", i)
+		}
+		if est.shortRegular.decay != shortDecay ||
+			est.shortTickets.decay != shortDecay {
+
+			est.Close()
+			t.Fatalf("load %d: unexpected short horizon decay", i)
+		}
+		est.Close()
+		checkDatabaseVersion(t, dbFile, 3)
+	}
+}
+
+// convertDatabase converts the estimator database at the passed path to the
+// passed version by removing all keys with the passed prefixes.
+func convertDatabase(t *testing.T, dbFile string, version byte, prefixes ...[]byte) {
+	t.Helper()
+
+	db, err := leveldb.OpenFile(dbFile, nil)
+	if err != nil {
+		t.Fatalf("unable to open estimator db: %v", err)
+	}
+	defer db.Close()
+
+	batch := new(leveldb.Batch)
+	for _, prefix := range prefixes {
+		iter := db.NewIterator(ldbutil.BytesPrefix(prefix), nil)
+		for iter.Next() {
+			batch.Delete(append([]byte(nil), iter.Key()...))
+		}
+		iter.Release()
+	}
+	batch.Put(dbKeyVersion, []byte{version})
+	if err := db.Write(batch, nil); err != nil {
+		t.Fatalf("unable to convert estimator db: %v", err)
+	}
+}
+
+// checkDatabaseVersion ensures the estimator database at the passed path has
+// the passed version.
+func checkDatabaseVersion(t *testing.T, dbFile string, want byte) {
+	t.Helper()
+
+	db, err := leveldb.OpenFile(dbFile, nil)
+	if err != nil {
+		t.Fatalf("unable to open estimator db: %v", err)
+	}
+	defer db.Close()
+
+	version, err := db.Get(dbKeyVersion, nil)
+	if err != nil {
+		t.Fatalf("unable to read estimator db version: %v", err)
+	}
+	if len(version) != 1 || version[0] != want {
+		t.Fatalf("unexpected estimator db version -- got %v, want %d",
+			version, want)
+	}
+}
+
+// statsEmpty returns whether or not the passed statistics do not contain any
+// confirmed transactions.
+func statsEmpty(s *txConfirmStats) bool {
+	for _, bucket := range s.buckets {
+		if bucket.confirmCount != 0 || bucket.feeSum != 0 {
+			return false
+		}
+		for _, conf := range bucket.confirmed {
+			if conf.txCount != 0 || conf.feeSum != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
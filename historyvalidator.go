@@ -0,0 +1,364 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/decred/dcrd/blockchain"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/database"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/wire"
+)
+
+// historyDbSuffix is the suffix appended to the path of the block database to
+// form the path of the database used to validate the history leading up to a
+// chain state snapshot.
+const historyDbSuffix = "_history"
+
+// historyProcessedMsg is sent by the history validator to the block handler
+// once it has processed the block at the given height.
+type historyProcessedMsg struct {
+	height int64
+}
+
+// historyDoneMsg is sent by the history validator to the block handler once
+// the history leading up to the chain state snapshot has been validated.
+type historyDoneMsg struct{}
+
+// historyRequest describes a block requested by the history validator.
+type historyRequest struct {
+	hash chainhash.Hash
+	peer *serverPeer
+	time time.Time
+}
+
+// historyValidator validates the history leading up to the chain state
+// snapshot the chain was loaded from in the background.  It downloads the
+// blocks up to the snapshot from peers that serve the full history, connects
+// them to a separate chain instance that is backed by its own database, and
+// ensures the resulting chain state matches the snapshot.
+//
+// The blocks are requested and received by the block handler while they are
+// processed by a separate goroutine so the main chain keeps syncing.  The
+// history database is removed once the snapshot is validated.  A mismatch
+// means the snapshot is not valid, so the node is shut down.
+type historyValidator struct {
+	bm       *blockManager
+	db       database.DB
+	dbPath   string
+	chain    *blockchain.BlockChain
+	snapshot *blockchain.SnapshotInfo
+	blocks   chan *dcrutil.Block
+
+	// The following fields are only accessed by the block handler.
+	//
+	// requests houses the blocks in flight by height and received houses the
+	// blocks that were received out of order.  nextQueueHeight is the height
+	// of the next block to queue for processing and processedHeight is the
+	// height of the most recently processed block.
+	requests        map[int64]*historyRequest
+	received        map[int64]*dcrutil.Block
+	nextQueueHeight int64
+	processedHeight int64
+}
+
+// openHistoryDB opens the database used to validate the history leading up to
+// a chain state snapshot, creating it when needed.
+func openHistoryDB(dbPath string) (database.DB, error) {
+	if cfg.DbType == "memdb" {
+		return database.Create(cfg.DbType)
+	}
+
+	db, err := database.Open(cfg.DbType, dbPath, activeNetParams.Net)
+	if err != nil {
+		// Return the error if it's not because the database doesn't
+		// exist.
+		if dbErr, ok := err.(database.Error); !ok || dbErr.ErrorCode !=
+			database.ErrDbDoesNotExist {
+
+			return nil, err
+		}
+		return database.Create(cfg.DbType, dbPath, activeNetParams.Net)
+	}
+	return db, nil
+}
+
+// newHistoryValidator returns a new history validator for the passed chain
+// state snapshot the chain of the block manager was loaded from.  It resumes
+// from the state of a previous run when the history database already exists.
+func newHistoryValidator(bm *blockManager, snapshot *blockchain.SnapshotInfo, interrupt <-chan struct{}) (*historyValidator, error) {
+	dbPath := blockDbPath(cfg.DbType) + historyDbSuffix
+	db, err := openHistoryDB(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	s := bm.server
+	chain, err := blockchain.New(&blockchain.Config{
+		DB:               db,
+		Interrupt:        interrupt,
+		ChainParams:      s.chainParams,
+		TimeSource:       s.timeSource,
+		SigCache:         s.sigCache,
+		UtxoCacheMaxSize: cfg.UtxoCacheMaxSize * 1024 * 1024,
+		AssumeValid:      cfg.assumeValid,
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	height := chain.BestSnapshot().Height
+	if height > snapshot.Height {
+		db.Close()
+		return nil, fmt.Errorf("the history database at %s is beyond the "+
+			"chain state snapshot height %d", dbPath, snapshot.Height)
+	}
+
+	return &historyValidator{
+		bm:              bm,
+		db:              db,
+		dbPath:          dbPath,
+		chain:           chain,
+		snapshot:        snapshot,
+		blocks:          make(chan *dcrutil.Block, blockDownloadWindow),
+		requests:        make(map[int64]*historyRequest),
+		received:        make(map[int64]*dcrutil.Block),
+		nextQueueHeight: height + 1,
+		processedHeight: height,
+	}, nil
+}
+
+// Start begins processing the downloaded blocks.
+func (v *historyValidator) Start() {
+	bmgrLog.Infof("Validating the history leading up to chain state "+
+		"snapshot %v at height %d in the background (current height %d)",
+		v.snapshot.Commitment, v.snapshot.Height, v.processedHeight)
+
+	v.bm.wg.Add(1)
+	go v.processHandler()
+}
+
+// processHandler connects the downloaded blocks to the history chain in order
+// until it reaches the snapshot height and then ensures its chain state matches
+// the snapshot.  It must be run as a goroutine.
+func (v *historyValidator) processHandler() {
+	defer v.bm.wg.Done()
+
+	for v.chain.BestSnapshot().Height < v.snapshot.Height {
+		select {
+		case block := <-v.blocks:
+			_, isOrphan, err := v.chain.ProcessBlock(block, blockchain.BFNone)
+			if err == nil && isOrphan {
+				err = fmt.Errorf("block %v is an orphan", block.Hash())
+			}
+			if err != nil {
+				v.db.Close()
+				v.fail(err)
+				return
+			}
+
+			select {
+			case v.bm.msgChan <- &historyProcessedMsg{height: block.Height()}:
+			case <-v.bm.quit:
+				v.db.Close()
+				return
+			}
+
+		case <-v.bm.quit:
+			v.db.Close()
+			return
+		}
+	}
+
+	// Ensure the validated chain state matches the snapshot.
+	info, err := v.chain.DumpSnapshot(ioutil.Discard)
+	v.db.Close()
+	if err != nil {
+		v.fail(err)
+		return
+	}
+	if info.Hash != v.snapshot.Hash || info.Commitment != v.snapshot.Commitment {
+		v.fail(fmt.Errorf("the validated chain state %v (commitment %v) "+
+			"does not match the snapshot chain state %v", info.Hash,
+			info.Commitment, v.snapshot.Hash))
+		return
+	}
+	if err := v.bm.chain.MarkSnapshotValidated(); err != nil {
+		bmgrLog.Errorf("Unable to mark chain state snapshot as validated: %v",
+			err)
+		return
+	}
+	if err := os.RemoveAll(v.dbPath); err != nil {
+		bmgrLog.Warnf("Unable to remove the history database: %v", err)
+	}
+	bmgrLog.Infof("Validated the history leading up to chain state "+
+		"snapshot %v at height %d", v.snapshot.Commitment, v.snapshot.Height)
+
+	select {
+	case v.bm.msgChan <- &historyDoneMsg{}:
+	case <-v.bm.quit:
+	}
+}
+
+// fail logs the passed history validation error and requests a shutdown since
+// the chain state the node is running with can't be trusted.
+func (v *historyValidator) fail(err error) {
+	bmgrLog.Criticalf("Unable to validate the history leading up to chain "+
+		"state snapshot %v: %v -- shutting down", v.snapshot.Commitment, err)
+	select {
+	case shutdownRequestChannel <- struct{}{}:
+	default:
+	}
+}
+
+// nextDownloadPeer returns the sync candidate peer that serves the full history
+// and has the fewest history blocks in flight, or nil when none of them are
+// able to accept more requests for the block at the passed height.
+//
+// This function MUST be called from the block handler goroutine.
+func (v *historyValidator) nextDownloadPeer(height int64, inFlight map[*serverPeer]int) *serverPeer {
+	var bestPeer *serverPeer
+	for e := v.bm.candidatePeers.Front(); e != nil; e = e.Next() {
+		sp := e.Value.(*serverPeer)
+		if sp.Services()&wire.SFNodeNetwork == 0 ||
+			sp.LastBlock() < height ||
+			inFlight[sp] >= maxInFlightBlocksPerPeer {
+
+			continue
+		}
+		if bestPeer == nil || inFlight[sp] < inFlight[bestPeer] {
+			bestPeer = sp
+		}
+	}
+	return bestPeer
+}
+
+// fetchBlocks creates and sends requests for the blocks within the download
+// window that are not already in flight or received.  The requests are spread
+// among the sync candidate peers.
+//
+// This function MUST be called from the block handler goroutine.
+func (v *historyValidator) fetchBlocks() {
+	inFlight := make(map[*serverPeer]int)
+	for _, req := range v.requests {
+		inFlight[req.peer]++
+	}
+
+	now := time.Now()
+	windowEnd := v.processedHeight + blockDownloadWindow
+	if windowEnd > v.snapshot.Height {
+		windowEnd = v.snapshot.Height
+	}
+	gdmsgs := make(map[*serverPeer]*wire.MsgGetData)
+	for height := v.nextQueueHeight; height <= windowEnd; height++ {
+		if _, ok := v.received[height]; ok {
+			continue
+		}
+		if _, ok := v.requests[height]; ok {
+			continue
+		}
+
+		// Stop when none of the peers are able to accept more requests.
+		sp := v.nextDownloadPeer(height, inFlight)
+		if sp == nil {
+			break
+		}
+		hash, err := v.bm.chain.BlockHashByHeight(height)
+		if err != nil {
+			bmgrLog.Warnf("Failed to look up history block at height "+
+				"%d: %v", height, err)
+			break
+		}
+
+		gdmsg, ok := gdmsgs[sp]
+		if !ok {
+			gdmsg = wire.NewMsgGetDataSizeHint(maxInFlightBlocksPerPeer)
+			gdmsgs[sp] = gdmsg
+		}
+		iv := wire.NewInvVect(wire.InvTypeBlock, hash)
+		if err := gdmsg.AddInvVect(iv); err != nil {
+			bmgrLog.Warnf("Failed to add invvect while fetching history "+
+				"blocks: %v", err)
+			break
+		}
+		v.requests[height] = &historyRequest{hash: *hash, peer: sp, time: now}
+		inFlight[sp]++
+	}
+	for sp, gdmsg := range gdmsgs {
+		sp.QueueMessage(gdmsg, nil)
+	}
+}
+
+// handleBlockMsg queues the block in the passed message for processing when it
+// was requested by the history validator along with any blocks after it that
+// were received out of order.  It returns whether or not the block was
+// requested by the history validator.
+//
+// This function MUST be called from the block handler goroutine.
+func (v *historyValidator) handleBlockMsg(bmsg *blockMsg) bool {
+	height := bmsg.block.Height()
+	req, ok := v.requests[height]
+	if !ok || req.hash != *bmsg.block.Hash() {
+		return false
+	}
+	delete(v.requests, height)
+	v.received[height] = bmsg.block
+
+	// Queue the blocks that are now in order for processing.  This never
+	// blocks since the channel has room for the entire download window.
+	for {
+		block, ok := v.received[v.nextQueueHeight]
+		if !ok {
+			break
+		}
+		delete(v.received, v.nextQueueHeight)
+		v.blocks <- block
+		v.nextQueueHeight++
+	}
+	return true
+}
+
+// handleProcessed updates the download window after the block at the passed
+// height has been processed and requests more blocks accordingly.
+//
+// This function MUST be called from the block handler goroutine.
+func (v *historyValidator) handleProcessed(height int64) {
+	v.processedHeight = height
+	v.fetchBlocks()
+}
+
+// handleStalls releases the blocks that have not been delivered within the
+// stall timeout so they are requested again, possibly from other peers.
+//
+// This function MUST be called from the block handler goroutine.
+func (v *historyValidator) handleStalls() {
+	now := time.Now()
+	for height, req := range v.requests {
+		if now.Sub(req.time) >= blockStallTimeout {
+			bmgrLog.Debugf("Peer %s stalled while downloading history "+
+				"block %v", req.peer, req.hash)
+			delete(v.requests, height)
+		}
+	}
+	v.fetchBlocks()
+}
+
+// handleDonePeer releases the blocks in flight from the passed peer that is no
+// longer connected so they are requested from other peers.
+//
+// This function MUST be called from the block handler goroutine.
+func (v *historyValidator) handleDonePeer(sp *serverPeer) {
+	for height, req := range v.requests {
+		if req.peer == sp {
+			delete(v.requests, height)
+		}
+	}
+	v.fetchBlocks()
+}
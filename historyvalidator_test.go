@@ -0,0 +1,289 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/blockchain"
+	"github.com/decred/dcrd/blockchain/chaingen"
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/database"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/txscript"
+)
+
+// historyTestHarness houses a chain that was loaded from a chain state snapshot
+// along with the blocks leading up to the snapshot.
+type historyTestHarness struct {
+	t        *testing.T
+	params   *chaincfg.Params
+	chain    *blockchain.BlockChain
+	snapshot *blockchain.SnapshotInfo
+	blocks   []*dcrutil.Block
+}
+
+// newHistoryTestHarness creates a chain with the passed number of blocks after
+// the genesis block, dumps a snapshot of its chain state, and loads it into a
+// new chain.  The global configuration is pointed to a temporary data directory
+// so the history database is created in it.  The returned function restores
+// the global configuration and removes all of the databases.
+func newHistoryTestHarness(t *testing.T, numBlocks int) (*historyTestHarness, func()) {
+	t.Helper()
+
+	dataDir, err := ioutil.TempDir("", "historyvalidatortest")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	oldCfg, oldParams := cfg, activeNetParams
+	cfg = &config{DataDir: dataDir, DbType: "ffldb"}
+	activeNetParams = &regNetParams
+	var dbs []database.DB
+	teardown := func() {
+		for _, db := range dbs {
+			db.Close()
+		}
+		cfg, activeNetParams = oldCfg, oldParams
+		os.RemoveAll(dataDir)
+	}
+	newChain := func(name string, params *chaincfg.Params, snapshot []byte) *blockchain.BlockChain {
+		db, err := database.Create("ffldb", filepath.Join(dataDir, name),
+			params.Net)
+		if err != nil {
+			teardown()
+			t.Fatalf("unable to create %s db: %v", name, err)
+		}
+		dbs = append(dbs, db)
+		config := blockchain.Config{
+			DB:          db,
+			ChainParams: params,
+			TimeSource:  blockchain.NewMedianTime(),
+			SigCache:    txscript.NewSigCache(1000),
+		}
+		if snapshot != nil {
+			config.Snapshot = bytes.NewReader(snapshot)
+		}
+		chain, err := blockchain.New(&config)
+		if err != nil {
+			teardown()
+			t.Fatalf("unable to create %s chain: %v", name, err)
+		}
+		return chain
+	}
+
+	// Generate the blocks and connect them to a source chain.
+	params := *regNetParams.Params
+	g, err := chaingen.MakeGenerator(&params)
+	if err != nil {
+		teardown()
+		t.Fatalf("unable to create generator: %v", err)
+	}
+	source := newChain("source", &params, nil)
+	var blocks []*dcrutil.Block
+	for i := 0; i < numBlocks; i++ {
+		if i == 0 {
+			g.CreatePremineBlock("bp", 0)
+		} else {
+			g.NextBlock(fmt.Sprintf("b%d", i), nil, nil)
+		}
+		block := dcrutil.NewBlock(g.Tip())
+		if _, _, err := source.ProcessBlock(block, blockchain.BFNone); err != nil {
+			teardown()
+			t.Fatalf("unable to process block %d: %v", i+1, err)
+		}
+		blocks = append(blocks, block)
+	}
+
+	// Dump a snapshot of the source chain and load it into a new chain.
+	var buf bytes.Buffer
+	info, err := source.DumpSnapshot(&buf)
+	if err != nil {
+		teardown()
+		t.Fatalf("unable to dump snapshot: %v", err)
+	}
+	params.Snapshots = []chaincfg.Snapshot{
+		{Height: info.Height, Hash: &info.Commitment},
+	}
+	chain := newChain("snapshot", &params, buf.Bytes())
+	snapshot := chain.UnvalidatedSnapshot()
+	if snapshot == nil {
+		teardown()
+		t.Fatal("chain loaded from snapshot does not report it")
+	}
+
+	return &historyTestHarness{
+		t:        t,
+		params:   &params,
+		chain:    chain,
+		snapshot: snapshot,
+		blocks:   blocks,
+	}, teardown
+}
+
+// newBlockManager returns a block manager with the chain of the harness that
+// is only suitable for running a history validator.
+func (h *historyTestHarness) newBlockManager() *blockManager {
+	return &blockManager{
+		server: &server{
+			chainParams: h.params,
+			timeSource:  blockchain.NewMedianTime(),
+			sigCache:    txscript.NewSigCache(1000),
+		},
+		chain:   h.chain,
+		msgChan: make(chan interface{}, len(h.blocks)+1),
+		quit:    make(chan struct{}),
+	}
+}
+
+// newValidator returns a new history validator for the passed snapshot using
+// the passed block manager.
+func (h *historyTestHarness) newValidator(bm *blockManager, snapshot *blockchain.SnapshotInfo) *historyValidator {
+	h.t.Helper()
+
+	v, err := newHistoryValidator(bm, snapshot, nil)
+	if err != nil {
+		h.t.Fatalf("unable to create history validator: %v", err)
+	}
+	return v
+}
+
+// waitMsg waits for the next message the history validator sends to the block
+// manager.
+func (h *historyTestHarness) waitMsg(bm *blockManager) interface{} {
+	h.t.Helper()
+
+	select {
+	case msg := <-bm.msgChan:
+		return msg
+	case <-time.After(time.Minute):
+		h.t.Fatal("timeout waiting for history validator")
+	}
+	return nil
+}
+
+// TestHistoryValidator ensures the history validator connects the blocks up to
+// the snapshot, resumes from where it stopped after a restart, and marks the
+// snapshot as validated once the resulting chain state matches it.
+func TestHistoryValidator(t *testing.T) {
+	const numBlocks = 8
+	h, teardown := newHistoryTestHarness(t, numBlocks)
+	defer teardown()
+
+	// Ensure the blocks that were requested are queued for processing in
+	// order regardless of the order they are received in while blocks that
+	// were not requested are ignored.
+	bm := h.newBlockManager()
+	v := h.newValidator(bm, h.snapshot)
+	if v.nextQueueHeight != 1 || v.processedHeight != 0 {
+		t.Fatalf("unexpected initial heights -- next %d, processed %d",
+			v.nextQueueHeight, v.processedHeight)
+	}
+	const numFirstRun = numBlocks / 2
+	for _, block := range h.blocks[:numFirstRun] {
+		v.requests[block.Height()] = &historyRequest{hash: *block.Hash()}
+	}
+	if v.handleBlockMsg(&blockMsg{block: h.blocks[numFirstRun]}) {
+		t.Fatal("unrequested block was accepted")
+	}
+	for i := numFirstRun - 1; i >= 0; i-- {
+		if !v.handleBlockMsg(&blockMsg{block: h.blocks[i]}) {
+			t.Fatalf("requested block %d was not accepted", i+1)
+		}
+		queued := len(v.blocks)
+		if i != 0 && queued != 0 {
+			t.Fatalf("block %d queued before its parent", i+1)
+		}
+	}
+	if len(v.blocks) != numFirstRun || len(v.requests) != 0 ||
+		len(v.received) != 0 {
+
+		t.Fatalf("unexpected state after receiving blocks -- queued %d, "+
+			"requested %d, received %d", len(v.blocks), len(v.requests),
+			len(v.received))
+	}
+
+	// Process the queued blocks and stop the validator.
+	v.Start()
+	for i := 1; i <= numFirstRun; i++ {
+		msg, ok := h.waitMsg(bm).(*historyProcessedMsg)
+		if !ok || msg.height != int64(i) {
+			t.Fatalf("unexpected message %#v, want processed height %d",
+				msg, i)
+		}
+	}
+	close(bm.quit)
+	bm.wg.Wait()
+	if h.chain.UnvalidatedSnapshot() == nil {
+		t.Fatal("snapshot marked validated before validating the history")
+	}
+
+	// Ensure a new validator resumes from the last processed block and
+	// marks the snapshot as validated once the remaining blocks are
+	// processed.
+	bm = h.newBlockManager()
+	v = h.newValidator(bm, h.snapshot)
+	if v.nextQueueHeight != numFirstRun+1 || v.processedHeight != numFirstRun {
+		t.Fatalf("unexpected resumed heights -- next %d, processed %d",
+			v.nextQueueHeight, v.processedHeight)
+	}
+	for _, block := range h.blocks[numFirstRun:] {
+		v.blocks <- block
+	}
+	v.Start()
+	for i := numFirstRun + 1; i <= numBlocks; i++ {
+		if _, ok := h.waitMsg(bm).(*historyProcessedMsg); !ok {
+			t.Fatalf("unexpected message while waiting for block %d", i)
+		}
+	}
+	if _, ok := h.waitMsg(bm).(*historyDoneMsg); !ok {
+		t.Fatal("history validation did not complete")
+	}
+	bm.wg.Wait()
+	if snapshot := h.chain.UnvalidatedSnapshot(); snapshot != nil {
+		t.Fatalf("snapshot %v not marked validated", snapshot.Commitment)
+	}
+	if _, err := os.Stat(v.dbPath); !os.IsNotExist(err) {
+		t.Fatalf("history database was not removed (err %v)", err)
+	}
+}
+
+// TestHistoryValidatorMismatch ensures the history validator requests a
+// shutdown without marking the snapshot as validated when the validated chain
+// state does not match the snapshot.
+func TestHistoryValidatorMismatch(t *testing.T) {
+	const numBlocks = 4
+	h, teardown := newHistoryTestHarness(t, numBlocks)
+	defer teardown()
+
+	bm := h.newBlockManager()
+	snapshot := *h.snapshot
+	snapshot.Commitment[0] ^= 0x01
+	v := h.newValidator(bm, &snapshot)
+	for _, block := range h.blocks {
+		v.blocks <- block
+	}
+	v.Start()
+
+	select {
+	case <-shutdownRequestChannel:
+	case <-time.After(time.Minute):
+		t.Fatal("history validator did not request a shutdown")
+	}
+	bm.wg.Wait()
+	for len(bm.msgChan) > 0 {
+		if _, ok := (<-bm.msgChan).(*historyDoneMsg); ok {
+			t.Fatal("history validation completed despite mismatch")
+		}
+	}
+	if h.chain.UnvalidatedSnapshot() == nil {
+		t.Fatal("mismatched snapshot marked validated")
+	}
+}
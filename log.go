@@ -18,6 +18,7 @@ import (
 	"github.com/decred/dcrd/database"
 	"github.com/decred/dcrd/fees"
 	"github.com/decred/dcrd/mempool/v2"
+	"github.com/decred/dcrd/mining"
 	"github.com/decred/dcrd/peer"
 	"github.com/decred/dcrd/txscript"
 	"github.com/decred/slog"
@@ -82,6 +83,7 @@ func init() {
 	fees.UseLogger(feesLog)
 	indexers.UseLogger(indxLog)
 	mempool.UseLogger(txmpLog)
+	mining.UseLogger(minrLog)
 	peer.UseLogger(peerLog)
 	stake.UseLogger(stkeLog)
 	txscript.UseLogger(scrpLog)
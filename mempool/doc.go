@@ -50,6 +50,9 @@ be an exhaustive list.
   - Reject invalid transactions according to the network consensus rules
   - Full script execution and validation with signature cache support
   - Individual transaction query support
+  - Package acceptance where transactions which depend on others in the
+    package may pay the fees for them
+  - Testing whether transactions would be accepted without adding them
 - Stake transaction support (ticket purchases, votes and revocations)
   - Option to accept or reject old votes
 - Orphan transaction support (transactions that spend from unknown outputs)
@@ -65,6 +68,11 @@ be an exhaustive list.
   - Max signature operations per transaction
   - Max orphan transaction size
   - Max number of orphan transactions allowed
+  - Max total pool size with eviction of the lowest fee rate transactions and
+    a dynamic minimum relay fee
+  - Opt-in replacement of regular transactions which signal replaceability via
+    their input sequence numbers by conflicting transactions that pay higher
+    fees
 - Additional metadata tracking for each transaction
   - Timestamp when the transaction was added to the pool
   - Most recent block height when the transaction was added to the pool
@@ -72,6 +80,10 @@ be an exhaustive list.
   - The starting priority for the transaction
 - Manual control of transaction removal
   - Recursive removal of all dependent transactions
+- Saving the pool to a versioned serialized format and loading it back with
+  full re-validation of every transaction
+- Subscription to events for transactions added to and removed from the pool
+  along with the reason each transaction was removed
 
 Errors
 
@@ -0,0 +1,168 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"fmt"
+
+	"github.com/decred/dcrd/blockchain/stake"
+	"github.com/decred/dcrd/dcrutil"
+)
+
+// EventType represents the type of an event emitted by the memory pool.
+type EventType int
+
+// Constants for the type of an event emitted by the memory pool.
+const (
+	// EventTxAdded indicates the associated transaction was added to the
+	// main pool.
+	EventTxAdded EventType = iota
+
+	// EventTxRemoved indicates the associated transaction was removed from
+	// the main pool.  The reason it was removed is provided by the Reason
+	// field of the event.
+	EventTxRemoved
+)
+
+// eventTypeStrings is a map of event types back to their constant names for
+// pretty printing.
+var eventTypeStrings = map[EventType]string{
+	EventTxAdded:   "EventTxAdded",
+	EventTxRemoved: "EventTxRemoved",
+}
+
+// String returns the EventType in human-readable form.
+func (e EventType) String() string {
+	if s, ok := eventTypeStrings[e]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown Event Type (%d)", int(e))
+}
+
+// RemovalReason identifies why a transaction was removed from the main pool.
+type RemovalReason int
+
+// Constants for the reasons a transaction is removed from the main pool.
+//
+// Transactions which depend on a removed transaction are removed along with it
+// for the same reason since they would otherwise become orphans.
+const (
+	// RemovalReasonMined indicates the transaction was included in a block
+	// connected to the main chain.
+	RemovalReasonMined RemovalReason = iota
+
+	// RemovalReasonConflict indicates the transaction spends an output that
+	// is also spent by a transaction in a block connected to the main chain.
+	RemovalReasonConflict
+
+	// RemovalReasonExpired indicates the transaction expired.
+	RemovalReasonExpired
+
+	// RemovalReasonStakePruned indicates the stake transaction is no longer
+	// valid for inclusion in the next block, such as a ticket purchase that
+	// does not pay the current stake difficulty or a vote on a block that is
+	// too old.
+	RemovalReasonStakePruned
+
+	// RemovalReasonEvicted indicates the transaction was evicted due to its
+	// low fee rate in order to keep the pool within its maximum size.
+	RemovalReasonEvicted
+
+	// RemovalReasonReplaced indicates the transaction was replaced by a
+	// transaction paying higher fees which spends one of the same outputs.
+	RemovalReasonReplaced
+
+	// RemovalReasonReorg indicates the transaction depends on a transaction
+	// that could not be added back to the pool after being removed from the
+	// main chain due to a reorganization or the disapproval of the regular
+	// transaction tree of a block.
+	RemovalReasonReorg
+
+	// RemovalReasonOther indicates the transaction was removed by a caller
+	// of RemoveTransaction that did not provide a specific reason.
+	RemovalReasonOther
+)
+
+// removalReasonStrings is a map of removal reasons back to their names.  The
+// names are used in RPC notifications and must not change.
+var removalReasonStrings = map[RemovalReason]string{
+	RemovalReasonMined:       "mined",
+	RemovalReasonConflict:    "conflict",
+	RemovalReasonExpired:     "expired",
+	RemovalReasonStakePruned: "stakepruned",
+	RemovalReasonEvicted:     "evicted",
+	RemovalReasonReplaced:    "replaced",
+	RemovalReasonReorg:       "reorg",
+	RemovalReasonOther:       "other",
+}
+
+// String returns the RemovalReason in human-readable form.
+func (r RemovalReason) String() string {
+	if s, ok := removalReasonStrings[r]; ok {
+		return s
+	}
+	return fmt.Sprintf("unknown(%d)", int(r))
+}
+
+// Event defines an event that is sent to the subscribers of the memory pool
+// whenever a transaction is added to or removed from the main pool.  Orphan
+// transactions do not generate events.
+type Event struct {
+	// Type is the type of the event.
+	Type EventType
+
+	// Tx is the transaction that was added or removed.
+	Tx *dcrutil.Tx
+
+	// TxType is the stake transaction type of the transaction.
+	TxType stake.TxType
+
+	// Fee is the total fee the transaction pays.
+	Fee int64
+
+	// Reason is the reason the transaction was removed.  It is only set
+	// for EventTxRemoved events.
+	Reason RemovalReason
+}
+
+// EventCallback is used for a caller to provide a callback for events emitted
+// by the memory pool.
+type EventCallback func(*Event)
+
+// Subscribe registers the passed callback to receive all events emitted by the
+// memory pool from this point on.
+//
+// The callbacks are invoked synchronously, in the order the events happen,
+// while the mempool lock is held, so they must not call any of the pool
+// methods and should return quickly.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) Subscribe(callback EventCallback) {
+	mp.subscribersMtx.Lock()
+	mp.subscribers = append(mp.subscribers, callback)
+	mp.subscribersMtx.Unlock()
+}
+
+// sendEvent sends an event with the passed details to all subscribers.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) sendEvent(typ EventType, txDesc *TxDesc, reason RemovalReason) {
+	mp.subscribersMtx.RLock()
+	defer mp.subscribersMtx.RUnlock()
+	if len(mp.subscribers) == 0 {
+		return
+	}
+
+	event := Event{
+		Type:   typ,
+		Tx:     txDesc.Tx,
+		TxType: txDesc.Type,
+		Fee:    txDesc.Fee,
+		Reason: reason,
+	}
+	for _, callback := range mp.subscribers {
+		callback(&event)
+	}
+}
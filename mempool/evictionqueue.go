@@ -0,0 +1,127 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"bytes"
+	"container/heap"
+)
+
+// evictionFeeRate returns the fee rate in atoms/kB used to order the
+// transaction associated with the passed descriptor for eviction.  It is the
+// greater of the fee rate of the transaction itself and the combined fee rate
+// of the transaction together with all of its descendants in the pool.  Since
+// the descendants are evicted along with the transaction, this ensures a
+// transaction with a low fee rate is not evicted before transactions with
+// lower fee rates when its descendants pay for it.
+func evictionFeeRate(txDesc *TxDesc) float64 {
+	txSize := txDesc.Tx.MsgTx().SerializeSize()
+	feeRate := float64(txDesc.Fee) * 1000 / float64(txSize)
+	if pkg := &txDesc.Descendants; pkg.Size > 0 {
+		pkgFeeRate := float64(pkg.Fees) * 1000 / float64(pkg.Size)
+		if pkgFeeRate > feeRate {
+			feeRate = pkgFeeRate
+		}
+	}
+	return feeRate
+}
+
+// evictionLess returns whether the transaction associated with descriptor a
+// should be evicted before the one associated with descriptor b.  Transactions
+// with lower eviction fee rates are evicted first and ties are broken by hash
+// so the order does not depend on the order the transactions were added in.
+func evictionLess(a, b *TxDesc) bool {
+	rateA, rateB := evictionFeeRate(a), evictionFeeRate(b)
+	if rateA != rateB {
+		return rateA < rateB
+	}
+	return bytes.Compare(a.Tx.Hash()[:], b.Tx.Hash()[:]) < 0
+}
+
+// evictionQueue implements a priority queue of the regular transactions in the
+// main pool ordered by evictionLess so the next transaction to evict when the
+// pool exceeds its maximum size is always at the front of the queue.  Every
+// descriptor in the queue tracks its own index so it can be removed or fixed
+// up in logarithmic time.  Since the eviction fee rate depends on the
+// descendant statistics of the descriptors, the queue must be fixed up with fix
+// whenever they change.
+type evictionQueue []*TxDesc
+
+// Len returns the number of items in the queue.  It is part of the
+// heap.Interface implementation.
+func (q evictionQueue) Len() int {
+	return len(q)
+}
+
+// Less returns whether the item in the queue with index i should sort before
+// the item with index j.  It is part of the heap.Interface implementation.
+func (q evictionQueue) Less(i, j int) bool {
+	return evictionLess(q[i], q[j])
+}
+
+// Swap swaps the items at the passed indices in the queue.  It is part of the
+// heap.Interface implementation.
+func (q evictionQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].evictionIdx = i
+	q[j].evictionIdx = j
+}
+
+// Push pushes the passed item onto the queue.  It is part of the
+// heap.Interface implementation.
+func (q *evictionQueue) Push(x interface{}) {
+	txDesc := x.(*TxDesc)
+	txDesc.evictionIdx = len(*q)
+	*q = append(*q, txDesc)
+}
+
+// Pop removes the last item of the queue and returns it.  It is part of the
+// heap.Interface implementation.
+func (q *evictionQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	txDesc := old[n-1]
+	old[n-1] = nil
+	txDesc.evictionIdx = -1
+	*q = old[:n-1]
+	return txDesc
+}
+
+// add adds the passed descriptor to the queue.
+func (q *evictionQueue) add(txDesc *TxDesc) {
+	heap.Push(q, txDesc)
+}
+
+// contains returns whether or not the passed descriptor is in the queue.
+func (q evictionQueue) contains(txDesc *TxDesc) bool {
+	return txDesc.evictionIdx >= 0 && txDesc.evictionIdx < len(q) &&
+		q[txDesc.evictionIdx] == txDesc
+}
+
+// remove removes the passed descriptor from the queue.  It has no effect when
+// the descriptor is not in the queue.
+func (q *evictionQueue) remove(txDesc *TxDesc) {
+	if q.contains(txDesc) {
+		heap.Remove(q, txDesc.evictionIdx)
+	}
+}
+
+// fix restores the order of the queue after the eviction fee rate of the passed
+// descriptor changed.  It has no effect when the descriptor is not in the
+// queue.
+func (q *evictionQueue) fix(txDesc *TxDesc) {
+	if q.contains(txDesc) {
+		heap.Fix(q, txDesc.evictionIdx)
+	}
+}
+
+// front returns the descriptor of the next transaction to evict or nil when
+// the queue is empty.
+func (q evictionQueue) front() *TxDesc {
+	if len(q) == 0 {
+		return nil
+	}
+	return q[0]
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/mining"
+	"github.com/decred/dcrd/wire"
+)
+
+// TestEvictionQueue ensures the eviction queue always has the descriptor that
+// sorts first according to evictionLess at its front as descriptors are added
+// and removed in random order.
+func TestEvictionQueue(t *testing.T) {
+	const numDescs = 500
+	descs := make([]*TxDesc, 0, numDescs)
+	for i := 0; i < numDescs; i++ {
+		// Give the transactions random sizes and fees with a limited set
+		// of fees so some of them end up with the same fee rate.
+		msgTx := wire.NewMsgTx()
+		msgTx.AddTxIn(&wire.TxIn{
+			PreviousOutPoint: wire.OutPoint{Index: uint32(i)},
+		})
+		msgTx.AddTxOut(wire.NewTxOut(0, make([]byte, rand.Intn(3))))
+		descs = append(descs, &TxDesc{TxDesc: mining.TxDesc{
+			Tx:  dcrutil.NewTx(msgTx),
+			Fee: int64(rand.Intn(10) * 1000),
+		}})
+	}
+
+	// checkFront ensures the front of the queue is the first of the passed
+	// descriptors according to evictionLess.
+	var q evictionQueue
+	checkFront := func(remaining map[*TxDesc]struct{}) {
+		t.Helper()
+		var want *TxDesc
+		for txDesc := range remaining {
+			if want == nil || evictionLess(txDesc, want) {
+				want = txDesc
+			}
+		}
+		if got := q.front(); got != want {
+			t.Fatalf("unexpected front of queue -- got %v, want %v",
+				got.Tx.Hash(), want.Tx.Hash())
+		}
+	}
+
+	remaining := make(map[*TxDesc]struct{})
+	for _, txDesc := range descs {
+		q.add(txDesc)
+		remaining[txDesc] = struct{}{}
+		checkFront(remaining)
+	}
+
+	// Removing or fixing a descriptor that is not in the queue must have no
+	// effect.
+	q.remove(&TxDesc{})
+	q.fix(&TxDesc{})
+	checkFront(remaining)
+
+	// Ensure the queue is reordered when the descendant statistics which the
+	// eviction fee rate depends on change.
+	for _, i := range rand.Perm(numDescs)[:numDescs/5] {
+		txDesc := descs[i]
+		txSize := int64(txDesc.Tx.MsgTx().SerializeSize())
+		txDesc.Descendants = mining.TxPackageStats{
+			Count: 2,
+			Size:  2 * txSize,
+			Fees:  txDesc.Fee + int64(rand.Intn(20)*1000),
+		}
+		q.fix(txDesc)
+		checkFront(remaining)
+	}
+
+	for _, i := range rand.Perm(numDescs) {
+		q.remove(descs[i])
+		delete(remaining, descs[i])
+		if len(remaining) > 0 {
+			checkFront(remaining)
+		}
+		if descs[i].evictionIdx != -1 {
+			t.Fatalf("removed descriptor has index %d",
+				descs[i].evictionIdx)
+		}
+	}
+	if q.front() != nil || len(q) != 0 {
+		t.Fatalf("queue not empty after removing all descriptors")
+	}
+}
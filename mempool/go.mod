@@ -12,7 +12,7 @@ require (
 	github.com/decred/dcrd/dcrec/secp256k1 v1.0.1
 	github.com/decred/dcrd/dcrutil v1.2.0
 	github.com/decred/dcrd/gcs v1.0.2 // indirect
-	github.com/decred/dcrd/mining v1.1.0
+	github.com/decred/dcrd/mining v1.2.0
 	github.com/decred/dcrd/txscript v1.0.2
 	github.com/decred/dcrd/wire v1.2.0
 	github.com/decred/slog v1.0.0
@@ -24,3 +24,5 @@ require (
 	golang.org/x/sys v0.0.0-20190203050204-7ae0202eb74c // indirect
 	gopkg.in/yaml.v2 v2.2.2 // indirect
 )
+
+replace github.com/decred/dcrd/mining => ../mining
@@ -56,6 +56,26 @@ const (
 	// maxNullDataOutputs is the maximum number of OP_RETURN null data
 	// pushes in a transaction, after which it is considered non-standard.
 	maxNullDataOutputs = 4
+
+	// rollingMinFeeHalfLife is the half-life of the dynamic minimum relay
+	// fee that is raised when transactions are evicted in order to keep the
+	// pool within its maximum size.
+	rollingMinFeeHalfLife = 12 * time.Hour
+
+	// maxReplacementEvictions is the maximum number of transactions that
+	// may be evicted from the pool, including the descendants of the
+	// directly conflicting transactions, when accepting a replacement
+	// transaction.
+	maxReplacementEvictions = 100
+
+	// MaxRBFSequence is the maximum sequence number an input of a regular
+	// transaction may have in order to signal that the transaction may be
+	// replaced by a conflicting transaction that pays a higher fee.
+	MaxRBFSequence = wire.MaxTxInSequenceNum - 2
+
+	// MaxPackageTxns is the maximum number of transactions allowed in a
+	// package processed by ProcessPackage.
+	MaxPackageTxns = 25
 )
 
 // Config is a descriptor containing the memory pool configuration.
@@ -158,6 +178,34 @@ type Policy struct {
 	// of big orphans.
 	MaxOrphanTxSize int
 
+	// MaxPoolSize is the maximum total serialized size in bytes of all
+	// transactions in the main pool.  Once it is exceeded, the regular
+	// transactions with the lowest fee rates are evicted along with any
+	// transactions in the pool which depend on them.  Stake transactions are
+	// never evicted, so they alone may exceed the limit.  A value of zero
+	// disables the limit.
+	MaxPoolSize int64
+
+	// MaxAncestorCount is the maximum number of transactions in the main
+	// pool, including itself, that a transaction in the pool may depend on.
+	// A value of zero disables the limit.
+	MaxAncestorCount int
+
+	// MaxAncestorSize is the maximum total serialized size in bytes of a
+	// transaction in the main pool along with all of the transactions in the
+	// pool it depends on.  A value of zero disables the limit.
+	MaxAncestorSize int64
+
+	// MaxDescendantCount is the maximum number of transactions in the main
+	// pool, including itself, that may depend on a transaction in the pool.
+	// A value of zero disables the limit.
+	MaxDescendantCount int
+
+	// MaxDescendantSize is the maximum total serialized size in bytes of a
+	// transaction in the main pool along with all of the transactions in the
+	// pool that depend on it.  A value of zero disables the limit.
+	MaxDescendantSize int64
+
 	// MaxSigOpsPerTx is the maximum number of signature operations
 	// in a single transaction we will relay or mine.  It is a fraction
 	// of the max signature operations for a block.
@@ -195,12 +243,20 @@ type TxDesc struct {
 	// StartingPriority is the priority of the transaction when it was added
 	// to the pool.
 	StartingPriority float64
+
+	// evictionIdx is the index of the descriptor in the eviction queue of
+	// the pool.  Only regular transactions are in the queue.
+	evictionIdx int
 }
 
 // VerboseTxDesc is a descriptor containing a transaction in the mempool along
 // with additional more expensive to calculate metadata.  Callers should prefer
 // working with the more efficient TxDesc unless they specifically need access
 // to the additional details provided.
+//
+// The embedded descriptor is a copy taken while the pool was locked, so its
+// ancestor and descendant statistics are consistent with each other and with
+// Depends.
 type VerboseTxDesc struct {
 	TxDesc
 
@@ -226,6 +282,22 @@ type TxPool struct {
 	orphans       map[chainhash.Hash]*dcrutil.Tx
 	orphansByPrev map[wire.OutPoint]map[chainhash.Hash]*dcrutil.Tx
 	outpoints     map[wire.OutPoint]*dcrutil.Tx
+	poolSize      int64 // total serialized size of the main pool.
+
+	// evictionQueue orders the regular transactions in the main pool by the
+	// order they are evicted in once the pool exceeds its maximum size.
+	evictionQueue evictionQueue
+
+	// rollingMinFee is the dynamic minimum relay fee in atoms/kB which is
+	// raised whenever transactions are evicted due to the pool size limit
+	// and decays over time.  It is zero when no eviction fee is in effect.
+	rollingMinFee        float64
+	lastRollingFeeUpdate time.Time
+
+	// subscribers are the callbacks which receive the events emitted by the
+	// pool.
+	subscribersMtx sync.RWMutex
+	subscribers    []EventCallback
 
 	// Votes on blocks.
 	votesMtx sync.RWMutex
@@ -588,11 +660,258 @@ func (mp *TxPool) HaveAllTransactions(hashes []chainhash.Hash) bool {
 	return inPool
 }
 
+// txAncestors returns the descriptors for all transactions in the main pool
+// that the passed transaction depends on, either directly or indirectly.
+//
+// When the passed limit is greater than zero, the walk stops as soon as more
+// than limit ancestors are found, so the result only contains all of them when
+// it has no more than limit entries.  The ancestor limits of the policy ensure
+// the unlimited walks over transactions already in the pool stay small.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) txAncestors(tx *dcrutil.Tx, limit int) map[chainhash.Hash]*TxDesc {
+	ancestors := make(map[chainhash.Hash]*TxDesc)
+	processList := []*dcrutil.Tx{tx}
+	for len(processList) > 0 {
+		if limit > 0 && len(ancestors) > limit {
+			break
+		}
+		processItem := processList[0]
+		processList = processList[1:]
+		for _, txIn := range processItem.MsgTx().TxIn {
+			originHash := txIn.PreviousOutPoint.Hash
+			if _, ok := ancestors[originHash]; ok {
+				continue
+			}
+			if txDesc, exists := mp.pool[originHash]; exists {
+				ancestors[originHash] = txDesc
+				processList = append(processList, txDesc.Tx)
+			}
+		}
+	}
+
+	return ancestors
+}
+
+// txDescendants returns the descriptors for all transactions in the main pool
+// that depend on the passed transaction, either directly or indirectly.
+//
+// The passed limit behaves the same as it does for txAncestors.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) txDescendants(tx *dcrutil.Tx, limit int) map[chainhash.Hash]*TxDesc {
+	descendants := make(map[chainhash.Hash]*TxDesc)
+	processList := []*dcrutil.Tx{tx}
+	for len(processList) > 0 {
+		if limit > 0 && len(descendants) > limit {
+			break
+		}
+		processItem := processList[0]
+		processList = processList[1:]
+		prevOut := wire.OutPoint{
+			Hash: *processItem.Hash(),
+			Tree: processItem.Tree(),
+		}
+		for txOutIdx := range processItem.MsgTx().TxOut {
+			prevOut.Index = uint32(txOutIdx)
+			txRedeemer, exists := mp.outpoints[prevOut]
+			if !exists {
+				continue
+			}
+			redeemerHash := *txRedeemer.Hash()
+			if _, ok := descendants[redeemerHash]; ok {
+				continue
+			}
+			if txDesc, exists := mp.pool[redeemerHash]; exists {
+				descendants[redeemerHash] = txDesc
+				processList = append(processList, txDesc.Tx)
+			}
+		}
+	}
+
+	return descendants
+}
+
+// txPackageStats returns the package statistics for the transaction associated
+// with the passed descriptor on its own.
+func txPackageStats(txDesc *TxDesc) mining.TxPackageStats {
+	return mining.TxPackageStats{
+		Count: 1,
+		Size:  int64(txDesc.Tx.MsgTx().SerializeSize()),
+		Fees:  txDesc.Fee,
+	}
+}
+
+// addPackageStats adds the package statistics in b to a.
+func addPackageStats(a *mining.TxPackageStats, b mining.TxPackageStats) {
+	a.Count += b.Count
+	a.Size += b.Size
+	a.Fees += b.Fees
+}
+
+// subPackageStats subtracts the package statistics in b from a.
+func subPackageStats(a *mining.TxPackageStats, b mining.TxPackageStats) {
+	a.Count -= b.Count
+	a.Size -= b.Size
+	a.Fees -= b.Fees
+}
+
+// recalcPackageStats recalculates the ancestor and descendant statistics of
+// the passed descriptor from scratch and fixes up its position in the eviction
+// queue accordingly.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) recalcPackageStats(txDesc *TxDesc) {
+	txDesc.Ancestors = txPackageStats(txDesc)
+	for _, ancestor := range mp.txAncestors(txDesc.Tx, 0) {
+		addPackageStats(&txDesc.Ancestors, txPackageStats(ancestor))
+	}
+	txDesc.Descendants = txPackageStats(txDesc)
+	for _, descendant := range mp.txDescendants(txDesc.Tx, 0) {
+		addPackageStats(&txDesc.Descendants, txPackageStats(descendant))
+	}
+	mp.evictionQueue.fix(txDesc)
+}
+
+// addToPackageStats updates the ancestor and descendant statistics of the
+// passed descriptor, which must have just been added to the main pool, along
+// with those of all related transactions in the pool.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) addToPackageStats(txDesc *TxDesc) {
+	ancestors := mp.txAncestors(txDesc.Tx, 0)
+	descendants := mp.txDescendants(txDesc.Tx, 0)
+
+	// Transactions already in the pool can only depend on the new one when it
+	// is being added back to the pool from a disconnected block.  Since some
+	// of the descendants might already share ancestors with it in that case,
+	// recalculate the statistics of all affected transactions from scratch.
+	if len(descendants) > 0 {
+		mp.recalcPackageStats(txDesc)
+		for _, ancestor := range ancestors {
+			mp.recalcPackageStats(ancestor)
+		}
+		for _, descendant := range descendants {
+			mp.recalcPackageStats(descendant)
+		}
+		return
+	}
+
+	stats := txPackageStats(txDesc)
+	txDesc.Ancestors = stats
+	txDesc.Descendants = stats
+	for _, ancestor := range ancestors {
+		addPackageStats(&txDesc.Ancestors, txPackageStats(ancestor))
+		addPackageStats(&ancestor.Descendants, stats)
+		mp.evictionQueue.fix(ancestor)
+	}
+}
+
+// removeFromPackageStats removes the transaction associated with the passed
+// descriptor, which must still be in the main pool, from the ancestor and
+// descendant statistics of all related transactions in the pool.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) removeFromPackageStats(txDesc *TxDesc) {
+	stats := txPackageStats(txDesc)
+	for _, ancestor := range mp.txAncestors(txDesc.Tx, 0) {
+		subPackageStats(&ancestor.Descendants, stats)
+		mp.evictionQueue.fix(ancestor)
+	}
+	for _, descendant := range mp.txDescendants(txDesc.Tx, 0) {
+		subPackageStats(&descendant.Ancestors, stats)
+	}
+}
+
+// checkPackageLimits returns an error when adding the passed transaction to
+// the main pool would result in it or any related transaction in the pool
+// exceeding the ancestor or descendant limits of the policy.
+//
+// Transactions already in the pool only depend on the passed transaction when
+// it is being added back to the pool from a disconnected block, in which case
+// the limits are checked for those descendants as well.  Since the ancestors of
+// the descendants might overlap with those of the passed transaction, the
+// checks for related transactions are conservative.
+//
+// The walks over the related transactions stop as soon as the count limits
+// are exceeded.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) checkPackageLimits(tx *dcrutil.Tx) error {
+	policy := &mp.cfg.Policy
+	txSize := int64(tx.MsgTx().SerializeSize())
+	ancestors := mp.txAncestors(tx, policy.MaxAncestorCount)
+	descendants := mp.txDescendants(tx, policy.MaxDescendantCount)
+	ancestorStats := mining.TxPackageStats{Count: 1, Size: txSize}
+	for _, ancestor := range ancestors {
+		addPackageStats(&ancestorStats, txPackageStats(ancestor))
+	}
+	descendantStats := mining.TxPackageStats{Count: 1, Size: txSize}
+	for _, descendant := range descendants {
+		addPackageStats(&descendantStats, txPackageStats(descendant))
+	}
+
+	// exceedsLimits returns whether the passed statistics exceed the passed
+	// limits, which are disabled when zero.
+	exceedsLimits := func(stats mining.TxPackageStats, maxCount int, maxSize int64) bool {
+		return (maxCount > 0 && stats.Count > maxCount) ||
+			(maxSize > 0 && stats.Size > maxSize)
+	}
+
+	if exceedsLimits(ancestorStats, policy.MaxAncestorCount,
+		policy.MaxAncestorSize) {
+
+		str := fmt.Sprintf("transaction %v has too many ancestors in the "+
+			"pool (count limit %d, size limit %d)", tx.Hash(),
+			policy.MaxAncestorCount, policy.MaxAncestorSize)
+		return txRuleError(wire.RejectNonstandard, str)
+	}
+	if exceedsLimits(descendantStats, policy.MaxDescendantCount,
+		policy.MaxDescendantSize) {
+
+		str := fmt.Sprintf("transaction %v has too many descendants in the "+
+			"pool (count limit %d, size limit %d)", tx.Hash(),
+			policy.MaxDescendantCount, policy.MaxDescendantSize)
+		return txRuleError(wire.RejectNonstandard, str)
+	}
+
+	// Ensure the ancestors would not exceed their descendant limits and the
+	// descendants would not exceed their ancestor limits.
+	for hash, ancestor := range ancestors {
+		stats := ancestor.Descendants
+		addPackageStats(&stats, descendantStats)
+		if exceedsLimits(stats, policy.MaxDescendantCount,
+			policy.MaxDescendantSize) {
+
+			str := fmt.Sprintf("transaction %v would exceed the descendant "+
+				"limits of transaction %v in the pool (count limit %d, "+
+				"size limit %d)", tx.Hash(), hash,
+				policy.MaxDescendantCount, policy.MaxDescendantSize)
+			return txRuleError(wire.RejectNonstandard, str)
+		}
+	}
+	for hash, descendant := range descendants {
+		stats := descendant.Ancestors
+		addPackageStats(&stats, ancestorStats)
+		if exceedsLimits(stats, policy.MaxAncestorCount,
+			policy.MaxAncestorSize) {
+
+			str := fmt.Sprintf("transaction %v would exceed the ancestor "+
+				"limits of transaction %v in the pool (count limit %d, "+
+				"size limit %d)", tx.Hash(), hash,
+				policy.MaxAncestorCount, policy.MaxAncestorSize)
+			return txRuleError(wire.RejectNonstandard, str)
+		}
+	}
+
+	return nil
+}
+
 // removeTransaction is the internal function which implements the public
 // RemoveTransaction.  See the comment for RemoveTransaction for more details.
 //
 // This function MUST be called with the mempool lock held (for writes).
-func (mp *TxPool) removeTransaction(tx *dcrutil.Tx, removeRedeemers bool) {
+func (mp *TxPool) removeTransaction(tx *dcrutil.Tx, removeRedeemers bool, reason RemovalReason) {
 	txHash := tx.Hash()
 	if removeRedeemers {
 		// Remove any transactions which rely on this one.
@@ -606,46 +925,109 @@ func (mp *TxPool) removeTransaction(tx *dcrutil.Tx, removeRedeemers bool) {
 		for i := uint32(0); i < uint32(len(tx.MsgTx().TxOut)); i++ {
 			prevOut.Index = i
 			if txRedeemer, exists := mp.outpoints[prevOut]; exists {
-				mp.removeTransaction(txRedeemer, true)
+				mp.removeTransaction(txRedeemer, true, reason)
 			}
 		}
 	}
 
 	// Remove the transaction if needed.
 	if txDesc, exists := mp.pool[*txHash]; exists {
-		log.Tracef("Removing transaction %v", txHash)
+		log.Tracef("Removing transaction %v (reason: %v)", txHash, reason)
+		mp.removeTxDesc(txDesc)
+		mp.notifyRemoved(txDesc, reason)
+	}
+}
 
-		// Remove unconfirmed address index entries associated with the
-		// transaction if enabled.
-		if mp.cfg.AddrIndex != nil {
-			mp.cfg.AddrIndex.RemoveUnconfirmedTx(txHash)
-		}
+// removeTxDesc removes the transaction associated with the passed descriptor
+// from the main pool along with its package statistics, the outpoints it
+// spends, and the eviction queue.  It does not remove the transactions which
+// depend on it or inform anything about the removal.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) removeTxDesc(txDesc *TxDesc) {
+	// Remove the transaction from the package statistics of the transactions
+	// related to it and from the eviction queue.
+	mp.removeFromPackageStats(txDesc)
+	mp.evictionQueue.remove(txDesc)
+
+	// Mark the referenced outpoints as unspent by the pool.
+	for _, txIn := range txDesc.Tx.MsgTx().TxIn {
+		delete(mp.outpoints, txIn.PreviousOutPoint)
+	}
+	delete(mp.pool, *txDesc.Tx.Hash())
+	mp.poolSize -= int64(txDesc.Tx.MsgTx().SerializeSize())
+}
 
-		// Mark the referenced outpoints as unspent by the pool.
-		for _, txIn := range txDesc.Tx.MsgTx().TxIn {
-			delete(mp.outpoints, txIn.PreviousOutPoint)
+// removeTxDescWithDescendants removes the transaction associated with the
+// passed descriptor from the main pool the same way as removeTxDesc, along
+// with all transactions in the pool which depend on it, and returns the
+// descriptors of all of them in the order they were removed.  Every
+// transaction is removed after the transactions which depend on it, the same
+// as removeTransaction does, so adding them back in reverse order with
+// addTxDesc restores the pool exactly.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) removeTxDescWithDescendants(txDesc *TxDesc, removed []*TxDesc) []*TxDesc {
+	prevOut := wire.OutPoint{Hash: *txDesc.Tx.Hash(), Tree: txDesc.Tx.Tree()}
+	for i := range txDesc.Tx.MsgTx().TxOut {
+		prevOut.Index = uint32(i)
+		txRedeemer, exists := mp.outpoints[prevOut]
+		if !exists {
+			continue
 		}
-		delete(mp.pool, *txHash)
-		atomic.StoreInt64(&mp.lastUpdated, time.Now().Unix())
-
-		// Inform associated fee estimator that the transaction has been removed
-		// from the mempool
-		if mp.cfg.RemoveTxFromFeeEstimation != nil {
-			mp.cfg.RemoveTxFromFeeEstimation(txHash)
+		if redeemerDesc, exists := mp.pool[*txRedeemer.Hash()]; exists {
+			removed = mp.removeTxDescWithDescendants(redeemerDesc, removed)
 		}
 	}
+	mp.removeTxDesc(txDesc)
+	return append(removed, txDesc)
+}
+
+// notifyRemoved informs the address index, the fee estimator, and the
+// subscribers of the pool that the transaction associated with the passed
+// descriptor was removed from the main pool for the passed reason.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) notifyRemoved(txDesc *TxDesc, reason RemovalReason) {
+	atomic.StoreInt64(&mp.lastUpdated, time.Now().Unix())
+
+	// Remove unconfirmed address index entries associated with the
+	// transaction if enabled.
+	txHash := txDesc.Tx.Hash()
+	if mp.cfg.AddrIndex != nil {
+		mp.cfg.AddrIndex.RemoveUnconfirmedTx(txHash)
+	}
+
+	// Inform associated fee estimator that the transaction has been removed
+	// from the mempool
+	if mp.cfg.RemoveTxFromFeeEstimation != nil {
+		mp.cfg.RemoveTxFromFeeEstimation(txHash)
+	}
+
+	mp.sendEvent(EventTxRemoved, txDesc, reason)
 }
 
 // RemoveTransaction removes the passed transaction from the mempool. When the
 // removeRedeemers flag is set, any transactions that redeem outputs from the
 // removed transaction will also be removed recursively from the mempool, as
-// they would otherwise become orphans.
+// they would otherwise become orphans.  The removal is reported to the
+// subscribers of the pool with RemovalReasonOther.  Use
+// RemoveTransactionWithReason to report a specific reason.
 //
 // This function is safe for concurrent access.
 func (mp *TxPool) RemoveTransaction(tx *dcrutil.Tx, removeRedeemers bool) {
+	mp.RemoveTransactionWithReason(tx, removeRedeemers, RemovalReasonOther)
+}
+
+// RemoveTransactionWithReason removes the passed transaction from the mempool
+// the same way as RemoveTransaction and reports the passed reason to the
+// subscribers of the pool for every removed transaction.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) RemoveTransactionWithReason(tx *dcrutil.Tx, removeRedeemers bool, reason RemovalReason) {
 	// Protect concurrent access.
 	mp.mtx.Lock()
-	mp.removeTransaction(tx, removeRedeemers)
+	mp.removeTransaction(tx, removeRedeemers, reason)
 	mp.mtx.Unlock()
 }
 
@@ -662,7 +1044,8 @@ func (mp *TxPool) RemoveDoubleSpends(tx *dcrutil.Tx) {
 	for _, txIn := range tx.MsgTx().TxIn {
 		if txRedeemer, ok := mp.outpoints[txIn.PreviousOutPoint]; ok {
 			if !txRedeemer.Hash().IsEqual(tx.Hash()) {
-				mp.removeTransaction(txRedeemer, true)
+				mp.removeTransaction(txRedeemer, true,
+					RemovalReasonConflict)
 			}
 		}
 	}
@@ -680,7 +1063,8 @@ func (mp *TxPool) addTransaction(utxoView *blockchain.UtxoViewpoint,
 	// Add the transaction to the pool and mark the referenced outpoints
 	// as spent by the pool.
 	msgTx := tx.MsgTx()
-	mp.pool[*tx.Hash()] = &TxDesc{
+	txSize := int64(msgTx.SerializeSize())
+	txDesc := &TxDesc{
 		TxDesc: mining.TxDesc{
 			Tx:     tx,
 			Type:   txType,
@@ -690,9 +1074,7 @@ func (mp *TxPool) addTransaction(utxoView *blockchain.UtxoViewpoint,
 		},
 		StartingPriority: mining.CalcPriority(msgTx, utxoView, height),
 	}
-	for _, txIn := range msgTx.TxIn {
-		mp.outpoints[txIn.PreviousOutPoint] = tx
-	}
+	mp.addTxDesc(txDesc)
 	atomic.StoreInt64(&mp.lastUpdated, time.Now().Unix())
 
 	// Add unconfirmed address index entries associated with the transaction
@@ -707,32 +1089,378 @@ func (mp *TxPool) addTransaction(utxoView *blockchain.UtxoViewpoint,
 	// Inform the associated fee estimator that a new transaction has been added
 	// to the mempool
 	if mp.cfg.AddTxToFeeEstimation != nil {
-		mp.cfg.AddTxToFeeEstimation(tx.Hash(), fee, int64(msgTx.SerializeSize()),
-			txType)
+		mp.cfg.AddTxToFeeEstimation(tx.Hash(), fee, txSize, txType)
+	}
+
+	mp.sendEvent(EventTxAdded, txDesc, 0)
+}
+
+// addTxDesc adds the transaction associated with the passed descriptor to the
+// main pool along with its package statistics, the outpoints it spends, and
+// the eviction queue when it is a regular transaction.  It does not inform
+// anything about the addition.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) addTxDesc(txDesc *TxDesc) {
+	tx := txDesc.Tx
+	mp.pool[*tx.Hash()] = txDesc
+	for _, txIn := range tx.MsgTx().TxIn {
+		mp.outpoints[txIn.PreviousOutPoint] = tx
+	}
+	mp.addToPackageStats(txDesc)
+	if txDesc.Type == stake.TxTypeRegular {
+		mp.evictionQueue.add(txDesc)
+	}
+	mp.poolSize += int64(tx.MsgTx().SerializeSize())
+}
+
+// currentMinRelayFee returns the minimum fee in atoms/kB that regular
+// transactions must pay in order to be accepted into the pool.  It is the
+// greater of the configured minimum relay fee and the dynamic fee that is
+// raised whenever transactions are evicted due to the pool size limit.
+//
+// The dynamic fee decays exponentially with a half-life of
+// rollingMinFeeHalfLife and is dropped entirely once it falls below half of
+// the configured minimum relay fee.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) currentMinRelayFee() dcrutil.Amount {
+	minRelayTxFee := mp.cfg.Policy.MinRelayTxFee
+	if mp.rollingMinFee == 0 {
+		return minRelayTxFee
+	}
+
+	now := time.Now()
+	halfLives := now.Sub(mp.lastRollingFeeUpdate).Hours() /
+		rollingMinFeeHalfLife.Hours()
+	mp.rollingMinFee /= math.Pow(2, halfLives)
+	mp.lastRollingFeeUpdate = now
+	if mp.rollingMinFee < float64(minRelayTxFee)/2 {
+		mp.rollingMinFee = 0
+		return minRelayTxFee
+	}
+
+	rollingMinFee := dcrutil.Amount(mp.rollingMinFee)
+	if rollingMinFee < minRelayTxFee {
+		return minRelayTxFee
+	}
+	return rollingMinFee
+}
+
+// limitPoolSize evicts the regular transactions with the lowest fee rates,
+// along with any transactions in the pool that depend on them, until the total
+// size of the pool no longer exceeds the configured maximum.  The transactions
+// to evict are chosen by evictToLimit.  The fee rate of a transaction includes
+// its descendants when they pay a higher fee rate as described by
+// evictionFeeRate, so a parent whose fees are paid by its children is kept as
+// long as the package as a whole pays enough.
+//
+// Stake transactions are never selected for eviction, so the pool may remain
+// above its maximum size when they alone exceed it.  Their number is instead
+// bounded by consensus and the stake pruning of the pool: votes and
+// revocations may only spend the limited set of live and missed tickets and
+// votes are pruned once they are too old, while every ticket purchase must
+// lock up the current stake difficulty and is pruned once it has not been
+// mined for heightDiffToPruneTicket blocks.  Votes can't depend on other
+// transactions in the pool since they spend mature tickets, so the votes
+// tracked for VoteHashesForBlock are never affected.  Ticket purchases that
+// spend outputs of an evicted transaction are removed along with it since
+// they would otherwise become orphans.
+//
+// The dynamic minimum relay fee is raised above the fee rate of the evicted
+// transactions so they are not immediately accepted again.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) limitPoolSize() {
+	maxPoolSize := mp.cfg.Policy.MaxPoolSize
+	if maxPoolSize <= 0 || mp.poolSize <= maxPoolSize {
+		return
+	}
+
+	poolSize := mp.poolSize
+	evicted, maxEvictedFeeRate := mp.evictToLimit(maxPoolSize)
+	if len(evicted) == 0 {
+		return
+	}
+	for _, txDesc := range evicted {
+		log.Debugf("Evicting transaction %v with fee rate %.0f atoms/kB "+
+			"(pool size %d > max %d)", txDesc.Tx.Hash(),
+			evictionFeeRate(txDesc), poolSize, maxPoolSize)
+		mp.notifyRemoved(txDesc, RemovalReasonEvicted)
+	}
+
+	// Raise the dynamic minimum relay fee so that it exceeds the fee rate of
+	// all evicted transactions by at least the configured minimum relay fee.
+	minFee := maxEvictedFeeRate + float64(mp.cfg.Policy.MinRelayTxFee)
+	if minFee > mp.rollingMinFee {
+		mp.rollingMinFee = minFee
+		mp.lastRollingFeeUpdate = time.Now()
+	}
+	log.Debugf("Evicted %d transactions to limit the pool size (minimum "+
+		"relay fee now %.0f atoms/kB)", len(evicted), mp.rollingMinFee)
+}
+
+// evictToLimit removes the transactions at the front of the eviction queue,
+// along with any transactions in the pool that depend on them, until the total
+// size of the pool no longer exceeds the passed maximum.  It returns the
+// descriptors of all removed transactions in the order they were removed
+// along with the highest eviction fee rate of the transactions that were taken
+// from the front of the queue.
+//
+// The transactions are only removed from the bookkeeping of the pool without
+// informing anything about the removal.  Both limitPoolSize and wouldBeEvicted
+// rely on this function so they always agree on the transactions to evict.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) evictToLimit(maxPoolSize int64) ([]*TxDesc, float64) {
+	var evicted []*TxDesc
+	var maxEvictedFeeRate float64
+	for mp.poolSize > maxPoolSize {
+		txDesc := mp.evictionQueue.front()
+		if txDesc == nil {
+			break
+		}
+		maxEvictedFeeRate = evictionFeeRate(txDesc)
+		evicted = mp.removeTxDescWithDescendants(txDesc, evicted)
+	}
+	return evicted, maxEvictedFeeRate
+}
+
+// txDescChange is a change made to the bookkeeping of the main pool by
+// addTxDesc or removeTxDesc which is recorded so it can be undone.
+type txDescChange struct {
+	txDesc *TxDesc
+	added  bool
+}
+
+// applyAcceptance applies the changes accepting the passed transaction, which
+// passed checkTransaction with the passed data, makes to the bookkeeping of
+// the pool.  That is, the transactions it replaces are removed, it is added,
+// and transactions are evicted with evictToLimit, exactly as they would be by
+// maybeAcceptTransaction, without informing anything about the changes.  It
+// returns the changes so they can be undone with undoTxDescChanges along with
+// whether or not the transaction remains in the pool.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) applyAcceptance(tx *dcrutil.Tx, data *txAcceptData) ([]txDescChange, bool) {
+	var removed []*TxDesc
+	for hash := range data.evictions {
+		if txDesc, exists := mp.pool[hash]; exists {
+			removed = mp.removeTxDescWithDescendants(txDesc, removed)
+		}
+	}
+	txDesc := &TxDesc{
+		TxDesc: mining.TxDesc{
+			Tx:     tx,
+			Type:   data.txType,
+			Height: data.bestHeight,
+			Fee:    data.fee,
+		},
+	}
+	mp.addTxDesc(txDesc)
+	changes := make([]txDescChange, 0, len(removed)+1)
+	for _, txDesc := range removed {
+		changes = append(changes, txDescChange{txDesc: txDesc})
+	}
+	changes = append(changes, txDescChange{txDesc: txDesc, added: true})
+
+	if maxPoolSize := mp.cfg.Policy.MaxPoolSize; maxPoolSize > 0 {
+		evicted, _ := mp.evictToLimit(maxPoolSize)
+		for _, txDesc := range evicted {
+			changes = append(changes, txDescChange{txDesc: txDesc})
+		}
+	}
+	_, accepted := mp.pool[*tx.Hash()]
+	return changes, accepted
+}
+
+// undoTxDescChanges undoes the passed changes to the bookkeeping of the pool
+// in reverse order, which restores the pool exactly as it was before them.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) undoTxDescChanges(changes []txDescChange) {
+	for i := len(changes) - 1; i >= 0; i-- {
+		change := &changes[i]
+		if change.added {
+			mp.removeTxDesc(change.txDesc)
+			continue
+		}
+		mp.addTxDesc(change.txDesc)
+	}
+}
+
+// wouldBeEvicted returns whether or not the passed transaction, which passed
+// checkTransaction with the passed data, would be evicted again by
+// limitPoolSize immediately after being added to the pool.  It temporarily
+// applies the changes with applyAcceptance and undoes them afterwards, so the
+// result always matches limitPoolSize.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) wouldBeEvicted(tx *dcrutil.Tx, data *txAcceptData) bool {
+	maxPoolSize := mp.cfg.Policy.MaxPoolSize
+	if maxPoolSize <= 0 {
+		return false
+	}
+	poolSize := mp.poolSize + int64(tx.MsgTx().SerializeSize())
+	for _, txDesc := range data.evictions {
+		poolSize -= int64(txDesc.Tx.MsgTx().SerializeSize())
+	}
+	if poolSize <= maxPoolSize {
+		return false
+	}
+
+	changes, accepted := mp.applyAcceptance(tx, data)
+	mp.undoTxDescChanges(changes)
+	return !accepted
+}
+
+// signalsReplacement returns whether or not the passed transaction signals
+// that it may be replaced by a conflicting transaction which pays a higher fee
+// by having at least one input with a sequence number of MaxRBFSequence or
+// less.
+func signalsReplacement(tx *dcrutil.Tx) bool {
+	for _, txIn := range tx.MsgTx().TxIn {
+		if txIn.Sequence <= MaxRBFSequence {
+			return true
+		}
 	}
+	return false
+}
+
+// isReplaceable returns whether or not the passed transaction in the main pool
+// may be replaced by a conflicting transaction.  Only regular transactions are
+// replaceable and they must either signal replaceability themselves or have
+// an unconfirmed ancestor in the pool that does.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) isReplaceable(tx *dcrutil.Tx) bool {
+	txDesc, exists := mp.pool[*tx.Hash()]
+	if !exists || txDesc.Type != stake.TxTypeRegular {
+		return false
+	}
+	if signalsReplacement(tx) {
+		return true
+	}
+	for _, ancestor := range mp.txAncestors(tx, 0) {
+		if signalsReplacement(ancestor.Tx) {
+			return true
+		}
+	}
+	return false
 }
 
 // checkPoolDoubleSpend checks whether or not the passed transaction is
 // attempting to spend coins already spent by other transactions in the pool.
+// Regular transactions may conflict with replaceable transactions in the pool,
+// in which case the conflicting transactions are returned so the caller can
+// validate the replacement once the fee of the transaction is known.
 // Note it does not check for double spends against transactions already in the
 // main chain.
 //
 // This function MUST be called with the mempool lock held (for reads).
-func (mp *TxPool) checkPoolDoubleSpend(tx *dcrutil.Tx, txType stake.TxType) error {
+func (mp *TxPool) checkPoolDoubleSpend(tx *dcrutil.Tx, txType stake.TxType) (map[chainhash.Hash]*dcrutil.Tx, error) {
+	var conflicts map[chainhash.Hash]*dcrutil.Tx
 	for i, txIn := range tx.MsgTx().TxIn {
 		// We don't care about double spends of stake bases.
 		if i == 0 && (txType == stake.TxTypeSSGen || txType == stake.TxTypeSSRtx) {
 			continue
 		}
 
-		if txR, exists := mp.outpoints[txIn.PreviousOutPoint]; exists {
+		txR, exists := mp.outpoints[txIn.PreviousOutPoint]
+		if !exists {
+			continue
+		}
+		if txType != stake.TxTypeRegular || !mp.isReplaceable(txR) {
 			str := fmt.Sprintf("transaction %v in the pool "+
 				"already spends the same coins", txR.Hash())
-			return txRuleError(wire.RejectDuplicate, str)
+			return nil, txRuleError(wire.RejectDuplicate, str)
+		}
+		if conflicts == nil {
+			conflicts = make(map[chainhash.Hash]*dcrutil.Tx)
 		}
+		conflicts[*txR.Hash()] = txR
 	}
 
-	return nil
+	return conflicts, nil
+}
+
+// validateReplacement ensures the passed transaction, which pays the passed
+// fee, is allowed to replace the passed conflicting transactions in the pool
+// and returns all of the transactions that would be evicted by the
+// replacement, which consist of the conflicts along with their descendants.
+//
+// A replacement must pay a strictly higher fee rate than the combined fee rate
+// of all of the transactions it evicts, must pay at least their combined fees
+// plus the minimum relay fee for its own size, may not evict more than
+// maxReplacementEvictions transactions, and may not spend any of the outputs
+// of the transactions it evicts.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) validateReplacement(tx *dcrutil.Tx, txFee int64, conflicts map[chainhash.Hash]*dcrutil.Tx) (map[chainhash.Hash]*TxDesc, error) {
+	txHash := tx.Hash()
+	evictions := make(map[chainhash.Hash]*TxDesc)
+	for conflictHash, conflict := range conflicts {
+		evictions[conflictHash] = mp.pool[conflictHash]
+		for hash, txDesc := range mp.txDescendants(conflict, 0) {
+			evictions[hash] = txDesc
+		}
+		if len(evictions) > maxReplacementEvictions {
+			str := fmt.Sprintf("replacement transaction %v would evict "+
+				"more than the maximum allowed %d transactions", txHash,
+				maxReplacementEvictions)
+			return nil, txRuleError(wire.RejectNonstandard, str)
+		}
+	}
+
+	// The replacement may not depend on any of the transactions it evicts
+	// since it would otherwise be invalid once they are removed.
+	for _, txIn := range tx.MsgTx().TxIn {
+		if _, ok := evictions[txIn.PreviousOutPoint.Hash]; ok {
+			str := fmt.Sprintf("replacement transaction %v spends "+
+				"outputs of transaction %v which it replaces", txHash,
+				txIn.PreviousOutPoint.Hash)
+			return nil, txRuleError(wire.RejectInvalid, str)
+		}
+	}
+
+	var evictedFees, evictedSize int64
+	for hash, txDesc := range evictions {
+		if txDesc.Type != stake.TxTypeRegular {
+			str := fmt.Sprintf("replacement transaction %v would evict "+
+				"stake transaction %v", txHash, hash)
+			return nil, txRuleError(wire.RejectDuplicate, str)
+		}
+		evictedFees += txDesc.Fee
+		evictedSize += int64(txDesc.Tx.MsgTx().SerializeSize())
+	}
+
+	// The replacement must pay a higher fee rate than all of the
+	// transactions it evicts combined so that it is more desirable to mine
+	// than all of them together.
+	txSize := int64(tx.MsgTx().SerializeSize())
+	feeRate := float64(txFee) * 1000 / float64(txSize)
+	evictedFeeRate := float64(evictedFees) * 1000 / float64(evictedSize)
+	if feeRate <= evictedFeeRate {
+		str := fmt.Sprintf("replacement transaction %v has a fee rate of "+
+			"%.0f atoms/kB which is not more than the combined %.0f "+
+			"atoms/kB of the %d transactions it replaces", txHash, feeRate,
+			evictedFeeRate, len(evictions))
+		return nil, txRuleError(wire.RejectInsufficientFee, str)
+	}
+
+	// The replacement must also pay for the bandwidth used to relay it on
+	// top of the fees of all of the transactions it evicts.
+	minFee := evictedFees + calcMinRequiredTxRelayFee(txSize,
+		mp.cfg.Policy.MinRelayTxFee)
+	if txFee < minFee {
+		str := fmt.Sprintf("replacement transaction %v has %v fees which "+
+			"is less than the %v fees of the transactions it replaces plus "+
+			"the minimum relay fee of %v", txHash, dcrutil.Amount(txFee),
+			dcrutil.Amount(evictedFees), dcrutil.Amount(minFee-evictedFees))
+		return nil, txRuleError(wire.RejectInsufficientFee, str)
+	}
+
+	return evictions, nil
 }
 
 // checkVoteDoubleSpend checks whether or not the passed vote is for a block
@@ -850,17 +1578,30 @@ func (mp *TxPool) FetchTransaction(txHash *chainhash.Hash) (*dcrutil.Tx, error)
 	return nil, fmt.Errorf("transaction is not in the pool")
 }
 
-// maybeAcceptTransaction is the internal function which implements the public
-// MaybeAcceptTransaction.  See the comment for MaybeAcceptTransaction for
-// more details.
+// txAcceptData houses the data computed while checking whether or not a
+// transaction is allowed into the pool that is needed to add it to the pool.
+type txAcceptData struct {
+	utxoView   *blockchain.UtxoViewpoint
+	txType     stake.TxType
+	bestHeight int64
+	fee        int64
+	conflicts  map[chainhash.Hash]*dcrutil.Tx
+	evictions  map[chainhash.Hash]*TxDesc
+}
+
+// checkTransaction performs all of the checks required to determine whether
+// or not the passed transaction is allowed into the pool without adding it to
+// the pool or removing any transactions from it.  It returns the data needed to
+// add the transaction to the pool when it is allowed or the hashes of the
+// missing parents when the transaction is an orphan.
+//
+// When the isPackage flag is set, the transaction is being checked as part of
+// a package of transactions whose combined fee rate is checked by the caller,
+// so the checks which only depend on the fee of the transaction itself are
+// skipped and the transaction may not replace any transactions in the pool.
 //
 // This function MUST be called with the mempool lock held (for writes).
-// DECRED - TODO
-// We need to make sure thing also assigns the TxType after it evaluates the tx,
-// so that we can easily pick different stake tx types from the mempool later.
-// This should probably be done at the bottom using "IsSStx" etc functions.
-// It should also set the dcrutil tree type for the tx as well.
-func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allowHighFees, rejectDupOrphans bool) ([]*chainhash.Hash, error) {
+func (mp *TxPool) checkTransaction(tx *dcrutil.Tx, isNew, rateLimit, allowHighFees, rejectDupOrphans, isPackage bool) (*txAcceptData, []*chainhash.Hash, error) {
 	msgTx := tx.MsgTx()
 	txHash := tx.Hash()
 	// Don't accept the transaction if it already exists in the pool.  This
@@ -869,7 +1610,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 	// weed out duplicates.
 	if mp.isTransactionInPool(txHash) || (rejectDupOrphans && mp.isOrphanInPool(txHash)) {
 		str := fmt.Sprintf("already have transaction %v", txHash)
-		return nil, txRuleError(wire.RejectDuplicate, str)
+		return nil, nil, txRuleError(wire.RejectDuplicate, str)
 	}
 
 	// Perform preliminary sanity checks on the transaction.  This makes
@@ -878,16 +1619,16 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 	err := blockchain.CheckTransactionSanity(msgTx, mp.cfg.ChainParams)
 	if err != nil {
 		if cerr, ok := err.(blockchain.RuleError); ok {
-			return nil, chainRuleError(cerr)
+			return nil, nil, chainRuleError(cerr)
 		}
-		return nil, err
+		return nil, nil, err
 	}
 
 	// A standalone transaction must not be a coinbase transaction.
 	if blockchain.IsCoinBase(tx) {
 		str := fmt.Sprintf("transaction %v is an individual coinbase",
 			txHash)
-		return nil, txRuleError(wire.RejectInvalid, str)
+		return nil, nil, txRuleError(wire.RejectInvalid, str)
 	}
 
 	// Get the current height of the main chain.  A standalone transaction
@@ -900,7 +1641,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 	if blockchain.IsExpired(tx, nextBlockHeight) {
 		str := fmt.Sprintf("transaction %v expired at height %d",
 			txHash, msgTx.Expiry)
-		return nil, txRuleError(wire.RejectInvalid, str)
+		return nil, nil, txRuleError(wire.RejectInvalid, str)
 	}
 
 	// Determine what type of transaction we're dealing with (regular or stake).
@@ -921,9 +1662,9 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 	acceptSeqLocks, err := mp.cfg.Policy.AcceptSequenceLocks()
 	if err != nil {
 		if cerr, ok := err.(blockchain.RuleError); ok {
-			return nil, chainRuleError(cerr)
+			return nil, nil, chainRuleError(cerr)
 		}
-		return nil, err
+		return nil, nil, err
 	}
 	if !acceptSeqLocks {
 		if msgTx.Version >= 2 && !isVote {
@@ -934,7 +1675,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 				}
 
 				str := "violates sequence lock consensus bug"
-				return nil, txRuleError(wire.RejectInvalid, str)
+				return nil, nil, txRuleError(wire.RejectInvalid, str)
 			}
 		}
 	}
@@ -944,7 +1685,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 	if isVote && nextBlockHeight < stakeValidationHeight {
 		str := fmt.Sprintf("votes are not valid until block height %d (next "+
 			"block height %d)", stakeValidationHeight, nextBlockHeight)
-		return nil, txRuleError(wire.RejectInvalid, str)
+		return nil, nil, txRuleError(wire.RejectInvalid, str)
 	}
 
 	// Reject revocations before they can possibly be valid.  A vote must be
@@ -955,7 +1696,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 	if isRevocation && nextBlockHeight < stakeValidationHeight+1 {
 		str := fmt.Sprintf("revocations are not valid until block height %d "+
 			"(next block height %d)", stakeValidationHeight+1, nextBlockHeight)
-		return nil, txRuleError(wire.RejectInvalid, str)
+		return nil, nil, txRuleError(wire.RejectInvalid, str)
 	}
 
 	// Don't allow non-standard transactions if the mempool config forbids
@@ -975,7 +1716,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 			}
 			str := fmt.Sprintf("transaction %v is not standard: %v",
 				txHash, err)
-			return nil, txRuleError(rejectCode, str)
+			return nil, nil, txRuleError(rejectCode, str)
 		}
 	}
 
@@ -987,14 +1728,14 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 		if err != nil {
 			// This is an unexpected error so don't turn it into a
 			// rule error.
-			return nil, err
+			return nil, nil, err
 		}
 
 		if msgTx.TxOut[0].Value < sDiff {
 			str := fmt.Sprintf("transaction %v has not enough funds "+
 				"to meet stake difficulty (ticket diff %v < next diff %v)",
 				txHash, msgTx.TxOut[0].Value, sDiff)
-			return nil, txRuleError(wire.RejectInsufficientFee, str)
+			return nil, nil, txRuleError(wire.RejectInsufficientFee, str)
 		}
 	}
 
@@ -1007,10 +1748,15 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 	// that happens later after fetching the referenced transaction inputs from
 	// the main chain which examines the actual spend data and prevents double
 	// spends.
+	//
+	// Regular transactions are allowed to conflict with replaceable regular
+	// transactions in the pool.  Those replacements are validated once the
+	// fee of the transaction is known.
+	var conflicts map[chainhash.Hash]*dcrutil.Tx
 	if !isVote && !isRevocation {
-		err = mp.checkPoolDoubleSpend(tx, txType)
+		conflicts, err = mp.checkPoolDoubleSpend(tx, txType)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 	} else if isVote {
@@ -1020,7 +1766,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 		// check to merely reject double spends of tickets is not possible.
 		err := mp.checkVoteDoubleSpend(tx)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		voteAlreadyFound := 0
@@ -1035,7 +1781,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 				str := fmt.Sprintf("transaction %v in the pool with more than "+
 					"%v votes", msgTx.TxIn[1].PreviousOutPoint,
 					maxVoteDoubleSpends)
-				return nil, txRuleError(wire.RejectDuplicate, str)
+				return nil, nil, txRuleError(wire.RejectDuplicate, str)
 			}
 		}
 
@@ -1047,7 +1793,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 					str := fmt.Sprintf("transaction %v in the pool as a "+
 						"revocation. Only one revocation is allowed.",
 						msgTx.TxIn[0].PreviousOutPoint)
-					return nil, txRuleError(wire.RejectDuplicate, str)
+					return nil, nil, txRuleError(wire.RejectDuplicate, str)
 				}
 			}
 		}
@@ -1063,7 +1809,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 				"block height of %v which is before the "+
 				"current cutoff height of %v",
 				tx.Hash(), voteHeight, nextBlockHeight-maximumVoteAgeDelta)
-			return nil, txRuleError(wire.RejectNonstandard, str)
+			return nil, nil, txRuleError(wire.RejectNonstandard, str)
 		}
 	}
 
@@ -1074,16 +1820,16 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 	utxoView, err := mp.fetchInputUtxos(tx)
 	if err != nil {
 		if cerr, ok := err.(blockchain.RuleError); ok {
-			return nil, chainRuleError(cerr)
+			return nil, nil, chainRuleError(cerr)
 		}
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Don't allow the transaction if it exists in the main chain and is not
 	// already fully spent.
 	txEntry := utxoView.LookupEntry(txHash)
 	if txEntry != nil && !txEntry.IsFullySpent() {
-		return nil, txRuleError(wire.RejectDuplicate,
+		return nil, nil, txRuleError(wire.RejectDuplicate,
 			"transaction already exists")
 	}
 	delete(utxoView.Entries(), *txHash)
@@ -1121,7 +1867,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 	}
 
 	if len(missingParents) > 0 {
-		return missingParents, nil
+		return nil, missingParents, nil
 	}
 
 	// Don't allow the transaction into the mempool unless its sequence
@@ -1130,12 +1876,12 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 	seqLock, err := mp.cfg.CalcSequenceLock(tx, utxoView)
 	if err != nil {
 		if cerr, ok := err.(blockchain.RuleError); ok {
-			return nil, chainRuleError(cerr)
+			return nil, nil, chainRuleError(cerr)
 		}
-		return nil, err
+		return nil, nil, err
 	}
 	if !blockchain.SequenceLockActive(seqLock, nextBlockHeight, medianTime) {
-		return nil, txRuleError(wire.RejectNonstandard,
+		return nil, nil, txRuleError(wire.RejectNonstandard,
 			"transaction sequence locks on inputs not met")
 	}
 
@@ -1148,9 +1894,9 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 		tx, nextBlockHeight, utxoView, false, mp.cfg.ChainParams)
 	if err != nil {
 		if cerr, ok := err.(blockchain.RuleError); ok {
-			return nil, chainRuleError(cerr)
+			return nil, nil, chainRuleError(cerr)
 		}
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Don't allow transactions with non-standard inputs if the mempool config
@@ -1167,7 +1913,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 			}
 			str := fmt.Sprintf("transaction %v has a non-standard "+
 				"input: %v", txHash, err)
-			return nil, txRuleError(rejectCode, str)
+			return nil, nil, txRuleError(rejectCode, str)
 		}
 	}
 
@@ -1184,16 +1930,16 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 		(txType == stake.TxTypeSSGen), utxoView)
 	if err != nil {
 		if cerr, ok := err.(blockchain.RuleError); ok {
-			return nil, chainRuleError(cerr)
+			return nil, nil, chainRuleError(cerr)
 		}
-		return nil, err
+		return nil, nil, err
 	}
 
 	numSigOps += blockchain.CountSigOps(tx, false, isVote)
 	if numSigOps > mp.cfg.Policy.MaxSigOpsPerTx {
 		str := fmt.Sprintf("transaction %v has too many sigops: %d > %d",
 			txHash, numSigOps, mp.cfg.Policy.MaxSigOpsPerTx)
-		return nil, txRuleError(wire.RejectNonstandard, str)
+		return nil, nil, txRuleError(wire.RejectNonstandard, str)
 	}
 
 	// Don't allow transactions with fees too low to get into a mined block.
@@ -1211,14 +1957,14 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 	serializedSize := int64(msgTx.SerializeSize())
 	minFee := calcMinRequiredTxRelayFee(serializedSize,
 		mp.cfg.Policy.MinRelayTxFee)
-	if txType == stake.TxTypeRegular { // Non-stake only
+	if txType == stake.TxTypeRegular && !isPackage { // Non-stake only
 		if serializedSize >= (DefaultBlockPrioritySize-1000) &&
 			txFee < minFee {
 
 			str := fmt.Sprintf("transaction %v has %v fees which "+
 				"is under the required amount of %v", txHash,
 				txFee, minFee)
-			return nil, txRuleError(wire.RejectInsufficientFee, str)
+			return nil, nil, txRuleError(wire.RejectInsufficientFee, str)
 		}
 	}
 
@@ -1229,7 +1975,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 	//
 	// This applies to non-stake transactions only.
 	if isNew && !mp.cfg.Policy.DisableRelayPriority && txFee < minFee &&
-		txType == stake.TxTypeRegular {
+		txType == stake.TxTypeRegular && !isPackage {
 
 		currentPriority := mining.CalcPriority(msgTx, utxoView,
 			nextBlockHeight)
@@ -1237,14 +1983,16 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 			str := fmt.Sprintf("transaction %v has insufficient "+
 				"priority (%g <= %g)", txHash,
 				currentPriority, mining.MinHighPriority)
-			return nil, txRuleError(wire.RejectInsufficientFee, str)
+			return nil, nil, txRuleError(wire.RejectInsufficientFee, str)
 		}
 	}
 
 	// Free-to-relay transactions are rate limited here to prevent
 	// penny-flooding with tiny transactions as a form of attack.
 	// This applies to non-stake transactions only.
-	if rateLimit && txFee < minFee && txType == stake.TxTypeRegular {
+	if rateLimit && txFee < minFee && txType == stake.TxTypeRegular &&
+		!isPackage {
+
 		nowUnix := time.Now().Unix()
 		// Decay passed data with an exponentially decaying ~10 minute
 		// window.
@@ -1256,7 +2004,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 		if mp.pennyTotal >= mp.cfg.Policy.FreeTxRelayLimit*10*1000 {
 			str := fmt.Sprintf("transaction %v has been rejected "+
 				"by the rate limiter due to low fees", txHash)
-			return nil, txRuleError(wire.RejectInsufficientFee, str)
+			return nil, nil, txRuleError(wire.RejectInsufficientFee, str)
 		}
 		oldTotal := mp.pennyTotal
 
@@ -1266,6 +2014,25 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 			mp.cfg.Policy.FreeTxRelayLimit*10*1000)
 	}
 
+	// Require regular transactions to pay the dynamic minimum relay fee
+	// which is in effect after transactions were evicted from the pool due
+	// to its size limit.  Unlike the checks above, neither small size nor
+	// high priority exempt a transaction from this fee since otherwise the
+	// evicted transactions could simply be accepted again.
+	if txType == stake.TxTypeRegular && !isPackage {
+		poolMinRelayFee := mp.currentMinRelayFee()
+		if poolMinRelayFee > mp.cfg.Policy.MinRelayTxFee {
+			poolMinFee := calcMinRequiredTxRelayFee(serializedSize,
+				poolMinRelayFee)
+			if txFee < poolMinFee {
+				str := fmt.Sprintf("transaction %v has %v fees which is "+
+					"under the mempool minimum fee of %v", txHash, txFee,
+					poolMinFee)
+				return nil, nil, txRuleError(wire.RejectInsufficientFee, str)
+			}
+		}
+	}
+
 	// Check that tickets also pay the minimum of the relay fee.  This fee is
 	// also performed on regular transactions above, but fees lower than the
 	// miniumum may be allowed when there is sufficient priority, and these
@@ -1277,7 +2044,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 			str := fmt.Sprintf("ticket purchase transaction %v has a %v "+
 				"fee which is under the required threshold amount of %d",
 				txHash, txFee, minTicketFee)
-			return nil, txRuleError(wire.RejectInsufficientFee, str)
+			return nil, nil, txRuleError(wire.RejectInsufficientFee, str)
 		}
 	}
 
@@ -1291,30 +2058,111 @@ func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allow
 			err = fmt.Errorf("transaction %v has %v fee which is above the "+
 				"allowHighFee check threshold amount of %v", txHash,
 				txFee, maxFee)
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
+	// Don't allow transactions which would exceed the ancestor or descendant
+	// limits once added to the pool.
+	if err := mp.checkPackageLimits(tx); err != nil {
+		return nil, nil, err
+	}
+
 	// Verify crypto signatures for each input and reject the transaction if
 	// any don't verify.
 	flags, err := mp.cfg.Policy.StandardVerifyFlags()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	err = blockchain.ValidateTransactionScripts(tx, utxoView, flags,
 		mp.cfg.SigCache)
 	if err != nil {
 		if cerr, ok := err.(blockchain.RuleError); ok {
-			return nil, chainRuleError(cerr)
+			return nil, nil, chainRuleError(cerr)
 		}
-		return nil, err
+		return nil, nil, err
+	}
+
+	// Ensure the transaction is allowed to replace any transactions in the
+	// pool it conflicts with.
+	var evictions map[chainhash.Hash]*TxDesc
+	if len(conflicts) > 0 && isPackage {
+		str := fmt.Sprintf("package transaction %v may not replace "+
+			"transactions in the pool", txHash)
+		return nil, nil, txRuleError(wire.RejectDuplicate, str)
+	}
+	if len(conflicts) > 0 {
+		evictions, err = mp.validateReplacement(tx, txFee, conflicts)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return &txAcceptData{
+		utxoView:   utxoView,
+		txType:     txType,
+		bestHeight: bestHeight,
+		fee:        txFee,
+		conflicts:  conflicts,
+		evictions:  evictions,
+	}, nil, nil
+}
+
+// maybeAcceptTransaction is the internal function which implements the public
+// MaybeAcceptTransaction.  See the comment for MaybeAcceptTransaction for
+// more details.
+//
+// This function MUST be called with the mempool lock held (for writes).
+// DECRED - TODO
+// We need to make sure thing also assigns the TxType after it evaluates the tx,
+// so that we can easily pick different stake tx types from the mempool later.
+// This should probably be done at the bottom using "IsSStx" etc functions.
+// It should also set the dcrutil tree type for the tx as well.
+func (mp *TxPool) maybeAcceptTransaction(tx *dcrutil.Tx, isNew, rateLimit, allowHighFees, rejectDupOrphans bool) ([]*chainhash.Hash, error) {
+	data, missingParents, err := mp.checkTransaction(tx, isNew, rateLimit,
+		allowHighFees, rejectDupOrphans, false)
+	if err != nil || len(missingParents) > 0 {
+		return missingParents, err
+	}
+	txHash := tx.Hash()
+
+	// Reject a replacement that would be evicted right away due to the pool
+	// size limit before removing any of the transactions it replaces so they
+	// are not lost.
+	if len(data.conflicts) > 0 && mp.wouldBeEvicted(tx, data) {
+
+		str := fmt.Sprintf("replacement transaction %v has insufficient "+
+			"fees to be accepted into the full mempool", txHash)
+		return nil, txRuleError(wire.RejectInsufficientFee, str)
+	}
+
+	// Remove the transactions that are replaced by the transaction along
+	// with all transactions which depend on them.
+	for _, conflict := range data.conflicts {
+		log.Debugf("Replacing transaction %v with %v", conflict.Hash(),
+			txHash)
+		mp.removeTransaction(conflict, true, RemovalReasonReplaced)
+	}
+	if len(data.evictions) > 0 {
+		log.Debugf("Evicted %d transactions to accept replacement %v",
+			len(data.evictions), txHash)
 	}
 
 	// Add to transaction pool.
-	mp.addTransaction(utxoView, tx, txType, bestHeight, txFee)
+	mp.addTransaction(data.utxoView, tx, data.txType, data.bestHeight,
+		data.fee)
+
+	// Evict the transactions with the lowest fee rates when the pool exceeds
+	// its maximum size and reject the transaction when it is one of them.
+	mp.limitPoolSize()
+	if !mp.isTransactionInPool(txHash) {
+		str := fmt.Sprintf("transaction %v has insufficient fees to be "+
+			"accepted into the full mempool", txHash)
+		return nil, txRuleError(wire.RejectInsufficientFee, str)
+	}
 
 	// Keep track of vote separately.
-	if isVote {
+	if data.txType == stake.TxTypeSSGen {
 		mp.votesMtx.Lock()
 		err := mp.insertVote(tx)
 		mp.votesMtx.Unlock()
@@ -1453,15 +2301,15 @@ func (mp *TxPool) pruneStakeTx(requiredStakeDifficulty, height int64) {
 		txType := stake.DetermineTxType(tx.Tx.MsgTx())
 		if txType == stake.TxTypeSStx &&
 			tx.Height+int64(heightDiffToPruneTicket) < height {
-			mp.removeTransaction(tx.Tx, true)
+			mp.removeTransaction(tx.Tx, true, RemovalReasonStakePruned)
 		}
 		if txType == stake.TxTypeSStx &&
 			tx.Tx.MsgTx().TxOut[0].Value < requiredStakeDifficulty {
-			mp.removeTransaction(tx.Tx, true)
+			mp.removeTransaction(tx.Tx, true, RemovalReasonStakePruned)
 		}
 		if (txType == stake.TxTypeSSRtx || txType == stake.TxTypeSSGen) &&
 			tx.Height+int64(heightDiffToPruneVotes) < height {
-			mp.removeTransaction(tx.Tx, true)
+			mp.removeTransaction(tx.Tx, true, RemovalReasonStakePruned)
 		}
 	}
 }
@@ -1477,7 +2325,7 @@ func (mp *TxPool) pruneExpiredTx() {
 		if blockchain.IsExpired(tx.Tx, nextBlockHeight) {
 			log.Debugf("Pruning expired transaction %v from the mempool",
 				tx.Tx.Hash())
-			mp.removeTransaction(tx.Tx, true)
+			mp.removeTransaction(tx.Tx, true, RemovalReasonExpired)
 		}
 	}
 }
@@ -1580,6 +2428,318 @@ func (mp *TxPool) ProcessTransaction(tx *dcrutil.Tx, allowOrphan, rateLimit, all
 	return nil, err
 }
 
+// TestAccept performs all of the checks ProcessTransaction performs to
+// determine whether or not the passed transaction would be accepted into the
+// memory pool without actually adding it to the pool, replacing any
+// transactions in the pool, or affecting the rate limiting of free
+// transactions.  It returns the fee the transaction pays when it would be
+// accepted.  Transactions which spend outputs of unknown transactions are
+// rejected since they would not be accepted into the main pool.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) TestAccept(tx *dcrutil.Tx, allowHighFees bool) (int64, error) {
+	fees, errs := mp.TestAcceptTransactions([]*dcrutil.Tx{tx}, allowHighFees)
+	return fees[0], errs[0]
+}
+
+// TestAcceptTransactions performs the same checks as TestAccept for each of
+// the passed transactions in order as if every transaction that would be
+// accepted was added to the pool before checking the next one.  This allows
+// transactions which spend outputs of earlier transactions to be tested
+// together.  The pool itself is left unchanged.
+//
+// It returns the fee each transaction pays when it would be accepted along
+// with the result of testing each transaction in the same order as the passed
+// transactions.  A nil result indicates the transaction would be accepted.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) TestAcceptTransactions(txns []*dcrutil.Tx, allowHighFees bool) ([]int64, []error) {
+	// Protect concurrent access.
+	mp.mtx.Lock()
+	defer mp.mtx.Unlock()
+
+	// Undo all of the changes the transactions that would be accepted made
+	// to the pool once all of them are tested.
+	var changes []txDescChange
+	defer func() {
+		mp.undoTxDescChanges(changes)
+	}()
+
+	fees := make([]int64, len(txns))
+	errs := make([]error, len(txns))
+	for i, tx := range txns {
+		data, missingParents, err := mp.checkTransaction(tx, true, false,
+			allowHighFees, true, false)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		if len(missingParents) > 0 {
+			str := fmt.Sprintf("orphan transaction %v references outputs "+
+				"of unknown or fully-spent transaction %v", tx.Hash(),
+				missingParents[0])
+			errs[i] = txRuleError(wire.RejectDuplicate, str)
+			continue
+		}
+
+		// Reject the transaction when the pool is full and it would be
+		// evicted right away just as ProcessTransaction does.  Replacements
+		// are rejected before removing anything while other transactions
+		// are only evicted after the transactions in front of them.
+		txChanges, accepted := mp.applyAcceptance(tx, data)
+		if !accepted {
+			if len(data.conflicts) > 0 {
+				mp.undoTxDescChanges(txChanges)
+			} else {
+				changes = append(changes, txChanges...)
+			}
+			str := fmt.Sprintf("transaction %v has insufficient fees to be "+
+				"accepted into the full mempool", tx.Hash())
+			errs[i] = txRuleError(wire.RejectInsufficientFee, str)
+			continue
+		}
+		changes = append(changes, txChanges...)
+		fees[i] = data.fee
+	}
+
+	return fees, errs
+}
+
+// ProcessPackage processes the passed package of transactions and accepts as
+// many of them into the memory pool as possible.  The package must be
+// topologically sorted such that every transaction appears after all of the
+// transactions in the package it depends on.
+//
+// Each transaction is first evaluated on its own exactly as it would be by
+// ProcessTransaction.  Regular transactions that are rejected solely due to
+// insufficient fees, along with the transactions in the package that depend
+// on them, are then evaluated together and accepted when the combined fee rate
+// of all of them satisfies the minimum relay fee.  This allows a child to pay
+// the fees for a parent that does not pay enough fees on its own.  The
+// transactions evaluated together are either all accepted or all rejected.
+//
+// It returns a slice of transactions added to the mempool, including any orphan
+// transactions that were accepted as a result, along with the result of
+// processing each transaction in the package in the same order as the
+// package.  A nil result indicates the transaction was accepted or was already
+// in the pool.  An error is returned without processing any of the
+// transactions when the package itself is invalid.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) ProcessPackage(txns []*dcrutil.Tx, rateLimit, allowHighFees bool) ([]*dcrutil.Tx, []error, error) {
+	if len(txns) == 0 || len(txns) > MaxPackageTxns {
+		str := fmt.Sprintf("package has %d transactions which is not "+
+			"between 1 and the maximum allowed %d", len(txns),
+			MaxPackageTxns)
+		return nil, nil, txRuleError(wire.RejectNonstandard, str)
+	}
+
+	// Ensure the package does not contain duplicate transactions and is
+	// topologically sorted.
+	pkgIndices := make(map[chainhash.Hash]int, len(txns))
+	for i, tx := range txns {
+		if _, ok := pkgIndices[*tx.Hash()]; ok {
+			str := fmt.Sprintf("package contains duplicate transaction %v",
+				tx.Hash())
+			return nil, nil, txRuleError(wire.RejectDuplicate, str)
+		}
+		pkgIndices[*tx.Hash()] = i
+	}
+	for i, tx := range txns {
+		for _, txIn := range tx.MsgTx().TxIn {
+			j, ok := pkgIndices[txIn.PreviousOutPoint.Hash]
+			if ok && j > i {
+				str := fmt.Sprintf("package is not sorted -- transaction "+
+					"%v spends outputs of later transaction %v", tx.Hash(),
+					txns[j].Hash())
+				return nil, nil, txRuleError(wire.RejectNonstandard, str)
+			}
+		}
+	}
+
+	// Protect concurrent access.
+	mp.mtx.Lock()
+	defer mp.mtx.Unlock()
+
+	// orphanError returns the error for a transaction in the package that
+	// spends outputs of the passed unknown transaction.
+	orphanError := func(tx *dcrutil.Tx, missingParent *chainhash.Hash) error {
+		str := fmt.Sprintf("orphan transaction %v references outputs of "+
+			"unknown or fully-spent transaction %v", tx.Hash(),
+			missingParent)
+		return txRuleError(wire.RejectDuplicate, str)
+	}
+
+	// Evaluate each transaction on its own.  Transactions that only fail due
+	// to insufficient fees and those which depend on them are deferred so
+	// they can be evaluated together below.
+	txErrs := make([]error, len(txns))
+	alreadyInPool := make(map[chainhash.Hash]struct{})
+	rejected := make(map[chainhash.Hash]struct{})
+	deferred := make(map[chainhash.Hash]struct{})
+	var deferredIndices []int
+	for i, tx := range txns {
+		var rejectedParent *chainhash.Hash
+		var dependsOnDeferred bool
+		for _, txIn := range tx.MsgTx().TxIn {
+			originHash := &txIn.PreviousOutPoint.Hash
+			if _, ok := rejected[*originHash]; ok {
+				rejectedParent = originHash
+				break
+			}
+			if _, ok := deferred[*originHash]; ok {
+				dependsOnDeferred = true
+			}
+		}
+		switch {
+		case rejectedParent != nil:
+			str := fmt.Sprintf("transaction %v depends on rejected "+
+				"package transaction %v", tx.Hash(), rejectedParent)
+			txErrs[i] = txRuleError(wire.RejectInvalid, str)
+			rejected[*tx.Hash()] = struct{}{}
+			continue
+
+		case dependsOnDeferred:
+			deferred[*tx.Hash()] = struct{}{}
+			deferredIndices = append(deferredIndices, i)
+			continue
+
+		case mp.isTransactionInPool(tx.Hash()):
+			alreadyInPool[*tx.Hash()] = struct{}{}
+			continue
+		}
+
+		missingParents, err := mp.maybeAcceptTransaction(tx, true,
+			rateLimit, allowHighFees, true)
+		if err == nil && len(missingParents) > 0 {
+			err = orphanError(tx, missingParents[0])
+		}
+		if err != nil {
+			code, _ := extractRejectCode(err)
+			if code == wire.RejectInsufficientFee &&
+				stake.DetermineTxType(tx.MsgTx()) == stake.TxTypeRegular {
+
+				deferred[*tx.Hash()] = struct{}{}
+				deferredIndices = append(deferredIndices, i)
+				continue
+			}
+			txErrs[i] = err
+			rejected[*tx.Hash()] = struct{}{}
+		}
+	}
+
+	// Evaluate the deferred transactions together against the fee rate of
+	// all of them combined.  Each of them is checked with the earlier ones
+	// temporarily added to the bookkeeping of the pool so their outputs are
+	// available, and none of them are actually added until the combined fee
+	// rate is known to be sufficient.  This ensures nothing is informed about
+	// transactions of a package that is rejected.
+	if len(deferredIndices) > 0 {
+		var changes []txDescChange
+		pkgData := make([]*txAcceptData, 0, len(deferredIndices))
+		var pkgFees, pkgSize int64
+		var pkgErr error
+		failedIdx := -1
+		for _, i := range deferredIndices {
+			tx := txns[i]
+			data, missingParents, err := mp.checkTransaction(tx, true,
+				false, allowHighFees, true, true)
+			if err == nil && len(missingParents) > 0 {
+				err = orphanError(tx, missingParents[0])
+			}
+			if err != nil {
+				pkgErr = err
+				failedIdx = i
+				break
+			}
+			txDesc := &TxDesc{
+				TxDesc: mining.TxDesc{
+					Tx:     tx,
+					Type:   data.txType,
+					Height: data.bestHeight,
+					Fee:    data.fee,
+				},
+			}
+			mp.addTxDesc(txDesc)
+			changes = append(changes, txDescChange{txDesc: txDesc, added: true})
+			pkgData = append(pkgData, data)
+			pkgFees += data.fee
+			pkgSize += int64(tx.MsgTx().SerializeSize())
+		}
+		mp.undoTxDescChanges(changes)
+		if pkgErr == nil {
+			minRelayFee := mp.cfg.Policy.MinRelayTxFee
+			if poolMinRelayFee := mp.currentMinRelayFee(); poolMinRelayFee >
+				minRelayFee {
+
+				minRelayFee = poolMinRelayFee
+			}
+			minFee := calcMinRequiredTxRelayFee(pkgSize, minRelayFee)
+			if pkgFees < minFee {
+				str := fmt.Sprintf("package of %d transactions has %v "+
+					"fees which is under the required amount of %v",
+					len(pkgData), pkgFees, minFee)
+				pkgErr = txRuleError(wire.RejectInsufficientFee, str)
+			}
+		}
+
+		// Reject all of the deferred transactions when any of them are
+		// invalid or they do not pay enough fees together.  Otherwise, add
+		// all of them to the pool.
+		if pkgErr != nil {
+			code, _ := extractRejectCode(pkgErr)
+			for _, i := range deferredIndices {
+				if failedIdx == -1 || i == failedIdx {
+					txErrs[i] = pkgErr
+					continue
+				}
+				str := fmt.Sprintf("package transaction %v was rejected: "+
+					"%v", txns[failedIdx].Hash(), pkgErr)
+				txErrs[i] = txRuleError(code, str)
+			}
+		} else {
+			for j, i := range deferredIndices {
+				data := pkgData[j]
+				mp.addTransaction(data.utxoView, txns[i], data.txType,
+					data.bestHeight, data.fee)
+				log.Debugf("Accepted package transaction %v (pool size: "+
+					"%v)", txns[i].Hash(), len(mp.pool))
+			}
+		}
+	}
+
+	// Evict the transactions with the lowest fee rates when the pool exceeds
+	// its maximum size and reject any of the package transactions that were
+	// evicted.
+	mp.limitPoolSize()
+	var acceptedTxs []*dcrutil.Tx
+	for i, tx := range txns {
+		if txErrs[i] != nil {
+			continue
+		}
+		if _, ok := alreadyInPool[*tx.Hash()]; ok {
+			continue
+		}
+		if !mp.isTransactionInPool(tx.Hash()) {
+			str := fmt.Sprintf("transaction %v has insufficient fees to "+
+				"be accepted into the full mempool", tx.Hash())
+			txErrs[i] = txRuleError(wire.RejectInsufficientFee, str)
+			continue
+		}
+		acceptedTxs = append(acceptedTxs, tx)
+	}
+
+	// Accept any orphan transactions that depend on the accepted package
+	// transactions.
+	var acceptedOrphans []*dcrutil.Tx
+	for _, tx := range acceptedTxs {
+		acceptedOrphans = append(acceptedOrphans, mp.processOrphans(tx)...)
+	}
+	acceptedTxs = append(acceptedTxs, acceptedOrphans...)
+
+	return acceptedTxs, txErrs, nil
+}
+
 // Count returns the number of transactions in the main pool.  It does not
 // include the orphan pool.
 //
@@ -1619,7 +2779,10 @@ func (mp *TxPool) TxDescs() []*TxDesc {
 	descs := make([]*TxDesc, len(mp.pool))
 	i := 0
 	for _, desc := range mp.pool {
-		descs[i] = desc
+		// Copy the descriptor since the package statistics are updated as
+		// related transactions are added to and removed from the pool.
+		descCopy := *desc
+		descs[i] = &descCopy
 		i++
 	}
 	mp.mtx.RUnlock()
@@ -1681,7 +2844,10 @@ func (mp *TxPool) MiningDescs() []*mining.TxDesc {
 	descs := make([]*mining.TxDesc, len(mp.pool))
 	i := 0
 	for _, desc := range mp.pool {
-		descs[i] = &desc.TxDesc
+		// Copy the descriptor since the package statistics are updated as
+		// related transactions are added to and removed from the pool.
+		miningDesc := desc.TxDesc
+		descs[i] = &miningDesc
 		i++
 	}
 	mp.mtx.RUnlock()
@@ -11,6 +11,8 @@ import (
 	"fmt"
 	"reflect"
 	"runtime"
+	"sort"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -506,6 +508,42 @@ func (p *poolHarness) CreateTx(out spendableOutput) (*dcrutil.Tx, error) {
 	return txns[0], err
 }
 
+// SplitOutput creates a transaction which splits the provided output into the
+// provided number of outputs, adds it as a utxo to fake its existence, and
+// returns its outputs.  The test is failed when the transaction can't be
+// created.
+func (p *poolHarness) SplitOutput(t *testing.T, out spendableOutput, numOutputs uint32) []spendableOutput {
+	t.Helper()
+	splitTx, err := p.CreateSignedTx([]spendableOutput{out}, numOutputs)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	p.AddFakeUTXO(splitTx, p.chain.BestHeight())
+	outs := make([]spendableOutput, 0, numOutputs)
+	for i := uint32(0); i < numOutputs; i++ {
+		outs = append(outs, txOutToSpendableOut(splitTx, i,
+			wire.TxTreeRegular))
+	}
+	return outs
+}
+
+// CreateFeeTx creates a transaction spending the provided outputs that
+// generates the provided number of outputs and pays the provided fee out of
+// its first output.  Any munge functions are invoked with the transaction
+// prior to signing it.  The test is failed when the transaction can't be
+// created.
+func (p *poolHarness) CreateFeeTx(t *testing.T, inputs []spendableOutput, numOutputs uint32, fee int64, mungers ...func(*wire.MsgTx)) *dcrutil.Tx {
+	t.Helper()
+	mungers = append([]func(*wire.MsgTx){func(tx *wire.MsgTx) {
+		tx.TxOut[0].Value -= fee
+	}}, mungers...)
+	tx, err := p.CreateSignedTx(inputs, numOutputs, mungers...)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	return tx
+}
+
 // CreateTicketPurchase creates a ticket purchase spending the first output of
 // the provided transaction.
 func (p *poolHarness) CreateTicketPurchase(sourceTx *dcrutil.Tx, cost int64) (*dcrutil.Tx, error) {
@@ -2006,3 +2044,1169 @@ func TestDuplicateVoteRejection(t *testing.T) {
 	}
 	testPoolMembership(tc, dupVote, false, true)
 }
+
+// TestMaxPoolSizeEviction ensures that exceeding the maximum pool size evicts
+// the regular transactions with the lowest fee rates along with their
+// dependents and that the dynamic minimum relay fee raised by the evictions
+// prevents the evicted transactions from being accepted again until it decays.
+func TestMaxPoolSizeEviction(t *testing.T) {
+	t.Parallel()
+
+	harness, spendableOuts, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	tc := &testContext{t, harness}
+
+	// Split the first spendable output provided by the harness into several
+	// outputs.
+	const numOutputs = 5
+	outs := harness.SplitOutput(t, spendableOuts[0], numOutputs)
+
+	// createTx creates a transaction spending the provided output and paying
+	// the provided fee.
+	createTx := func(out spendableOutput, fee int64) *dcrutil.Tx {
+		t.Helper()
+		return harness.CreateFeeTx(t, []spendableOutput{out}, 1, fee)
+	}
+
+	// Create transactions paying increasing fees along with a child of the
+	// lowest fee transaction that pays a high fee.
+	var txns []*dcrutil.Tx
+	for i := 0; i < numOutputs; i++ {
+		txns = append(txns, createTx(outs[i], 500*int64(i+1)))
+	}
+	child := createTx(txOutToSpendableOut(txns[0], 0, wire.TxTreeRegular),
+		5000)
+
+	// Add all but the two highest fee transactions without any size limit.
+	initialTxns := []*dcrutil.Tx{txns[0], child, txns[1], txns[2]}
+	for _, tx := range initialTxns {
+		_, err := harness.txPool.ProcessTransaction(tx, false, false, true)
+		if err != nil {
+			t.Fatalf("ProcessTransaction: failed to accept valid tx: %v",
+				err)
+		}
+		testPoolMembership(tc, tx, false, true)
+	}
+
+	// Limit the pool to its current size and add a higher fee transaction to
+	// force an eviction.  Ensure the lowest fee rate transaction is kept
+	// since its child pays for it, while the transaction with the next lowest
+	// fee rate is evicted instead.
+	harness.txPool.cfg.Policy.MaxPoolSize = harness.txPool.poolSize
+	_, err = harness.txPool.ProcessTransaction(txns[numOutputs-1], false,
+		false, true)
+	if err != nil {
+		t.Fatalf("ProcessTransaction: failed to accept valid tx: %v", err)
+	}
+	testPoolMembership(tc, txns[numOutputs-1], false, true)
+	testPoolMembership(tc, txns[0], false, true)
+	testPoolMembership(tc, child, false, true)
+	testPoolMembership(tc, txns[1], false, false)
+	testPoolMembership(tc, txns[2], false, true)
+
+	// Ensure the evicted transaction is rejected due to the dynamic minimum
+	// relay fee.
+	_, err = harness.txPool.ProcessTransaction(txns[1], false, false, true)
+	if code, _ := extractRejectCode(err); code != wire.RejectInsufficientFee {
+		t.Fatalf("ProcessTransaction: unexpected result for evicted tx -- "+
+			"got %v, want reject code %v", err, wire.RejectInsufficientFee)
+	}
+	testPoolMembership(tc, txns[1], false, false)
+
+	// Ensure a transaction that pays the dynamic minimum relay fee, but has
+	// the lowest fee rate of all transactions in the full pool is rejected.
+	harness.txPool.cfg.Policy.MaxPoolSize = harness.txPool.poolSize
+	minRelayFee := dcrutil.Amount(harness.txPool.rollingMinFee)
+	lowFeeTx := txns[numOutputs-2]
+	lowFeeSize := int64(lowFeeTx.MsgTx().SerializeSize())
+	if minFee := calcMinRequiredTxRelayFee(lowFeeSize, minRelayFee); minFee >
+		harness.txPool.pool[*txns[2].Hash()].Fee {
+
+		t.Fatalf("test setup: dynamic minimum fee %v too high", minFee)
+	}
+	lowFeeTx = createTx(outs[numOutputs-2], calcMinRequiredTxRelayFee(
+		lowFeeSize, minRelayFee)+1)
+	_, err = harness.txPool.ProcessTransaction(lowFeeTx, false, false, true)
+	if code, _ := extractRejectCode(err); code != wire.RejectInsufficientFee {
+		t.Fatalf("ProcessTransaction: unexpected result for low fee tx -- "+
+			"got %v, want reject code %v", err, wire.RejectInsufficientFee)
+	}
+	testPoolMembership(tc, lowFeeTx, false, false)
+	if harness.txPool.poolSize > harness.txPool.cfg.Policy.MaxPoolSize {
+		t.Fatalf("pool size %d exceeds max %d", harness.txPool.poolSize,
+			harness.txPool.cfg.Policy.MaxPoolSize)
+	}
+
+	// Ensure the dynamic minimum relay fee decays back to the configured
+	// minimum relay fee over time such that a transaction which only pays
+	// the configured minimum relay fee is accepted again.
+	harness.txPool.cfg.Policy.MaxPoolSize = 0
+	harness.txPool.lastRollingFeeUpdate = time.Now().Add(
+		-20 * rollingMinFeeHalfLife)
+	minFeeTx := createTx(outs[numOutputs-2], calcMinRequiredTxRelayFee(
+		lowFeeSize+10, harness.txPool.cfg.Policy.MinRelayTxFee))
+	_, err = harness.txPool.ProcessTransaction(minFeeTx, false, false, true)
+	if err != nil {
+		t.Fatalf("ProcessTransaction: failed to accept tx paying the "+
+			"minimum relay fee after decay: %v", err)
+	}
+	testPoolMembership(tc, minFeeTx, false, true)
+	if harness.txPool.rollingMinFee != 0 {
+		t.Fatalf("unexpected dynamic minimum relay fee after decay -- got "+
+			"%v, want 0", harness.txPool.rollingMinFee)
+	}
+}
+
+// TestMaxPoolSizeStakeExemption ensures stake transactions are never evicted
+// to limit the pool size, even when they alone exceed the maximum pool size,
+// while regular transactions still are.
+func TestMaxPoolSizeStakeExemption(t *testing.T) {
+	t.Parallel()
+
+	harness, spendableOuts, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	tc := &testContext{t, harness}
+
+	// Add a ticket purchase that spends an output of a transaction that is
+	// faked to exist in the chain along with a regular transaction.
+	outs := harness.SplitOutput(t, spendableOuts[0], 3)
+	ticketSource := harness.CreateFeeTx(t, outs[2:], 1, 0)
+	harness.AddFakeUTXO(ticketSource, harness.chain.BestHeight())
+	ticket, err := harness.CreateTicketPurchase(ticketSource, 40000)
+	if err != nil {
+		t.Fatalf("unable to create ticket purchase transaction: %v", err)
+	}
+	regular := harness.CreateFeeTx(t, outs[:1], 1, 1000)
+	for _, tx := range []*dcrutil.Tx{ticket, regular} {
+		_, err := harness.txPool.ProcessTransaction(tx, false, false, true)
+		if err != nil {
+			t.Fatalf("ProcessTransaction: failed to accept valid tx: %v",
+				err)
+		}
+		testPoolMembership(tc, tx, false, true)
+	}
+
+	// Limit the pool to less than the size of the ticket purchase alone and
+	// ensure adding another regular transaction evicts all of the regular
+	// transactions while the ticket purchase remains in the pool.
+	ticketSize := int64(ticket.MsgTx().SerializeSize())
+	harness.txPool.cfg.Policy.MaxPoolSize = ticketSize - 1
+	highFee := harness.CreateFeeTx(t, outs[1:2], 1, 5000)
+	_, err = harness.txPool.ProcessTransaction(highFee, false, false, true)
+	if code, _ := extractRejectCode(err); code != wire.RejectInsufficientFee {
+		t.Fatalf("ProcessTransaction: unexpected result for tx in full "+
+			"pool -- got %v, want reject code %v", err,
+			wire.RejectInsufficientFee)
+	}
+	testPoolMembership(tc, highFee, false, false)
+	testPoolMembership(tc, regular, false, false)
+	testPoolMembership(tc, ticket, false, true)
+	if harness.txPool.poolSize != ticketSize {
+		t.Fatalf("unexpected pool size -- got %d, want %d",
+			harness.txPool.poolSize, ticketSize)
+	}
+}
+
+// TestAncestorDescendantStats ensures the pool tracks the aggregate ancestor
+// and descendant statistics of transactions as they are added to and removed
+// from the pool.
+func TestAncestorDescendantStats(t *testing.T) {
+	t.Parallel()
+
+	harness, spendableOuts, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	tc := &testContext{t, harness}
+
+	// createTx creates a transaction spending the provided output, paying
+	// the provided fee, and generating the provided number of outputs.
+	createTx := func(out spendableOutput, numOutputs uint32, fee int64) *dcrutil.Tx {
+		t.Helper()
+		return harness.CreateFeeTx(t, []spendableOutput{out}, numOutputs, fee)
+	}
+
+	// Create the following graph of transactions where the parent has two
+	// children and one of the children has a child of its own:
+	//
+	//   parent -> child1 -> grandchild
+	//          -> child2
+	parent := createTx(spendableOuts[0], 2, 1000)
+	child1 := createTx(txOutToSpendableOut(parent, 0, wire.TxTreeRegular), 1,
+		2000)
+	child2 := createTx(txOutToSpendableOut(parent, 1, wire.TxTreeRegular), 1,
+		4000)
+	grandchild := createTx(txOutToSpendableOut(child1, 0,
+		wire.TxTreeRegular), 1, 3000)
+
+	// Add the transactions out of order so the grandchild is added before
+	// its parent is known to ensure stats are updated when orphans are
+	// processed.
+	for _, tx := range []*dcrutil.Tx{parent, grandchild, child1, child2} {
+		_, err := harness.txPool.ProcessTransaction(tx, true, false, true)
+		if err != nil {
+			t.Fatalf("ProcessTransaction: failed to accept tx: %v", err)
+		}
+	}
+	for _, tx := range []*dcrutil.Tx{parent, child1, child2, grandchild} {
+		testPoolMembership(tc, tx, false, true)
+	}
+
+	size := func(txns ...*dcrutil.Tx) int64 {
+		var total int64
+		for _, tx := range txns {
+			total += int64(tx.MsgTx().SerializeSize())
+		}
+		return total
+	}
+
+	// testStats ensures the ancestor and descendant stats of the provided
+	// transaction match the expected values.
+	testStats := func(name string, tx *dcrutil.Tx, wantAncestors,
+		wantDescendants mining.TxPackageStats) {
+
+		t.Helper()
+		var txDesc *TxDesc
+		for _, desc := range harness.txPool.TxDescs() {
+			if *desc.Tx.Hash() == *tx.Hash() {
+				txDesc = desc
+				break
+			}
+		}
+		if txDesc == nil {
+			t.Fatalf("%s: transaction is not in the pool", name)
+		}
+		if txDesc.Ancestors != wantAncestors {
+			t.Fatalf("%s: unexpected ancestor stats -- got %+v, want %+v",
+				name, txDesc.Ancestors, wantAncestors)
+		}
+		if txDesc.Descendants != wantDescendants {
+			t.Fatalf("%s: unexpected descendant stats -- got %+v, want %+v",
+				name, txDesc.Descendants, wantDescendants)
+		}
+	}
+
+	testStats("parent", parent,
+		mining.TxPackageStats{Count: 1, Size: size(parent), Fees: 1000},
+		mining.TxPackageStats{Count: 4, Size: size(parent, child1, child2,
+			grandchild), Fees: 10000})
+	testStats("child1", child1,
+		mining.TxPackageStats{Count: 2, Size: size(parent, child1),
+			Fees: 3000},
+		mining.TxPackageStats{Count: 2, Size: size(child1, grandchild),
+			Fees: 5000})
+	testStats("child2", child2,
+		mining.TxPackageStats{Count: 2, Size: size(parent, child2),
+			Fees: 5000},
+		mining.TxPackageStats{Count: 1, Size: size(child2), Fees: 4000})
+	testStats("grandchild", grandchild,
+		mining.TxPackageStats{Count: 3, Size: size(parent, child1,
+			grandchild), Fees: 6000},
+		mining.TxPackageStats{Count: 1, Size: size(grandchild), Fees: 3000})
+
+	// Remove the first child along with its dependent and ensure the stats
+	// of the remaining transactions are updated accordingly.
+	harness.txPool.RemoveTransactionWithReason(child1, true, RemovalReasonMined)
+	testPoolMembership(tc, child1, false, false)
+	testPoolMembership(tc, grandchild, false, false)
+	testStats("parent after removal", parent,
+		mining.TxPackageStats{Count: 1, Size: size(parent), Fees: 1000},
+		mining.TxPackageStats{Count: 2, Size: size(parent, child2),
+			Fees: 5000})
+	testStats("child2 after removal", child2,
+		mining.TxPackageStats{Count: 2, Size: size(parent, child2),
+			Fees: 5000},
+		mining.TxPackageStats{Count: 1, Size: size(child2), Fees: 4000})
+}
+
+// TestPackageLimits ensures transactions which would exceed the ancestor or
+// descendant limits of the policy, either themselves or for any related
+// transaction in the pool, are rejected.
+func TestPackageLimits(t *testing.T) {
+	t.Parallel()
+
+	harness, spendableOuts, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	tc := &testContext{t, harness}
+	policy := &harness.txPool.cfg.Policy
+	outs := harness.SplitOutput(t, spendableOuts[0], 2)
+
+	// spendOf returns the spendable output with the passed index of the passed
+	// transaction.
+	spendOf := func(tx *dcrutil.Tx, i uint32) spendableOutput {
+		return txOutToSpendableOut(tx, i, wire.TxTreeRegular)
+	}
+	acceptTx := func(tx *dcrutil.Tx) {
+		t.Helper()
+		_, err := harness.txPool.ProcessTransaction(tx, false, false, true)
+		if err != nil {
+			t.Fatalf("ProcessTransaction: failed to accept tx: %v", err)
+		}
+		testPoolMembership(tc, tx, false, true)
+	}
+	rejectTx := func(tx *dcrutil.Tx) {
+		t.Helper()
+		_, err := harness.txPool.ProcessTransaction(tx, false, false, true)
+		if code, _ := extractRejectCode(err); code != wire.RejectNonstandard {
+			t.Fatalf("ProcessTransaction: unexpected result -- got %v, "+
+				"want reject code %v", err, wire.RejectNonstandard)
+		}
+		testPoolMembership(tc, tx, false, false)
+	}
+	size := func(txns ...*dcrutil.Tx) int64 {
+		var total int64
+		for _, tx := range txns {
+			total += int64(tx.MsgTx().SerializeSize())
+		}
+		return total
+	}
+
+	// Create a chain of three transactions and ensure a fourth transaction
+	// in the chain exceeds the ancestor count limit.
+	policy.MaxAncestorCount = 3
+	policy.MaxDescendantCount = 3
+	chainA := harness.CreateFeeTx(t, outs[:1], 1, 1000)
+	chainB := harness.CreateFeeTx(t, []spendableOutput{spendOf(chainA, 0)}, 1,
+		1000)
+	chainC := harness.CreateFeeTx(t, []spendableOutput{spendOf(chainB, 0)}, 1,
+		1000)
+	for _, tx := range []*dcrutil.Tx{chainA, chainB, chainC} {
+		acceptTx(tx)
+	}
+	rejectTx(harness.CreateFeeTx(t, []spendableOutput{spendOf(chainC, 0)}, 1,
+		1000))
+
+	// Ensure a transaction which is added back to the pool, such as from a
+	// disconnected block, is rejected when the transactions in the pool that
+	// depend on it would exceed the limits.
+	harness.txPool.RemoveTransaction(chainA, false)
+	policy.MaxDescendantCount = 2
+	_, err = harness.txPool.MaybeAcceptTransaction(chainA, false, true)
+	if code, _ := extractRejectCode(err); code != wire.RejectNonstandard {
+		t.Fatalf("MaybeAcceptTransaction: unexpected result -- got %v, "+
+			"want reject code %v", err, wire.RejectNonstandard)
+	}
+	testPoolMembership(tc, chainA, false, false)
+	policy.MaxDescendantCount = 3
+	_, err = harness.txPool.MaybeAcceptTransaction(chainA, false, true)
+	if err != nil {
+		t.Fatalf("MaybeAcceptTransaction: failed to accept tx: %v", err)
+	}
+	testPoolMembership(tc, chainA, false, true)
+
+	// Create a parent with two children and ensure a third child exceeds the
+	// descendant count limit of the parent.
+	parent := harness.CreateFeeTx(t, outs[1:], 3, 1000)
+	child1 := harness.CreateFeeTx(t, []spendableOutput{spendOf(parent, 0)}, 1,
+		1000)
+	child2 := harness.CreateFeeTx(t, []spendableOutput{spendOf(parent, 1)}, 1,
+		1000)
+	for _, tx := range []*dcrutil.Tx{parent, child1, child2} {
+		acceptTx(tx)
+	}
+	child3 := harness.CreateFeeTx(t, []spendableOutput{spendOf(parent, 2)}, 1,
+		1000)
+	rejectTx(child3)
+
+	// Ensure the size limits are enforced the same way.
+	policy.MaxAncestorCount = 0
+	policy.MaxDescendantCount = 0
+	policy.MaxDescendantSize = size(parent, child1, child2, child3) - 1
+	rejectTx(child3)
+	policy.MaxDescendantSize = 0
+	policy.MaxAncestorSize = size(chainA, chainB, chainC) + 1
+	rejectTx(harness.CreateFeeTx(t, []spendableOutput{spendOf(chainC, 0)}, 1,
+		1000))
+	policy.MaxAncestorSize = 0
+	acceptTx(child3)
+}
+
+// TestReplaceByFee ensures that regular transactions which signal
+// replaceability may be replaced by conflicting transactions according to the
+// replacement policy and that all other conflicting transactions are rejected.
+func TestReplaceByFee(t *testing.T) {
+	t.Parallel()
+
+	harness, spendableOuts, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	tc := &testContext{t, harness}
+
+	// Track the transactions that are removed from fee estimation.
+	removedFromFeeEstimation := make(map[chainhash.Hash]struct{})
+	harness.txPool.cfg.RemoveTxFromFeeEstimation = func(txHash *chainhash.Hash) {
+		removedFromFeeEstimation[*txHash] = struct{}{}
+	}
+
+	// Split the first spendable output provided by the harness into several
+	// outputs.
+	const numOutputs = 6
+	outs := harness.SplitOutput(t, spendableOuts[0], numOutputs)
+
+	// createTx creates a transaction spending the provided outputs, paying
+	// the provided fee, and generating the provided number of outputs.  The
+	// transaction signals replaceability when requested.
+	createTx := func(inputs []spendableOutput, numOutputs uint32, fee int64, signal bool) *dcrutil.Tx {
+		t.Helper()
+		return harness.CreateFeeTx(t, inputs, numOutputs, fee,
+			func(tx *wire.MsgTx) {
+				if signal {
+					tx.TxIn[0].Sequence = MaxRBFSequence
+				}
+			})
+	}
+	acceptTx := func(tx *dcrutil.Tx) {
+		t.Helper()
+		_, err := harness.txPool.ProcessTransaction(tx, false, false, true)
+		if err != nil {
+			t.Fatalf("ProcessTransaction: failed to accept tx: %v", err)
+		}
+		testPoolMembership(tc, tx, false, true)
+	}
+	rejectTx := func(tx *dcrutil.Tx, wantCode wire.RejectCode) {
+		t.Helper()
+		_, err := harness.txPool.ProcessTransaction(tx, false, false, true)
+		if code, _ := extractRejectCode(err); code != wantCode {
+			t.Fatalf("ProcessTransaction: unexpected result -- got %v, "+
+				"want reject code %v", err, wantCode)
+		}
+		testPoolMembership(tc, tx, false, false)
+	}
+
+	// Ensure a transaction that does not signal replaceability can't be
+	// replaced even when the replacement pays a higher fee.
+	nonReplaceable := createTx(outs[:1], 1, 1000, false)
+	acceptTx(nonReplaceable)
+	rejectTx(createTx(outs[:1], 1, 10000, true), wire.RejectDuplicate)
+	testPoolMembership(tc, nonReplaceable, false, true)
+
+	// Add a transaction that signals replaceability along with a child that
+	// inherits the replaceability of its parent.
+	original := createTx(outs[1:2], 2, 1000, true)
+	acceptTx(original)
+	child := createTx([]spendableOutput{txOutToSpendableOut(original, 0,
+		wire.TxTreeRegular)}, 1, 1000, false)
+	acceptTx(child)
+
+	// Ensure replacements that don't pay more than the combined fees of the
+	// conflicting transaction and its descendants are rejected.
+	rejectTx(createTx(outs[1:2], 1, 1500, false), wire.RejectInsufficientFee)
+
+	// Ensure replacements that pay a higher absolute fee, but a lower fee
+	// rate than the transactions they replace are rejected.
+	rejectTx(createTx(outs[1:2], 40, 2500, false), wire.RejectInsufficientFee)
+
+	// Ensure replacements that spend the outputs of the transactions they
+	// replace are rejected.
+	rejectTx(createTx([]spendableOutput{outs[1], txOutToSpendableOut(
+		original, 1, wire.TxTreeRegular)}, 1, 10000, false),
+		wire.RejectInvalid)
+
+	// Ensure a replacement of the child on its own is accepted since it
+	// inherits the replaceability of its parent.
+	childReplacement := createTx([]spendableOutput{txOutToSpendableOut(
+		original, 0, wire.TxTreeRegular)}, 1, 2000, false)
+	acceptTx(childReplacement)
+	testPoolMembership(tc, child, false, false)
+	testPoolMembership(tc, original, false, true)
+
+	// Ensure a valid replacement evicts the conflicting transaction along
+	// with its descendants and removes them from fee estimation.
+	replacement := createTx(outs[1:2], 1, 5000, false)
+	acceptTx(replacement)
+	for _, tx := range []*dcrutil.Tx{original, child, childReplacement} {
+		testPoolMembership(tc, tx, false, false)
+	}
+	for _, tx := range []*dcrutil.Tx{original, childReplacement} {
+		if _, ok := removedFromFeeEstimation[*tx.Hash()]; !ok {
+			t.Fatalf("replaced transaction %v was not removed from fee "+
+				"estimation", tx.Hash())
+		}
+	}
+
+	// Ensure replacements which would evict more than the maximum allowed
+	// number of transactions are rejected.
+	chainRoot := createTx(outs[2:3], 1, 1000, true)
+	acceptTx(chainRoot)
+	chainedTxns, err := harness.CreateTxChain(txOutToSpendableOut(chainRoot,
+		0, wire.TxTreeRegular), maxReplacementEvictions)
+	if err != nil {
+		t.Fatalf("unable to create transaction chain: %v", err)
+	}
+	for _, tx := range chainedTxns {
+		acceptTx(tx)
+	}
+	rejectTx(createTx(outs[2:3], 1, 100000, false), wire.RejectNonstandard)
+	testPoolMembership(tc, chainRoot, false, true)
+
+	// Add a transaction that pays a low fee rate along with a child that pays
+	// a high fee rate and another unrelated transaction.
+	lowRate := createTx(outs[3:4], 1, 1000, true)
+	acceptTx(lowRate)
+	highRateChild := createTx([]spendableOutput{txOutToSpendableOut(lowRate,
+		0, wire.TxTreeRegular)}, 1, 20000, false)
+	acceptTx(highRateChild)
+	unrelated := createTx(outs[4:5], 1, 1000, true)
+	acceptTx(unrelated)
+	evicted := []*dcrutil.Tx{lowRate, highRateChild, unrelated}
+	var evictedFees, evictedSize int64
+	for _, tx := range evicted {
+		evictedFees += harness.txPool.pool[*tx.Hash()].Fee
+		evictedSize += int64(tx.MsgTx().SerializeSize())
+	}
+
+	// Ensure a large replacement of the unrelated transaction and the low fee
+	// rate transaction, which also evicts the child of the latter, is
+	// rejected when its fee rate is higher than the fee rates of both of the
+	// transactions it directly conflicts with, but not higher than the
+	// combined fee rate of all of the transactions it evicts, even though it
+	// pays enough fees otherwise.
+	minRelayTxFee := harness.txPool.cfg.Policy.MinRelayTxFee
+	largeSize := int64(createTx(outs[3:5], 80, 0, true).MsgTx().SerializeSize())
+	largeFee := evictedFees + calcMinRequiredTxRelayFee(largeSize,
+		minRelayTxFee) + 1000
+	if largeFee*evictedSize >= evictedFees*largeSize {
+		t.Fatalf("test setup: large replacement fee rate is not lower " +
+			"than the combined fee rate")
+	}
+	rejectTx(createTx(outs[3:5], 80, largeFee, true),
+		wire.RejectInsufficientFee)
+	for _, tx := range evicted {
+		testPoolMembership(tc, tx, false, true)
+	}
+
+	// Ensure a replacement that pays a higher fee rate than all of them
+	// combined is accepted.
+	bothReplacement := createTx(outs[3:5], 1, largeFee, true)
+	acceptTx(bothReplacement)
+	for _, tx := range evicted {
+		testPoolMembership(tc, tx, false, false)
+	}
+
+	// Ensure a replacement that pays more fees and a higher fee rate than
+	// the transaction it replaces is rejected when the additional fees don't
+	// cover the minimum relay fee for its own size.
+	minRelayFee := calcMinRequiredTxRelayFee(int64(
+		bothReplacement.MsgTx().SerializeSize()), minRelayTxFee)
+	rejectTx(createTx(outs[3:5], 1, largeFee+minRelayFee/2, true),
+		wire.RejectInsufficientFee)
+	testPoolMembership(tc, bothReplacement, false, true)
+	finalReplacement := createTx(outs[3:5], 1, largeFee+minRelayFee*2, true)
+	acceptTx(finalReplacement)
+	testPoolMembership(tc, bothReplacement, false, false)
+
+	// Ensure a replacement that would be evicted right away because the pool
+	// is full and it pays the lowest fee rate in the pool is rejected without
+	// removing the transaction it replaces.
+	harness.txPool.RemoveTransaction(nonReplaceable, true)
+	harness.txPool.RemoveTransaction(chainRoot, true)
+	replaceable := createTx(outs[5:6], 1, 1000, true)
+	acceptTx(replaceable)
+	harness.txPool.cfg.Policy.MaxPoolSize = harness.txPool.poolSize
+	lowFeeSize := int64(createTx(outs[5:6], 2, 0, false).MsgTx().SerializeSize())
+	lowFee := 1500 + calcMinRequiredTxRelayFee(lowFeeSize, minRelayTxFee)
+	lowFeeTx := createTx(outs[5:6], 2, lowFee, false)
+	if evictionLess(harness.txPool.pool[*replacement.Hash()],
+		&TxDesc{TxDesc: mining.TxDesc{Tx: lowFeeTx, Fee: lowFee}}) {
+
+		t.Fatalf("test setup: replacement does not pay the lowest fee rate")
+	}
+	rejectTx(lowFeeTx, wire.RejectInsufficientFee)
+	testPoolMembership(tc, replaceable, false, true)
+	for _, tx := range []*dcrutil.Tx{replacement, finalReplacement} {
+		testPoolMembership(tc, tx, false, true)
+	}
+}
+
+// TestProcessPackage ensures that packages of transactions are accepted when
+// the combined fee rate of the transactions which don't pay enough fees on
+// their own satisfies the minimum relay fee and that invalid packages are
+// rejected.
+func TestProcessPackage(t *testing.T) {
+	t.Parallel()
+
+	harness, spendableOuts, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	tc := &testContext{t, harness}
+
+	// Split the first spendable output provided by the harness into several
+	// outputs.
+	const numOutputs = 4
+	outs := harness.SplitOutput(t, spendableOuts[0], numOutputs)
+
+	// createTx creates a transaction spending the provided output and paying
+	// the provided fee.
+	createTx := func(out spendableOutput, fee int64) *dcrutil.Tx {
+		t.Helper()
+		return harness.CreateFeeTx(t, []spendableOutput{out}, 1, fee)
+	}
+	spendOf := func(tx *dcrutil.Tx) spendableOutput {
+		return txOutToSpendableOut(tx, 0, wire.TxTreeRegular)
+	}
+
+	// Raise the dynamic minimum relay fee so that transactions which do not
+	// pay any fees are rejected on their own.
+	const minRelayFee = 10000
+	harness.txPool.rollingMinFee = minRelayFee
+	harness.txPool.lastRollingFeeUpdate = time.Now()
+
+	// Ensure a zero fee parent is rejected on its own.
+	parent := createTx(outs[0], 0)
+	_, err = harness.txPool.ProcessTransaction(parent, false, false, true)
+	if code, _ := extractRejectCode(err); code != wire.RejectInsufficientFee {
+		t.Fatalf("ProcessTransaction: unexpected result for zero fee tx -- "+
+			"got %v, want reject code %v", err, wire.RejectInsufficientFee)
+	}
+
+	// Ensure packages which are empty, too large, contain duplicates, or
+	// are not sorted are rejected without processing any transactions.
+	child := createTx(spendOf(parent), 5000)
+	tooLarge := make([]*dcrutil.Tx, MaxPackageTxns+1)
+	for i := range tooLarge {
+		tooLarge[i] = parent
+	}
+	invalidPkgs := [][]*dcrutil.Tx{
+		nil,
+		tooLarge,
+		{parent, parent},
+		{child, parent},
+	}
+	for i, pkg := range invalidPkgs {
+		_, _, err := harness.txPool.ProcessPackage(pkg, false, true)
+		if _, ok := err.(RuleError); !ok {
+			t.Fatalf("ProcessPackage #%d: unexpected error -- got %v, "+
+				"want RuleError", i, err)
+		}
+		testPoolMembership(tc, parent, false, false)
+		testPoolMembership(tc, child, false, false)
+	}
+
+	// processPackage processes the passed package and ensures the result of
+	// each transaction has the expected reject code, where a code of zero
+	// indicates the transaction is expected to be accepted.  Transactions
+	// which are already in the pool are not expected to be reported as newly
+	// accepted.
+	processPackage := func(pkg []*dcrutil.Tx, wantCodes []wire.RejectCode) {
+		t.Helper()
+		var numAccepted int
+		for i, tx := range pkg {
+			if wantCodes[i] == 0 && !harness.txPool.IsTransactionInPool(
+				tx.Hash()) {
+
+				numAccepted++
+			}
+		}
+		acceptedTxs, txErrs, err := harness.txPool.ProcessPackage(pkg,
+			false, true)
+		if err != nil {
+			t.Fatalf("ProcessPackage: unexpected error: %v", err)
+		}
+		for i, tx := range pkg {
+			if wantCodes[i] == 0 {
+				if txErrs[i] != nil {
+					t.Fatalf("ProcessPackage: tx %d rejected: %v", i,
+						txErrs[i])
+				}
+				testPoolMembership(tc, tx, false, true)
+				continue
+			}
+			code, _ := extractRejectCode(txErrs[i])
+			if code != wantCodes[i] {
+				t.Fatalf("ProcessPackage: unexpected result for tx %d -- "+
+					"got %v, want reject code %v", i, txErrs[i],
+					wantCodes[i])
+			}
+			testPoolMembership(tc, tx, false, false)
+		}
+		if len(acceptedTxs) != numAccepted {
+			t.Fatalf("ProcessPackage: unexpected number of accepted txns "+
+				"-- got %d, want %d", len(acceptedTxs), numAccepted)
+		}
+	}
+
+	// Record all events emitted by the pool.
+	var events []Event
+	harness.txPool.Subscribe(func(event *Event) {
+		events = append(events, *event)
+	})
+
+	// Ensure a package with a child which does not pay enough fees for
+	// both transactions is rejected without informing the subscribers of the
+	// pool about any of them.
+	lowFeeChild := createTx(spendOf(parent), 100)
+	processPackage([]*dcrutil.Tx{parent, lowFeeChild},
+		[]wire.RejectCode{wire.RejectInsufficientFee,
+			wire.RejectInsufficientFee})
+	if len(events) != 0 {
+		t.Fatalf("ProcessPackage: unexpected events for rejected package "+
+			"-- got %d, want 0", len(events))
+	}
+
+	// Ensure a package with a child which pays enough fees for both
+	// transactions is accepted along with an unrelated transaction that
+	// pays enough fees on its own.
+	unrelated := createTx(outs[1], 5000)
+	processPackage([]*dcrutil.Tx{parent, unrelated, child},
+		[]wire.RejectCode{0, 0, 0})
+
+	// Ensure transactions which depend on a rejected package transaction
+	// are rejected while those that are already in the pool are not.
+	invalidTx := createTx(outs[2], 5000)
+	invalidTx.MsgTx().TxIn[0].SignatureScript = nil
+	invalidTx = dcrutil.NewTx(invalidTx.MsgTx())
+	invalidChild := createTx(spendOf(invalidTx), 5000)
+	processPackage([]*dcrutil.Tx{parent, invalidTx, invalidChild},
+		[]wire.RejectCode{0, wire.RejectInvalid, wire.RejectInvalid})
+
+	// Ensure a package accepted into a full pool is not evicted right away
+	// due to the low fee rate of the parent when the combined fee rate of
+	// the package is higher than that of the other transactions in the pool,
+	// which are evicted instead.  The pool is limited such that only one of
+	// the transactions needs to be evicted.
+	harness.txPool.RemoveTransaction(parent, true)
+	fullPoolParent := createTx(outs[3], 0)
+	fullPoolChild := createTx(spendOf(fullPoolParent), 12000)
+	harness.txPool.cfg.Policy.MaxPoolSize = harness.txPool.poolSize +
+		int64(fullPoolParent.MsgTx().SerializeSize())
+	processPackage([]*dcrutil.Tx{fullPoolParent, fullPoolChild},
+		[]wire.RejectCode{0, 0})
+	testPoolMembership(tc, unrelated, false, false)
+}
+
+// TestTestAccept ensures that testing whether or not transactions would be
+// accepted into the pool reports the same results as processing them without
+// modifying the pool.
+func TestTestAccept(t *testing.T) {
+	t.Parallel()
+
+	harness, spendableOuts, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	tc := &testContext{t, harness}
+
+	// Split the first spendable output provided by the harness into several
+	// outputs.
+	const numOutputs = 3
+	outs := harness.SplitOutput(t, spendableOuts[0], numOutputs)
+
+	// createTx creates a transaction spending the provided output and paying
+	// the provided fee after applying the provided munge functions.
+	createTx := func(out spendableOutput, fee int64, mungers ...func(*wire.MsgTx)) *dcrutil.Tx {
+		t.Helper()
+		return harness.CreateFeeTx(t, []spendableOutput{out}, 1, fee,
+			mungers...)
+	}
+
+	// Ensure a valid transaction is reported as accepted along with its fee
+	// without being added to the pool.
+	const fee = 5000
+	tx := createTx(outs[0], fee)
+	gotFee, err := harness.txPool.TestAccept(tx, false)
+	if err != nil {
+		t.Fatalf("TestAccept: unexpected error for valid tx: %v", err)
+	}
+	if gotFee != fee {
+		t.Fatalf("TestAccept: unexpected fee -- got %d, want %d", gotFee,
+			fee)
+	}
+	testPoolMembership(tc, tx, false, false)
+
+	// Ensure an orphan is rejected without being added to the orphan pool.
+	orphan := createTx(txOutToSpendableOut(tx, 0, wire.TxTreeRegular), fee)
+	_, err = harness.txPool.TestAccept(orphan, false)
+	if code, _ := extractRejectCode(err); code != wire.RejectDuplicate {
+		t.Fatalf("TestAccept: unexpected result for orphan -- got %v, want "+
+			"reject code %v", err, wire.RejectDuplicate)
+	}
+	testPoolMembership(tc, orphan, false, false)
+
+	// Ensure a transaction that is already in the pool is rejected.
+	_, err = harness.txPool.ProcessTransaction(tx, false, false, true)
+	if err != nil {
+		t.Fatalf("ProcessTransaction: failed to accept valid tx: %v", err)
+	}
+	_, err = harness.txPool.TestAccept(tx, false)
+	if code, _ := extractRejectCode(err); code != wire.RejectDuplicate {
+		t.Fatalf("TestAccept: unexpected result for duplicate tx -- got "+
+			"%v, want reject code %v", err, wire.RejectDuplicate)
+	}
+
+	// Ensure non-standard transactions, transactions with invalid scripts,
+	// and transactions that do not pay the dynamic minimum relay fee are
+	// rejected with the appropriate reject codes.
+	nonStandard := createTx(outs[1], fee, func(tx *wire.MsgTx) {
+		tx.Version = harness.txPool.cfg.Policy.MaxTxVersion + 1
+	})
+	badScript := createTx(outs[1], fee)
+	badScript.MsgTx().TxIn[0].SignatureScript = nil
+	badScript = dcrutil.NewTx(badScript.MsgTx())
+	lowFee := createTx(outs[2], 1000)
+	tests := []struct {
+		name        string
+		tx          *dcrutil.Tx
+		minRelayFee float64
+		wantCode    wire.RejectCode
+	}{
+		{"non-standard", nonStandard, 0, wire.RejectNonstandard},
+		{"invalid script", badScript, 0, wire.RejectInvalid},
+		{"low fee", lowFee, 1e6, wire.RejectInsufficientFee},
+	}
+	for _, test := range tests {
+		harness.txPool.rollingMinFee = test.minRelayFee
+		harness.txPool.lastRollingFeeUpdate = time.Now()
+		_, err := harness.txPool.TestAccept(test.tx, false)
+		if code, _ := extractRejectCode(err); code != test.wantCode {
+			t.Fatalf("TestAccept (%s): unexpected result -- got %v, want "+
+				"reject code %v", test.name, err, test.wantCode)
+		}
+		testPoolMembership(tc, test.tx, false, false)
+	}
+	harness.txPool.rollingMinFee = 0
+
+	// Ensure a replacement is reported as accepted without evicting the
+	// transaction it replaces.
+	original := createTx(outs[1], fee, func(tx *wire.MsgTx) {
+		tx.TxIn[0].Sequence = MaxRBFSequence
+	})
+	_, err = harness.txPool.ProcessTransaction(original, false, false, true)
+	if err != nil {
+		t.Fatalf("ProcessTransaction: failed to accept valid tx: %v", err)
+	}
+	replacement := createTx(outs[1], 4*fee)
+	gotFee, err = harness.txPool.TestAccept(replacement, false)
+	if err != nil {
+		t.Fatalf("TestAccept: unexpected error for replacement: %v", err)
+	}
+	if gotFee != 4*fee {
+		t.Fatalf("TestAccept: unexpected replacement fee -- got %d, want "+
+			"%d", gotFee, 4*fee)
+	}
+	testPoolMembership(tc, original, false, true)
+	testPoolMembership(tc, replacement, false, false)
+
+	// Ensure a transaction that would be evicted right away because it has
+	// the lowest fee rate in the full pool is rejected the same way it is
+	// when processed, while a transaction with a higher fee rate is
+	// reported as accepted without evicting anything.
+	harness.txPool.cfg.Policy.MaxPoolSize = harness.txPool.poolSize
+	_, err = harness.txPool.TestAccept(lowFee, false)
+	if code, _ := extractRejectCode(err); code != wire.RejectInsufficientFee {
+		t.Fatalf("TestAccept: unexpected result for tx evicted from full "+
+			"pool -- got %v, want reject code %v", err,
+			wire.RejectInsufficientFee)
+	}
+	highFee := createTx(outs[2], 4*fee)
+	if _, err := harness.txPool.TestAccept(highFee, false); err != nil {
+		t.Fatalf("TestAccept: unexpected error for tx accepted into full "+
+			"pool: %v", err)
+	}
+	testPoolMembership(tc, tx, false, true)
+	testPoolMembership(tc, original, false, true)
+	testPoolMembership(tc, highFee, false, false)
+	_, err = harness.txPool.ProcessTransaction(lowFee, false, false, true)
+	if code, _ := extractRejectCode(err); code != wire.RejectInsufficientFee {
+		t.Fatalf("ProcessTransaction: unexpected result for tx evicted "+
+			"from full pool -- got %v, want reject code %v", err,
+			wire.RejectInsufficientFee)
+	}
+	testPoolMembership(tc, lowFee, false, false)
+	harness.txPool.cfg.Policy.MaxPoolSize = 0
+
+	// Ensure a batch is tested in order such that a transaction spending an
+	// output of an earlier transaction in the batch is reported as accepted
+	// instead of as an orphan, while a transaction spending an output of a
+	// transaction that is rejected is still reported as an orphan.  None of
+	// them may be added to the pool.
+	parent := createTx(outs[2], fee)
+	child := createTx(txOutToSpendableOut(parent, 0, wire.TxTreeRegular), fee)
+	rejected := createTx(txOutToSpendableOut(child, 0, wire.TxTreeRegular),
+		fee, func(tx *wire.MsgTx) {
+			tx.Version = harness.txPool.cfg.Policy.MaxTxVersion + 1
+		})
+	rejectedChild := createTx(txOutToSpendableOut(rejected, 0,
+		wire.TxTreeRegular), fee)
+	batch := []*dcrutil.Tx{parent, child, rejected, rejectedChild}
+	wantCodes := []wire.RejectCode{0, 0, wire.RejectNonstandard,
+		wire.RejectDuplicate}
+	fees, errs := harness.txPool.TestAcceptTransactions(batch, false)
+	for i, tx := range batch {
+		if wantCodes[i] == 0 {
+			if errs[i] != nil {
+				t.Fatalf("TestAcceptTransactions: unexpected error for "+
+					"tx %d: %v", i, errs[i])
+			}
+			if fees[i] != fee {
+				t.Fatalf("TestAcceptTransactions: unexpected fee for tx "+
+					"%d -- got %d, want %d", i, fees[i], fee)
+			}
+		} else if code, _ := extractRejectCode(errs[i]); code != wantCodes[i] {
+			t.Fatalf("TestAcceptTransactions: unexpected result for tx "+
+				"%d -- got %v, want reject code %v", i, errs[i],
+				wantCodes[i])
+		}
+		testPoolMembership(tc, tx, false, false)
+	}
+
+	// Ensure the child alone is still reported as an orphan since the
+	// parent was not added to the pool.
+	_, err = harness.txPool.TestAccept(child, false)
+	if code, _ := extractRejectCode(err); code != wire.RejectDuplicate {
+		t.Fatalf("TestAccept: unexpected result for orphan -- got %v, want "+
+			"reject code %v", err, wire.RejectDuplicate)
+	}
+}
+
+// TestWouldBeEvicted ensures that checking whether a transaction would be
+// evicted from the full pool right away agrees with the evictions that happen
+// when the transaction is actually processed and that the check leaves the
+// pool unchanged.
+func TestWouldBeEvicted(t *testing.T) {
+	t.Parallel()
+
+	harness, spendableOuts, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	tc := &testContext{t, harness}
+	const numOutputs = 8
+	outs := harness.SplitOutput(t, spendableOuts[0], numOutputs)
+
+	// Fill the pool with a parent and child whose combined fee rate is the
+	// lowest in the pool along with transactions paying increasing fees, some
+	// of which signal replaceability.
+	spendOf := func(tx *dcrutil.Tx) spendableOutput {
+		return txOutToSpendableOut(tx, 0, wire.TxTreeRegular)
+	}
+	signal := func(tx *wire.MsgTx) { tx.TxIn[0].Sequence = MaxRBFSequence }
+	parent := harness.CreateFeeTx(t, outs[:1], 1, 500)
+	txns := []*dcrutil.Tx{
+		parent,
+		harness.CreateFeeTx(t, []spendableOutput{spendOf(parent)}, 1, 3000),
+		harness.CreateFeeTx(t, outs[1:2], 1, 2000, signal),
+		harness.CreateFeeTx(t, outs[2:3], 1, 4000),
+		harness.CreateFeeTx(t, outs[3:4], 1, 6000, signal),
+	}
+	for _, tx := range txns {
+		_, err := harness.txPool.ProcessTransaction(tx, false, false, true)
+		if err != nil {
+			t.Fatalf("ProcessTransaction: failed to accept tx: %v", err)
+		}
+	}
+
+	// poolState returns a description of the bookkeeping of the pool which
+	// must not be changed by checking whether a transaction would be evicted.
+	poolState := func() string {
+		mp := harness.txPool
+		state := fmt.Sprintf("size %d, outpoints %d, queue %d, front %v",
+			mp.poolSize, len(mp.outpoints), len(mp.evictionQueue),
+			mp.evictionQueue.front().Tx.Hash())
+		descs := make([]string, 0, len(mp.pool))
+		for hash, txDesc := range mp.pool {
+			descs = append(descs, fmt.Sprintf("%v %+v %+v", hash,
+				txDesc.Ancestors, txDesc.Descendants))
+		}
+		sort.Strings(descs)
+		return state + ", " + strings.Join(descs, ", ")
+	}
+
+	// Ensure the result of TestAccept matches the result of processing the
+	// transaction for transactions which are evicted right away as well as
+	// for transactions which evict others, including replacements.  The pool
+	// is limited to its current size before each transaction.
+	tests := []struct {
+		name    string
+		tx      *dcrutil.Tx
+		evicted bool
+	}{{
+		name:    "lowest fee rate",
+		tx:      harness.CreateFeeTx(t, outs[4:5], 1, 250),
+		evicted: true,
+	}, {
+		name:    "evicts parent and child",
+		tx:      harness.CreateFeeTx(t, outs[5:6], 1, 5000),
+		evicted: false,
+	}, {
+		name:    "replacement with lowest fee rate",
+		tx:      harness.CreateFeeTx(t, outs[1:2], 3, 5000),
+		evicted: true,
+	}, {
+		name:    "replacement with highest fee rate",
+		tx:      harness.CreateFeeTx(t, outs[3:4], 2, 12000),
+		evicted: false,
+	}, {
+		name:    "evicts lowest fee rate",
+		tx:      harness.CreateFeeTx(t, outs[6:7], 1, 8000),
+		evicted: false,
+	}}
+	for _, test := range tests {
+		harness.txPool.cfg.Policy.MaxPoolSize = harness.txPool.poolSize
+		before := poolState()
+		_, err := harness.txPool.TestAccept(test.tx, false)
+		if after := poolState(); after != before {
+			t.Fatalf("%s: TestAccept changed the pool -- got %s, want %s",
+				test.name, after, before)
+		}
+		testEvicted := err != nil
+		if err != nil {
+			code, _ := extractRejectCode(err)
+			if code != wire.RejectInsufficientFee {
+				t.Fatalf("%s: TestAccept: unexpected error: %v", test.name,
+					err)
+			}
+		}
+		_, err = harness.txPool.ProcessTransaction(test.tx, false, false,
+			true)
+		processEvicted := err != nil
+		if err != nil {
+			code, _ := extractRejectCode(err)
+			if code != wire.RejectInsufficientFee {
+				t.Fatalf("%s: ProcessTransaction: unexpected error: %v",
+					test.name, err)
+			}
+		}
+		if testEvicted != test.evicted || processEvicted != test.evicted {
+			t.Fatalf("%s: unexpected eviction -- TestAccept %v, "+
+				"ProcessTransaction %v, want %v", test.name, testEvicted,
+				processEvicted, test.evicted)
+		}
+		testPoolMembership(tc, test.tx, false, !test.evicted)
+	}
+}
+
+// TestEvents ensures the events emitted by the pool to its subscribers when
+// transactions are added to and removed from the main pool are accurate.
+func TestEvents(t *testing.T) {
+	t.Parallel()
+
+	harness, spendableOuts, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+
+	// Record all events emitted by the pool.
+	var events []Event
+	harness.txPool.Subscribe(func(event *Event) {
+		events = append(events, *event)
+	})
+
+	// Split the first spendable output provided by the harness into several
+	// outputs.
+	const numOutputs = 4
+	outs := harness.SplitOutput(t, spendableOuts[0], numOutputs)
+
+	createTx := func(inputs []spendableOutput, fee int64, mungers ...func(*wire.MsgTx)) *dcrutil.Tx {
+		t.Helper()
+		return harness.CreateFeeTx(t, inputs, 1, fee, mungers...)
+	}
+	acceptTx := func(tx *dcrutil.Tx) {
+		t.Helper()
+		_, err := harness.txPool.ProcessTransaction(tx, false, false, true)
+		if err != nil {
+			t.Fatalf("ProcessTransaction: failed to accept tx: %v", err)
+		}
+	}
+
+	// checkEvents ensures the events emitted since the last check match the
+	// provided events in order.
+	type wantEvent struct {
+		typ    EventType
+		tx     *dcrutil.Tx
+		reason RemovalReason
+	}
+	checkEvents := func(desc string, want ...wantEvent) {
+		t.Helper()
+		if len(events) != len(want) {
+			t.Fatalf("%s: unexpected number of events -- got %d, want %d",
+				desc, len(events), len(want))
+		}
+		for i, event := range events {
+			if event.Type != want[i].typ {
+				t.Fatalf("%s: unexpected type for event %d -- got %v, "+
+					"want %v", desc, i, event.Type, want[i].typ)
+			}
+			if *event.Tx.Hash() != *want[i].tx.Hash() {
+				t.Fatalf("%s: unexpected tx for event %d -- got %v, "+
+					"want %v", desc, i, event.Tx.Hash(),
+					want[i].tx.Hash())
+			}
+			if event.Type == EventTxRemoved &&
+				event.Reason != want[i].reason {
+
+				t.Fatalf("%s: unexpected reason for event %d -- got %v, "+
+					"want %v", desc, i, event.Reason, want[i].reason)
+			}
+		}
+		events = events[:0]
+	}
+
+	// Ensure adding a transaction along with a child emits an added event
+	// for each of them along with their fees.
+	parent := createTx(outs[:1], 1000, func(tx *wire.MsgTx) {
+		tx.TxIn[0].Sequence = MaxRBFSequence
+	})
+	acceptTx(parent)
+	child := createTx([]spendableOutput{txOutToSpendableOut(parent, 0,
+		wire.TxTreeRegular)}, 1000)
+	acceptTx(child)
+	if len(events) > 0 && events[0].Fee != 1000 {
+		t.Fatalf("unexpected fee for added event -- got %d, want 1000",
+			events[0].Fee)
+	}
+	checkEvents("add", wantEvent{typ: EventTxAdded, tx: parent},
+		wantEvent{typ: EventTxAdded, tx: child})
+
+	// Ensure replacing the parent emits removed events for it and its child
+	// with the replaced reason followed by an added event for the
+	// replacement.
+	replacement := createTx(outs[:1], 10000)
+	acceptTx(replacement)
+	checkEvents("replace",
+		wantEvent{typ: EventTxRemoved, tx: child, reason: RemovalReasonReplaced},
+		wantEvent{typ: EventTxRemoved, tx: parent, reason: RemovalReasonReplaced},
+		wantEvent{typ: EventTxAdded, tx: replacement})
+
+	// Ensure a transaction that expires emits a removed event with the
+	// expired reason.
+	nextBlockHeight := harness.chain.BestHeight() + 1
+	expiring := createTx(outs[1:2], 1000, func(tx *wire.MsgTx) {
+		tx.Expiry = uint32(nextBlockHeight + 1)
+	})
+	acceptTx(expiring)
+	harness.chain.SetHeight(harness.chain.BestHeight() + 1)
+	harness.txPool.PruneExpiredTx()
+	checkEvents("expire", wantEvent{typ: EventTxAdded, tx: expiring},
+		wantEvent{typ: EventTxRemoved, tx: expiring,
+			reason: RemovalReasonExpired})
+
+	// Ensure a transaction that is mined emits a removed event with the
+	// mined reason.
+	mined := createTx(outs[2:3], 1000)
+	acceptTx(mined)
+	harness.txPool.RemoveTransactionWithReason(mined, false, RemovalReasonMined)
+	checkEvents("mined", wantEvent{typ: EventTxAdded, tx: mined},
+		wantEvent{typ: EventTxRemoved, tx: mined, reason: RemovalReasonMined})
+
+	// Ensure a transaction that is double spent by a mined transaction emits
+	// a removed event with the conflict reason.
+	conflicted := createTx(outs[3:4], 1000)
+	acceptTx(conflicted)
+	harness.txPool.RemoveDoubleSpends(createTx(outs[3:4], 2000))
+	checkEvents("conflict", wantEvent{typ: EventTxAdded, tx: conflicted},
+		wantEvent{typ: EventTxRemoved, tx: conflicted,
+			reason: RemovalReasonConflict})
+
+	// Ensure removing a transaction that is not in the pool does not emit
+	// any events.
+	harness.txPool.RemoveTransactionWithReason(mined, true, RemovalReasonMined)
+	checkEvents("remove unknown")
+}
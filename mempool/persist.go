@@ -0,0 +1,160 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/decred/dcrd/blockchain/stake"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/wire"
+)
+
+const (
+	// persistVersion is the current version of the serialized format used
+	// to save the transactions in the pool.
+	persistVersion = 1
+)
+
+// The transactions in the pool are serialized in the following format:
+//
+//   <version><num txns><entry 1><entry 2>...<entry N>
+//
+//   Field          Type       Size
+//   version        uint32     4 bytes
+//   num txns       uint32     4 bytes
+//   entries        []entry    variable
+//
+// Each entry is serialized as:
+//
+//   Field          Type       Size
+//   time added     int64      8 bytes (unix seconds)
+//   height         int64      8 bytes
+//   tx type        uint8      1 byte
+//   transaction    wire.MsgTx variable
+//
+// All integers are encoded in little endian.  The entries are ordered such
+// that every transaction appears after all of the transactions in the pool it
+// depends on so they can be accepted back into the pool in order.
+
+// Save serializes all of the transactions in the main pool, along with the
+// time they were added to the pool, the block height at that time, and their
+// stake transaction type, to the passed writer using a versioned format that
+// can later be loaded by Load.  It returns the number of saved transactions.
+// Orphan transactions are not saved.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) Save(w io.Writer) (int, error) {
+	// Order the transactions by their number of ancestors in the pool so
+	// that every transaction is saved after the transactions it depends on.
+	descs := mp.TxDescs()
+	sort.Slice(descs, func(i, j int) bool {
+		return descs[i].Ancestors.Count < descs[j].Ancestors.Count
+	})
+
+	var buf [8]byte
+	binary.LittleEndian.PutUint32(buf[:4], persistVersion)
+	if _, err := w.Write(buf[:4]); err != nil {
+		return 0, err
+	}
+	binary.LittleEndian.PutUint32(buf[:4], uint32(len(descs)))
+	if _, err := w.Write(buf[:4]); err != nil {
+		return 0, err
+	}
+	for _, desc := range descs {
+		binary.LittleEndian.PutUint64(buf[:], uint64(desc.Added.Unix()))
+		if _, err := w.Write(buf[:]); err != nil {
+			return 0, err
+		}
+		binary.LittleEndian.PutUint64(buf[:], uint64(desc.Height))
+		if _, err := w.Write(buf[:]); err != nil {
+			return 0, err
+		}
+		if _, err := w.Write([]byte{byte(desc.Type)}); err != nil {
+			return 0, err
+		}
+		if err := desc.Tx.MsgTx().Serialize(w); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(descs), nil
+}
+
+// Load reads transactions previously serialized by Save from the passed reader
+// and attempts to add each of them back to the pool via ProcessTransaction so
+// they are fully validated against the current state of the chain.  The time
+// each accepted transaction was added to the pool and the block height at that
+// time are restored to their saved values.  Transactions which are no longer
+// valid are skipped.  It returns the transactions which were added to the
+// pool, including any orphans they caused to be accepted, so the caller can
+// relay them.  The transactions added before an error was encountered are
+// returned along with it.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) Load(r io.Reader) ([]*dcrutil.Tx, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(r, buf[:4]); err != nil {
+		return nil, err
+	}
+	version := binary.LittleEndian.Uint32(buf[:4])
+	if version != persistVersion {
+		return nil, fmt.Errorf("unsupported mempool serialization version "+
+			"%d", version)
+	}
+	if _, err := io.ReadFull(r, buf[:4]); err != nil {
+		return nil, err
+	}
+	numTxns := binary.LittleEndian.Uint32(buf[:4])
+
+	var acceptedTxs []*dcrutil.Tx
+	for i := uint32(0); i < numTxns; i++ {
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return acceptedTxs, err
+		}
+		added := time.Unix(int64(binary.LittleEndian.Uint64(buf[:])), 0)
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return acceptedTxs, err
+		}
+		height := int64(binary.LittleEndian.Uint64(buf[:]))
+		if _, err := io.ReadFull(r, buf[:1]); err != nil {
+			return acceptedTxs, err
+		}
+		txType := stake.TxType(buf[0])
+		var msgTx wire.MsgTx
+		if err := msgTx.Deserialize(r); err != nil {
+			return acceptedTxs, err
+		}
+
+		if stake.DetermineTxType(&msgTx) != txType {
+			return acceptedTxs, fmt.Errorf("saved transaction %v does "+
+				"not have the saved type %v", msgTx.TxHash(), txType)
+		}
+
+		tx := dcrutil.NewTx(&msgTx)
+		accepted, err := mp.ProcessTransaction(tx, false, false, true)
+		if err != nil {
+			log.Debugf("Unable to load saved transaction %v: %v",
+				tx.Hash(), err)
+			continue
+		}
+		acceptedTxs = append(acceptedTxs, accepted...)
+
+		// Restore the time the transaction was originally added to the
+		// pool and the block height at that time.
+		mp.mtx.Lock()
+		if txDesc, exists := mp.pool[*tx.Hash()]; exists {
+			txDesc.Added = added
+			txDesc.Height = height
+		}
+		mp.mtx.Unlock()
+	}
+
+	return acceptedTxs, nil
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg"
+)
+
+// TestSaveLoad ensures the transactions in the pool can be saved and loaded
+// back into the pool along with their metadata and that invalid serialized
+// data is rejected.
+func TestSaveLoad(t *testing.T) {
+	t.Parallel()
+
+	harness, spendableOuts, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	tc := &testContext{t, harness}
+
+	// Add a chain of transactions to the pool.
+	chainedTxns, err := harness.CreateTxChain(spendableOuts[0], 3)
+	if err != nil {
+		t.Fatalf("unable to create transaction chain: %v", err)
+	}
+	for _, tx := range chainedTxns {
+		_, err := harness.txPool.ProcessTransaction(tx, false, false, true)
+		if err != nil {
+			t.Fatalf("ProcessTransaction: failed to accept tx: %v", err)
+		}
+	}
+
+	// Modify the metadata of the transactions so it is possible to detect
+	// that it is restored when the transactions are loaded.
+	added := time.Unix(time.Now().Unix()-3600, 0)
+	for i, tx := range chainedTxns {
+		txDesc := harness.txPool.pool[*tx.Hash()]
+		txDesc.Added = added
+		txDesc.Height = int64(i + 1)
+	}
+
+	// Save the pool and ensure all transactions were saved.
+	var buf bytes.Buffer
+	numSaved, err := harness.txPool.Save(&buf)
+	if err != nil {
+		t.Fatalf("Save: unexpected error: %v", err)
+	}
+	if numSaved != len(chainedTxns) {
+		t.Fatalf("Save: unexpected number of saved transactions -- got %d, "+
+			"want %d", numSaved, len(chainedTxns))
+	}
+	serialized := buf.Bytes()
+
+	// Remove all transactions from the pool and load them back.
+	harness.txPool.RemoveTransactionWithReason(chainedTxns[0], true,
+		RemovalReasonMined)
+	for _, tx := range chainedTxns {
+		testPoolMembership(tc, tx, false, false)
+	}
+	loaded, err := harness.txPool.Load(bytes.NewReader(serialized))
+	if err != nil {
+		t.Fatalf("Load: unexpected error: %v", err)
+	}
+	if len(loaded) != len(chainedTxns) {
+		t.Fatalf("Load: unexpected number of loaded transactions -- got "+
+			"%d, want %d", len(loaded), len(chainedTxns))
+	}
+	for i, tx := range chainedTxns {
+		testPoolMembership(tc, tx, false, true)
+		txDesc := harness.txPool.pool[*tx.Hash()]
+		if !txDesc.Added.Equal(added) {
+			t.Fatalf("Load: unexpected added time for tx %d -- got %v, "+
+				"want %v", i, txDesc.Added, added)
+		}
+		if txDesc.Height != int64(i+1) {
+			t.Fatalf("Load: unexpected height for tx %d -- got %d, want "+
+				"%d", i, txDesc.Height, i+1)
+		}
+	}
+
+	// Ensure loading the transactions again skips the duplicates.
+	loaded, err = harness.txPool.Load(bytes.NewReader(serialized))
+	if err != nil {
+		t.Fatalf("Load: unexpected error: %v", err)
+	}
+	if len(loaded) != 0 {
+		t.Fatalf("Load: unexpected number of loaded duplicate transactions "+
+			"-- got %d, want 0", len(loaded))
+	}
+
+	// Ensure an unsupported version is rejected.
+	badVersion := make([]byte, len(serialized))
+	copy(badVersion, serialized)
+	binary.LittleEndian.PutUint32(badVersion, persistVersion+1)
+	if _, err := harness.txPool.Load(bytes.NewReader(badVersion)); err == nil {
+		t.Fatal("Load: did not receive error for unsupported version")
+	}
+
+	// Ensure truncated data is rejected.
+	truncated := serialized[:len(serialized)-1]
+	if _, err := harness.txPool.Load(bytes.NewReader(truncated)); err == nil {
+		t.Fatal("Load: did not receive error for truncated data")
+	}
+
+	// Ensure a transaction with a type that does not match the saved type
+	// is rejected.
+	badType := make([]byte, len(serialized))
+	copy(badType, serialized)
+	badType[8+16] = 0xff
+	if _, err := harness.txPool.Load(bytes.NewReader(badType)); err == nil {
+		t.Fatal("Load: did not receive error for mismatched tx type")
+	}
+
+	// Ensure an empty pool round trips.
+	emptyHarness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	buf.Reset()
+	if _, err := emptyHarness.txPool.Save(&buf); err != nil {
+		t.Fatalf("Save: unexpected error: %v", err)
+	}
+	loaded, err = emptyHarness.txPool.Load(&buf)
+	if err != nil || len(loaded) != 0 {
+		t.Fatalf("Load: unexpected result for empty pool -- got %d, %v",
+			len(loaded), err)
+	}
+}
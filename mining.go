@@ -6,7 +6,6 @@
 package main
 
 import (
-	"container/heap"
 	"encoding/binary"
 	"fmt"
 	"math"
@@ -43,192 +42,8 @@ const (
 	// coinbaseFlags is some extra data appended to the coinbase script
 	// sig.
 	coinbaseFlags = "/dcrd/"
-
-	// kilobyte is the size of a kilobyte.
-	kilobyte = 1000
-)
-
-// txPrioItem houses a transaction along with extra information that allows the
-// transaction to be prioritized and track dependencies on other transactions
-// which have not been mined into a block yet.
-type txPrioItem struct {
-	tx       *dcrutil.Tx
-	txType   stake.TxType
-	fee      int64
-	priority float64
-	feePerKB float64
-
-	// dependsOn holds a map of transaction hashes which this one depends
-	// on.  It will only be set when the transaction references other
-	// transactions in the source pool and hence must come after them in
-	// a block.
-	dependsOn map[chainhash.Hash]struct{}
-}
-
-// txPriorityQueueLessFunc describes a function that can be used as a compare
-// function for a transaction priority queue (txPriorityQueue).
-type txPriorityQueueLessFunc func(*txPriorityQueue, int, int) bool
-
-// txPriorityQueue implements a priority queue of txPrioItem elements that
-// supports an arbitrary compare function as defined by txPriorityQueueLessFunc.
-type txPriorityQueue struct {
-	lessFunc txPriorityQueueLessFunc
-	items    []*txPrioItem
-}
-
-// Len returns the number of items in the priority queue.  It is part of the
-// heap.Interface implementation.
-func (pq *txPriorityQueue) Len() int {
-	return len(pq.items)
-}
-
-// Less returns whether the item in the priority queue with index i should sort
-// before the item with index j by deferring to the assigned less function.  It
-// is part of the heap.Interface implementation.
-func (pq *txPriorityQueue) Less(i, j int) bool {
-	return pq.lessFunc(pq, i, j)
-}
-
-// Swap swaps the items at the passed indices in the priority queue.  It is
-// part of the heap.Interface implementation.
-func (pq *txPriorityQueue) Swap(i, j int) {
-	pq.items[i], pq.items[j] = pq.items[j], pq.items[i]
-}
-
-// Push pushes the passed item onto the priority queue.  It is part of the
-// heap.Interface implementation.
-func (pq *txPriorityQueue) Push(x interface{}) {
-	pq.items = append(pq.items, x.(*txPrioItem))
-}
-
-// Pop removes the highest priority item (according to Less) from the priority
-// queue and returns it.  It is part of the heap.Interface implementation.
-func (pq *txPriorityQueue) Pop() interface{} {
-	n := len(pq.items)
-	item := pq.items[n-1]
-	pq.items[n-1] = nil
-	pq.items = pq.items[0 : n-1]
-	return item
-}
-
-// SetLessFunc sets the compare function for the priority queue to the provided
-// function.  It also invokes heap.Init on the priority queue using the new
-// function so it can immediately be used with heap.Push/Pop.
-func (pq *txPriorityQueue) SetLessFunc(lessFunc txPriorityQueueLessFunc) {
-	pq.lessFunc = lessFunc
-	heap.Init(pq)
-}
-
-// stakePriority is an integer that is used to sort stake transactions
-// by importance when they enter the min heap for block construction.
-// 2 is for votes (highest), followed by 1 for tickets (2nd highest),
-// followed by 0 for regular transactions and revocations (lowest).
-type stakePriority int
-
-const (
-	regOrRevocPriority stakePriority = iota
-	ticketPriority
-	votePriority
 )
 
-// stakePriority assigns a stake priority based on a transaction type.
-func txStakePriority(txType stake.TxType) stakePriority {
-	prio := regOrRevocPriority
-	switch txType {
-	case stake.TxTypeSSGen:
-		prio = votePriority
-	case stake.TxTypeSStx:
-		prio = ticketPriority
-	}
-
-	return prio
-}
-
-// compareStakePriority compares the stake priority of two transactions.
-// It uses votes > tickets > regular transactions or revocations. It
-// returns 1 if i > j, 0 if i == j, and -1 if i < j in terms of stake
-// priority.
-func compareStakePriority(i, j *txPrioItem) int {
-	iStakePriority := txStakePriority(i.txType)
-	jStakePriority := txStakePriority(j.txType)
-
-	if iStakePriority > jStakePriority {
-		return 1
-	}
-	if iStakePriority < jStakePriority {
-		return -1
-	}
-	return 0
-}
-
-// txPQByStakeAndFee sorts a txPriorityQueue by stake priority, followed by
-// fees per kilobyte, and then transaction priority.
-func txPQByStakeAndFee(pq *txPriorityQueue, i, j int) bool {
-	// Sort by stake priority, continue if they're the same stake priority.
-	cmp := compareStakePriority(pq.items[i], pq.items[j])
-	if cmp == 1 {
-		return true
-	}
-	if cmp == -1 {
-		return false
-	}
-
-	// Using > here so that pop gives the highest fee item as opposed
-	// to the lowest.  Sort by fee first, then priority.
-	if pq.items[i].feePerKB == pq.items[j].feePerKB {
-		return pq.items[i].priority > pq.items[j].priority
-	}
-
-	// The stake priorities are equal, so return based on fees
-	// per KB.
-	return pq.items[i].feePerKB > pq.items[j].feePerKB
-}
-
-// txPQByStakeAndFeeAndThenPriority sorts a txPriorityQueue by stake priority,
-// followed by fees per kilobyte, and then if the transaction type is regular
-// or a revocation it sorts it by priority.
-func txPQByStakeAndFeeAndThenPriority(pq *txPriorityQueue, i, j int) bool {
-	// Sort by stake priority, continue if they're the same stake priority.
-	cmp := compareStakePriority(pq.items[i], pq.items[j])
-	if cmp == 1 {
-		return true
-	}
-	if cmp == -1 {
-		return false
-	}
-
-	bothAreLowStakePriority :=
-		txStakePriority(pq.items[i].txType) == regOrRevocPriority &&
-			txStakePriority(pq.items[j].txType) == regOrRevocPriority
-
-	// Use fees per KB on high stake priority transactions.
-	if !bothAreLowStakePriority {
-		return pq.items[i].feePerKB > pq.items[j].feePerKB
-	}
-
-	// Both transactions are of low stake importance. Use > here so that
-	// pop gives the highest priority item as opposed to the lowest.
-	// Sort by priority first, then fee.
-	if pq.items[i].priority == pq.items[j].priority {
-		return pq.items[i].feePerKB > pq.items[j].feePerKB
-	}
-
-	return pq.items[i].priority > pq.items[j].priority
-}
-
-// newTxPriorityQueue returns a new transaction priority queue that reserves the
-// passed amount of space for the elements.  The new priority queue uses the
-// less than function lessFunc to sort the items in the min heap. The priority
-// queue can grow larger than the reserved space, but extra copies of the
-// underlying array can be avoided by reserving a sane value.
-func newTxPriorityQueue(reserve int, lessFunc func(*txPriorityQueue, int, int) bool) *txPriorityQueue {
-	pq := &txPriorityQueue{
-		items: make([]*txPrioItem, 0, reserve),
-	}
-	pq.SetLessFunc(lessFunc)
-	return pq
-}
-
 // containsTx is a helper function that checks to see if a list of transactions
 // contains any of the TxIns of some transaction.
 func containsTxIns(txs []*dcrutil.Tx, tx *dcrutil.Tx) bool {
@@ -615,14 +430,14 @@ func spendTransaction(utxoView *blockchain.UtxoViewpoint, tx *dcrutil.Tx, height
 
 // logSkippedDeps logs any dependencies which are also skipped as a result of
 // skipping a transaction while generating a block template at the trace level.
-func logSkippedDeps(tx *dcrutil.Tx, deps map[chainhash.Hash]*txPrioItem) {
+func logSkippedDeps(tx *dcrutil.Tx, deps map[chainhash.Hash]*mining.TxCandidate) {
 	if deps == nil {
 		return
 	}
 
-	for _, item := range deps {
+	for _, candidate := range deps {
 		minrLog.Tracef("Skipping tx %s since it depends on %s\n",
-			item.tx.Hash(), tx.Hash())
+			candidate.Tx.Hash(), tx.Hash())
 	}
 }
 
@@ -1017,6 +832,13 @@ func newBlkTmplGenerator(policy *mining.Policy, txSource mining.TxSource,
 // coinbase which will replace the one generated for the block template.  Thus
 // the need to have configured address can be avoided.
 //
+// The order in which the transactions are considered for inclusion is decided
+// by the transaction selector configured by the NewTxSelector policy setting,
+// and transactions may additionally be filtered by their output scripts
+// according to the AllowScripts and DenyScripts policy settings.  The
+// remainder of this description applies to the standard selector, which is
+// used when no selector is configured.
+//
 // The transactions selected and included are prioritized according to several
 // factors.  First, each transaction has a priority calculated based on its
 // value, age of inputs, and size.  Transactions which consist of larger
@@ -1025,6 +847,12 @@ func newBlkTmplGenerator(policy *mining.Policy, txSource mining.TxSource,
 // higher fee per kilobyte are preferred.  Finally, the block generation related
 // policy settings are all taken into account.
 //
+// Transactions that other transactions in the source pool depend on are
+// prioritized by the highest fee per kilobyte of either the transaction itself
+// or any of its dependents combined with all of their ancestors.  This allows a
+// dependent transaction that pays a high fee to pull the transactions it depends
+// on into the block even when they pay a low fee (child pays for parent).
+//
 // Transactions which only spend outputs from other transactions already in the
 // block chain are immediately added to a priority queue which either
 // prioritizes based on the priority (then fee per kilobyte) or the fee per
@@ -1048,7 +876,8 @@ func newBlkTmplGenerator(policy *mining.Policy, txSource mining.TxSource,
 // policy setting, exceed the maximum allowed signature operations per block, or
 // otherwise cause the block to be invalid are skipped.
 //
-// Given the above, a block generated by this function is of the following form:
+// Given the above, a block generated by this function using the standard
+// selector is of the following form:
 //
 //   -----------------------------------  --  --
 //  |      Coinbase Transaction         |   |   |
@@ -1178,19 +1007,8 @@ func (g *BlkTmplGenerator) NewBlockTemplate(payToAddress dcrutil.Address) (*Bloc
 		}
 	}
 
-	// Get the current source transactions and create a priority queue to
-	// hold the transactions which are ready for inclusion into a block
-	// along with some priority related and fee metadata.  Reserve the same
-	// number of items that are available for the priority queue.  Also,
-	// choose the initial sort order for the priority queue based on whether
-	// or not there is an area allocated for high-priority transactions.
+	// Get the current source transactions.
 	sourceTxns := g.txSource.MiningDescs()
-	sortedByFee := g.policy.BlockPrioritySize == 0
-	lessFunc := txPQByStakeAndFeeAndThenPriority
-	if sortedByFee {
-		lessFunc = txPQByStakeAndFee
-	}
-	priorityQueue := newTxPriorityQueue(len(sourceTxns), lessFunc)
 
 	// Create a slice to hold the transactions to be included in the
 	// generated block with reserved space.  Also create a utxo view to
@@ -1199,12 +1017,14 @@ func (g *BlkTmplGenerator) NewBlockTemplate(payToAddress dcrutil.Address) (*Bloc
 	blockTxns := make([]*dcrutil.Tx, 0, len(sourceTxns))
 	blockUtxos := blockchain.NewUtxoViewpoint()
 
+	// candidates houses the priority and fee metadata for all transactions
+	// that are candidates for inclusion in the block.
+	candidates := make([]*mining.TxCandidate, 0, len(sourceTxns))
+
 	// dependers is used to track transactions which depend on another
-	// transaction in the source pool.  This, in conjunction with the
-	// dependsOn map kept with each dependent transaction helps quickly
-	// determine which dependent transactions are now eligible for inclusion
-	// in the block once each transaction has been included.
-	dependers := make(map[chainhash.Hash]map[chainhash.Hash]*txPrioItem)
+	// transaction in the source pool so the dependent transactions that are
+	// also skipped as a result of skipping a transaction can be logged.
+	dependers := make(map[chainhash.Hash]map[chainhash.Hash]*mining.TxCandidate)
 
 	// Create slices to hold the fees and number of signature operations
 	// for each of the selected transactions and add an entry for the
@@ -1265,7 +1085,7 @@ mempoolLoop:
 		// Setup dependencies for any transactions which reference
 		// other transactions in the mempool so they can be properly
 		// ordered below.
-		prioItem := &txPrioItem{tx: txDesc.Tx, txType: txDesc.Type}
+		var dependsOn map[chainhash.Hash]struct{}
 		for i, txIn := range tx.MsgTx().TxIn {
 			// Evaluate if this is a stakebase input or not. If it is, continue
 			// without evaluation of the input.
@@ -1289,17 +1109,10 @@ mempoolLoop:
 				// The transaction is referencing another
 				// transaction in the source pool, so setup an
 				// ordering dependency.
-				deps, exists := dependers[*originHash]
-				if !exists {
-					deps = make(map[chainhash.Hash]*txPrioItem)
-					dependers[*originHash] = deps
-				}
-				deps[*prioItem.tx.Hash()] = prioItem
-				if prioItem.dependsOn == nil {
-					prioItem.dependsOn = make(
-						map[chainhash.Hash]struct{})
+				if dependsOn == nil {
+					dependsOn = make(map[chainhash.Hash]struct{})
 				}
-				prioItem.dependsOn[*originHash] = struct{}{}
+				dependsOn[*originHash] = struct{}{}
 
 				// Skip the check below. We already know the
 				// referenced transaction is available.
@@ -1310,24 +1123,18 @@ mempoolLoop:
 		// Calculate the final transaction priority using the input
 		// value age sum as well as the adjusted transaction size.  The
 		// formula is: sum(inputValue * inputAge) / adjustedTxSize
-		prioItem.priority = mining.CalcPriority(tx.MsgTx(), utxos,
-			nextBlockHeight)
-
-		// Calculate the fee in Atoms/KB.
-		// NOTE: This is a more precise value than the one calculated
-		// during calcMinRelayFee which rounds up to the nearest full
-		// kilobyte boundary.  This is beneficial since it provides an
-		// incentive to create smaller transactions.
-		txSize := tx.MsgTx().SerializeSize()
-		prioItem.feePerKB = (float64(txDesc.Fee) * float64(kilobyte)) /
-			float64(txSize)
-		prioItem.fee = txDesc.Fee
-
-		// Add the transaction to the priority queue to mark it ready
-		// for inclusion in the block unless it has dependencies.
-		if prioItem.dependsOn == nil {
-			heap.Push(priorityQueue, prioItem)
+		priority := mining.CalcPriority(tx.MsgTx(), utxos, nextBlockHeight)
+		candidate := mining.NewTxCandidate(txDesc, priority)
+		candidate.DependsOn = dependsOn
+		for originHash := range dependsOn {
+			deps, exists := dependers[originHash]
+			if !exists {
+				deps = make(map[chainhash.Hash]*mining.TxCandidate)
+				dependers[originHash] = deps
+			}
+			deps[*tx.Hash()] = candidate
 		}
+		candidates = append(candidates, candidate)
 
 		// Merge the referenced outputs from the input transactions to
 		// this transaction into the block utxo view.  This allows the
@@ -1335,8 +1142,16 @@ mempoolLoop:
 		mergeUtxoView(blockUtxos, utxos)
 	}
 
-	minrLog.Tracef("Priority queue len %d, dependers len %d",
-		priorityQueue.Len(), len(dependers))
+	// Calculate the fees of the packages the transactions are part of so
+	// the transactions that others depend on may be prioritized by the fees
+	// of the entire package (child pays for parent) and create the selector
+	// that decides the order in which the transactions are considered for
+	// inclusion according to the policy.
+	mining.ApplyPackageFeesPerKB(candidates)
+	selector := mining.NewPolicyTxSelector(candidates, g.policy)
+
+	minrLog.Tracef("Candidates len %d, dependers len %d", len(candidates),
+		len(dependers))
 
 	// The starting block size is the size of the block header plus the max
 	// possible transaction count size, plus the size of the coinbase
@@ -1360,20 +1175,23 @@ mempoolLoop:
 	}
 
 	// Choose which transactions make it into the block.
-	for priorityQueue.Len() > 0 {
-		// Grab the highest priority (or highest fee per kilobyte
-		// depending on the sort order) transaction.
-		prioItem := heap.Pop(priorityQueue).(*txPrioItem)
-		tx := prioItem.tx
+	for {
+		// Grab the next transaction to consider as decided by the
+		// selector.
+		candidate := selector.Next(blockSize)
+		if candidate == nil {
+			break
+		}
+		tx := candidate.Tx
 
 		// Store if this is an SStx or not.
-		isSStx := prioItem.txType == stake.TxTypeSStx
+		isSStx := candidate.Type == stake.TxTypeSStx
 
 		// Store if this is an SSGen or not.
-		isSSGen := prioItem.txType == stake.TxTypeSSGen
+		isSSGen := candidate.Type == stake.TxTypeSSGen
 
 		// Store if this is an SSRtx or not.
-		isSSRtx := prioItem.txType == stake.TxTypeSSRtx
+		isSSRtx := candidate.Type == stake.TxTypeSSRtx
 
 		// Grab the list of transactions which depend on this one (if any).
 		deps := dependers[*tx.Hash()]
@@ -1465,51 +1283,6 @@ mempoolLoop:
 			}
 		}
 
-		// Skip free transactions once the block is larger than the
-		// minimum block size, except for stake transactions.
-		if sortedByFee &&
-			(prioItem.feePerKB < float64(g.policy.TxMinFreeFee)) &&
-			(tx.Tree() != wire.TxTreeStake) &&
-			(blockPlusTxSize >= g.policy.BlockMinSize) {
-
-			minrLog.Tracef("Skipping tx %s with feePerKB %.2f "+
-				"< TxMinFreeFee %d and block size %d >= "+
-				"minBlockSize %d", tx.Hash(), prioItem.feePerKB,
-				g.policy.TxMinFreeFee, blockPlusTxSize,
-				g.policy.BlockMinSize)
-			logSkippedDeps(tx, deps)
-			continue
-		}
-
-		// Prioritize by fee per kilobyte once the block is larger than
-		// the priority size or there are no more high-priority
-		// transactions.
-		if !sortedByFee && (blockPlusTxSize >= g.policy.BlockPrioritySize ||
-			prioItem.priority <= mining.MinHighPriority) {
-
-			minrLog.Tracef("Switching to sort by fees per "+
-				"kilobyte blockSize %d >= BlockPrioritySize "+
-				"%d || priority %.2f <= minHighPriority %.2f",
-				blockPlusTxSize, g.policy.BlockPrioritySize,
-				prioItem.priority, mining.MinHighPriority)
-
-			sortedByFee = true
-			priorityQueue.SetLessFunc(txPQByStakeAndFee)
-
-			// Put the transaction back into the priority queue and
-			// skip it so it is re-priortized by fees if it won't
-			// fit into the high-priority section or the priority is
-			// too low.  Otherwise this transaction will be the
-			// final one in the high-priority section, so just fall
-			// though to the code below so it is added now.
-			if blockPlusTxSize > g.policy.BlockPrioritySize ||
-				prioItem.priority < mining.MinHighPriority {
-
-				heap.Push(priorityQueue, prioItem)
-				continue
-			}
-		}
-
 		// Ensure the transaction inputs pass all of the necessary
 		// preconditions before allowing it to be added to the block.
 		// The fraud proof is not checked because it will be filled in
@@ -1558,23 +1331,15 @@ mempoolLoop:
 			foundWinningTickets[tx.MsgTx().TxIn[1].PreviousOutPoint.Hash] = true
 		}
 
-		txFeesMap[*tx.Hash()] = prioItem.fee
+		txFeesMap[*tx.Hash()] = candidate.Fee
 		txSigOpCountsMap[*tx.Hash()] = numSigOps
 
 		minrLog.Tracef("Adding tx %s (priority %.2f, feePerKB %.2f)",
-			prioItem.tx.Hash(), prioItem.priority, prioItem.feePerKB)
-
-		// Add transactions which depend on this one (and also do not
-		// have any other unsatisified dependencies) to the priority
-		// queue.
-		for _, item := range deps {
-			// Add the transaction to the priority queue if there
-			// are no more dependencies after this one.
-			delete(item.dependsOn, *tx.Hash())
-			if len(item.dependsOn) == 0 {
-				heap.Push(priorityQueue, item)
-			}
-		}
+			tx.Hash(), candidate.Priority, candidate.PackageFeePerKB)
+
+		// Notify the selector so the transactions which depend on this
+		// one become available.
+		selector.Included(candidate)
 	}
 
 	// Build tx list for stake tx.
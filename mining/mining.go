@@ -19,6 +19,21 @@ const (
 	MinHighPriority = dcrutil.AtomsPerCoin * 144.0 / 250
 )
 
+// TxPackageStats houses aggregate statistics about a set of related unconfirmed
+// transactions in a transaction source, such as a transaction along with all of
+// its ancestors or all of its descendants.
+type TxPackageStats struct {
+	// Count is the number of transactions in the set.
+	Count int
+
+	// Size is the total serialized size in bytes of the transactions in the
+	// set.
+	Size int64
+
+	// Fees is the total fee paid by the transactions in the set.
+	Fees int64
+}
+
 // TxDesc is a descriptor about a transaction in a transaction source along with
 // additional metadata.
 type TxDesc struct {
@@ -37,6 +52,16 @@ type TxDesc struct {
 
 	// Fee is the total fee the transaction associated with the entry pays.
 	Fee int64
+
+	// Ancestors houses aggregate statistics about the transaction associated
+	// with the entry along with all of the transactions in the source pool it
+	// depends on, either directly or indirectly.
+	Ancestors TxPackageStats
+
+	// Descendants houses aggregate statistics about the transaction
+	// associated with the entry along with all of the transactions in the
+	// source pool that depend on it, either directly or indirectly.
+	Descendants TxPackageStats
 }
 
 // VoteDesc is a descriptor about a vote transaction in a transaction source
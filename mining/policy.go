@@ -38,6 +38,22 @@ type Policy struct {
 	// required for a transaction to be treated as free for mining purposes
 	// (block template generation).
 	TxMinFreeFee dcrutil.Amount
+
+	// NewTxSelector creates the transaction selector which decides the
+	// order in which the transactions are considered for inclusion when
+	// generating a block template.  The standard selector is used when it
+	// is nil.
+	NewTxSelector NewTxSelectorFunc
+
+	// AllowScripts are the output scripts a regular transaction must pay to
+	// at least one of in order to be included when generating a block
+	// template.  All transactions are allowed when it is empty.
+	AllowScripts [][]byte
+
+	// DenyScripts are the output scripts which prevent a regular transaction
+	// paying to any of them from being included when generating a block
+	// template.
+	DenyScripts [][]byte
 }
 
 // minInt is a helper function to return the minimum of two ints.  This avoids
@@ -0,0 +1,124 @@
+// Copyright (c) 2016 The btcsuite developers
+// Copyright (c) 2015-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mining
+
+import (
+	"container/heap"
+	"math/rand"
+	"testing"
+
+	"github.com/decred/dcrd/blockchain/stake"
+)
+
+// TestStakeTxFeePrioHeap tests the priority heaps including the stake types for
+// both transaction fees per KB and transaction priority. It ensures that the
+// primary sorting is first by stake type, and then by the latter chosen priority
+// type.
+func TestStakeTxFeePrioHeap(t *testing.T) {
+	numElements := 1000
+	numEdgeConditionElements := 12
+	// Create some fake priority items that exercise the expected sort
+	// edge conditions.
+	testItems := []*TxCandidate{
+		{PackageFeePerKB: 5678, Type: stake.TxTypeRegular, Priority: 3},
+		{PackageFeePerKB: 5678, Type: stake.TxTypeRegular, Priority: 1},
+		{PackageFeePerKB: 5678, Type: stake.TxTypeRegular, Priority: 1}, // Duplicate fee and prio
+		{PackageFeePerKB: 5678, Type: stake.TxTypeRegular, Priority: 5},
+		{PackageFeePerKB: 5678, Type: stake.TxTypeRegular, Priority: 2},
+		{PackageFeePerKB: 1234, Type: stake.TxTypeRegular, Priority: 3},
+		{PackageFeePerKB: 1234, Type: stake.TxTypeRegular, Priority: 1},
+		{PackageFeePerKB: 1234, Type: stake.TxTypeRegular, Priority: 5},
+		{PackageFeePerKB: 1234, Type: stake.TxTypeRegular, Priority: 5}, // Duplicate fee and prio
+		{PackageFeePerKB: 1234, Type: stake.TxTypeRegular, Priority: 2},
+		{PackageFeePerKB: 10000, Type: stake.TxTypeRegular, Priority: 0}, // Higher fee, smaller prio
+		{PackageFeePerKB: 0, Type: stake.TxTypeRegular, Priority: 10000}, // Higher prio, lower fee
+	}
+	ph := newTxPriorityQueue((numElements + numEdgeConditionElements), txPQByStakeAndFee)
+
+	// Add random data in addition to the edge conditions already manually
+	// specified.
+	for i := 0; i < (numElements + numEdgeConditionElements); i++ {
+		if i >= numEdgeConditionElements {
+			randType := stake.TxType(rand.Intn(4))
+			randPrio := rand.Float64() * 100
+			randFeePerKB := rand.Float64() * 10
+			testItems = append(testItems, &TxCandidate{
+				Type:            randType,
+				PackageFeePerKB: randFeePerKB,
+				Priority:        randPrio,
+			})
+		}
+
+		heap.Push(ph, testItems[i])
+	}
+
+	// Test sorting by stake and fee per KB.
+	last := &TxCandidate{
+		Type:            stake.TxTypeSSGen,
+		Priority:        10000.0,
+		PackageFeePerKB: 10000.0,
+	}
+	for i := 0; i < numElements; i++ {
+		prioItem := heap.Pop(ph)
+		txpi, ok := prioItem.(*TxCandidate)
+		if ok {
+			if txpi.PackageFeePerKB > last.PackageFeePerKB &&
+				compareStakePriority(txpi, last) >= 0 {
+				t.Errorf("bad pop: %v fee per KB was more than last of %v "+
+					"while the txtype was %v but last was %v",
+					txpi.PackageFeePerKB, last.PackageFeePerKB, txpi.Type, last.Type)
+			}
+			last = txpi
+		}
+	}
+
+	ph = newTxPriorityQueue(len(testItems), txPQByStakeAndFeeAndThenPriority)
+	for i := 0; i < numElements; i++ {
+		randType := stake.TxType(rand.Intn(4))
+		randPrio := rand.Float64() * 100
+		randFeePerKB := rand.Float64() * 10
+		prioItem := &TxCandidate{
+			Type:            randType,
+			PackageFeePerKB: randFeePerKB,
+			Priority:        randPrio,
+		}
+		heap.Push(ph, prioItem)
+	}
+
+	// Test sorting with fees per KB for high stake priority, then
+	// priority for low stake priority.
+	last = &TxCandidate{
+		Type:            stake.TxTypeSSGen,
+		Priority:        10000.0,
+		PackageFeePerKB: 10000.0,
+	}
+	for i := 0; i < numElements; i++ {
+		prioItem := heap.Pop(ph)
+		txpi, ok := prioItem.(*TxCandidate)
+		if ok {
+			bothAreLowStakePriority :=
+				txStakePriority(txpi.Type) == regOrRevocPriority &&
+					txStakePriority(last.Type) == regOrRevocPriority
+			if !bothAreLowStakePriority {
+				if txpi.PackageFeePerKB > last.PackageFeePerKB &&
+					compareStakePriority(txpi, last) >= 0 {
+					t.Errorf("bad pop: %v fee per KB was more than last of %v "+
+						"while the txtype was %v but last was %v",
+						txpi.PackageFeePerKB, last.PackageFeePerKB, txpi.Type, last.Type)
+				}
+			}
+			if bothAreLowStakePriority {
+				if txpi.Priority > last.Priority &&
+					compareStakePriority(txpi, last) >= 0 {
+					t.Errorf("bad pop: %v priority was more than last of %v "+
+						"while the txtype was %v but last was %v",
+						txpi.PackageFeePerKB, last.PackageFeePerKB, txpi.Type, last.Type)
+				}
+			}
+			last = txpi
+		}
+	}
+}
@@ -0,0 +1,580 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mining
+
+import (
+	"container/heap"
+	"sort"
+
+	"github.com/decred/dcrd/blockchain/stake"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/wire"
+)
+
+const (
+	// kilobyte is the size of a kilobyte.
+	kilobyte = 1000
+)
+
+// Names of the transaction selectors which may be chosen by name.
+const (
+	// TxSelectorStandard is the name of the selector returned by
+	// NewStandardTxSelector.
+	TxSelectorStandard = "standard"
+
+	// TxSelectorFeeRate is the name of the selector returned by
+	// NewFeeRateTxSelector.
+	TxSelectorFeeRate = "feerate"
+
+	// TxSelectorAncestorFeeRate is the name of the selector returned by
+	// NewAncestorFeeRateTxSelector.
+	TxSelectorAncestorFeeRate = "ancestorfeerate"
+
+	// TxSelectorPriority is the name of the selector returned by
+	// NewPriorityTxSelector.
+	TxSelectorPriority = "priority"
+)
+
+// TxCandidate houses a transaction from a transaction source which is a
+// candidate for inclusion in a block template along with the metadata used by
+// transaction selectors to choose among the candidates.
+type TxCandidate struct {
+	// Tx is the candidate transaction.
+	Tx *dcrutil.Tx
+
+	// Type is the type of the candidate transaction.
+	Type stake.TxType
+
+	// Fee is the total fee the candidate transaction pays.
+	Fee int64
+
+	// Size is the serialized size of the candidate transaction in bytes.
+	Size uint32
+
+	// Priority is the priority of the candidate transaction as calculated by
+	// CalcPriority.
+	Priority float64
+
+	// FeePerKB is the fee per kilobyte of the candidate transaction itself.
+	FeePerKB float64
+
+	// AncestorFeePerKB is the fee per kilobyte of the candidate transaction
+	// along with all of its ancestors in the transaction source.
+	AncestorFeePerKB float64
+
+	// PackageFeePerKB is the highest fee per kilobyte of the candidate
+	// transaction itself and of any package of a descendant along with all
+	// of its ancestors, which allows transactions that pay a low fee to be
+	// pulled into a block by descendants that pay a high fee (child pays for
+	// parent).  It is set by ApplyPackageFeesPerKB.
+	PackageFeePerKB float64
+
+	// DependsOn holds the hashes of the candidate transactions this one
+	// depends on.  It is nil when the transaction only spends outputs that
+	// are not in the transaction source and hence may be included in a
+	// block before any other candidate.
+	DependsOn map[chainhash.Hash]struct{}
+}
+
+// NewTxCandidate returns a new candidate for the transaction described by the
+// passed descriptor with the provided priority.  The fees per kilobyte are
+// calculated from the descriptor.  The caller is responsible for setting the
+// dependencies of the candidate.
+func NewTxCandidate(txDesc *TxDesc, priority float64) *TxCandidate {
+	// Calculate the fee in Atoms/KB.
+	// NOTE: This is a more precise value than the one calculated
+	// during calcMinRelayFee which rounds up to the nearest full
+	// kilobyte boundary.  This is beneficial since it provides an
+	// incentive to create smaller transactions.
+	txSize := txDesc.Tx.MsgTx().SerializeSize()
+	feePerKB := (float64(txDesc.Fee) * float64(kilobyte)) / float64(txSize)
+
+	// Calculate the fee in Atoms/KB of the package consisting of the
+	// transaction and all of its ancestors.  Fall back to the fee of the
+	// transaction itself when the source does not track them.
+	ancestorFeePerKB := feePerKB
+	if txDesc.Ancestors.Size > 0 {
+		ancestorFeePerKB = (float64(txDesc.Ancestors.Fees) *
+			float64(kilobyte)) / float64(txDesc.Ancestors.Size)
+	}
+
+	return &TxCandidate{
+		Tx:               txDesc.Tx,
+		Type:             txDesc.Type,
+		Fee:              txDesc.Fee,
+		Size:             uint32(txSize),
+		Priority:         priority,
+		FeePerKB:         feePerKB,
+		AncestorFeePerKB: ancestorFeePerKB,
+		PackageFeePerKB:  feePerKB,
+	}
+}
+
+// ApplyPackageFeesPerKB raises the package fee per kilobyte of all candidates
+// that a dependent candidate depends on, either directly or indirectly, to the
+// fee per kilobyte of the dependent candidate along with all of its ancestors
+// when it is higher.  This ensures the transactions may be prioritized by the
+// fees of the entire package (child pays for parent).
+func ApplyPackageFeesPerKB(candidates []*TxCandidate) {
+	candidatesByHash := make(map[chainhash.Hash]*TxCandidate, len(candidates))
+	for _, candidate := range candidates {
+		candidatesByHash[*candidate.Tx.Hash()] = candidate
+	}
+
+	for _, candidate := range candidates {
+		if candidate.DependsOn == nil {
+			continue
+		}
+
+		pkgFeePerKB := candidate.AncestorFeePerKB
+		visited := make(map[chainhash.Hash]struct{})
+		processList := []*TxCandidate{candidate}
+		for len(processList) > 0 {
+			processItem := processList[0]
+			processList = processList[1:]
+			for originHash := range processItem.DependsOn {
+				if _, ok := visited[originHash]; ok {
+					continue
+				}
+				visited[originHash] = struct{}{}
+
+				ancestor, ok := candidatesByHash[originHash]
+				if !ok {
+					continue
+				}
+				if pkgFeePerKB > ancestor.PackageFeePerKB {
+					ancestor.PackageFeePerKB = pkgFeePerKB
+				}
+				processList = append(processList, ancestor)
+			}
+		}
+	}
+}
+
+// TxSelector decides the order in which the candidate transactions for a block
+// template are considered for inclusion in it.  Selectors only implement the
+// policy used to choose among the candidates.  The caller remains responsible
+// for enforcing the consensus rules, such as the maximum block size, and may
+// therefore skip candidates returned by Next.
+//
+// Selectors must never return a candidate before all of the candidates it
+// depends on have been included.
+type TxSelector interface {
+	// Next returns the next candidate that should be considered for
+	// inclusion in a block template which currently has the passed size in
+	// bytes.  It returns nil when there are no more candidates to consider.
+	Next(blockSize uint32) *TxCandidate
+
+	// Included notifies the selector that the passed candidate, which was
+	// returned by the most recent call to Next, was included in the block
+	// template.  This makes the candidates which depend on it available.
+	Included(candidate *TxCandidate)
+}
+
+// NewTxSelectorFunc describes a function that creates a transaction selector
+// which chooses among the passed candidates in accordance with the provided
+// policy.
+type NewTxSelectorFunc func(candidates []*TxCandidate, policy *Policy) TxSelector
+
+// txSelectors houses the transaction selectors which may be chosen by name.
+// All of them select stake transactions before regular transactions since the
+// priority queues they use are sorted by the stake type first.
+var txSelectors = map[string]NewTxSelectorFunc{
+	TxSelectorStandard:        NewStandardTxSelector,
+	TxSelectorFeeRate:         NewFeeRateTxSelector,
+	TxSelectorAncestorFeeRate: NewAncestorFeeRateTxSelector,
+	TxSelectorPriority:        NewPriorityTxSelector,
+}
+
+// TxSelectorByName returns the function that creates the transaction selector
+// with the passed name along with whether or not it exists.
+func TxSelectorByName(name string) (NewTxSelectorFunc, bool) {
+	newSelector, ok := txSelectors[name]
+	return newSelector, ok
+}
+
+// TxSelectorNames returns the sorted names of the transaction selectors which
+// may be chosen by name.
+func TxSelectorNames() []string {
+	names := make([]string, 0, len(txSelectors))
+	for name := range txSelectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewPolicyTxSelector returns the transaction selector configured by the passed
+// policy for the provided candidates.  The standard selector is used when the
+// policy does not specify one.  The selector additionally filters candidates
+// based on their output scripts when the policy specifies scripts to allow or
+// deny.
+func NewPolicyTxSelector(candidates []*TxCandidate, policy *Policy) TxSelector {
+	newSelector := policy.NewTxSelector
+	if newSelector == nil {
+		newSelector = NewStandardTxSelector
+	}
+	selector := newSelector(candidates, policy)
+	if len(policy.AllowScripts) > 0 || len(policy.DenyScripts) > 0 {
+		selector = NewScriptFilterTxSelector(selector, policy.AllowScripts,
+			policy.DenyScripts)
+	}
+	return selector
+}
+
+// candidateQueue houses the candidates whose dependencies have been included
+// in a priority queue and tracks the remaining candidates until all of the
+// candidates they depend on are included.
+type candidateQueue struct {
+	pq *txPriorityQueue
+
+	// dependers maps the hash of a candidate to the candidates which depend
+	// on it.
+	dependers map[chainhash.Hash][]*TxCandidate
+
+	// numDeps is the number of candidates each candidate depends on which
+	// have not been included yet.
+	numDeps map[*TxCandidate]int
+}
+
+// newCandidateQueue returns a new candidate queue for the passed candidates
+// which is sorted by the provided less function.
+func newCandidateQueue(candidates []*TxCandidate, lessFunc txPriorityQueueLessFunc) *candidateQueue {
+	q := &candidateQueue{
+		pq:        newTxPriorityQueue(len(candidates), lessFunc),
+		dependers: make(map[chainhash.Hash][]*TxCandidate),
+		numDeps:   make(map[*TxCandidate]int),
+	}
+	for _, candidate := range candidates {
+		if len(candidate.DependsOn) == 0 {
+			heap.Push(q.pq, candidate)
+			continue
+		}
+
+		q.numDeps[candidate] = len(candidate.DependsOn)
+		for originHash := range candidate.DependsOn {
+			q.dependers[originHash] = append(q.dependers[originHash],
+				candidate)
+		}
+	}
+	return q
+}
+
+// pop removes and returns the highest priority candidate from the queue or nil
+// when it is empty.
+func (q *candidateQueue) pop() *TxCandidate {
+	if q.pq.Len() == 0 {
+		return nil
+	}
+	return heap.Pop(q.pq).(*TxCandidate)
+}
+
+// push adds the passed candidate back to the queue.
+func (q *candidateQueue) push(candidate *TxCandidate) {
+	heap.Push(q.pq, candidate)
+}
+
+// included adds the candidates which depend on the passed candidate to the
+// queue once it was the last of their dependencies to be included.
+func (q *candidateQueue) included(candidate *TxCandidate) {
+	hash := candidate.Tx.Hash()
+	for _, depender := range q.dependers[*hash] {
+		q.numDeps[depender]--
+		if q.numDeps[depender] == 0 {
+			delete(q.numDeps, depender)
+			heap.Push(q.pq, depender)
+		}
+	}
+	delete(q.dependers, *hash)
+}
+
+// isLowFee returns whether the passed candidate should be skipped because it
+// pays less than the minimum fee the policy requires for it to be considered
+// a non-free transaction and adding it would result in a block of the passed
+// size which is larger than the minimum block size.  Stake transactions are
+// never considered low fee.
+func isLowFee(policy *Policy, candidate *TxCandidate, blockPlusTxSize uint32) bool {
+	if candidate.Tx.Tree() == wire.TxTreeStake {
+		return false
+	}
+
+	if candidate.PackageFeePerKB < float64(policy.TxMinFreeFee) &&
+		blockPlusTxSize >= policy.BlockMinSize {
+
+		log.Tracef("Skipping tx %s with feePerKB %.2f < TxMinFreeFee %d "+
+			"and block size %d >= minBlockSize %d", candidate.Tx.Hash(),
+			candidate.PackageFeePerKB, policy.TxMinFreeFee,
+			blockPlusTxSize, policy.BlockMinSize)
+		return true
+	}
+
+	return false
+}
+
+// standardTxSelector implements the TxSelector interface by first selecting
+// the transactions with the highest priority until the high-priority area of
+// the block is filled and then by the fees of the packages they are part of.
+type standardTxSelector struct {
+	policy      *Policy
+	queue       *candidateQueue
+	sortedByFee bool
+}
+
+// NewStandardTxSelector returns a transaction selector which fills the area
+// allocated for high-priority transactions by the BlockPrioritySize policy
+// setting by priority (then fee per kilobyte) and the remainder of the block
+// by fee per kilobyte (then priority).  The fee per kilobyte of a candidate is
+// the fee of the highest paying package it is part of (child pays for parent).
+//
+// Once the block is larger than the BlockMinSize policy setting, transactions
+// paying less than the TxMinFreeFee policy setting are skipped.
+func NewStandardTxSelector(candidates []*TxCandidate, policy *Policy) TxSelector {
+	sortedByFee := policy.BlockPrioritySize == 0
+	lessFunc := txPQByStakeAndFeeAndThenPriority
+	if sortedByFee {
+		lessFunc = txPQByStakeAndFee
+	}
+	return &standardTxSelector{
+		policy:      policy,
+		queue:       newCandidateQueue(candidates, lessFunc),
+		sortedByFee: sortedByFee,
+	}
+}
+
+// Next returns the next candidate that should be considered for inclusion.
+//
+// This is part of the TxSelector interface.
+func (s *standardTxSelector) Next(blockSize uint32) *TxCandidate {
+	for {
+		candidate := s.queue.pop()
+		if candidate == nil {
+			return nil
+		}
+
+		// Skip free transactions once the block is larger than the
+		// minimum block size, except for stake transactions.
+		blockPlusTxSize := blockSize + candidate.Size
+		if s.sortedByFee && isLowFee(s.policy, candidate, blockPlusTxSize) {
+			continue
+		}
+
+		// Prioritize by fee per kilobyte once the block is larger than
+		// the priority size or there are no more high-priority
+		// transactions.
+		if !s.sortedByFee &&
+			(blockPlusTxSize >= s.policy.BlockPrioritySize ||
+				candidate.Priority <= MinHighPriority) {
+
+			log.Tracef("Switching to sort by fees per kilobyte blockSize "+
+				"%d >= BlockPrioritySize %d || priority %.2f <= "+
+				"minHighPriority %.2f", blockPlusTxSize,
+				s.policy.BlockPrioritySize, candidate.Priority,
+				MinHighPriority)
+
+			s.sortedByFee = true
+			s.queue.pq.SetLessFunc(txPQByStakeAndFee)
+
+			// Put the transaction back into the priority queue and
+			// skip it so it is re-priortized by fees if it won't
+			// fit into the high-priority section or the priority is
+			// too low.  Otherwise this transaction will be the
+			// final one in the high-priority section, so just fall
+			// though to the code below so it is returned now.
+			if blockPlusTxSize > s.policy.BlockPrioritySize ||
+				candidate.Priority < MinHighPriority {
+
+				s.queue.push(candidate)
+				continue
+			}
+		}
+
+		return candidate
+	}
+}
+
+// Included notifies the selector that the passed candidate was included.
+//
+// This is part of the TxSelector interface.
+func (s *standardTxSelector) Included(candidate *TxCandidate) {
+	s.queue.included(candidate)
+}
+
+// feeTxSelector implements the TxSelector interface by selecting transactions
+// in the order of a fixed compare function while skipping low fee
+// transactions.
+type feeTxSelector struct {
+	policy *Policy
+	queue  *candidateQueue
+}
+
+// NewFeeRateTxSelector returns a transaction selector which selects
+// transactions purely by their own fee per kilobyte (then priority) without
+// considering the fees paid by any related transactions.  Thus, a transaction
+// paying a high fee which depends on one paying a low fee is only selected once
+// the latter has been selected on its own merits.
+//
+// Once the block is larger than the BlockMinSize policy setting, transactions
+// paying less than the TxMinFreeFee policy setting are skipped.
+func NewFeeRateTxSelector(candidates []*TxCandidate, policy *Policy) TxSelector {
+	return &feeTxSelector{
+		policy: policy,
+		queue:  newCandidateQueue(candidates, txPQByStakeAndOwnFee),
+	}
+}
+
+// NewAncestorFeeRateTxSelector returns a transaction selector which selects
+// transactions by the fee per kilobyte of the highest paying package they are
+// part of (then priority), where a package is a transaction along with all of
+// its ancestors.  This allows a transaction paying a high fee to pull the
+// transactions it depends on into the block (child pays for parent).  Unlike
+// the standard selector, no area is allocated for high-priority transactions.
+//
+// Once the block is larger than the BlockMinSize policy setting, transactions
+// paying less than the TxMinFreeFee policy setting are skipped.
+func NewAncestorFeeRateTxSelector(candidates []*TxCandidate, policy *Policy) TxSelector {
+	return &feeTxSelector{
+		policy: policy,
+		queue:  newCandidateQueue(candidates, txPQByStakeAndFee),
+	}
+}
+
+// Next returns the next candidate that should be considered for inclusion.
+//
+// This is part of the TxSelector interface.
+func (s *feeTxSelector) Next(blockSize uint32) *TxCandidate {
+	for {
+		candidate := s.queue.pop()
+		if candidate == nil {
+			return nil
+		}
+
+		if isLowFee(s.policy, candidate, blockSize+candidate.Size) {
+			continue
+		}
+
+		return candidate
+	}
+}
+
+// Included notifies the selector that the passed candidate was included.
+//
+// This is part of the TxSelector interface.
+func (s *feeTxSelector) Included(candidate *TxCandidate) {
+	s.queue.included(candidate)
+}
+
+// priorityTxSelector implements the TxSelector interface by selecting
+// transactions by priority.
+type priorityTxSelector struct {
+	policy *Policy
+	queue  *candidateQueue
+}
+
+// NewPriorityTxSelector returns a transaction selector which selects
+// transactions by priority (then fee per kilobyte) for the entire block
+// regardless of the BlockPrioritySize policy setting.
+//
+// Once the block is larger than the BlockMinSize policy setting, transactions
+// which are not high priority and pay less than the TxMinFreeFee policy
+// setting are skipped.
+func NewPriorityTxSelector(candidates []*TxCandidate, policy *Policy) TxSelector {
+	return &priorityTxSelector{
+		policy: policy,
+		queue: newCandidateQueue(candidates,
+			txPQByStakeAndFeeAndThenPriority),
+	}
+}
+
+// Next returns the next candidate that should be considered for inclusion.
+//
+// This is part of the TxSelector interface.
+func (s *priorityTxSelector) Next(blockSize uint32) *TxCandidate {
+	for {
+		candidate := s.queue.pop()
+		if candidate == nil {
+			return nil
+		}
+
+		if candidate.Priority <= MinHighPriority &&
+			isLowFee(s.policy, candidate, blockSize+candidate.Size) {
+			continue
+		}
+
+		return candidate
+	}
+}
+
+// Included notifies the selector that the passed candidate was included.
+//
+// This is part of the TxSelector interface.
+func (s *priorityTxSelector) Included(candidate *TxCandidate) {
+	s.queue.included(candidate)
+}
+
+// scriptFilterTxSelector implements the TxSelector interface by filtering the
+// candidates returned by another selector based on their output scripts.
+type scriptFilterTxSelector struct {
+	TxSelector
+	allow map[string]struct{}
+	deny  map[string]struct{}
+}
+
+// NewScriptFilterTxSelector returns a transaction selector which only returns
+// the candidates returned by the passed selector which do not pay to any of the
+// deny scripts and, when any allow scripts are provided, pay to at least one of
+// them.  Since the filtered candidates are never included, any candidates that
+// depend on them are filtered as well.
+//
+// The filters only apply to transactions in the regular transaction tree since
+// stake transactions, such as votes, are required for the block to be valid.
+func NewScriptFilterTxSelector(selector TxSelector, allow, deny [][]byte) TxSelector {
+	toSet := func(scripts [][]byte) map[string]struct{} {
+		set := make(map[string]struct{}, len(scripts))
+		for _, script := range scripts {
+			set[string(script)] = struct{}{}
+		}
+		return set
+	}
+	return &scriptFilterTxSelector{
+		TxSelector: selector,
+		allow:      toSet(allow),
+		deny:       toSet(deny),
+	}
+}
+
+// permitted returns whether the passed candidate passes the script filters.
+func (s *scriptFilterTxSelector) permitted(candidate *TxCandidate) bool {
+	if candidate.Tx.Tree() == wire.TxTreeStake {
+		return true
+	}
+
+	allowed := len(s.allow) == 0
+	for _, txOut := range candidate.Tx.MsgTx().TxOut {
+		if _, ok := s.deny[string(txOut.PkScript)]; ok {
+			return false
+		}
+		if _, ok := s.allow[string(txOut.PkScript)]; ok {
+			allowed = true
+		}
+	}
+	return allowed
+}
+
+// Next returns the next candidate returned by the underlying selector which
+// passes the script filters.
+//
+// This is part of the TxSelector interface.
+func (s *scriptFilterTxSelector) Next(blockSize uint32) *TxCandidate {
+	for {
+		candidate := s.TxSelector.Next(blockSize)
+		if candidate == nil || s.permitted(candidate) {
+			return candidate
+		}
+
+		log.Tracef("Skipping tx %s due to the script filters",
+			candidate.Tx.Hash())
+	}
+}
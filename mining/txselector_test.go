@@ -0,0 +1,113 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mining
+
+import (
+	"container/heap"
+	"reflect"
+	"testing"
+
+	"github.com/decred/dcrd/blockchain/stake"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/wire"
+)
+
+// TestTxSelectorByName ensures the transaction selectors may be looked up by
+// their names.
+func TestTxSelectorByName(t *testing.T) {
+	wantNames := []string{TxSelectorAncestorFeeRate, TxSelectorFeeRate,
+		TxSelectorPriority, TxSelectorStandard}
+	if names := TxSelectorNames(); !reflect.DeepEqual(names, wantNames) {
+		t.Fatalf("unexpected selector names -- got %v, want %v", names,
+			wantNames)
+	}
+	for _, name := range wantNames {
+		if _, ok := TxSelectorByName(name); !ok {
+			t.Errorf("selector %q not found", name)
+		}
+	}
+	if _, ok := TxSelectorByName("unknown"); ok {
+		t.Error("unknown selector found")
+	}
+}
+
+// TestApplyPackageFeesPerKB ensures transactions that other transactions
+// depend on are prioritized by the fees of the highest paying package they are
+// part of so that a dependent transaction paying a high fee pulls the
+// transactions it depends on into a block (child pays for parent).
+func TestApplyPackageFeesPerKB(t *testing.T) {
+	// newCandidate returns a candidate for a unique transaction with the
+	// provided fees per kilobyte that depends on the provided candidates.
+	var lockTime uint32
+	newCandidate := func(feePerKB, ancestorFeePerKB float64, dependsOn ...*TxCandidate) *TxCandidate {
+		msgTx := wire.NewMsgTx()
+		msgTx.LockTime = lockTime
+		lockTime++
+		candidate := &TxCandidate{
+			Tx:               dcrutil.NewTx(msgTx),
+			Type:             stake.TxTypeRegular,
+			FeePerKB:         feePerKB,
+			AncestorFeePerKB: ancestorFeePerKB,
+			PackageFeePerKB:  feePerKB,
+		}
+		for _, dep := range dependsOn {
+			if candidate.DependsOn == nil {
+				candidate.DependsOn = make(map[chainhash.Hash]struct{})
+			}
+			candidate.DependsOn[*dep.Tx.Hash()] = struct{}{}
+		}
+		return candidate
+	}
+
+	// Create a low fee parent with a high fee child that in turn has a free
+	// grandchild along with an unrelated transaction and another parent that
+	// only has a lower fee child.
+	parent := newCandidate(1000, 1000)
+	child := newCandidate(50000, 25500, parent)
+	grandchild := newCandidate(0, 17000, child)
+	unrelated := newCandidate(10000, 10000)
+	highFeeParent := newCandidate(30000, 30000)
+	lowFeeChild := newCandidate(2000, 16000, highFeeParent)
+	candidates := []*TxCandidate{grandchild, child, unrelated, parent,
+		highFeeParent, lowFeeChild}
+	ApplyPackageFeesPerKB(candidates)
+
+	tests := []struct {
+		name      string
+		candidate *TxCandidate
+		want      float64
+	}{
+		{"parent", parent, 25500},
+		{"child", child, 50000},
+		{"grandchild", grandchild, 0},
+		{"unrelated", unrelated, 10000},
+		{"high fee parent", highFeeParent, 30000},
+		{"low fee child", lowFeeChild, 2000},
+	}
+	for _, test := range tests {
+		if test.candidate.PackageFeePerKB != test.want {
+			t.Errorf("%s: unexpected package fee per KB -- got %v, want %v",
+				test.name, test.candidate.PackageFeePerKB, test.want)
+		}
+	}
+
+	// Ensure the low fee parent is now prioritized before the unrelated
+	// transaction.
+	pq := newTxPriorityQueue(len(candidates), txPQByStakeAndFee)
+	for _, candidate := range candidates {
+		if candidate.DependsOn == nil {
+			heap.Push(pq, candidate)
+		}
+	}
+	wantOrder := []*TxCandidate{highFeeParent, parent, unrelated}
+	for i, want := range wantOrder {
+		got := heap.Pop(pq).(*TxCandidate)
+		if got != want {
+			t.Fatalf("unexpected candidate popped at index %d -- got %v, "+
+				"want %v", i, got.Tx.Hash(), want.Tx.Hash())
+		}
+	}
+}
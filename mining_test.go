@@ -1,128 +1,370 @@
-// Copyright (c) 2016 The btcsuite developers
-// Copyright (c) 2015-2016 The Decred developers
+// Copyright (c) 2019 The Decred developers
 // Use of this source code is governed by an ISC
 // license that can be found in the LICENSE file.
 
 package main
 
 import (
-	"container/heap"
-	"math/rand"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 
+	"github.com/decred/dcrd/blockchain"
+	"github.com/decred/dcrd/blockchain/chaingen"
 	"github.com/decred/dcrd/blockchain/stake"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/database"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/mining"
+	"github.com/decred/dcrd/txscript"
+	"github.com/decred/dcrd/wire"
 )
 
-// TestStakeTxFeePrioHeap tests the priority heaps including the stake types for
-// both transaction fees per KB and transaction priority. It ensures that the
-// primary sorting is first by stake type, and then by the latter chosen priority
-// type.
-func TestStakeTxFeePrioHeap(t *testing.T) {
-	numElements := 1000
-	numEdgeConditionElements := 12
-	// Create some fake priority items that exercise the expected sort
-	// edge conditions.
-	testItems := []*txPrioItem{
-		{feePerKB: 5678, txType: stake.TxTypeRegular, priority: 3},
-		{feePerKB: 5678, txType: stake.TxTypeRegular, priority: 1},
-		{feePerKB: 5678, txType: stake.TxTypeRegular, priority: 1}, // Duplicate fee and prio
-		{feePerKB: 5678, txType: stake.TxTypeRegular, priority: 5},
-		{feePerKB: 5678, txType: stake.TxTypeRegular, priority: 2},
-		{feePerKB: 1234, txType: stake.TxTypeRegular, priority: 3},
-		{feePerKB: 1234, txType: stake.TxTypeRegular, priority: 1},
-		{feePerKB: 1234, txType: stake.TxTypeRegular, priority: 5},
-		{feePerKB: 1234, txType: stake.TxTypeRegular, priority: 5}, // Duplicate fee and prio
-		{feePerKB: 1234, txType: stake.TxTypeRegular, priority: 2},
-		{feePerKB: 10000, txType: stake.TxTypeRegular, priority: 0}, // Higher fee, smaller prio
-		{feePerKB: 0, txType: stake.TxTypeRegular, priority: 10000}, // Higher prio, lower fee
+// fakeTxSource provides an implementation of the mining.TxSource interface
+// which houses a fixed set of transactions that are not associated with a
+// mempool.
+type fakeTxSource struct {
+	descs []*mining.TxDesc
+	pool  map[chainhash.Hash]*mining.TxDesc
+}
+
+// Ensure the fakeTxSource type implements the mining.TxSource interface.
+var _ mining.TxSource = (*fakeTxSource)(nil)
+
+// LastUpdated returns a fixed time since the fake source never changes once
+// the transactions are added.
+//
+// This is part of the mining.TxSource interface.
+func (s *fakeTxSource) LastUpdated() time.Time {
+	return time.Unix(1546300800, 0)
+}
+
+// MiningDescs returns the descriptors of all transactions in the fake source
+// in the order they were added.
+//
+// This is part of the mining.TxSource interface.
+func (s *fakeTxSource) MiningDescs() []*mining.TxDesc {
+	return s.descs
+}
+
+// HaveTransaction returns whether or not the passed transaction hash exists in
+// the fake source.
+//
+// This is part of the mining.TxSource interface.
+func (s *fakeTxSource) HaveTransaction(hash *chainhash.Hash) bool {
+	_, ok := s.pool[*hash]
+	return ok
+}
+
+// HaveAllTransactions returns whether or not all of the passed transaction
+// hashes exist in the fake source.
+//
+// This is part of the mining.TxSource interface.
+func (s *fakeTxSource) HaveAllTransactions(hashes []chainhash.Hash) bool {
+	for i := range hashes {
+		if !s.HaveTransaction(&hashes[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// VoteHashesForBlock returns no votes since the fake source does not house
+// any votes.
+//
+// This is part of the mining.TxSource interface.
+func (s *fakeTxSource) VoteHashesForBlock(hash *chainhash.Hash) []chainhash.Hash {
+	return nil
+}
+
+// VotesForBlocks returns no votes since the fake source does not house any
+// votes.
+//
+// This is part of the mining.TxSource interface.
+func (s *fakeTxSource) VotesForBlocks(hashes []chainhash.Hash) [][]mining.VoteDesc {
+	return make([][]mining.VoteDesc, len(hashes))
+}
+
+// IsRegTxTreeKnownDisapproved always returns false for the fake source.
+//
+// This is part of the mining.TxSource interface.
+func (s *fakeTxSource) IsRegTxTreeKnownDisapproved(hash *chainhash.Hash) bool {
+	return false
+}
+
+// addTx adds the passed transaction of the given type which pays the provided
+// fee to the fake source along with the statistics of it and all of its
+// ancestors in the source.
+func (s *fakeTxSource) addTx(msgTx *wire.MsgTx, txType stake.TxType, fee int64) *dcrutil.Tx {
+	tx := dcrutil.NewTx(msgTx)
+	tx.SetTree(wire.TxTreeRegular)
+	if txType != stake.TxTypeRegular {
+		tx.SetTree(wire.TxTreeStake)
+	}
+	size := int64(msgTx.SerializeSize())
+	desc := &mining.TxDesc{Tx: tx, Type: txType, Fee: fee}
+	desc.Ancestors = mining.TxPackageStats{Count: 1, Size: size, Fees: fee}
+	visited := make(map[chainhash.Hash]struct{})
+	processList := []*wire.MsgTx{msgTx}
+	for len(processList) > 0 {
+		processItem := processList[0]
+		processList = processList[1:]
+		for _, txIn := range processItem.TxIn {
+			originHash := txIn.PreviousOutPoint.Hash
+			origin, ok := s.pool[originHash]
+			if !ok {
+				continue
+			}
+			if _, ok := visited[originHash]; ok {
+				continue
+			}
+			visited[originHash] = struct{}{}
+
+			desc.Ancestors.Count++
+			desc.Ancestors.Size += int64(origin.Tx.MsgTx().SerializeSize())
+			desc.Ancestors.Fees += origin.Fee
+			processList = append(processList, origin.Tx.MsgTx())
+		}
+	}
+
+	s.descs = append(s.descs, desc)
+	s.pool[*tx.Hash()] = desc
+	return tx
+}
+
+// TestNewBlockTemplateTxSelectors ensures block templates include the expected
+// transactions from a synthetic transaction source in the expected order for
+// each of the transaction selectors and script filters.
+func TestNewBlockTemplateTxSelectors(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "miningtest")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dataDir)
+	oldCfg, oldParams := cfg, activeNetParams
+	cfg, activeNetParams = &config{}, &regNetParams
+	defer func() {
+		cfg, activeNetParams = oldCfg, oldParams
+	}()
+
+	// Create a chain with enough blocks for tickets to be allowed in the
+	// next block and several mature coinbases.
+	params := *regNetParams.Params
+	db, err := database.Create("ffldb", filepath.Join(dataDir, "blocks"),
+		params.Net)
+	if err != nil {
+		t.Fatalf("unable to create db: %v", err)
+	}
+	defer db.Close()
+	chain, err := blockchain.New(&blockchain.Config{
+		DB:          db,
+		ChainParams: &params,
+		TimeSource:  blockchain.NewMedianTime(),
+		SigCache:    txscript.NewSigCache(1000),
+	})
+	if err != nil {
+		t.Fatalf("unable to create chain: %v", err)
+	}
+	g, err := chaingen.MakeGenerator(&params)
+	if err != nil {
+		t.Fatalf("unable to create generator: %v", err)
 	}
-	ph := newTxPriorityQueue((numElements + numEdgeConditionElements), txPQByStakeAndFee)
-
-	// Add random data in addition to the edge conditions already manually
-	// specified.
-	for i := 0; i < (numElements + numEdgeConditionElements); i++ {
-		if i >= numEdgeConditionElements {
-			randType := stake.TxType(rand.Intn(4))
-			randPrio := rand.Float64() * 100
-			randFeePerKB := rand.Float64() * 10
-			testItems = append(testItems, &txPrioItem{
-				tx:       nil,
-				txType:   randType,
-				feePerKB: randFeePerKB,
-				priority: randPrio,
-			})
+	var blocks []*wire.MsgBlock
+	for i := 0; i < int(params.StakeEnabledHeight); i++ {
+		if i == 0 {
+			g.CreatePremineBlock("bp", 0)
+		} else {
+			g.NextBlock(fmt.Sprintf("b%d", i), nil, nil)
 		}
+		block := dcrutil.NewBlock(g.Tip())
+		_, _, err := chain.ProcessBlock(block, blockchain.BFNone)
+		if err != nil {
+			t.Fatalf("unable to process block %d: %v", i+1, err)
+		}
+		blocks = append(blocks, g.Tip())
+	}
 
-		heap.Push(ph, testItems[i])
+	// Create a source with a ticket along with a free transaction, an
+	// unrelated transaction paying a medium fee, and a low fee parent that
+	// has a high fee child.  The free transaction spends the oldest
+	// coinbase output and therefore has the highest priority followed by
+	// the unrelated transaction and the parent.  The child has no priority
+	// since its input is not in the chain.
+	source := &fakeTxSource{pool: make(map[chainhash.Hash]*mining.TxDesc)}
+	spend := func(height int) *chaingen.SpendableOut {
+		out := chaingen.MakeSpendableOut(blocks[height-1], 0, 2)
+		return &out
+	}
+	addSpend := func(out *chaingen.SpendableOut, fee int64) *dcrutil.Tx {
+		msgTx := g.CreateSpendTx(out, dcrutil.Amount(fee))
+		return source.addTx(msgTx, stake.TxTypeRegular, fee)
 	}
+	ticketPrice := dcrutil.Amount(chain.BestSnapshot().NextStakeDiff)
+	ticket := source.addTx(g.CreateTicketPurchaseTx(spend(5), ticketPrice,
+		2000), stake.TxTypeSStx, 2000)
+	free := addSpend(spend(2), 0)
+	unrelated := addSpend(spend(3), 3000)
+	parent := addSpend(spend(4), 300)
+	parentOut := chaingen.MakeSpendableOutForTx(parent.MsgTx(), 0, 0, 0)
+	child := addSpend(&parentOut, 15000)
 
-	// Test sorting by stake and fee per KB.
-	last := &txPrioItem{
-		tx:       nil,
-		txType:   stake.TxTypeSSGen,
-		priority: 10000.0,
-		feePerKB: 10000.0,
+	// opReturnScript returns the unique data-only output script of the
+	// passed transaction created by the generator.
+	opReturnScript := func(tx *dcrutil.Tx) []byte {
+		return tx.MsgTx().TxOut[1].PkScript
 	}
-	for i := 0; i < numElements; i++ {
-		prioItem := heap.Pop(ph)
-		txpi, ok := prioItem.(*txPrioItem)
-		if ok {
-			if txpi.feePerKB > last.feePerKB &&
-				compareStakePriority(txpi, last) >= 0 {
-				t.Errorf("bad pop: %v fee per KB was more than last of %v "+
-					"while the txtype was %v but last was %v",
-					txpi.feePerKB, last.feePerKB, txpi.txType, last.txType)
-			}
-			last = txpi
+
+	// policy returns a policy which uses the passed selector and does not
+	// allocate any space for high-priority transactions.
+	policy := func(newSelector mining.NewTxSelectorFunc) mining.Policy {
+		return mining.Policy{
+			BlockMaxSize:  375000,
+			TxMinFreeFee:  1000,
+			NewTxSelector: newSelector,
 		}
 	}
 
-	ph = newTxPriorityQueue(len(testItems), txPQByStakeAndFeeAndThenPriority)
-	for i := 0; i < numElements; i++ {
-		randType := stake.TxType(rand.Intn(4))
-		randPrio := rand.Float64() * 100
-		randFeePerKB := rand.Float64() * 10
-		prioItem := &txPrioItem{
-			tx:       nil,
-			txType:   randType,
-			feePerKB: randFeePerKB,
-			priority: randPrio,
+	// The high-priority area fits the ticket and the free transaction.
+	ticketSize := uint32(ticket.MsgTx().SerializeSize())
+	prioritySize := blockHeaderOverhead + ticketSize +
+		uint32(free.MsgTx().SerializeSize()) + 1
+
+	tests := []struct {
+		name      string
+		policy    func() mining.Policy
+		want      []*dcrutil.Tx
+		wantStake []*dcrutil.Tx
+	}{{
+		name: "standard by package fee",
+		policy: func() mining.Policy {
+			return policy(mining.NewStandardTxSelector)
+		},
+		want:      []*dcrutil.Tx{parent, child, unrelated},
+		wantStake: []*dcrutil.Tx{ticket},
+	}, {
+		name:      "default selector is standard",
+		policy:    func() mining.Policy { return policy(nil) },
+		want:      []*dcrutil.Tx{parent, child, unrelated},
+		wantStake: []*dcrutil.Tx{ticket},
+	}, {
+		name: "standard with high-priority area",
+		policy: func() mining.Policy {
+			p := policy(mining.NewStandardTxSelector)
+			p.BlockPrioritySize = prioritySize
+			return p
+		},
+		want:      []*dcrutil.Tx{free, parent, child, unrelated},
+		wantStake: []*dcrutil.Tx{ticket},
+	}, {
+		name: "standard with minimum block size",
+		policy: func() mining.Policy {
+			p := policy(mining.NewStandardTxSelector)
+			p.BlockMinSize = 100000
+			return p
+		},
+		want:      []*dcrutil.Tx{parent, child, unrelated, free},
+		wantStake: []*dcrutil.Tx{ticket},
+	}, {
+		name: "fee rate",
+		policy: func() mining.Policy {
+			return policy(mining.NewFeeRateTxSelector)
+		},
+		want:      []*dcrutil.Tx{unrelated, parent, child},
+		wantStake: []*dcrutil.Tx{ticket},
+	}, {
+		name: "ancestor fee rate ignores high-priority area",
+		policy: func() mining.Policy {
+			p := policy(mining.NewAncestorFeeRateTxSelector)
+			p.BlockPrioritySize = prioritySize
+			return p
+		},
+		want:      []*dcrutil.Tx{parent, child, unrelated},
+		wantStake: []*dcrutil.Tx{ticket},
+	}, {
+		name: "priority",
+		policy: func() mining.Policy {
+			return policy(mining.NewPriorityTxSelector)
+		},
+		want:      []*dcrutil.Tx{free, unrelated, parent, child},
+		wantStake: []*dcrutil.Tx{ticket},
+	}, {
+		name: "deny script excludes dependents",
+		policy: func() mining.Policy {
+			p := policy(mining.NewAncestorFeeRateTxSelector)
+			p.DenyScripts = [][]byte{opReturnScript(parent)}
+			return p
+		},
+		want:      []*dcrutil.Tx{unrelated},
+		wantStake: []*dcrutil.Tx{ticket},
+	}, {
+		name: "allow script does not filter stake transactions",
+		policy: func() mining.Policy {
+			p := policy(mining.NewFeeRateTxSelector)
+			p.AllowScripts = [][]byte{opReturnScript(free),
+				opReturnScript(unrelated)}
+			return p
+		},
+		want:      []*dcrutil.Tx{unrelated},
+		wantStake: []*dcrutil.Tx{ticket},
+	}, {
+		name: "stake transactions first at maximum block size",
+		policy: func() mining.Policy {
+			p := policy(mining.NewFeeRateTxSelector)
+			p.BlockMaxSize = blockHeaderOverhead + ticketSize + 1
+			return p
+		},
+		want:      nil,
+		wantStake: []*dcrutil.Tx{ticket},
+	}}
+
+	// txHashes returns the hashes of the passed transactions.
+	txHashes := func(txns []*dcrutil.Tx) []chainhash.Hash {
+		hashes := make([]chainhash.Hash, 0, len(txns))
+		for _, tx := range txns {
+			hashes = append(hashes, *tx.Hash())
 		}
-		heap.Push(ph, prioItem)
+		return hashes
 	}
 
-	// Test sorting with fees per KB for high stake priority, then
-	// priority for low stake priority.
-	last = &txPrioItem{
-		tx:       nil,
-		txType:   stake.TxTypeSSGen,
-		priority: 10000.0,
-		feePerKB: 10000.0,
+	// msgTxHashes returns the hashes of the passed transactions.
+	msgTxHashes := func(txns []*wire.MsgTx) []chainhash.Hash {
+		hashes := make([]chainhash.Hash, 0, len(txns))
+		for _, tx := range txns {
+			hashes = append(hashes, tx.TxHash())
+		}
+		return hashes
 	}
-	for i := 0; i < numElements; i++ {
-		prioItem := heap.Pop(ph)
-		txpi, ok := prioItem.(*txPrioItem)
-		if ok {
-			bothAreLowStakePriority :=
-				txStakePriority(txpi.txType) == regOrRevocPriority &&
-					txStakePriority(last.txType) == regOrRevocPriority
-			if !bothAreLowStakePriority {
-				if txpi.feePerKB > last.feePerKB &&
-					compareStakePriority(txpi, last) >= 0 {
-					t.Errorf("bad pop: %v fee per KB was more than last of %v "+
-						"while the txtype was %v but last was %v",
-						txpi.feePerKB, last.feePerKB, txpi.txType, last.txType)
-				}
-			}
-			if bothAreLowStakePriority {
-				if txpi.priority > last.priority &&
-					compareStakePriority(txpi, last) >= 0 {
-					t.Errorf("bad pop: %v priority was more than last of %v "+
-						"while the txtype was %v but last was %v",
-						txpi.feePerKB, last.feePerKB, txpi.txType, last.txType)
-				}
-			}
-			last = txpi
+
+	bm := &blockManager{chain: chain}
+	for _, test := range tests {
+		policy := test.policy()
+		generator := newBlkTmplGenerator(&policy, source,
+			blockchain.NewMedianTime(), txscript.NewSigCache(1000),
+			&params, chain, bm)
+		template, err := generator.NewBlockTemplate(nil)
+		if err != nil {
+			t.Errorf("%s: unable to create block template: %v",
+				test.name, err)
+			continue
+		}
+
+		// The transactions in the template are copies, so compare their
+		// hashes while skipping the coinbase.
+		got := msgTxHashes(template.Block.Transactions[1:])
+		want := txHashes(test.want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: unexpected selected transactions -- got %v, "+
+				"want %v", test.name, got, want)
+		}
+		gotStake := msgTxHashes(template.Block.STransactions)
+		wantStake := txHashes(test.wantStake)
+		if !reflect.DeepEqual(gotStake, wantStake) {
+			t.Errorf("%s: unexpected selected stake transactions -- "+
+				"got %v, want %v", test.name, gotStake, wantStake)
 		}
 	}
 }
@@ -70,6 +70,17 @@ Disconnect to disconnect from the peer and clean up all resources.
 WaitForDisconnect can be used to block until peer disconnection and resource
 cleanup has completed.
 
+Encrypted Transport
+
+Setting the V2Transport field of the Config struct enables the v2 transport
+which encrypts and authenticates all messages with ChaCha20-Poly1305 using keys
+derived from an ephemeral secp256k1 ECDH key exchange performed before the
+version message.  Outbound peers initiate the key exchange, so it should only
+be enabled for them when the remote peer is expected to support it, while
+inbound peers detect whether the remote peer initiated it and otherwise fall
+back to the plaintext v1 transport.  The Transport function reports the
+transport in use.
+
 Callbacks
 
 In order to do anything useful with a peer, it is necessary to react to decred
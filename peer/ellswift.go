@@ -0,0 +1,235 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package peer
+
+import (
+	"crypto/rand"
+	"io"
+	"math/big"
+
+	"github.com/decred/dcrd/dcrec/secp256k1"
+)
+
+// ellSwiftLen is the length of the ElligatorSwift encoding of a public key.
+// It consists of the 32-byte big-endian field elements u and t.
+//
+// The field arithmetic in this file uses big integers since the secp256k1
+// package does not export its field element type.  The curve parameters and
+// point multiplication are taken from the secp256k1 package.
+const ellSwiftLen = 64
+
+var (
+	// fieldPrime is the prime of the field the secp256k1 curve is defined
+	// over.
+	fieldPrime = secp256k1.S256().P
+
+	// fieldSqrtExp is the exponent used to compute square roots in the
+	// field.  It is (p+1)/4 which works since p = 3 mod 4.
+	fieldSqrtExp = secp256k1.S256().QPlus1Div4()
+
+	// fieldSqrtMinus3 is a square root of -3 in the field.
+	fieldSqrtMinus3 = fieldSqrt(new(big.Int).Sub(fieldPrime, big.NewInt(3)))
+)
+
+// fieldMod reduces x modulo the field prime in place and returns it.
+func fieldMod(x *big.Int) *big.Int {
+	return x.Mod(x, fieldPrime)
+}
+
+// fieldSqrt returns a square root of x in the field or nil when x is not a
+// square.
+func fieldSqrt(x *big.Int) *big.Int {
+	r := new(big.Int).Exp(x, fieldSqrtExp, fieldPrime)
+	check := fieldMod(new(big.Int).Mul(r, r))
+	if check.Cmp(fieldMod(new(big.Int).Set(x))) != 0 {
+		return nil
+	}
+	return r
+}
+
+// fieldDiv returns a/b in the field.  The divisor must not be zero.
+func fieldDiv(a, b *big.Int) *big.Int {
+	inv := new(big.Int).ModInverse(b, fieldPrime)
+	return fieldMod(inv.Mul(inv, a))
+}
+
+// curveRHS returns x^3 + 7 in the field, which is y^2 for points on the curve.
+func curveRHS(x *big.Int) *big.Int {
+	r := new(big.Int).Mul(x, x)
+	r.Mul(r, x)
+	return fieldMod(r.Add(r, big.NewInt(7)))
+}
+
+// isValidX returns whether x is the x coordinate of a point on the curve.
+func isValidX(x *big.Int) bool {
+	return fieldSqrt(curveRHS(x)) != nil
+}
+
+// xSwiftEC maps the field elements u and t to the x coordinate of a point on
+// the curve as specified by the ElligatorSwift encoding.  Every pair of field
+// elements maps to a valid x coordinate.
+func xSwiftEC(u, t *big.Int) *big.Int {
+	u = fieldMod(new(big.Int).Set(u))
+	t = fieldMod(new(big.Int).Set(t))
+	if u.Sign() == 0 {
+		u.SetInt64(1)
+	}
+	if t.Sign() == 0 {
+		t.SetInt64(1)
+	}
+	u3Plus7 := curveRHS(u)
+	t2 := fieldMod(new(big.Int).Mul(t, t))
+	if fieldMod(new(big.Int).Add(u3Plus7, t2)).Sign() == 0 {
+		t = fieldMod(t.Lsh(t, 1))
+		t2 = fieldMod(new(big.Int).Mul(t, t))
+	}
+
+	// X = (u^3 + 7 - t^2) / 2t
+	// Y = (X + t) / (sqrt(-3) * u)
+	x := fieldDiv(fieldMod(new(big.Int).Sub(u3Plus7, t2)),
+		fieldMod(new(big.Int).Lsh(t, 1)))
+	y := fieldDiv(fieldMod(new(big.Int).Add(x, t)),
+		fieldMod(new(big.Int).Mul(fieldSqrtMinus3, u)))
+
+	// The candidates are u + 4Y^2, (-X/Y - u)/2, and (X/Y - u)/2.  At least
+	// one of them is always a valid x coordinate.
+	two := big.NewInt(2)
+	xy := fieldDiv(x, y)
+	candidate := fieldMod(new(big.Int).Mul(y, y))
+	candidate = fieldMod(candidate.Lsh(candidate, 2).Add(candidate, u))
+	if isValidX(candidate) {
+		return candidate
+	}
+	candidate = fieldMod(new(big.Int).Neg(xy))
+	candidate = fieldDiv(fieldMod(candidate.Sub(candidate, u)), two)
+	if isValidX(candidate) {
+		return candidate
+	}
+	candidate = fieldMod(new(big.Int).Sub(xy, u))
+	return fieldDiv(candidate, two)
+}
+
+// xSwiftECInv returns a field element t such that xSwiftEC(u, t) is x, or nil
+// when no such element exists for the passed u and case.  The case selects
+// which of the up to eight preimages is returned.
+func xSwiftECInv(x, u *big.Int, c byte) *big.Int {
+	var v, s *big.Int
+	u3Plus7 := curveRHS(u)
+	u2 := fieldMod(new(big.Int).Mul(u, u))
+	if c&2 == 0 {
+		negXU := fieldMod(new(big.Int).Neg(new(big.Int).Add(x, u)))
+		if isValidX(negXU) {
+			return nil
+		}
+
+		// s = -(u^3 + 7) / (u^2 + uv + v^2)
+		v = x
+		denom := new(big.Int).Mul(u, v)
+		denom.Add(denom, u2)
+		denom = fieldMod(denom.Add(denom, new(big.Int).Mul(v, v)))
+		if denom.Sign() == 0 {
+			return nil
+		}
+		s = fieldDiv(fieldMod(new(big.Int).Neg(u3Plus7)), denom)
+	} else {
+		s = fieldMod(new(big.Int).Sub(x, u))
+		if s.Sign() == 0 {
+			return nil
+		}
+
+		// r = sqrt(-s(4(u^3 + 7) + 3su^2))
+		// v = (-u + r/s) / 2
+		r := new(big.Int).Mul(big.NewInt(3), s)
+		r.Mul(r, u2)
+		r.Add(r, new(big.Int).Lsh(u3Plus7, 2))
+		r = fieldMod(r.Mul(r, new(big.Int).Neg(s)))
+		r = fieldSqrt(r)
+		if r == nil || (c&1 != 0 && r.Sign() == 0) {
+			return nil
+		}
+		v = fieldMod(new(big.Int).Sub(fieldDiv(r, s), u))
+		v = fieldDiv(v, big.NewInt(2))
+	}
+	w := fieldSqrt(s)
+	if w == nil {
+		return nil
+	}
+
+	// The result is w(u(1 - sqrt(-3))/2 + v) or w(u(1 + sqrt(-3))/2 + v)
+	// depending on the case, negated for cases 0 and 5.
+	factor := big.NewInt(1)
+	if c&1 == 0 {
+		factor.Sub(factor, fieldSqrtMinus3)
+	} else {
+		factor.Add(factor, fieldSqrtMinus3)
+	}
+	factor = fieldDiv(fieldMod(factor.Mul(factor, u)), big.NewInt(2))
+	t := fieldMod(factor.Add(factor, v).Mul(factor, w))
+	if c&5 == 0 || c&5 == 5 {
+		t = fieldMod(t.Neg(t))
+	}
+	return t
+}
+
+// putFieldBytes serializes the passed field element into the passed 32-byte
+// slice as a big-endian integer padded with leading zeros.
+func putFieldBytes(b []byte, x *big.Int) {
+	for i := range b {
+		b[i] = 0
+	}
+	xb := x.Bytes()
+	copy(b[len(b)-len(xb):], xb)
+}
+
+// ellSwiftEncode returns a uniformly random ElligatorSwift encoding of the
+// passed x coordinate.
+func ellSwiftEncode(x *big.Int) ([ellSwiftLen]byte, error) {
+	var enc [ellSwiftLen]byte
+	var buf [33]byte
+	for {
+		if _, err := io.ReadFull(rand.Reader, buf[:]); err != nil {
+			return enc, err
+		}
+		u := fieldMod(new(big.Int).SetBytes(buf[:32]))
+		if u.Sign() == 0 {
+			continue
+		}
+		t := xSwiftECInv(x, u, buf[32]&7)
+		if t == nil {
+			continue
+		}
+		putFieldBytes(enc[:32], u)
+		putFieldBytes(enc[32:], t)
+		return enc, nil
+	}
+}
+
+// ellSwiftDecode returns the x coordinate encoded by the passed ElligatorSwift
+// encoding.  Every encoding decodes to a valid x coordinate.
+func ellSwiftDecode(enc []byte) *big.Int {
+	u := new(big.Int).SetBytes(enc[:32])
+	t := new(big.Int).SetBytes(enc[32:ellSwiftLen])
+	return xSwiftEC(u, t)
+}
+
+// ellSwiftECDH returns the x coordinate of the product of the passed private
+// key and the point encoded by the passed ElligatorSwift encoding as a 32-byte
+// big-endian shared secret.
+//
+// This is NOT constant time.  Neither the big integer field arithmetic used to
+// decode the encoding nor the point multiplication of the secp256k1 package
+// run in constant time, so the timing may leak information about the private
+// key.  The keys are ephemeral and only used for a single connection, which
+// limits the exposure.
+func ellSwiftECDH(privKey *secp256k1.PrivateKey, enc []byte) []byte {
+	// Either point with the decoded x coordinate results in the same x
+	// coordinate of the product, so the sign of y does not matter.
+	x := ellSwiftDecode(enc)
+	y := fieldSqrt(curveRHS(x))
+	sx, _ := secp256k1.S256().ScalarMult(x, y, privKey.D.Bytes())
+	secret := make([]byte, 32)
+	putFieldBytes(secret, sx)
+	return secret
+}
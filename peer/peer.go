@@ -8,6 +8,7 @@ package peer
 import (
 	"bytes"
 	"container/list"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
@@ -28,7 +29,7 @@ import (
 
 const (
 	// MaxProtocolVersion is the max protocol version the peer supports.
-	MaxProtocolVersion = wire.NodeCFVersion
+	MaxProtocolVersion = wire.AddrV2Version
 
 	// outputBufferSize is the number of elements the output channels use.
 	outputBufferSize = 5000
@@ -184,6 +185,27 @@ type MessageListeners struct {
 	// message.
 	OnSendHeaders func(p *Peer, msg *wire.MsgSendHeaders)
 
+	// OnSendCmpct is invoked when a peer receives a sendcmpct wire message.
+	OnSendCmpct func(p *Peer, msg *wire.MsgSendCmpct)
+
+	// OnCmpctBlock is invoked when a peer receives a cmpctblock wire
+	// message.
+	OnCmpctBlock func(p *Peer, msg *wire.MsgCmpctBlock)
+
+	// OnGetBlockTxn is invoked when a peer receives a getblocktxn wire
+	// message.
+	OnGetBlockTxn func(p *Peer, msg *wire.MsgGetBlockTxn)
+
+	// OnBlockTxn is invoked when a peer receives a blocktxn wire message.
+	OnBlockTxn func(p *Peer, msg *wire.MsgBlockTxn)
+
+	// OnSendAddrV2 is invoked when a peer receives a sendaddrv2 wire
+	// message.
+	OnSendAddrV2 func(p *Peer, msg *wire.MsgSendAddrV2)
+
+	// OnAddrV2 is invoked when a peer receives an addrv2 wire message.
+	OnAddrV2 func(p *Peer, msg *wire.MsgAddrV2)
+
 	// OnRead is invoked when a peer receives a wire message.  It consists
 	// of the number of bytes read, the message, and whether or not an error
 	// in the read occurred.  Typically, callers will opt to use the
@@ -252,6 +274,14 @@ type Config struct {
 	// not send inv messages for transactions.
 	DisableRelayTx bool
 
+	// V2Transport specifies whether the encrypted v2 transport is used.
+	// Outbound peers initiate the v2 transport handshake before sending
+	// the version message, so it should only be set for them when the
+	// remote peer is expected to support it.  Inbound peers use the v2
+	// transport when the remote peer initiates it and transparently fall
+	// back to the v1 transport otherwise.
+	V2Transport bool
+
 	// Listeners houses callback functions to be invoked on receiving peer
 	// messages.
 	Listeners MessageListeners
@@ -357,6 +387,7 @@ type StatsSnap struct {
 	LastPingNonce  uint64
 	LastPingTime   time.Time
 	LastPingMicros int64
+	Transport      TransportVersion
 }
 
 // HashFunc is a function which returns a block hash, height and error
@@ -408,6 +439,11 @@ type Peer struct {
 
 	conn net.Conn
 
+	// stream is used to read and write messages.  It is the connection
+	// itself for the v1 transport or wraps it for the v2 transport and is
+	// only modified during protocol negotiation.
+	stream io.ReadWriter
+
 	// These fields are set at creation time and never modified, so they are
 	// safe to read from concurrently without a mutex.
 	addr    string
@@ -423,6 +459,10 @@ type Peer struct {
 	advertisedProtoVer   uint32 // protocol version advertised by remote
 	protocolVersion      uint32 // negotiated protocol version
 	sendHeadersPreferred bool   // peer sent a sendheaders message
+	cmpctBlockVersion    uint64 // compact block version from sendcmpct
+	sendCmpctPreferred   bool   // peer requested compact announcements
+	sendAddrV2Preferred  bool   // peer sent a sendaddrv2 message
+	transport            TransportVersion
 	versionSent          bool
 	verAckReceived       bool
 
@@ -508,6 +548,7 @@ func (p *Peer) StatsSnapshot() *StatsSnap {
 	userAgent := p.userAgent
 	services := p.services
 	protocolVersion := p.advertisedProtoVer
+	transport := p.transport
 	p.flagsMtx.Unlock()
 
 	// Get a copy of all relevant flags and stats.
@@ -529,6 +570,7 @@ func (p *Peer) StatsSnapshot() *StatsSnap {
 		LastPingNonce:  p.lastPingNonce,
 		LastPingMicros: p.lastPingMicros,
 		LastPingTime:   p.lastPingTime,
+		Transport:      transport,
 	}
 
 	p.statsMtx.RUnlock()
@@ -772,6 +814,45 @@ func (p *Peer) WantsHeaders() bool {
 	return sendHeadersPreferred
 }
 
+// CmpctBlockVersion returns the compact block encoding version the peer
+// signalled support for via a sendcmpct message.  It will be zero when the peer
+// has not signalled support for any compact block version this package
+// understands.
+//
+// This function is safe for concurrent access.
+func (p *Peer) CmpctBlockVersion() uint64 {
+	p.flagsMtx.Lock()
+	version := p.cmpctBlockVersion
+	p.flagsMtx.Unlock()
+
+	return version
+}
+
+// WantsCmpctBlocks returns if the peer wants new blocks to be announced by
+// directly sending compact block messages instead of inventory vectors or
+// headers.
+//
+// This function is safe for concurrent access.
+func (p *Peer) WantsCmpctBlocks() bool {
+	p.flagsMtx.Lock()
+	sendCmpctPreferred := p.sendCmpctPreferred
+	p.flagsMtx.Unlock()
+
+	return sendCmpctPreferred
+}
+
+// WantsAddrV2 returns if the peer prefers to be sent addresses via addrv2
+// messages instead of addr messages.
+//
+// This function is safe for concurrent access.
+func (p *Peer) WantsAddrV2() bool {
+	p.flagsMtx.Lock()
+	sendAddrV2Preferred := p.sendAddrV2Preferred
+	p.flagsMtx.Unlock()
+
+	return sendAddrV2Preferred
+}
+
 // PushAddrMsg sends an addr message to the connected peer using the provided
 // addresses.  This function is useful over manually sending the message via
 // QueueMessage since it automatically limits the addresses to the maximum
@@ -779,32 +860,45 @@ func (p *Peer) WantsHeaders() bool {
 // are too many.  It returns the addresses that were actually sent and no
 // message will be sent if there are no entries in the provided addresses slice.
 //
+// An addrv2 message is sent instead when the peer prefers it.  Otherwise,
+// addresses that can only be represented by the addrv2 message, such as Tor v3
+// onion services, are not sent.
+//
 // This function is safe for concurrent access.
 func (p *Peer) PushAddrMsg(addresses []*wire.NetAddress) ([]*wire.NetAddress, error) {
+	addrList := make([]*wire.NetAddress, 0, len(addresses))
+	wantsAddrV2 := p.WantsAddrV2()
+	for _, na := range addresses {
+		if wantsAddrV2 || na.IsIP() {
+			addrList = append(addrList, na)
+		}
+	}
 
 	// Nothing to send.
-	if len(addresses) == 0 {
+	if len(addrList) == 0 {
 		return nil, nil
 	}
 
-	msg := wire.NewMsgAddr()
-	msg.AddrList = make([]*wire.NetAddress, len(addresses))
-	copy(msg.AddrList, addresses)
-
 	// Randomize the addresses sent if there are more than the maximum allowed.
-	if len(msg.AddrList) > wire.MaxAddrPerMsg {
+	if len(addrList) > wire.MaxAddrPerMsg {
 		// Shuffle the address list.
-		for i := range msg.AddrList {
+		for i := range addrList {
 			j := rand.Intn(i + 1)
-			msg.AddrList[i], msg.AddrList[j] = msg.AddrList[j], msg.AddrList[i]
+			addrList[i], addrList[j] = addrList[j], addrList[i]
 		}
 
 		// Truncate it to the maximum size.
-		msg.AddrList = msg.AddrList[:wire.MaxAddrPerMsg]
+		addrList = addrList[:wire.MaxAddrPerMsg]
 	}
 
+	var msg wire.Message
+	if wantsAddrV2 {
+		msg = &wire.MsgAddrV2{AddrList: addrList}
+	} else {
+		msg = &wire.MsgAddr{AddrList: addrList}
+	}
 	p.QueueMessage(msg, nil)
-	return msg.AddrList, nil
+	return addrList, nil
 }
 
 // PushGetBlocksMsg sends a getblocks message for the provided block locator
@@ -958,7 +1052,7 @@ func (p *Peer) handlePongMsg(msg *wire.MsgPong) {
 
 // readMessage reads the next wire message from the peer with logging.
 func (p *Peer) readMessage() (wire.Message, []byte, error) {
-	n, msg, buf, err := wire.ReadMessageN(p.conn, p.ProtocolVersion(),
+	n, msg, buf, err := wire.ReadMessageN(p.stream, p.ProtocolVersion(),
 		p.cfg.ChainParams.Net)
 	atomic.AddUint64(&p.bytesReceived, uint64(n))
 	if p.cfg.Listeners.OnRead != nil {
@@ -1020,9 +1114,22 @@ func (p *Peer) writeMessage(msg wire.Message) error {
 		return spew.Sdump(buf.Bytes())
 	}))
 
-	// Write the message to the peer.
-	n, err := wire.WriteMessageN(p.conn, msg, p.ProtocolVersion(),
-		p.cfg.ChainParams.Net)
+	// Write the message to the peer.  Messages are serialized before
+	// writing them to the v2 transport so that each one is sent as a single
+	// record.
+	var n int
+	var err error
+	if p.Transport() == TransportV2 {
+		var buf bytes.Buffer
+		n, err = wire.WriteMessageN(&buf, msg, p.ProtocolVersion(),
+			p.cfg.ChainParams.Net)
+		if err == nil {
+			_, err = p.stream.Write(buf.Bytes())
+		}
+	} else {
+		n, err = wire.WriteMessageN(p.stream, msg, p.ProtocolVersion(),
+			p.cfg.ChainParams.Net)
+	}
 	atomic.AddUint64(&p.bytesSent, uint64(n))
 	if p.cfg.Listeners.OnWrite != nil {
 		p.cfg.Listeners.OnWrite(p, n, msg, err)
@@ -1078,11 +1185,16 @@ func (p *Peer) maybeAddDeadline(pendingResponses map[string]time.Time, msgCmd st
 		pendingResponses[wire.CmdInv] = deadline
 
 	case wire.CmdGetData:
-		// Expects a block, tx, or notfound message.
+		// Expects a block, cmpctblock, tx, or notfound message.
 		pendingResponses[wire.CmdBlock] = deadline
+		pendingResponses[wire.CmdCmpctBlock] = deadline
 		pendingResponses[wire.CmdTx] = deadline
 		pendingResponses[wire.CmdNotFound] = deadline
 
+	case wire.CmdGetBlockTxn:
+		// Expects a blocktxn message.
+		pendingResponses[wire.CmdBlockTxn] = deadline
+
 	case wire.CmdGetHeaders:
 		// Expects a headers message.  Use a longer deadline since it
 		// can take a while for the remote peer to load all of the
@@ -1139,10 +1251,13 @@ out:
 				switch msgCmd := msg.message.Command(); msgCmd {
 				case wire.CmdBlock:
 					fallthrough
+				case wire.CmdCmpctBlock:
+					fallthrough
 				case wire.CmdTx:
 					fallthrough
 				case wire.CmdNotFound:
 					delete(pendingResponses, wire.CmdBlock)
+					delete(pendingResponses, wire.CmdCmpctBlock)
 					delete(pendingResponses, wire.CmdTx)
 					delete(pendingResponses, wire.CmdNotFound)
 
@@ -1437,6 +1552,50 @@ out:
 				p.cfg.Listeners.OnSendHeaders(p, msg)
 			}
 
+		case *wire.MsgSendCmpct:
+			// Ignore compact block versions that are not understood
+			// as they might be sent by future peers that also
+			// support older versions.
+			if msg.CmpctBlockVersion == wire.CmpctBlockVersion1 {
+				p.flagsMtx.Lock()
+				p.cmpctBlockVersion = msg.CmpctBlockVersion
+				p.sendCmpctPreferred = msg.AnnounceUsingCmpct
+				p.flagsMtx.Unlock()
+			}
+
+			if p.cfg.Listeners.OnSendCmpct != nil {
+				p.cfg.Listeners.OnSendCmpct(p, msg)
+			}
+
+		case *wire.MsgCmpctBlock:
+			if p.cfg.Listeners.OnCmpctBlock != nil {
+				p.cfg.Listeners.OnCmpctBlock(p, msg)
+			}
+
+		case *wire.MsgGetBlockTxn:
+			if p.cfg.Listeners.OnGetBlockTxn != nil {
+				p.cfg.Listeners.OnGetBlockTxn(p, msg)
+			}
+
+		case *wire.MsgBlockTxn:
+			if p.cfg.Listeners.OnBlockTxn != nil {
+				p.cfg.Listeners.OnBlockTxn(p, msg)
+			}
+
+		case *wire.MsgSendAddrV2:
+			p.flagsMtx.Lock()
+			p.sendAddrV2Preferred = true
+			p.flagsMtx.Unlock()
+
+			if p.cfg.Listeners.OnSendAddrV2 != nil {
+				p.cfg.Listeners.OnSendAddrV2(p, msg)
+			}
+
+		case *wire.MsgAddrV2:
+			if p.cfg.Listeners.OnAddrV2 != nil {
+				p.cfg.Listeners.OnAddrV2(p, msg)
+			}
+
 		default:
 			log.Debugf("Received unhandled message of type %v "+
 				"from %v", rmsg.Command(), p)
@@ -1936,10 +2095,80 @@ func (p *Peer) writeLocalVersionMsg() error {
 	return nil
 }
 
-// negotiateInboundProtocol waits to receive a version message from the peer
-// then sends our version message. If the events do not occur in that order then
-// it returns an error.
+// negotiateInboundTransport determines whether the remote peer is initiating
+// the v2 transport and completes its handshake when it is.  Peers using the v1
+// transport always start by sending a message with the network magic which is
+// never a valid v2 transport public key.
+func (p *Peer) negotiateInboundTransport() error {
+	if !p.cfg.V2Transport {
+		return nil
+	}
+
+	var magic [4]byte
+	if _, err := io.ReadFull(p.conn, magic[:]); err != nil {
+		return err
+	}
+	if binary.LittleEndian.Uint32(magic[:]) == uint32(p.cfg.ChainParams.Net) {
+		// Replay the magic that was already read for the v1 transport.
+		p.stream = struct {
+			io.Reader
+			io.Writer
+		}{io.MultiReader(bytes.NewReader(magic[:]), p.conn), p.conn}
+		return nil
+	}
+
+	stream, err := respondV2Transport(p.conn, p.cfg.ChainParams.Net,
+		magic[:])
+	if err != nil {
+		return err
+	}
+	p.setV2Transport(stream)
+	return nil
+}
+
+// negotiateOutboundTransport initiates the v2 transport handshake when it is
+// enabled.
+func (p *Peer) negotiateOutboundTransport() error {
+	if !p.cfg.V2Transport {
+		return nil
+	}
+
+	stream, err := initiateV2Transport(p.conn, p.cfg.ChainParams.Net)
+	if err != nil {
+		return err
+	}
+	p.setV2Transport(stream)
+	return nil
+}
+
+// setV2Transport sets the stream used to read and write messages to the passed
+// v2 transport stream.
+func (p *Peer) setV2Transport(stream *v2Stream) {
+	p.stream = stream
+
+	p.flagsMtx.Lock()
+	p.transport = TransportV2
+	p.flagsMtx.Unlock()
+}
+
+// Transport returns the transport used to exchange messages with the peer.
+//
+// This function is safe for concurrent access.
+func (p *Peer) Transport() TransportVersion {
+	p.flagsMtx.Lock()
+	transport := p.transport
+	p.flagsMtx.Unlock()
+
+	return transport
+}
+
+// negotiateInboundProtocol negotiates the transport and waits to receive a
+// version message from the peer then sends our version message. If the events
+// do not occur in that order then it returns an error.
 func (p *Peer) negotiateInboundProtocol() error {
+	if err := p.negotiateInboundTransport(); err != nil {
+		return err
+	}
 	if err := p.readRemoteVersionMsg(); err != nil {
 		return err
 	}
@@ -1947,10 +2176,13 @@ func (p *Peer) negotiateInboundProtocol() error {
 	return p.writeLocalVersionMsg()
 }
 
-// negotiateOutboundProtocol sends our version message then waits to receive a
-// version message from the peer.  If the events do not occur in that order then
-// it returns an error.
+// negotiateOutboundProtocol negotiates the transport and sends our version
+// message then waits to receive a version message from the peer.  If the events
+// do not occur in that order then it returns an error.
 func (p *Peer) negotiateOutboundProtocol() error {
+	if err := p.negotiateOutboundTransport(); err != nil {
+		return err
+	}
 	if err := p.writeLocalVersionMsg(); err != nil {
 		return err
 	}
@@ -2006,6 +2238,7 @@ func (p *Peer) AssociateConnection(conn net.Conn) {
 	}
 
 	p.conn = conn
+	p.stream = conn
 	p.timeConnected = time.Now()
 
 	if p.inbound {
@@ -2070,6 +2303,7 @@ func newPeerBase(cfg *Config, inbound bool) *Peer {
 		cfg:             *cfg, // Copy so caller can't mutate.
 		services:        cfg.Services,
 		protocolVersion: protocolVersion,
+		transport:       TransportV1,
 	}
 	return &p
 }
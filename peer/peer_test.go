@@ -320,6 +320,79 @@ func TestPeerConnection(t *testing.T) {
 	}
 }
 
+// TestPeerTransport tests that the v2 transport is negotiated when it is
+// enabled for both peers and that inbound peers fall back to the v1 transport
+// for outbound peers that do not initiate it.
+func TestPeerTransport(t *testing.T) {
+	tests := []struct {
+		name          string
+		inboundV2     bool
+		outboundV2    bool
+		wantTransport peer.TransportVersion
+	}{
+		{"both v2", true, true, peer.TransportV2},
+		{"inbound v2 fallback", true, false, peer.TransportV1},
+		{"both v1", false, false, peer.TransportV1},
+	}
+
+	for _, test := range tests {
+		verack := make(chan struct{}, 2)
+		peerCfg := peer.Config{
+			Listeners: peer.MessageListeners{
+				OnVerAck: func(p *peer.Peer, msg *wire.MsgVerAck) {
+					verack <- struct{}{}
+				},
+			},
+			UserAgentName:    "peer",
+			UserAgentVersion: "1.0",
+			ChainParams:      &chaincfg.MainNetParams,
+		}
+		inConn, outConn := pipe(
+			&conn{raddr: "10.0.0.1:8333"},
+			&conn{raddr: "10.0.0.2:8333"},
+		)
+		inCfg := peerCfg
+		inCfg.V2Transport = test.inboundV2
+		inPeer := peer.NewInboundPeer(&inCfg)
+		inPeer.AssociateConnection(inConn)
+
+		outCfg := peerCfg
+		outCfg.V2Transport = test.outboundV2
+		outPeer, err := peer.NewOutboundPeer(&outCfg, "10.0.0.2:8333")
+		if err != nil {
+			t.Fatalf("%s: NewOutboundPeer: unexpected err %v", test.name,
+				err)
+		}
+		outPeer.AssociateConnection(outConn)
+
+		for i := 0; i < 2; i++ {
+			select {
+			case <-verack:
+			case <-time.After(time.Second):
+				t.Fatalf("%s: verack timeout", test.name)
+			}
+		}
+
+		for _, p := range []*peer.Peer{inPeer, outPeer} {
+			if got := p.Transport(); got != test.wantTransport {
+				t.Errorf("%s: unexpected transport for %v - got %v, "+
+					"want %v", test.name, p, got,
+					test.wantTransport)
+			}
+			if got := p.StatsSnapshot().Transport; got != test.wantTransport {
+				t.Errorf("%s: unexpected stats transport for %v - "+
+					"got %v, want %v", test.name, p, got,
+					test.wantTransport)
+			}
+		}
+
+		inPeer.Disconnect()
+		outPeer.Disconnect()
+		inPeer.WaitForDisconnect()
+		outPeer.WaitForDisconnect()
+	}
+}
+
 // TestPeerListeners tests that the peer listeners are called as expected.
 func TestPeerListeners(t *testing.T) {
 	verack := make(chan struct{}, 1)
@@ -399,6 +472,24 @@ func TestPeerListeners(t *testing.T) {
 			OnSendHeaders: func(p *peer.Peer, msg *wire.MsgSendHeaders) {
 				ok <- msg
 			},
+			OnSendCmpct: func(p *peer.Peer, msg *wire.MsgSendCmpct) {
+				ok <- msg
+			},
+			OnCmpctBlock: func(p *peer.Peer, msg *wire.MsgCmpctBlock) {
+				ok <- msg
+			},
+			OnGetBlockTxn: func(p *peer.Peer, msg *wire.MsgGetBlockTxn) {
+				ok <- msg
+			},
+			OnBlockTxn: func(p *peer.Peer, msg *wire.MsgBlockTxn) {
+				ok <- msg
+			},
+			OnSendAddrV2: func(p *peer.Peer, msg *wire.MsgSendAddrV2) {
+				ok <- msg
+			},
+			OnAddrV2: func(p *peer.Peer, msg *wire.MsgAddrV2) {
+				ok <- msg
+			},
 		},
 		UserAgentName:    "peer",
 		UserAgentVersion: "1.0",
@@ -533,6 +624,30 @@ func TestPeerListeners(t *testing.T) {
 			"OnSendHeaders",
 			wire.NewMsgSendHeaders(),
 		},
+		{
+			"OnSendCmpct",
+			wire.NewMsgSendCmpct(true, wire.CmpctBlockVersion1),
+		},
+		{
+			"OnCmpctBlock",
+			wire.NewMsgCmpctBlock(&wire.BlockHeader{}, 0),
+		},
+		{
+			"OnGetBlockTxn",
+			wire.NewMsgGetBlockTxn(&chainhash.Hash{}, []uint32{0}, nil),
+		},
+		{
+			"OnBlockTxn",
+			wire.NewMsgBlockTxn(&chainhash.Hash{}),
+		},
+		{
+			"OnSendAddrV2",
+			wire.NewMsgSendAddrV2(),
+		},
+		{
+			"OnAddrV2",
+			wire.NewMsgAddrV2(),
+		},
 	}
 	t.Logf("Running %d tests", len(tests))
 	for _, test := range tests {
@@ -545,6 +660,23 @@ func TestPeerListeners(t *testing.T) {
 			return
 		}
 	}
+
+	// Ensure the compact block preferences signalled via sendcmpct were
+	// recorded.
+	if !inPeer.WantsCmpctBlocks() {
+		t.Errorf("TestPeerListeners: peer does not want compact blocks " +
+			"after sendcmpct")
+	}
+	if v := inPeer.CmpctBlockVersion(); v != wire.CmpctBlockVersion1 {
+		t.Errorf("TestPeerListeners: wrong compact block version - "+
+			"got %d, want %d", v, wire.CmpctBlockVersion1)
+	}
+
+	// Ensure the addrv2 preference signalled via sendaddrv2 was recorded.
+	if !inPeer.WantsAddrV2() {
+		t.Errorf("TestPeerListeners: peer does not want addrv2 messages " +
+			"after sendaddrv2")
+	}
 	inPeer.Disconnect()
 	outPeer.Disconnect()
 }
@@ -0,0 +1,296 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package peer
+
+import (
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/decred/dcrd/dcrec/secp256k1"
+	"github.com/decred/dcrd/wire"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// TransportVersion identifies the transport used to exchange messages with a
+// peer.
+type TransportVersion int
+
+const (
+	// TransportV1 is the original transport which sends messages in
+	// plaintext.
+	TransportV1 TransportVersion = 1
+
+	// TransportV2 is the transport which encrypts and authenticates all
+	// messages after an ephemeral ECDH key exchange.
+	TransportV2 TransportVersion = 2
+)
+
+// String returns the TransportVersion in human-readable form.
+func (v TransportVersion) String() string {
+	switch v {
+	case TransportV1:
+		return "v1"
+	case TransportV2:
+		return "v2"
+	}
+	return fmt.Sprintf("Unknown TransportVersion (%d)", int(v))
+}
+
+const (
+	// v2PubKeyLen is the length of the ElligatorSwift encoded ephemeral
+	// public keys exchanged during the v2 transport handshake.  The
+	// encoding is indistinguishable from uniformly random bytes.
+	v2PubKeyLen = ellSwiftLen
+
+	// v2RecordHeaderLen is the length of the header of each record sent
+	// via the v2 transport.  It houses the length of the encrypted payload
+	// as a little-endian 24-bit integer encrypted with a dedicated length
+	// key.
+	v2RecordHeaderLen = 3
+
+	// maxV2RecordPayload is the maximum number of plaintext bytes sent in a
+	// single record via the v2 transport.  Larger writes are split across
+	// multiple records.
+	maxV2RecordPayload = 1 << 16
+)
+
+var (
+	// v2TransportSalt is the salt used to derive the v2 transport keys
+	// from the ECDH shared secret.  It is followed by the network magic so
+	// that keys are never shared across networks.
+	v2TransportSalt = []byte("dcrd v2 transport")
+
+	// ErrV2RecordTooLarge indicates a record received via the v2 transport
+	// claims to be larger than the maximum allowed record size.
+	ErrV2RecordTooLarge = errors.New("v2 transport record is too large")
+)
+
+// v2Stream implements io.ReadWriter on top of an underlying connection by
+// encrypting and authenticating everything written to it with the
+// ChaCha20-Poly1305 AEAD and decrypting and verifying everything read from it.
+//
+// Data is sent as a sequence of records, each consisting of a header with the
+// encrypted length of the plaintext followed by the sealed plaintext.  The
+// length is encrypted with the ChaCha20 keystream of a separate length key so
+// that it can be decrypted before the payload is read, and the encrypted
+// header is authenticated as additional data of the payload.  Each direction
+// uses its own keys along with a nonce that is incremented for every record.
+//
+// A v2Stream is not safe for concurrent reads or concurrent writes, however
+// reading and writing concurrently is safe.
+type v2Stream struct {
+	rw          io.ReadWriter
+	sendAEAD    cipher.AEAD
+	recvAEAD    cipher.AEAD
+	sendLenAEAD cipher.AEAD
+	recvLenAEAD cipher.AEAD
+	sendNonce   uint64
+	recvNonce   uint64
+	readBuf     []byte
+}
+
+// v2Nonce returns the AEAD nonce for the passed record counter.
+func v2Nonce(counter uint64) []byte {
+	var nonce [chacha20poly1305.NonceSize]byte
+	binary.LittleEndian.PutUint64(nonce[4:], counter)
+	return nonce[:]
+}
+
+// cryptRecordHeader encrypts or decrypts the passed record header in place
+// with the keystream of the passed length AEAD for the record counter.  Sealing
+// zero bytes produces the raw ChaCha20 keystream followed by a tag that is
+// discarded.
+func cryptRecordHeader(header *[v2RecordHeaderLen]byte, lenAEAD cipher.AEAD, counter uint64) {
+	var zero [v2RecordHeaderLen]byte
+	keystream := lenAEAD.Seal(nil, v2Nonce(counter), zero[:], nil)
+	for i := range header {
+		header[i] ^= keystream[i]
+	}
+}
+
+// Read decrypts data received from the underlying connection into b.  This is
+// part of the io.Reader interface implementation.
+func (s *v2Stream) Read(b []byte) (int, error) {
+	if len(s.readBuf) == 0 {
+		var header [v2RecordHeaderLen]byte
+		if _, err := io.ReadFull(s.rw, header[:]); err != nil {
+			return 0, err
+		}
+		plainHeader := header
+		cryptRecordHeader(&plainHeader, s.recvLenAEAD, s.recvNonce)
+		payloadLen := uint32(plainHeader[0]) |
+			uint32(plainHeader[1])<<8 | uint32(plainHeader[2])<<16
+		if payloadLen > maxV2RecordPayload {
+			return 0, ErrV2RecordTooLarge
+		}
+		sealed := make([]byte, int(payloadLen)+s.recvAEAD.Overhead())
+		if _, err := io.ReadFull(s.rw, sealed); err != nil {
+			return 0, err
+		}
+		plaintext, err := s.recvAEAD.Open(sealed[:0],
+			v2Nonce(s.recvNonce), sealed, header[:])
+		if err != nil {
+			return 0, err
+		}
+		s.recvNonce++
+		s.readBuf = plaintext
+	}
+
+	n := copy(b, s.readBuf)
+	s.readBuf = s.readBuf[n:]
+	return n, nil
+}
+
+// Write encrypts b and sends it to the underlying connection.  This is part of
+// the io.Writer interface implementation.
+func (s *v2Stream) Write(b []byte) (int, error) {
+	var written int
+	for len(b) > 0 {
+		chunk := b
+		if len(chunk) > maxV2RecordPayload {
+			chunk = chunk[:maxV2RecordPayload]
+		}
+
+		header := [v2RecordHeaderLen]byte{byte(len(chunk)),
+			byte(len(chunk) >> 8), byte(len(chunk) >> 16)}
+		cryptRecordHeader(&header, s.sendLenAEAD, s.sendNonce)
+		record := make([]byte, v2RecordHeaderLen, v2RecordHeaderLen+
+			len(chunk)+s.sendAEAD.Overhead())
+		copy(record, header[:])
+		record = s.sendAEAD.Seal(record, v2Nonce(s.sendNonce), chunk,
+			header[:])
+		s.sendNonce++
+		if _, err := s.rw.Write(record); err != nil {
+			return written, err
+		}
+
+		written += len(chunk)
+		b = b[len(chunk):]
+	}
+	return written, nil
+}
+
+// newV2Stream derives the keys for both directions of the v2 transport from
+// the ECDH shared secret of the passed private key and remote public key and
+// returns a stream that uses them to wrap the passed connection.  The keys
+// commit to the network as well as the public keys of both the initiator and
+// responder.
+func newV2Stream(rw io.ReadWriter, net wire.CurrencyNet, privKey *secp256k1.PrivateKey, localPubKey, remotePubKey []byte, initiator bool) (*v2Stream, error) {
+	secret := ellSwiftECDH(privKey, remotePubKey)
+
+	initiatorPubKey, responderPubKey := localPubKey, remotePubKey
+	if !initiator {
+		initiatorPubKey, responderPubKey = remotePubKey, localPubKey
+	}
+	salt := make([]byte, len(v2TransportSalt)+4)
+	copy(salt, v2TransportSalt)
+	binary.LittleEndian.PutUint32(salt[len(v2TransportSalt):], uint32(net))
+	info := make([]byte, 0, v2PubKeyLen*2)
+	info = append(info, initiatorPubKey...)
+	info = append(info, responderPubKey...)
+	kdf := hkdf.New(sha256.New, secret, salt, info)
+
+	// Derive the payload and length keys for the initiator followed by
+	// those for the responder.
+	var aeads [4]cipher.AEAD
+	for i := range aeads {
+		var key [chacha20poly1305.KeySize]byte
+		if _, err := io.ReadFull(kdf, key[:]); err != nil {
+			return nil, err
+		}
+		aead, err := chacha20poly1305.New(key[:])
+		if err != nil {
+			return nil, err
+		}
+		aeads[i] = aead
+	}
+
+	stream := &v2Stream{rw: rw}
+	if initiator {
+		stream.sendAEAD, stream.sendLenAEAD = aeads[0], aeads[1]
+		stream.recvAEAD, stream.recvLenAEAD = aeads[2], aeads[3]
+	} else {
+		stream.sendAEAD, stream.sendLenAEAD = aeads[2], aeads[3]
+		stream.recvAEAD, stream.recvLenAEAD = aeads[0], aeads[1]
+	}
+	return stream, nil
+}
+
+// generateV2PubKey returns a new ephemeral private key along with the
+// ElligatorSwift encoding of its public key for use in the v2 transport
+// handshake.  Encodings that start with the network magic are never returned
+// since they would be mistaken for the v1 transport.
+func generateV2PubKey(net wire.CurrencyNet) (*secp256k1.PrivateKey, []byte, error) {
+	for {
+		privKey, err := secp256k1.GeneratePrivateKey()
+		if err != nil {
+			return nil, nil, err
+		}
+		pubKey, err := ellSwiftEncode(privKey.PublicKey.X)
+		if err != nil {
+			return nil, nil, err
+		}
+		if binary.LittleEndian.Uint32(pubKey[:4]) == uint32(net) {
+			continue
+		}
+		return privKey, pubKey[:], nil
+	}
+}
+
+// initiateV2Transport performs the initiator side of the v2 transport handshake
+// over the passed connection by sending an ephemeral public key and reading the
+// ephemeral public key of the responder.  It returns a stream that encrypts
+// and authenticates all further data.
+func initiateV2Transport(rw io.ReadWriter, net wire.CurrencyNet) (*v2Stream, error) {
+	privKey, pubKey, err := generateV2PubKey(net)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := rw.Write(pubKey); err != nil {
+		return nil, err
+	}
+
+	var remotePubKey [v2PubKeyLen]byte
+	if _, err := io.ReadFull(rw, remotePubKey[:]); err != nil {
+		return nil, err
+	}
+	return newV2Stream(rw, net, privKey, pubKey, remotePubKey[:], true)
+}
+
+// respondV2Transport performs the responder side of the v2 transport handshake
+// over the passed connection.  The prefix is the data that was already read
+// from the connection in order to determine the initiator is attempting the
+// v2 transport.  It returns a stream that encrypts and authenticates all
+// further data.
+func respondV2Transport(rw io.ReadWriter, net wire.CurrencyNet, prefix []byte) (*v2Stream, error) {
+	if len(prefix) > v2PubKeyLen {
+		return nil, fmt.Errorf("v2 transport handshake prefix of %d "+
+			"bytes exceeds public key length", len(prefix))
+	}
+	var remotePubKey [v2PubKeyLen]byte
+	copy(remotePubKey[:], prefix)
+	if _, err := io.ReadFull(rw, remotePubKey[len(prefix):]); err != nil {
+		return nil, err
+	}
+
+	privKey, pubKey, err := generateV2PubKey(net)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := newV2Stream(rw, net, privKey, pubKey, remotePubKey[:],
+		false)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := rw.Write(pubKey); err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
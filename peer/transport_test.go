@@ -0,0 +1,246 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package peer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1"
+	"github.com/decred/dcrd/wire"
+)
+
+// v2StreamPair performs the v2 transport handshake over an in-memory
+// connection and returns the resulting initiator and responder streams along
+// with the underlying connections.
+func v2StreamPair(t *testing.T) (*v2Stream, *v2Stream, net.Conn, net.Conn) {
+	t.Helper()
+
+	initConn, respConn := net.Pipe()
+	type result struct {
+		stream *v2Stream
+		err    error
+	}
+	respResult := make(chan result, 1)
+	go func() {
+		var prefix [4]byte
+		if _, err := io.ReadFull(respConn, prefix[:]); err != nil {
+			respResult <- result{nil, err}
+			return
+		}
+		stream, err := respondV2Transport(respConn, wire.MainNet,
+			prefix[:])
+		respResult <- result{stream, err}
+	}()
+
+	initStream, err := initiateV2Transport(initConn, wire.MainNet)
+	if err != nil {
+		t.Fatalf("initiateV2Transport: unexpected error: %v", err)
+	}
+	resp := <-respResult
+	if resp.err != nil {
+		t.Fatalf("respondV2Transport: unexpected error: %v", resp.err)
+	}
+	return initStream, resp.stream, initConn, respConn
+}
+
+// TestV2Stream ensures data written to a v2 transport stream is encrypted and
+// read back intact in both directions, including data that spans multiple
+// records.
+func TestV2Stream(t *testing.T) {
+	initStream, respStream, initConn, respConn := v2StreamPair(t)
+	defer initConn.Close()
+	defer respConn.Close()
+
+	small := []byte("version")
+	large := bytes.Repeat([]byte{0x5a}, maxV2RecordPayload*2+10)
+	tests := []struct {
+		name string
+		w    *v2Stream
+		r    *v2Stream
+		data []byte
+	}{
+		{"initiator small", initStream, respStream, small},
+		{"responder small", respStream, initStream, small},
+		{"initiator multiple records", initStream, respStream, large},
+	}
+
+	for _, test := range tests {
+		writeErr := make(chan error, 1)
+		go func() {
+			_, err := test.w.Write(test.data)
+			writeErr <- err
+		}()
+		got := make([]byte, len(test.data))
+		if _, err := io.ReadFull(test.r, got); err != nil {
+			t.Fatalf("%s: unexpected read error: %v", test.name, err)
+		}
+		if err := <-writeErr; err != nil {
+			t.Fatalf("%s: unexpected write error: %v", test.name, err)
+		}
+		if !bytes.Equal(got, test.data) {
+			t.Fatalf("%s: mismatched data", test.name)
+		}
+	}
+}
+
+// TestV2StreamTampered ensures records that are modified in transit are
+// rejected.
+func TestV2StreamTampered(t *testing.T) {
+	initStream, respStream, initConn, respConn := v2StreamPair(t)
+	defer initConn.Close()
+	defer respConn.Close()
+
+	// Capture a sealed record by writing to a buffer instead of the
+	// connection and flip a bit in the ciphertext.
+	var record bytes.Buffer
+	initStream.rw = &record
+	if _, err := initStream.Write([]byte("ping")); err != nil {
+		t.Fatalf("unexpected write error: %v", err)
+	}
+	tampered := record.Bytes()
+	tampered[v2RecordHeaderLen] ^= 0x01
+
+	respStream.rw = bytes.NewBuffer(tampered)
+	var buf [4]byte
+	if _, err := respStream.Read(buf[:]); err == nil {
+		t.Fatal("unexpected success reading tampered record")
+	}
+}
+
+// TestV2StreamHeaderEncrypted ensures the length in the header of records sent
+// via the v2 transport is encrypted and differs between records of the same
+// length.
+func TestV2StreamHeaderEncrypted(t *testing.T) {
+	initStream, respStream, initConn, respConn := v2StreamPair(t)
+	defer initConn.Close()
+	defer respConn.Close()
+
+	data := bytes.Repeat([]byte{0x5a}, 0x0102*2)
+	var records bytes.Buffer
+	initStream.rw = &records
+	if _, err := initStream.Write(data[:0x0102]); err != nil {
+		t.Fatalf("unexpected write error: %v", err)
+	}
+	first := append([]byte(nil), records.Bytes()[:v2RecordHeaderLen]...)
+	secondOffset := records.Len()
+	if _, err := initStream.Write(data[:0x0102]); err != nil {
+		t.Fatalf("unexpected write error: %v", err)
+	}
+	second := records.Bytes()[secondOffset : secondOffset+v2RecordHeaderLen]
+
+	plain := []byte{0x02, 0x01, 0x00}
+	if bytes.Equal(first, plain) || bytes.Equal(second, plain) {
+		t.Fatal("record length sent in plaintext")
+	}
+	if bytes.Equal(first, second) {
+		t.Fatal("identical encrypted lengths for separate records")
+	}
+
+	// Ensure both records are read back intact.
+	respStream.rw = &records
+	got := make([]byte, 0x0102*2)
+	if _, err := io.ReadFull(respStream, got); err != nil {
+		t.Fatalf("unexpected read error: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("mismatched data")
+	}
+}
+
+// TestEllSwift ensures ElligatorSwift encodings decode to the encoded x
+// coordinate and that both sides of an ECDH exchange using them agree on the
+// shared secret.
+func TestEllSwift(t *testing.T) {
+	for i := 0; i < 16; i++ {
+		privKey1, pubKey1, err := generateV2PubKey(wire.MainNet)
+		if err != nil {
+			t.Fatalf("generateV2PubKey: unexpected error: %v", err)
+		}
+		privKey2, pubKey2, err := generateV2PubKey(wire.MainNet)
+		if err != nil {
+			t.Fatalf("generateV2PubKey: unexpected error: %v", err)
+		}
+		if len(pubKey1) != v2PubKeyLen {
+			t.Fatalf("unexpected encoding length -- got %d, want %d",
+				len(pubKey1), v2PubKeyLen)
+		}
+		if x := ellSwiftDecode(pubKey1); x.Cmp(privKey1.PublicKey.X) != 0 {
+			t.Fatalf("mismatched decoded x coordinate -- got %x, want %x",
+				x, privKey1.PublicKey.X)
+		}
+
+		secret1 := ellSwiftECDH(privKey1, pubKey2)
+		secret2 := ellSwiftECDH(privKey2, pubKey1)
+		if !bytes.Equal(secret1, secret2) {
+			t.Fatalf("mismatched shared secrets -- %x != %x", secret1,
+				secret2)
+		}
+	}
+
+	// Ensure arbitrary encodings, including those with zero and
+	// out-of-range field elements, decode to valid x coordinates.
+	encodings := [][]byte{
+		make([]byte, ellSwiftLen),
+		bytes.Repeat([]byte{0xff}, ellSwiftLen),
+		bytes.Repeat([]byte{0x5a}, ellSwiftLen),
+	}
+	for _, enc := range encodings {
+		if x := ellSwiftDecode(enc); !isValidX(x) {
+			t.Fatalf("encoding %x decoded to invalid x coordinate %x",
+				enc, x)
+		}
+	}
+
+	// Ensure field elements are reduced before decoding such that encodings
+	// of u and t that are at least the field prime decode the same as their
+	// reduced values.
+	var reduced, unreduced [ellSwiftLen]byte
+	putFieldBytes(reduced[:32], big.NewInt(5))
+	putFieldBytes(reduced[32:], big.NewInt(9))
+	putFieldBytes(unreduced[:32], new(big.Int).Add(fieldPrime, big.NewInt(5)))
+	putFieldBytes(unreduced[32:], new(big.Int).Add(fieldPrime, big.NewInt(9)))
+	x1, x2 := ellSwiftDecode(reduced[:]), ellSwiftDecode(unreduced[:])
+	if x1.Cmp(x2) != 0 {
+		t.Fatalf("unreduced encoding decoded to %x, want %x", x2, x1)
+	}
+
+	// Ensure every preimage found by the inverse for random valid x
+	// coordinates and field elements u maps back to the same x coordinate
+	// for all cases, and that preimages are found for some of them.
+	var numPreimages int
+	for i := 0; i < 64; i++ {
+		privKey, err := secp256k1.GeneratePrivateKey()
+		if err != nil {
+			t.Fatalf("GeneratePrivateKey: unexpected error: %v", err)
+		}
+		x := privKey.PublicKey.X
+		u, err := rand.Int(rand.Reader, fieldPrime)
+		if err != nil {
+			t.Fatalf("rand.Int: unexpected error: %v", err)
+		}
+		if u.Sign() == 0 {
+			continue
+		}
+		for c := byte(0); c < 8; c++ {
+			tt := xSwiftECInv(x, u, c)
+			if tt == nil {
+				continue
+			}
+			numPreimages++
+			if got := xSwiftEC(u, tt); got.Cmp(x) != 0 {
+				t.Fatalf("preimage for case %d of x %x and u %x maps to "+
+					"%x", c, x, u, got)
+			}
+		}
+	}
+	if numPreimages == 0 {
+		t.Fatal("no preimages found for any x coordinate")
+	}
+}
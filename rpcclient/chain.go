@@ -563,6 +563,119 @@ func (c *Client) GetRawMempoolVerbose(txType dcrjson.GetRawMempoolTxTypeCmd) (ma
 	return c.GetRawMempoolVerboseAsync(txType).Receive()
 }
 
+// FutureDumpUtxoSetResult is a future promise to deliver the result of a
+// DumpUtxoSetAsync RPC invocation (or an applicable error).
+type FutureDumpUtxoSetResult chan *response
+
+// Receive waits for the response promised by the future and returns
+// information about the chain state snapshot written by the server.
+func (r FutureDumpUtxoSetResult) Receive() (*dcrjson.DumpUtxoSetResult, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal the result as a dumputxoset result object.
+	var result dcrjson.DumpUtxoSetResult
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DumpUtxoSetAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on the
+// returned instance.
+//
+// See DumpUtxoSet for the blocking version and more details.
+func (c *Client) DumpUtxoSetAsync(path string) FutureDumpUtxoSetResult {
+	cmd := dcrjson.NewDumpUtxoSetCmd(path)
+	return c.sendCmd(cmd)
+}
+
+// DumpUtxoSet writes a snapshot of the chain state as of the current best block
+// of the server to the passed path, which is relative to the data directory of
+// the server unless it is absolute.
+func (c *Client) DumpUtxoSet(path string) (*dcrjson.DumpUtxoSetResult, error) {
+	return c.DumpUtxoSetAsync(path).Receive()
+}
+
+// FutureSaveMempoolResult is a future promise to deliver the result of a
+// SaveMempoolAsync RPC invocation (or an applicable error).
+type FutureSaveMempoolResult chan *response
+
+// Receive waits for the response promised by the future and returns the number
+// of transactions saved to the mempool file.
+func (r FutureSaveMempoolResult) Receive() (int64, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return 0, err
+	}
+
+	// Unmarshal the result as an int64.
+	var count int64
+	err = json.Unmarshal(res, &count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// SaveMempoolAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on the
+// returned instance.
+//
+// See SaveMempool for the blocking version and more details.
+func (c *Client) SaveMempoolAsync() FutureSaveMempoolResult {
+	cmd := dcrjson.NewSaveMempoolCmd()
+	return c.sendCmd(cmd)
+}
+
+// SaveMempool saves the transactions in the memory pool of the server to its
+// mempool file and returns the number of saved transactions.
+func (c *Client) SaveMempool() (int64, error) {
+	return c.SaveMempoolAsync().Receive()
+}
+
+// FutureLoadMempoolResult is a future promise to deliver the result of a
+// LoadMempoolAsync RPC invocation (or an applicable error).
+type FutureLoadMempoolResult chan *response
+
+// Receive waits for the response promised by the future and returns the number
+// of transactions added to the memory pool from the mempool file.
+func (r FutureLoadMempoolResult) Receive() (int64, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return 0, err
+	}
+
+	// Unmarshal the result as an int64.
+	var count int64
+	err = json.Unmarshal(res, &count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// LoadMempoolAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on the
+// returned instance.
+//
+// See LoadMempool for the blocking version and more details.
+func (c *Client) LoadMempoolAsync() FutureLoadMempoolResult {
+	cmd := dcrjson.NewLoadMempoolCmd()
+	return c.sendCmd(cmd)
+}
+
+// LoadMempool loads the transactions saved to the mempool file of the server
+// back into its memory pool and returns the number of transactions that were
+// added.
+func (c *Client) LoadMempool() (int64, error) {
+	return c.LoadMempoolAsync().Receive()
+}
+
 // FutureVerifyChainResult is a future promise to deliver the result of a
 // VerifyChainAsync, VerifyChainLevelAsyncRPC, or VerifyChainBlocksAsync
 // invocation (or an applicable error).
@@ -737,6 +850,43 @@ func (c *Client) GetTxOut(txHash *chainhash.Hash, index uint32, mempool bool) (*
 	return c.GetTxOutAsync(txHash, index, mempool).Receive()
 }
 
+// FutureGetTxOutSetInfoResult is a future promise to deliver the result of a
+// GetTxOutSetInfoAsync RPC invocation (or an applicable error).
+type FutureGetTxOutSetInfoResult chan *response
+
+// Receive waits for the response promised by the future and returns
+// statistics about the unspent transaction output set.
+func (r FutureGetTxOutSetInfoResult) Receive() (*dcrjson.GetTxOutSetInfoResult, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal result as a gettxoutsetinfo result object.
+	var result dcrjson.GetTxOutSetInfoResult
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetTxOutSetInfoAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See GetTxOutSetInfo for the blocking version and more details.
+func (c *Client) GetTxOutSetInfoAsync() FutureGetTxOutSetInfoResult {
+	cmd := dcrjson.NewGetTxOutSetInfoCmd()
+	return c.sendCmd(cmd)
+}
+
+// GetTxOutSetInfo returns statistics about the unspent transaction output set
+// as of the current best block of the server.
+func (c *Client) GetTxOutSetInfo() (*dcrjson.GetTxOutSetInfoResult, error) {
+	return c.GetTxOutSetInfoAsync().Receive()
+}
+
 // FutureRescanResult is a future promise to deliver the result of a
 // RescanAsynnc RPC invocation (or an applicable error).
 type FutureRescanResult chan *response
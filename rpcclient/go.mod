@@ -10,7 +10,7 @@ require (
 	github.com/decred/dcrd/database v1.0.3 // indirect
 	github.com/decred/dcrd/dcrec v0.0.0-20190130161649-59ed4247a1d5 // indirect
 	github.com/decred/dcrd/dcrec/edwards v0.0.0-20190130161649-59ed4247a1d5 // indirect
-	github.com/decred/dcrd/dcrjson/v2 v2.0.0
+	github.com/decred/dcrd/dcrjson/v2 v2.1.0
 	github.com/decred/dcrd/dcrutil v1.2.0
 	github.com/decred/dcrd/gcs v1.0.2
 	github.com/decred/dcrd/txscript v1.0.2 // indirect
@@ -26,3 +26,5 @@ require (
 	golang.org/x/sys v0.0.0-20190203050204-7ae0202eb74c // indirect
 	gopkg.in/yaml.v2 v2.2.2 // indirect
 )
+
+replace github.com/decred/dcrd/dcrjson/v2 => ../dcrjson
@@ -281,6 +281,9 @@ func (c *Client) trackRegisteredNtfns(cmd interface{}) {
 		} else {
 			c.ntfnState.notifyNewTx = true
 		}
+
+	case *dcrjson.NotifyMempoolEventsCmd:
+		c.ntfnState.notifyMempoolEvents = true
 	}
 }
 
@@ -585,6 +588,14 @@ func (c *Client) reregisterNtfns() error {
 		}
 	}
 
+	// Reregister notifymempoolevents if needed.
+	if stateCopy.notifyMempoolEvents {
+		log.Debugf("Reregistering [notifymempoolevents]")
+		if err := c.NotifyMempoolEvents(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -39,6 +39,7 @@ type notificationState struct {
 	notifyStakeDifficulty       bool
 	notifyNewTx                 bool
 	notifyNewTxVerbose          bool
+	notifyMempoolEvents         bool
 }
 
 // Copy returns a deep copy of the receiver.
@@ -51,6 +52,7 @@ func (s *notificationState) Copy() *notificationState {
 	stateCopy.notifyStakeDifficulty = s.notifyStakeDifficulty
 	stateCopy.notifyNewTx = s.notifyNewTx
 	stateCopy.notifyNewTxVerbose = s.notifyNewTxVerbose
+	stateCopy.notifyMempoolEvents = s.notifyMempoolEvents
 
 	return &stateCopy
 }
@@ -153,6 +155,18 @@ type NotificationHandlers struct {
 	// made to register for the notification and the function is non-nil.
 	OnTxAcceptedVerbose func(txDetails *dcrjson.TxRawResult)
 
+	// OnMempoolTxAdded is invoked when a transaction is added to the memory
+	// pool.  It will only be invoked if a preceding call to
+	// NotifyMempoolEvents has been made to register for the notification
+	// and the function is non-nil.
+	OnMempoolTxAdded func(hash *chainhash.Hash, txType string, fee dcrutil.Amount)
+
+	// OnMempoolTxRemoved is invoked when a transaction is removed from the
+	// memory pool along with the reason it was removed.  It will only be
+	// invoked if a preceding call to NotifyMempoolEvents has been made to
+	// register for the notification and the function is non-nil.
+	OnMempoolTxRemoved func(hash *chainhash.Hash, reason string)
+
 	// OnDcrdConnected is invoked when a wallet connects or disconnects from
 	// dcrd.
 	//
@@ -398,6 +412,40 @@ func (c *Client) handleNotification(ntfn *rawNotification) {
 
 		c.ntfnHandlers.OnTxAcceptedVerbose(rawTx)
 
+	// OnMempoolTxAdded
+	case dcrjson.MempoolTxAddedNtfnMethod:
+		// Ignore the notification if the client is not interested in
+		// it.
+		if c.ntfnHandlers.OnMempoolTxAdded == nil {
+			return
+		}
+
+		hash, txType, fee, err := parseMempoolTxAddedNtfnParams(ntfn.Params)
+		if err != nil {
+			log.Warnf("Received invalid mempool tx added "+
+				"notification: %v", err)
+			return
+		}
+
+		c.ntfnHandlers.OnMempoolTxAdded(hash, txType, fee)
+
+	// OnMempoolTxRemoved
+	case dcrjson.MempoolTxRemovedNtfnMethod:
+		// Ignore the notification if the client is not interested in
+		// it.
+		if c.ntfnHandlers.OnMempoolTxRemoved == nil {
+			return
+		}
+
+		hash, reason, err := parseMempoolTxRemovedNtfnParams(ntfn.Params)
+		if err != nil {
+			log.Warnf("Received invalid mempool tx removed "+
+				"notification: %v", err)
+			return
+		}
+
+		c.ntfnHandlers.OnMempoolTxRemoved(hash, reason)
+
 		// OnDcrdConnected
 	case walletjson.DcrdConnectedNtfnMethod:
 		// Ignore the notification if the client is not interested in
@@ -898,6 +946,83 @@ func parseTxAcceptedNtfnParams(params []json.RawMessage) (*chainhash.Hash,
 	return txHash, amt, nil
 }
 
+// parseMempoolTxAddedNtfnParams parses out the transaction hash, transaction
+// type, and fee from the parameters of a mempooltxadded notification.
+func parseMempoolTxAddedNtfnParams(params []json.RawMessage) (*chainhash.Hash,
+	string, dcrutil.Amount, error) {
+
+	if len(params) != 3 {
+		return nil, "", 0, wrongNumParams(len(params))
+	}
+
+	// Unmarshal first parameter as a string.
+	var txHashStr string
+	err := json.Unmarshal(params[0], &txHashStr)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	// Unmarshal second parameter as a string.
+	var txType string
+	err = json.Unmarshal(params[1], &txType)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	// Unmarshal third parameter as a floating point number.
+	var ffee float64
+	err = json.Unmarshal(params[2], &ffee)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	// Bounds check fee.
+	fee, err := dcrutil.NewAmount(ffee)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	// Decode string encoding of transaction hash.
+	txHash, err := chainhash.NewHashFromStr(txHashStr)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	return txHash, txType, fee, nil
+}
+
+// parseMempoolTxRemovedNtfnParams parses out the transaction hash and removal
+// reason from the parameters of a mempooltxremoved notification.
+func parseMempoolTxRemovedNtfnParams(params []json.RawMessage) (*chainhash.Hash,
+	string, error) {
+
+	if len(params) != 2 {
+		return nil, "", wrongNumParams(len(params))
+	}
+
+	// Unmarshal first parameter as a string.
+	var txHashStr string
+	err := json.Unmarshal(params[0], &txHashStr)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Unmarshal second parameter as a string.
+	var reason string
+	err = json.Unmarshal(params[1], &reason)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Decode string encoding of transaction hash.
+	txHash, err := chainhash.NewHashFromStr(txHashStr)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return txHash, reason, nil
+}
+
 // parseTxAcceptedVerboseNtfnParams parses out details about a raw transaction
 // from the parameters of a txacceptedverbose notification.
 func parseTxAcceptedVerboseNtfnParams(params []json.RawMessage) (*dcrjson.TxRawResult,
@@ -1422,6 +1547,55 @@ func (c *Client) NotifyNewTransactions(verbose bool) error {
 	return c.NotifyNewTransactionsAsync(verbose).Receive()
 }
 
+// FutureNotifyMempoolEventsResult is a future promise to deliver the result of
+// a NotifyMempoolEventsAsync RPC invocation (or an applicable error).
+type FutureNotifyMempoolEventsResult chan *response
+
+// Receive waits for the response promised by the future and returns an error
+// if the registration was not successful.
+func (r FutureNotifyMempoolEventsResult) Receive() error {
+	_, err := receiveFuture(r)
+	return err
+}
+
+// NotifyMempoolEventsAsync returns an instance of a type that can be used to
+// get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See NotifyMempoolEvents for the blocking version and more details.
+//
+// NOTE: This is a dcrd extension and requires a websocket connection.
+func (c *Client) NotifyMempoolEventsAsync() FutureNotifyMempoolEventsResult {
+	// Not supported in HTTP POST mode.
+	if c.config.HTTPPostMode {
+		return newFutureError(ErrWebsocketsRequired)
+	}
+
+	// Ignore the notification if the client is not interested in
+	// notifications.
+	if c.ntfnHandlers == nil {
+		return newNilFutureResult()
+	}
+
+	cmd := dcrjson.NewNotifyMempoolEventsCmd()
+	return c.sendCmd(cmd)
+}
+
+// NotifyMempoolEvents registers the client to receive notifications every time
+// a transaction is added to or removed from the memory pool.  The
+// notifications are delivered to the notification handlers associated with the
+// client.  Calling this function has no effect if there are no notification
+// handlers and will result in an error if the client is configured to run in
+// HTTP POST mode.
+//
+// The notifications delivered as a result of this call will be via one of
+// OnMempoolTxAdded or OnMempoolTxRemoved.
+//
+// NOTE: This is a dcrd extension and requires a websocket connection.
+func (c *Client) NotifyMempoolEvents() error {
+	return c.NotifyMempoolEventsAsync().Receive()
+}
+
 // FutureLoadTxFilterResult is a future promise to deliver the result
 // of a LoadTxFilterAsync RPC invocation (or an applicable error).
 type FutureLoadTxFilterResult chan *response
@@ -439,6 +439,106 @@ func (c *Client) SendRawTransaction(tx *wire.MsgTx, allowHighFees bool) (*chainh
 	return c.SendRawTransactionAsync(tx, allowHighFees).Receive()
 }
 
+// FutureSendRawPackageResult is a future promise to deliver the result of a
+// SendRawPackageAsync RPC invocation (or an applicable error).
+type FutureSendRawPackageResult chan *response
+
+// Receive waits for the response promised by the future and returns the result
+// of submitting each transaction in the package to the server.
+func (r FutureSendRawPackageResult) Receive() ([]dcrjson.SendRawPackageResult, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal result as an array of sendrawpackage results.
+	var results []dcrjson.SendRawPackageResult
+	err = json.Unmarshal(res, &results)
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// SendRawPackageAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on the
+// returned instance.
+//
+// See SendRawPackage for the blocking version and more details.
+func (c *Client) SendRawPackageAsync(txns []*wire.MsgTx, allowHighFees bool) FutureSendRawPackageResult {
+	txHexes := make([]string, 0, len(txns))
+	for _, tx := range txns {
+		// Serialize the transaction and convert to hex string.
+		buf := bytes.NewBuffer(make([]byte, 0, tx.SerializeSize()))
+		if err := tx.Serialize(buf); err != nil {
+			return newFutureError(err)
+		}
+		txHexes = append(txHexes, hex.EncodeToString(buf.Bytes()))
+	}
+
+	cmd := dcrjson.NewSendRawPackageCmd(txHexes, &allowHighFees)
+	return c.sendCmd(cmd)
+}
+
+// SendRawPackage submits the encoded package of transactions, which must be
+// sorted such that every transaction appears after all of the transactions in
+// the package it depends on, to the server which will then relay the accepted
+// transactions to the network.  It returns the result of submitting each
+// transaction in the same order as the package.
+func (c *Client) SendRawPackage(txns []*wire.MsgTx, allowHighFees bool) ([]dcrjson.SendRawPackageResult, error) {
+	return c.SendRawPackageAsync(txns, allowHighFees).Receive()
+}
+
+// FutureTestMempoolAcceptResult is a future promise to deliver the result of a
+// TestMempoolAcceptAsync RPC invocation (or an applicable error).
+type FutureTestMempoolAcceptResult chan *response
+
+// Receive waits for the response promised by the future and returns whether or
+// not each of the tested transactions would be accepted to the memory pool.
+func (r FutureTestMempoolAcceptResult) Receive() ([]dcrjson.TestMempoolAcceptResult, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal result as an array of testmempoolaccept results.
+	var results []dcrjson.TestMempoolAcceptResult
+	err = json.Unmarshal(res, &results)
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// TestMempoolAcceptAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See TestMempoolAccept for the blocking version and more details.
+func (c *Client) TestMempoolAcceptAsync(txns []*wire.MsgTx, allowHighFees bool) FutureTestMempoolAcceptResult {
+	txHexes := make([]string, 0, len(txns))
+	for _, tx := range txns {
+		// Serialize the transaction and convert to hex string.
+		buf := bytes.NewBuffer(make([]byte, 0, tx.SerializeSize()))
+		if err := tx.Serialize(buf); err != nil {
+			return newFutureError(err)
+		}
+		txHexes = append(txHexes, hex.EncodeToString(buf.Bytes()))
+	}
+
+	cmd := dcrjson.NewTestMempoolAcceptCmd(txHexes, &allowHighFees)
+	return c.sendCmd(cmd)
+}
+
+// TestMempoolAccept returns whether or not each of the passed transactions
+// would be accepted to the memory pool of the server without adding them to it
+// or relaying them.  Each transaction is tested independently.
+func (c *Client) TestMempoolAccept(txns []*wire.MsgTx, allowHighFees bool) ([]dcrjson.TestMempoolAcceptResult, error) {
+	return c.TestMempoolAcceptAsync(txns, allowHighFees).Receive()
+}
+
 // FutureSignRawTransactionResult is a future promise to deliver the result
 // of one of the SignRawTransactionAsync family of RPC invocations (or an
 // applicable error).
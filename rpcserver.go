@@ -25,6 +25,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 	"runtime"
 	"sort"
 	"strconv"
@@ -40,10 +41,12 @@ import (
 	"github.com/decred/dcrd/certgen"
 	"github.com/decred/dcrd/chaincfg"
 	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/connmgr"
 	"github.com/decred/dcrd/database"
 	"github.com/decred/dcrd/dcrec/secp256k1"
 	"github.com/decred/dcrd/dcrjson/v2"
 	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/fees"
 	"github.com/decred/dcrd/internal/version"
 	"github.com/decred/dcrd/mempool/v2"
 	"github.com/decred/dcrd/txscript"
@@ -170,13 +173,16 @@ type commandHandler func(*rpcServer, interface{}, <-chan struct{}) (interface{},
 var rpcHandlers map[string]commandHandler
 var rpcHandlersBeforeInit = map[string]commandHandler{
 	"addnode":               handleAddNode,
+	"clearbanned":           handleClearBanned,
 	"createrawsstx":         handleCreateRawSStx,
 	"createrawssrtx":        handleCreateRawSSRtx,
 	"createrawtransaction":  handleCreateRawTransaction,
 	"debuglevel":            handleDebugLevel,
 	"decoderawtransaction":  handleDecodeRawTransaction,
 	"decodescript":          handleDecodeScript,
+	"dumputxoset":           handleDumpUtxoSet,
 	"estimatefee":           handleEstimateFee,
+	"estimaterawfee":        handleEstimateRawFee,
 	"estimatesmartfee":      handleEstimateSmartFee,
 	"estimatestakediff":     handleEstimateStakeDiff,
 	"existsaddress":         handleExistsAddress,
@@ -217,22 +223,30 @@ var rpcHandlersBeforeInit = map[string]commandHandler{
 	"getstakedifficulty":    handleGetStakeDifficulty,
 	"getstakeversioninfo":   handleGetStakeVersionInfo,
 	"getstakeversions":      handleGetStakeVersions,
+	"getsyncinfo":           handleGetSyncInfo,
 	"getticketpoolvalue":    handleGetTicketPoolValue,
 	"getvoteinfo":           handleGetVoteInfo,
 	"gettxout":              handleGetTxOut,
+	"gettxoutsetinfo":       handleGetTxOutSetInfo,
 	"getwork":               handleGetWork,
 	"help":                  handleHelp,
+	"listbanned":            handleListBanned,
 	"livetickets":           handleLiveTickets,
+	"loadmempool":           handleLoadMempool,
 	"missedtickets":         handleMissedTickets,
 	"node":                  handleNode,
 	"ping":                  handlePing,
 	"searchrawtransactions": handleSearchRawTransactions,
 	"rebroadcastmissed":     handleRebroadcastMissed,
 	"rebroadcastwinners":    handleRebroadcastWinners,
+	"savemempool":           handleSaveMempool,
+	"sendrawpackage":        handleSendRawPackage,
 	"sendrawtransaction":    handleSendRawTransaction,
+	"setban":                handleSetBan,
 	"setgenerate":           handleSetGenerate,
 	"stop":                  handleStop,
 	"submitblock":           handleSubmitBlock,
+	"testmempoolaccept":     handleTestMempoolAccept,
 	"ticketfeeinfo":         handleTicketFeeInfo,
 	"ticketsforaddress":     handleTicketsForAddress,
 	"ticketvwap":            handleTicketVWAP,
@@ -266,7 +280,6 @@ var rpcAskWallet = map[string]struct{}{
 	"getstakeinfo":            {},
 	"getvotechoices":          {},
 	"gettransaction":          {},
-	"gettxoutsetinfo":         {},
 	"getunconfirmedbalance":   {},
 	"importprivkey":           {},
 	"keypoolrefill":           {},
@@ -336,8 +349,10 @@ var rpcLimited = map[string]struct{}{
 	"getrawtransaction":     {},
 	"gettxout":              {},
 	"searchrawtransactions": {},
+	"sendrawpackage":        {},
 	"sendrawtransaction":    {},
 	"submitblock":           {},
+	"testmempoolaccept":     {},
 	"validateaddress":       {},
 	"verifymessage":         {},
 	"version":               {},
@@ -618,6 +633,12 @@ func messageToHex(msg wire.Message) (string, error) {
 	return hex.EncodeToString(buf.Bytes()), nil
 }
 
+// handleClearBanned implements the clearbanned command.
+func handleClearBanned(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	s.server.banList.Clear()
+	return nil, nil
+}
+
 // handleCreateRawTransaction handles createrawtransaction commands.
 func handleCreateRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*dcrjson.CreateRawTransactionCmd)
@@ -1367,8 +1388,9 @@ func handleEstimateFee(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 
 // handleEstimateSmartFee implements the estimatesmartfee command.
 //
-// The default estimation mode when unset is assumed as "conservative". As of
-// 2018-12, the only supported mode is "conservative".
+// The default estimation mode when unset is assumed as "conservative".  The
+// "ticket" mode conservatively estimates the fee rate for ticket purchases,
+// which are tracked separately from regular transactions.
 func handleEstimateSmartFee(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*dcrjson.EstimateSmartFeeCmd)
 
@@ -1377,12 +1399,22 @@ func handleEstimateSmartFee(s *rpcServer, cmd interface{}, closeChan <-chan stru
 		mode = *c.Mode
 	}
 
-	if mode != dcrjson.EstimateSmartFeeConservative {
-		return nil, rpcInvalidError("Only the default and conservative modes " +
-			"are supported for smart fee estimation at the moment")
+	estimator := s.server.feeEstimator
+	confs := int32(c.Confirmations)
+	var fee dcrutil.Amount
+	var err error
+	switch mode {
+	case dcrjson.EstimateSmartFeeConservative:
+		fee, err = estimator.EstimateFee(confs)
+	case dcrjson.EstimateSmartFeeEconomical:
+		fee, err = estimator.EstimateFeeWithMode(confs,
+			fees.EstimateEconomical)
+	case dcrjson.EstimateSmartFeeTicket:
+		fee, err = estimator.EstimateTicketFee(confs)
+	default:
+		return nil, rpcInvalidError("Unsupported smart fee estimation "+
+			"mode %q", mode)
 	}
-
-	fee, err := s.server.feeEstimator.EstimateFee(int32(c.Confirmations))
 	if err != nil {
 		return nil, rpcInternalError(err.Error(), "Could not estimate fee")
 	}
@@ -1390,6 +1422,67 @@ func handleEstimateSmartFee(s *rpcServer, cmd interface{}, closeChan <-chan stru
 	return fee.ToCoin(), nil
 }
 
+// rawFeeEstimateResult converts the passed raw fee estimate to its JSON-RPC
+// result.
+func rawFeeEstimateResult(raw *fees.RawFeeEstimate) *dcrjson.EstimateRawFeeHorizonResult {
+	convertRange := func(r *fees.BucketRangeStats) *dcrjson.EstimateRawFeeBucketRange {
+		if r == nil {
+			return nil
+		}
+		return &dcrjson.EstimateRawFeeBucketRange{
+			StartRange:     r.StartRange.ToCoin(),
+			EndRange:       r.EndRange.ToCoin(),
+			WithinTarget:   r.WithinTarget,
+			TotalConfirmed: r.TotalConfirmed,
+			InMempool:      r.InMemPool,
+		}
+	}
+
+	res := &dcrjson.EstimateRawFeeHorizonResult{
+		Decay: raw.Decay,
+		Pass:  convertRange(raw.Pass),
+		Fail:  convertRange(raw.Fail),
+	}
+	if raw.Err != nil {
+		res.Errors = []string{raw.Err.Error()}
+	} else {
+		res.FeeRate = raw.FeeRate.ToCoin()
+	}
+	return res
+}
+
+// handleEstimateRawFee implements the estimaterawfee command.
+func handleEstimateRawFee(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*dcrjson.EstimateRawFeeCmd)
+
+	threshold := 0.95
+	if c.Threshold != nil {
+		threshold = *c.Threshold
+	}
+	if threshold <= 0 || threshold > 1 {
+		return nil, rpcInvalidError("Threshold must be in the range (0, 1] "+
+			"-- got %v", threshold)
+	}
+
+	estimator := s.server.feeEstimator
+	confs := int32(c.Confirmations)
+	short, err := estimator.EstimateRawFee(confs, threshold,
+		fees.EstimateEconomical)
+	if err != nil {
+		return nil, rpcInvalidError("Could not estimate fee: %v", err)
+	}
+	long, err := estimator.EstimateRawFee(confs, threshold,
+		fees.EstimateConservative)
+	if err != nil {
+		return nil, rpcInvalidError("Could not estimate fee: %v", err)
+	}
+
+	return &dcrjson.EstimateRawFeeResult{
+		Short: rawFeeEstimateResult(short),
+		Long:  rawFeeEstimateResult(long),
+	}, nil
+}
+
 // handleEstimateStakeDiff implements the estimatestakediff command.
 func handleEstimateStakeDiff(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*dcrjson.EstimateStakeDiffCmd)
@@ -1767,6 +1860,17 @@ func handleGetAddedNodeInfo(s *rpcServer, cmd interface{}, closeChan <-chan stru
 
 // handleGetBestBlock implements the getbestblock command.
 func handleGetBestBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	// The best known header is the best block in headers-only mode since
+	// no block data is downloaded.
+	if cfg.HeadersOnly {
+		hash, height := s.chain.BestHeader()
+		result := &dcrjson.GetBestBlockResult{
+			Hash:   hash.String(),
+			Height: height,
+		}
+		return result, nil
+	}
+
 	// All other "get block" commands give either the height, the hash, or
 	// both but require the block SHA.  This gets both for the best block.
 	best := s.chain.BestSnapshot()
@@ -1779,6 +1883,11 @@ func handleGetBestBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}
 
 // handleGetBestBlockHash implements the getbestblockhash command.
 func handleGetBestBlockHash(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if cfg.HeadersOnly {
+		hash, _ := s.chain.BestHeader()
+		return hash.String(), nil
+	}
+
 	best := s.chain.BestSnapshot()
 	return best.Hash.String(), nil
 }
@@ -2072,7 +2181,25 @@ func handleGetBlockHeader(s *rpcServer, cmd interface{}, closeChan <-chan struct
 	var nextHashString string
 	confirmations := int64(-1)
 	height := int64(blockHeader.Height)
-	if s.chain.MainChainHasBlock(hash) {
+	if cfg.HeadersOnly {
+		// The confirmations and next block are relative to the chain
+		// that ends with the best known header in headers-only mode
+		// since no blocks are connected to the main chain.
+		_, bestHeight := s.chain.BestHeader()
+		chainHash, err := s.chain.BestHeaderHashByHeight(height)
+		if err == nil && *chainHash == *hash {
+			if height < bestHeight {
+				nextHash, err := s.chain.BestHeaderHashByHeight(height + 1)
+				if err != nil {
+					context := "No next block"
+					return nil, rpcInternalError(err.Error(),
+						context)
+				}
+				nextHashString = nextHash.String()
+			}
+			confirmations = 1 + bestHeight - height
+		}
+	} else if s.chain.MainChainHasBlock(hash) {
 		if height < best.Height {
 			nextHash, err := s.chain.BlockHashByHeight(height + 1)
 			if err != nil {
@@ -3054,7 +3181,7 @@ func handleGetHashesPerSec(s *rpcServer, cmd interface{}, closeChan <-chan struc
 
 // handleGetCFilter implements the getcfilter command.
 func handleGetCFilter(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	if s.server.cfIndex == nil {
+	if s.server.cfIndex == nil && !cfg.HeadersOnly {
 		return nil, &dcrjson.RPCError{
 			Code:    dcrjson.ErrRPCNoCFIndex,
 			Message: "Compact filters must be enabled for this command",
@@ -3078,6 +3205,24 @@ func handleGetCFilter(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 			c.FilterType)
 	}
 
+	// The filters are fetched from peers on demand in headers-only mode
+	// since the block data needed to build them is not available.
+	if cfg.HeadersOnly {
+		if _, err := s.chain.HeaderByHash(hash); err != nil {
+			return nil, &dcrjson.RPCError{
+				Code:    dcrjson.ErrRPCBlockNotFound,
+				Message: fmt.Sprintf("Block not found: %v", hash),
+			}
+		}
+		filterBytes, err := s.server.FetchCFilter(hash, filterType)
+		if err != nil {
+			context := fmt.Sprintf("Failed to fetch %v filter for "+
+				"block %v", filterType, hash)
+			return nil, rpcInternalError(err.Error(), context)
+		}
+		return hex.EncodeToString(filterBytes), nil
+	}
+
 	filterBytes, err := s.server.cfIndex.FilterByBlockHash(hash, filterType)
 	if err != nil {
 		context := fmt.Sprintf("Failed to load %v filter for block %v",
@@ -3097,7 +3242,7 @@ func handleGetCFilter(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 
 // handleGetCFilterHeader implements the getcfilterheader command.
 func handleGetCFilterHeader(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	if s.server.cfIndex == nil {
+	if s.server.cfIndex == nil && !cfg.HeadersOnly {
 		return nil, &dcrjson.RPCError{
 			Code:    dcrjson.ErrRPCNoCFIndex,
 			Message: "The CF index must be enabled for this command",
@@ -3121,6 +3266,24 @@ func handleGetCFilterHeader(s *rpcServer, cmd interface{}, closeChan <-chan stru
 			c.FilterType)
 	}
 
+	// The filter headers are fetched from peers on demand in headers-only
+	// mode since the block data needed to build them is not available.
+	if cfg.HeadersOnly {
+		if _, err := s.chain.HeaderByHash(hash); err != nil {
+			return nil, &dcrjson.RPCError{
+				Code:    dcrjson.ErrRPCBlockNotFound,
+				Message: fmt.Sprintf("Block not found: %v", hash),
+			}
+		}
+		header, err := s.server.FetchCFilterHeader(hash, filterType)
+		if err != nil {
+			context := fmt.Sprintf("Failed to fetch %v filter header "+
+				"for block %v", filterType, hash)
+			return nil, rpcInternalError(err.Error(), context)
+		}
+		return header.String(), nil
+	}
+
 	headerBytes, err := s.server.cfIndex.FilterHeaderByBlockHash(hash, filterType)
 	if err != nil {
 		context := fmt.Sprintf("Failed to load %v filter header for block %v",
@@ -3266,10 +3429,20 @@ func handleGetMiningInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{
 // handleGetNetTotals implements the getnettotals command.
 func handleGetNetTotals(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	totalBytesRecv, totalBytesSent := s.server.NetTotals()
+	uploadTarget := s.server.uploadTarget
+	bytesLeft, timeLeft := uploadTarget.Status()
 	reply := &dcrjson.GetNetTotalsResult{
 		TotalBytesRecv: totalBytesRecv,
 		TotalBytesSent: totalBytesSent,
 		TimeMillis:     time.Now().UTC().UnixNano() / int64(time.Millisecond),
+		UploadTarget: dcrjson.GetNetTotalsUploadTargetResult{
+			TimeFrame:             int64(uploadTargetTimeframe / time.Second),
+			Target:                uploadTarget.target,
+			TargetReached:         uploadTarget.Reached(false),
+			ServeHistoricalBlocks: !uploadTarget.Reached(true),
+			BytesLeftInCycle:      bytesLeft,
+			TimeLeftInCycle:       int64(timeLeft / time.Second),
+		},
 	}
 	return reply, nil
 }
@@ -3377,26 +3550,31 @@ func handleGetPeerInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 	infos := make([]*dcrjson.GetPeerInfoResult, 0, len(peers))
 	for _, p := range peers {
 		statsSnap := p.StatsSnapshot()
+		bytesSentPerMsg, bytesRecvPerMsg := p.BytesPerMsg()
 		info := &dcrjson.GetPeerInfoResult{
-			ID:             statsSnap.ID,
-			Addr:           statsSnap.Addr,
-			AddrLocal:      p.LocalAddr().String(),
-			Services:       fmt.Sprintf("%08d", uint64(statsSnap.Services)),
-			RelayTxes:      !p.disableRelayTx,
-			LastSend:       statsSnap.LastSend.Unix(),
-			LastRecv:       statsSnap.LastRecv.Unix(),
-			BytesSent:      statsSnap.BytesSent,
-			BytesRecv:      statsSnap.BytesRecv,
-			ConnTime:       statsSnap.ConnTime.Unix(),
-			PingTime:       float64(statsSnap.LastPingMicros),
-			TimeOffset:     statsSnap.TimeOffset,
-			Version:        statsSnap.Version,
-			SubVer:         statsSnap.UserAgent,
-			Inbound:        statsSnap.Inbound,
-			StartingHeight: statsSnap.StartingHeight,
-			CurrentHeight:  statsSnap.LastBlock,
-			BanScore:       int32(p.banScore.Int()),
-			SyncNode:       p == syncPeer,
+			ID:              statsSnap.ID,
+			Addr:            statsSnap.Addr,
+			AddrLocal:       p.LocalAddr().String(),
+			Services:        fmt.Sprintf("%08d", uint64(statsSnap.Services)),
+			RelayTxes:       !p.disableRelayTx,
+			LastSend:        statsSnap.LastSend.Unix(),
+			LastRecv:        statsSnap.LastRecv.Unix(),
+			BytesSent:       statsSnap.BytesSent,
+			BytesRecv:       statsSnap.BytesRecv,
+			ConnTime:        statsSnap.ConnTime.Unix(),
+			PingTime:        float64(statsSnap.LastPingMicros),
+			TimeOffset:      statsSnap.TimeOffset,
+			Version:         statsSnap.Version,
+			SubVer:          statsSnap.UserAgent,
+			Inbound:         statsSnap.Inbound,
+			StartingHeight:  statsSnap.StartingHeight,
+			CurrentHeight:   statsSnap.LastBlock,
+			BanScore:        int32(p.banScore.Int()),
+			SyncNode:        p == syncPeer,
+			Transport:       statsSnap.Transport.String(),
+			ConnType:        p.connType(),
+			BytesSentPerMsg: bytesSentPerMsg,
+			BytesRecvPerMsg: bytesRecvPerMsg,
 		}
 		if p.LastPingNonce() != 0 {
 			wait := float64(time.Since(statsSnap.LastPingTime).Nanoseconds())
@@ -3460,6 +3638,12 @@ func handleGetRawMempool(s *rpcServer, cmd interface{}, closeChan <-chan struct{
 				StartingPriority: desc.StartingPriority,
 				CurrentPriority:  desc.CurrentPriority,
 				Depends:          make([]string, len(desc.Depends)),
+				AncestorCount:    int64(desc.Ancestors.Count),
+				AncestorSize:     desc.Ancestors.Size,
+				AncestorFees:     dcrutil.Amount(desc.Ancestors.Fees).ToCoin(),
+				DescendantCount:  int64(desc.Descendants.Count),
+				DescendantSize:   desc.Descendants.Size,
+				DescendantFees:   dcrutil.Amount(desc.Descendants.Fees).ToCoin(),
 			}
 			for j, depDesc := range desc.Depends {
 				mpd.Depends[j] = depDesc.Tx.Hash().String()
@@ -3768,6 +3952,41 @@ func handleGetStakeVersions(s *rpcServer, cmd interface{}, closeChan <-chan stru
 	return result, nil
 }
 
+// handleGetSyncInfo implements the getsyncinfo command.
+func handleGetSyncInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	// unixTime returns the passed time as a unix time or zero when it is
+	// not set.
+	unixTime := func(t time.Time) int64 {
+		if t.IsZero() {
+			return 0
+		}
+		return t.Unix()
+	}
+
+	info := s.server.blockManager.SyncInfo()
+	result := &dcrjson.GetSyncInfoResult{
+		SyncHeight:         info.syncHeight,
+		HeadersFirstMode:   info.headersFirstMode,
+		SyncNodeSwitches:   info.syncPeerSwitches,
+		LastSyncNodeSwitch: unixTime(info.lastSyncPeerSwitch),
+		Peers:              make([]dcrjson.GetSyncInfoPeerResult, 0, len(info.peers)),
+	}
+	if info.syncPeer != nil {
+		result.SyncNode = info.syncPeer.Addr()
+	}
+	for _, p := range info.peers {
+		result.Peers = append(result.Peers, dcrjson.GetSyncInfoPeerResult{
+			ID:             p.peer.ID(),
+			Addr:           p.peer.Addr(),
+			SyncNode:       p.peer == info.syncPeer,
+			BlocksInFlight: int32(p.blocksInFlight),
+			DownloadRate:   int64(p.downloadRate),
+			LastStall:      unixTime(p.lastBlockStall),
+		})
+	}
+	return result, nil
+}
+
 // handleGetTicketPoolValue implements the getticketpoolvalue command.
 func handleGetTicketPoolValue(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	amt, err := s.server.blockManager.TicketPoolValue()
@@ -3908,6 +4127,31 @@ func bigToLEUint256(n *big.Int) [uint256Size]byte {
 	return buf
 }
 
+// handleGetTxOutSetInfo implements the gettxoutsetinfo command.
+func handleGetTxOutSetInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	// The UTXO set is not maintained in headers-only mode.
+	if cfg.HeadersOnly {
+		return nil, rpcInternalError("The UTXO set is not available in "+
+			"headers-only mode", "Configuration")
+	}
+
+	stats, err := s.chain.FetchUtxoStats()
+	if err != nil {
+		return nil, rpcInternalError(err.Error(),
+			"Could not fetch UTXO set statistics")
+	}
+
+	return &dcrjson.GetTxOutSetInfoResult{
+		Height:         stats.Height,
+		BestBlock:      stats.Hash.String(),
+		Transactions:   stats.Transactions,
+		TxOuts:         stats.Utxos,
+		SerializedSize: stats.SerializedSize,
+		HashSerialized: stats.SetHash.String(),
+		TotalAmount:    dcrutil.Amount(stats.Total).ToCoin(),
+	}, nil
+}
+
 // handleGetTxOut handles gettxout commands.
 func handleGetTxOut(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*dcrjson.GetTxOutCmd)
@@ -4408,6 +4652,21 @@ func handleHelp(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (inter
 	return help, nil
 }
 
+// handleListBanned implements the listbanned command.
+func handleListBanned(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	entries := s.server.banList.Entries()
+	results := make([]dcrjson.ListBannedResult, 0, len(entries))
+	for _, entry := range entries {
+		results = append(results, dcrjson.ListBannedResult{
+			Address:     entry.Subnet.String(),
+			BanCreated:  entry.Created.Unix(),
+			BannedUntil: entry.Expiry.Unix(),
+			BanReason:   entry.Reason,
+		})
+	}
+	return results, nil
+}
+
 // handleLiveTickets implements the livetickets command.
 func handleLiveTickets(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	lt, err := s.server.blockManager.chain.LiveTickets()
@@ -4424,6 +4683,19 @@ func handleLiveTickets(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 	return dcrjson.LiveTicketsResult{Tickets: ltString}, nil
 }
 
+// handleLoadMempool implements the loadmempool command.
+func handleLoadMempool(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	acceptedTxs, err := s.server.loadMempool()
+	if len(acceptedTxs) > 0 {
+		s.server.AnnounceNewTransactions(acceptedTxs)
+	}
+	if err != nil {
+		return nil, rpcInternalError(err.Error(), "Could not load mempool")
+	}
+
+	return int64(len(acceptedTxs)), nil
+}
+
 // handleMissedTickets implements the missedtickets command.
 func handleMissedTickets(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	mt, err := s.server.blockManager.chain.MissedTickets()
@@ -4758,6 +5030,59 @@ func fetchMempoolTxnsForAddress(s *rpcServer, addr dcrutil.Address, numToSkip, n
 	return mpTxns[numToSkip:rangeEnd], numToSkip
 }
 
+// handleDumpUtxoSet implements the dumputxoset command.
+func handleDumpUtxoSet(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*dcrjson.DumpUtxoSetCmd)
+
+	// Relative paths are relative to the data directory.  Refuse to
+	// overwrite existing files.
+	path := cleanAndExpandPath(c.Path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cfg.DataDir, path)
+	}
+	if fileExists(path) {
+		return nil, rpcInvalidError("File %s already exists", path)
+	}
+
+	// Write the snapshot to a temporary file that is renamed once it is
+	// complete so partially written snapshots are never mistaken for
+	// complete ones.
+	tmpPath := path + ".incomplete"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return nil, rpcInternalError(err.Error(),
+			"Could not create snapshot file")
+	}
+	info, err := s.chain.DumpSnapshot(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return nil, rpcInternalError(err.Error(), "Could not dump UTXO set")
+	}
+
+	return &dcrjson.DumpUtxoSetResult{
+		Height:     info.Height,
+		Hash:       info.Hash.String(),
+		Commitment: info.Commitment.String(),
+		Path:       path,
+	}, nil
+}
+
+// handleSaveMempool implements the savemempool command.
+func handleSaveMempool(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	numSaved, err := s.server.saveMempool()
+	if err != nil {
+		return nil, rpcInternalError(err.Error(), "Could not save mempool")
+	}
+
+	return int64(numSaved), nil
+}
+
 // handleSearchRawTransactions implements the searchrawtransactions command.
 func handleSearchRawTransactions(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	// Respond with an error if the address index is not enabled.
@@ -5027,6 +5352,77 @@ func handleSearchRawTransactions(s *rpcServer, cmd interface{}, closeChan <-chan
 	return srtList, nil
 }
 
+// handleSendRawPackage implements the sendrawpackage command.
+func handleSendRawPackage(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*dcrjson.SendRawPackageCmd)
+
+	// Deserialize all of the transactions in the package.
+	txns := make([]*dcrutil.Tx, 0, len(c.HexTxs))
+	for _, hexStr := range c.HexTxs {
+		if len(hexStr)%2 != 0 {
+			hexStr = "0" + hexStr
+		}
+		serializedTx, err := hex.DecodeString(hexStr)
+		if err != nil {
+			return nil, rpcDecodeHexError(hexStr)
+		}
+		msgTx := wire.NewMsgTx()
+		err = msgTx.Deserialize(bytes.NewReader(serializedTx))
+		if err != nil {
+			return nil, rpcDeserializationError("Could not decode Tx: %v",
+				err)
+		}
+		txns = append(txns, dcrutil.NewTx(msgTx))
+	}
+
+	acceptedTxs, txErrs, err := s.server.blockManager.ProcessPackage(txns,
+		false, *c.AllowHighFees)
+	if err != nil {
+		if _, ok := err.(mempool.RuleError); ok {
+			err = fmt.Errorf("Rejected package: %v", err)
+			rpcsLog.Debugf("%v", err)
+			return nil, rpcRuleError("%v", err)
+		}
+
+		err = fmt.Errorf("failed to process package: %v", err)
+		rpcsLog.Errorf("%v", err)
+		return nil, rpcDeserializationError("rejected: %v", err)
+	}
+
+	s.server.AnnounceNewTransactions(acceptedTxs)
+
+	results := make([]dcrjson.SendRawPackageResult, 0, len(txns))
+	for i, tx := range txns {
+		result := dcrjson.SendRawPackageResult{
+			TxID:     tx.Hash().String(),
+			Accepted: txErrs[i] == nil,
+		}
+		if txErrs[i] != nil {
+			rpcsLog.Debugf("Rejected package transaction %v: %v",
+				tx.Hash(), txErrs[i])
+			rejectCode, reason := mempool.ErrToRejectErr(txErrs[i])
+			result.RejectCode = rejectCode.String()
+			result.Reason = reason
+			results = append(results, result)
+			continue
+		}
+		results = append(results, result)
+
+		// Keep track of the accepted package transactions so that they
+		// can be rebroadcast if they don't make their way into a block.
+		// Votes are only valid for a specific block and are time
+		// sensitive, so they are not added to the rebroadcast logic.
+		if txType := stake.DetermineTxType(tx.MsgTx()); txType !=
+			stake.TxTypeSSGen {
+
+			iv := wire.NewInvVect(wire.InvTypeTx, tx.Hash())
+			s.server.AddRebroadcastInventory(iv, tx)
+		}
+	}
+
+	return results, nil
+}
+
 // handleSendRawTransaction implements the sendrawtransaction command.
 func handleSendRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*dcrjson.SendRawTransactionCmd)
@@ -5093,6 +5489,51 @@ func handleSendRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan st
 	return tx.Hash().String(), nil
 }
 
+// handleSetBan implements the setban command.
+func handleSetBan(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*dcrjson.SetBanCmd)
+
+	subnet, err := connmgr.ParseSubnet(c.Subnet)
+	if err != nil {
+		return nil, rpcInvalidError("%v: %v", c.SubCmd, err)
+	}
+
+	switch c.SubCmd {
+	case dcrjson.SBAdd:
+		// The ban time is either an absolute unix time or a number of
+		// seconds from now.  Use the configured ban duration when it is
+		// not specified.
+		var banTime int64
+		if c.BanTime != nil {
+			banTime = *c.BanTime
+		}
+		expiry := time.Now().Add(cfg.BanDuration)
+		switch {
+		case c.Absolute != nil && *c.Absolute:
+			expiry = time.Unix(banTime, 0)
+		case banTime > 0:
+			expiry = time.Now().Add(time.Duration(banTime) * time.Second)
+		}
+		if !expiry.After(time.Now()) {
+			return nil, rpcInvalidError("%v: ban time is in the past",
+				c.SubCmd)
+		}
+
+		s.server.BanSubnet(subnet, expiry, "manual")
+	case dcrjson.SBRemove:
+		if !s.server.banList.Unban(subnet) {
+			return nil, rpcInvalidError("%v: %v is not banned", c.SubCmd,
+				subnet)
+		}
+	default:
+		return nil, rpcInvalidError("%v: invalid subcommand for setban",
+			c.SubCmd)
+	}
+
+	// no data returned unless an error.
+	return nil, nil
+}
+
 // handleSetGenerate implements the setgenerate command.
 func handleSetGenerate(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*dcrjson.SetGenerateCmd)
@@ -5380,6 +5821,62 @@ func ticketFeeInfoForRange(s *rpcServer, start int64, end int64, txType stake.Tx
 	}, nil
 }
 
+// handleTestMempoolAccept implements the testmempoolaccept command.
+func handleTestMempoolAccept(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*dcrjson.TestMempoolAcceptCmd)
+
+	// Deserialize all of the transactions up front so that malformed input
+	// is reported before any of them are tested.
+	txns := make([]*dcrutil.Tx, 0, len(c.HexTxs))
+	for _, hexStr := range c.HexTxs {
+		if len(hexStr)%2 != 0 {
+			hexStr = "0" + hexStr
+		}
+		serializedTx, err := hex.DecodeString(hexStr)
+		if err != nil {
+			return nil, rpcDecodeHexError(hexStr)
+		}
+		msgTx := wire.NewMsgTx()
+		err = msgTx.Deserialize(bytes.NewReader(serializedTx))
+		if err != nil {
+			return nil, rpcDeserializationError("Could not decode Tx: %v",
+				err)
+		}
+		txns = append(txns, dcrutil.NewTx(msgTx))
+	}
+
+	// Test the transactions in order against the current state of the
+	// memory pool as if every earlier transaction that would be accepted
+	// was already added to it so that transactions spending outputs of
+	// earlier ones in the request are not reported as orphans.  None of
+	// them are actually added to the pool.
+	fees, errs := s.server.txMemPool.TestAcceptTransactions(txns,
+		*c.AllowHighFees)
+	results := make([]dcrjson.TestMempoolAcceptResult, 0, len(txns))
+	for i, tx := range txns {
+		result := dcrjson.TestMempoolAcceptResult{
+			TxID: tx.Hash().String(),
+		}
+		if err := errs[i]; err != nil {
+			if _, ok := err.(mempool.RuleError); !ok {
+				context := "Failed to test transaction"
+				return nil, rpcInternalError(err.Error(), context)
+			}
+			rejectCode, reason := mempool.ErrToRejectErr(err)
+			result.RejectCode = rejectCode.String()
+			result.Reason = reason
+			results = append(results, result)
+			continue
+		}
+		result.Allowed = true
+		result.Size = int64(tx.MsgTx().SerializeSize())
+		result.Fee = dcrutil.Amount(fees[i]).ToCoin()
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
 // handleTicketFeeInfo implements the ticketfeeinfo command.
 func handleTicketFeeInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*dcrjson.TicketFeeInfoCmd)
@@ -0,0 +1,229 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/decred/dcrd/blockchain/stake"
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/dcrjson/v2"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/fees"
+	"github.com/decred/dcrd/wire"
+)
+
+// newTestFeeEstimator returns a fee estimator which has observed the passed
+// number of regular transactions and ticket purchases paying the respective
+// passed fee rates being mined in the block after they entered the mempool.
+func newTestFeeEstimator(t *testing.T, numTxns int, regularRate, ticketRate dcrutil.Amount) *fees.Estimator {
+	t.Helper()
+
+	est, err := fees.NewEstimator(&fees.EstimatorConfig{
+		MaxConfirms:  fees.DefaultMaxConfirmations,
+		MinBucketFee: 10000,
+		MaxBucketFee: 10000 * dcrutil.Amount(fees.DefaultMaxBucketFeeMultiplier),
+		FeeRateStep:  fees.DefaultFeeRateStep,
+		ChainParams:  &chaincfg.MainNetParams,
+	})
+	if err != nil {
+		t.Fatalf("unable to create fee estimator: %v", err)
+	}
+	est.Enable(100)
+
+	// Use transactions of 1000 bytes so the fees are equal to the rates.
+	block := &wire.MsgBlock{Header: wire.BlockHeader{Height: 101}}
+	for i := 0; i < numTxns*2; i++ {
+		tx := wire.NewMsgTx()
+		tx.LockTime = uint32(i)
+		txHash := tx.TxHash()
+		if i < numTxns {
+			est.AddMemPoolTransaction(&txHash, int64(regularRate), 1000,
+				stake.TxTypeRegular)
+			block.Transactions = append(block.Transactions, tx)
+			continue
+		}
+		est.AddMemPoolTransaction(&txHash, int64(ticketRate), 1000,
+			stake.TxTypeSStx)
+		block.STransactions = append(block.STransactions, tx)
+	}
+	if err := est.ProcessBlock(dcrutil.NewBlock(block)); err != nil {
+		est.Close()
+		t.Fatalf("unable to process block: %v", err)
+	}
+	return est
+}
+
+// TestHandleEstimateSmartFee ensures the estimatesmartfee handler uses the
+// statistics which correspond to the requested estimation mode.
+func TestHandleEstimateSmartFee(t *testing.T) {
+	const regularRate, ticketRate = 20000, 50000
+	est := newTestFeeEstimator(t, 10, regularRate, ticketRate)
+	defer est.Close()
+	s := &rpcServer{server: &server{feeEstimator: est}}
+
+	mode := func(m dcrjson.EstimateSmartFeeMode) *dcrjson.EstimateSmartFeeMode {
+		return &m
+	}
+	tests := []struct {
+		name    string
+		mode    *dcrjson.EstimateSmartFeeMode
+		want    dcrutil.Amount
+		wantErr bool
+	}{
+		{"default", nil, regularRate, false},
+		{"conservative", mode(dcrjson.EstimateSmartFeeConservative),
+			regularRate, false},
+		{"economical", mode(dcrjson.EstimateSmartFeeEconomical),
+			regularRate, false},
+		{"ticket", mode(dcrjson.EstimateSmartFeeTicket), ticketRate, false},
+		{"unsupported", mode("unsupported"), 0, true},
+	}
+	for _, test := range tests {
+		cmd := dcrjson.NewEstimateSmartFeeCmd(1, test.mode)
+		result, err := handleEstimateSmartFee(s, cmd, nil)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s: did not receive expected error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if result != test.want.ToCoin() {
+			t.Errorf("%s: unexpected fee -- got %v, want %v", test.name,
+				result, test.want.ToCoin())
+		}
+	}
+}
+
+// TestRawFeeEstimateResult ensures raw fee estimates are converted to the
+// expected estimaterawfee results.
+func TestRawFeeEstimateResult(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  *fees.RawFeeEstimate
+		want *dcrjson.EstimateRawFeeHorizonResult
+	}{{
+		name: "estimate",
+		raw: &fees.RawFeeEstimate{
+			FeeRate:    20000,
+			Decay:      0.998,
+			SuccessPct: 0.95,
+			Pass: &fees.BucketRangeStats{
+				StartRange:     19487,
+				EndRange:       21436,
+				WithinTarget:   9.5,
+				TotalConfirmed: 10,
+				InMemPool:      1,
+			},
+			Fail: &fees.BucketRangeStats{
+				StartRange:     10000,
+				EndRange:       19487,
+				WithinTarget:   2,
+				TotalConfirmed: 5,
+				InMemPool:      3,
+			},
+		},
+		want: &dcrjson.EstimateRawFeeHorizonResult{
+			FeeRate: 0.0002,
+			Decay:   0.998,
+			Pass: &dcrjson.EstimateRawFeeBucketRange{
+				StartRange:     0.00019487,
+				EndRange:       0.00021436,
+				WithinTarget:   9.5,
+				TotalConfirmed: 10,
+				InMempool:      1,
+			},
+			Fail: &dcrjson.EstimateRawFeeBucketRange{
+				StartRange:     0.0001,
+				EndRange:       0.00019487,
+				WithinTarget:   2,
+				TotalConfirmed: 5,
+				InMempool:      3,
+			},
+		},
+	}, {
+		name: "no estimate",
+		raw: &fees.RawFeeEstimate{
+			Decay:      0.962,
+			SuccessPct: 0.85,
+			Err:        fees.ErrNotEnoughTxsForEstimate,
+		},
+		want: &dcrjson.EstimateRawFeeHorizonResult{
+			Decay:  0.962,
+			Errors: []string{fees.ErrNotEnoughTxsForEstimate.Error()},
+		},
+	}}
+	for _, test := range tests {
+		got := rawFeeEstimateResult(test.raw)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: unexpected result -- got %+v, want %+v",
+				test.name, got, test.want)
+		}
+	}
+}
+
+// TestHandleEstimateRawFee ensures the estimaterawfee handler validates the
+// threshold and reports the estimates of both decay horizons.
+func TestHandleEstimateRawFee(t *testing.T) {
+	const regularRate, ticketRate = 20000, 50000
+	est := newTestFeeEstimator(t, 10, regularRate, ticketRate)
+	defer est.Close()
+	s := &rpcServer{server: &server{feeEstimator: est}}
+
+	// Ensure thresholds outside of the range (0, 1] are rejected.
+	for _, threshold := range []float64{0, -0.1, 1.1} {
+		threshold := threshold
+		cmd := dcrjson.NewEstimateRawFeeCmd(1, &threshold)
+		if _, err := handleEstimateRawFee(s, cmd, nil); err == nil {
+			t.Fatalf("did not receive expected error for threshold %v",
+				threshold)
+		}
+	}
+
+	// Ensure both horizons report the estimate with their own decay when
+	// using the default threshold.
+	cmd := dcrjson.NewEstimateRawFeeCmd(1, nil)
+	result, err := handleEstimateRawFee(s, cmd, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res := result.(*dcrjson.EstimateRawFeeResult)
+	horizons := []struct {
+		name  string
+		res   *dcrjson.EstimateRawFeeHorizonResult
+		decay float64
+	}{
+		{"short", res.Short, 0.962},
+		{"long", res.Long, 0.998},
+	}
+	for _, h := range horizons {
+		if h.res.Decay != h.decay {
+			t.Errorf("%s: unexpected decay -- got %v, want %v", h.name,
+				h.res.Decay, h.decay)
+		}
+		if len(h.res.Errors) != 0 {
+			t.Errorf("%s: unexpected errors: %v", h.name, h.res.Errors)
+			continue
+		}
+		want := dcrutil.Amount(regularRate).ToCoin()
+		if h.res.FeeRate != want {
+			t.Errorf("%s: unexpected fee rate -- got %v, want %v", h.name,
+				h.res.FeeRate, want)
+		}
+		if h.res.Pass == nil || h.res.Pass.TotalConfirmed != 10 ||
+			h.res.Pass.WithinTarget != 10 {
+
+			t.Errorf("%s: unexpected pass range %+v", h.name, h.res.Pass)
+		}
+		if h.res.Fail != nil {
+			t.Errorf("%s: unexpected fail range %+v", h.name, h.res.Fail)
+		}
+	}
+}
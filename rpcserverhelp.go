@@ -40,6 +40,24 @@ var helpDescsEnUS = map[string]string{
 	"node-target":        "Either the IP address and port of the peer to operate on, or a valid peer ID.",
 	"node-connectsubcmd": "'perm' to make the connected peer a permanent one, 'temp' to try a single connect to a peer",
 
+	// SetBanCmd help.
+	"setban--synopsis": "Attempts to add or remove an IP address or subnet from the ban list.\n" +
+		"Peers within a newly banned IP address or subnet are disconnected.",
+	"setban-subnet":   "The IP address or subnet in CIDR notation (for example 192.168.0.0/24) to operate on",
+	"setban-subcmd":   "'add' to ban the IP address or subnet, or 'remove' to remove its ban",
+	"setban-bantime":  "The number of seconds to ban for or 0 to use the configured ban duration (--banduration)",
+	"setban-absolute": "Interpret bantime as an absolute unix time at which the ban expires",
+
+	// ListBannedCmd help.
+	"listbanned--synopsis":         "Returns the banned IP addresses and subnets.",
+	"listbannedresult-address":     "The banned IP address or subnet in CIDR notation",
+	"listbannedresult-bancreated":  "The time the ban was created in seconds since 1 Jan 1970 GMT",
+	"listbannedresult-banneduntil": "The time the ban expires in seconds since 1 Jan 1970 GMT",
+	"listbannedresult-banreason":   "The reason for the ban ('manual' or 'misbehaving')",
+
+	// ClearBannedCmd help.
+	"clearbanned--synopsis": "Removes all bans from the ban list.",
+
 	// TransactionInput help.
 	"transactioninput-amount": "The previous output amount",
 	"transactioninput-txid":   "The hash of the input transaction",
@@ -157,6 +175,17 @@ var helpDescsEnUS = map[string]string{
 	"decodescript--synopsis": "Returns a JSON object with information about the provided hex-encoded script.",
 	"decodescript-hexscript": "Hex-encoded script",
 
+	// DumpUtxoSetCmd help.
+	"dumputxoset--synopsis": "Writes a snapshot of the chain state as of the current best block, including the UTXO set, the ticket database state, and the block index, to a file.\n" +
+		"The snapshot can be loaded by new nodes with the --loadsnapshot option once its commitment hash is listed in the network parameters.",
+	"dumputxoset-path": "The file to write the snapshot to, relative to the data directory unless it is an absolute path -- it must not already exist",
+
+	// DumpUtxoSetResult help.
+	"dumputxosetresult-height":     "The height of the block the snapshot was created at",
+	"dumputxosetresult-hash":       "The hash of the block the snapshot was created at",
+	"dumputxosetresult-commitment": "The hash that commits to the entire contents of the snapshot",
+	"dumputxosetresult-path":       "The path of the written snapshot file",
+
 	// ExistsAddressCmd help.
 	"existsaddress--synopsis": "Test for the existence of the provided address",
 	"existsaddress-address":   "The address to check",
@@ -489,6 +518,21 @@ var helpDescsEnUS = map[string]string{
 	"versioninterval-voteversions":            "Tally of all vote versions.",
 	"versioninterval-posversions":             "Tally of the stake versions.",
 
+	// GetSyncInfoCmd help.
+	"getsyncinfo--synopsis":                "Returns information about the chain sync and the block download rates of the peers it is synced from.",
+	"getsyncinforesult-syncnode":           "The address of the peer the chain is synced from",
+	"getsyncinforesult-syncheight":         "The latest known block height being synced to",
+	"getsyncinforesult-headersfirstmode":   "Whether or not the blocks are being downloaded from multiple peers based on the headers of the sync peer",
+	"getsyncinforesult-syncnodeswitches":   "The number of times the sync peer was replaced for downloading blocks significantly slower than another peer",
+	"getsyncinforesult-lastsyncnodeswitch": "The time the sync peer was last replaced in seconds since 1 Jan 1970 GMT or 0 if it never was",
+	"getsyncinforesult-peers":              "The sync candidate peers",
+	"getsyncinfopeerresult-id":             "A unique node ID",
+	"getsyncinfopeerresult-addr":           "The ip address and port of the peer",
+	"getsyncinfopeerresult-syncnode":       "Whether or not the peer is the sync peer",
+	"getsyncinfopeerresult-blocksinflight": "The number of blocks requested from the peer that have not been received yet",
+	"getsyncinfopeerresult-downloadrate":   "The average number of bytes of requested blocks the peer delivers per second",
+	"getsyncinfopeerresult-laststall":      "The time the peer last stalled or was replaced as the sync peer in seconds since 1 Jan 1970 GMT or 0 if it never did",
+
 	// GetStakeDifficultyCmd help.
 	"getstakeversions--synopsis":           "Returns the stake versions statistics.",
 	"getstakeversions-hash":                "The start block hash.",
@@ -613,28 +657,47 @@ var helpDescsEnUS = map[string]string{
 	"getnettotalsresult-totalbytesrecv": "Total bytes received",
 	"getnettotalsresult-totalbytessent": "Total bytes sent",
 	"getnettotalsresult-timemillis":     "Number of milliseconds since 1 Jan 1970 GMT",
+	"getnettotalsresult-uploadtarget":   "The state of the upload target",
+
+	// GetNetTotalsUploadTargetResult help.
+	"getnettotalsuploadtargetresult-timeframe":             "Length of the upload target cycle in seconds",
+	"getnettotalsuploadtargetresult-target":                "Target number of bytes to upload per cycle or 0 when disabled",
+	"getnettotalsuploadtargetresult-targetreached":         "Whether or not the target has been reached",
+	"getnettotalsuploadtargetresult-servehistoricalblocks": "Whether or not historical blocks are served to non-whitelisted peers",
+	"getnettotalsuploadtargetresult-bytesleftincycle":      "Number of bytes left in the current cycle",
+	"getnettotalsuploadtargetresult-timeleftincycle":       "Number of seconds left in the current cycle",
 
 	// GetPeerInfoResult help.
-	"getpeerinforesult-id":             "A unique node ID",
-	"getpeerinforesult-addr":           "The ip address and port of the peer",
-	"getpeerinforesult-addrlocal":      "Local address",
-	"getpeerinforesult-services":       "Services bitmask which represents the services supported by the peer",
-	"getpeerinforesult-relaytxes":      "Peer has requested transactions be relayed to it",
-	"getpeerinforesult-lastsend":       "Time the last message was received in seconds since 1 Jan 1970 GMT",
-	"getpeerinforesult-lastrecv":       "Time the last message was sent in seconds since 1 Jan 1970 GMT",
-	"getpeerinforesult-bytessent":      "Total bytes sent",
-	"getpeerinforesult-bytesrecv":      "Total bytes received",
-	"getpeerinforesult-conntime":       "Time the connection was made in seconds since 1 Jan 1970 GMT",
-	"getpeerinforesult-timeoffset":     "The time offset of the peer",
-	"getpeerinforesult-pingtime":       "Number of microseconds the last ping took",
-	"getpeerinforesult-pingwait":       "Number of microseconds a queued ping has been waiting for a response",
-	"getpeerinforesult-version":        "The protocol version of the peer",
-	"getpeerinforesult-subver":         "The user agent of the peer",
-	"getpeerinforesult-inbound":        "Whether or not the peer is an inbound connection",
-	"getpeerinforesult-startingheight": "The latest block height the peer knew about when the connection was established",
-	"getpeerinforesult-currentheight":  "The current height of the peer",
-	"getpeerinforesult-banscore":       "The ban score",
-	"getpeerinforesult-syncnode":       "Whether or not the peer is the sync peer",
+	"getpeerinforesult-id":                     "A unique node ID",
+	"getpeerinforesult-addr":                   "The ip address and port of the peer",
+	"getpeerinforesult-addrlocal":              "Local address",
+	"getpeerinforesult-services":               "Services bitmask which represents the services supported by the peer",
+	"getpeerinforesult-relaytxes":              "Peer has requested transactions be relayed to it",
+	"getpeerinforesult-lastsend":               "Time the last message was received in seconds since 1 Jan 1970 GMT",
+	"getpeerinforesult-lastrecv":               "Time the last message was sent in seconds since 1 Jan 1970 GMT",
+	"getpeerinforesult-bytessent":              "Total bytes sent",
+	"getpeerinforesult-bytesrecv":              "Total bytes received",
+	"getpeerinforesult-conntime":               "Time the connection was made in seconds since 1 Jan 1970 GMT",
+	"getpeerinforesult-timeoffset":             "The time offset of the peer",
+	"getpeerinforesult-pingtime":               "Number of microseconds the last ping took",
+	"getpeerinforesult-pingwait":               "Number of microseconds a queued ping has been waiting for a response",
+	"getpeerinforesult-version":                "The protocol version of the peer",
+	"getpeerinforesult-subver":                 "The user agent of the peer",
+	"getpeerinforesult-inbound":                "Whether or not the peer is an inbound connection",
+	"getpeerinforesult-startingheight":         "The latest block height the peer knew about when the connection was established",
+	"getpeerinforesult-currentheight":          "The current height of the peer",
+	"getpeerinforesult-banscore":               "The ban score",
+	"getpeerinforesult-syncnode":               "Whether or not the peer is the sync peer",
+	"getpeerinforesult-transport":              "The transport used to exchange messages with the peer (v1: plaintext, v2: encrypted and authenticated)",
+	"getpeerinforesult-conntype":               "The type of the connection to the peer (inbound, outbound-full-relay, block-relay-only, manual)",
+	"getpeerinforesult-bytessentpermsg":        "Total bytes sent per message type",
+	"getpeerinforesult-bytessentpermsg--desc":  "Total bytes sent per message type",
+	"getpeerinforesult-bytessentpermsg--key":   "command",
+	"getpeerinforesult-bytessentpermsg--value": "n",
+	"getpeerinforesult-bytesrecvpermsg":        "Total bytes received per message type",
+	"getpeerinforesult-bytesrecvpermsg--desc":  "Total bytes received per message type",
+	"getpeerinforesult-bytesrecvpermsg--key":   "command",
+	"getpeerinforesult-bytesrecvpermsg--value": "n",
 
 	// GetPeerInfoCmd help.
 	"getpeerinfo--synopsis": "Returns data about each connected network peer as an array of json objects.",
@@ -647,6 +710,12 @@ var helpDescsEnUS = map[string]string{
 	"getrawmempoolverboseresult-startingpriority": "Priority when transaction entered the pool",
 	"getrawmempoolverboseresult-currentpriority":  "Current priority",
 	"getrawmempoolverboseresult-depends":          "Unconfirmed transactions used as inputs for this transaction",
+	"getrawmempoolverboseresult-ancestorcount":    "Number of transactions in the mempool this transaction depends on, including itself",
+	"getrawmempoolverboseresult-ancestorsize":     "Total size in bytes of this transaction and all mempool transactions it depends on",
+	"getrawmempoolverboseresult-ancestorfees":     "Total fees in decred of this transaction and all mempool transactions it depends on",
+	"getrawmempoolverboseresult-descendantcount":  "Number of transactions in the mempool that depend on this transaction, including itself",
+	"getrawmempoolverboseresult-descendantsize":   "Total size in bytes of this transaction and all mempool transactions that depend on it",
+	"getrawmempoolverboseresult-descendantfees":   "Total fees in decred of this transaction and all mempool transactions that depend on it",
 
 	// GetRawMempoolCmd help.
 	"getrawmempool--synopsis":   "Returns information about all of the transactions currently in the memory pool.",
@@ -676,6 +745,19 @@ var helpDescsEnUS = map[string]string{
 	"gettxoutresult-version":       "The transaction version",
 	"gettxoutresult-coinbase":      "Whether or not the transaction is a coinbase",
 
+	// GetTxOutSetInfoCmd help.
+	"gettxoutsetinfo--synopsis": "Returns statistics about the unspent transaction output set as of the current best block.\n" +
+		"The entire set is iterated, so this call may take some time.",
+
+	// GetTxOutSetInfoResult help.
+	"gettxoutsetinforesult-height":         "The height of the best block",
+	"gettxoutsetinforesult-bestblock":      "The hash of the best block",
+	"gettxoutsetinforesult-transactions":   "The number of transactions with unspent outputs",
+	"gettxoutsetinforesult-txouts":         "The number of unspent transaction outputs",
+	"gettxoutsetinforesult-serializedsize": "The size of the serialized unspent transaction output set in the database",
+	"gettxoutsetinforesult-hashserialized": "The hash of the serialized unspent transaction output set",
+	"gettxoutsetinforesult-totalamount":    "The total amount of all unspent transaction outputs in DCR",
+
 	// GetTxOutCmd help.
 	"gettxout--synopsis":      "Returns information about an unspent transaction output..",
 	"gettxout-txid":           "The hash of the transaction",
@@ -703,6 +785,11 @@ var helpDescsEnUS = map[string]string{
 	"help--result0":    "List of commands",
 	"help--result1":    "Help for specified command",
 
+	// LoadMempoolCmd help.
+	"loadmempool--synopsis": "Loads the transactions saved to the mempool file in the data directory back into the memory pool.\n" +
+		"Every transaction is fully validated against the current best chain and those which are no longer valid are skipped.",
+	"loadmempool--result0": "The number of transactions added to the memory pool",
+
 	// PingCmd help.
 	"ping--synopsis": "Queues a ping to be sent to each connected peer.\n" +
 		"Ping times are provided by getpeerinfo via the pingtime and pingwait fields.",
@@ -713,6 +800,10 @@ var helpDescsEnUS = map[string]string{
 	// RebroadcastWinnerCmd help.
 	"rebroadcastwinners--synopsis": "Asks the daemon to rebroadcast the winners of the voting lottery.\n",
 
+	// SaveMempoolCmd help.
+	"savemempool--synopsis": "Saves the transactions in the memory pool to the mempool file in the data directory so they can be loaded on the next startup.",
+	"savemempool--result0":  "The number of saved transactions",
+
 	// SearchRawTransactionsCmd help.
 	"searchrawtransactions--synopsis": "Returns raw data for transactions involving the passed address.\n" +
 		"Returned transactions are pulled from both the database, and transactions currently in the mempool.\n" +
@@ -730,6 +821,19 @@ var helpDescsEnUS = map[string]string{
 	"searchrawtransactions-filteraddrs": "Address list.  Only inputs or outputs with matching address will be returned",
 	"searchrawtransactions--result0":    "Hex-encoded serialized transaction",
 
+	// SendRawPackageCmd help.
+	"sendrawpackage--synopsis": "Submits a package of serialized, hex-encoded transactions to the local peer and relays the accepted transactions to the network.\n" +
+		"The transactions must be sorted such that every transaction appears after all of the transactions in the package it depends on.\n" +
+		"Transactions which do not pay enough fees on their own are accepted when the combined fee rate of them and the transactions in the package that depend on them is sufficient.",
+	"sendrawpackage-hextxs":        "Serialized, hex-encoded signed transactions",
+	"sendrawpackage-allowhighfees": "Whether or not to allow insanely high fees",
+
+	// SendRawPackageResult help.
+	"sendrawpackageresult-txid":       "The hash of the transaction",
+	"sendrawpackageresult-accepted":   "Whether or not the transaction was accepted to the memory pool or was already in it",
+	"sendrawpackageresult-rejectcode": "The reject code when the transaction was rejected",
+	"sendrawpackageresult-reason":     "The reason the transaction was rejected",
+
 	// SendRawTransactionCmd help.
 	"sendrawtransaction--synopsis":     "Submits the serialized, hex-encoded transaction to the local peer and relays it to the network.",
 	"sendrawtransaction-hextx":         "Serialized, hex-encoded signed transaction",
@@ -756,6 +860,20 @@ var helpDescsEnUS = map[string]string{
 	"submitblock--condition1": "Block rejected",
 	"submitblock--result1":    "The reason the block was rejected",
 
+	// TestMempoolAcceptCmd help.
+	"testmempoolaccept--synopsis": "Tests whether or not each of the serialized, hex-encoded transactions would be accepted to the memory pool without adding them to it or relaying them.\n" +
+		"The transactions are tested in order as if each earlier transaction that would be accepted was already added to the memory pool, so transactions may spend outputs of earlier transactions in the same request.",
+	"testmempoolaccept-hextxs":        "Serialized, hex-encoded signed transactions",
+	"testmempoolaccept-allowhighfees": "Whether or not to allow insanely high fees",
+
+	// TestMempoolAcceptResult help.
+	"testmempoolacceptresult-txid":       "The hash of the transaction",
+	"testmempoolacceptresult-allowed":    "Whether or not the transaction would be accepted to the memory pool",
+	"testmempoolacceptresult-size":       "The serialized size of the transaction in bytes (only when allowed is true)",
+	"testmempoolacceptresult-fee":        "The fee paid by the transaction in DCR (only when allowed is true)",
+	"testmempoolacceptresult-rejectcode": "The reject code when the transaction would be rejected",
+	"testmempoolacceptresult-reason":     "The reason the transaction would be rejected",
+
 	// ValidateAddressResult help.
 	"validateaddresschainresult-isvalid": "Whether or not the address is valid",
 	"validateaddresschainresult-address": "The Decred address (only when isvalid is true)",
@@ -812,6 +930,12 @@ var helpDescsEnUS = map[string]string{
 	// StopNotifyNewTransactionsCmd help.
 	"stopnotifynewtransactions--synopsis": "Stop sending either a txaccepted or a txacceptedverbose notification when a new transaction is accepted into the mempool.",
 
+	// NotifyMempoolEventsCmd help.
+	"notifymempoolevents--synopsis": "Send a mempooltxadded notification when a transaction is added to the mempool and a mempooltxremoved notification, which includes the reason, when a transaction is removed from it.",
+
+	// StopNotifyMempoolEventsCmd help.
+	"stopnotifymempoolevents--synopsis": "Stop sending mempooltxadded and mempooltxremoved notifications when transactions are added to or removed from the mempool.",
+
 	// OutPoint help.
 	"outpoint-hash":  "The hex-encoded bytes of the outpoint hash",
 	"outpoint-index": "The index of the outpoint",
@@ -834,10 +958,33 @@ var helpDescsEnUS = map[string]string{
 	"estimatefee-numblocks": "(unused)",
 	"estimatefee--result0":  "Estimated fee.",
 
+	// EstimateRawFee help.
+	"estimaterawfee--synopsis":     "Returns the details of the historical fee data used to estimate the fee rate of regular transactions for both the short and long decay horizons.",
+	"estimaterawfee-confirmations": "Estimate the fee rate a transaction requires so that it is mined in up to this number of blocks.",
+	"estimaterawfee-threshold":     "The minimum percentage (in the range (0, 1]) of transactions in a range of fee rate buckets that must have been mined within the target for the range to pass.",
+
+	// EstimateRawFeeResult help.
+	"estimaterawfeeresult-short": "The estimate using the statistics decayed over a short horizon (as used by the economical mode of estimatesmartfee).",
+	"estimaterawfeeresult-long":  "The estimate using the statistics decayed over a long horizon (as used by the conservative mode of estimatesmartfee).",
+
+	// EstimateRawFeeHorizonResult help.
+	"estimaterawfeehorizonresult-feerate": "The estimated fee rate (in DCR/KB), omitted when no estimate could be produced.",
+	"estimaterawfeehorizonresult-decay":   "The factor used to decay the statistics every new block.",
+	"estimaterawfeehorizonresult-pass":    "The lowest range of fee rate buckets which met the threshold, omitted when no range passed.",
+	"estimaterawfeehorizonresult-fail":    "The highest range of fee rate buckets which did not meet the threshold, omitted when no range failed.",
+	"estimaterawfeehorizonresult-errors":  "The reasons no fee rate could be estimated, if any.",
+
+	// EstimateRawFeeBucketRange help.
+	"estimaterawfeebucketrange-startrange":     "The lower bound of the fee rates (in DCR/KB) in the range.",
+	"estimaterawfeebucketrange-endrange":       "The upper bound of the fee rates (in DCR/KB) in the range.",
+	"estimaterawfeebucketrange-withintarget":   "The (decayed) number of transactions in the range mined within the target.",
+	"estimaterawfeebucketrange-totalconfirmed": "The (decayed) total number of transactions in the range that were mined.",
+	"estimaterawfeebucketrange-inmempool":      "The number of transactions in the range still in the mempool after waiting for the target number of blocks.",
+
 	// EstimateSmartFee help.
 	"estimatesmartfee--synopsis":     "Returns the estimated fee using the historical fee data in dcr/kb.",
 	"estimatesmartfee-confirmations": "Estimate the fee rate a transaction requires so that it is mined in up to this number of blocks.",
-	"estimatesmartfee-mode":          "The only supported mode for the moment is 'conservative'.",
+	"estimatesmartfee-mode":          "The estimation mode: 'conservative' or 'economical' for regular transactions or 'ticket' for ticket purchases.",
 	"estimatesmartfee--result0":      "Estimated fee rate (in DCR/KB).",
 
 	// EstimateStakeDiff help.
@@ -943,13 +1090,16 @@ var helpDescsEnUS = map[string]string{
 // pointer to the type (or nil to indicate no return value).
 var rpcResultTypes = map[string][]interface{}{
 	"addnode":               nil,
+	"clearbanned":           nil,
 	"createrawsstx":         {(*string)(nil)},
 	"createrawssrtx":        {(*string)(nil)},
 	"createrawtransaction":  {(*string)(nil)},
 	"debuglevel":            {(*string)(nil), (*string)(nil)},
 	"decoderawtransaction":  {(*dcrjson.TxRawDecodeResult)(nil)},
 	"decodescript":          {(*dcrjson.DecodeScriptResult)(nil)},
+	"dumputxoset":           {(*dcrjson.DumpUtxoSetResult)(nil)},
 	"estimatefee":           {(*float64)(nil)},
+	"estimaterawfee":        {(*dcrjson.EstimateRawFeeResult)(nil)},
 	"estimatesmartfee":      {(*float64)(nil)},
 	"estimatestakediff":     {(*dcrjson.EstimateStakeDiffResult)(nil)},
 	"existsaddress":         {(*bool)(nil)},
@@ -990,23 +1140,31 @@ var rpcResultTypes = map[string][]interface{}{
 	"getpeerinfo":           {(*[]dcrjson.GetPeerInfoResult)(nil)},
 	"getrawmempool":         {(*[]string)(nil), (*dcrjson.GetRawMempoolVerboseResult)(nil)},
 	"getrawtransaction":     {(*string)(nil), (*dcrjson.TxRawResult)(nil)},
+	"getsyncinfo":           {(*dcrjson.GetSyncInfoResult)(nil)},
 	"getticketpoolvalue":    {(*float64)(nil)},
 	"gettxout":              {(*dcrjson.GetTxOutResult)(nil)},
+	"gettxoutsetinfo":       {(*dcrjson.GetTxOutSetInfoResult)(nil)},
 	"getvoteinfo":           {(*dcrjson.GetVoteInfoResult)(nil)},
 	"getwork":               {(*dcrjson.GetWorkResult)(nil), (*bool)(nil)},
 	"getcoinsupply":         {(*int64)(nil)},
 	"help":                  {(*string)(nil), (*string)(nil)},
+	"listbanned":            {(*[]dcrjson.ListBannedResult)(nil)},
 	"livetickets":           {(*dcrjson.LiveTicketsResult)(nil)},
+	"loadmempool":           {(*int64)(nil)},
 	"missedtickets":         {(*dcrjson.MissedTicketsResult)(nil)},
 	"node":                  nil,
 	"ping":                  nil,
 	"rebroadcastmissed":     nil,
 	"rebroadcastwinners":    nil,
+	"savemempool":           {(*int64)(nil)},
 	"searchrawtransactions": {(*string)(nil), (*[]dcrjson.SearchRawTransactionsResult)(nil)},
+	"sendrawpackage":        {(*[]dcrjson.SendRawPackageResult)(nil)},
 	"sendrawtransaction":    {(*string)(nil)},
+	"setban":                nil,
 	"setgenerate":           nil,
 	"stop":                  {(*string)(nil)},
 	"submitblock":           {nil, (*string)(nil)},
+	"testmempoolaccept":     {(*[]dcrjson.TestMempoolAcceptResult)(nil)},
 	"ticketfeeinfo":         {(*dcrjson.TicketFeeInfoResult)(nil)},
 	"ticketsforaddress":     {(*dcrjson.TicketsForAddressResult)(nil)},
 	"ticketvwap":            {(*float64)(nil)},
@@ -1024,11 +1182,13 @@ var rpcResultTypes = map[string][]interface{}{
 	"notifynewtickets":            nil,
 	"notifystakedifficulty":       nil,
 	"notifyblocks":                nil,
+	"notifymempoolevents":         nil,
 	"notifynewtransactions":       nil,
 	"notifyreceived":              nil,
 	"notifyspent":                 nil,
 	"rescan":                      nil,
 	"stopnotifyblocks":            nil,
+	"stopnotifymempoolevents":     nil,
 	"stopnotifynewtransactions":   nil,
 	"stopnotifyreceived":          nil,
 	"stopnotifyspent":             nil,
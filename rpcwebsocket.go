@@ -28,6 +28,7 @@ import (
 	"github.com/decred/dcrd/chaincfg/chainhash"
 	"github.com/decred/dcrd/dcrjson/v2"
 	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/mempool/v2"
 	"github.com/decred/dcrd/txscript"
 	"github.com/decred/dcrd/wire"
 )
@@ -70,11 +71,13 @@ var wsHandlersBeforeInit = map[string]wsCommandHandler{
 	"notifynewtickets":            handleNewTickets,
 	"notifystakedifficulty":       handleStakeDifficulty,
 	"notifynewtransactions":       handleNotifyNewTransactions,
+	"notifymempoolevents":         handleNotifyMempoolEvents,
 	"session":                     handleSession,
 	"help":                        handleWebsocketHelp,
 	"rescan":                      handleRescan,
 	"stopnotifyblocks":            handleStopNotifyBlocks,
 	"stopnotifynewtransactions":   handleStopNotifyNewTransactions,
+	"stopnotifymempoolevents":     handleStopNotifyMempoolEvents,
 }
 
 // WebsocketHandler handles a new websocket client by creating a new wsClient,
@@ -313,6 +316,20 @@ func (m *wsNotificationManager) NotifyMempoolTx(tx *dcrutil.Tx, isNew bool) {
 	}
 }
 
+// NotifyMempoolEvent passes an event emitted by the mempool when a transaction
+// is added to or removed from it to the notification manager for mempool event
+// notification processing.
+func (m *wsNotificationManager) NotifyMempoolEvent(event *mempool.Event) {
+	// As NotifyMempoolEvent will be called by mempool and the RPC server
+	// may no longer be running, use a select statement to unblock
+	// enqueuing the notification once the RPC server has begun
+	// shutting down.
+	select {
+	case m.queueNotification <- (*notificationMempoolEvent)(event):
+	case <-m.quit:
+	}
+}
+
 // WinningTicketsNtfnData is the data that is used to generate
 // winning ticket notifications (which indicate a block and
 // the tickets eligible to vote on it).
@@ -462,6 +479,8 @@ type notificationTxAcceptedByMempool struct {
 	tx    *dcrutil.Tx
 }
 
+type notificationMempoolEvent mempool.Event
+
 // Notification control requests
 type notificationRegisterClient wsClient
 type notificationUnregisterClient wsClient
@@ -477,6 +496,8 @@ type notificationRegisterStakeDifficulty wsClient
 type notificationUnregisterStakeDifficulty wsClient
 type notificationRegisterNewMempoolTxs wsClient
 type notificationUnregisterNewMempoolTxs wsClient
+type notificationRegisterMempoolEvents wsClient
+type notificationUnregisterMempoolEvents wsClient
 
 // notificationHandler reads notifications and control messages from the queue
 // handler and processes one at a time.
@@ -497,6 +518,7 @@ func (m *wsNotificationManager) notificationHandler() {
 	ticketNewNotifications := make(map[chan struct{}]*wsClient)
 	stakeDifficultyNotifications := make(map[chan struct{}]*wsClient)
 	txNotifications := make(map[chan struct{}]*wsClient)
+	mempoolEventNotifications := make(map[chan struct{}]*wsClient)
 
 out:
 	for {
@@ -548,6 +570,12 @@ out:
 				}
 				m.notifyRelevantTxAccepted(n.tx, clients)
 
+			case *notificationMempoolEvent:
+				if len(mempoolEventNotifications) != 0 {
+					m.notifyMempoolEvent(mempoolEventNotifications,
+						(*mempool.Event)(n))
+				}
+
 			case *notificationRegisterBlocks:
 				wsc := (*wsClient)(n)
 				blockNotifications[wsc.quit] = wsc
@@ -598,6 +626,7 @@ out:
 				// the client itself.
 				delete(blockNotifications, wsc.quit)
 				delete(txNotifications, wsc.quit)
+				delete(mempoolEventNotifications, wsc.quit)
 				delete(clients, wsc.quit)
 
 			case *notificationRegisterNewMempoolTxs:
@@ -608,6 +637,14 @@ out:
 				wsc := (*wsClient)(n)
 				delete(txNotifications, wsc.quit)
 
+			case *notificationRegisterMempoolEvents:
+				wsc := (*wsClient)(n)
+				mempoolEventNotifications[wsc.quit] = wsc
+
+			case *notificationUnregisterMempoolEvents:
+				wsc := (*wsClient)(n)
+				delete(mempoolEventNotifications, wsc.quit)
+
 			default:
 				rpcsLog.Warn("Unhandled notification type")
 			}
@@ -972,6 +1009,60 @@ func (m *wsNotificationManager) UnregisterNewMempoolTxsUpdates(wsc *wsClient) {
 	m.queueNotification <- (*notificationUnregisterNewMempoolTxs)(wsc)
 }
 
+// RegisterMempoolEvents requests notifications to the passed websocket client
+// when transactions are added to or removed from the memory pool.
+func (m *wsNotificationManager) RegisterMempoolEvents(wsc *wsClient) {
+	m.queueNotification <- (*notificationRegisterMempoolEvents)(wsc)
+}
+
+// UnregisterMempoolEvents removes notifications to the passed websocket client
+// when transactions are added to or removed from the memory pool.
+func (m *wsNotificationManager) UnregisterMempoolEvents(wsc *wsClient) {
+	m.queueNotification <- (*notificationUnregisterMempoolEvents)(wsc)
+}
+
+// notifyMempoolEvent notifies websocket clients that have registered for
+// mempool events that a transaction was added to or removed from the memory
+// pool.  Removal notifications include the reason the transaction was removed.
+func (m *wsNotificationManager) notifyMempoolEvent(clients map[chan struct{}]*wsClient, event *mempool.Event) {
+	var ntfn interface{}
+	txHashStr := event.Tx.Hash().String()
+	switch event.Type {
+	case mempool.EventTxAdded:
+		var txTypeStr string
+		switch event.TxType {
+		case stake.TxTypeRegular:
+			txTypeStr = "regular"
+		case stake.TxTypeSStx:
+			txTypeStr = "ticket"
+		case stake.TxTypeSSGen:
+			txTypeStr = "vote"
+		case stake.TxTypeSSRtx:
+			txTypeStr = "revocation"
+		}
+		ntfn = dcrjson.NewMempoolTxAddedNtfn(txHashStr, txTypeStr,
+			dcrutil.Amount(event.Fee).ToCoin())
+
+	case mempool.EventTxRemoved:
+		ntfn = dcrjson.NewMempoolTxRemovedNtfn(txHashStr,
+			event.Reason.String())
+
+	default:
+		rpcsLog.Warnf("Unhandled mempool event type %v", event.Type)
+		return
+	}
+
+	marshalledJSON, err := dcrjson.MarshalCmd("1.0", nil, ntfn)
+	if err != nil {
+		rpcsLog.Errorf("Failed to marshal mempool event notification: %v",
+			err)
+		return
+	}
+	for _, wsc := range clients {
+		wsc.QueueNotification(marshalledJSON)
+	}
+}
+
 // notifyForNewTx notifies websocket clients that have registered for updates
 // when a new transaction is added to the memory pool.
 func (m *wsNotificationManager) notifyForNewTx(clients map[chan struct{}]*wsClient, tx *dcrutil.Tx) {
@@ -2080,6 +2171,20 @@ func handleStopNotifyNewTransactions(wsc *wsClient, icmd interface{}) (interface
 	return nil, nil
 }
 
+// handleNotifyMempoolEvents implements the notifymempoolevents command
+// extension for websocket connections.
+func handleNotifyMempoolEvents(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.RegisterMempoolEvents(wsc)
+	return nil, nil
+}
+
+// handleStopNotifyMempoolEvents implements the stopnotifymempoolevents command
+// extension for websocket connections.
+func handleStopNotifyMempoolEvents(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.UnregisterMempoolEvents(wsc)
+	return nil, nil
+}
+
 // rescanBlock rescans a block for any relevant transactions for the passed
 // lookup keys.  Any discovered transactions are returned hex encoded as a
 // string slice.
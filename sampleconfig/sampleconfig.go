@@ -58,6 +58,12 @@ const FileContents = `[Application Options]
 ; to correlate connections.
 ; torisolation=1
 
+; Disable the encrypted v2 peer-to-peer transport.  Peers that support it are
+; otherwise connected to with all messages encrypted and authenticated, while
+; inbound peers that do not support it always fall back to the plaintext v1
+; transport.
+; nov2transport=1
+
 ; Use Universal Plug and Play (UPnP) to automatically open the listen port
 ; and obtain the external IP address from supported devices.  NOTE: This option
 ; will have no effect if exernal IP addresses are specified.
@@ -115,6 +121,23 @@ const FileContents = `[Application Options]
 ; Maximum number of inbound and outbound peers.
 ; maxpeers=8
 
+; Number of additional outbound peers that are only used to relay blocks as
+; opposed to also relaying transactions and addresses.  These connections make
+; it harder for attackers to infer the network topology.
+; blockrelayonlypeers=2
+
+; Maximum number of block relay only peers to save on shutdown and reconnect to
+; first on startup.
+; maxanchors=2
+
+; Limit the data uploaded to peers to 5000 MiB per 24 hours.  Blocks older than
+; a week are no longer served to non-whitelisted peers once the limit is close
+; to being reached while new blocks continue to be relayed.  Since room is
+; reserved for relaying a maximum size block for every block expected per 24
+; hours, the limit must be at least 108 MiB on mainnet.  A value of 0 disables
+; the limit.
+; maxuploadtarget=5000
+
 ; Disable banning of misbehaving peers.
 ; nobanning=1
 
@@ -243,6 +266,11 @@ const FileContents = `[Application Options]
 ; Limit orphan transaction pool to 1000 transactions.
 ; maxorphantx=1000
 
+; Limit the transaction memory pool to 300 MiB.  The transactions with the
+; lowest fee rates are evicted when the limit is exceeded.  A value of 0
+; disables the limit.
+; maxmempool=300
+
 ; Do not accept transactions from remote peers.
 ; blocksonly=1
 
@@ -253,6 +281,21 @@ const FileContents = `[Application Options]
 ; Reject non-standard transactions regardless of default network settings.
 ; rejectnonstd=1
 
+; Only download and validate block headers without maintaining the UTXO set.
+; Committed filters are fetched from peers on demand and transactions are not
+; accepted.  The header-based RPCs such as getblockheader, getbestblock, and
+; getchaintips remain available.
+; headersonly=1
+
+; Load the chain state from a snapshot file created with the dumputxoset RPC
+; when the database does not contain a chain yet and sync from its height while
+; the history leading up to it is validated in the background.  Only snapshots
+; listed in the network parameters are accepted.  Like pruned nodes, nodes
+; loaded from a snapshot only serve recent blocks.  This option may not be
+; combined with txindex, addrindex, or headersonly, and it disables the exists
+; address and committed filter indexes.
+; loadsnapshot=~/utxoset.dat
+
 
 ; ------------------------------------------------------------------------------
 ; Optional Transaction Indexes
@@ -275,6 +318,18 @@ const FileContents = `[Application Options]
 ; addrindex=1
 
 
+; ------------------------------------------------------------------------------
+; Block Data Pruning
+; ------------------------------------------------------------------------------
+
+; Delete the oldest block data to keep the stored block data near 2048 MiB.  The
+; block headers, the UTXO set, the stake ticket database, and the most recent
+; blocks are always retained.  Pruned nodes no longer serve historical blocks to
+; peers and pruning may not be enabled along with the txindex or addrindex
+; options.  The minimum is 1024 MiB and a value of 0 disables pruning.
+; prune=2048
+
+
 ; ------------------------------------------------------------------------------
 ; Signature Verification Cache
 ; ------------------------------------------------------------------------------
@@ -283,6 +338,33 @@ const FileContents = `[Application Options]
 ; sigcachemaxsize=50000
 
 
+; ------------------------------------------------------------------------------
+; Unspent Transaction Output Cache
+; ------------------------------------------------------------------------------
+
+; Limit the in-memory cache of unspent transaction outputs to a max of 500 MiB.
+; Modified outputs are held in the cache and written to the database in batches
+; periodically, when the cache is full, and on shutdown, which greatly speeds up
+; the initial chain sync.  A value of 0 writes them to the database as each block
+; is processed.  The default is 150 MiB.
+; utxocachemaxsize=500
+
+
+; ------------------------------------------------------------------------------
+; Assumed Valid Block
+; ------------------------------------------------------------------------------
+
+; Skip script validation for the specified block and all of its ancestors when
+; it is part of the best known header chain.  All other consensus rules,
+; including those for stake and votes, are still enforced and blocks are fully
+; validated when the block is not part of the best header chain.  Each network
+; has a default block that is updated with each release and a value of 0
+; validates the scripts of all blocks.  Since scripts are never validated for
+; blocks at or before the latest checkpoint, the block only speeds up the sync
+; when it is after the latest checkpoint.
+; assumevalid=0
+
+
 ; ------------------------------------------------------------------------------
 ; Coin Generation (Mining) Settings - The following options control the
 ; generation of block templates used by external mining applications through RPC
@@ -321,6 +403,26 @@ const FileContents = `[Application Options]
 ; by the blockmaxsize option and will be limited as needed.
 ; blockprioritysize=20000
 
+; Specify the strategy used to select the transactions to include when creating
+; a block.  Votes are always selected first, followed by tickets.  The available
+; strategies are:
+;   standard         Fill the high-priority area (blockprioritysize) by priority
+;                    and the remainder of the block by the fee rate of the
+;                    highest paying package each transaction is part of
+;   feerate          Select by the fee rate of each transaction on its own
+;   ancestorfeerate  Select by the fee rate of the highest paying package each
+;                    transaction is part of without a high-priority area
+;   priority         Select by priority for the entire block
+; miningselector=standard
+
+; Only include regular transactions which pay to at least one of the specified
+; addresses when creating a block.  One address per line.
+; miningallowaddr=youraddress
+
+; Do not include regular transactions which pay to any of the specified
+; addresses when creating a block.  One address per line.
+; miningdenyaddr=youraddress
+
 
 ; ------------------------------------------------------------------------------
 ; Debug
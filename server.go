@@ -6,14 +6,17 @@
 package main
 
 import (
+	"bufio"
 	"crypto/rand"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"math"
 	"net"
+	"os"
 	"path"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -43,7 +46,7 @@ import (
 const (
 	// defaultServices describes the default services that are supported by
 	// the server.
-	defaultServices = wire.SFNodeNetwork | wire.SFNodeCF
+	defaultServices = wire.SFNodeNetwork | wire.SFNodeCF | wire.SFNodeP2PV2
 
 	// defaultRequiredServices describes the default services that are
 	// required to be supported by outbound peers.
@@ -59,7 +62,27 @@ const (
 	connectionRetryInterval = time.Second * 5
 
 	// maxProtocolVersion is the max protocol version the server supports.
-	maxProtocolVersion = wire.NodeCFVersion
+	maxProtocolVersion = wire.AddrV2Version
+
+	// mempoolFileName is the name of the file in the data directory the
+	// transactions in the memory pool are saved to on shutdown and loaded
+	// from on startup.
+	mempoolFileName = "mempool.dat"
+
+	// banListFilename is the name of the file in the data directory that
+	// houses the banned IP addresses and subnets.
+	banListFilename = "banlist.json"
+
+	// v1TransportFallbackTime is the amount of time connections to an
+	// outbound peer that failed the v2 transport handshake fall back to the
+	// v1 transport before the v2 transport is attempted again.
+	v1TransportFallbackTime = 24 * time.Hour
+
+	// maxV1TransportAddrs is the maximum number of addresses of outbound
+	// peers that failed the v2 transport handshake to remember.  The
+	// address that failed the longest ago is forgotten to make room for a
+	// new one.
+	maxV1TransportAddrs = 1000
 )
 
 var (
@@ -115,12 +138,11 @@ type updatePeerHeightsMsg struct {
 }
 
 // peerState maintains state of inbound, persistent, outbound peers as well
-// as banned peers and outbound groups.
+// as outbound groups.
 type peerState struct {
 	inboundPeers    map[int32]*serverPeer
 	outboundPeers   map[int32]*serverPeer
 	persistentPeers map[int32]*serverPeer
-	banned          map[string]time.Time
 	outboundGroups  map[string]int
 }
 
@@ -182,14 +204,25 @@ type server struct {
 	shutdown      int32
 	shutdownSched int32
 
+	// cmpctHighBandwidthPeers is the number of connected peers that were
+	// asked to announce new blocks via compact blocks.  It must only be
+	// used atomically.
+	cmpctHighBandwidthPeers int32
+
+	// mempoolLoaded is set once the transactions saved to the mempool file
+	// have finished loading.  It must only be used atomically.
+	mempoolLoaded int32
+
 	chainParams          *chaincfg.Params
 	addrManager          *addrmgr.AddrManager
 	connManager          *connmgr.ConnManager
+	banList              *connmgr.BanList
 	sigCache             *txscript.SigCache
 	rpcServer            *rpcServer
 	blockManager         *blockManager
 	txMemPool            *mempool.TxPool
 	feeEstimator         *fees.Estimator
+	mempoolFile          string
 	cpuMiner             *CPUMiner
 	modifyRebroadcastInv chan interface{}
 	newPeers             chan *serverPeer
@@ -206,6 +239,26 @@ type server struct {
 	timeSource           blockchain.MedianTimeSource
 	services             wire.ServiceFlag
 
+	// v1TransportAddrs houses the addresses of outbound peers that failed
+	// the v2 transport handshake along with the time they failed so that
+	// future connections to them fall back to the v1 transport for a while.
+	v1TransportMtx   sync.Mutex
+	v1TransportAddrs map[string]time.Time
+
+	// uploadTarget limits the data uploaded to peers per cycle by no
+	// longer serving historical blocks to non-whitelisted peers once it is
+	// reached.
+	uploadTarget *uploadTarget
+
+	// historyPruned indicates that the block data before the most recent
+	// blocks may not be available because it was pruned or the chain state
+	// was loaded from a snapshot.
+	historyPruned bool
+
+	// cfFetcher requests committed filters from peers on demand in
+	// headers-only mode.
+	cfFetcher *cfFetcher
+
 	// The following fields are used for optional indexes.  They will be nil
 	// if the associated index is not enabled.  These fields are set during
 	// initial creation of the server and never changed afterwards, so they
@@ -219,6 +272,11 @@ type server struct {
 // serverPeer extends the peer to maintain state shared by the server and
 // the blockmanager.
 type serverPeer struct {
+	// The following variables must only be used atomically.
+	// Putting the int64s first makes them 64-bit aligned for 32-bit systems.
+	lastBlockTime int64 // Unix nanos of the last novel block from the peer.
+	lastTxTime    int64 // Unix nanos of the last novel tx from the peer.
+
 	*peer.Peer
 
 	connReq         *connmgr.ConnReq
@@ -239,6 +297,47 @@ type serverPeer struct {
 	// request.  It is used to prevent more than one response per connection.
 	addrsSent bool
 
+	// cmpctHighBandwidth tracks whether or not the peer was asked to
+	// announce new blocks by directly sending compact blocks.  It is set
+	// during version negotiation and never modified afterwards.
+	cmpctHighBandwidth bool
+
+	// cmpctBlock is the block that is being reconstructed from a compact
+	// block sent by the peer while waiting on its missing transactions.  It
+	// must only be accessed from the block manager goroutine.
+	cmpctBlock *cmpctBlockState
+
+	// lastBlockStall is the last time the peer failed to deliver a block
+	// requested in headers-first mode in a timely fashion.  Peers that
+	// stalled recently are only assigned new block requests when no other
+	// peers are available.  It must only be accessed from the block manager
+	// goroutine.
+	lastBlockStall time.Time
+
+	// blockDownloadRate tracks the rate at which the peer delivers the
+	// blocks requested from it in headers-first mode.  syncPeerReplaced
+	// tracks whether the peer was replaced as the sync peer while it still
+	// had headers to provide.  They must only be accessed from the block
+	// manager goroutine.
+	blockDownloadRate blockDownloadRate
+	syncPeerReplaced  bool
+
+	// headersRequested tracks whether or not headers were requested from
+	// the peer in headers-only mode and it has not responded yet.  It must
+	// only be accessed from the block manager goroutine.
+	headersRequested bool
+
+	// v2TransportAttempted tracks whether or not the v2 transport handshake
+	// was initiated with the outbound peer.  It is set before the
+	// connection is associated with the peer and never modified afterwards.
+	v2TransportAttempted bool
+
+	// bytesSentPerMsg and bytesRecvPerMsg track the total number of bytes
+	// sent to and received from the peer per message type.
+	bytesPerMsgMtx  sync.Mutex
+	bytesSentPerMsg map[string]uint64
+	bytesRecvPerMsg map[string]uint64
+
 	// The following chans are used to sync blockmanager and server.
 	txProcessed    chan struct{}
 	blockProcessed chan struct{}
@@ -253,6 +352,8 @@ func newServerPeer(s *server, isPersistent bool) *serverPeer {
 		requestedTxns:   make(map[chainhash.Hash]struct{}),
 		requestedBlocks: make(map[chainhash.Hash]struct{}),
 		knownAddresses:  make(map[string]struct{}),
+		bytesSentPerMsg: make(map[string]uint64),
+		bytesRecvPerMsg: make(map[string]uint64),
 		quit:            make(chan struct{}),
 		txProcessed:     make(chan struct{}, 1),
 		blockProcessed:  make(chan struct{}, 1),
@@ -299,6 +400,27 @@ func (sp *serverPeer) relayTxDisabled() bool {
 	return isDisabled
 }
 
+// isBlockRelayOnly returns whether or not the peer is an outbound peer that is
+// only used to relay blocks as opposed to also relaying transactions and
+// addresses.
+func (sp *serverPeer) isBlockRelayOnly() bool {
+	return sp.connReq != nil && sp.connReq.BlockRelayOnly
+}
+
+// connType returns a human-readable string for the type of the connection to
+// the peer.
+func (sp *serverPeer) connType() string {
+	switch {
+	case sp.Inbound():
+		return "inbound"
+	case sp.persistent:
+		return "manual"
+	case sp.isBlockRelayOnly():
+		return "block-relay-only"
+	}
+	return "outbound-full-relay"
+}
+
 // pushAddrMsg sends an addr message to the connected peer using the provided
 // addresses.
 func (sp *serverPeer) pushAddrMsg(addresses []*wire.NetAddress) {
@@ -406,12 +528,16 @@ func (sp *serverPeer) OnVersion(p *peer.Peer, msg *wire.MsgVersion) *wire.MsgRej
 	// remote peer for outbound connections.  This is skipped when running
 	// on the simulation test network since it is only intended to connect
 	// to specified peers and actively avoids advertising and connecting to
-	// discovered peers.
+	// discovered peers.  Addresses are also never exchanged with block relay
+	// only peers to make it harder to infer the network topology.
+	isBlockRelayOnly := sp.isBlockRelayOnly()
 	if !cfg.SimNet && !isInbound {
 		// Advertise the local address when the server accepts incoming
 		// connections and it believes itself to be close to the best
 		// known tip.
-		if !cfg.DisableListen && sp.server.blockManager.IsCurrent() {
+		if !cfg.DisableListen && !isBlockRelayOnly &&
+			sp.server.blockManager.IsCurrent() {
+
 			// Get address that best matches.
 			lna := addrManager.GetBestLocalAddress(remoteAddr)
 			if addrmgr.IsRoutable(lna) {
@@ -423,7 +549,7 @@ func (sp *serverPeer) OnVersion(p *peer.Peer, msg *wire.MsgVersion) *wire.MsgRej
 
 		// Request known addresses if the server address manager needs
 		// more.
-		if addrManager.NeedMoreAddresses() {
+		if !isBlockRelayOnly && addrManager.NeedMoreAddresses() {
 			p.QueueMessage(wire.NewMsgGetAddr(), nil)
 		}
 
@@ -431,13 +557,41 @@ func (sp *serverPeer) OnVersion(p *peer.Peer, msg *wire.MsgVersion) *wire.MsgRej
 		addrManager.Good(remoteAddr)
 	}
 
-	// Choose whether or not to relay transactions.
-	sp.setDisableRelayTx(msg.DisableRelayTx)
+	// Choose whether or not to relay transactions.  Transactions are never
+	// relayed to block relay only peers.
+	sp.setDisableRelayTx(msg.DisableRelayTx || isBlockRelayOnly)
 
 	// Add the remote peer time as a sample for creating an offset against
 	// the local clock to keep the network time in sync.
 	sp.server.timeSource.AddTimeSample(p.Addr(), msg.Timestamp)
 
+	// Signal support for compact block relay to peers that understand it
+	// unless transactions are not being relayed since the memory pool is
+	// needed to reconstruct blocks.  A limited number of outbound peers are
+	// asked to announce new blocks by directly sending compact blocks to
+	// minimize block propagation latency.
+	if sp.ProtocolVersion() >= wire.CompactBlocksVersion && !cfg.BlocksOnly {
+		var highBandwidth bool
+		if !isInbound {
+			numPeers := atomic.AddInt32(
+				&sp.server.cmpctHighBandwidthPeers, 1)
+			highBandwidth = numPeers <= maxHighBandwidthCmpctPeers
+			if !highBandwidth {
+				atomic.AddInt32(&sp.server.cmpctHighBandwidthPeers,
+					-1)
+			}
+		}
+		sp.cmpctHighBandwidth = highBandwidth
+		p.QueueMessage(wire.NewMsgSendCmpct(highBandwidth,
+			wire.CmpctBlockVersion1), nil)
+	}
+
+	// Signal support for addrv2 messages to peers that understand them so
+	// they relay addresses that can not be represented by addr messages.
+	if sp.ProtocolVersion() >= wire.AddrV2Version && !isBlockRelayOnly {
+		p.QueueMessage(wire.NewMsgSendAddrV2(), nil)
+	}
+
 	// Signal the block manager this peer is a new sync candidate.
 	sp.server.blockManager.NewPeer(sp)
 
@@ -450,6 +604,13 @@ func (sp *serverPeer) OnVersion(p *peer.Peer, msg *wire.MsgVersion) *wire.MsgRej
 // and sends an inventory message with the contents of the memory pool up to the
 // maximum inventory allowed per message.
 func (sp *serverPeer) OnMemPool(p *peer.Peer, msg *wire.MsgMemPool) {
+	// Transactions are never relayed to block relay only peers.
+	if sp.isBlockRelayOnly() {
+		peerLog.Tracef("Ignoring mempool from %v - block relay only "+
+			"peer", p)
+		return
+	}
+
 	// A decaying ban score increase is applied to prevent flooding.
 	// The ban score accumulates and passes the ban threshold if a burst of
 	// mempool messages comes from a peer. The score decays each minute to
@@ -597,6 +758,11 @@ func (sp *serverPeer) OnTx(p *peer.Peer, msg *wire.MsgTx) {
 			msg.TxHash(), p)
 		return
 	}
+	if sp.isBlockRelayOnly() {
+		peerLog.Tracef("Ignoring tx %v from %v - block relay only peer",
+			msg.TxHash(), p)
+		return
+	}
 
 	// Add the transaction to the known inventory for the peer.
 	// Convert the raw MsgTx to a dcrutil.Tx which provides some convenience
@@ -638,12 +804,66 @@ func (sp *serverPeer) OnBlock(p *peer.Peer, msg *wire.MsgBlock, buf []byte) {
 	<-sp.blockProcessed
 }
 
+// OnCmpctBlock is invoked when a peer receives a cmpctblock wire message.  It
+// blocks until the block described by the compact block has either been fully
+// processed or its missing transactions have been requested.
+func (sp *serverPeer) OnCmpctBlock(p *peer.Peer, msg *wire.MsgCmpctBlock) {
+	// Add the block to the known inventory for the peer.
+	blockHash := msg.Header.BlockHash()
+	iv := wire.NewInvVect(wire.InvTypeBlock, &blockHash)
+	p.AddKnownInventory(iv)
+
+	// Queue the compact block up to be handled by the block manager and
+	// intentionally block further receives until it is processed for the
+	// same reasons as full blocks.
+	sp.server.blockManager.QueueCmpctBlock(msg, sp)
+	<-sp.blockProcessed
+}
+
+// OnBlockTxn is invoked when a peer receives a blocktxn wire message.  It
+// blocks until the block being reconstructed with the transactions has been
+// fully processed.
+func (sp *serverPeer) OnBlockTxn(p *peer.Peer, msg *wire.MsgBlockTxn) {
+	sp.server.blockManager.QueueBlockTxn(msg, sp)
+	<-sp.blockProcessed
+}
+
+// OnGetBlockTxn is invoked when a peer receives a getblocktxn wire message.  It
+// responds with the requested transactions of a recent block or with the full
+// block when the block is not recent.
+func (sp *serverPeer) OnGetBlockTxn(p *peer.Peer, msg *wire.MsgGetBlockTxn) {
+	chain := sp.server.blockManager.chain
+	block, err := chain.BlockByHash(&msg.BlockHash)
+	if err != nil {
+		peerLog.Debugf("Unable to fetch block %v requested via getblocktxn "+
+			"by %s: %v", msg.BlockHash, sp, err)
+		return
+	}
+
+	// Serve the full block instead of individual transactions when the
+	// block is not recent to limit the amount of data that is kept
+	// readily available for reconstruction.
+	best := chain.BestSnapshot()
+	if best.Height-block.Height() >= maxBlockTxnDepth {
+		sp.QueueMessage(block.MsgBlock(), nil)
+		return
+	}
+
+	blockTxn, err := newBlockTxnMsg(block, msg)
+	if err != nil {
+		sp.addBanScore(100, 0, fmt.Sprintf("invalid getblocktxn: %v",
+			err))
+		return
+	}
+	sp.QueueMessage(blockTxn, nil)
+}
+
 // OnInv is invoked when a peer receives an inv wire message and is used to
 // examine the inventory being advertised by the remote peer and react
 // accordingly.  We pass the message down to blockmanager which will call
 // QueueMessage with any appropriate responses.
 func (sp *serverPeer) OnInv(p *peer.Peer, msg *wire.MsgInv) {
-	if !cfg.BlocksOnly {
+	if !cfg.BlocksOnly && !sp.isBlockRelayOnly() {
 		if len(msg.InvList) > 0 {
 			sp.server.blockManager.QueueInv(msg, sp)
 		}
@@ -697,6 +917,44 @@ func (sp *serverPeer) OnGetData(p *peer.Peer, msg *wire.MsgGetData) {
 	// This incremental score decays each minute to half of its value.
 	sp.addBanScore(0, uint32(length)*99/wire.MaxInvPerMsg, "getdata")
 
+	// Disconnect peers requesting historical blocks once the upload target
+	// is reached.  New blocks are still served so the tip continues to be
+	// relayed.
+	if sp.historicalBlocksLimited() {
+		for _, iv := range msg.InvList {
+			if iv.Type != wire.InvTypeBlock &&
+				iv.Type != wire.InvTypeCmpctBlock {
+
+				continue
+			}
+			if sp.server.isHistoricalBlock(&iv.Hash) {
+				peerLog.Infof("Historical block %v requested by %s "+
+					"after upload target reached -- disconnecting",
+					iv.Hash, sp)
+				sp.Disconnect()
+				return
+			}
+		}
+	}
+
+	// Refuse to serve blocks whose data was pruned by reporting them as not
+	// found without attempting to load them.
+	invList := msg.InvList
+	if sp.server.historyPruned {
+		invList = make([]*wire.InvVect, 0, len(msg.InvList))
+		for _, iv := range msg.InvList {
+			isBlock := iv.Type == wire.InvTypeBlock ||
+				iv.Type == wire.InvTypeCmpctBlock
+			if isBlock && sp.server.isPrunedBlock(&iv.Hash) {
+				peerLog.Debugf("Pruned block %v requested by %s",
+					iv.Hash, sp)
+				notFound.AddInvVect(iv)
+				continue
+			}
+			invList = append(invList, iv)
+		}
+	}
+
 	// We wait on this wait channel periodically to prevent queuing
 	// far more data than we can send in a reasonable time, wasting memory.
 	// The waiting occurs after the database fetch for the next one to
@@ -704,10 +962,10 @@ func (sp *serverPeer) OnGetData(p *peer.Peer, msg *wire.MsgGetData) {
 	var waitChan chan struct{}
 	doneChan := make(chan struct{}, 1)
 
-	for i, iv := range msg.InvList {
+	for i, iv := range invList {
 		var c chan struct{}
 		// If this will be the last message we send.
-		if i == length-1 && len(notFound.InvList) == 0 {
+		if i == len(invList)-1 && len(notFound.InvList) == 0 {
 			c = doneChan
 		} else if (i+1)%3 == 0 {
 			// Buffered so as to not make the send goroutine block.
@@ -719,6 +977,8 @@ func (sp *serverPeer) OnGetData(p *peer.Peer, msg *wire.MsgGetData) {
 			err = sp.server.pushTxMsg(sp, &iv.Hash, c, waitChan)
 		case wire.InvTypeBlock:
 			err = sp.server.pushBlockMsg(sp, &iv.Hash, c, waitChan)
+		case wire.InvTypeCmpctBlock:
+			err = sp.server.pushCmpctBlockMsg(sp, &iv.Hash, c, waitChan)
 		default:
 			peerLog.Warnf("Unknown type in inventory request %d",
 				iv.Type)
@@ -732,7 +992,7 @@ func (sp *serverPeer) OnGetData(p *peer.Peer, msg *wire.MsgGetData) {
 			// being no outstanding not found inventory, consume
 			// it here because there is now not found inventory
 			// that will use the channel momentarily.
-			if i == len(msg.InvList)-1 && c != nil {
+			if i == len(invList)-1 && c != nil {
 				<-c
 			}
 		}
@@ -767,6 +1027,16 @@ func (sp *serverPeer) OnGetBlocks(p *peer.Peer, msg *wire.MsgGetBlocks) {
 	hashList := chain.LocateBlocks(msg.BlockLocatorHashes, &msg.HashStop,
 		wire.MaxBlocksPerMsg)
 
+	// Ignore requests for historical blocks once the upload target is
+	// reached.
+	if len(hashList) > 0 && sp.historicalBlocksLimited() &&
+		sp.server.isHistoricalBlock(&hashList[0]) {
+
+		peerLog.Debugf("Ignoring getblocks for historical block %v from "+
+			"%s - upload target reached", hashList[0], sp)
+		return
+	}
+
 	// Generate inventory message.
 	invMsg := wire.NewMsgInv()
 	for i := range hashList {
@@ -982,6 +1252,25 @@ func (sp *serverPeer) OnGetCFHeaders(p *peer.Peer, msg *wire.MsgGetCFHeaders) {
 	sp.QueueMessage(headersMsg, nil)
 }
 
+// OnCFilter is invoked when a peer receives a cfilter wire message.  It is
+// used to deliver committed filters requested on demand in headers-only mode.
+func (sp *serverPeer) OnCFilter(p *peer.Peer, msg *wire.MsgCFilter) {
+	if !sp.server.cfFetcher.DeliverFilter(sp, msg) {
+		peerLog.Debugf("Ignoring unrequested %v filter for block %v "+
+			"from %v", msg.FilterType, &msg.BlockHash, sp)
+	}
+}
+
+// OnCFHeaders is invoked when a peer receives a cfheaders wire message.  It is
+// used to deliver committed filter headers requested on demand in headers-only
+// mode.
+func (sp *serverPeer) OnCFHeaders(p *peer.Peer, msg *wire.MsgCFHeaders) {
+	if !sp.server.cfFetcher.DeliverFilterHeaders(sp, msg) {
+		peerLog.Debugf("Ignoring unrequested %v filter headers up to "+
+			"block %v from %v", msg.FilterType, &msg.StopHash, sp)
+	}
+}
+
 // OnGetCFTypes is invoked when a peer receives a getcftypes wire message.
 func (sp *serverPeer) OnGetCFTypes(p *peer.Peer, msg *wire.MsgGetCFTypes) {
 	// Disconnect and/or ban depending on the node cf services flag and
@@ -1035,6 +1324,19 @@ func (sp *serverPeer) OnGetAddr(p *peer.Peer, msg *wire.MsgGetAddr) {
 // OnAddr is invoked when a peer receives an addr wire message and is used to
 // notify the server about advertised addresses.
 func (sp *serverPeer) OnAddr(p *peer.Peer, msg *wire.MsgAddr) {
+	sp.handleAddrList(p, msg.Command(), msg.AddrList)
+}
+
+// OnAddrV2 is invoked when a peer receives an addrv2 wire message and is used
+// to notify the server about advertised addresses, including those on networks
+// that can not be represented by addr messages.
+func (sp *serverPeer) OnAddrV2(p *peer.Peer, msg *wire.MsgAddrV2) {
+	sp.handleAddrList(p, msg.Command(), msg.AddrList)
+}
+
+// handleAddrList adds the addresses advertised by a peer via an addr or addrv2
+// message to the known addresses of the peer and the address manager.
+func (sp *serverPeer) handleAddrList(p *peer.Peer, command string, addrList []*wire.NetAddress) {
 	// Ignore addresses when running on the simulation test network.  This
 	// helps prevent the network from becoming another public test network
 	// since it will not be able to learn about other peers that have not
@@ -1043,16 +1345,24 @@ func (sp *serverPeer) OnAddr(p *peer.Peer, msg *wire.MsgAddr) {
 		return
 	}
 
+	// Ignore addresses from block relay only peers since addresses are
+	// never exchanged with them.
+	if sp.isBlockRelayOnly() {
+		peerLog.Tracef("Ignoring %s from %v - block relay only peer",
+			command, p)
+		return
+	}
+
 	// A message that has no addresses is invalid.
-	if len(msg.AddrList) == 0 {
+	if len(addrList) == 0 {
 		peerLog.Errorf("Command [%s] from %s does not contain any addresses",
-			msg.Command(), p)
+			command, p)
 		p.Disconnect()
 		return
 	}
 
 	now := time.Now()
-	for _, na := range msg.AddrList {
+	for _, na := range addrList {
 		// Don't add more address if we're disconnecting.
 		if !p.Connected() {
 			return
@@ -1074,19 +1384,72 @@ func (sp *serverPeer) OnAddr(p *peer.Peer, msg *wire.MsgAddr) {
 	// addresses, and last seen updates.
 	// XXX bitcoind gives a 2 hour time penalty here, do we want to do the
 	// same?
-	sp.server.addrManager.AddAddresses(msg.AddrList, p.NA())
+	sp.server.addrManager.AddAddresses(addrList, p.NA())
 }
 
 // OnRead is invoked when a peer receives a message and it is used to update
-// the bytes received by the server.
+// the bytes received by the server and peer.
 func (sp *serverPeer) OnRead(p *peer.Peer, bytesRead int, msg wire.Message, err error) {
 	sp.server.AddBytesReceived(uint64(bytesRead))
+
+	sp.bytesPerMsgMtx.Lock()
+	sp.bytesRecvPerMsg[msgCommand(msg)] += uint64(bytesRead)
+	sp.bytesPerMsgMtx.Unlock()
 }
 
 // OnWrite is invoked when a peer sends a message and it is used to update
-// the bytes sent by the server.
+// the bytes sent by the server and peer.
 func (sp *serverPeer) OnWrite(p *peer.Peer, bytesWritten int, msg wire.Message, err error) {
 	sp.server.AddBytesSent(uint64(bytesWritten))
+
+	sp.bytesPerMsgMtx.Lock()
+	sp.bytesSentPerMsg[msgCommand(msg)] += uint64(bytesWritten)
+	sp.bytesPerMsgMtx.Unlock()
+}
+
+// msgCommand returns the command of the passed message for the purposes of
+// per message type byte accounting.  Messages that failed to decode are
+// accounted as other.
+func msgCommand(msg wire.Message) string {
+	if msg == nil {
+		return "*other*"
+	}
+	return msg.Command()
+}
+
+// BytesPerMsg returns copies of the total number of bytes sent to and received
+// from the peer per message type.  It is safe for concurrent access.
+func (sp *serverPeer) BytesPerMsg() (map[string]uint64, map[string]uint64) {
+	sp.bytesPerMsgMtx.Lock()
+	defer sp.bytesPerMsgMtx.Unlock()
+
+	sent := make(map[string]uint64, len(sp.bytesSentPerMsg))
+	for command, bytes := range sp.bytesSentPerMsg {
+		sent[command] = bytes
+	}
+	recv := make(map[string]uint64, len(sp.bytesRecvPerMsg))
+	for command, bytes := range sp.bytesRecvPerMsg {
+		recv[command] = bytes
+	}
+	return sent, recv
+}
+
+// historicalBlocksLimited returns whether or not serving historical blocks to
+// the peer is limited due to the upload target being reached.  Whitelisted
+// peers are never limited.
+func (sp *serverPeer) historicalBlocksLimited() bool {
+	return !sp.isWhitelisted && sp.server.uploadTarget.Reached(true)
+}
+
+// isHistoricalBlock returns whether or not the block with the passed hash is
+// old enough to be considered historical for the purposes of the upload
+// target.  Unknown blocks are not considered historical.
+func (s *server) isHistoricalBlock(hash *chainhash.Hash) bool {
+	header, err := s.blockManager.chain.HeaderByHash(hash)
+	if err != nil {
+		return false
+	}
+	return s.timeSource.AdjustedTime().Sub(header.Timestamp) > historicalBlockAge
 }
 
 // randomUint16Number returns a random uint16 in a specified input range.  Note
@@ -1195,6 +1558,22 @@ func (s *server) pushTxMsg(sp *serverPeer, hash *chainhash.Hash, doneChan chan<-
 	return nil
 }
 
+// isPrunedBlock returns whether or not the block with the passed hash is known
+// but its block data was deleted due to pruning or was never downloaded since
+// the chain state was loaded from a snapshot.
+func (s *server) isPrunedBlock(hash *chainhash.Hash) bool {
+	if !s.historyPruned {
+		return false
+	}
+
+	chain := s.blockManager.chain
+	if _, err := chain.HeaderByHash(hash); err != nil {
+		return false
+	}
+	haveBlock, err := chain.HaveBlock(hash)
+	return err == nil && !haveBlock
+}
+
 // pushBlockMsg sends a block message for the provided block hash to the
 // connected peer.  An error is returned if the block hash is not known.
 func (s *server) pushBlockMsg(sp *serverPeer, hash *chainhash.Hash, doneChan chan<- struct{}, waitChan <-chan struct{}) error {
@@ -1240,6 +1619,47 @@ func (s *server) pushBlockMsg(sp *serverPeer, hash *chainhash.Hash, doneChan cha
 	return nil
 }
 
+// pushCmpctBlockMsg sends a compact block message for the provided block hash
+// to the connected peer.  A full block is sent instead when the block is not
+// recent.  An error is returned if the block hash is not known.
+func (s *server) pushCmpctBlockMsg(sp *serverPeer, hash *chainhash.Hash, doneChan chan<- struct{}, waitChan <-chan struct{}) error {
+	chain := sp.server.blockManager.chain
+	block, err := chain.BlockByHash(hash)
+	if err != nil {
+		peerLog.Tracef("Unable to fetch requested block hash %v: %v",
+			hash, err)
+
+		if doneChan != nil {
+			doneChan <- struct{}{}
+		}
+		return err
+	}
+
+	// Peers are unlikely to have the transactions of blocks that are not
+	// recent, so send the full block instead.
+	best := chain.BestSnapshot()
+	if best.Height-block.Height() >= maxCmpctBlockDepth {
+		return s.pushBlockMsg(sp, hash, doneChan, waitChan)
+	}
+
+	nonce, err := wire.RandomUint64()
+	if err != nil {
+		if doneChan != nil {
+			doneChan <- struct{}{}
+		}
+		return err
+	}
+	cmpctBlock := newCmpctBlock(block.MsgBlock(), nonce)
+
+	// Once we have fetched data wait for any previous operation to finish.
+	if waitChan != nil {
+		<-waitChan
+	}
+
+	sp.QueueMessage(cmpctBlock, doneChan)
+	return nil
+}
+
 // handleUpdatePeerHeight updates the heights of all peers who were known to
 // announce a block we recently accepted.
 func (s *server) handleUpdatePeerHeights(state *peerState, umsg updatePeerHeightsMsg) {
@@ -1289,16 +1709,11 @@ func (s *server) handleAddPeerMsg(state *peerState, sp *serverPeer) bool {
 		sp.Disconnect()
 		return false
 	}
-	if banEnd, ok := state.banned[host]; ok {
-		if time.Now().Before(banEnd) {
-			srvrLog.Debugf("Peer %s is banned for another %v - disconnecting",
-				host, time.Until(banEnd))
-			sp.Disconnect()
-			return false
-		}
-
-		srvrLog.Infof("Peer %s is no longer banned", host)
-		delete(state.banned, host)
+	if banEnd, banned := s.banList.BanExpiry(net.ParseIP(host)); banned {
+		srvrLog.Debugf("Peer %s is banned for another %v - disconnecting",
+			host, time.Until(banEnd))
+		sp.Disconnect()
+		return false
 	}
 
 	// Limit max number of connections from a single IP.  However, allow
@@ -1314,8 +1729,11 @@ func (s *server) handleAddPeerMsg(state *peerState, sp *serverPeer) bool {
 	}
 
 	// Limit max number of total peers.  However, allow whitelisted inbound
-	// peers regardless.
-	if state.Count()+1 > cfg.MaxPeers && !isInboundWhitelisted {
+	// peers regardless and attempt to make room for new inbound peers by
+	// evicting the least useful existing inbound peer.
+	if state.Count()+1 > cfg.MaxPeers && !isInboundWhitelisted &&
+		!(sp.Inbound() && s.evictInboundPeer(state)) {
+
 		srvrLog.Infof("Max peers reached [%d] - disconnecting peer %s",
 			cfg.MaxPeers, sp)
 		sp.Disconnect()
@@ -1340,6 +1758,83 @@ func (s *server) handleAddPeerMsg(state *peerState, sp *serverPeer) bool {
 	return true
 }
 
+// evictInboundPeer attempts to make room for a new inbound peer by
+// disconnecting the least useful existing inbound peer as determined by the
+// connection manager.  Whitelisted peers are never evicted.  It returns whether
+// or not a peer was evicted.  It is invoked from the peerHandler goroutine.
+func (s *server) evictInboundPeer(state *peerState) bool {
+	// unixNanoTime converts the passed unix nanoseconds to a time with zero
+	// mapping to the zero time.
+	unixNanoTime := func(nanos int64) time.Time {
+		if nanos == 0 {
+			return time.Time{}
+		}
+		return time.Unix(0, nanos)
+	}
+
+	candidates := make([]connmgr.EvictionCandidate, 0, len(state.inboundPeers))
+	for id, sp := range state.inboundPeers {
+		if sp.isWhitelisted || sp.NA() == nil {
+			continue
+		}
+		candidates = append(candidates, connmgr.EvictionCandidate{
+			ID:            id,
+			NetGroup:      addrmgr.GroupKey(sp.NA()),
+			PingTime:      time.Duration(sp.LastPingMicros()) * time.Microsecond,
+			LastBlockTime: unixNanoTime(atomic.LoadInt64(&sp.lastBlockTime)),
+			LastTxTime:    unixNanoTime(atomic.LoadInt64(&sp.lastTxTime)),
+			ConnTime:      sp.TimeConnected(),
+		})
+	}
+
+	id, ok := s.connManager.SelectInboundToEvict(candidates)
+	if !ok {
+		return false
+	}
+	sp := state.inboundPeers[id]
+	srvrLog.Infof("Max peers reached [%d] - evicting inbound peer %s",
+		cfg.MaxPeers, sp)
+	delete(state.inboundPeers, id)
+	sp.Disconnect()
+	return true
+}
+
+// saveAnchors saves the addresses of up to the max number of anchors of the
+// connected block relay only peers that have been connected the longest so they
+// are reconnected to first on the next startup.  It is invoked from the
+// peerHandler goroutine.
+func (s *server) saveAnchors(state *peerState) {
+	if cfg.MaxAnchors == 0 {
+		return
+	}
+
+	var anchors []*serverPeer
+	for _, sp := range state.outboundPeers {
+		if sp.isBlockRelayOnly() && sp.VerAckReceived() {
+			anchors = append(anchors, sp)
+		}
+	}
+	if len(anchors) == 0 {
+		return
+	}
+	sort.Slice(anchors, func(i, j int) bool {
+		return anchors[i].TimeConnected().Before(anchors[j].TimeConnected())
+	})
+	if len(anchors) > cfg.MaxAnchors {
+		anchors = anchors[:cfg.MaxAnchors]
+	}
+
+	addrs := make([]string, 0, len(anchors))
+	for _, sp := range anchors {
+		addrs = append(addrs, sp.connReq.Addr.String())
+	}
+	if err := saveAnchors(cfg.DataDir, addrs); err != nil {
+		srvrLog.Warnf("Unable to save anchors: %v", err)
+		return
+	}
+	srvrLog.Debugf("Saved %d anchors", len(addrs))
+}
+
 // handleDonePeerMsg deals with peers that have signalled they are done.  It is
 // invoked from the peerHandler goroutine.
 func (s *server) handleDonePeerMsg(state *peerState, sp *serverPeer) {
@@ -1385,20 +1880,52 @@ func (s *server) handleBanPeerMsg(state *peerState, sp *serverPeer) {
 		srvrLog.Debugf("can't split ban peer %s %v", sp.Addr(), err)
 		return
 	}
+	subnet, err := connmgr.ParseSubnet(host)
+	if err != nil {
+		srvrLog.Debugf("can't ban peer %s: %v", sp.Addr(), err)
+		return
+	}
 	direction := directionString(sp.Inbound())
 	srvrLog.Infof("Banned peer %s (%s) for %v", host, direction,
 		cfg.BanDuration)
-	state.banned[host] = time.Now().Add(cfg.BanDuration)
+	expiry := time.Now().Add(cfg.BanDuration)
+	s.banList.Ban(subnet, expiry, "misbehaving")
 }
 
 // handleRelayInvMsg deals with relaying inventory to peers that are not already
 // known to have it.  It is invoked from the peerHandler goroutine.
 func (s *server) handleRelayInvMsg(state *peerState, msg relayMsg) {
+	// The compact block for peers that requested new blocks be announced
+	// via compact blocks is lazily created so it's only done once.
+	var cmpctBlock *wire.MsgCmpctBlock
+	var cmpctBlockErr error
 	state.forAllPeers(func(sp *serverPeer) {
 		if !sp.Connected() {
 			return
 		}
 
+		// If the inventory is a block and the peer wants compact blocks,
+		// generate and send a compact block message instead of an
+		// inventory message.
+		if msg.invVect.Type == wire.InvTypeBlock && sp.WantsCmpctBlocks() &&
+			cmpctBlockErr == nil {
+
+			if cmpctBlock == nil {
+				cmpctBlock, cmpctBlockErr = s.relayCmpctBlock(
+					&msg.invVect.Hash)
+				if cmpctBlockErr != nil {
+					peerLog.Errorf("Failed to create compact "+
+						"block %v: %v", msg.invVect.Hash,
+						cmpctBlockErr)
+				}
+			}
+			if cmpctBlock != nil {
+				sp.AddKnownInventory(msg.invVect)
+				sp.QueueMessage(cmpctBlock, nil)
+				return
+			}
+		}
+
 		// If the inventory is a block and the peer prefers headers,
 		// generate and send a headers message instead of an inventory
 		// message.
@@ -1440,6 +1967,21 @@ func (s *server) handleRelayInvMsg(state *peerState, msg relayMsg) {
 	})
 }
 
+// relayCmpctBlock returns a compact block for the block with the passed hash
+// that is suitable for relaying to peers that requested new blocks be announced
+// via compact blocks.
+func (s *server) relayCmpctBlock(hash *chainhash.Hash) (*wire.MsgCmpctBlock, error) {
+	block, err := s.blockManager.chain.BlockByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := wire.RandomUint64()
+	if err != nil {
+		return nil, err
+	}
+	return newCmpctBlock(block.MsgBlock(), nonce), nil
+}
+
 // handleBroadcastMsg deals with broadcasting messages to peers.  It is invoked
 // from the peerHandler goroutine.
 func (s *server) handleBroadcastMsg(state *peerState, bmsg *broadcastMsg) {
@@ -1653,14 +2195,20 @@ func newPeerConfig(sp *serverPeer) *peer.Config {
 			OnBlock:          sp.OnBlock,
 			OnInv:            sp.OnInv,
 			OnHeaders:        sp.OnHeaders,
+			OnCmpctBlock:     sp.OnCmpctBlock,
+			OnGetBlockTxn:    sp.OnGetBlockTxn,
+			OnBlockTxn:       sp.OnBlockTxn,
 			OnGetData:        sp.OnGetData,
 			OnGetBlocks:      sp.OnGetBlocks,
 			OnGetHeaders:     sp.OnGetHeaders,
 			OnGetCFilter:     sp.OnGetCFilter,
 			OnGetCFHeaders:   sp.OnGetCFHeaders,
 			OnGetCFTypes:     sp.OnGetCFTypes,
+			OnCFilter:        sp.OnCFilter,
+			OnCFHeaders:      sp.OnCFHeaders,
 			OnGetAddr:        sp.OnGetAddr,
 			OnAddr:           sp.OnAddr,
+			OnAddrV2:         sp.OnAddrV2,
 			OnRead:           sp.OnRead,
 			OnWrite:          sp.OnWrite,
 		},
@@ -1674,6 +2222,7 @@ func newPeerConfig(sp *serverPeer) *peer.Config {
 		Services:          sp.server.services,
 		DisableRelayTx:    cfg.BlocksOnly,
 		ProtocolVersion:   maxProtocolVersion,
+		V2Transport:       sp.server.services&wire.SFNodeP2PV2 != 0,
 	}
 }
 
@@ -1696,7 +2245,13 @@ func (s *server) inboundPeerConnected(conn net.Conn) {
 // manager of the attempt.
 func (s *server) outboundPeerConnected(c *connmgr.ConnReq, conn net.Conn) {
 	sp := newServerPeer(s, c.Permanent)
-	p, err := peer.NewOutboundPeer(newPeerConfig(sp), c.Addr.String())
+	peerCfg := newPeerConfig(sp)
+	peerCfg.V2Transport = s.shouldAttemptV2Transport(c)
+	sp.v2TransportAttempted = peerCfg.V2Transport
+	if c.BlockRelayOnly {
+		peerCfg.DisableRelayTx = true
+	}
+	p, err := peer.NewOutboundPeer(peerCfg, c.Addr.String())
 	if err != nil {
 		srvrLog.Debugf("Cannot create outbound peer %s: %v", c.Addr, err)
 		s.connManager.Disconnect(c.ID())
@@ -1709,12 +2264,83 @@ func (s *server) outboundPeerConnected(c *connmgr.ConnReq, conn net.Conn) {
 	s.addrManager.Attempt(sp.NA())
 }
 
+// shouldAttemptV2Transport returns whether or not the v2 transport handshake
+// should be initiated with the peer for the passed connection request.  It is
+// attempted when it is enabled and the address is either known to advertise
+// support for it or is a persistent peer, unless a previous attempt failed.
+func (s *server) shouldAttemptV2Transport(c *connmgr.ConnReq) bool {
+	if s.services&wire.SFNodeP2PV2 == 0 {
+		return false
+	}
+
+	addr := c.Addr.String()
+	s.v1TransportMtx.Lock()
+	failedTime, v1Only := s.v1TransportAddrs[addr]
+	if v1Only && time.Since(failedTime) >= v1TransportFallbackTime {
+		delete(s.v1TransportAddrs, addr)
+		v1Only = false
+	}
+	s.v1TransportMtx.Unlock()
+	if v1Only {
+		return false
+	}
+	if c.Permanent {
+		return true
+	}
+
+	na, err := s.addrManager.DeserializeNetAddress(addr)
+	if err != nil {
+		return false
+	}
+	return s.addrManager.GetServices(na)&wire.SFNodeP2PV2 != 0
+}
+
+// addV1TransportAddr records that the outbound peer with the passed address
+// failed the v2 transport handshake at the passed time.  The address that failed
+// the longest ago is forgotten when the maximum number of addresses are already
+// recorded.
+//
+// This function is safe for concurrent access.
+func (s *server) addV1TransportAddr(addr string, failedTime time.Time) {
+	s.v1TransportMtx.Lock()
+	defer s.v1TransportMtx.Unlock()
+
+	if _, ok := s.v1TransportAddrs[addr]; !ok &&
+		len(s.v1TransportAddrs) >= maxV1TransportAddrs {
+
+		var oldestAddr string
+		var oldestTime time.Time
+		for a, t := range s.v1TransportAddrs {
+			if oldestAddr == "" || t.Before(oldestTime) {
+				oldestAddr, oldestTime = a, t
+			}
+		}
+		delete(s.v1TransportAddrs, oldestAddr)
+	}
+	s.v1TransportAddrs[addr] = failedTime
+}
+
 // peerDoneHandler handles peer disconnects by notifiying the server that it's
 // done.
 func (s *server) peerDoneHandler(sp *serverPeer) {
 	sp.WaitForDisconnect()
+
+	// Fall back to the v1 transport for future connections to outbound
+	// peers that disconnected during the v2 transport handshake since they
+	// most likely do not support it.
+	if sp.v2TransportAttempted && sp.Transport() != peer.TransportV2 {
+		srvrLog.Debugf("Falling back to the v1 transport for %s", sp)
+		s.addV1TransportAddr(sp.connReq.Addr.String(), time.Now())
+	}
+
 	s.donePeers <- sp
 
+	// Allow another peer to be asked to announce new blocks via compact
+	// blocks.
+	if sp.cmpctHighBandwidth {
+		atomic.AddInt32(&s.cmpctHighBandwidthPeers, -1)
+	}
+
 	// Only tell block manager we are gone if we ever told it we existed.
 	if sp.VersionKnown() {
 		s.blockManager.DonePeer(sp)
@@ -1740,7 +2366,6 @@ func (s *server) peerHandler() {
 		inboundPeers:    make(map[int32]*serverPeer),
 		persistentPeers: make(map[int32]*serverPeer),
 		outboundPeers:   make(map[int32]*serverPeer),
-		banned:          make(map[string]time.Time),
 		outboundGroups:  make(map[string]int),
 	}
 
@@ -1789,6 +2414,10 @@ out:
 			s.handleQuery(state, qmsg)
 
 		case <-s.quit:
+			// Save the block relay only peers as anchors to
+			// reconnect to on the next startup.
+			s.saveAnchors(state)
+
 			// Disconnect all peers on server shutdown.
 			state.forAllPeers(func(sp *serverPeer) {
 				srvrLog.Tracef("Shutdown peer %s", sp)
@@ -1880,6 +2509,43 @@ func (s *server) Peers() []*serverPeer {
 	return <-replyChan
 }
 
+// cfFetchPeers returns the connected peers that serve committed filters.
+func (s *server) cfFetchPeers() []cfFetchPeer {
+	var peers []cfFetchPeer
+	for _, sp := range s.Peers() {
+		if sp.Connected() && sp.ProtocolVersion() >= wire.NodeCFVersion &&
+			sp.Services()&wire.SFNodeCF == wire.SFNodeCF {
+
+			peers = append(peers, sp)
+		}
+	}
+	return peers
+}
+
+// FetchCFilter requests the committed filter of the passed type for the block
+// with the passed hash from the connected peers that serve them.  It is used
+// in headers-only mode where the filters can't be built locally.
+func (s *server) FetchCFilter(hash *chainhash.Hash, filterType wire.FilterType) ([]byte, error) {
+	header, err := s.blockManager.chain.HeaderByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	var prevHash *chainhash.Hash
+	if *hash != *s.chainParams.GenesisHash {
+		prevHash = &header.PrevBlock
+	}
+	return s.cfFetcher.FetchFilter(s.cfFetchPeers(), hash, prevHash,
+		filterType, s.quit)
+}
+
+// FetchCFilterHeader requests the committed filter header of the passed type
+// for the block with the passed hash from the connected peers that serve them.
+// It is used in headers-only mode where the headers can't be built locally.
+func (s *server) FetchCFilterHeader(hash *chainhash.Hash, filterType wire.FilterType) (chainhash.Hash, error) {
+	return s.cfFetcher.FetchFilterHeader(s.cfFetchPeers(), hash, filterType,
+		s.quit)
+}
+
 // DisconnectNodeByAddr disconnects a peer by target address. Both outbound and
 // inbound nodes will be searched for the target node. An error message will
 // be returned if the peer was not found.
@@ -1908,6 +2574,29 @@ func (s *server) DisconnectNodeByID(id int32) error {
 	return <-replyChan
 }
 
+// BanSubnet bans the passed IP address or subnet until the provided expiry time
+// for the given reason and disconnects all inbound and outbound peers within
+// it.  The ban is persisted to the ban list file.
+func (s *server) BanSubnet(subnet *net.IPNet, expiry time.Time, reason string) {
+	s.banList.Ban(subnet, expiry, reason)
+
+	inSubnet := func(sp *serverPeer) bool {
+		host, _, err := net.SplitHostPort(sp.Addr())
+		if err != nil {
+			return false
+		}
+		ip := net.ParseIP(host)
+		return ip != nil && subnet.Contains(ip)
+	}
+	for {
+		replyChan := make(chan error)
+		s.query <- disconnectNodeMsg{cmp: inSubnet, reply: replyChan}
+		if err := <-replyChan; err != nil {
+			break
+		}
+	}
+}
+
 // RemoveNodeByAddr removes a peer from the list of persistent peers if
 // present. An error will be returned if the peer was not found.
 func (s *server) RemoveNodeByAddr(addr string) error {
@@ -1946,9 +2635,11 @@ func (s *server) ConnectNode(addr string, permanent bool) error {
 }
 
 // AddBytesSent adds the passed number of bytes to the total bytes sent counter
-// for the server.  It is safe for concurrent access.
+// for the server and the bytes uploaded during the current upload target
+// cycle.  It is safe for concurrent access.
 func (s *server) AddBytesSent(bytesSent uint64) {
 	atomic.AddUint64(&s.bytesSent, bytesSent)
+	s.uploadTarget.AddBytesSent(bytesSent)
 }
 
 // AddBytesReceived adds the passed number of bytes to the total bytes received
@@ -2114,6 +2805,22 @@ func (s *server) Start() {
 		go s.rebroadcastHandler()
 
 		s.rpcServer.Start()
+
+		// Relay the transactions added to and removed from the memory
+		// pool to the websocket clients that requested mempool events.
+		s.txMemPool.Subscribe(func(event *mempool.Event) {
+			s.rpcServer.ntfnMgr.NotifyMempoolEvent(event)
+		})
+	}
+
+	// Load the transactions that were in the memory pool when the server
+	// was last shutdown in the background since it can take a while for
+	// large pools.  This is done after the peer handler and RPC server are
+	// started so the loaded transactions are relayed and notified like any
+	// others.
+	if !cfg.NoPersistMempool {
+		s.wg.Add(1)
+		go s.mempoolLoadHandler()
 	}
 
 	// Start the CPU miner if generation is enabled.
@@ -2143,6 +2850,28 @@ func (s *server) Stop() error {
 		s.rpcServer.Stop()
 	}
 
+	// Save the transactions in the memory pool so they can be loaded on the
+	// next startup.  The pool is not saved when it has not finished loading
+	// since that would replace the saved file with a partial pool.
+	switch {
+	case cfg.NoPersistMempool:
+	case atomic.LoadInt32(&s.mempoolLoaded) == 0:
+		srvrLog.Infof("Not saving the mempool since it has not finished " +
+			"loading")
+	default:
+		numSaved, err := s.saveMempool()
+		if err != nil {
+			srvrLog.Warnf("Unable to save mempool: %v", err)
+		} else {
+			srvrLog.Infof("Saved %d mempool transactions", numSaved)
+		}
+	}
+
+	// Save any modifications to the ban list that have not been saved yet.
+	if err := s.banList.Save(); err != nil {
+		srvrLog.Warnf("Unable to save ban list: %v", err)
+	}
+
 	s.feeEstimator.Close()
 
 	// Signal the remaining goroutines to quit.
@@ -2150,6 +2879,76 @@ func (s *server) Stop() error {
 	return nil
 }
 
+// saveMempool saves the transactions in the memory pool to the mempool file in
+// the data directory and returns the number of saved transactions.  The
+// transactions are written to a temporary file which then replaces the mempool
+// file so a failed save does not corrupt a previously saved file.
+func (s *server) saveMempool() (int, error) {
+	tmpFile := s.mempoolFile + ".tmp"
+	f, err := os.Create(tmpFile)
+	if err != nil {
+		return 0, err
+	}
+	w := bufio.NewWriter(f)
+	numSaved, err := s.txMemPool.Save(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpFile)
+		return 0, err
+	}
+
+	return numSaved, os.Rename(tmpFile, s.mempoolFile)
+}
+
+// loadMempool loads the transactions saved to the mempool file in the data
+// directory back into the memory pool and returns the transactions that were
+// accepted.
+func (s *server) loadMempool() ([]*dcrutil.Tx, error) {
+	f, err := os.Open(s.mempoolFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return s.txMemPool.Load(bufio.NewReader(f))
+}
+
+// mempoolLoadHandler loads the transactions saved to the mempool file when the
+// server was last shutdown and announces the accepted ones to the connected
+// peers.
+//
+// It must be run as a goroutine.
+func (s *server) mempoolLoadHandler() {
+	defer s.wg.Done()
+
+	acceptedTxs, err := s.loadMempool()
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		srvrLog.Warnf("Unable to load mempool: %v", err)
+	default:
+		srvrLog.Infof("Loaded %d transactions into the mempool",
+			len(acceptedTxs))
+	}
+	atomic.StoreInt32(&s.mempoolLoaded, 1)
+
+	// Don't relay the transactions when the server is shutting down since
+	// the peer handler is no longer running.
+	select {
+	case <-s.quit:
+		return
+	default:
+	}
+	if len(acceptedTxs) > 0 {
+		s.AnnounceNewTransactions(acceptedTxs)
+	}
+}
+
 // WaitForShutdown blocks until the main listener and peer handlers are stopped.
 func (s *server) WaitForShutdown() {
 	s.wg.Wait()
@@ -2325,10 +3124,45 @@ func standardScriptVerifyFlags(chain *blockchain.BlockChain) (txscript.ScriptFla
 // Decred network type specified by chainParams.  Use start to begin accepting
 // connections from peers.
 func newServer(listenAddrs []string, db database.DB, chainParams *chaincfg.Params, dataDir string, interrupt <-chan struct{}) (*server, error) {
+	// The history leading up to a chain state snapshot is not available
+	// when the chain state is loaded from one during this run or was loaded
+	// from one previously, so the indexes that are built from it are not
+	// supported.
+	loadedSnapshot, err := blockchain.FetchLoadedSnapshot(db)
+	if err != nil {
+		return nil, err
+	}
+	fromSnapshot := cfg.LoadSnapshot != "" || loadedSnapshot != nil
+	if fromSnapshot {
+		if cfg.TxIndex || cfg.AddrIndex || cfg.HeadersOnly {
+			return nil, errors.New("the --txindex, --addrindex, and " +
+				"--headersonly options are not supported when the " +
+				"chain state is loaded from a snapshot")
+		}
+		cfg.NoExistsAddrIndex = true
+		cfg.NoCFilters = true
+	}
+
 	services := defaultServices
 	if cfg.NoCFilters {
 		services &^= wire.SFNodeCF
 	}
+	if cfg.NoV2Transport {
+		services &^= wire.SFNodeP2PV2
+	}
+	if cfg.HeadersOnly {
+		// Neither blocks nor committed filters can be served without
+		// the block data.
+		services &^= wire.SFNodeNetwork | wire.SFNodeCF
+	}
+	historyPruned := cfg.Prune != 0 || fromSnapshot
+	if historyPruned && !cfg.HeadersOnly {
+		// Only the most recent blocks can be served once the older
+		// block data is pruned or when the chain state was loaded from
+		// a snapshot.
+		services &^= wire.SFNodeNetwork
+		services |= wire.SFNodeNetworkLimited
+	}
 
 	amgr := addrmgr.New(cfg.DataDir, dcrdLookup)
 
@@ -2459,6 +3293,7 @@ func newServer(listenAddrs []string, db database.DB, chainParams *chaincfg.Param
 		}
 	}
 
+	uploadTarget := uint64(cfg.MaxUploadTarget) * 1024 * 1024
 	s := server{
 		chainParams:          chainParams,
 		addrManager:          amgr,
@@ -2476,6 +3311,14 @@ func newServer(listenAddrs []string, db database.DB, chainParams *chaincfg.Param
 		timeSource:           blockchain.NewMedianTime(),
 		services:             services,
 		sigCache:             txscript.NewSigCache(cfg.SigCacheMaxSize),
+		v1TransportAddrs:     make(map[string]time.Time),
+		uploadTarget:         newUploadTarget(uploadTarget, chainParams),
+		historyPruned:        historyPruned,
+		cfFetcher:            newCFFetcher(cfFetchTimeout),
+		banList:              connmgr.NewBanList(path.Join(dataDir, banListFilename)),
+	}
+	if err := s.banList.Load(); err != nil {
+		srvrLog.Warnf("Unable to load ban list: %v", err)
 	}
 
 	// Create the transaction and address indexes if needed.
@@ -2555,6 +3398,11 @@ func newServer(listenAddrs []string, db database.DB, chainParams *chaincfg.Param
 			FreeTxRelayLimit:     cfg.FreeTxRelayLimit,
 			MaxOrphanTxs:         cfg.MaxOrphanTxs,
 			MaxOrphanTxSize:      defaultMaxOrphanTxSize,
+			MaxPoolSize:          int64(cfg.MaxMempoolSize) * 1024 * 1024,
+			MaxAncestorCount:     defaultMaxAncestorCount,
+			MaxAncestorSize:      defaultMaxAncestorSize,
+			MaxDescendantCount:   defaultMaxDescendantCount,
+			MaxDescendantSize:    defaultMaxDescendantSize,
 			MaxSigOpsPerTx:       blockchain.MaxSigOpsPerBlock / 5,
 			MinRelayTxFee:        cfg.minRelayTxFee,
 			AllowOldVotes:        cfg.AllowOldVotes,
@@ -2583,6 +3431,7 @@ func newServer(listenAddrs []string, db database.DB, chainParams *chaincfg.Param
 		RemoveTxFromFeeEstimation: s.feeEstimator.RemoveMemPoolTransaction,
 	}
 	s.txMemPool = mempool.New(&txC)
+	s.mempoolFile = path.Join(dataDir, mempoolFileName)
 
 	// Create the mining policy and block template generator based on the
 	// configuration options.
@@ -2594,6 +3443,9 @@ func newServer(listenAddrs []string, db database.DB, chainParams *chaincfg.Param
 		BlockMaxSize:      cfg.BlockMaxSize,
 		BlockPrioritySize: cfg.BlockPrioritySize,
 		TxMinFreeFee:      cfg.minRelayTxFee,
+		NewTxSelector:     cfg.miningSelector,
+		AllowScripts:      cfg.miningAllowScripts,
+		DenyScripts:       cfg.miningDenyScripts,
 	}
 	blockTemplateGenerator := newBlkTmplGenerator(&policy, s.txMemPool,
 		s.timeSource, s.sigCache, s.chainParams, bm.chain, bm)
@@ -2633,6 +3485,22 @@ func newServer(listenAddrs []string, db database.DB, chainParams *chaincfg.Param
 					continue
 				}
 
+				// Skip banned addresses.
+				if s.banList.IsBanned(addr.NetAddress().IP) {
+					continue
+				}
+
+				// Skip networks that can't be dialed.  I2P is not
+				// supported and onion services require Tor.
+				switch addr.NetAddress().Network {
+				case wire.NetworkI2P:
+					continue
+				case wire.NetworkTorV3:
+					if cfg.NoOnion {
+						continue
+					}
+				}
+
 				// only allow recent nodes (10mins) after we failed 30
 				// times
 				if tries < 30 && time.Since(addr.LastAttempt()) < 10*time.Minute {
@@ -2653,19 +3521,48 @@ func newServer(listenAddrs []string, db database.DB, chainParams *chaincfg.Param
 		}
 	}
 
+	// Load the anchors saved on the last shutdown to reconnect to them as
+	// block relay only peers.  The anchors file is always removed so the
+	// anchors are only used once.
+	var anchors []net.Addr
+	anchorAddrs, err := loadAnchors(cfg.DataDir)
+	if err != nil {
+		srvrLog.Warnf("Unable to load anchors: %v", err)
+	}
+	if newAddressFunc != nil {
+		for _, addr := range anchorAddrs {
+			if len(anchors) >= cfg.MaxAnchors {
+				break
+			}
+			netAddr, err := addrStringToNetAddr(addr)
+			if err != nil {
+				srvrLog.Debugf("Unable to resolve anchor %s: %v",
+					addr, err)
+				continue
+			}
+			anchors = append(anchors, netAddr)
+		}
+	}
+
 	// Create a connection manager.
 	targetOutbound := defaultTargetOutbound
 	if cfg.MaxPeers < targetOutbound {
 		targetOutbound = cfg.MaxPeers
 	}
+	targetBlockRelayOnly := cfg.BlockRelayOnlyPeers
+	if cfg.MaxPeers-targetOutbound < targetBlockRelayOnly {
+		targetBlockRelayOnly = cfg.MaxPeers - targetOutbound
+	}
 	cmgr, err := connmgr.New(&connmgr.Config{
-		Listeners:      listeners,
-		OnAccept:       s.inboundPeerConnected,
-		RetryDuration:  connectionRetryInterval,
-		TargetOutbound: uint32(targetOutbound),
-		Dial:           dcrdDial,
-		OnConnection:   s.outboundPeerConnected,
-		GetNewAddress:  newAddressFunc,
+		Listeners:            listeners,
+		OnAccept:             s.inboundPeerConnected,
+		RetryDuration:        connectionRetryInterval,
+		TargetOutbound:       uint32(targetOutbound),
+		TargetBlockRelayOnly: uint32(targetBlockRelayOnly),
+		Anchors:              anchors,
+		Dial:                 dcrdDial,
+		OnConnection:         s.outboundPeerConnected,
+		GetNewAddress:        newAddressFunc,
 	})
 	if err != nil {
 		return nil, err
@@ -2715,6 +3612,17 @@ func addrStringToNetAddr(addr string) (net.Addr, error) {
 		return nil, err
 	}
 
+	port, err := strconv.Atoi(strPort)
+	if err != nil {
+		return nil, err
+	}
+
+	// Onion services do not resolve to an IP address, so return the host
+	// as is in order to dial it via the Tor proxy.
+	if strings.HasSuffix(host, ".onion") {
+		return &connmgr.OnionAddr{Host: host, Port: port}, nil
+	}
+
 	// Attempt to look up an IP address associated with the parsed host.
 	// The dcrdLookup function will transparently handle performing the
 	// lookup over Tor if necessary.
@@ -2726,11 +3634,6 @@ func addrStringToNetAddr(addr string) (net.Addr, error) {
 		return nil, fmt.Errorf("no addresses found for %s", host)
 	}
 
-	port, err := strconv.Atoi(strPort)
-	if err != nil {
-		return nil, err
-	}
-
 	return &net.TCPAddr{
 		IP:   ips[0],
 		Port: port,
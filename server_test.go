@@ -0,0 +1,64 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/connmgr"
+	"github.com/decred/dcrd/wire"
+)
+
+// TestV1TransportAddrs ensures outbound peers that failed the v2 transport
+// handshake only fall back to the v1 transport for a limited time and that
+// the number of remembered addresses is limited.
+func TestV1TransportAddrs(t *testing.T) {
+	s := &server{
+		services:         wire.SFNodeP2PV2,
+		v1TransportAddrs: make(map[string]time.Time),
+	}
+	c := &connmgr.ConnReq{
+		Addr:      &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 9108},
+		Permanent: true,
+	}
+	addr := c.Addr.String()
+
+	// Ensure the v2 transport is not attempted after a recent failure.
+	now := time.Now()
+	s.addV1TransportAddr(addr, now)
+	if s.shouldAttemptV2Transport(c) {
+		t.Fatal("v2 transport attempted after a recent failure")
+	}
+
+	// Ensure the v2 transport is attempted again once the fallback time
+	// passes and the address is forgotten.
+	s.addV1TransportAddr(addr, now.Add(-v1TransportFallbackTime))
+	if !s.shouldAttemptV2Transport(c) {
+		t.Fatal("v2 transport not attempted after the fallback time")
+	}
+	if _, ok := s.v1TransportAddrs[addr]; ok {
+		t.Fatal("address not forgotten after the fallback time")
+	}
+
+	// Ensure the address that failed the longest ago is forgotten once the
+	// maximum number of addresses is reached.
+	for i := 0; i <= maxV1TransportAddrs; i++ {
+		a := fmt.Sprintf("10.0.%d.%d:9108", i/256, i%256)
+		s.addV1TransportAddr(a, now.Add(time.Duration(i)*time.Second))
+	}
+	if len(s.v1TransportAddrs) != maxV1TransportAddrs {
+		t.Fatalf("unexpected number of addresses -- got %d, want %d",
+			len(s.v1TransportAddrs), maxV1TransportAddrs)
+	}
+	if _, ok := s.v1TransportAddrs["10.0.0.0:9108"]; ok {
+		t.Fatal("oldest address not forgotten")
+	}
+	if _, ok := s.v1TransportAddrs["10.0.0.1:9108"]; !ok {
+		t.Fatal("address other than the oldest forgotten")
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg"
+)
+
+const (
+	// uploadTargetTimeframe is the duration of each cycle the upload target
+	// applies to.
+	uploadTargetTimeframe = 24 * time.Hour
+
+	// historicalBlockAge is the minimum age of a block for it to be
+	// considered historical.  Historical blocks are no longer served to
+	// non-whitelisted peers once the upload target is reached.
+	historicalBlockAge = 7 * 24 * time.Hour
+)
+
+// uploadTarget tracks the number of bytes uploaded to peers during the current
+// cycle in order to limit the upload to a target number of bytes per cycle.
+//
+// The target is considered reached for the purposes of serving historical
+// blocks early enough to leave room in the remaining budget for relaying a
+// maximum size block for every block expected to be found during the remainder
+// of the cycle.  This ensures new tips continue to be relayed.
+type uploadTarget struct {
+	target       uint64
+	blockTime    time.Duration
+	maxBlockSize uint64
+
+	// timeNow returns the current time.  It is only overridden by tests.
+	timeNow func() time.Time
+
+	mtx        sync.Mutex
+	cycleStart time.Time
+	cycleBytes uint64
+}
+
+// maxBlockSize returns the largest block size allowed by the passed network
+// parameters.
+func maxBlockSize(params *chaincfg.Params) uint64 {
+	var maxSize uint64
+	for _, size := range params.MaximumBlockSizes {
+		if uint64(size) > maxSize {
+			maxSize = uint64(size)
+		}
+	}
+	return maxSize
+}
+
+// minUploadTarget returns the minimum upload target for the passed network
+// parameters.  It is the space reserved for relaying a maximum size block for
+// every block expected to be found during a full cycle, so lower targets would
+// prevent historical blocks from ever being served.
+func minUploadTarget(params *chaincfg.Params) uint64 {
+	if params.TargetTimePerBlock <= 0 {
+		return 0
+	}
+	blocksPerCycle := uint64(uploadTargetTimeframe / params.TargetTimePerBlock)
+	return blocksPerCycle * maxBlockSize(params)
+}
+
+// newUploadTarget returns a new upload target that limits the upload to the
+// passed number of bytes per cycle.  A target of zero disables the limit.  The
+// network parameters are used to determine the space reserved for relaying new
+// blocks.
+func newUploadTarget(target uint64, params *chaincfg.Params) *uploadTarget {
+	return &uploadTarget{
+		target:       target,
+		blockTime:    params.TargetTimePerBlock,
+		maxBlockSize: maxBlockSize(params),
+		timeNow:      time.Now,
+		cycleStart:   time.Now(),
+	}
+}
+
+// maybeStartCycle resets the bytes uploaded when the current cycle has ended.
+//
+// This function MUST be called with the mutex held (for writes).
+func (u *uploadTarget) maybeStartCycle(now time.Time) {
+	if now.Sub(u.cycleStart) >= uploadTargetTimeframe {
+		u.cycleStart = now
+		u.cycleBytes = 0
+	}
+}
+
+// AddBytesSent adds the passed number of bytes to those uploaded during the
+// current cycle.
+//
+// This function is safe for concurrent access.
+func (u *uploadTarget) AddBytesSent(bytesSent uint64) {
+	u.mtx.Lock()
+	u.maybeStartCycle(u.timeNow())
+	u.cycleBytes += bytesSent
+	u.mtx.Unlock()
+}
+
+// timeLeft returns the time left in the current cycle.
+//
+// This function MUST be called with the mutex held (for reads).
+func (u *uploadTarget) timeLeft(now time.Time) time.Duration {
+	timeLeft := u.cycleStart.Add(uploadTargetTimeframe).Sub(now)
+	if timeLeft < 0 {
+		return 0
+	}
+	return timeLeft
+}
+
+// Reached returns whether or not the upload target for the current cycle has
+// been reached.  When historical is set, the space reserved for relaying new
+// blocks during the remainder of the cycle is taken into account in order to
+// determine whether or not historical blocks may still be served.  It always
+// returns false when the target is disabled.
+//
+// This function is safe for concurrent access.
+func (u *uploadTarget) Reached(historical bool) bool {
+	if u.target == 0 {
+		return false
+	}
+
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	now := u.timeNow()
+	u.maybeStartCycle(now)
+	var buffer uint64
+	if historical && u.blockTime > 0 {
+		buffer = uint64(u.timeLeft(now)/u.blockTime) * u.maxBlockSize
+	}
+	return buffer >= u.target || u.cycleBytes >= u.target-buffer
+}
+
+// Status returns the number of bytes and time left in the current cycle.  The
+// bytes left are zero when the target is disabled.
+//
+// This function is safe for concurrent access.
+func (u *uploadTarget) Status() (uint64, time.Duration) {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	now := u.timeNow()
+	u.maybeStartCycle(now)
+	var bytesLeft uint64
+	if u.cycleBytes < u.target {
+		bytesLeft = u.target - u.cycleBytes
+	}
+	return bytesLeft, u.timeLeft(now)
+}
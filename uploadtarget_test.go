@@ -0,0 +1,125 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg"
+)
+
+// TestUploadTarget ensures the upload target is reached at the expected points
+// for both historical and new blocks and that it resets at the start of each
+// cycle.
+func TestUploadTarget(t *testing.T) {
+	params := &chaincfg.Params{
+		TargetTimePerBlock: time.Hour,
+		MaximumBlockSizes:  []int{1000, 2000},
+	}
+	now := time.Unix(1550000000, 0)
+	target := newUploadTarget(50000, params)
+	target.timeNow = func() time.Time { return now }
+	target.cycleStart = now
+
+	// Ensure the target is not reached initially.
+	if target.Reached(false) || target.Reached(true) {
+		t.Fatal("upload target reached before uploading")
+	}
+
+	// There are 24 expected blocks of up to 2000 bytes left in the cycle,
+	// so historical blocks may only be served until 2000 bytes are sent.
+	target.AddBytesSent(1999)
+	if target.Reached(true) {
+		t.Fatal("historical upload target reached before buffer")
+	}
+	target.AddBytesSent(1)
+	if !target.Reached(true) {
+		t.Fatal("historical upload target not reached at buffer")
+	}
+	if target.Reached(false) {
+		t.Fatal("upload target reached before target")
+	}
+
+	// The buffer shrinks as the cycle progresses.
+	now = now.Add(12 * time.Hour)
+	if target.Reached(true) {
+		t.Fatal("historical upload target reached with shrunk buffer")
+	}
+	bytesLeft, timeLeft := target.Status()
+	if bytesLeft != 48000 || timeLeft != 12*time.Hour {
+		t.Fatalf("unexpected status - got %d bytes and %v left", bytesLeft,
+			timeLeft)
+	}
+
+	// Ensure the target is reached once all bytes are sent.
+	target.AddBytesSent(48000)
+	if !target.Reached(false) {
+		t.Fatal("upload target not reached after target")
+	}
+
+	// Ensure the target resets at the start of the next cycle.
+	now = now.Add(12 * time.Hour)
+	if target.Reached(false) || target.Reached(true) {
+		t.Fatal("upload target reached in new cycle")
+	}
+	bytesLeft, timeLeft = target.Status()
+	if bytesLeft != 50000 || timeLeft != uploadTargetTimeframe {
+		t.Fatalf("unexpected status - got %d bytes and %v left", bytesLeft,
+			timeLeft)
+	}
+
+	// Ensure a disabled target is never reached.
+	target = newUploadTarget(0, params)
+	target.AddBytesSent(1 << 40)
+	if target.Reached(false) || target.Reached(true) {
+		t.Fatal("disabled upload target reached")
+	}
+}
+
+// TestMinUploadTarget ensures the minimum upload target reserves space for a
+// maximum size block for every block expected during a cycle.
+func TestMinUploadTarget(t *testing.T) {
+	tests := []struct {
+		name   string
+		params *chaincfg.Params
+		want   uint64
+	}{{
+		name: "hourly blocks",
+		params: &chaincfg.Params{
+			TargetTimePerBlock: time.Hour,
+			MaximumBlockSizes:  []int{1000, 2000},
+		},
+		want: 24 * 2000,
+	}, {
+		name:   "mainnet",
+		params: &chaincfg.MainNetParams,
+		want:   288 * 393216,
+	}, {
+		name:   "no block time",
+		params: &chaincfg.Params{MaximumBlockSizes: []int{1000}},
+		want:   0,
+	}}
+	for _, test := range tests {
+		if got := minUploadTarget(test.params); got != test.want {
+			t.Errorf("%s: unexpected minimum upload target -- got %d, "+
+				"want %d", test.name, got, test.want)
+		}
+	}
+
+	// Ensure the historical upload target is reached immediately for targets
+	// below the minimum.
+	params := tests[0].params
+	now := time.Unix(1550000000, 0)
+	target := newUploadTarget(minUploadTarget(params)-1, params)
+	target.timeNow = func() time.Time { return now }
+	target.cycleStart = now
+	if !target.Reached(true) {
+		t.Fatal("historical upload target below minimum not reached")
+	}
+	if target.Reached(false) {
+		t.Fatal("upload target below minimum reached before uploading")
+	}
+}
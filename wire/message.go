@@ -55,6 +55,12 @@ const (
 	CmdCFilter        = "cfilter"
 	CmdCFHeaders      = "cfheaders"
 	CmdCFTypes        = "cftypes"
+	CmdSendCmpct      = "sendcmpct"
+	CmdCmpctBlock     = "cmpctblock"
+	CmdGetBlockTxn    = "getblocktxn"
+	CmdBlockTxn       = "blocktxn"
+	CmdSendAddrV2     = "sendaddrv2"
+	CmdAddrV2         = "addrv2"
 )
 
 // Message is an interface that describes a Decred message.  A type that
@@ -151,6 +157,24 @@ func makeEmptyMessage(command string) (Message, error) {
 	case CmdCFTypes:
 		msg = &MsgCFTypes{}
 
+	case CmdSendCmpct:
+		msg = &MsgSendCmpct{}
+
+	case CmdCmpctBlock:
+		msg = &MsgCmpctBlock{}
+
+	case CmdGetBlockTxn:
+		msg = &MsgGetBlockTxn{}
+
+	case CmdBlockTxn:
+		msg = &MsgBlockTxn{}
+
+	case CmdSendAddrV2:
+		msg = &MsgSendAddrV2{}
+
+	case CmdAddrV2:
+		msg = &MsgAddrV2{}
+
 	default:
 		return nil, fmt.Errorf("unhandled command [%s]", command)
 	}
@@ -77,6 +77,13 @@ func TestMessage(t *testing.T) {
 	msgCFHeaders := NewMsgCFHeaders()
 	msgCFTypes := NewMsgCFTypes([]FilterType{GCSFilterExtended})
 	msgReject := NewMsgReject("block", RejectDuplicate, "duplicate block")
+	msgSendCmpct := NewMsgSendCmpct(true, CmpctBlockVersion1)
+	msgCmpctBlock := NewMsgCmpctBlock(&testBlock.Header, 123123)
+	msgGetBlockTxn := NewMsgGetBlockTxn(&chainhash.Hash{}, []uint32{0},
+		[]uint32{})
+	msgBlockTxn := NewMsgBlockTxn(&chainhash.Hash{})
+	msgSendAddrV2 := NewMsgSendAddrV2()
+	msgAddrV2 := NewMsgAddrV2()
 
 	tests := []struct {
 		in     Message     // Value to encode
@@ -107,6 +114,12 @@ func TestMessage(t *testing.T) {
 		{msgCFilter, msgCFilter, pver, MainNet, 65},           // [24]
 		{msgCFHeaders, msgCFHeaders, pver, MainNet, 58},       // [25]
 		{msgCFTypes, msgCFTypes, pver, MainNet, 26},           // [26]
+		{msgSendCmpct, msgSendCmpct, pver, MainNet, 33},       // [27]
+		{msgCmpctBlock, msgCmpctBlock, pver, MainNet, 216},    // [28]
+		{msgGetBlockTxn, msgGetBlockTxn, pver, MainNet, 59},   // [29]
+		{msgBlockTxn, msgBlockTxn, pver, MainNet, 58},         // [30]
+		{msgSendAddrV2, msgSendAddrV2, pver, MainNet, 24},     // [31]
+		{msgAddrV2, msgAddrV2, pver, MainNet, 25},             // [32]
 	}
 
 	t.Logf("Running %d tests", len(tests))
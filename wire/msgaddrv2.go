@@ -0,0 +1,154 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+)
+
+// maxNetAddressV2Payload is the maximum payload size for a NetAddress in the
+// addrv2 message encoding.
+//
+// Timestamp 4 bytes + services varint + network id 1 byte + address length
+// varint + address + port 2 bytes.
+const maxNetAddressV2Payload = 4 + MaxVarIntPayload + 1 + MaxVarIntPayload +
+	maxAddrV2Size + 2
+
+// MsgAddrV2 implements the Message interface and represents a Decred addrv2
+// message.  It is used to provide a list of known active peers on the network
+// in the same manner as the addr message (MsgAddr) except that each address is
+// encoded along with the network it belongs to.  This allows addresses on
+// networks that can not be represented as an IP address, such as Tor v3 onion
+// services, I2P, and CJDNS, to be relayed.
+//
+// Addresses on networks that are not known are skipped when decoding.
+//
+// Use the AddAddress function to build up the list of known addresses when
+// sending an addrv2 message to another peer.
+//
+// This message was not added until protocol versions starting with
+// AddrV2Version.
+type MsgAddrV2 struct {
+	AddrList []*NetAddress
+}
+
+// AddAddress adds a known active peer to the message.
+func (msg *MsgAddrV2) AddAddress(na *NetAddress) error {
+	if len(msg.AddrList)+1 > MaxAddrPerMsg {
+		str := fmt.Sprintf("too many addresses in message [max %v]",
+			MaxAddrPerMsg)
+		return messageError("MsgAddrV2.AddAddress", str)
+	}
+
+	msg.AddrList = append(msg.AddrList, na)
+	return nil
+}
+
+// AddAddresses adds multiple known active peers to the message.
+func (msg *MsgAddrV2) AddAddresses(netAddrs ...*NetAddress) error {
+	for _, na := range netAddrs {
+		err := msg.AddAddress(na)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClearAddresses removes all addresses from the message.
+func (msg *MsgAddrV2) ClearAddresses() {
+	msg.AddrList = []*NetAddress{}
+}
+
+// BtcDecode decodes r using the Decred protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgAddrV2) BtcDecode(r io.Reader, pver uint32) error {
+	const op = "MsgAddrV2.BtcDecode"
+	if pver < AddrV2Version {
+		str := fmt.Sprintf("addrv2 message invalid for protocol "+
+			"version %d", pver)
+		return messageError(op, str)
+	}
+
+	count, err := ReadVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+
+	// Limit to max addresses per message.
+	if count > MaxAddrPerMsg {
+		str := fmt.Sprintf("too many addresses for message "+
+			"[count %v, max %v]", count, MaxAddrPerMsg)
+		return messageError(op, str)
+	}
+
+	addrList := make([]NetAddress, count)
+	msg.AddrList = make([]*NetAddress, 0, count)
+	for i := uint64(0); i < count; i++ {
+		na := &addrList[i]
+		known, err := readNetAddressV2(r, pver, na)
+		if err != nil {
+			return err
+		}
+		if known {
+			msg.AddAddress(na)
+		}
+	}
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the Decred protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgAddrV2) BtcEncode(w io.Writer, pver uint32) error {
+	const op = "MsgAddrV2.BtcEncode"
+	if pver < AddrV2Version {
+		str := fmt.Sprintf("addrv2 message invalid for protocol "+
+			"version %d", pver)
+		return messageError(op, str)
+	}
+
+	count := len(msg.AddrList)
+	if count > MaxAddrPerMsg {
+		str := fmt.Sprintf("too many addresses for message "+
+			"[count %v, max %v]", count, MaxAddrPerMsg)
+		return messageError(op, str)
+	}
+
+	err := WriteVarInt(w, pver, uint64(count))
+	if err != nil {
+		return err
+	}
+
+	for _, na := range msg.AddrList {
+		err = writeNetAddressV2(w, pver, na)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgAddrV2) Command() string {
+	return CmdAddrV2
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgAddrV2) MaxPayloadLength(pver uint32) uint32 {
+	// Num addresses (varInt) + max allowed addresses.
+	return MaxVarIntPayload + (MaxAddrPerMsg * maxNetAddressV2Payload)
+}
+
+// NewMsgAddrV2 returns a new Decred addrv2 message that conforms to the
+// Message interface.  See MsgAddrV2 for details.
+func NewMsgAddrV2() *MsgAddrV2 {
+	return &MsgAddrV2{
+		AddrList: make([]*NetAddress, 0, MaxAddrPerMsg),
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+// testAddrV2 returns an addrv2 message that contains an address on each of the
+// supported networks along with its expected wire encoding.
+func testAddrV2() (*MsgAddrV2, []byte) {
+	ts := time.Unix(0x495fab29, 0) // 2009-01-03 12:15:05 -0600 CST
+	repeat := func(b byte, n int) []byte {
+		return bytes.Repeat([]byte{b}, n)
+	}
+	torV2 := append(append([]byte{}, onionCatPrefix...), repeat(0x01, 10)...)
+
+	msg := NewMsgAddrV2()
+	msg.AddAddresses(
+		&NetAddress{Timestamp: ts, Services: SFNodeNetwork,
+			IP: net.ParseIP("127.0.0.1"), Port: 9108},
+		&NetAddress{Timestamp: ts, Services: SFNodeNetwork,
+			IP: net.ParseIP("2001:470::1"), Port: 9108},
+		&NetAddress{Timestamp: ts, Services: SFNodeNetwork,
+			IP: net.IP(torV2), Port: 9108},
+		&NetAddress{Timestamp: ts, Services: SFNodeNetwork | SFNodeCF,
+			IP: net.IP(repeat(0x02, 32)), Port: 9108,
+			Network: NetworkTorV3},
+		&NetAddress{Timestamp: ts, Services: 0,
+			IP: net.IP(repeat(0x03, 32)), Port: 0, Network: NetworkI2P},
+		&NetAddress{Timestamp: ts, Services: SFNodeNetwork,
+			IP: net.ParseIP("fc00::1"), Port: 9108,
+			Network: NetworkCJDNS},
+	)
+
+	var buf bytes.Buffer
+	buf.Write([]byte{0x06}) // Num addresses
+	header := func(services byte, netID NetworkID, addrLen byte) {
+		buf.Write([]byte{0x29, 0xab, 0x5f, 0x49}) // Timestamp
+		buf.Write([]byte{services, byte(netID), addrLen})
+	}
+	header(0x01, NetworkIPv4, 4)
+	buf.Write([]byte{0x7f, 0x00, 0x00, 0x01, 0x23, 0x94})
+	header(0x01, NetworkIPv6, 16)
+	buf.Write(net.ParseIP("2001:470::1"))
+	buf.Write([]byte{0x23, 0x94})
+	header(0x01, NetworkTorV2, 10)
+	buf.Write(repeat(0x01, 10))
+	buf.Write([]byte{0x23, 0x94})
+	header(0x05, NetworkTorV3, 32)
+	buf.Write(repeat(0x02, 32))
+	buf.Write([]byte{0x23, 0x94})
+	header(0x00, NetworkI2P, 32)
+	buf.Write(repeat(0x03, 32))
+	buf.Write([]byte{0x00, 0x00})
+	header(0x01, NetworkCJDNS, 16)
+	buf.Write(net.ParseIP("fc00::1"))
+	buf.Write([]byte{0x23, 0x94})
+
+	return msg, buf.Bytes()
+}
+
+// TestAddrV2 tests the MsgAddrV2 API.
+func TestAddrV2(t *testing.T) {
+	pver := ProtocolVersion
+
+	// Ensure the command is expected value.
+	wantCmd := "addrv2"
+	msg := NewMsgAddrV2()
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgAddrV2: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	// Ensure max payload is expected value for latest protocol version.
+	// Num addresses (varInt) + max allowed addresses.
+	wantPayload := uint32(537009)
+	maxPayload := msg.MaxPayloadLength(pver)
+	if maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length for "+
+			"protocol version %d - got %v, want %v", pver,
+			maxPayload, wantPayload)
+	}
+
+	// Ensure adding more than the max allowed addresses fails.
+	na := NewNetAddressIPPort(net.ParseIP("127.0.0.1"), 9108, SFNodeNetwork)
+	for i := 0; i < MaxAddrPerMsg; i++ {
+		if err := msg.AddAddress(na); err != nil {
+			t.Fatalf("AddAddress: unexpected error: %v", err)
+		}
+	}
+	if err := msg.AddAddress(na); err == nil {
+		t.Errorf("AddAddress: expected error on too many addresses " +
+			"not received")
+	}
+
+	// Ensure the address list is cleared.
+	msg.ClearAddresses()
+	if len(msg.AddrList) != 0 {
+		t.Errorf("ClearAddresses: address list is not empty - "+
+			"got %v [%v], want %v", len(msg.AddrList),
+			spew.Sdump(msg.AddrList[0]), 0)
+	}
+
+	// Ensure the network IDs have the expected string representations.
+	if s := NetworkTorV3.String(); s != "TorV3" {
+		t.Errorf("String: unexpected result - got %q, want %q", s,
+			"TorV3")
+	}
+	if s := NetworkID(0xff).String(); s != "Unknown NetworkID (255)" {
+		t.Errorf("String: unexpected result - got %q, want %q", s,
+			"Unknown NetworkID (255)")
+	}
+}
+
+// TestAddrV2Wire tests the MsgAddrV2 wire encode and decode for addresses on
+// all of the supported networks.
+func TestAddrV2Wire(t *testing.T) {
+	msg, encoded := testAddrV2()
+
+	// Encode the message to wire format.
+	var buf bytes.Buffer
+	if err := msg.BtcEncode(&buf, ProtocolVersion); err != nil {
+		t.Fatalf("BtcEncode error %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), encoded) {
+		t.Fatalf("BtcEncode\n got: %s want: %s", spew.Sdump(buf.Bytes()),
+			spew.Sdump(encoded))
+	}
+
+	// Decode the message from wire format.
+	var readMsg MsgAddrV2
+	err := readMsg.BtcDecode(bytes.NewReader(encoded), ProtocolVersion)
+	if err != nil {
+		t.Fatalf("BtcDecode error %v", err)
+	}
+	if !reflect.DeepEqual(&readMsg, msg) {
+		t.Fatalf("BtcDecode\n got: %s want: %s", spew.Sdump(&readMsg),
+			spew.Sdump(msg))
+	}
+
+	// Ensure addresses on unknown networks are skipped.
+	unknown := []byte{
+		0x02,                   // Num addresses
+		0x29, 0xab, 0x5f, 0x49, // Timestamp
+		0x01,             // Services
+		0x63,             // Unknown network
+		0x03,             // Address length
+		0x01, 0x02, 0x03, // Address
+		0x23, 0x94, // Port
+	}
+	unknown = append(unknown, encoded[1:1+13]...)
+	readMsg = MsgAddrV2{}
+	err = readMsg.BtcDecode(bytes.NewReader(unknown), ProtocolVersion)
+	if err != nil {
+		t.Fatalf("BtcDecode error %v", err)
+	}
+	if !reflect.DeepEqual(readMsg.AddrList, msg.AddrList[:1]) {
+		t.Fatalf("BtcDecode\n got: %s want: %s",
+			spew.Sdump(readMsg.AddrList), spew.Sdump(msg.AddrList[:1]))
+	}
+}
+
+// TestAddrV2WireErrors performs negative tests against wire encode and decode
+// of MsgAddrV2 to confirm error paths work correctly.
+func TestAddrV2WireErrors(t *testing.T) {
+	pver := ProtocolVersion
+	wireErr := &MessageError{}
+	baseMsg, baseEncoded := testAddrV2()
+
+	// Ensure encoding and decoding fail with a protocol version prior to
+	// addrv2 support.
+	var buf bytes.Buffer
+	err := baseMsg.BtcEncode(&buf, AddrV2Version-1)
+	if reflect.TypeOf(err) != reflect.TypeOf(wireErr) {
+		t.Errorf("BtcEncode: wrong error for old protocol version - "+
+			"got %v, want %T", err, wireErr)
+	}
+	var msg MsgAddrV2
+	err = msg.BtcDecode(bytes.NewReader(baseEncoded), AddrV2Version-1)
+	if reflect.TypeOf(err) != reflect.TypeOf(wireErr) {
+		t.Errorf("BtcDecode: wrong error for old protocol version - "+
+			"got %v, want %T", err, wireErr)
+	}
+
+	// Ensure addresses with a length that does not match their network are
+	// rejected when encoding.
+	badLen := NewMsgAddrV2()
+	badLen.AddAddress(&NetAddress{IP: net.ParseIP("::1"),
+		Network: NetworkTorV3})
+	err = badLen.BtcEncode(&buf, pver)
+	if reflect.TypeOf(err) != reflect.TypeOf(wireErr) {
+		t.Errorf("BtcEncode: wrong error for invalid address length - "+
+			"got %v, want %T", err, wireErr)
+	}
+
+	tests := []struct {
+		name string // Test description
+		buf  []byte // Wire encoding
+	}{{
+		name: "too many addresses",
+		buf:  []byte{0xfd, 0xe9, 0x03},
+	}, {
+		name: "invalid length for known network",
+		buf: []byte{0x01, 0x29, 0xab, 0x5f, 0x49, 0x01,
+			byte(NetworkIPv4), 0x05, 0x7f, 0x00, 0x00, 0x00, 0x01,
+			0x23, 0x94},
+	}, {
+		name: "address too large",
+		buf: []byte{0x01, 0x29, 0xab, 0x5f, 0x49, 0x01, 0x63, 0xfd,
+			0x01, 0x02},
+	}, {
+		name: "cjdns address outside of fc00::/8",
+		buf: append(append([]byte{0x01, 0x29, 0xab, 0x5f, 0x49, 0x01,
+			byte(NetworkCJDNS), 0x10}, net.ParseIP("fd00::1")...),
+			0x23, 0x94),
+	}}
+	for _, test := range tests {
+		err := msg.BtcDecode(bytes.NewReader(test.buf), pver)
+		if reflect.TypeOf(err) != reflect.TypeOf(wireErr) {
+			t.Errorf("%q: wrong error - got %v, want %T", test.name,
+				err, wireErr)
+		}
+	}
+
+	// Ensure truncated messages are rejected at every point.
+	for i := 0; i < len(baseEncoded); i += 5 {
+		r := newFixedReader(i, baseEncoded)
+		if err := msg.BtcDecode(r, pver); err == nil {
+			t.Errorf("BtcDecode: unexpected success for message "+
+				"truncated to %d bytes", i)
+		}
+	}
+}
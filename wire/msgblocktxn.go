@@ -0,0 +1,146 @@
+// Copyright (c) 2016 The btcsuite developers
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+)
+
+// MsgBlockTxn implements the Message interface and represents a blocktxn
+// message.  It is used to deliver the transactions of a block requested via a
+// getblocktxn message (MsgGetBlockTxn).  The transactions of each tree are in
+// the same order as the indexes in the request.
+//
+// This message was not added until protocol versions starting with
+// CompactBlocksVersion.
+type MsgBlockTxn struct {
+	BlockHash     chainhash.Hash
+	Transactions  []*MsgTx
+	STransactions []*MsgTx
+}
+
+// readBlockTxnTree reads the transactions of a single transaction tree of a
+// blocktxn message from r.
+func readBlockTxnTree(r io.Reader, pver uint32, op string) ([]*MsgTx, error) {
+	maxTxPerTree := MaxTxPerTxTree(pver)
+	count, err := ReadVarInt(r, pver)
+	if err != nil {
+		return nil, err
+	}
+	if count > maxTxPerTree {
+		str := fmt.Sprintf("too many transactions to fit into a block "+
+			"[count %d, max %d]", count, maxTxPerTree)
+		return nil, messageError(op, str)
+	}
+
+	txns := make([]*MsgTx, 0, count)
+	for i := uint64(0); i < count; i++ {
+		var tx MsgTx
+		err := tx.BtcDecode(r, pver)
+		if err != nil {
+			return nil, err
+		}
+		txns = append(txns, &tx)
+	}
+
+	return txns, nil
+}
+
+// writeBlockTxnTree writes the transactions of a single transaction tree of a
+// blocktxn message to w.
+func writeBlockTxnTree(w io.Writer, pver uint32, txns []*MsgTx, op string) error {
+	maxTxPerTree := MaxTxPerTxTree(pver)
+	count := uint64(len(txns))
+	if count > maxTxPerTree {
+		str := fmt.Sprintf("too many transactions to fit into a block "+
+			"[count %d, max %d]", count, maxTxPerTree)
+		return messageError(op, str)
+	}
+
+	err := WriteVarInt(w, pver, count)
+	if err != nil {
+		return err
+	}
+	for _, tx := range txns {
+		err := tx.BtcEncode(w, pver)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BtcDecode decodes r using the protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgBlockTxn) BtcDecode(r io.Reader, pver uint32) error {
+	const op = "MsgBlockTxn.BtcDecode"
+	if pver < CompactBlocksVersion {
+		str := fmt.Sprintf("blocktxn message invalid for protocol "+
+			"version %d", pver)
+		return messageError(op, str)
+	}
+
+	err := readElement(r, &msg.BlockHash)
+	if err != nil {
+		return err
+	}
+
+	msg.Transactions, err = readBlockTxnTree(r, pver, op)
+	if err != nil {
+		return err
+	}
+	msg.STransactions, err = readBlockTxnTree(r, pver, op)
+	return err
+}
+
+// BtcEncode encodes the receiver to w using the protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgBlockTxn) BtcEncode(w io.Writer, pver uint32) error {
+	const op = "MsgBlockTxn.BtcEncode"
+	if pver < CompactBlocksVersion {
+		str := fmt.Sprintf("blocktxn message invalid for protocol "+
+			"version %d", pver)
+		return messageError(op, str)
+	}
+
+	err := writeElement(w, &msg.BlockHash)
+	if err != nil {
+		return err
+	}
+
+	err = writeBlockTxnTree(w, pver, msg.Transactions, op)
+	if err != nil {
+		return err
+	}
+	return writeBlockTxnTree(w, pver, msg.STransactions, op)
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgBlockTxn) Command() string {
+	return CmdBlockTxn
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgBlockTxn) MaxPayloadLength(pver uint32) uint32 {
+	// Block hash + the transactions of a full block.
+	return chainhash.HashSize + MaxBlockPayload
+}
+
+// NewMsgBlockTxn returns a new blocktxn message that conforms to the Message
+// interface for the passed block hash.  See MsgBlockTxn for details.
+func NewMsgBlockTxn(blockHash *chainhash.Hash) *MsgBlockTxn {
+	return &MsgBlockTxn{
+		BlockHash:     *blockHash,
+		Transactions:  make([]*MsgTx, 0),
+		STransactions: make([]*MsgTx, 0),
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2016 The btcsuite developers
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestBlockTxnWire tests the MsgBlockTxn wire encode and decode.
+func TestBlockTxnWire(t *testing.T) {
+	hash := testBlock.Header.BlockHash()
+	msg := NewMsgBlockTxn(&hash)
+	msg.Transactions = []*MsgTx{multiTx}
+	msg.STransactions = []*MsgTx{multiTx, multiTx}
+
+	// Ensure the command is expected value.
+	wantCmd := "blocktxn"
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgBlockTxn: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	wantBuf := append([]byte{}, hash[:]...)
+	wantBuf = append(wantBuf, 0x01)
+	wantBuf = append(wantBuf, multiTxEncoded...)
+	wantBuf = append(wantBuf, 0x02)
+	wantBuf = append(wantBuf, multiTxEncoded...)
+	wantBuf = append(wantBuf, multiTxEncoded...)
+
+	// Encode the message to wire format.
+	var buf bytes.Buffer
+	if err := msg.BtcEncode(&buf, ProtocolVersion); err != nil {
+		t.Fatalf("BtcEncode error %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), wantBuf) {
+		t.Fatalf("BtcEncode\n got: %s want: %s", spew.Sdump(buf.Bytes()),
+			spew.Sdump(wantBuf))
+	}
+
+	// Decode the message from wire format.
+	var readMsg MsgBlockTxn
+	err := readMsg.BtcDecode(bytes.NewReader(wantBuf), ProtocolVersion)
+	if err != nil {
+		t.Fatalf("BtcDecode error %v", err)
+	}
+	if !reflect.DeepEqual(&readMsg, msg) {
+		t.Fatalf("BtcDecode\n got: %s want: %s", spew.Sdump(&readMsg),
+			spew.Sdump(msg))
+	}
+
+	// Ensure the message is rejected prior to compact block support.
+	wireErr := &MessageError{}
+	err = readMsg.BtcDecode(bytes.NewReader(wantBuf), CompactBlocksVersion-1)
+	if reflect.TypeOf(err) != reflect.TypeOf(wireErr) {
+		t.Errorf("BtcDecode: wrong error for old protocol version - "+
+			"got %v, want %T", err, wireErr)
+	}
+
+	// Ensure truncated messages are rejected.
+	for i := 0; i < len(wantBuf); i += 11 {
+		r := newFixedReader(i, wantBuf)
+		if err := readMsg.BtcDecode(r, ProtocolVersion); err == nil {
+			t.Errorf("BtcDecode: unexpected success for message "+
+				"truncated to %d bytes", i)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+// Copyright (c) 2016 The btcsuite developers
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	// CmpctShortIDSize is the number of bytes used to encode each short
+	// transaction ID in a compact block.
+	CmpctShortIDSize = 6
+
+	// MaxCmpctShortID is the maximum value a short transaction ID may have
+	// since only the low CmpctShortIDSize bytes are encoded.
+	MaxCmpctShortID = 1<<(8*CmpctShortIDSize) - 1
+
+	// maxCmpctIndexPayload is the maximum number of bytes a differentially
+	// encoded transaction index can be.  The maximum number of transactions
+	// per tree always fits in a three byte varint.
+	maxCmpctIndexPayload = 3
+)
+
+// PrefilledTx houses a transaction that is sent in full as a part of a compact
+// block along with its absolute index within its transaction tree.
+type PrefilledTx struct {
+	Index uint32
+	Tx    *MsgTx
+}
+
+// MsgCmpctBlock implements the Message interface and represents a cmpctblock
+// message.  It is used to relay a block to peers that are likely to already
+// have most of its transactions in their memory pool by replacing each
+// transaction with a short transaction ID.  The short IDs are derived from the
+// transaction hashes keyed by the block header and the provided nonce.
+//
+// Both the regular and stake transaction trees are encoded independently.
+// Transactions the receiver is not expected to have, such as the coinbase, may
+// be sent in full via the prefilled transactions of the associated tree.  The
+// prefilled transaction indexes are the absolute positions of the transactions
+// within their tree while the short IDs fill the remaining positions in order.
+//
+// Use the getblocktxn message (MsgGetBlockTxn) to request any transactions
+// that could not be found after receiving a compact block.
+//
+// This message was not added until protocol versions starting with
+// CompactBlocksVersion.
+type MsgCmpctBlock struct {
+	Header        BlockHeader
+	Nonce         uint64
+	ShortIDs      []uint64
+	PrefilledTxs  []PrefilledTx
+	SShortIDs     []uint64
+	PrefilledSTxs []PrefilledTx
+}
+
+// NumTransactions returns the total number of transactions in the regular
+// transaction tree of the block represented by the compact block.
+func (msg *MsgCmpctBlock) NumTransactions() int {
+	return len(msg.ShortIDs) + len(msg.PrefilledTxs)
+}
+
+// NumSTransactions returns the total number of transactions in the stake
+// transaction tree of the block represented by the compact block.
+func (msg *MsgCmpctBlock) NumSTransactions() int {
+	return len(msg.SShortIDs) + len(msg.PrefilledSTxs)
+}
+
+// readCmpctTree reads the short IDs and prefilled transactions of a single
+// transaction tree of a compact block from r.
+func readCmpctTree(r io.Reader, pver uint32, op string) ([]uint64, []PrefilledTx, error) {
+	maxTxPerTree := MaxTxPerTxTree(pver)
+	count, err := ReadVarInt(r, pver)
+	if err != nil {
+		return nil, nil, err
+	}
+	if count > maxTxPerTree {
+		str := fmt.Sprintf("too many short ids for message "+
+			"[count %d, max %d]", count, maxTxPerTree)
+		return nil, nil, messageError(op, str)
+	}
+
+	var buf [8]byte
+	shortIDs := make([]uint64, 0, count)
+	for i := uint64(0); i < count; i++ {
+		_, err := io.ReadFull(r, buf[:CmpctShortIDSize])
+		if err != nil {
+			return nil, nil, err
+		}
+		shortIDs = append(shortIDs, littleEndian.Uint64(buf[:]))
+	}
+
+	prefilledCount, err := ReadVarInt(r, pver)
+	if err != nil {
+		return nil, nil, err
+	}
+	numTxns := count + prefilledCount
+	if prefilledCount > maxTxPerTree || numTxns > maxTxPerTree {
+		str := fmt.Sprintf("too many transactions to fit into a block "+
+			"[count %d, max %d]", numTxns, maxTxPerTree)
+		return nil, nil, messageError(op, str)
+	}
+
+	// The prefilled transaction indexes are differentially encoded, so
+	// convert them back to their absolute positions while ensuring they
+	// are within the bounds of the tree.
+	prefilled := make([]PrefilledTx, 0, prefilledCount)
+	var nextIndex uint64
+	for i := uint64(0); i < prefilledCount; i++ {
+		diff, err := ReadVarInt(r, pver)
+		if err != nil {
+			return nil, nil, err
+		}
+		index := nextIndex + diff
+		if diff >= numTxns || index >= numTxns {
+			str := fmt.Sprintf("prefilled transaction index %d is "+
+				"out of range [max %d]", index, numTxns-1)
+			return nil, nil, messageError(op, str)
+		}
+		nextIndex = index + 1
+
+		var tx MsgTx
+		err = tx.BtcDecode(r, pver)
+		if err != nil {
+			return nil, nil, err
+		}
+		prefilled = append(prefilled, PrefilledTx{
+			Index: uint32(index),
+			Tx:    &tx,
+		})
+	}
+
+	return shortIDs, prefilled, nil
+}
+
+// writeCmpctTree writes the short IDs and prefilled transactions of a single
+// transaction tree of a compact block to w.
+func writeCmpctTree(w io.Writer, pver uint32, shortIDs []uint64, prefilled []PrefilledTx, op string) error {
+	maxTxPerTree := MaxTxPerTxTree(pver)
+	numTxns := uint64(len(shortIDs) + len(prefilled))
+	if numTxns > maxTxPerTree {
+		str := fmt.Sprintf("too many transactions to fit into a block "+
+			"[count %d, max %d]", numTxns, maxTxPerTree)
+		return messageError(op, str)
+	}
+
+	err := WriteVarInt(w, pver, uint64(len(shortIDs)))
+	if err != nil {
+		return err
+	}
+	var buf [8]byte
+	for _, shortID := range shortIDs {
+		if shortID > MaxCmpctShortID {
+			str := fmt.Sprintf("short id %x exceeds the maximum "+
+				"allowed value %x", shortID, uint64(MaxCmpctShortID))
+			return messageError(op, str)
+		}
+		littleEndian.PutUint64(buf[:], shortID)
+		_, err := w.Write(buf[:CmpctShortIDSize])
+		if err != nil {
+			return err
+		}
+	}
+
+	err = WriteVarInt(w, pver, uint64(len(prefilled)))
+	if err != nil {
+		return err
+	}
+	var nextIndex uint64
+	for _, ptx := range prefilled {
+		index := uint64(ptx.Index)
+		if index < nextIndex || index >= numTxns {
+			str := fmt.Sprintf("prefilled transaction index %d is "+
+				"not ascending or out of range [max %d]", index,
+				numTxns-1)
+			return messageError(op, str)
+		}
+		err := WriteVarInt(w, pver, index-nextIndex)
+		if err != nil {
+			return err
+		}
+		nextIndex = index + 1
+
+		err = ptx.Tx.BtcEncode(w, pver)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BtcDecode decodes r using the protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgCmpctBlock) BtcDecode(r io.Reader, pver uint32) error {
+	const op = "MsgCmpctBlock.BtcDecode"
+	if pver < CompactBlocksVersion {
+		str := fmt.Sprintf("cmpctblock message invalid for protocol "+
+			"version %d", pver)
+		return messageError(op, str)
+	}
+
+	err := readBlockHeader(r, pver, &msg.Header)
+	if err != nil {
+		return err
+	}
+	err = readElement(r, &msg.Nonce)
+	if err != nil {
+		return err
+	}
+
+	msg.ShortIDs, msg.PrefilledTxs, err = readCmpctTree(r, pver, op)
+	if err != nil {
+		return err
+	}
+	msg.SShortIDs, msg.PrefilledSTxs, err = readCmpctTree(r, pver, op)
+	return err
+}
+
+// BtcEncode encodes the receiver to w using the protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgCmpctBlock) BtcEncode(w io.Writer, pver uint32) error {
+	const op = "MsgCmpctBlock.BtcEncode"
+	if pver < CompactBlocksVersion {
+		str := fmt.Sprintf("cmpctblock message invalid for protocol "+
+			"version %d", pver)
+		return messageError(op, str)
+	}
+
+	err := writeBlockHeader(w, pver, &msg.Header)
+	if err != nil {
+		return err
+	}
+	err = writeElement(w, msg.Nonce)
+	if err != nil {
+		return err
+	}
+
+	err = writeCmpctTree(w, pver, msg.ShortIDs, msg.PrefilledTxs, op)
+	if err != nil {
+		return err
+	}
+	return writeCmpctTree(w, pver, msg.SShortIDs, msg.PrefilledSTxs, op)
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgCmpctBlock) Command() string {
+	return CmdCmpctBlock
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgCmpctBlock) MaxPayloadLength(pver uint32) uint32 {
+	// A compact block is never larger than the full block it represents
+	// aside from the nonce, the additional short id and prefilled counts
+	// for each tree, and the index of each prefilled transaction.
+	maxTxPerTree := uint32(MaxTxPerTxTree(pver))
+	return MaxBlockPayload + 8 + 2*(MaxVarIntPayload*2+
+		maxTxPerTree*maxCmpctIndexPayload)
+}
+
+// NewMsgCmpctBlock returns a new cmpctblock message that conforms to the
+// Message interface using the passed block header and nonce.  See
+// MsgCmpctBlock for details.
+func NewMsgCmpctBlock(header *BlockHeader, nonce uint64) *MsgCmpctBlock {
+	return &MsgCmpctBlock{
+		Header:        *header,
+		Nonce:         nonce,
+		ShortIDs:      make([]uint64, 0),
+		PrefilledTxs:  make([]PrefilledTx, 0),
+		SShortIDs:     make([]uint64, 0),
+		PrefilledSTxs: make([]PrefilledTx, 0),
+	}
+}
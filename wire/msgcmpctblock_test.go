@@ -0,0 +1,186 @@
+// Copyright (c) 2016 The btcsuite developers
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+// testCmpctBlock returns a compact block that exercises short ids and
+// differentially encoded prefilled transactions in both trees along with its
+// expected wire encoding.
+func testCmpctBlock(t *testing.T) (*MsgCmpctBlock, []byte) {
+	t.Helper()
+
+	msg := NewMsgCmpctBlock(&testBlock.Header, 0x0102030405060708)
+	msg.ShortIDs = []uint64{0x010203040506, MaxCmpctShortID}
+	msg.PrefilledTxs = []PrefilledTx{
+		{Index: 0, Tx: testBlock.Transactions[0]},
+		{Index: 3, Tx: multiTx},
+	}
+	msg.SShortIDs = []uint64{0x0a}
+	msg.PrefilledSTxs = []PrefilledTx{}
+
+	var buf bytes.Buffer
+	if err := writeBlockHeader(&buf, ProtocolVersion, &msg.Header); err != nil {
+		t.Fatalf("writeBlockHeader: %v", err)
+	}
+	buf.Write([]byte{0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01}) // Nonce
+	buf.Write([]byte{0x02})                                           // Num short ids
+	buf.Write([]byte{0x06, 0x05, 0x04, 0x03, 0x02, 0x01})             // Short id 0
+	buf.Write([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff})             // Short id 1
+	buf.Write([]byte{0x02})                                           // Num prefilled
+	buf.Write([]byte{0x00})                                           // Index 0
+	if err := testBlock.Transactions[0].BtcEncode(&buf, ProtocolVersion); err != nil {
+		t.Fatalf("BtcEncode: %v", err)
+	}
+	buf.Write([]byte{0x02}) // Index 3 encoded as 3 - (0 + 1)
+	buf.Write(multiTxEncoded)
+	buf.Write([]byte{0x01})                               // Num stake short ids
+	buf.Write([]byte{0x0a, 0x00, 0x00, 0x00, 0x00, 0x00}) // Stake short id 0
+	buf.Write([]byte{0x00})                               // Num stake prefilled
+
+	return msg, buf.Bytes()
+}
+
+// TestCmpctBlock tests the MsgCmpctBlock API.
+func TestCmpctBlock(t *testing.T) {
+	msg, _ := testCmpctBlock(t)
+
+	// Ensure the command is expected value.
+	wantCmd := "cmpctblock"
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgCmpctBlock: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	// Ensure the number of transactions in each tree accounts for both the
+	// short ids and the prefilled transactions.
+	if n := msg.NumTransactions(); n != 4 {
+		t.Errorf("NumTransactions: wrong count - got %d, want 4", n)
+	}
+	if n := msg.NumSTransactions(); n != 1 {
+		t.Errorf("NumSTransactions: wrong count - got %d, want 1", n)
+	}
+
+	// Ensure the max payload allows for a full block.
+	if maxPayload := msg.MaxPayloadLength(ProtocolVersion); maxPayload <
+		MaxBlockPayload {
+
+		t.Errorf("MaxPayloadLength: max payload length %d is less than "+
+			"the max block payload %d", maxPayload, MaxBlockPayload)
+	}
+}
+
+// TestCmpctBlockWire tests the MsgCmpctBlock wire encode and decode.
+func TestCmpctBlockWire(t *testing.T) {
+	msg, encoded := testCmpctBlock(t)
+
+	// Encode the message to wire format.
+	var buf bytes.Buffer
+	if err := msg.BtcEncode(&buf, ProtocolVersion); err != nil {
+		t.Fatalf("BtcEncode error %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), encoded) {
+		t.Fatalf("BtcEncode\n got: %s want: %s", spew.Sdump(buf.Bytes()),
+			spew.Sdump(encoded))
+	}
+
+	// Decode the message from wire format.
+	var readMsg MsgCmpctBlock
+	err := readMsg.BtcDecode(bytes.NewReader(encoded), ProtocolVersion)
+	if err != nil {
+		t.Fatalf("BtcDecode error %v", err)
+	}
+	if !reflect.DeepEqual(&readMsg, msg) {
+		t.Fatalf("BtcDecode\n got: %s want: %s", spew.Sdump(&readMsg),
+			spew.Sdump(msg))
+	}
+}
+
+// TestCmpctBlockWireErrors performs negative tests against wire encode and
+// decode of MsgCmpctBlock to confirm error paths work correctly.
+func TestCmpctBlockWireErrors(t *testing.T) {
+	pver := ProtocolVersion
+	wireErr := &MessageError{}
+	baseMsg, baseEncoded := testCmpctBlock(t)
+
+	// Ensure encoding and decoding fail with a protocol version prior to
+	// compact block support.
+	var buf bytes.Buffer
+	err := baseMsg.BtcEncode(&buf, CompactBlocksVersion-1)
+	if reflect.TypeOf(err) != reflect.TypeOf(wireErr) {
+		t.Errorf("BtcEncode: wrong error for old protocol version - "+
+			"got %v, want %T", err, wireErr)
+	}
+	var msg MsgCmpctBlock
+	err = msg.BtcDecode(bytes.NewReader(baseEncoded), CompactBlocksVersion-1)
+	if reflect.TypeOf(err) != reflect.TypeOf(wireErr) {
+		t.Errorf("BtcDecode: wrong error for old protocol version - "+
+			"got %v, want %T", err, wireErr)
+	}
+
+	// Ensure short ids that do not fit in the encoding are rejected.
+	badShortID := *baseMsg
+	badShortID.ShortIDs = []uint64{MaxCmpctShortID + 1}
+	err = badShortID.BtcEncode(&buf, pver)
+	if reflect.TypeOf(err) != reflect.TypeOf(wireErr) {
+		t.Errorf("BtcEncode: wrong error for oversized short id - "+
+			"got %v, want %T", err, wireErr)
+	}
+
+	// Ensure prefilled transactions that are not in ascending order are
+	// rejected.
+	badOrder := *baseMsg
+	badOrder.PrefilledTxs = []PrefilledTx{
+		{Index: 2, Tx: multiTx},
+		{Index: 1, Tx: multiTx},
+	}
+	err = badOrder.BtcEncode(&buf, pver)
+	if reflect.TypeOf(err) != reflect.TypeOf(wireErr) {
+		t.Errorf("BtcEncode: wrong error for unordered prefilled "+
+			"transactions - got %v, want %T", err, wireErr)
+	}
+
+	// Ensure prefilled transactions with an index outside of the tree are
+	// rejected.
+	badIndex := *baseMsg
+	badIndex.PrefilledTxs = []PrefilledTx{{Index: 3, Tx: multiTx}}
+	err = badIndex.BtcEncode(&buf, pver)
+	if reflect.TypeOf(err) != reflect.TypeOf(wireErr) {
+		t.Errorf("BtcEncode: wrong error for out of range prefilled "+
+			"transaction - got %v, want %T", err, wireErr)
+	}
+
+	// Ensure decoding a prefilled transaction index outside of the tree is
+	// rejected.  The tree only has one transaction, but the prefilled
+	// transaction claims index 1.
+	var encoded bytes.Buffer
+	writeBlockHeader(&encoded, pver, &baseMsg.Header)
+	encoded.Write([]byte{0, 0, 0, 0, 0, 0, 0, 0}) // Nonce
+	encoded.Write([]byte{0x00})                   // Num short ids
+	encoded.Write([]byte{0x01})                   // Num prefilled
+	encoded.Write([]byte{0x01})                   // Index 1
+	encoded.Write(multiTxEncoded)
+	err = msg.BtcDecode(bytes.NewReader(encoded.Bytes()), pver)
+	if reflect.TypeOf(err) != reflect.TypeOf(wireErr) {
+		t.Errorf("BtcDecode: wrong error for out of range prefilled "+
+			"transaction - got %v, want %T", err, wireErr)
+	}
+
+	// Ensure truncated messages are rejected at every point.
+	for i := 0; i < len(baseEncoded); i += 7 {
+		r := newFixedReader(i, baseEncoded)
+		if err := msg.BtcDecode(r, pver); err == nil {
+			t.Errorf("BtcDecode: unexpected success for message "+
+				"truncated to %d bytes", i)
+		}
+	}
+}
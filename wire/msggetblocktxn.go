@@ -0,0 +1,167 @@
+// Copyright (c) 2016 The btcsuite developers
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+)
+
+// MsgGetBlockTxn implements the Message interface and represents a getblocktxn
+// message.  It is used to request the transactions of a block that could not
+// be reconstructed from a compact block (MsgCmpctBlock).  The requested
+// transactions are identified by their absolute indexes within the regular and
+// stake transaction trees of the block, which must be in ascending order.
+//
+// The blocktxn message (MsgBlockTxn) is used to reply to this message.
+//
+// This message was not added until protocol versions starting with
+// CompactBlocksVersion.
+type MsgGetBlockTxn struct {
+	BlockHash  chainhash.Hash
+	Indexes    []uint32
+	STxIndexes []uint32
+}
+
+// readCmpctIndexes reads a list of differentially encoded transaction indexes
+// from r and returns their absolute values.
+func readCmpctIndexes(r io.Reader, pver uint32, op string) ([]uint32, error) {
+	maxTxPerTree := MaxTxPerTxTree(pver)
+	count, err := ReadVarInt(r, pver)
+	if err != nil {
+		return nil, err
+	}
+	if count > maxTxPerTree {
+		str := fmt.Sprintf("too many transaction indexes for message "+
+			"[count %d, max %d]", count, maxTxPerTree)
+		return nil, messageError(op, str)
+	}
+
+	indexes := make([]uint32, 0, count)
+	var nextIndex uint64
+	for i := uint64(0); i < count; i++ {
+		diff, err := ReadVarInt(r, pver)
+		if err != nil {
+			return nil, err
+		}
+		index := nextIndex + diff
+		if diff >= maxTxPerTree || index >= maxTxPerTree {
+			str := fmt.Sprintf("transaction index %d is out of "+
+				"range [max %d]", index, maxTxPerTree-1)
+			return nil, messageError(op, str)
+		}
+		indexes = append(indexes, uint32(index))
+		nextIndex = index + 1
+	}
+
+	return indexes, nil
+}
+
+// writeCmpctIndexes writes the passed ascending transaction indexes to w using
+// differential encoding.
+func writeCmpctIndexes(w io.Writer, pver uint32, indexes []uint32, op string) error {
+	maxTxPerTree := MaxTxPerTxTree(pver)
+	count := uint64(len(indexes))
+	if count > maxTxPerTree {
+		str := fmt.Sprintf("too many transaction indexes for message "+
+			"[count %d, max %d]", count, maxTxPerTree)
+		return messageError(op, str)
+	}
+
+	err := WriteVarInt(w, pver, count)
+	if err != nil {
+		return err
+	}
+	var nextIndex uint64
+	for _, index := range indexes {
+		if uint64(index) < nextIndex || uint64(index) >= maxTxPerTree {
+			str := fmt.Sprintf("transaction index %d is not "+
+				"ascending or out of range [max %d]", index,
+				maxTxPerTree-1)
+			return messageError(op, str)
+		}
+		err := WriteVarInt(w, pver, uint64(index)-nextIndex)
+		if err != nil {
+			return err
+		}
+		nextIndex = uint64(index) + 1
+	}
+
+	return nil
+}
+
+// BtcDecode decodes r using the protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgGetBlockTxn) BtcDecode(r io.Reader, pver uint32) error {
+	const op = "MsgGetBlockTxn.BtcDecode"
+	if pver < CompactBlocksVersion {
+		str := fmt.Sprintf("getblocktxn message invalid for protocol "+
+			"version %d", pver)
+		return messageError(op, str)
+	}
+
+	err := readElement(r, &msg.BlockHash)
+	if err != nil {
+		return err
+	}
+
+	msg.Indexes, err = readCmpctIndexes(r, pver, op)
+	if err != nil {
+		return err
+	}
+	msg.STxIndexes, err = readCmpctIndexes(r, pver, op)
+	return err
+}
+
+// BtcEncode encodes the receiver to w using the protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgGetBlockTxn) BtcEncode(w io.Writer, pver uint32) error {
+	const op = "MsgGetBlockTxn.BtcEncode"
+	if pver < CompactBlocksVersion {
+		str := fmt.Sprintf("getblocktxn message invalid for protocol "+
+			"version %d", pver)
+		return messageError(op, str)
+	}
+
+	err := writeElement(w, &msg.BlockHash)
+	if err != nil {
+		return err
+	}
+
+	err = writeCmpctIndexes(w, pver, msg.Indexes, op)
+	if err != nil {
+		return err
+	}
+	return writeCmpctIndexes(w, pver, msg.STxIndexes, op)
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgGetBlockTxn) Command() string {
+	return CmdGetBlockTxn
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgGetBlockTxn) MaxPayloadLength(pver uint32) uint32 {
+	// Block hash + index count and max indexes for each tree.
+	maxTxPerTree := uint32(MaxTxPerTxTree(pver))
+	return chainhash.HashSize + 2*(MaxVarIntPayload+
+		maxTxPerTree*maxCmpctIndexPayload)
+}
+
+// NewMsgGetBlockTxn returns a new getblocktxn message that conforms to the
+// Message interface using the passed parameters.  See MsgGetBlockTxn for
+// details.
+func NewMsgGetBlockTxn(blockHash *chainhash.Hash, indexes, sTxIndexes []uint32) *MsgGetBlockTxn {
+	return &MsgGetBlockTxn{
+		BlockHash:  *blockHash,
+		Indexes:    indexes,
+		STxIndexes: sTxIndexes,
+	}
+}
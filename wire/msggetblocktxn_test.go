@@ -0,0 +1,102 @@
+// Copyright (c) 2016 The btcsuite developers
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+)
+
+// TestGetBlockTxnWire tests the MsgGetBlockTxn wire encode and decode for
+// various indexes.
+func TestGetBlockTxnWire(t *testing.T) {
+	hash := testBlock.Header.BlockHash()
+	wireErr := &MessageError{}
+
+	tests := []struct {
+		name string          // Test description
+		in   *MsgGetBlockTxn // Message to encode
+		buf  []byte          // Wire encoding
+		err  error           // Expected error
+	}{{
+		name: "no indexes",
+		in:   NewMsgGetBlockTxn(&hash, []uint32{}, []uint32{}),
+		buf:  append(hash[:], 0x00, 0x00),
+	}, {
+		name: "differentially encoded indexes",
+		in: NewMsgGetBlockTxn(&hash, []uint32{1, 2, 5, 300},
+			[]uint32{0}),
+		buf: append(hash[:], 0x04, 0x01, 0x00, 0x02, 0xfd, 0x26, 0x01,
+			0x01, 0x00),
+	}, {
+		name: "unordered indexes",
+		in:   NewMsgGetBlockTxn(&hash, []uint32{2, 1}, nil),
+		err:  wireErr,
+	}, {
+		name: "duplicate indexes",
+		in:   NewMsgGetBlockTxn(&hash, nil, []uint32{3, 3}),
+		err:  wireErr,
+	}}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		err := test.in.BtcEncode(&buf, ProtocolVersion)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.err) {
+			t.Errorf("%q: wrong encode error - got %v, want %v",
+				test.name, err, test.err)
+			continue
+		}
+		if test.err != nil {
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), test.buf) {
+			t.Errorf("%q: BtcEncode\n got: %s want: %s", test.name,
+				spew.Sdump(buf.Bytes()), spew.Sdump(test.buf))
+			continue
+		}
+
+		var msg MsgGetBlockTxn
+		err = msg.BtcDecode(bytes.NewReader(test.buf), ProtocolVersion)
+		if err != nil {
+			t.Errorf("%q: BtcDecode error %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(&msg, test.in) {
+			t.Errorf("%q: BtcDecode\n got: %s want: %s", test.name,
+				spew.Sdump(&msg), spew.Sdump(test.in))
+		}
+	}
+}
+
+// TestGetBlockTxnWireErrors performs negative tests against wire decode of
+// MsgGetBlockTxn to confirm error paths work correctly.
+func TestGetBlockTxnWireErrors(t *testing.T) {
+	wireErr := &MessageError{}
+	var hash chainhash.Hash
+
+	// Ensure the message is rejected prior to compact block support.
+	msg := NewMsgGetBlockTxn(&hash, []uint32{0}, nil)
+	var buf bytes.Buffer
+	err := msg.BtcEncode(&buf, CompactBlocksVersion-1)
+	if reflect.TypeOf(err) != reflect.TypeOf(wireErr) {
+		t.Errorf("BtcEncode: wrong error for old protocol version - "+
+			"got %v, want %T", err, wireErr)
+	}
+
+	// Ensure differential indexes that overflow the maximum number of
+	// transactions per tree are rejected.
+	encoded := append(hash[:], 0x02, 0x00, 0xfe, 0xff, 0xff, 0xff, 0xff,
+		0x00)
+	err = msg.BtcDecode(bytes.NewReader(encoded), ProtocolVersion)
+	if reflect.TypeOf(err) != reflect.TypeOf(wireErr) {
+		t.Errorf("BtcDecode: wrong error for out of range index - "+
+			"got %v, want %T", err, wireErr)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2016 The btcsuite developers
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+)
+
+// CmpctBlockVersion1 is the version of the compact block encoding defined by
+// the cmpctblock, getblocktxn and blocktxn messages which use six byte short
+// transaction IDs for both the regular and stake transaction trees.
+const CmpctBlockVersion1 uint64 = 1
+
+// MsgSendCmpct implements the Message interface and represents a sendcmpct
+// message.  It is used to signal support for compact block relay along with
+// the compact block encoding version the sender understands.
+//
+// When AnnounceUsingCmpct is set, the sender is requesting that new blocks be
+// announced by directly sending a cmpctblock message (high-bandwidth mode)
+// rather than via an inventory vector or headers message (low-bandwidth mode).
+//
+// This message was not added until protocol versions starting with
+// CompactBlocksVersion.
+type MsgSendCmpct struct {
+	AnnounceUsingCmpct bool
+	CmpctBlockVersion  uint64
+}
+
+// BtcDecode decodes r using the protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgSendCmpct) BtcDecode(r io.Reader, pver uint32) error {
+	if pver < CompactBlocksVersion {
+		str := fmt.Sprintf("sendcmpct message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgSendCmpct.BtcDecode", str)
+	}
+
+	return readElements(r, &msg.AnnounceUsingCmpct, &msg.CmpctBlockVersion)
+}
+
+// BtcEncode encodes the receiver to w using the protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgSendCmpct) BtcEncode(w io.Writer, pver uint32) error {
+	if pver < CompactBlocksVersion {
+		str := fmt.Sprintf("sendcmpct message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgSendCmpct.BtcEncode", str)
+	}
+
+	return writeElements(w, msg.AnnounceUsingCmpct, msg.CmpctBlockVersion)
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgSendCmpct) Command() string {
+	return CmdSendCmpct
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgSendCmpct) MaxPayloadLength(pver uint32) uint32 {
+	// 1 byte announce flag + 8 bytes version.
+	return 9
+}
+
+// NewMsgSendCmpct returns a new sendcmpct message that conforms to the Message
+// interface.  See MsgSendCmpct for details.
+func NewMsgSendCmpct(announceUsingCmpct bool, version uint64) *MsgSendCmpct {
+	return &MsgSendCmpct{
+		AnnounceUsingCmpct: announceUsingCmpct,
+		CmpctBlockVersion:  version,
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2016 The btcsuite developers
+// Copyright (c) 2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestSendCmpct tests the MsgSendCmpct API against the latest protocol
+// version.
+func TestSendCmpct(t *testing.T) {
+	pver := ProtocolVersion
+
+	msg := NewMsgSendCmpct(true, CmpctBlockVersion1)
+	if !msg.AnnounceUsingCmpct || msg.CmpctBlockVersion != CmpctBlockVersion1 {
+		t.Errorf("NewMsgSendCmpct: wrong fields - got %v", spew.Sdump(msg))
+	}
+
+	// Ensure the command is expected value.
+	wantCmd := "sendcmpct"
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgSendCmpct: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	// Ensure max payload is expected value for latest protocol version.
+	wantPayload := uint32(9)
+	maxPayload := msg.MaxPayloadLength(pver)
+	if maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length for "+
+			"protocol version %d - got %v, want %v", pver,
+			maxPayload, wantPayload)
+	}
+}
+
+// TestSendCmpctWire tests the MsgSendCmpct wire encode and decode for various
+// protocol versions.
+func TestSendCmpctWire(t *testing.T) {
+	wireErr := &MessageError{}
+
+	tests := []struct {
+		in   MsgSendCmpct // Message to encode
+		buf  []byte       // Wire encoding
+		pver uint32       // Protocol version for wire encoding
+		err  error        // Expected error
+	}{
+		// Latest protocol version with high-bandwidth mode.
+		{
+			MsgSendCmpct{AnnounceUsingCmpct: true, CmpctBlockVersion: 1},
+			[]byte{0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			ProtocolVersion,
+			nil,
+		},
+
+		// Protocol version CompactBlocksVersion with low-bandwidth mode.
+		{
+			MsgSendCmpct{AnnounceUsingCmpct: false, CmpctBlockVersion: 1},
+			[]byte{0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			CompactBlocksVersion,
+			nil,
+		},
+
+		// Protocol version prior to compact block support.
+		{
+			MsgSendCmpct{AnnounceUsingCmpct: true, CmpctBlockVersion: 1},
+			[]byte{0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			CompactBlocksVersion - 1,
+			wireErr,
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode the message to wire format.
+		var buf bytes.Buffer
+		err := test.in.BtcEncode(&buf, test.pver)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.err) {
+			t.Errorf("BtcEncode #%d wrong error got: %v, want: %v",
+				i, err, test.err)
+			continue
+		}
+		if test.err == nil && !bytes.Equal(buf.Bytes(), test.buf) {
+			t.Errorf("BtcEncode #%d\n got: %s want: %s", i,
+				spew.Sdump(buf.Bytes()), spew.Sdump(test.buf))
+			continue
+		}
+
+		// Decode the message from wire format.
+		var msg MsgSendCmpct
+		rbuf := bytes.NewReader(test.buf)
+		err = msg.BtcDecode(rbuf, test.pver)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.err) {
+			t.Errorf("BtcDecode #%d wrong error got: %v, want: %v",
+				i, err, test.err)
+			continue
+		}
+		if test.err == nil && !reflect.DeepEqual(msg, test.in) {
+			t.Errorf("BtcDecode #%d\n got: %s want: %s", i,
+				spew.Sdump(msg), spew.Sdump(test.in))
+			continue
+		}
+	}
+}
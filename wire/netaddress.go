@@ -6,8 +6,10 @@
 package wire
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"time"
@@ -29,8 +31,78 @@ func maxNetAddressPayload(pver uint32) uint32 {
 	return plen
 }
 
+// NetworkID identifies the network an address belongs to in the addrv2 message
+// (MsgAddrV2) encoding.
+type NetworkID uint8
+
+// These constants define the networks supported by the addrv2 message.  They
+// match the network IDs defined by BIP0155.
+const (
+	// NetworkIPv4 identifies an IPv4 address.
+	NetworkIPv4 NetworkID = 1
+
+	// NetworkIPv6 identifies an IPv6 address.
+	NetworkIPv6 NetworkID = 2
+
+	// NetworkTorV2 identifies a Tor v2 onion service address.
+	NetworkTorV2 NetworkID = 3
+
+	// NetworkTorV3 identifies a Tor v3 onion service address.
+	NetworkTorV3 NetworkID = 4
+
+	// NetworkI2P identifies an I2P address.
+	NetworkI2P NetworkID = 5
+
+	// NetworkCJDNS identifies a CJDNS address.
+	NetworkCJDNS NetworkID = 6
+)
+
+// networkAddrLens maps the supported networks to the length of their encoded
+// addresses.
+var networkAddrLens = map[NetworkID]int{
+	NetworkIPv4:  4,
+	NetworkIPv6:  16,
+	NetworkTorV2: 10,
+	NetworkTorV3: 32,
+	NetworkI2P:   32,
+	NetworkCJDNS: 16,
+}
+
+// Map of network IDs back to their constant names for pretty printing.
+var networkIDStrings = map[NetworkID]string{
+	NetworkIPv4:  "IPv4",
+	NetworkIPv6:  "IPv6",
+	NetworkTorV2: "TorV2",
+	NetworkTorV3: "TorV3",
+	NetworkI2P:   "I2P",
+	NetworkCJDNS: "CJDNS",
+}
+
+// String returns the NetworkID in human-readable form.
+func (id NetworkID) String() string {
+	if s, ok := networkIDStrings[id]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown NetworkID (%d)", uint8(id))
+}
+
+// maxAddrV2Size is the maximum size of an encoded address in the addrv2
+// message.  Addresses on unknown networks up to this size are skipped while
+// larger ones are rejected.
+const maxAddrV2Size = 512
+
+// onionCatPrefix is the IPv6 prefix used to represent Tor v2 onion service
+// addresses as IPv6 addresses.  This is the same range used by OnionCat.
+var onionCatPrefix = []byte{0xfd, 0x87, 0xd8, 0x7e, 0xeb, 0x43}
+
 // NetAddress defines information about a peer on the network including the time
 // it was last seen, the services it supports, its IP address, and port.
+//
+// Addresses on networks that can not be represented as an IP address, such as
+// Tor v3 onion services and I2P, along with CJDNS addresses, which are IPv6
+// addresses that must be distinguished from the IPv6 unique local range, set
+// Network to identify the network and store their raw address in IP.  Such
+// addresses can only be relayed via the addrv2 message (MsgAddrV2).
 type NetAddress struct {
 	// Last time the address was seen.  This is, unfortunately, encoded as a
 	// uint32 on the wire and therefore is limited to 2106.  This field is
@@ -47,6 +119,18 @@ type NetAddress struct {
 	// Port the peer is using.  This is encoded in big endian on the wire
 	// which differs from most everything else.
 	Port uint16
+
+	// Network identifies the network of addresses that are not IPv4, IPv6,
+	// or Tor v2 onion service addresses (which are represented by their
+	// OnionCat IPv6 address).  It is zero for those addresses.
+	Network NetworkID
+}
+
+// IsIP returns whether or not the address is an IPv4, IPv6, or Tor v2 onion
+// service address represented by its OnionCat IPv6 address and can therefore
+// be relayed via the addr message (MsgAddr).
+func (na *NetAddress) IsIP() bool {
+	return na.Network == 0
 }
 
 // HasService returns whether the specified service is supported by the address.
@@ -159,3 +243,129 @@ func writeNetAddress(w io.Writer, pver uint32, na *NetAddress, ts bool) error {
 	// Sigh.  Decred protocol mixes little and big endian.
 	return binary.Write(w, bigEndian, na.Port)
 }
+
+// addrV2Encoding returns the network ID and encoded address of the passed
+// NetAddress for use in the addrv2 message.
+func addrV2Encoding(na *NetAddress) (NetworkID, []byte) {
+	if !na.IsIP() {
+		return na.Network, na.IP
+	}
+	if ip4 := na.IP.To4(); ip4 != nil {
+		return NetworkIPv4, ip4
+	}
+	if len(na.IP) == net.IPv6len && bytes.HasPrefix(na.IP, onionCatPrefix) {
+		return NetworkTorV2, na.IP[len(onionCatPrefix):]
+	}
+
+	// Ensure to always write 16 bytes even if the ip is nil.
+	ip := make([]byte, net.IPv6len)
+	copy(ip, na.IP.To16())
+	return NetworkIPv6, ip
+}
+
+// readNetAddressV2 reads an encoded NetAddress in the addrv2 message format
+// from r.  It returns false without an error when the address belongs to an
+// unknown network since such addresses must be skipped.
+func readNetAddressV2(r io.Reader, pver uint32, na *NetAddress) (bool, error) {
+	const op = "readNetAddressV2"
+	err := readElement(r, (*uint32Time)(&na.Timestamp))
+	if err != nil {
+		return false, err
+	}
+	services, err := ReadVarInt(r, pver)
+	if err != nil {
+		return false, err
+	}
+	var netID NetworkID
+	err = readElement(r, (*uint8)(&netID))
+	if err != nil {
+		return false, err
+	}
+	addrLen, err := ReadVarInt(r, pver)
+	if err != nil {
+		return false, err
+	}
+	if addrLen > maxAddrV2Size {
+		str := fmt.Sprintf("address is too large [len %d, max %d]",
+			addrLen, maxAddrV2Size)
+		return false, messageError(op, str)
+	}
+	wantLen, known := networkAddrLens[netID]
+	if known && uint64(wantLen) != addrLen {
+		str := fmt.Sprintf("invalid %v address length [len %d, want %d]",
+			netID, addrLen, wantLen)
+		return false, messageError(op, str)
+	}
+	addr := make([]byte, addrLen)
+	_, err = io.ReadFull(r, addr)
+	if err != nil {
+		return false, err
+	}
+	// Sigh.  Decred protocol mixes little and big endian.
+	port, err := binarySerializer.Uint16(r, bigEndian)
+	if err != nil {
+		return false, err
+	}
+	if !known {
+		return false, nil
+	}
+
+	*na = NetAddress{
+		Timestamp: na.Timestamp,
+		Services:  ServiceFlag(services),
+		Port:      port,
+	}
+	switch netID {
+	case NetworkIPv4:
+		na.IP = net.IPv4(addr[0], addr[1], addr[2], addr[3])
+	case NetworkIPv6:
+		na.IP = net.IP(addr)
+	case NetworkTorV2:
+		ip := make([]byte, 0, net.IPv6len)
+		ip = append(ip, onionCatPrefix...)
+		na.IP = net.IP(append(ip, addr...))
+	case NetworkCJDNS:
+		// CJDNS addresses are always in the fc00::/8 range.
+		if addr[0] != 0xfc {
+			str := fmt.Sprintf("invalid CJDNS address %v",
+				net.IP(addr))
+			return false, messageError(op, str)
+		}
+		na.IP = net.IP(addr)
+		na.Network = netID
+	default:
+		na.IP = net.IP(addr)
+		na.Network = netID
+	}
+	return true, nil
+}
+
+// writeNetAddressV2 serializes a NetAddress to w in the addrv2 message format.
+func writeNetAddressV2(w io.Writer, pver uint32, na *NetAddress) error {
+	netID, addr := addrV2Encoding(na)
+	if wantLen, ok := networkAddrLens[netID]; !ok || len(addr) != wantLen {
+		str := fmt.Sprintf("invalid %v address length [len %d]", netID,
+			len(addr))
+		return messageError("writeNetAddressV2", str)
+	}
+
+	err := writeElement(w, uint32(na.Timestamp.Unix()))
+	if err != nil {
+		return err
+	}
+	err = WriteVarInt(w, pver, uint64(na.Services))
+	if err != nil {
+		return err
+	}
+	err = writeElement(w, uint8(netID))
+	if err != nil {
+		return err
+	}
+	err = WriteVarBytes(w, pver, addr)
+	if err != nil {
+		return err
+	}
+
+	// Sigh.  Decred protocol mixes little and big endian.
+	return binary.Write(w, bigEndian, na.Port)
+}
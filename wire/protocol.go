@@ -17,7 +17,7 @@ const (
 	InitialProcotolVersion uint32 = 1
 
 	// ProtocolVersion is the latest protocol version this package supports.
-	ProtocolVersion uint32 = 6
+	ProtocolVersion uint32 = 8
 
 	// NodeBloomVersion is the protocol version which added the SFNodeBloom
 	// service flag (unused).
@@ -39,6 +39,16 @@ const (
 	// flag and the cfheaders, cfilter, cftypes, getcfheaders, getcfilter and
 	// getcftypes messages.
 	NodeCFVersion uint32 = 6
+
+	// CompactBlocksVersion is the protocol version which adds the sendcmpct,
+	// cmpctblock, getblocktxn and blocktxn messages along with the compact
+	// block inventory vector type.
+	CompactBlocksVersion uint32 = 7
+
+	// AddrV2Version is the protocol version which adds the sendaddrv2 and
+	// addrv2 messages which support addresses on networks other than IPv4
+	// and IPv6 such as Tor v3 onion services, I2P, and CJDNS.
+	AddrV2Version uint32 = 8
 )
 
 // ServiceFlag identifies services supported by a Decred peer.
@@ -55,13 +65,23 @@ const (
 	// SFNodeCF is a flag used to indicate a peer supports committed
 	// filters (CFs).
 	SFNodeCF
+
+	// SFNodeP2PV2 is a flag used to indicate a peer supports the encrypted
+	// v2 peer-to-peer transport.
+	SFNodeP2PV2
+
+	// SFNodeNetworkLimited is a flag used to indicate a peer only serves
+	// the most recent blocks because the older block data was pruned.
+	SFNodeNetworkLimited
 )
 
 // Map of service flags back to their constant names for pretty printing.
 var sfStrings = map[ServiceFlag]string{
-	SFNodeNetwork: "SFNodeNetwork",
-	SFNodeBloom:   "SFNodeBloom",
-	SFNodeCF:      "SFNodeCF",
+	SFNodeNetwork:        "SFNodeNetwork",
+	SFNodeBloom:          "SFNodeBloom",
+	SFNodeCF:             "SFNodeCF",
+	SFNodeP2PV2:          "SFNodeP2PV2",
+	SFNodeNetworkLimited: "SFNodeNetworkLimited",
 }
 
 // orderedSFStrings is an ordered list of service flags from highest to
@@ -70,6 +90,8 @@ var orderedSFStrings = []ServiceFlag{
 	SFNodeNetwork,
 	SFNodeBloom,
 	SFNodeCF,
+	SFNodeP2PV2,
+	SFNodeNetworkLimited,
 }
 
 // String returns the ServiceFlag in human-readable form.
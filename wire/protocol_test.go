@@ -17,7 +17,9 @@ func TestServiceFlagStringer(t *testing.T) {
 		{SFNodeNetwork, "SFNodeNetwork"},
 		{SFNodeBloom, "SFNodeBloom"},
 		{SFNodeCF, "SFNodeCF"},
-		{0xffffffff, "SFNodeNetwork|SFNodeBloom|SFNodeCF|0xfffffff8"},
+		{SFNodeP2PV2, "SFNodeP2PV2"},
+		{SFNodeNetworkLimited, "SFNodeNetworkLimited"},
+		{0xffffffff, "SFNodeNetwork|SFNodeBloom|SFNodeCF|SFNodeP2PV2|SFNodeNetworkLimited|0xffffffe0"},
 	}
 
 	t.Logf("Running %d tests", len(tests))